@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	cp "github.com/otiai10/copy"
+	"github.com/spf13/cobra"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/executors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func newArtifacts(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	artifactsCmd := &cobra.Command{
+		Use:   "artifacts",
+		Short: "List and get the artifacts collected from actions",
+	}
+	artifactsCmd.AddCommand(newArtifactsLs(uii, contextProvider))
+	artifactsCmd.AddCommand(newArtifactsGet(uii, contextProvider))
+	return artifactsCmd
+}
+
+func newArtifactsLs(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	return &cobra.Command{
+		Use:   "ls [run-id]",
+		Short: "List the collected artifacts of runs",
+		Long: `List the artifacts collected into .shuttle/artifacts from oldest to latest run
+with when they were collected, the ID of their run and their path. With a run
+ID only the artifacts of that run are listed.`,
+		Args:          cobra.MaximumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := contextProvider()
+			if err != nil {
+				return err
+			}
+			runs, err := executors.ListArtifacts(executors.ArtifactsDirectory(context.LocalShuttleDirectoryPath))
+			if err != nil {
+				return err
+			}
+			if len(args) == 1 {
+				run, ok := findArtifactRun(runs, args[0])
+				if !ok {
+					return errors.NewExitCode(2, "No artifacts found for run '%s'", args[0])
+				}
+				runs = []executors.ArtifactRun{run}
+			}
+			if len(runs) == 0 {
+				uii.Infoln("No artifacts found")
+				return nil
+			}
+			for _, run := range runs {
+				for _, file := range run.Files {
+					uii.Output("%s  %s  %s", run.CollectedAt.Local().Format(time.RFC3339), run.RunID, file)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func newArtifactsGet(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	var runID string
+
+	getCmd := &cobra.Command{
+		Use:   "get <artifact> [destination]",
+		Short: "Copy a collected artifact out of .shuttle/artifacts",
+		Long: `Copy an artifact of the latest run collecting it, or of the run given with
+--run, to destination. The artifact is its project relative path, eg.
+coverage.out, and may be a directory of artifacts, eg. dist.
+
+The destination defaults to the base name of the artifact in the working
+directory. Use - to print the artifact to stdout.`,
+		Example: `  shuttle artifacts get coverage.out
+  shuttle artifacts get dist/app build/ --run 0b6c3a0e-8b4a-4f5e-9f51-2a3f0d5c1e7b
+  shuttle artifacts get reports/junit.xml - | less`,
+		Args:          cobra.RangeArgs(1, 2),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := contextProvider()
+			if err != nil {
+				return err
+			}
+			runs, err := executors.ListArtifacts(executors.ArtifactsDirectory(context.LocalShuttleDirectoryPath))
+			if err != nil {
+				return err
+			}
+			artifact := path.Clean(filepath.ToSlash(args[0]))
+			source, ok := findArtifact(runs, runID, artifact)
+			if !ok {
+				if runID != "" {
+					return errors.NewExitCode(2, "No artifact '%s' found for run '%s'", artifact, runID)
+				}
+				return errors.NewExitCode(2, "No artifact '%s' found", artifact)
+			}
+
+			destination := path.Base(artifact)
+			if len(args) == 2 {
+				destination = args[1]
+			}
+			if destination == "-" {
+				if info, err := os.Stat(source); err == nil && info.IsDir() {
+					return errors.NewExitCode(2, "Artifact '%s' is a directory and cannot be printed: copy it to a destination instead", artifact)
+				}
+				file, err := os.Open(source)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				_, err = io.Copy(cmd.OutOrStdout(), file)
+				return err
+			}
+			if info, err := os.Stat(destination); err == nil && info.IsDir() {
+				destination = filepath.Join(destination, path.Base(artifact))
+			}
+			if err := cp.Copy(source, destination); err != nil {
+				return errors.NewExitCode(1, "Failed to copy artifact '%s' to '%s': %v", artifact, destination, err)
+			}
+			uii.Infoln("Copied artifact %s to %s", artifact, destination)
+			return nil
+		},
+	}
+
+	getCmd.Flags().StringVar(&runID, "run", "", "Get the artifact of the run with this ID instead of the latest run collecting it")
+
+	return getCmd
+}
+
+// findArtifactRun returns the run of runs with id.
+func findArtifactRun(runs []executors.ArtifactRun, id string) (executors.ArtifactRun, bool) {
+	for _, run := range runs {
+		if run.RunID == id {
+			return run, true
+		}
+	}
+	return executors.ArtifactRun{}, false
+}
+
+// findArtifact returns the path of artifact, a file or directory of artifacts,
+// of the latest of runs collecting it. Only the run with runID is searched if
+// it is set.
+func findArtifact(runs []executors.ArtifactRun, runID, artifact string) (string, bool) {
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		if runID != "" && run.RunID != runID {
+			continue
+		}
+		for _, file := range run.Files {
+			if file == artifact || strings.HasPrefix(file, artifact+"/") {
+				return filepath.Join(run.Path, filepath.FromSlash(artifact)), true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifacts(t *testing.T) {
+	t.Setenv("SHUTTLE_ARTIFACTS_UPLOAD", "")
+	project := t.TempDir()
+	destination := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(project, "shuttle.yaml"), []byte(`plan: false
+scripts:
+  build:
+    actions:
+    - shell: mkdir -p dist; echo app > dist/app; echo cover > coverage.out
+      artifacts:
+      - coverage.out
+      - dist/*
+`), 0o644))
+
+	executeTestContainsCases(t, []testCase{
+		{
+			name:      "no artifacts",
+			input:     args("-p", project, "artifacts", "ls"),
+			erroutput: "No artifacts found",
+		},
+		{
+			name:      "collect",
+			input:     args("-p", project, "run", "build"),
+			erroutput: "Collected 2 artifacts of action 0 into ",
+		},
+	})
+
+	executeTestCasesWithCustomAssertion(t, []testCase{
+		{
+			name:  "list",
+			input: args("-p", project, "artifacts", "ls"),
+		},
+	}, func(t *testing.T, tc testCase, stdout, stderr string) {
+		assert.Regexp(t, `(?m)^\S+  [0-9a-f-]{36}  coverage.out\n\S+  [0-9a-f-]{36}  dist/app\n$`, stdout)
+	})
+
+	executeTestContainsCases(t, []testCase{
+		{
+			name:      "get to stdout",
+			input:     args("-p", project, "artifacts", "get", "coverage.out", "-"),
+			stdoutput: "cover\n",
+		},
+		{
+			name:      "get directory",
+			input:     args("-p", project, "artifacts", "get", "dist", destination),
+			erroutput: "Copied artifact dist to " + filepath.Join(destination, "dist"),
+		},
+		{
+			name:  "get directory to stdout",
+			input: args("-p", project, "artifacts", "get", "dist", "-"),
+			err:   errors.New("exit code 2 - Artifact 'dist' is a directory and cannot be printed: copy it to a destination instead"),
+		},
+		{
+			name:  "get missing artifact",
+			input: args("-p", project, "artifacts", "get", "missing.txt"),
+			err:   errors.New("exit code 2 - No artifact 'missing.txt' found"),
+		},
+		{
+			name:  "get of unknown run",
+			input: args("-p", project, "artifacts", "get", "coverage.out", "--run", "unknown"),
+			err:   errors.New("exit code 2 - No artifact 'coverage.out' found for run 'unknown'"),
+		},
+		{
+			name:  "list unknown run",
+			input: args("-p", project, "artifacts", "ls", "unknown"),
+			err:   errors.New("exit code 2 - No artifacts found for run 'unknown'"),
+		},
+	})
+	content, err := os.ReadFile(filepath.Join(destination, "dist", "app"))
+	require.NoError(t, err)
+	assert.Equal(t, "app\n", string(content))
+}
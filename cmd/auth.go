@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/lunarway/shuttle/pkg/credentials"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func newAuth(uii *ui.UI) *cobra.Command {
+	authCmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Manage credentials of private plan repositories and registries",
+		Long: `Manage credentials of private plan repositories and registries.
+
+Git and OCI plans are fetched with the credentials of their host found in
+order in the OS keychain as stored by shuttle auth login, a GitHub App
+configured with SHUTTLE_GITHUB_APP_ID, netrc, the docker configuration and
+GH_TOKEN or GITHUB_TOKEN for GitHub. SHUTTLE_GIT_TOKEN and SHUTTLE_OCI_USERNAME
+take precedence over all of them.`,
+	}
+
+	authCmd.AddCommand(
+		newAuthLogin(uii),
+		newAuthLogout(uii),
+		newAuthStatus(),
+	)
+
+	return authCmd
+}
+
+func newAuthLogin(uii *ui.UI) *cobra.Command {
+	var (
+		withToken bool
+		username  string
+	)
+
+	loginCmd := &cobra.Command{
+		Use:   "login <host>",
+		Short: "Store a token of a host in the OS keychain",
+		Long: `Store a token of a host, eg. github.com or ghcr.io, in the OS keychain.
+
+The token is prompted for or read from stdin with --with-token. It is used as
+the password of --username which most git servers ignore for tokens.`,
+		Example: `  shuttle auth login github.com
+  echo "$TOKEN" | shuttle auth login ghcr.io --with-token --username bot`,
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			host := credentials.Host(args[0])
+			var token string
+			switch {
+			case withToken:
+				content, err := io.ReadAll(cmd.InOrStdin())
+				if err != nil {
+					return errors.NewExitCode(2, "Failed to read token from stdin: %v", err)
+				}
+				token = strings.TrimSpace(string(content))
+			case stdinIsTerminal():
+				uii.Flush()
+				err := survey.AskOne(&survey.Password{Message: fmt.Sprintf("Token of %s", host)}, &token)
+				if err != nil {
+					return err
+				}
+			default:
+				return errors.NewExitCode(2, "No terminal to prompt for the token: pass it on stdin with --with-token")
+			}
+			if token == "" {
+				return errors.NewExitCode(2, "Token of %s is empty", host)
+			}
+
+			err := credentials.Store(host, credentials.Credential{Username: username, Password: token})
+			if err != nil {
+				return errors.NewExitCode(1, "Failed to log in to %s: %v", host, err)
+			}
+			uii.Infoln("Stored credentials of %s in the keychain", host)
+			return nil
+		},
+	}
+
+	loginCmd.Flags().BoolVar(&withToken, "with-token", false, "Read the token from stdin")
+	loginCmd.Flags().StringVar(&username, "username", "", "Username the token is used with, eg. for registries requiring one")
+
+	return loginCmd
+}
+
+func newAuthLogout(uii *ui.UI) *cobra.Command {
+	return &cobra.Command{
+		Use:           "logout <host>",
+		Short:         "Remove the token of a host from the OS keychain",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			host := credentials.Host(args[0])
+			err := credentials.Remove(host)
+			if stderrors.Is(err, credentials.ErrNotStored) {
+				return errors.NewExitCode(1, "No credentials of %s are stored in the keychain", host)
+			}
+			if err != nil {
+				return errors.NewExitCode(1, "Failed to log out of %s: %v", host, err)
+			}
+			uii.Infoln("Removed credentials of %s from the keychain", host)
+			return nil
+		},
+	}
+}
+
+func newAuthStatus() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status <host>",
+		Short: "Show where the credentials of a host are found",
+		Long: `Show where the credentials of a host are found without printing them.
+
+The exit code is 1 if no credentials are found for the host.`,
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			host := credentials.Host(args[0])
+			credential, ok, err := credentials.Lookup(host)
+			if err != nil {
+				return errors.NewExitCode(1, "Failed to find credentials of %s: %v", host, err)
+			}
+			if !ok {
+				return errors.NewExitCode(1, "No credentials found for %s", host)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s: %s as %s\n", host, credential.Source, credential.Username)
+			return nil
+		},
+	}
+}
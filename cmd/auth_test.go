@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestAuth(t *testing.T) {
+	keyring.MockInit()
+	t.Setenv("NETRC", filepath.Join(t.TempDir(), "netrc"))
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	t.Setenv("SHUTTLE_GITHUB_APP_ID", "")
+
+	stderr := &bytes.Buffer{}
+	loginCmd := newAuth(ui.Create(&bytes.Buffer{}, stderr))
+	loginCmd.SetIn(strings.NewReader("secret-token\n"))
+	loginCmd.SetArgs([]string{"login", "https://git.example.com", "--with-token"})
+	require.NoError(t, loginCmd.Execute())
+	assert.Equal(t, "Stored credentials of git.example.com in the keychain\n", stderr.String())
+
+	testCases := []testCase{
+		{
+			name:      "status",
+			input:     args("auth", "status", "git.example.com"),
+			stdoutput: "git.example.com: keychain as x-access-token\n",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "status without credentials",
+			input:     args("auth", "status", "unknown.example.com"),
+			stdoutput: "",
+			erroutput: "",
+			err:       errors.New("exit code 1 - No credentials found for unknown.example.com"),
+		},
+		{
+			name:      "logout",
+			input:     args("auth", "logout", "git.example.com"),
+			stdoutput: "",
+			erroutput: "Removed credentials of git.example.com from the keychain\n",
+			err:       nil,
+		},
+		{
+			name:      "logout without credentials",
+			input:     args("auth", "logout", "git.example.com"),
+			stdoutput: "",
+			erroutput: "",
+			err:       errors.New("exit code 1 - No credentials of git.example.com are stored in the keychain"),
+		},
+		{
+			name:      "login without token",
+			input:     args("auth", "login", "git.example.com", "--with-token"),
+			stdoutput: "",
+			erroutput: "",
+			err:       errors.New("exit code 2 - Token of git.example.com is empty"),
+		},
+	}
+	executeTestCases(t, testCases)
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/executors"
+	"github.com/lunarway/shuttle/pkg/executors/golang/compile/matcher"
+	"github.com/lunarway/shuttle/pkg/executors/golang/discover"
+	"github.com/lunarway/shuttle/pkg/executors/golang/shuttlefolder"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func newCache(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage files cached by shuttle",
+	}
+	cacheCmd.AddCommand(newCacheClean(uii, contextProvider))
+	cacheCmd.AddCommand(newCacheClear(uii, contextProvider))
+	return cacheCmd
+}
+
+func newCacheClear(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear",
+		Short: "Remove the cached results of scripts with inputs",
+		Long: `Remove the cached results of scripts with inputs such that they are run again
+the next time even if their inputs are unchanged.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := contextProvider()
+			if err != nil {
+				return err
+			}
+			dir := executors.CacheDirectory(context.LocalShuttleDirectoryPath)
+			if err := os.RemoveAll(dir); err != nil {
+				return fmt.Errorf("remove script cache: %w", err)
+			}
+			uii.Output("Cleared script cache %s", relativePath(context.ProjectPath, dir))
+			return nil
+		},
+	}
+}
+
+func newCacheClean(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	var (
+		dryRun    bool
+		olderThan time.Duration
+	)
+
+	cleanCmd := &cobra.Command{
+		Use:   "clean",
+		Short: "Remove compiled golang action binaries that are no longer used",
+		Long: `Remove compiled golang action binaries of the project and its plan that do
+not match the current sources of the golang actions.
+
+All binaries are listed with their size. Binaries of the current sources are
+never removed whatever platform they are compiled for.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if olderThan < 0 {
+				return errors.NewExitCode(2, "--older-than must not be negative but was %s", olderThan)
+			}
+			context, err := contextProvider()
+			if err != nil {
+				return err
+			}
+			return cleanBinaries(cmd, uii, context, dryRun, olderThan, time.Now())
+		},
+	}
+
+	cleanCmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the binaries that would be removed without removing them")
+	cleanCmd.Flags().DurationVar(&olderThan, "older-than", 0, "Only remove binaries last used longer ago than this, eg. 168h")
+
+	return cleanCmd
+}
+
+// binaryDirectory is a shuttle directory with golang action binaries and the
+// hash of the current sources if any
+type binaryDirectory struct {
+	shuttledir string
+	hash       string
+}
+
+// cleanBinaries removes the stale golang action binaries of the project and
+// its plan.
+func cleanBinaries(
+	cmd *cobra.Command,
+	uii *ui.UI,
+	context config.ShuttleProjectContext,
+	dryRun bool,
+	olderThan time.Duration,
+	now time.Time,
+) error {
+	directories, err := binaryDirectories(cmd, context)
+	if err != nil {
+		return err
+	}
+
+	var total, removed, freed int64
+	count := 0
+	for _, directory := range directories {
+		binaries, err := shuttlefolder.Binaries(directory.shuttledir)
+		if err != nil {
+			return fmt.Errorf("list golang action binaries: %w", err)
+		}
+		stale := map[string]bool{}
+		for _, binary := range shuttlefolder.StaleBinaries(binaries, directory.hash, olderThan, now) {
+			stale[binary.Path] = true
+		}
+		for _, binary := range binaries {
+			count++
+			total += binary.Size
+			status := "kept"
+			switch {
+			case directory.hash != "" && shuttlefolder.IsBinaryOf(path.Base(binary.Path), directory.hash):
+				status = "current"
+			case stale[binary.Path] && dryRun:
+				status = "would be removed"
+			case stale[binary.Path]:
+				if err := os.Remove(binary.Path); err != nil {
+					return fmt.Errorf("remove golang action binary: %w", err)
+				}
+				status = "removed"
+			}
+			if stale[binary.Path] {
+				removed++
+				freed += binary.Size
+			}
+			uii.Output("%s  %s  %s", relativePath(context.ProjectPath, binary.Path), formatSize(binary.Size), status)
+		}
+	}
+
+	verb := "Removed"
+	if dryRun {
+		verb = "Would remove"
+	}
+	uii.Output("%s %d of %d binaries freeing %s of %s", verb, removed, count, formatSize(freed), formatSize(total))
+	return nil
+}
+
+// binaryDirectories returns the shuttle directories of the project and the plan
+// with the hashes of their current golang action sources. The
+// directories are returned even without golang actions as binaries of removed
+// actions are stale.
+func binaryDirectories(cmd *cobra.Command, context config.ShuttleProjectContext) ([]binaryDirectory, error) {
+	discovered, err := discover.Discover(cmd.Context(), path.Join(context.ProjectPath, "shuttle.yaml"), &context)
+	if err != nil {
+		return nil, err
+	}
+
+	parents := []string{context.ProjectPath}
+	actions := []*discover.ActionsDiscovered{discovered.Local}
+	if context.Config.Plan != "" {
+		parents = append(parents, path.Join(context.ProjectPath, ".shuttle/plan"))
+		actions = append(actions, discovered.Plan)
+	}
+
+	directories := make([]binaryDirectory, len(parents))
+	for i, parent := range parents {
+		directories[i].shuttledir = path.Join(parent, ".shuttle/actions")
+		if actions[i] == nil {
+			continue
+		}
+		hash, err := matcher.GetHash(cmd.Context(), actions[i])
+		if err != nil {
+			return nil, fmt.Errorf("hash golang actions: %w", err)
+		}
+		directories[i].hash = hash
+	}
+	return directories, nil
+}
+
+func relativePath(base, target string) string {
+	relative, err := filepath.Rel(base, target)
+	if err != nil {
+		return target
+	}
+	return relative
+}
+
+// formatSize formats size in bytes with a binary unit, eg. 1.5 MB.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size) / unit
+	units := []string{"KB", "MB", "GB", "TB"}
+	i := 0
+	for value >= unit && i < len(units)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", value, units[i])
+}
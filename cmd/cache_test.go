@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheClean(t *testing.T) {
+	testCases := []testCase{
+		{
+			name:      "no binaries",
+			input:     args("-p", "testdata/project", "cache", "clean", "--dry-run"),
+			stdoutput: "Would remove 0 of 0 binaries freeing 0 B of 0 B\n",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "negative older than",
+			input:     args("-p", "testdata/project", "cache", "clean", "--older-than", "-1h"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - --older-than must not be negative but was -1h0m0s\n",
+			err:       errors.New("exit code 2 - --older-than must not be negative but was -1h0m0s"),
+		},
+	}
+	executeTestCases(t, testCases)
+}
+
+func TestCacheClear(t *testing.T) {
+	testCases := []testCase{
+		{
+			name:      "no cache",
+			input:     args("-p", "testdata/project", "cache", "clear"),
+			stdoutput: "Cleared script cache .shuttle/cache\n",
+			erroutput: "",
+			err:       nil,
+		},
+	}
+	executeTestCases(t, testCases)
+}
+
+func TestFormatSize(t *testing.T) {
+	tt := []struct {
+		size   int64
+		output string
+	}{
+		{size: 0, output: "0 B"},
+		{size: 1023, output: "1023 B"},
+		{size: 1536, output: "1.5 KB"},
+		{size: 5 * 1024 * 1024, output: "5.0 MB"},
+		{size: 3 * 1024 * 1024 * 1024, output: "3.0 GB"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.output, func(t *testing.T) {
+			assert.Equal(t, tc.output, formatSize(tc.size))
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	stderrors "errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lunarway/shuttle/pkg/changes"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func newChanged(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	var (
+		base  string
+		quiet bool
+	)
+
+	changedCmd := &cobra.Command{
+		Use:   "changed <pathspec>...",
+		Short: "Check if files of the project changed compared to a base ref",
+		Long: `Check if files of the project matching any of the git pathspecs changed
+compared to a base ref.
+
+Changes are compared to the merge base of HEAD and the base such that commits
+on the base not in HEAD are not changes. Uncommitted and untracked files are
+changes as well. The base is SHUTTLE_CHANGED_BASE if set or else the default
+branch of origin, eg. origin/main, and main for repositories without origin.
+Shallow clones, eg. in CI, are fetched and deepened as needed to find the
+merge base.
+
+The changed files are printed and the exit code is 1 if no files changed.`,
+		Example: `  shuttle changed services/api
+  shuttle changed --base origin/develop '*.go' go.mod`,
+		Args:          cobra.MinimumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uii.SetContext(ui.LevelSilent)
+
+			context, err := contextProvider()
+			if err != nil {
+				return err
+			}
+			if !cmd.Flags().Changed("base") {
+				base = changes.Base(context.ProjectPath)
+			}
+
+			files, err := changes.Files(context.ProjectPath, base, args...)
+			if stderrors.Is(err, changes.ErrNotRepository) {
+				return errors.NewExitCode(2, "Project %s is not within a git repository", context.ProjectPath)
+			}
+			if err != nil {
+				return errors.NewExitCode(2, "Failed to detect changes: %v", err)
+			}
+			if len(files) == 0 {
+				return errors.NewExitCode(1, "")
+			}
+			if !quiet {
+				for _, file := range files {
+					fmt.Fprintln(cmd.OutOrStdout(), file)
+				}
+			}
+			return nil
+		},
+	}
+
+	changedCmd.Flags().
+		StringVar(&base, "base", "", "Ref to compare to instead of SHUTTLE_CHANGED_BASE or the default branch, eg. origin/develop")
+	changedCmd.Flags().
+		BoolVarP(&quiet, "quiet", "q", false, "Only report changes with the exit code")
+
+	return changedCmd
+}
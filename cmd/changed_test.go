@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChanged(t *testing.T) {
+	t.Setenv("SHUTTLE_CHANGED_BASE", "")
+	project := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(project, "shuttle.yaml"), []byte("plan: false\n"), 0o644))
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch=main"},
+		{"add", "."},
+		{"-c", "user.name=shuttle", "-c", "user.email=shuttle@example.com", "commit", "--quiet", "-m", "initial"},
+		{"checkout", "--quiet", "-b", "feature"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = project
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(project, "services", "api"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(project, "services", "api", "main.go"), []byte("package main"), 0o644))
+
+	testCases := []testCase{
+		{
+			name:      "changed",
+			input:     args("-p", project, "changed", "services/api"),
+			stdoutput: "services/api/main.go\n",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "quiet",
+			input:     args("-p", project, "changed", "-q", "services"),
+			stdoutput: "",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "not changed",
+			input:     args("-p", project, "changed", "services/web", "*.md"),
+			stdoutput: "",
+			erroutput: "",
+			err:       errors.New("exit code 1 - "),
+		},
+		{
+			name:      "unknown base",
+			input:     args("-p", project, "changed", "--base", "develop", "services"),
+			stdoutput: "",
+			erroutput: "",
+			err:       errors.New("exit code 2 - Failed to detect changes: no common commit of HEAD and 'develop': fatal: Not a valid object name develop"),
+		},
+		{
+			name:      "no pathspec",
+			input:     args("-p", project, "changed"),
+			stdoutput: "",
+			erroutput: "",
+			err:       errors.New("requires at least 1 arg(s), only received 0"),
+		},
+	}
+	executeTestCases(t, testCases)
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/executors/golang/compile"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func newClean(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	var (
+		binaries  bool
+		dryRun    bool
+		olderThan time.Duration
+	)
+
+	cleanCmd := &cobra.Command{
+		Use:   "clean",
+		Short: "Remove files of shuttle that are no longer used",
+		Long: `Remove files of shuttle that are no longer used.
+
+With --binaries the compiled golang action binaries of the project and its
+plan not used for SHUTTLE_GOLANG_ACTIONS_BINARY_MAX_AGE, by default 720h, are
+removed like when compiling the actions. Binaries of the current sources are
+never removed.`,
+		Example:      `  shuttle clean --binaries --older-than 168h`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !binaries {
+				return errors.NewExitCode(2, "Nothing to clean: pass --binaries to remove unused golang action binaries")
+			}
+			if olderThan < 0 {
+				return errors.NewExitCode(2, "--older-than must not be negative but was %s", olderThan)
+			}
+			if !cmd.Flags().Changed("older-than") {
+				maxAge, err := compile.BinaryMaxAge()
+				if err != nil {
+					return errors.NewExitCode(2, "%v", err)
+				}
+				olderThan = maxAge
+			}
+			context, err := contextProvider()
+			if err != nil {
+				return err
+			}
+			return cleanBinaries(cmd, uii, context, dryRun, olderThan, time.Now())
+		},
+	}
+
+	cleanCmd.Flags().BoolVar(&binaries, "binaries", false, "Remove golang action binaries not used recently")
+	cleanCmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the binaries that would be removed without removing them")
+	cleanCmd.Flags().DurationVar(&olderThan, "older-than", 0, "Remove binaries not used for this long instead of SHUTTLE_GOLANG_ACTIONS_BINARY_MAX_AGE, eg. 168h")
+
+	return cleanCmd
+}
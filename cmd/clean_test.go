@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	testCases := []testCase{
+		{
+			name:      "nothing to clean",
+			input:     args("-p", "testdata/project", "clean"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - Nothing to clean: pass --binaries to remove unused golang action binaries\n",
+			err:       errors.New("exit code 2 - Nothing to clean: pass --binaries to remove unused golang action binaries"),
+		},
+		{
+			name:      "no binaries",
+			input:     args("-p", "testdata/project", "clean", "--binaries", "--dry-run"),
+			stdoutput: "Would remove 0 of 0 binaries freeing 0 B of 0 B\n",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "negative older than",
+			input:     args("-p", "testdata/project", "clean", "--binaries", "--older-than", "-1h"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - --older-than must not be negative but was -1h0m0s\n",
+			err:       errors.New("exit code 2 - --older-than must not be negative but was -1h0m0s"),
+		},
+	}
+	executeTestCases(t, testCases)
+}
@@ -8,10 +8,13 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
 	"github.com/lunarway/shuttle/pkg/config"
+	shuttleerrors "github.com/lunarway/shuttle/pkg/errors"
 	"github.com/lunarway/shuttle/pkg/executors/golang/executer"
 	"github.com/lunarway/shuttle/pkg/telemetry"
 	"github.com/lunarway/shuttle/pkg/ui"
@@ -22,55 +25,6 @@ var (
 	commit  = "<unspecified-commit>"
 )
 
-const rootCmdCompletion = `
-__shuttle_run_script_args() {
-	local cur prev args_output args
-	COMPREPLY=()
-	cur="${COMP_WORDS[COMP_CWORD]}"
-	prev="${COMP_WORDS[COMP_CWORD-1]}"
-
-	template=$'{{ range $i, $arg := .Args }}{{ $arg.Name }}\n{{ end }}'
-	if args_output=$(shuttle --skip-pull run "$1" --help --template "$template" 2>/dev/null); then
-		args=($(echo "${args_output}"))
-		COMPREPLY=( $( compgen -W "${args[*]}" -- "$cur" ) )
-		compopt -o nospace
-	fi
-}
-
-# find available scripts to run
-__shuttle_run_scripts() {
-	local cur prev scripts currentScript
-	COMPREPLY=()
-	cur="${COMP_WORDS[COMP_CWORD]}"
-	prev="${COMP_WORDS[COMP_CWORD-1]}"
-	currentScript="${COMP_WORDS[2]}"
-
-	if [[ ! "${prev}" = "run" ]]; then
-		__shuttle_run_script_args $currentScript
-		return 0
-	fi
-
-	template=$'{{ range $name, $script := .Scripts }}{{ $name }}\n{{ end }}'
-	if scripts_output=$(shuttle --skip-pull ls --template "$template" 2>/dev/null); then
-		scripts=($(echo "${scripts_output}"))
-		COMPREPLY=( $( compgen -W "${scripts[*]}" -- "$cur" ) )
-	fi
-	return 0
-}
-
-# called when the build in completion fails to match
-__shuttle_custom_func() {
-  case ${last_command} in
-      shuttle_run)
-          __shuttle_run_scripts
-          return
-          ;;
-      *)
-          ;;
-  esac
-}
-`
-
 func newRoot(uii *ui.UI) (*cobra.Command, contextProvider, repositoryContext) {
 	telemetry.Setup()
 
@@ -79,7 +33,14 @@ func newRoot(uii *ui.UI) (*cobra.Command, contextProvider, repositoryContext) {
 		projectPath        string
 		clean              bool
 		skipGitPlanPulling bool
+		refreshPlans       bool
+		offline            bool
+		updatePlan         bool
+		insecureSkipVerify bool
 		plan               string
+		planDir            string
+		profile            string
+		outputFlag         string
 	)
 
 	rootCmd := &cobra.Command{
@@ -91,7 +52,12 @@ A CLI for handling shared build and deploy tools between many
 projects no matter what technologies the project is using.
 
 Read more about shuttle at https://github.com/lunarway/shuttle`, version),
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			format, err := ui.ParseFormat(outputFlag)
+			if err != nil {
+				return shuttleerrors.NewExitCode(2, "Invalid --output: %v", err)
+			}
+			uii.SetFormat(format)
 			if verboseFlag {
 				uii.SetUserLevel(ui.LevelVerbose)
 			}
@@ -99,23 +65,52 @@ Read more about shuttle at https://github.com/lunarway/shuttle`, version),
 			uii.Verboseln("- version: %s", version)
 			uii.Verboseln("- commit: %s", commit)
 			uii.Verboseln("- project-path: %s", projectPath)
+			return nil
 		},
-		BashCompletionFunction: rootCmdCompletion,
 	}
 
 	rootCmd.PersistentFlags().StringVarP(&projectPath, "project", "p", ".", "Project path")
 	rootCmd.PersistentFlags().BoolVarP(&clean, "clean", "c", false, "Start from clean setup")
 	rootCmd.PersistentFlags().
 		BoolVar(&skipGitPlanPulling, "skip-pull", false, "Skip git plan pulling step")
+	rootCmd.PersistentFlags().
+		BoolVar(&refreshPlans, "refresh-plans", false, "Fetch git plans even if a cached plan is still valid")
+	rootCmd.PersistentFlags().
+		BoolVar(&offline, "offline", false, "Use the git plans last checked out without fetching them. Defaults to SHUTTLE_OFFLINE")
+	rootCmd.PersistentFlags().
+		BoolVar(&updatePlan, "update-plan", false, "Use the latest revision of the plan instead of the one locked in shuttle.lock")
+	rootCmd.PersistentFlags().
+		BoolVar(&insecureSkipVerify, "insecure-skip-verify", false, "Use the plan even if it is not signed by the trusted plan public key")
 	rootCmd.PersistentFlags().StringVar(&plan, "plan", "", `Overload the plan used.
 Specifying a local path with either an absolute path (/some/plan) or a relative path (../some/plan) to another location
 for the selected plan.
 Select a version of a git plan by using #branch, #sha or #tag
 If none of above is used, then the argument will expect a full plan spec.`)
+	rootCmd.PersistentFlags().StringVar(&planDir, "plan-dir", "", `Use a subdirectory of the project as the plan.
+The directory is relative to the project path, eg. --plan-dir plan, and cannot be combined with --plan.`)
+	rootCmd.PersistentFlags().
+		StringVar(&profile, "profile", os.Getenv("SHUTTLE_PROFILE"), "Profile of shuttle.yaml to use, eg. prod. Defaults to SHUTTLE_PROFILE")
 	rootCmd.PersistentFlags().BoolVarP(&verboseFlag, "verbose", "v", false, "Print verbose output")
+	rootCmd.PersistentFlags().
+		StringVar(&outputFlag, "output", string(ui.FormatText), `Output format, either text, json, prefixed or plain.
+json writes one JSON object per line. prefixed prefixes every output line of actions with the action, eg. [build/0],
+coloured per action on terminals, and plain never prefixes them, not even for parallel actions`)
 
 	ctxProvider := func() (config.ShuttleProjectContext, error) {
-		return getProjectContext(rootCmd, uii, projectPath, clean, plan, skipGitPlanPulling)
+		return getProjectContext(
+			rootCmd,
+			uii,
+			projectPath,
+			clean,
+			plan,
+			planDir,
+			skipGitPlanPulling,
+			refreshPlans,
+			offline,
+			updatePlan,
+			insecureSkipVerify,
+			profile,
+		)
 	}
 
 	repositoryCtxProvider := func() bool {
@@ -136,6 +131,9 @@ func Execute(stdout, stderr io.Writer) {
 		return
 	}
 
+	enableOutputFrames(uii, stdout)
+	defer uii.Flush()
+
 	if err := rootCmd.Execute(); err != nil {
 		telemetry.TraceError(
 			stdcontext.Background(),
@@ -147,34 +145,135 @@ func Execute(stdout, stderr io.Writer) {
 	}
 }
 
+// defaultOutputFrameInterval is the interval output is flushed at when
+// writing to a terminal
+const defaultOutputFrameInterval = 50 * time.Millisecond
+
+// enableOutputFrames coalesces output to reduce flicker when stdout is an
+// interactive terminal and output is written as text. Other formats are read
+// by tools as it is written. The interval can be changed or disabled with
+// SHUTTLE_OUTPUT_FRAME_INTERVAL.
+func enableOutputFrames(uii *ui.UI, stdout io.Writer) {
+	if uii.Format() != ui.FormatText || !isTerminal(stdout) {
+		return
+	}
+
+	interval := defaultOutputFrameInterval
+	if raw := os.Getenv("SHUTTLE_OUTPUT_FRAME_INTERVAL"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			uii.Errorln("Ignoring invalid SHUTTLE_OUTPUT_FRAME_INTERVAL '%s': %v", raw, err)
+			return
+		}
+		interval = parsed
+	}
+	if interval <= 0 {
+		return
+	}
+	uii.SetFrameInterval(interval)
+}
+
+// isTerminal returns true if w is an interactive terminal.
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(file.Fd()))
+}
+
+// outputLineDecorator returns the decorator of output lines of actions for
+// format or nil if it is up to the actions. Prefixes are coloured when stdout
+// is a terminal unless NO_COLOR is set.
+func outputLineDecorator(format ui.Format, stdout io.Writer) ui.LineDecorator {
+	switch format {
+	case ui.FormatPrefixed:
+		return ui.NewPrefixedLines(isTerminal(stdout) && os.Getenv("NO_COLOR") == "")
+	case ui.FormatPlain:
+		return ui.PlainLines{}
+	default:
+		return nil
+	}
+}
+
 func initializedRootFromArgs(stdout, stderr io.Writer, args []string) (*cobra.Command, *ui.UI, error) {
 	uii := ui.Create(stdout, stderr)
 
 	rootCmd, ctxProvider, isInRepoContext := newRoot(uii)
 	rootCmd.SetOut(stdout)
 	rootCmd.SetErr(stderr)
+	// the plan is resolved before the command is run so the context ID is
+	// set up front for its spans to be part of the trace of the command, and
+	// its timing part of timing reports
+	ctx, _ := telemetry.WithTimings(telemetry.WithContextID(stdcontext.Background()))
+	rootCmd.SetContext(ctx)
 
 	// Parses falgs early such that we can build PersistentFlags on rootCmd used
 	// for building various subcommands in both run and ls. This is required otherwise
 	// Run and LS will not get closured variables from contextProvider
 	rootCmd.ParseFlags(args)
 
+	// select the output format early as output is written while resolving the
+	// project context. Invalid values are reported once the command runs.
+	outputFlag, _ := rootCmd.PersistentFlags().GetString("output")
+	if format, err := ui.ParseFormat(outputFlag); err == nil {
+		uii.SetFormat(format)
+		uii.SetLineDecorator(outputLineDecorator(format, stdout))
+	}
+
+	// completions use the plan already fetched to the project to not wait for
+	// the network on every <TAB>
+	if isCompletionRequest(args) {
+		rootCmd.PersistentFlags().Set("skip-pull", "true")
+		uii.SetContext(ui.LevelSilent)
+	}
+
+	// plan diff and upgrade compare the checked out revision of the plan to
+	// another one so it must not be pulled first
+	if isPlanRevisionRequest(rootCmd) {
+		rootCmd.PersistentFlags().Set("skip-pull", "true")
+	}
+
 	if isInRepoContext() {
 		runCmd, err := newRun(uii, ctxProvider)
 		if err != nil {
-			return nil, nil, err
+			// doctor diagnoses why the project cannot be loaded and
+			// self-update may be what makes it load, eg. as the plan requires
+			// a newer shuttle
+			switch {
+			case isDoctorRequest(rootCmd):
+				rootCmd.AddCommand(newDoctor(uii, ctxProvider), newVersion(uii))
+			case isSelfUpdateRequest(rootCmd):
+				rootCmd.AddCommand(newSelfUpdate(uii), newVersion(uii))
+			default:
+				return nil, nil, err
+			}
+			return rootCmd, uii, nil
 		}
 		rootCmd.AddCommand(
+			newArtifacts(uii, ctxProvider),
+			newAuth(uii),
+			newCache(uii, ctxProvider),
+			newChanged(uii, ctxProvider),
+			newClean(uii, ctxProvider),
 			newDocumentation(uii, ctxProvider),
+			newDoctor(uii, ctxProvider),
 			newCompletion(uii),
 			newGet(uii, ctxProvider),
 			newGitPlan(uii, ctxProvider),
+			newGolang(uii),
 			newHas(uii, ctxProvider),
+			newHistory(uii, ctxProvider),
+			newInit(uii, ctxProvider),
+			newLock(uii, ctxProvider),
 			newLs(uii, ctxProvider),
+			newLogs(uii, ctxProvider),
 			newPlan(uii, ctxProvider),
 			runCmd,
 			newPrepare(uii, ctxProvider),
+			newServe(uii, ctxProvider),
+			newReport(uii, ctxProvider),
+			newSchema(uii),
+			newSelfUpdate(uii),
 			newTemplate(uii, ctxProvider),
+			newValidate(uii, ctxProvider),
 			newVersion(uii),
 			newConfig(uii, ctxProvider),
 			newTelemetry(uii),
@@ -183,11 +282,17 @@ func initializedRootFromArgs(stdout, stderr io.Writer, args []string) (*cobra.Co
 		return rootCmd, uii, nil
 	} else {
 		rootCmd.AddCommand(
-			newNoContextRun(),
+			newAuth(uii),
+			newNoContextRun(uii),
+			newNoContextPlan(uii),
+			newGolang(uii),
 			newCompletion(uii),
+			newSchema(uii),
+			newSelfUpdate(uii),
 			newVersion(uii),
 			newTelemetry(uii),
 			newHas(uii, ctxProvider),
+			newInit(uii, ctxProvider),
 			newConfig(uii, ctxProvider),
 		)
 
@@ -208,7 +313,13 @@ func getProjectContext(
 	projectPath string,
 	clean bool,
 	plan string,
+	planDir string,
 	skipGitPlanPulling bool,
+	refreshPlans bool,
+	offline bool,
+	updatePlan bool,
+	insecureSkipVerify bool,
+	profile string,
 ) (config.ShuttleProjectContext, error) {
 	dir, err := os.Getwd()
 	if err != nil {
@@ -224,6 +335,23 @@ func getProjectContext(
 		fullProjectPath = path.Join(dir, projectPath)
 	}
 
+	if planDir != "" {
+		if plan != "" {
+			return config.ShuttleProjectContext{}, shuttleerrors.NewExitCode(
+				2,
+				"--plan and --plan-dir cannot be used together",
+			)
+		}
+		if path.IsAbs(planDir) || strings.HasPrefix(path.Clean(planDir), "..") {
+			return config.ShuttleProjectContext{}, shuttleerrors.NewExitCode(
+				2,
+				"Plan directory '%s' must be relative to and within the project",
+				planDir,
+			)
+		}
+		plan = path.Join(fullProjectPath, planDir)
+	}
+
 	if plan == "" {
 		env := os.Getenv("SHUTTLE_PLAN_OVERLOAD")
 		if env != "" {
@@ -231,51 +359,39 @@ func getProjectContext(
 		}
 	}
 
+	ctx := rootCmd.Context()
+	if ctx == nil {
+		ctx = stdcontext.Background()
+	}
+	spanAttributes := map[string]string{telemetry.TelemetryPlan: plan}
+	_, endSpan := telemetry.StartSpan(ctx, "shuttle.plan", spanAttributes)
 	var c config.ShuttleProjectContext
-	projectContext, err := c.Setup(
+	_, err = c.Setup(
 		fullProjectPath,
 		uii,
 		clean,
 		skipGitPlanPulling,
+		refreshPlans,
+		offline,
 		plan,
 		projectFlagSet,
+		updatePlan,
+		insecureSkipVerify,
+		profile,
 	)
+	if plan == "" {
+		spanAttributes[telemetry.TelemetryPlan] = c.Config.Plan
+	}
+	endSpan(err)
 	if err != nil {
 		return config.ShuttleProjectContext{}, err
 	}
 
-	ctx := stdcontext.Background()
-	taskActions, err := executer.List(
-		ctx,
-		uii,
-		fmt.Sprintf("%s/shuttle.yaml", projectContext.ProjectPath),
-		&c,
-	)
+	err = executer.AddScripts(ctx, uii, &c)
 	if err != nil {
 		return config.ShuttleProjectContext{}, err
 	}
 
-	for name, action := range taskActions.Actions {
-		args := make([]config.ShuttleScriptArgs, 0)
-
-		for _, taskArg := range action.Args {
-			args = append(args, config.ShuttleScriptArgs{
-				Name:     taskArg.Name,
-				Required: true,
-			})
-		}
-
-		c.Scripts[name] = config.ShuttlePlanScript{
-			Description: name,
-			Actions: []config.ShuttleAction{
-				{
-					Task: name,
-				},
-			},
-			Args: args,
-		}
-	}
-
 	return c, nil
 }
 
@@ -3,8 +3,10 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/lunarway/shuttle/pkg/config"
 	"github.com/lunarway/shuttle/pkg/git"
 	"github.com/lunarway/shuttle/pkg/ui"
 	"github.com/spf13/cobra"
@@ -61,9 +63,120 @@ func newConfig(uii *ui.UI, contextProvder contextProvider) *cobra.Command {
 
 	envCmd.Flags().
 		StringSliceVar(&envVarsToExclude, "exclude-env-vars", make([]string, 0), "Exclude environment variables from being displayed. Example: shuttle config --exclude-env-vars VAR1,VAR2,VAR3")
+	envCmd.AddCommand(
+		newConfigGet(uii),
+		newConfigSet(uii),
+		newConfigUnset(uii),
+	)
 	return envCmd
 }
 
+func newConfigGet(uii *ui.UI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print a value of shuttle.yaml",
+		Long: `Print a value of shuttle.yaml, eg. vars.squad. Keys are dot separated and
+elements of lists are selected by their index, eg. scripts.build.actions.0.
+Values that are not scalars are printed as YAML.`,
+		Example:      "shuttle config get vars.squad",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := openShuttleFile(cmd)
+			if err != nil {
+				return err
+			}
+			value, err := file.Get(args[0])
+			if err != nil {
+				return err
+			}
+			uii.Output("%s", value)
+			return nil
+		},
+	}
+}
+
+func newConfigSet(uii *ui.UI) *cobra.Command {
+	var asString bool
+	setCmd := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a value of shuttle.yaml",
+		Long: `Set a value of shuttle.yaml keeping its comments and the order of keys.
+Values are parsed as YAML, eg. 3 is a number and [a, b] a list, unless --string
+is set. Missing parent keys are added.`,
+		Example:      "shuttle config set vars.docker.image earth-united/moon-base",
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := openShuttleFile(cmd)
+			if err != nil {
+				return err
+			}
+			err = file.Set(args[0], args[1], asString)
+			if err != nil {
+				return err
+			}
+			err = file.Write()
+			if err != nil {
+				return err
+			}
+			uii.Verboseln("Set '%s' in %s", args[0], file.Path)
+			return nil
+		},
+	}
+	setCmd.Flags().BoolVar(&asString, "string", false, "Set the value as a string even if it is a number, boolean or list in YAML")
+	return setCmd
+}
+
+func newConfigUnset(uii *ui.UI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "unset <key>",
+		Short: "Remove a value of shuttle.yaml",
+		Long: `Remove a value of shuttle.yaml keeping its comments and the order of keys.
+Keys that are not set are ignored.`,
+		Example:      "shuttle config unset vars.docker.image",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := openShuttleFile(cmd)
+			if err != nil {
+				return err
+			}
+			removed, err := file.Unset(args[0])
+			if err != nil {
+				return err
+			}
+			if !removed {
+				uii.Verboseln("'%s' is not set in %s", args[0], file.Path)
+				return nil
+			}
+			err = file.Write()
+			if err != nil {
+				return err
+			}
+			uii.Verboseln("Removed '%s' from %s", args[0], file.Path)
+			return nil
+		},
+	}
+}
+
+// openShuttleFile opens the shuttle.yaml of the project of --project without
+// resolving its plan such that it can be edited even if it is invalid.
+func openShuttleFile(cmd *cobra.Command) (*config.ShuttleFile, error) {
+	projectPath, err := cmd.Flags().GetString("project")
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(projectPath) {
+		dir, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		projectPath = filepath.Join(dir, projectPath)
+	}
+	return config.OpenShuttleFile(projectPath, cmd.Flags().Changed("project"))
+}
+
 func breakLine(cmd *cobra.Command) {
 	fmt.Fprintln(cmd.OutOrStdout(), "")
 }
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -56,3 +57,30 @@ func TestConfig(t *testing.T) {
 		},
 	)
 }
+
+func TestConfigGet(t *testing.T) {
+	testCases := []testCase{
+		{
+			name:      "scalar",
+			input:     args("-p", "testdata/project", "config", "get", "vars.service"),
+			stdoutput: "shuttle\n",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "mapping",
+			input:     args("-p", "testdata/project", "config", "get", "vars.nested.sub"),
+			stdoutput: "field: baz\n",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "missing key",
+			input:     args("-p", "testdata/project", "config", "get", "vars.missing"),
+			stdoutput: "",
+			erroutput: "Error: exit code 1 - Key 'vars.missing' is not set in shuttle.yaml\n",
+			err:       errors.New("exit code 1 - Key 'vars.missing' is not set in shuttle.yaml"),
+		},
+	}
+	executeTestCases(t, testCases)
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	stderrors "errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/executors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func newDoctor(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose the environment scripts of the project are run in",
+		Long: `Diagnose the environment scripts of the project are run in.
+
+The shell running shell actions is checked along with Git Bash and cygpath on
+Windows, git, go if the project has golang actions, docker if actions run in
+containers and the tools actions require. Git plans are checked for being
+reachable and the .shuttle directory for being writable.
+
+Every check is listed with what was found and how to fix warnings and
+failures. shuttle exits with code 1 if any check fails.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var diagnoses []executors.Diagnosis
+			context, err := contextProvider()
+			if err != nil {
+				// the project is diagnosed as far as shuttle.yaml goes to tell
+				// why it cannot be loaded
+				diagnoses = append(diagnoses, executors.Diagnosis{
+					Check:       "project",
+					Status:      executors.DiagnosisFail,
+					Detail:      errorMessage(err),
+					Remediation: "Fix the problem and see how the project and its plan are loaded with shuttle ls -v",
+				})
+				context = doctorFallbackContext(cmd)
+			}
+			diagnoses = append(diagnoses, executors.Diagnose(cmd.Context(), context)...)
+
+			failed := printDiagnoses(uii, diagnoses)
+			if failed > 0 {
+				return errors.NewExitCode(1, "%d of %d checks failed", failed, len(diagnoses))
+			}
+			uii.Infoln("All checks passed")
+			return nil
+		},
+	}
+
+	return doctorCmd
+}
+
+// doctorFallbackContext returns the project context as far as it can be read
+// from shuttle.yaml without fetching its plan.
+func doctorFallbackContext(cmd *cobra.Command) config.ShuttleProjectContext {
+	projectPath, _ := cmd.Flags().GetString("project")
+	projectPath, _ = filepath.Abs(projectPath)
+	context := config.ShuttleProjectContext{ProjectPath: projectPath}
+	if f, err := openShuttleFile(cmd); err == nil {
+		context.ProjectPath = filepath.Dir(f.Path)
+		if plan, err := f.Get("plan"); err == nil {
+			context.Config.Plan = plan
+		}
+	}
+	context.LocalShuttleDirectoryPath = filepath.Join(context.ProjectPath, ".shuttle")
+	return context
+}
+
+// errorMessage returns the message of err without its exit code and the hints
+// following the first blank line.
+func errorMessage(err error) string {
+	message := err.Error()
+	var exitCode *errors.ExitCode
+	if stderrors.As(err, &exitCode) {
+		message = exitCode.Message
+	}
+	message, _, _ = strings.Cut(message, "\n\n")
+	return strings.TrimSpace(message)
+}
+
+// printDiagnoses prints a line per diagnosis followed by the remediation of
+// warnings and failures. Details of several lines are aligned. It returns the number of failed checks.
+func printDiagnoses(uii *ui.UI, diagnoses []executors.Diagnosis) int {
+	width := 0
+	for _, diagnosis := range diagnoses {
+		if len(diagnosis.Check) > width {
+			width = len(diagnosis.Check)
+		}
+	}
+	failed := 0
+	for _, diagnosis := range diagnoses {
+		lines := strings.Split(diagnosis.Detail, "\n")
+		uii.Output("%-4s  %-*s  %s", diagnosis.Status, width, diagnosis.Check, lines[0])
+		for _, line := range lines[1:] {
+			uii.Output("      %-*s  %s", width, "", strings.TrimSpace(line))
+		}
+		if diagnosis.Status != executors.DiagnosisOK && diagnosis.Remediation != "" {
+			uii.Output("      %-*s  → %s", width, "", diagnosis.Remediation)
+		}
+		if diagnosis.Status == executors.DiagnosisFail {
+			failed++
+		}
+	}
+	return failed
+}
+
+// isDoctorRequest returns true if the parsed arguments of rootCmd run shuttle
+// doctor which is available even if the project cannot be loaded.
+func isDoctorRequest(rootCmd *cobra.Command) bool {
+	args := rootCmd.Flags().Args()
+	return len(args) > 0 && args[0] == "doctor"
+}
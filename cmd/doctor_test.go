@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoctor(t *testing.T) {
+	t.Run("project", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		input := args("-p", "testdata/project", "doctor")
+		rootCmd, _, err := initializedRootFromArgs(&stdout, &stderr, input)
+		require.NoError(t, err)
+		rootCmd.SetArgs(input)
+
+		require.NoError(t, rootCmd.Execute())
+
+		assert.Contains(t, stdout.String(), "ok    .shuttle  writable\n")
+		assert.Equal(t, "All checks passed\n", stderr.String())
+	})
+
+	t.Run("project failing to load", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		input := args("-p", "testdata/invalid-yaml", "doctor")
+		rootCmd, _, err := initializedRootFromArgs(&stdout, &stderr, input)
+		require.NoError(t, err, "doctor is available when the project cannot be loaded")
+		rootCmd.SetArgs(input)
+
+		err = rootCmd.Execute()
+
+		assert.EqualError(t, err, "exit code 1 - 1 of 4 checks failed")
+		assert.Contains(t, stdout.String(), "fail  project   Failed to parse shuttle configuration: schema errors:\n                ")
+	})
+}
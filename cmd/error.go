@@ -13,15 +13,24 @@ func checkError(uii *ui.UI, err error) {
 	if err == nil {
 		return
 	}
+	// buffered output is lost on os.Exit
+	exit := func(code int) {
+		uii.Flush()
+		os.Exit(code)
+	}
 	var exitCode *shuttleerrors.ExitCode
 	if errors.As(err, &exitCode) {
 		uii.Errorln("shuttle failed\n%s", exitCode.Message)
-		os.Exit(exitCode.Code)
+		exit(exitCode.Code)
 	}
 	if errors.Is(err, stdcontext.Canceled) {
 		uii.Errorln("Operation cancelled")
-		os.Exit(2)
+		exit(shuttleerrors.ExitCodeCancelled)
+	}
+	if errors.Is(err, stdcontext.DeadlineExceeded) {
+		uii.Errorln("Timed out")
+		exit(shuttleerrors.ExitCodeTimeout)
 	}
 	uii.Errorln("shuttle failed\nError: %s", err)
-	os.Exit(1)
+	exit(1)
 }
@@ -41,6 +41,29 @@ func TestGet(t *testing.T) {
 			erroutput: "",
 			err:       nil,
 		},
+		{
+			name: "profile variables",
+			input: args(
+				"-p",
+				"testdata/profiles",
+				"--profile",
+				"prod",
+				"get",
+				"k8s",
+				"--template",
+				"{{ .namespace }} {{ .replicas }}",
+			),
+			stdoutput: "dev 3",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "profile name",
+			input:     args("-p", "testdata/profiles", "--profile", "prod", "get", "shuttle_profile"),
+			stdoutput: "prod",
+			erroutput: "",
+			err:       nil,
+		},
 		{
 			name:      "bool",
 			input:     args("-p", "testdata/project", "get", "boolVar"),
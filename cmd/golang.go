@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"os"
+	"path"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/executors/golang/compile"
+	"github.com/lunarway/shuttle/pkg/executors/golang/discover"
+	"github.com/lunarway/shuttle/pkg/executors/golang/shuttlefolder"
+	"github.com/lunarway/shuttle/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+func newGolang(uii *ui.UI) *cobra.Command {
+	golangCmd := &cobra.Command{
+		Use:   "golang",
+		Short: "Manage golang actions",
+	}
+	golangCmd.AddCommand(newGolangPrebuild(uii))
+	return golangCmd
+}
+
+func newGolangPrebuild(uii *ui.UI) *cobra.Command {
+	var (
+		platforms []string
+		output    string
+	)
+
+	prebuildCmd := &cobra.Command{
+		Use:   "prebuild [directory]",
+		Short: "Compile the golang actions of a plan for several platforms",
+		Long: `Compile the golang actions of the plan in a directory, by default the current
+directory, for each of the GOOS/GOARCH platforms of --platforms. The binaries
+are written to the prebuilt directory of the plan, or --output, and the paths
+of the binaries are printed.
+
+Projects using a plan with prebuilt binaries use the binary of their platform
+instead of compiling the actions, so they need no go toolchain. Binaries are
+named by the hash of the action sources and only used for the exact same
+sources. Binaries of other sources are removed from the directory.`,
+		Example:      `  shuttle golang prebuild --platforms linux/amd64,darwin/arm64,windows/amd64`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := "."
+			if len(args) == 1 {
+				dir = args[0]
+			}
+			if len(platforms) == 0 {
+				return errors.NewExitCode(2, "--platforms is required, eg. --platforms linux/amd64,darwin/arm64")
+			}
+			targets := make([]shuttlefolder.Target, len(platforms))
+			for i, platform := range platforms {
+				target, err := shuttlefolder.ParseTarget(platform)
+				if err != nil {
+					return errors.NewExitCode(2, "Invalid --platforms: %v", err)
+				}
+				targets[i] = target
+			}
+
+			if _, err := os.Stat(path.Join(dir, "plan.yaml")); err != nil {
+				return errors.NewExitCode(2, "Cannot prebuild plan '%s': the directory has no plan.yaml", dir)
+			}
+			plan, err := (&config.ShuttlePlanConfiguration{}).Load(dir)
+			if err != nil {
+				return err
+			}
+			actions, err := discover.DiscoverPlan(dir, plan.GolangActions)
+			if err != nil {
+				return err
+			}
+			if actions == nil || len(actions.Files) == 0 {
+				return errors.NewExitCode(2, "Cannot prebuild plan '%s': the plan has no golang actions", dir)
+			}
+
+			if output == "" {
+				output = path.Join(dir, shuttlefolder.PrebuiltDir)
+			}
+			paths, err := compile.Prebuild(cmd.Context(), uii, actions, targets, output)
+			if err != nil {
+				return err
+			}
+			for _, path := range paths {
+				uii.Output("%s", path)
+			}
+			return nil
+		},
+	}
+
+	prebuildCmd.Flags().StringSliceVar(&platforms, "platforms", nil, "GOOS/GOARCH platforms to compile for, eg. linux/amd64,darwin/arm64")
+	prebuildCmd.Flags().StringVar(&output, "output", "", "Directory to write the binaries to (default \"<directory>/prebuilt\")")
+
+	return prebuildCmd
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGolangPrebuild(t *testing.T) {
+	testCases := []testCase{
+		{
+			name:      "missing platforms",
+			input:     args("-p", "testdata/project", "golang", "prebuild", "testdata/base"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - --platforms is required, eg. --platforms linux/amd64,darwin/arm64\n",
+			err:       errors.New("exit code 2 - --platforms is required, eg. --platforms linux/amd64,darwin/arm64"),
+		},
+		{
+			name:      "invalid platform",
+			input:     args("-p", "testdata/project", "golang", "prebuild", "--platforms", "linux/amd64,darwin", "testdata/base"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - Invalid --platforms: target 'darwin' is invalid: must be on the form GOOS/GOARCH, eg. linux/amd64\n",
+			err:       errors.New("exit code 2 - Invalid --platforms: target 'darwin' is invalid: must be on the form GOOS/GOARCH, eg. linux/amd64"),
+		},
+		{
+			name:      "no plan",
+			input:     args("-p", "testdata/project", "golang", "prebuild", "--platforms", "linux/amd64", "testdata/project"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - Cannot prebuild plan 'testdata/project': the directory has no plan.yaml\n",
+			err:       errors.New("exit code 2 - Cannot prebuild plan 'testdata/project': the directory has no plan.yaml"),
+		},
+		{
+			name:      "no golang actions",
+			input:     args("-p", "testdata/project", "golang", "prebuild", "--platforms", "linux/amd64", "testdata/project-local/plan"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - Cannot prebuild plan 'testdata/project-local/plan': the plan has no golang actions\n",
+			err:       errors.New("exit code 2 - Cannot prebuild plan 'testdata/project-local/plan': the plan has no golang actions"),
+		},
+	}
+	executeTestCases(t, testCases)
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/executors"
+	"github.com/lunarway/shuttle/pkg/git"
+	"github.com/lunarway/shuttle/pkg/oci"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func newHistory(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	var (
+		filter   executors.HistoryFilter
+		since    string
+		jsonFlag bool
+	)
+
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "List past runs of scripts",
+		Long: `List past runs of scripts recorded in .shuttle/history.jsonl from oldest to
+latest with who ran them, their arguments with secrets redacted, the revision
+of the plan, how long they took and their exit code.`,
+		Example: `  shuttle history --script deploy --since 168h
+  shuttle history --failed --json`,
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if since != "" {
+				var err error
+				filter.Since, err = parseSince(since, time.Now())
+				if err != nil {
+					return err
+				}
+			}
+			context, err := contextProvider()
+			if err != nil {
+				return err
+			}
+			entries, err := executors.ReadHistory(executors.HistoryPath(context.LocalShuttleDirectoryPath))
+			if err != nil {
+				return err
+			}
+			entries = executors.FilterHistory(entries, filter)
+			if jsonFlag {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				for _, entry := range entries {
+					if err := encoder.Encode(entry); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			printHistory(uii, entries)
+			return nil
+		},
+	}
+
+	historyCmd.Flags().StringVar(&filter.Script, "script", "", "List runs of `script` only")
+	historyCmd.Flags().StringVar(&filter.User, "user", "", "List runs of `user` only")
+	historyCmd.Flags().StringVar(&since, "since", "", "List runs started within a duration, eg. 24h, or since a date, eg. 2024-03-01")
+	historyCmd.Flags().BoolVar(&filter.Failed, "failed", false, "List failed runs only")
+	historyCmd.Flags().IntVarP(&filter.Limit, "limit", "n", 20, "Number of latest runs to list. 0 lists all of them")
+	historyCmd.Flags().BoolVar(&jsonFlag, "json", false, "Print the runs as JSON lines")
+
+	return historyCmd
+}
+
+// parseSince returns the time of since which is either a duration before now
+// or an RFC 3339 time or date.
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if duration, err := time.ParseDuration(since); err == nil {
+		return now.Add(-duration), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, since, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.NewExitCode(2, "--since '%s' is invalid: must be a duration, eg. 24h, or a date, eg. 2024-03-01", since)
+}
+
+// printHistory prints the start, script, user, exit code, duration, plan
+// revision and arguments of runs.
+func printHistory(uii *ui.UI, entries []executors.HistoryEntry) {
+	if len(entries) == 0 {
+		uii.Infoln("No runs found")
+		return
+	}
+	for _, entry := range entries {
+		names := make([]string, 0, len(entry.Args))
+		for name := range entry.Args {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		args := make([]string, 0, len(names))
+		for _, name := range names {
+			args = append(args, fmt.Sprintf("%s=%s", name, entry.Args[name]))
+		}
+		revision := entry.PlanRevision
+		if len(revision) > 7 && !strings.Contains(revision, ":") {
+			revision = revision[:7]
+		}
+		line := fmt.Sprintf(
+			"%s  %s  %s  exit %d  %s",
+			entry.StartedAt.Local().Format(time.RFC3339),
+			entry.Script,
+			entry.User,
+			entry.ExitCode,
+			(time.Duration(entry.DurationMs) * time.Millisecond).String(),
+		)
+		if revision != "" {
+			line += "  plan " + revision
+		}
+		if len(args) != 0 {
+			line += "  " + strings.Join(args, " ")
+		}
+		uii.Output("%s", line)
+	}
+}
+
+// recordRun records a run of script in the history of the project and sends
+// it to the audit endpoint if one is configured. Failures are reported without
+// failing the run.
+func recordRun(ctx stdcontext.Context, uii *ui.UI, context config.ShuttleProjectContext, script string, args map[string]string, start time.Time, err error) {
+	entry := executors.NewHistoryEntry(ctx, context, script, args, planRevision(context), start, err)
+	recordErr := executors.RecordHistory(executors.HistoryPath(context.LocalShuttleDirectoryPath), entry, context.Config.History)
+	if recordErr != nil {
+		uii.Errorln("Failed to record run in history: %v", recordErr)
+	}
+	if endpoint := executors.HistoryEndpoint(context.Config.History); endpoint != "" {
+		// the run is sent even if it was cancelled
+		sendErr := executors.SendHistoryEntry(stdcontext.WithoutCancel(ctx), endpoint, entry)
+		if sendErr != nil {
+			uii.Errorln("Failed to send run to audit endpoint: %v", sendErr)
+		}
+	}
+}
+
+// planRevision returns the checked out commit of a git plan or the pulled
+// digest of an OCI plan of context. It is empty for other plans.
+func planRevision(context config.ShuttleProjectContext) string {
+	switch plan := context.Config.Plan; {
+	case git.IsPlan(plan):
+		return git.Commit(context.LocalPlanPath)
+	case oci.IsPlan(plan):
+		return oci.PulledDigest(context.LocalShuttleDirectoryPath)
+	}
+	return ""
+}
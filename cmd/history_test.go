@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/executors"
+)
+
+func TestHistory(t *testing.T) {
+	t.Setenv("SHUTTLE_HISTORY_ENDPOINT", "")
+	project := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(project, "shuttle.yaml"), []byte(`plan: false
+scripts:
+  deploy:
+    args:
+    - name: env
+    - name: token
+      secret: true
+    actions:
+    - shell: echo deployed
+  fail:
+    actions:
+    - shell: exit 3
+`), 0o644))
+
+	executeTestContainsCases(t, []testCase{
+		{
+			name:      "deploy",
+			input:     args("-p", project, "run", "deploy", "env=prod", "token=hunter22"),
+			stdoutput: "deployed",
+		},
+		{
+			name:  "fail",
+			input: args("-p", project, "run", "fail"),
+			err:   errors.New("exit code 4 - Failed executing script `fail`: shell script `exit 3`\nExit code: 3"),
+		},
+		{
+			name:      "dry run",
+			input:     args("-p", project, "run", "deploy", "--dry-run"),
+			stdoutput: "echo deployed",
+		},
+	})
+
+	entries, err := executors.ReadHistory(executors.HistoryPath(filepath.Join(project, ".shuttle")))
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "dry runs are not recorded")
+	assert.Equal(t, "deploy", entries[0].Script)
+	assert.Equal(t, map[string]string{"env": "prod", "token": "***"}, entries[0].Args)
+	assert.Equal(t, 0, entries[0].ExitCode)
+	assert.NotEmpty(t, entries[0].RunID)
+	assert.WithinDuration(t, time.Now(), entries[0].StartedAt, time.Minute)
+	assert.Equal(t, "fail", entries[1].Script)
+	assert.Equal(t, 4, entries[1].ExitCode)
+
+	executeTestCasesWithCustomAssertion(t, []testCase{
+		{
+			name:  "list failed",
+			input: args("-p", project, "history", "--failed"),
+		},
+	}, func(t *testing.T, tc testCase, stdout, stderr string) {
+		assert.Contains(t, stdout, "  fail  "+entries[1].User+"  exit 4  ")
+		assert.NotContains(t, stdout, "deploy")
+	})
+	executeTestContainsCases(t, []testCase{
+		{
+			name:      "list script",
+			input:     args("-p", project, "history", "--script", "deploy"),
+			stdoutput: "  deploy  " + entries[0].User + "  exit 0  ",
+		},
+		{
+			name:      "no runs",
+			input:     args("-p", project, "history", "--since", "2h", "--script", "build"),
+			erroutput: "No runs found",
+		},
+		{
+			name:  "invalid since",
+			input: args("-p", project, "history", "--since", "yesterday"),
+			err:   errors.New("exit code 2 - --since 'yesterday' is invalid: must be a duration, eg. 24h, or a date, eg. 2024-03-01"),
+		},
+	})
+}
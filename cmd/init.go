@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	tmplFuncs "github.com/lunarway/shuttle/pkg/templates"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func newInit(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	var (
+		vars    []string
+		noInput bool
+	)
+
+	initCmd := &cobra.Command{
+		Use:   "init <plan>",
+		Short: "Initialize a project using a plan",
+		Long: `Initialize the project, by default the current directory, with a shuttle.yaml
+using a plan, prompt for the variables the plan declares in its init manifest
+and render the templates it ships into the project.
+
+Templates are the files in the init directory of the plan unless the init
+manifest lists them. They are rendered like shuttle template with the variables
+as .Vars and must not overwrite existing files.`,
+		Example: `  shuttle init https://github.com/lunarway/shuttle-example-go-plan.git
+  shuttle -p services/api init https://github.com/lunarway/shuttle-example-go-plan.git --var service=api --no-input`,
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			projectPath, err := cmd.Flags().GetString("project")
+			if err != nil {
+				return err
+			}
+			values := make(map[string]string, len(vars))
+			for _, v := range vars {
+				name, value, ok := strings.Cut(v, "=")
+				if !ok || name == "" {
+					return errors.NewExitCode(2, "Variable '%s' is invalid: must be on the form <name>=<value>", v)
+				}
+				values[name] = value
+			}
+
+			shuttleFilePath := filepath.Join(projectPath, "shuttle.yaml")
+			if fileAvailable(shuttleFilePath) {
+				return errors.NewExitCode(2, "Project '%s' has a shuttle.yaml already", projectPath)
+			}
+			if err := os.MkdirAll(projectPath, os.ModePerm); err != nil {
+				return errors.NewExitCode(2, "Failed to create project '%s': %v", projectPath, err)
+			}
+			content, err := yaml.Marshal(struct {
+				Plan string `yaml:"plan"`
+			}{args[0]})
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(shuttleFilePath, content, 0o644); err != nil {
+				return errors.NewExitCode(2, "Failed to write '%s': %v", shuttleFilePath, err)
+			}
+			// a failed init can be retried
+			defer func() {
+				if err != nil {
+					os.Remove(shuttleFilePath)
+				}
+			}()
+
+			context, err := contextProvider()
+			if err != nil {
+				return err
+			}
+			prompt := !noInput && stdinIsTerminal()
+			variables, err := initVariables(uii, context, values, prompt)
+			if err != nil {
+				return err
+			}
+			files, err := renderInitFiles(context, variables)
+			if err != nil {
+				return err
+			}
+
+			shuttleFile, err := config.OpenShuttleFile(context.ProjectPath, true)
+			if err != nil {
+				return err
+			}
+			for _, variable := range variables {
+				asString := variable.Type != config.ArgTypeInt && variable.Type != config.ArgTypeBool
+				if err := shuttleFile.Set("vars."+variable.Name, variable.Value, asString); err != nil {
+					return err
+				}
+			}
+			if err := shuttleFile.Write(); err != nil {
+				return err
+			}
+			for _, file := range files {
+				target := filepath.Join(context.ProjectPath, filepath.FromSlash(file.Path))
+				if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+					return errors.NewExitCode(2, "Failed to create '%s': %v", file.Path, err)
+				}
+				if err := os.WriteFile(target, file.Content, 0o644); err != nil {
+					return errors.NewExitCode(2, "Failed to write '%s': %v", file.Path, err)
+				}
+				uii.Infoln("Created %s", file.Path)
+			}
+			uii.Infoln("Initialized project with plan %s. Run 'shuttle ls' to list its scripts", context.Config.Plan)
+			return nil
+		},
+	}
+
+	initCmd.Flags().StringArrayVar(&vars, "var", nil, "Value of a variable on the form <name>=<value> instead of prompting for it. Can be repeated")
+	initCmd.Flags().BoolVar(&noInput, "no-input", false, "Use the defaults of variables not set with --var instead of prompting for them even if stdin is a terminal")
+
+	return initCmd
+}
+
+// initVariable is a variable of shuttle.yaml set by shuttle init.
+type initVariable struct {
+	Name  string
+	Type  string
+	Value string
+}
+
+// initVariables returns the variables of the init manifest of the plan of
+// context in order followed by the remaining values sorted by name. Variables
+// not in values are prompted for if prompt is set or use their default.
+// Variables without a value are left out.
+func initVariables(uii *ui.UI, context config.ShuttleProjectContext, values map[string]string, prompt bool) ([]initVariable, error) {
+	var variables []initVariable
+	declared := make(map[string]bool)
+	for _, arg := range context.Plan.Init.Vars {
+		declared[arg.Name] = true
+		value, ok := values[arg.Name]
+		if !ok {
+			value = arg.Default
+			if prompt {
+				var err error
+				value, err = promptValue(uii, arg.Name, arg, arg.Default, context.ProjectPath)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		if value == "" {
+			if arg.Required {
+				return nil, errors.NewExitCode(2, "Variable '%s' is required: pass it with --var %s=<value>", arg.Name, arg.Name)
+			}
+			continue
+		}
+		if problem := arg.ValueProblem(value, context.ProjectPath); problem != "" {
+			return nil, errors.NewExitCode(2, "Variable '%s' %s", arg.Name, problem)
+		}
+		variables = append(variables, initVariable{Name: arg.Name, Type: arg.Type, Value: value})
+	}
+
+	var names []string
+	for name := range values {
+		if !declared[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		variables = append(variables, initVariable{Name: name, Value: values[name]})
+	}
+	return variables, nil
+}
+
+// initFile is a rendered init template of the plan.
+type initFile struct {
+	Path    string
+	Content []byte
+}
+
+// renderInitFiles renders the init templates of the plan of projectContext with
+// variables. No file is written and existing files of the project are an
+// error.
+func renderInitFiles(projectContext config.ShuttleProjectContext, variables []initVariable) ([]initFile, error) {
+	templates, err := projectContext.Plan.Init.InitFiles(projectContext.LocalPlanPath)
+	if err != nil {
+		return nil, err
+	}
+	funcs, err := tmplFuncs.LoadFunctions(path.Join(projectContext.LocalPlanPath, "templates", tmplFuncs.FunctionsFile))
+	if err != nil {
+		return nil, err
+	}
+	data := context{
+		Vars:        nestedVariables(variables),
+		PlanPath:    projectContext.LocalPlanPath,
+		ProjectPath: projectContext.ProjectPath,
+	}
+	render := func(name, text string) ([]byte, error) {
+		tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		var output bytes.Buffer
+		if err := tmpl.Execute(&output, data); err != nil {
+			return nil, err
+		}
+		return output.Bytes(), nil
+	}
+
+	var files []initFile
+	for _, file := range templates {
+		target, err := render(file.Template, file.Path)
+		if err != nil {
+			return nil, errors.NewExitCode(1, "Failed to render path of init template '%s': %v", file.Template, err)
+		}
+		name := filepath.ToSlash(path.Clean(string(target)))
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return nil, errors.NewExitCode(1, "Path '%s' of init template '%s' must be relative to and within the project", name, file.Template)
+		}
+		if fileAvailable(filepath.Join(projectContext.ProjectPath, filepath.FromSlash(name))) {
+			return nil, errors.NewExitCode(2, "File '%s' of init template '%s' exists already", name, file.Template)
+		}
+		text, err := os.ReadFile(filepath.Join(projectContext.LocalPlanPath, filepath.FromSlash(file.Template)))
+		if err != nil {
+			return nil, errors.NewExitCode(1, "Failed to read init template '%s': %v", file.Template, err)
+		}
+		content, err := render(file.Template, string(text))
+		if err != nil {
+			return nil, errors.NewExitCode(1, "Failed to render init template '%s': %v", file.Template, err)
+		}
+		files = append(files, initFile{Path: name, Content: content})
+	}
+	return files, nil
+}
+
+// nestedVariables returns variables as they are read from shuttle.yaml with
+// dotted names, eg. docker.image, as nested maps and integers and booleans
+// typed.
+func nestedVariables(variables []initVariable) map[string]interface{} {
+	vars := make(map[string]interface{})
+	for _, variable := range variables {
+		var value interface{} = variable.Value
+		switch variable.Type {
+		case config.ArgTypeInt:
+			value, _ = strconv.Atoi(variable.Value)
+		case config.ArgTypeBool:
+			value, _ = strconv.ParseBool(variable.Value)
+		}
+		parent := vars
+		names := strings.Split(variable.Name, ".")
+		for _, name := range names[:len(names)-1] {
+			child, ok := parent[name].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				parent[name] = child
+			}
+			parent = child
+		}
+		parent[names[len(names)-1]] = value
+	}
+	return vars
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInit(t *testing.T) {
+	plan := t.TempDir()
+	writeFile := func(path, content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	writeFile(filepath.Join(plan, "plan.yaml"), `init:
+  vars:
+  - name: service
+    required: true
+  - name: replicas
+    type: int
+    default: "2"
+  - name: docker.image
+    default: golang
+scripts:
+  build:
+    actions:
+    - shell: go build ./...
+`)
+	writeFile(filepath.Join(plan, "init", "README.md.tmpl"), "# {{.Vars.service}}\n")
+	writeFile(filepath.Join(plan, "init", "deploy", "values.yaml.tmpl"), "replicas: {{.Vars.replicas}}\nimage: {{.Vars.docker.image}}\n")
+
+	t.Run("init", func(t *testing.T) {
+		project := filepath.Join(t.TempDir(), "api")
+
+		executeTestContainsCases(t, []testCase{
+			{
+				name:      "init",
+				input:     args("-p", project, "init", plan, "--var", "service=api", "--var", "team=platform"),
+				erroutput: "Initialized project with plan " + plan,
+			},
+		})
+
+		assertFile(t, filepath.Join(project, "shuttle.yaml"), `plan: `+plan+`
+vars:
+  service: api
+  replicas: 2
+  docker:
+    image: golang
+  team: platform
+`)
+		assertFile(t, filepath.Join(project, "README.md"), "# api\n")
+		assertFile(t, filepath.Join(project, "deploy", "values.yaml"), "replicas: 2\nimage: golang\n")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		existing := t.TempDir()
+		writeFile(filepath.Join(existing, "shuttle.yaml"), "plan: false\n")
+		conflicting := t.TempDir()
+		writeFile(filepath.Join(conflicting, "README.md"), "# readme\n")
+		missing := t.TempDir()
+		invalid := t.TempDir()
+
+		executeTestContainsCases(t, []testCase{
+			{
+				name:  "existing project",
+				input: args("-p", existing, "init", plan),
+				err:   errors.New("exit code 2 - Project '" + existing + "' has a shuttle.yaml already"),
+			},
+			{
+				name:  "existing file",
+				input: args("-p", conflicting, "init", plan, "--var", "service=api"),
+				err:   errors.New("exit code 2 - File 'README.md' of init template 'init/README.md.tmpl' exists already"),
+			},
+			{
+				name:  "missing required variable",
+				input: args("-p", missing, "init", plan),
+				err:   errors.New("exit code 2 - Variable 'service' is required: pass it with --var service=<value>"),
+			},
+			{
+				name:  "invalid variable",
+				input: args("-p", invalid, "init", plan, "--var", "service=api", "--var", "replicas=many"),
+				err:   errors.New("exit code 2 - Variable 'replicas' must be an integer but was 'many'"),
+			},
+		})
+
+		// failed projects are left as they were
+		assert.NoFileExists(t, filepath.Join(conflicting, "shuttle.yaml"))
+		assert.NoFileExists(t, filepath.Join(missing, "shuttle.yaml"))
+		assertFile(t, filepath.Join(existing, "shuttle.yaml"), "plan: false\n")
+	})
+}
+
+func assertFile(t *testing.T, path string, content string) {
+	t.Helper()
+	actual, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(actual), path)
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/lunarway/shuttle/pkg/executors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func newLock(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	lockCmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Manage the locks of scripts",
+	}
+	lockCmd.AddCommand(newLockRelease(uii, contextProvider))
+	return lockCmd
+}
+
+func newLockRelease(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	return &cobra.Command{
+		Use:   "release <lock>",
+		Short: "Release a lock of scripts regardless of who holds it",
+		Long: `Release a lock of scripts regardless of who holds it, eg. as the run holding
+it is stuck. The lock is the expanded lock of the script, eg. deploy-prod. A
+run still holding the lock keeps running but no longer excludes other runs.`,
+		Example:       `  shuttle lock release deploy-prod`,
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := contextProvider()
+			if err != nil {
+				return err
+			}
+			if err := executors.ReleaseLock(cmd.Context(), context, args[0]); err != nil {
+				return err
+			}
+			uii.Infoln("Released lock '%s'", args[0])
+			return nil
+		},
+	}
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockRelease(t *testing.T) {
+	t.Setenv("SHUTTLE_LOCK_ENDPOINT", "")
+	project := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(project, "shuttle.yaml"), []byte(`plan: false
+scripts:
+  deploy:
+    lock: deploy-$env
+    args:
+    - name: env
+    actions:
+    - shell: ./deploy.sh
+`), 0o644))
+	locks := filepath.Join(project, ".shuttle", "locks")
+	require.NoError(t, os.MkdirAll(locks, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(locks, "deploy-prod.lock"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(locks, "deploy-prod.json"), []byte(`{"user":"alice"}`), 0o644))
+
+	executeTestCases(t, []testCase{
+		{
+			name:      "release",
+			input:     args("-p", project, "lock", "release", "deploy-prod"),
+			erroutput: "Released lock 'deploy-prod'\n",
+		},
+	})
+	assert.NoFileExists(t, filepath.Join(locks, "deploy-prod.lock"))
+	assert.NoFileExists(t, filepath.Join(locks, "deploy-prod.json"))
+}
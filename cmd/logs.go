@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	stdcontext "context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/executors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+const logsPollInterval = 200 * time.Millisecond
+
+func newLogs(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	var (
+		follow bool
+		last   bool
+		script string
+	)
+
+	logsCmd := &cobra.Command{
+		Use:   "logs [script]",
+		Short: "Show the output of a script running in the background or of past runs",
+		Long: `Show the output of a script started with a background action.
+
+By default new output is followed until the script exits after which its exit
+code is printed.
+
+Without a script the logs of past runs written to .shuttle/logs are listed.
+Show the latest of them with --last or the latest of a script with --script.`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 && (last || script != "") {
+				return errors.NewExitCode(2, "--last and --script cannot be used with a background script")
+			}
+			context, err := contextProvider()
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 0 {
+				logs, err := executors.ListRunLogs(executors.RunLogsDirectory(context.LocalShuttleDirectoryPath))
+				if err != nil {
+					return err
+				}
+				if !last && script == "" {
+					printRunLogs(uii, logs)
+					return nil
+				}
+				return showRunLog(cmd.OutOrStdout(), logs, script)
+			}
+
+			ctx, cancel := withSignal(cmd.Context(), uii)
+			defer cancel()
+
+			state := executors.BackgroundStateFor(context, args[0])
+			return followBackgroundLog(ctx, cmd.OutOrStdout(), uii, state, args[0], follow, logsPollInterval)
+		},
+	}
+
+	logsCmd.Flags().BoolVarP(&follow, "follow", "f", true, "Follow output until the script exits")
+	logsCmd.Flags().BoolVar(&last, "last", false, "Show the log of the latest run")
+	logsCmd.Flags().StringVar(&script, "script", "", "Show the log of the latest run of `script`")
+
+	return logsCmd
+}
+
+// printRunLogs prints the start, script and path of run logs from oldest to
+// latest.
+func printRunLogs(uii *ui.UI, logs []executors.RunLogFile) {
+	if len(logs) == 0 {
+		uii.Infoln("No run logs found")
+		return
+	}
+	for _, log := range logs {
+		uii.Output("%s  %s  %s", log.StartedAt.Local().Format(time.RFC3339), log.Script, log.Path)
+	}
+}
+
+// showRunLog copies the latest of logs to out. If script is set the latest
+// run of it is copied.
+func showRunLog(out io.Writer, logs []executors.RunLogFile, script string) error {
+	for i := len(logs) - 1; i >= 0; i-- {
+		if script != "" && logs[i].Script != script {
+			continue
+		}
+		file, err := os.Open(logs[i].Path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(out, file)
+		return err
+	}
+	if script != "" {
+		return errors.NewExitCode(2, "No run logs found for script '%s'", script)
+	}
+	return errors.NewExitCode(2, "No run logs found")
+}
+
+// followBackgroundLog copies the log of a background script to out. If follow
+// is set new output is copied until the script exits. Rotated or truncated
+// log files are reopened from the start.
+func followBackgroundLog(
+	ctx stdcontext.Context,
+	out io.Writer,
+	uii *ui.UI,
+	state executors.BackgroundState,
+	script string,
+	follow bool,
+	interval time.Duration,
+) error {
+	file, err := os.Open(state.LogPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.NewExitCode(2, "No background output found for script '%s'", script)
+		}
+		return err
+	}
+	defer func() {
+		file.Close()
+	}()
+
+	var offset int64
+	for {
+		n, err := io.Copy(out, file)
+		if err != nil {
+			return err
+		}
+		offset += n
+
+		if code, done := state.ExitCode(); done {
+			_, err := io.Copy(out, file)
+			if err != nil {
+				return err
+			}
+			uii.Infoln("Script '%s' exited with code %d", script, code)
+			return nil
+		}
+		if !state.Running() {
+			uii.Infoln("Script '%s' stopped without reporting an exit code", script)
+			return nil
+		}
+		if !follow {
+			uii.Infoln("Script '%s' is still running", script)
+			return nil
+		}
+
+		rotated, err := logRotated(file, state.LogPath, offset)
+		if err != nil {
+			return err
+		}
+		if rotated {
+			file.Close()
+			file, err = os.Open(state.LogPath)
+			if err != nil {
+				return err
+			}
+			offset = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// logRotated returns true if the file at logPath has been replaced or
+// truncated since file was opened.
+func logRotated(file *os.File, logPath string, offset int64) (bool, error) {
+	current, err := os.Stat(logPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// the log is being rotated so wait for the new file to appear
+			return false, nil
+		}
+		return false, err
+	}
+	opened, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	return !os.SameFile(current, opened) || current.Size() < offset, nil
+}
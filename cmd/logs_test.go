@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"bytes"
+	stdcontext "context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/executors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestFollowBackgroundLog(t *testing.T) {
+	newState := func(t *testing.T) executors.BackgroundState {
+		dir := t.TempDir()
+		return executors.BackgroundState{
+			LogPath:  filepath.Join(dir, "output.log"),
+			PIDPath:  filepath.Join(dir, "pid"),
+			ExitPath: filepath.Join(dir, "exit"),
+		}
+	}
+
+	t.Run("missing log", func(t *testing.T) {
+		state := newState(t)
+		var out, stderr bytes.Buffer
+
+		err := followBackgroundLog(stdcontext.Background(), &out, ui.Create(&stderr, &stderr), state, "build", true, time.Millisecond)
+
+		assert.EqualError(t, err, "exit code 2 - No background output found for script 'build'")
+	})
+
+	t.Run("exited script", func(t *testing.T) {
+		state := newState(t)
+		require.NoError(t, os.WriteFile(state.LogPath, []byte("line 1\nline 2\n"), 0644))
+		require.NoError(t, os.WriteFile(state.ExitPath, []byte("3\n"), 0644))
+		var out, stderr bytes.Buffer
+
+		err := followBackgroundLog(stdcontext.Background(), &out, ui.Create(&stderr, &stderr), state, "build", true, time.Millisecond)
+
+		require.NoError(t, err)
+		assert.Equal(t, "line 1\nline 2\n", out.String())
+		assert.Equal(t, "Script 'build' exited with code 3\n", stderr.String())
+	})
+
+	t.Run("follows new output", func(t *testing.T) {
+		state := newState(t)
+		require.NoError(t, os.WriteFile(state.LogPath, []byte("line 1\n"), 0644))
+		var out, stderr bytes.Buffer
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			f, err := os.OpenFile(state.LogPath, os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				return
+			}
+			f.WriteString("line 2\n")
+			f.Close()
+			os.WriteFile(state.ExitPath, []byte("0\n"), 0644)
+		}()
+
+		err := followBackgroundLog(stdcontext.Background(), &out, ui.Create(&stderr, &stderr), state, "build", true, time.Millisecond)
+
+		require.NoError(t, err)
+		assert.Equal(t, "line 1\nline 2\n", out.String())
+		assert.Equal(t, "Script 'build' exited with code 0\n", stderr.String())
+	})
+
+	t.Run("reopens rotated log", func(t *testing.T) {
+		state := newState(t)
+		require.NoError(t, os.WriteFile(state.LogPath, []byte("old line\n"), 0644))
+		var out, stderr bytes.Buffer
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			os.Rename(state.LogPath, state.LogPath+".1")
+			os.WriteFile(state.LogPath, []byte("new line\n"), 0644)
+			time.Sleep(20 * time.Millisecond)
+			os.WriteFile(state.ExitPath, []byte("0\n"), 0644)
+		}()
+
+		err := followBackgroundLog(stdcontext.Background(), &out, ui.Create(&stderr, &stderr), state, "build", true, time.Millisecond)
+
+		require.NoError(t, err)
+		assert.Equal(t, "old line\nnew line\n", out.String())
+	})
+
+	t.Run("no follow on running script", func(t *testing.T) {
+		state := newState(t)
+		require.NoError(t, os.WriteFile(state.LogPath, []byte("line 1\n"), 0644))
+		var out, stderr bytes.Buffer
+
+		err := followBackgroundLog(stdcontext.Background(), &out, ui.Create(&stderr, &stderr), state, "build", false, time.Millisecond)
+
+		require.NoError(t, err)
+		assert.Equal(t, "line 1\n", out.String())
+		assert.Equal(t, "Script 'build' is still running\n", stderr.String())
+	})
+}
+
+func TestLogs_runLogs(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	input := args("-p", "testdata/project", "run", "hello_stdout")
+	rootCmd, _, err := initializedRootFromArgs(&stdout, &stderr, input)
+	require.NoError(t, err)
+	rootCmd.SetArgs(input)
+	require.NoError(t, rootCmd.Execute())
+
+	t.Run("latest of script", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		input := args("-p", "testdata/project", "logs", "--script", "hello_stdout")
+		rootCmd, _, err := initializedRootFromArgs(&stdout, &stderr, input)
+		require.NoError(t, err)
+		rootCmd.SetArgs(input)
+
+		require.NoError(t, rootCmd.Execute())
+
+		assert.Regexp(t, `(?m)^\S+ script hello_stdout started$`, stdout.String())
+		assert.Regexp(t, `(?m)^\S+ \[hello_stdout/0\] command: sh -c .*echo "Hello stdout"$`, stdout.String())
+		assert.Regexp(t, `(?m)^\S+ \[hello_stdout/0\] stdout: Hello stdout$`, stdout.String())
+		assert.Regexp(t, `(?m)^\S+ script hello_stdout exited with code 0 after \S+$`, stdout.String())
+	})
+
+	testCases := []testCase{
+		{
+			name:      "unknown script",
+			input:     args("-p", "testdata/project", "logs", "--script", "unknown"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - No run logs found for script 'unknown'\n",
+			err:       errors.New("exit code 2 - No run logs found for script 'unknown'"),
+		},
+		{
+			name:      "last with background script",
+			input:     args("-p", "testdata/project", "logs", "--last", "hello_stdout"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - --last and --script cannot be used with a background script\n",
+			err:       errors.New("exit code 2 - --last and --script cannot be used with a background script"),
+		},
+	}
+	executeTestCases(t, testCases)
+}
@@ -14,13 +14,39 @@ Available Scripts:
 {{- end}}
 `
 
+const lsOriginTempl = `
+{{- $max := .Max -}}
+{{- $originMax := .OriginMax -}}
+Available Scripts:
+{{- range $key, $value := .Scripts}}
+  {{rightPad $key $max }} {{rightPad $value.Origin $originMax }} {{upperFirst $value.Description}}
+{{- end}}
+`
+
+const lsArgsTempl = `
+{{- $max := .Max -}}
+Available Scripts:
+{{- range $key, $value := .Scripts}}
+  {{rightPad $key $max }} {{upperFirst $value.Description}}
+{{- range $arg := $value.Args}}
+    {{ $arg }}
+{{- end}}
+{{- end}}
+`
+
 type templData struct {
 	Scripts map[string]config.ShuttlePlanScript
 	Max     int
+	// OriginMax is the width of the widest origin of the scripts
+	OriginMax int
 }
 
 func newLs(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
-	var lsFlagTemplate string
+	var (
+		lsFlagTemplate string
+		lsFlagOrigin   bool
+		lsFlagArgs     bool
+	)
 
 	lsCmd := &cobra.Command{
 		Use:          "ls [command]",
@@ -33,14 +59,20 @@ func newLs(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
 			}
 
 			var templ string
-			if lsFlagTemplate != "" {
+			switch {
+			case lsFlagTemplate != "":
 				templ = lsFlagTemplate
-			} else {
+			case lsFlagOrigin:
+				templ = lsOriginTempl
+			case lsFlagArgs:
+				templ = lsArgsTempl
+			default:
 				templ = lsDefaultTempl
 			}
 			err = ui.Template(cmd.OutOrStdout(), "ls", templ, templData{
-				Scripts: context.Scripts,
-				Max:     calculateRightPadForKeys(context.Scripts),
+				Scripts:   context.Scripts,
+				Max:       calculateRightPadForKeys(context.Scripts),
+				OriginMax: calculateRightPadForOrigins(context.Scripts),
 			})
 			if err != nil {
 				return err
@@ -52,6 +84,10 @@ func newLs(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
 
 	lsCmd.Flags().
 		StringVar(&lsFlagTemplate, "template", "", "Template string to use. The template format is golang templates [http://golang.org/pkg/text/template/#pkg-overview].")
+	lsCmd.Flags().
+		BoolVar(&lsFlagOrigin, "origin", false, "Show the plan, or shuttle.yaml, contributing each script")
+	lsCmd.Flags().
+		BoolVar(&lsFlagArgs, "args", false, "Show the arguments of each script with their types and defaults")
 
 	return lsCmd
 }
@@ -65,3 +101,13 @@ func calculateRightPadForKeys(m map[string]config.ShuttlePlanScript) int {
 	}
 	return max + 2
 }
+
+func calculateRightPadForOrigins(m map[string]config.ShuttlePlanScript) int {
+	max := 0
+	for _, script := range m {
+		if max < len(script.Origin) {
+			max = len(script.Origin)
+		}
+	}
+	return max + 2
+}
@@ -2,19 +2,25 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
+	"os"
 	"testing"
 )
 
 func TestLs(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+
+	invalidYaml := fmt.Sprintf("exit code 2 - Failed to parse shuttle configuration: schema errors:\n  %s/testdata/invalid-yaml/shuttle.yaml:1:1: document must be an object but is a string\n\nMake sure your 'shuttle.yaml' is valid.", pwd)
 	testCases := []testCase{
 		{
 			name:      "invalid shuttle.yaml file",
 			input:     args("-p", "testdata/invalid-yaml", "ls"),
 			stdoutput: "",
-			erroutput: "Error: exit code 2 - Failed to parse shuttle configuration: yaml: unmarshal errors:\n  line 1: cannot unmarshal !!str `plan` into config.ShuttleConfig\n\nMake sure your 'shuttle.yaml' is valid.\n",
-			initErr: errors.New(
-				"exit code 2 - Failed to parse shuttle configuration: yaml: unmarshal errors:\n  line 1: cannot unmarshal !!str `plan` into config.ShuttleConfig\n\nMake sure your 'shuttle.yaml' is valid.",
-			),
+			erroutput: "Error: " + invalidYaml + "\n",
+			initErr:   errors.New(invalidYaml),
 		},
 		{
 			name:      "list one action",
@@ -23,6 +29,20 @@ func TestLs(t *testing.T) {
 			erroutput: "",
 			err:       nil,
 		},
+		{
+			name:      "list arguments",
+			input:     args("-p", "testdata/typed-args", "ls", "--args"),
+			stdoutput: "Available Scripts:\n  deploy       Deploy the service\n    env (enum: dev|prod, default: dev)  Environment to deploy to\n    replicas (int)\n    tag (pattern: ^v[0-9]+$)\n",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "list origins of plan overlays",
+			input:     args("-p", "testdata/overlays", "ls", "--origin"),
+			stdoutput: "Available Scripts:\n  hello-base      ./base-plan    Write output\n  hello-project   shuttle.yaml   Write output\n  hello-team      ./team-plan    Write output\n",
+			erroutput: "",
+			err:       nil,
+		},
 	}
 	executeTestCases(t, testCases)
 }
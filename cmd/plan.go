@@ -1,6 +1,13 @@
 package cmd
 
 import (
+	"os"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/git"
+	"github.com/lunarway/shuttle/pkg/oci"
+	"github.com/lunarway/shuttle/pkg/signature"
 	"github.com/lunarway/shuttle/pkg/ui"
 	"github.com/spf13/cobra"
 )
@@ -23,6 +30,7 @@ See http://golang.org/pkg/text/template/#pkg-overview for more details.
 Available fields are:
 
   .LocalPlanPath     Path to the plan on the local file system.
+  .Overlays          Plans listed after the first plan merged onto it in order.
   .Plan              Pretty plan string. Empty if no plan is set.
   .PlanRaw           Raw plan string as read from the configuration.
   .ProjectPath       Path to the current project.
@@ -35,6 +43,7 @@ Available fields are:
 		RunE: func(cmd *cobra.Command, args []string) error {
 			type templData struct {
 				LocalPlanPath     string
+				Overlays          []string
 				Plan              string
 				PlanRaw           interface{}
 				ProjectPath       string
@@ -56,6 +65,7 @@ Available fields are:
 				Plan:              context.Config.Plan,
 				PlanRaw:           context.Config.PlanRaw,
 				LocalPlanPath:     context.LocalPlanPath,
+				Overlays:          context.Config.Overlays,
 				ProjectPath:       context.ProjectPath,
 				TempDirectoryPath: context.TempDirectoryPath,
 			})
@@ -69,6 +79,146 @@ Available fields are:
 
 	planCmd.Flags().
 		StringVar(&planFlagTemplate, "template", "", "Template string to use. See --help for details.")
+	planCmd.AddCommand(newPlanPush(uii))
+	planCmd.AddCommand(newPlanLock(uii, contextProvider))
+	planCmd.AddCommand(newPlanDiff(uii, contextProvider))
+	planCmd.AddCommand(newPlanUpgrade(uii, contextProvider))
+	planCmd.AddCommand(newPlanDigest(uii))
+
+	return planCmd
+}
 
+// newNoContextPlan returns the plan command available outside projects, eg. in
+// the repository of a plan, which can only push and digest plans.
+func newNoContextPlan(uii *ui.UI) *cobra.Command {
+	planCmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Manage plans",
+	}
+	planCmd.AddCommand(newPlanPush(uii))
+	planCmd.AddCommand(newPlanDigest(uii))
 	return planCmd
 }
+
+func newPlanPush(uii *ui.UI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "push <reference> [directory]",
+		Short: "Push a plan to an OCI registry",
+		Long: `Push the plan in a directory, by default the current directory, to an OCI
+registry such that projects can use it with plan: oci://<registry>/<repository>:<tag>.
+
+The .git and .shuttle directories of the plan are not pushed. Credentials are
+read from SHUTTLE_OCI_USERNAME and SHUTTLE_OCI_PASSWORD.`,
+		Example:      `  shuttle plan push oci://ghcr.io/org/plan:v1.2.3`,
+		Args:         cobra.RangeArgs(1, 2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := "."
+			if len(args) == 2 {
+				dir = args[1]
+			}
+			digest, err := oci.PushPlan(cmd.Context(), args[0], dir)
+			if err != nil {
+				return err
+			}
+			uii.Output("Pushed plan %s to %s@%s", dir, args[0], digest)
+			return nil
+		},
+	}
+}
+
+func newPlanDigest(uii *ui.UI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "digest [directory]",
+		Short: "Output the digest of a plan to sign",
+		Long: `Output the digest of the plan in a directory, by default the current
+directory. Sign the output with minisign or cosign to let projects verify the
+plan with planPublicKey:
+
+  shuttle plan digest > plan.digest
+  minisign -Sm plan.digest                                    # plan.digest.minisig
+  cosign sign-blob --key cosign.key plan.digest > plan.digest.sig
+
+The digest covers all files of the plan except the .git and .shuttle
+directories and the digest and signature files.`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := "."
+			if len(args) == 1 {
+				dir = args[0]
+			}
+			digest, err := signature.Digest(dir)
+			if err != nil {
+				return errors.NewExitCode(2, "Failed to digest plan '%s': %v", dir, err)
+			}
+			uii.Output("%s", digest)
+			return nil
+		},
+	}
+}
+
+func newPlanLock(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	return &cobra.Command{
+		Use:   "lock",
+		Short: "Lock the plan to its latest revision",
+		Long: `Fetch the latest revision of the plan and record its exact git commit or OCI
+digest in shuttle.lock. All commands use the locked revision of the plan until
+it is locked again or --update-plan is passed.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flag("plan").Value.String() != "" || cmd.Flag("plan-dir").Value.String() != "" || os.Getenv("SHUTTLE_PLAN_OVERLOAD") != "" {
+				return errors.NewExitCode(2, "Cannot lock an overloaded plan: lock the plan of shuttle.yaml instead")
+			}
+			// the lock is replaced so the plan is fetched at its latest revision
+			if err := cmd.Flags().Set("update-plan", "true"); err != nil {
+				return err
+			}
+			context, err := contextProvider()
+			if err != nil {
+				return err
+			}
+			lock, err := planLockOf(context)
+			if err != nil {
+				return err
+			}
+			if err := config.WritePlanLock(context.ProjectPath, lock); err != nil {
+				return err
+			}
+			if lock.Commit != "" {
+				uii.Output("Locked plan %s at commit %s", lock.Plan, lock.Commit)
+			} else {
+				uii.Output("Locked plan %s at digest %s", lock.Plan, lock.Digest)
+			}
+			return nil
+		},
+	}
+}
+
+// planLockOf returns the lock of the plan fetched for context.
+func planLockOf(context config.ShuttleProjectContext) (config.PlanLock, error) {
+	plan := context.Config.Plan
+	switch {
+	case plan == "":
+		return config.PlanLock{}, errors.NewExitCode(2, "Cannot lock plan: the project has no plan")
+	case git.IsPlan(plan):
+		changed, err := git.ChangedFiles(context.LocalPlanPath)
+		if err != nil {
+			return config.PlanLock{}, errors.NewExitCode(1, "Cannot lock plan %s: %v", plan, err)
+		}
+		if len(changed) > 0 {
+			return config.PlanLock{}, errors.NewExitCode(
+				1,
+				"Cannot lock plan %s as it has %d locally changed files. Commit or revert the changes first.",
+				plan,
+				len(changed),
+			)
+		}
+		return config.PlanLock{Plan: plan, Commit: git.Commit(context.LocalPlanPath)}, nil
+	case oci.IsPlan(plan):
+		return config.PlanLock{Plan: plan, Digest: oci.PulledDigest(context.LocalShuttleDirectoryPath)}, nil
+	default:
+		return config.PlanLock{}, errors.NewExitCode(2, "Cannot lock plan '%s': only git and OCI plans can be locked", plan)
+	}
+}
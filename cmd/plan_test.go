@@ -2,7 +2,17 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
 )
 
 func TestPlan(t *testing.T) {
@@ -36,3 +46,233 @@ func TestPlan(t *testing.T) {
 	}
 	executeTestCases(t, testCases)
 }
+
+func TestPlanPush(t *testing.T) {
+	testCases := []testCase{
+		{
+			name:      "no plan.yaml",
+			input:     args("-p", "testdata/project", "plan", "push", "oci://localhost:5000/org/plan:v1", "testdata/project"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - Cannot push plan from 'testdata/project': the directory has no plan.yaml\n",
+			err:       errors.New("exit code 2 - Cannot push plan from 'testdata/project': the directory has no plan.yaml"),
+		},
+		{
+			name:      "digest reference",
+			input:     args("-p", "testdata/project", "plan", "push", "oci://localhost:5000/org/plan@sha256:"+strings.Repeat("a", 64), "testdata/base"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - Cannot push plan to oci://localhost:5000/org/plan@sha256:" + strings.Repeat("a", 64) + ": the reference must have a tag and not a digest\n",
+			err:       errors.New("exit code 2 - Cannot push plan to oci://localhost:5000/org/plan@sha256:" + strings.Repeat("a", 64) + ": the reference must have a tag and not a digest"),
+		},
+		{
+			name:      "invalid reference",
+			input:     args("-p", "testdata/project", "plan", "push", "oci://localhost:5000/Org/plan", "testdata/base"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - OCI reference 'oci://localhost:5000/Org/plan' is invalid: repository 'Org/plan' must be lowercase alphanumerics separated by /, ., _ or -\n",
+			err:       errors.New("exit code 2 - OCI reference 'oci://localhost:5000/Org/plan' is invalid: repository 'Org/plan' must be lowercase alphanumerics separated by /, ., _ or -"),
+		},
+	}
+	executeTestCases(t, testCases)
+}
+
+func TestPlanDigest(t *testing.T) {
+	testCases := []testCase{
+		{
+			name:      "plan",
+			input:     args("-p", "testdata/project", "plan", "digest", "testdata/base"),
+			stdoutput: "sha256:609e816becd6526267f067f97d63245ef3d001cc23577963f37e50c4340ee571\n",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "missing directory",
+			input:     args("-p", "testdata/project", "plan", "digest", "testdata/missing"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - Failed to digest plan 'testdata/missing': lstat testdata/missing: no such file or directory\n",
+			err:       errors.New("exit code 2 - Failed to digest plan 'testdata/missing': lstat testdata/missing: no such file or directory"),
+		},
+	}
+	executeTestCases(t, testCases)
+}
+
+func TestPlanLock(t *testing.T) {
+	testCases := []testCase{
+		{
+			name:      "no plan",
+			input:     args("-p", "testdata/project", "plan", "lock"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - Cannot lock plan: the project has no plan\n",
+			err:       errors.New("exit code 2 - Cannot lock plan: the project has no plan"),
+		},
+		{
+			name:      "local plan",
+			input:     args("-p", "testdata/project-plan-inside", "plan", "lock"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - Cannot lock plan './plan': only git and OCI plans can be locked\n",
+			err:       errors.New("exit code 2 - Cannot lock plan './plan': only git and OCI plans can be locked"),
+		},
+	}
+	executeTestCases(t, testCases)
+}
+
+func TestPlanLock_overloaded(t *testing.T) {
+	executeTestContainsCases(t, []testCase{
+		{
+			name:  "plan dir",
+			input: args("-p", "testdata/project-plan-inside", "--plan-dir", "plan", "plan", "lock"),
+			err:   errors.New("exit code 2 - Cannot lock an overloaded plan: lock the plan of shuttle.yaml instead"),
+		},
+	})
+}
+
+func TestPlanDiffUpgrade(t *testing.T) {
+	const plan = "https://github.com/lunarway/shuttle-example-go-plan.git"
+	// the token keeps credentials from being looked up for the plan
+	t.Setenv("SHUTTLE_GIT_TOKEN", "token")
+	t.Setenv("SHUTTLE_PLANS_ALREADY_VALIDATED", "")
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=shuttle", "-c", "user.email=shuttle@example.com"}, args...)...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+		return strings.TrimSpace(string(output))
+	}
+	writeFile := func(path, content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	origin := t.TempDir()
+	writeFile(filepath.Join(origin, "plan.yaml"), `scripts:
+  build:
+    description: Build the project
+    actions:
+    - shell: go build ./...
+    args:
+    - name: env
+      type: enum
+      values: [dev, prod]
+  lint:
+    actions:
+    - shell: golangci-lint run
+`)
+	git(origin, "init", "--quiet", "--initial-branch=master")
+	git(origin, "add", ".")
+	git(origin, "commit", "--quiet", "-m", "initial")
+	first := git(origin, "rev-parse", "HEAD")
+
+	project := t.TempDir()
+	writeFile(filepath.Join(project, "shuttle.yaml"), "# the plan\nplan: "+plan+"\n")
+	git(project, "clone", "--quiet", origin, filepath.Join(".shuttle", "plan"))
+
+	writeFile(filepath.Join(origin, "plan.yaml"), `scripts:
+  build:
+    description: Build the project
+    actions:
+    - shell: go build -v ./...
+    args:
+    - name: env
+      type: enum
+      values: [prod]
+    - name: race
+  test:
+    actions:
+    - shell: go test ./...
+`)
+	writeFile(filepath.Join(origin, "templates", "ci.tmpl"), "{{.}}")
+	git(origin, "add", ".")
+	git(origin, "commit", "--quiet", "-m", "ci")
+	git(origin, "tag", "v2")
+	second := git(origin, "rev-parse", "HEAD")
+
+	diff := fmt.Sprintf(`Plan %s from %s to %s
+
+Scripts:
+  + test
+  - lint (breaking)
+  ~ build (breaking)
+      + arg race
+      ~ arg env (enum: dev|prod) -> env (enum: prod) (breaking)
+      ~ actions
+
+Files:
+  M plan.yaml
+  A templates/ci.tmpl
+
+The plan has breaking changes: check the invocations of the changed scripts before upgrading.
+`, plan, first[:7], second[:7])
+
+	executeTestCases(t, []testCase{
+		{
+			name:      "diff",
+			input:     args("-p", project, "plan", "diff"),
+			stdoutput: diff,
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "diff exit code",
+			input:     args("-p", project, "plan", "diff", "--exit-code", "v2"),
+			stdoutput: diff,
+			erroutput: "",
+			err:       errors.New("exit code 1 - "),
+		},
+		{
+			name:      "diff same revision",
+			input:     args("-p", project, "plan", "diff", first),
+			stdoutput: fmt.Sprintf("Plan %s is at %s already\n", plan, first[:7]),
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "upgrade unlocked",
+			input:     args("-p", project, "plan", "upgrade", "--yes"),
+			stdoutput: "",
+			erroutput: "",
+			err: errors.New("exit code 2 - Plan " + plan + " is not locked and its latest revision is used already\n\n" +
+				"Pass a branch, tag or commit to upgrade to or lock the plan with 'shuttle plan lock'."),
+		},
+		{
+			name:      "upgrade to tag",
+			input:     args("-p", project, "plan", "upgrade", "--yes", "v2"),
+			stdoutput: diff,
+			erroutput: "Pinned plan " + plan + "#v2 in shuttle.yaml\n",
+			err:       nil,
+		},
+	})
+
+	content, err := os.ReadFile(filepath.Join(project, "shuttle.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "# the plan\nplan: "+plan+"#v2\n", string(content))
+	assert.NoFileExists(t, filepath.Join(project, config.PlanLockFile), "unlocked plan stays unlocked")
+
+	t.Run("upgrade locked", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(project, "shuttle.yaml"), []byte("plan: "+plan+"\n"), 0o644))
+		require.NoError(t, config.WritePlanLock(project, config.PlanLock{Plan: plan, Commit: first}))
+
+		executeTestCases(t, []testCase{
+			{
+				name:      "upgrade",
+				input:     args("-p", project, "plan", "upgrade", "--yes"),
+				stdoutput: diff,
+				erroutput: "Locked plan " + plan + " at commit " + second + "\n",
+				err:       nil,
+			},
+		})
+
+		lock, err := config.ReadPlanLock(project)
+		require.NoError(t, err)
+		assert.Equal(t, &config.PlanLock{Plan: plan, Commit: second}, lock)
+	})
+}
+
+func TestPlanUpgrade_overloaded(t *testing.T) {
+	executeTestContainsCases(t, []testCase{
+		{
+			name:  "plan dir",
+			input: args("-p", "testdata/project-plan-inside", "--plan-dir", "plan", "plan", "upgrade"),
+			err:   errors.New("exit code 2 - Cannot upgrade an overloaded plan: upgrade the plan of shuttle.yaml instead"),
+		},
+	})
+}
@@ -0,0 +1,315 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/git"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// planRevisionDiff is the difference between the checked out revision of a git
+// plan and another revision of it.
+type planRevisionDiff struct {
+	Plan    string
+	From    string
+	To      string
+	Scripts config.PlanDiff
+	Files   []git.FileChange
+}
+
+func newPlanDiff(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	var exitCode bool
+
+	diffCmd := &cobra.Command{
+		Use:   "diff [ref]",
+		Short: "Show what changes when the plan is upgraded to a ref",
+		Long: `Show what changed between the checked out revision of a git plan and a
+branch, tag or commit of it, by default the latest revision of its head: added,
+removed and changed scripts and arguments and the changed files of the plan,
+eg. templates.
+
+Changes that may break existing invocations, ie. removed scripts or arguments
+and arguments that became stricter, are marked as breaking.`,
+		Example: `  shuttle plan diff
+  shuttle plan diff v2.0.0`,
+		Args:          cobra.MaximumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := planRevisionContext(contextProvider)
+			if err != nil {
+				return err
+			}
+			diff, err := diffPlanRevision(context, firstArg(args), uii)
+			if err != nil {
+				return err
+			}
+			writePlanRevisionDiff(cmd.OutOrStdout(), diff)
+			if exitCode && diff.From != diff.To {
+				return errors.NewExitCode(1, "")
+			}
+			return nil
+		},
+	}
+
+	diffCmd.Flags().BoolVar(&exitCode, "exit-code", false, "Exit with 1 if the plan changed")
+
+	return diffCmd
+}
+
+func newPlanUpgrade(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	var yes bool
+
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade [ref]",
+		Short: "Show the changes of the plan and upgrade the project to a ref",
+		Long: `Show the changes of the plan like shuttle plan diff and upgrade the project to
+them once confirmed.
+
+With a ref the branch, tag or commit of the plan in shuttle.yaml is replaced by
+it. A project with a shuttle.lock is locked at the new revision.`,
+		Example: `  shuttle plan upgrade
+  shuttle plan upgrade v2.0.0 --yes`,
+		Args:          cobra.MaximumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flag("plan").Value.String() != "" || cmd.Flag("plan-dir").Value.String() != "" || os.Getenv("SHUTTLE_PLAN_OVERLOAD") != "" {
+				return errors.NewExitCode(2, "Cannot upgrade an overloaded plan: upgrade the plan of shuttle.yaml instead")
+			}
+			ref := firstArg(args)
+			context, err := planRevisionContext(contextProvider)
+			if err != nil {
+				return err
+			}
+			lock, err := config.ReadPlanLock(context.ProjectPath)
+			if err != nil {
+				return err
+			}
+			if ref == "" && lock == nil {
+				return errors.NewExitCode(
+					2,
+					"Plan %s is not locked and its latest revision is used already\n\nPass a branch, tag or commit to upgrade to or lock the plan with 'shuttle plan lock'.",
+					context.Config.Plan,
+				)
+			}
+			// the lock must describe the plan as committed
+			if _, err := planLockOf(context); err != nil {
+				return err
+			}
+
+			diff, err := diffPlanRevision(context, ref, uii)
+			if err != nil {
+				return err
+			}
+			writePlanRevisionDiff(cmd.OutOrStdout(), diff)
+			if diff.From == diff.To && ref == "" {
+				return nil
+			}
+
+			if !yes {
+				if !stdinIsTerminal() {
+					return errors.NewExitCode(2, "No terminal to confirm the upgrade: pass --yes to upgrade without confirming")
+				}
+				confirmed := false
+				uii.Flush()
+				err := survey.AskOne(&survey.Confirm{Message: fmt.Sprintf("Upgrade plan to %s?", shortCommit(diff.To))}, &confirmed)
+				if err != nil {
+					return err
+				}
+				if !confirmed {
+					return errors.NewExitCode(1, "Plan upgrade cancelled")
+				}
+			}
+
+			plan := context.Config.Plan
+			if ref != "" {
+				base, _, _ := strings.Cut(plan, "#")
+				plan = base + "#" + ref
+				if err := pinPlan(cmd, context, plan); err != nil {
+					return err
+				}
+				uii.Infoln("Pinned plan %s in shuttle.yaml", plan)
+			}
+			if lock != nil {
+				err := config.WritePlanLock(context.ProjectPath, config.PlanLock{Plan: plan, Commit: diff.To})
+				if err != nil {
+					return err
+				}
+				uii.Infoln("Locked plan %s at commit %s", plan, diff.To)
+			}
+			return nil
+		},
+	}
+
+	upgradeCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Upgrade without confirming")
+
+	return upgradeCmd
+}
+
+// isPlanRevisionRequest returns whether shuttle is invoked to compare
+// revisions of the plan.
+func isPlanRevisionRequest(rootCmd *cobra.Command) bool {
+	args := rootCmd.Flags().Args()
+	return len(args) > 1 && args[0] == "plan" && (args[1] == "diff" || args[1] == "upgrade")
+}
+
+// planRevisionContext returns the project context of a git plan. The plan is
+// not pulled, see isPlanRevisionRequest, such that its checked out revision
+// can be compared to another one.
+func planRevisionContext(contextProvider contextProvider) (config.ShuttleProjectContext, error) {
+	context, err := contextProvider()
+	if err != nil {
+		return config.ShuttleProjectContext{}, err
+	}
+	if !git.IsPlan(context.Config.Plan) {
+		return config.ShuttleProjectContext{}, errors.NewExitCode(2, "Cannot compare revisions of plan '%s': only git plans have revisions", context.Config.Plan)
+	}
+	return context, nil
+}
+
+// diffPlanRevision returns the difference between the checked out revision of
+// the plan of context and ref.
+func diffPlanRevision(context config.ShuttleProjectContext, ref string, uii *ui.UI) (planRevisionDiff, error) {
+	plan := context.Config.Plan
+	to, err := git.FetchRevision(context.LocalPlanPath, plan, ref, uii)
+	if err != nil {
+		return planRevisionDiff{}, err
+	}
+	diff := planRevisionDiff{Plan: plan, From: git.Commit(context.LocalPlanPath), To: to}
+	if diff.From == diff.To {
+		return diff, nil
+	}
+
+	diff.Files, err = git.ChangedFilesBetween(context.LocalPlanPath, diff.From, diff.To)
+	if err != nil {
+		return planRevisionDiff{}, errors.NewExitCode(1, "Failed to diff plan %s: %v", plan, err)
+	}
+	dir, err := os.MkdirTemp("", "shuttle-plan-diff")
+	if err != nil {
+		return planRevisionDiff{}, err
+	}
+	defer os.RemoveAll(dir)
+	var revisions [2]config.ShuttlePlanConfiguration
+	for i, commit := range []string{diff.From, diff.To} {
+		revisionPath := filepath.Join(dir, commit)
+		if err := git.ExportRevision(context.LocalPlanPath, commit, revisionPath); err != nil {
+			return planRevisionDiff{}, errors.NewExitCode(1, "Failed to diff plan %s: %v", plan, err)
+		}
+		if _, err := revisions[i].Load(revisionPath); err != nil {
+			return planRevisionDiff{}, err
+		}
+	}
+	diff.Scripts = config.DiffPlans(revisions[0], revisions[1])
+	return diff, nil
+}
+
+// writePlanRevisionDiff writes diff to w with added, removed and changed
+// scripts and arguments marked with +, - and ~.
+func writePlanRevisionDiff(w io.Writer, diff planRevisionDiff) {
+	if diff.From == diff.To {
+		fmt.Fprintf(w, "Plan %s is at %s already\n", diff.Plan, shortCommit(diff.To))
+		return
+	}
+	fmt.Fprintf(w, "Plan %s from %s to %s\n", diff.Plan, shortCommit(diff.From), shortCommit(diff.To))
+
+	breaking := func(isBreaking bool) string {
+		if isBreaking {
+			return " (breaking)"
+		}
+		return ""
+	}
+	scripts := diff.Scripts
+	if !scripts.Empty() {
+		fmt.Fprintf(w, "\nScripts:\n")
+		for _, name := range scripts.Added {
+			fmt.Fprintf(w, "  + %s\n", name)
+		}
+		for _, name := range scripts.Removed {
+			fmt.Fprintf(w, "  - %s (breaking)\n", name)
+		}
+		for _, script := range scripts.Changed {
+			fmt.Fprintf(w, "  ~ %s%s\n", script.Name, breaking(script.Breaking()))
+			for _, arg := range script.AddedArgs {
+				fmt.Fprintf(w, "      + arg %s%s\n", argSignature(arg), breaking(arg.Required && arg.Default == ""))
+			}
+			for _, arg := range script.RemovedArgs {
+				fmt.Fprintf(w, "      - arg %s (breaking)\n", argSignature(arg))
+			}
+			for _, arg := range script.ChangedArgs {
+				fmt.Fprintf(w, "      ~ arg %s -> %s%s\n", argSignature(arg.From), argSignature(arg.To), breaking(arg.Breaking()))
+			}
+			if len(script.Fields) != 0 {
+				fmt.Fprintf(w, "      ~ %s\n", strings.Join(script.Fields, ", "))
+			}
+		}
+	}
+	if len(diff.Files) != 0 {
+		fmt.Fprintf(w, "\nFiles:\n")
+		for _, file := range diff.Files {
+			fmt.Fprintf(w, "  %s %s\n", file.Status, file.Path)
+		}
+	}
+	if scripts.Breaking() {
+		fmt.Fprintf(w, "\nThe plan has breaking changes: check the invocations of the changed scripts before upgrading.\n")
+	}
+}
+
+// argSignature returns the name of arg with whether it is required and its
+// constraints.
+func argSignature(arg config.ShuttleScriptArgs) string {
+	var details []string
+	if arg.Required {
+		details = append(details, "required")
+	}
+	if constraints := arg.Constraints(); constraints != "" {
+		details = append(details, constraints)
+	}
+	if len(details) == 0 {
+		return arg.Name
+	}
+	return fmt.Sprintf("%s (%s)", arg.Name, strings.Join(details, ", "))
+}
+
+// pinPlan replaces the plan of context in shuttle.yaml, or of its active
+// profile if that sets the plan, with plan.
+func pinPlan(cmd *cobra.Command, context config.ShuttleProjectContext, plan string) error {
+	key, raw := "plan", context.Config.PlanRaw
+	if profile, ok := context.Config.Profiles[context.Config.Profile]; ok && profile.PlanRaw != nil {
+		key, raw = fmt.Sprintf("profiles.%s.plan", context.Config.Profile), profile.PlanRaw
+	}
+	if _, ok := raw.([]interface{}); ok {
+		key += ".0"
+	}
+	file, err := openShuttleFile(cmd)
+	if err != nil {
+		return err
+	}
+	if err := file.Set(key, plan, true); err != nil {
+		return err
+	}
+	return file.Write()
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
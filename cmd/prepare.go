@@ -1,24 +1,64 @@
 package cmd
 
 import (
+	"path"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/executors/golang/executer"
+	"github.com/lunarway/shuttle/pkg/executors/golang/shuttlefolder"
 	"github.com/lunarway/shuttle/pkg/ui"
 	"github.com/spf13/cobra"
 )
 
 func newPrepare(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	var target string
+
 	prepareCmd := &cobra.Command{
 		Use:   "prepare",
 		Short: "Load external resources",
-		Long:  `Load external resources as a preparation step, before starting to use shuttle`,
+		Long: `Load external resources as a preparation step, before starting to use shuttle
+
+With --target the golang actions are compiled for the given platform and the
+paths of the binaries are printed, eg. to copy them into a container.`,
+		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			_, err := contextProvider()
+			var buildTarget shuttlefolder.Target
+			if target != "" {
+				var err error
+				buildTarget, err = shuttlefolder.ParseTarget(target)
+				if err != nil {
+					return errors.NewExitCode(2, "Invalid --target: %v", err)
+				}
+			}
+
+			context, err := contextProvider()
 			if err != nil {
 				return err
 			}
+			if target == "" {
+				return nil
+			}
 
+			binaries, err := executer.Build(
+				cmd.Context(),
+				uii,
+				path.Join(context.ProjectPath, "shuttle.yaml"),
+				&context,
+				buildTarget,
+			)
+			if err != nil {
+				return err
+			}
+			for _, binary := range []string{binaries.Local.Path, binaries.Plan.Path} {
+				if binary != "" {
+					uii.Output("%s", binary)
+				}
+			}
 			return nil
 		},
 	}
 
+	prepareCmd.Flags().StringVar(&target, "target", "", "Compile golang actions for a GOOS/GOARCH platform, eg. linux/amd64")
+
 	return prepareCmd
 }
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPrepare(t *testing.T) {
+	testCases := []testCase{
+		{
+			name:      "no target",
+			input:     args("-p", "testdata/project", "prepare"),
+			stdoutput: "",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "invalid target",
+			input:     args("-p", "testdata/project", "prepare", "--target", "linux"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - Invalid --target: target 'linux' is invalid: must be on the form GOOS/GOARCH, eg. linux/amd64\n",
+			err:       errors.New("exit code 2 - Invalid --target: target 'linux' is invalid: must be on the form GOOS/GOARCH, eg. linux/amd64"),
+		},
+	}
+	executeTestCases(t, testCases)
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"encoding/json"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lunarway/shuttle/pkg/executors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func newReport(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Show timing reports written by shuttle run --report timings",
+	}
+	reportCmd.AddCommand(newReportLast(uii, contextProvider))
+	return reportCmd
+}
+
+func newReportLast(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	var asJSON bool
+	lastCmd := &cobra.Command{
+		Use:   "last",
+		Short: "Show the timing report of the latest run written with --report timings",
+		Long: `Show how long the plan, scripts and actions of the latest run written
+with shuttle run --report timings took. Reports are kept in .shuttle/reports.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := contextProvider()
+			if err != nil {
+				return err
+			}
+			report, err := executors.LastTimingReport(executors.TimingReportsDirectory(context.LocalShuttleDirectoryPath))
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				content, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return err
+				}
+				uii.Output("%s", content)
+				return nil
+			}
+			report.Print(uii.Output)
+			return nil
+		},
+	}
+	lastCmd.Flags().BoolVar(&asJSON, "json", false, "Print the report as JSON")
+	return lastCmd
+}
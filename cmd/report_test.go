@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReportLast(t *testing.T) {
+	testCases := []testCase{
+		{
+			name:      "no reports",
+			input:     args("-p", "testdata/project", "report", "last"),
+			stdoutput: "",
+			erroutput: "Error: exit code 1 - No timing reports found: run a script with --report timings to write one\n",
+			err:       errors.New("exit code 1 - No timing reports found: run a script with --report timings to write one"),
+		},
+	}
+	executeTestCases(t, testCases)
+}
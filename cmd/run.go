@@ -2,18 +2,25 @@ package cmd
 
 import (
 	stdcontext "context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"sort"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/iancoleman/strcase"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
 	"github.com/lunarway/shuttle/pkg/config"
+	shuttleerrors "github.com/lunarway/shuttle/pkg/errors"
 	"github.com/lunarway/shuttle/pkg/executors"
+	"github.com/lunarway/shuttle/pkg/git"
+	"github.com/lunarway/shuttle/pkg/telemetry"
 	"github.com/lunarway/shuttle/pkg/ui"
 )
 
@@ -26,31 +33,75 @@ func newNoopRun() *cobra.Command {
 	}
 }
 
-func newNoContextRun() *cobra.Command {
+func newNoContextRun(uii *ui.UI) *cobra.Command {
 	runCmd := newNoopRun()
+	var flags projectsFlags
+	addProjectsFlags(runCmd, &flags)
 
 	runCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if flags.pattern != "" {
+			return runProjectsCommand(cmd, uii, flags, args)
+		}
 		return fmt.Errorf("shuttle run is not available in this context. To use shuttle run you need to be in a project with a shuttle.yaml file")
 	}
 
 	return runCmd
 }
 
+// runProjectsCommand runs the script given as the first of args with the
+// remaining args across projects.
+func runProjectsCommand(cmd *cobra.Command, uii *ui.UI, flags projectsFlags, args []string) error {
+	if len(args) == 0 {
+		return shuttleerrors.NewExitCode(2, "A script to run must be given with --projects")
+	}
+	return runInProjects(cmd, uii, flags, args[0], args[1:])
+}
+
+// runFlags are the flags shared by all scripts of shuttle run
+type runFlags struct {
+	template         string
+	validateArgs     bool
+	interactive      bool
+	noInput          bool
+	requireCleanPlan bool
+	strictCleanPlan  bool
+	cleanTmp         bool
+	rerun            bool
+	confirmRerun     bool
+	detectSecrets    string
+	allowSecrets     []string
+	maskEnv          []string
+	dryRun           bool
+	prefixOutput     bool
+	logFile          string
+	preserveExitCode bool
+	noCache          bool
+	noWait           bool
+	watch            bool
+	watchGlobs       []string
+	watchDebounce    time.Duration
+	projects         projectsFlags
+	matrix           matrixFlags
+	reports          reportFlags
+}
+
 func newRun(uii *ui.UI, contextProvider contextProvider) (*cobra.Command, error) {
-	var (
-		flagTemplate   string
-		validateArgs   bool
-		interactiveArg bool
-	)
+	var flags runFlags
 	shuttleInteractive := os.Getenv("SHUTTLE_INTERACTIVE")
 	var shuttleInteractiveDefault bool
 	if shuttleInteractive == "true" {
 		shuttleInteractiveDefault = true
 	}
 
-	executorRegistry := executors.NewRegistry(executors.ShellExecutor, executors.TaskExecutor)
+	executorRegistry := executors.NewDefaultRegistry()
 
 	runCmd := newNoopRun()
+	runCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if flags.projects.pattern == "" {
+			return cmd.Help()
+		}
+		return runProjectsCommand(cmd, uii, flags.projects, args)
+	}
 
 	context, err := contextProvider()
 	if err != nil {
@@ -66,18 +117,57 @@ func newRun(uii *ui.UI, contextProvider contextProvider) (*cobra.Command, error)
 				script,
 				value,
 				executorRegistry,
-				&interactiveArg,
-				&validateArgs,
+				&flags,
 			),
 		)
 	}
 
 	runCmd.PersistentFlags().
-		StringVar(&flagTemplate, "template", "", "Template string to use. The template format is golang templates [http://golang.org/pkg/text/template/#pkg-overview].")
+		StringVar(&flags.template, "template", "", "Template string to use. The template format is golang templates [http://golang.org/pkg/text/template/#pkg-overview].")
+	runCmd.PersistentFlags().
+		BoolVar(&flags.validateArgs, "validate", true, "Validate arguments against script definition in plan and exit with 1 on unknown or missing arguments")
+	runCmd.PersistentFlags().
+		BoolVar(&flags.requireCleanPlan, "require-clean-plan", false, "Fail if the plan has uncommitted changes. Plans that are not git repositories are only warned about unless --strict-clean-plan is set")
+	runCmd.PersistentFlags().
+		BoolVar(&flags.strictCleanPlan, "strict-clean-plan", false, "Fail --require-clean-plan checks if the plan is not a git repository")
+	runCmd.PersistentFlags().
+		BoolVar(&flags.cleanTmp, "clean-tmp", false, "Remove the temporary directory of each action when it completes unless the action sets keepTmp")
+	runCmd.PersistentFlags().
+		BoolVar(&flags.rerun, "rerun", false, "Mark the run as a re-run of a previous run. Actions that are not idempotent must be confirmed before they are run again")
+	runCmd.PersistentFlags().
+		BoolVar(&flags.confirmRerun, "confirm-rerun", false, "Confirm re-running actions that are not idempotent without prompting")
 	runCmd.PersistentFlags().
-		BoolVar(&validateArgs, "validate", true, "Validate arguments against script definition in plan and exit with 1 on unknown or missing arguments")
+		StringVar(&flags.detectSecrets, "detect-secrets", "", "Suppress output lines that look like secrets and either 'fail' the run or 'warn' about them")
+	runCmd.PersistentFlags().Lookup("detect-secrets").NoOptDefVal = string(executors.SecretDetectionFail)
 	runCmd.PersistentFlags().
-		BoolVar(&interactiveArg, "interactive", shuttleInteractiveDefault, "sets whether to enable ui for getting missing values via. prompt instead of failing immediadly, default is set by [SHUTTLE_INTERACTIVE=true/false]")
+		StringArrayVar(&flags.allowSecrets, "detect-secrets-allow", nil, "Regular expression of values that are not secrets to suppress false positives of --detect-secrets. Can be repeated")
+	runCmd.PersistentFlags().
+		StringArrayVar(&flags.maskEnv, "mask-env", executors.DefaultMaskedEnv, "Pattern of names of environment variables whose values are masked in the output of actions, eg. '*_TOKEN'. Can be repeated and replaces the defaults. Set it to '' to disable masking")
+	runCmd.PersistentFlags().
+		BoolVar(&flags.dryRun, "dry-run", false, "Print the commands of actions with their environment instead of running them. Values that may be secrets are redacted")
+	runCmd.PersistentFlags().
+		BoolVar(&flags.preserveExitCode, "preserve-exit-code", false, "Exit with the exit code of a failing shell action instead of 4")
+	runCmd.PersistentFlags().
+		BoolVar(&flags.watch, "watch", false, "Run the script again whenever files of the project change. A run in progress is stopped when files change")
+	runCmd.PersistentFlags().
+		StringArrayVar(&flags.watchGlobs, "watch-glob", nil, "Project relative glob pattern of files to watch with --watch, eg. '**/*.go'. Can be repeated. Defaults to all files except .git and .shuttle")
+	runCmd.PersistentFlags().
+		DurationVar(&flags.watchDebounce, "watch-debounce", executors.DefaultWatchDebounce, "How long files must be unchanged before the script is run again with --watch")
+	runCmd.PersistentFlags().
+		BoolVar(&flags.noCache, "no-cache", false, "Run scripts with inputs even if their inputs are unchanged since they last succeeded")
+	runCmd.PersistentFlags().
+		BoolVar(&flags.noWait, "no-wait", false, "Fail instead of waiting when the lock of a script is held by another run")
+	runCmd.PersistentFlags().
+		StringVar(&flags.logFile, "log-file", "", "Write the output of shell actions to this file besides the terminal. {script} and {action} are replaced to write a file per action, eg. logs/{script}-{action}.log")
+	runCmd.PersistentFlags().
+		BoolVar(&flags.prefixOutput, "prefix-output", false, "Prefix every output line of shell actions with the script and index of the action, eg. [build/0], or the label of the action")
+	runCmd.PersistentFlags().
+		BoolVar(&flags.interactive, "interactive", shuttleInteractiveDefault, "sets whether to enable ui for getting missing values via. prompt instead of failing immediadly, default is set by [SHUTTLE_INTERACTIVE=true/false]")
+	runCmd.PersistentFlags().
+		BoolVar(&flags.noInput, "no-input", false, "Fail on missing required arguments instead of prompting for them even if stdin is a terminal, eg. in CI")
+	addProjectsFlags(runCmd, &flags.projects)
+	addMatrixFlags(runCmd, &flags.matrix)
+	addReportFlags(runCmd, &flags.reports)
 	return runCmd, nil
 }
 
@@ -87,8 +177,7 @@ func newRunSubCommand(
 	script string,
 	value config.ShuttlePlanScript,
 	executorRegistry *executors.Registry,
-	interactiveArg *bool,
-	validateArgs *bool,
+	flags *runFlags,
 ) *cobra.Command {
 	// Args are best suited as kebab-case on the command line
 	argName := func(input string) string {
@@ -114,28 +203,14 @@ func newRunSubCommand(
 		}
 	}
 
-	// In case interactive is turned on and arg is missing, we ask for missing values
+	// In case interactive is turned on and arg is missing, we ask for missing
+	// values. Enum arguments are picked from their values.
 	createPrompt := func(inputArgs map[string]*string, arg config.ShuttleScriptArgs) (string, error) {
-		prompt := []*survey.Question{
-			{
-				Name: argName(arg.Name),
-				Prompt: &survey.Input{
-					Message: argName(arg.Name),
-					Default: *inputArgs[arg.Name],
-					Help:    arg.Description,
-				},
-			},
-		}
-		if arg.Required {
-			prompt[0].Validate = survey.Required
-		}
-		var output string
-		err := survey.Ask(prompt, &output)
-		if err != nil {
-			return "", err
+		defaultValue := *inputArgs[arg.Name]
+		if defaultValue == "" {
+			defaultValue = arg.Default
 		}
-
-		return output, nil
+		return promptValue(uii, argName(arg.Name), arg, defaultValue, context.ProjectPath)
 	}
 
 	// Decide whether to fall back on prompt or give a hard error
@@ -147,7 +222,7 @@ func newRunSubCommand(
 
 			arg := arg
 
-			if *inputArgs[arg.Name] == "" && *interactiveArg {
+			if *inputArgs[arg.Name] == "" && promptForArgs(flags, stdinIsTerminal()) {
 				output, err := createPrompt(inputArgs, arg)
 				if err != nil {
 					return err
@@ -156,11 +231,19 @@ func newRunSubCommand(
 					inputArgs[arg.Name] = &output
 				}
 
-			} else if *inputArgs[arg.Name] == "" && arg.Required && *validateArgs {
+			} else if *inputArgs[arg.Name] == "" && arg.Required && flags.validateArgs {
 				return fmt.Errorf("required flag(s) \"%s\" not set", argName(arg.Name))
 			}
 		}
 
+		if flags.validateArgs {
+			args := make(map[string]string, len(inputArgs))
+			for k, v := range inputArgs {
+				args[k] = *v
+			}
+			return value.ValidateExclusiveArgs(script, args)
+		}
+
 		return nil
 	}
 
@@ -182,7 +265,7 @@ func newRunSubCommand(
 		Long:         value.Description,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if *interactiveArg {
+			if flags.interactive {
 				uii.Verboseln("Running using interactive mode!")
 			}
 
@@ -191,10 +274,29 @@ func newRunSubCommand(
 			defer traceEnd()
 
 			applyLegacyArgs(args, inputArgs)
+			if flags.projects.pattern != "" {
+				// arguments are validated by each project as scripts may differ
+				return runInProjects(cmd, uii, flags.projects, script, projectScriptArgs(inputArgs))
+			}
+			if !flags.matrix.disabled {
+				dimensions, err := parseMatrix(script, value, flags.matrix.values)
+				if err != nil {
+					return err
+				}
+				if len(dimensions) > 0 {
+					return runInMatrix(cmd, uii, context, flags.matrix, flags.projects.keepGoing, script, dimensions, inputArgs)
+				}
+			}
 			if err := validateInputArgs(value, inputArgs); err != nil {
 				return err
 			}
 
+			if flags.requireCleanPlan {
+				if err := checkCleanPlan(uii, context, flags.strictCleanPlan); err != nil {
+					return err
+				}
+			}
+
 			ctx, cancel := withSignal(ctx, uii)
 			defer cancel()
 			actualArgs := make(map[string]string, len(inputArgs))
@@ -202,7 +304,112 @@ func newRunSubCommand(
 				actualArgs[k] = *v
 			}
 
-			err := executorRegistry.Execute(ctx, context, script, actualArgs, *validateArgs)
+			secretDetection, err := executors.ParseSecretDetection(flags.detectSecrets, flags.allowSecrets)
+			if err != nil {
+				return err
+			}
+			maskedEnv, err := executors.ParseMaskedEnv(flags.maskEnv)
+			if err != nil {
+				return err
+			}
+			reports, err := parseReports(flags.reports)
+			if err != nil {
+				return err
+			}
+			options := []executors.ExecuteOption{
+				executors.WithCleanTmp(flags.cleanTmp),
+				executors.WithSecretDetection(secretDetection),
+				executors.WithMaskedEnv(maskedEnv),
+				executors.WithInteractive(flags.interactive),
+				executors.WithDryRun(flags.dryRun),
+				executors.WithStdin(os.Stdin),
+				executors.WithOutputPrefix(flags.prefixOutput),
+				executors.WithLogFile(flags.logFile),
+				executors.WithPreserveExitCode(flags.preserveExitCode),
+				executors.WithNoCache(flags.noCache),
+				executors.WithNoWaitLock(flags.noWait),
+				executors.WithRunMetadata(executors.RunMetadata{
+					ShuttleVersion: version,
+					PlanRevision:   planRevision(context),
+				}),
+			}
+			if flags.rerun {
+				options = append(options, executors.WithRerun(confirmRerun(uii, flags)))
+			}
+			var summary executors.RunSummary
+			if reports.junit != "" {
+				options = append(options, executors.WithSummary(&summary))
+			}
+
+			runScript := func(ctx stdcontext.Context) error {
+				start := time.Now()
+				runOptions := options
+				if !flags.dryRun && !context.Config.RunLogs.Disabled {
+					runLog, err := executors.CreateRunLog(executors.RunLogsDirectory(context.LocalShuttleDirectoryPath), script, start, context.Config.RunLogs)
+					if err != nil {
+						return err
+					}
+					defer func() {
+						if closeErr := runLog.Close(); closeErr != nil {
+							uii.Errorln("Failed to write run log %s: %v", runLog.Path, closeErr)
+						}
+					}()
+					runOptions = append(append([]executors.ExecuteOption{}, options...), executors.WithRunLog(runLog))
+				}
+				err := executorRegistry.Run(
+					ctx,
+					context,
+					script,
+					actualArgs,
+					flags.validateArgs,
+					runOptions...,
+				)
+				if reports.junit != "" {
+					if reportErr := executors.WriteJUnitFile(reports.junit, summary); reportErr != nil {
+						if err == nil {
+							err = shuttleerrors.NewExitCode(1, "Failed to write JUnit report: %v", reportErr)
+						} else {
+							uii.Errorln("Failed to write JUnit report: %v", reportErr)
+						}
+					}
+				}
+				// timings are taken on every run to not accumulate them while
+				// watching
+				timings := telemetry.TimingsFrom(ctx).Take()
+				if flags.reports.timings || reports.timings {
+					report := executors.NewTimingReport(script, telemetry.RunIDFrom(ctx), context.ProjectPath, timings, start, err)
+					if flags.reports.timings {
+						report.Print(uii.Infoln)
+					}
+					if reports.timings {
+						if reportErr := executors.WriteTimingReport(executors.TimingReportsDirectory(context.LocalShuttleDirectoryPath), report); reportErr != nil {
+							if err == nil {
+								err = shuttleerrors.NewExitCode(1, "Failed to write timing report: %v", reportErr)
+							} else {
+								uii.Errorln("Failed to write timing report: %v", reportErr)
+							}
+						}
+					}
+				}
+				if !flags.dryRun && !context.Config.History.Disabled {
+					recordRun(ctx, uii, context, script, actualArgs, start, err)
+				}
+				return err
+			}
+			if flags.watch {
+				watcher, err := executors.NewWatcher(uii, context.ProjectPath, flags.watchGlobs, flags.watchDebounce)
+				if err != nil {
+					return err
+				}
+				watcher.Ignore(executors.ScriptWrittenFiles(context.Scripts[script])...)
+				err = watcher.Run(ctx, fmt.Sprintf("script `%s`", script), runScript)
+				if err != nil {
+					traceError(err)
+					return err
+				}
+				return nil
+			}
+			err = runScript(ctx)
 			if err != nil {
 				traceError(err)
 				return err
@@ -212,41 +419,206 @@ func newRunSubCommand(
 		},
 	}
 
-	if !*validateArgs {
+	if !flags.validateArgs {
 		cmd.Args = cobra.ArbitraryArgs
 	}
 
+	// A help template allows tools like shell completion to inspect the
+	// arguments of a script
+	defaultHelp := cmd.HelpFunc()
+	cmd.SetHelpFunc(func(c *cobra.Command, args []string) {
+		if flags.template == "" {
+			defaultHelp(c, args)
+			return
+		}
+		err := executors.Help(context.Scripts, script, c.OutOrStdout(), flags.template)
+		if err != nil {
+			uii.Errorln("Failed to render help: %v", err)
+		}
+	})
+
+	cmd.ValidArgsFunction = completeScriptArgs(value.Args)
 	for _, arg := range value.Args {
 		arg := arg
-		cmd.Flags().StringVar(inputArgs[arg.Name], argName(arg.Name), "", arg.Description)
+		cmd.Flags().StringVar(inputArgs[arg.Name], argName(arg.Name), "", argUsage(arg))
+		cmd.RegisterFlagCompletionFunc(argName(arg.Name), completeArgFlag(arg))
 	}
 
 	return cmd
 }
 
+// promptForArgs returns true if missing required arguments are prompted for,
+// ie. with --interactive or if stdin is a terminal unless --no-input is set.
+func promptForArgs(flags *runFlags, stdinIsTerminal bool) bool {
+	if flags.noInput {
+		return false
+	}
+	return flags.interactive || stdinIsTerminal
+}
+
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// promptValue prompts for the value of arg validated by its type. Enum
+// arguments are picked from their values. Relative paths are resolved from
+// projectPath.
+func promptValue(uii *ui.UI, message string, arg config.ShuttleScriptArgs, defaultValue string, projectPath string) (string, error) {
+	question := &survey.Question{Name: message}
+	if arg.Type == config.ArgTypeEnum {
+		picker := &survey.Select{
+			Message: message,
+			Options: arg.Values,
+			Help:    arg.Description,
+		}
+		if defaultValue != "" {
+			picker.Default = defaultValue
+		}
+		question.Prompt = picker
+	} else {
+		question.Prompt = &survey.Input{
+			Message: message,
+			Default: defaultValue,
+			Help:    arg.Description,
+		}
+		question.Validate = func(answer interface{}) error {
+			value, _ := answer.(string)
+			if value == "" {
+				if arg.Required {
+					return survey.Required(answer)
+				}
+				return nil
+			}
+			if problem := arg.ValueProblem(value, projectPath); problem != "" {
+				return errors.New(problem)
+			}
+			return nil
+		}
+	}
+	var output string
+	uii.Flush()
+	err := survey.Ask([]*survey.Question{question}, &output)
+	if err != nil {
+		return "", err
+	}
+	return output, nil
+}
+
+// argUsage returns the usage of the flag of arg, ie. its description followed
+// by its type, default and pattern if any.
+func argUsage(arg config.ShuttleScriptArgs) string {
+	constraints := arg.Constraints()
+	switch {
+	case constraints == "":
+		return arg.Description
+	case arg.Description == "":
+		return fmt.Sprintf("(%s)", constraints)
+	default:
+		return fmt.Sprintf("%s (%s)", arg.Description, constraints)
+	}
+}
+
+// projectScriptArgs returns the set arguments of inputArgs on the form
+// <argument>=<value> in a stable order.
+func projectScriptArgs(inputArgs map[string]*string) []string {
+	var args []string
+	for name, value := range inputArgs {
+		if *value == "" {
+			continue
+		}
+		args = append(args, fmt.Sprintf("%s=%s", name, *value))
+	}
+	sort.Strings(args)
+	return args
+}
+
+// confirmRerun returns a confirmer for re-running actions that are not
+// idempotent. Re-runs are confirmed by --confirm-rerun or by prompting in
+// interactive mode.
+func confirmRerun(uii *ui.UI, flags *runFlags) executors.RerunConfirmer {
+	return func(context executors.ActionExecutionContext) (bool, error) {
+		if flags.confirmRerun {
+			return true, nil
+		}
+		if !flags.interactive {
+			return false, nil
+		}
+		confirmed := false
+		uii.Flush()
+		err := survey.AskOne(&survey.Confirm{
+			Message: fmt.Sprintf(
+				"Action %d of script '%s' is not idempotent. Run it again?",
+				context.ActionIndex,
+				context.ScriptContext.ScriptName,
+			),
+		}, &confirmed)
+		if err != nil {
+			return false, err
+		}
+		return confirmed, nil
+	}
+}
+
+// checkCleanPlan returns an error if the plan of the project has uncommitted
+// changes. Plans that are not git repositories, eg. local plans, are only
+// reported as warnings unless strict is set.
+func checkCleanPlan(uii *ui.UI, context config.ShuttleProjectContext, strict bool) error {
+	if context.LocalPlanPath == "" {
+		return nil
+	}
+
+	files, err := git.ChangedFiles(context.LocalPlanPath)
+	if errors.Is(err, git.ErrNotRepository) {
+		if strict {
+			return shuttleerrors.NewExitCode(
+				2,
+				"Plan at '%s' is not a git repository so it cannot be verified to be clean",
+				context.LocalPlanPath,
+			)
+		}
+		uii.EmphasizeInfoln("Plan at '%s' is not a git repository. Skipping clean plan check", context.LocalPlanPath)
+		return nil
+	}
+	if err != nil {
+		return shuttleerrors.NewExitCode(2, "Failed to check plan for uncommitted changes: %v", err)
+	}
+
+	if len(files) != 0 {
+		var s strings.Builder
+		fmt.Fprintf(&s, "Plan at '%s' has uncommitted changes:\n", context.LocalPlanPath)
+		for _, file := range files {
+			fmt.Fprintf(&s, "  %s\n", file)
+		}
+		s.WriteString("\nCommit or revert the changes or run without --require-clean-plan.")
+		return shuttleerrors.NewExitCode(2, "%s", s.String())
+	}
+	return nil
+}
+
 // withSignal returns a copy of parent with a new Done channel. The returned
 // context's Done channel is closed when the returned cancel function is called,
-// if the parent context's Done channel is closed, if a SIGINT signal is
-// catched, whichever happens first.
+// if the parent context's Done channel is closed, if a SIGINT or SIGTERM signal
+// is catched, whichever happens first. Contexts cancelled by a signal have a
+// cause matching shuttleerrors.ErrInterrupted which carries the signal.
 //
 // Canceling this context releases resources associated with it, so code should
 // call cancel as soon as the operations running in this Context complete.
 func withSignal(parent stdcontext.Context, uii *ui.UI) (stdcontext.Context, func()) {
-	parent, cancel := stdcontext.WithCancel(parent)
+	parent, cancel := stdcontext.WithCancelCause(parent)
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
 		select {
 		case s := <-c:
 			uii.Infoln("Received %v signal...", s)
-			cancel()
+			cancel(shuttleerrors.NewInterrupt(s))
 		case <-parent.Done():
 		}
 	}()
 
 	return parent, func() {
 		signal.Stop(c)
-		cancel()
+		cancel(nil)
 	}
 }
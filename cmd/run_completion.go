@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"github.com/spf13/cobra"
+
+	"github.com/lunarway/shuttle/pkg/config"
+)
+
+// isCompletionRequest returns true if args request shell completions, ie. the
+// hidden command the completion scripts call on <TAB>.
+func isCompletionRequest(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	return args[0] == cobra.ShellCompRequestCmd || args[0] == cobra.ShellCompNoDescRequestCmd
+}
+
+// completeScriptArgs completes the legacy <argument>=<value> arguments of a
+// script. Arguments already given are left out and values are suggested for
+// enums and booleans once the name of an argument is typed.
+func completeScriptArgs(scriptArgs []config.ShuttleScriptArgs) func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if name, _, ok := strings.Cut(toComplete, "="); ok {
+			for _, arg := range scriptArgs {
+				if arg.Name != name {
+					continue
+				}
+				var completions []string
+				for _, value := range argValues(arg) {
+					completions = append(completions, fmt.Sprintf("%s=%s", name, value))
+				}
+				return completions, cobra.ShellCompDirectiveNoFileComp
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		given := make(map[string]bool)
+		for _, arg := range args {
+			name, _, _ := strings.Cut(arg, "=")
+			given[name] = true
+		}
+		var completions []string
+		for _, arg := range scriptArgs {
+			if given[arg.Name] || cmd.Flags().Changed(strcase.ToKebab(arg.Name)) {
+				continue
+			}
+			completion := arg.Name + "="
+			if usage := argUsage(arg); usage != "" {
+				completion += "\t" + usage
+			}
+			completions = append(completions, completion)
+		}
+		return completions, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeArgFlag completes the value of the flag of arg. Paths are completed
+// as files by the shell.
+func completeArgFlag(arg config.ShuttleScriptArgs) func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if arg.Type == config.ArgTypePath {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		return argValues(arg), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// argValues returns the values arg accepts if they can be listed.
+func argValues(arg config.ShuttleScriptArgs) []string {
+	switch arg.Type {
+	case config.ArgTypeEnum:
+		return arg.Values
+	case config.ArgTypeBool:
+		return []string{"true", "false"}
+	default:
+		return nil
+	}
+}
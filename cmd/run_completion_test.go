@@ -0,0 +1,51 @@
+package cmd
+
+import "testing"
+
+func TestRunCompletion(t *testing.T) {
+	testCases := []testCase{
+		{
+			name:      "scripts with descriptions",
+			input:     args("-p", "testdata/typed-args", "__complete", "run", ""),
+			stdoutput: "deploy\tDeploy the service\n:4\n",
+			erroutput: "Completion ended with directive: ShellCompDirectiveNoFileComp\n",
+		},
+		{
+			name:  "argument names",
+			input: args("-p", "testdata/typed-args", "__complete", "run", "deploy", ""),
+			stdoutput: `env=	Environment to deploy to (enum: dev|prod, default: dev)
+replicas=	(int)
+tag=	(pattern: ^v[0-9]+$)
+:6
+`,
+			erroutput: "Completion ended with directive: ShellCompDirectiveNoSpace, ShellCompDirectiveNoFileComp\n",
+		},
+		{
+			name:  "given arguments are left out",
+			input: args("-p", "testdata/typed-args", "__complete", "run", "deploy", "env=dev", "--replicas", "2", ""),
+			stdoutput: `tag=	(pattern: ^v[0-9]+$)
+:6
+`,
+			erroutput: "Completion ended with directive: ShellCompDirectiveNoSpace, ShellCompDirectiveNoFileComp\n",
+		},
+		{
+			name:      "enum values of legacy argument",
+			input:     args("-p", "testdata/typed-args", "__complete", "run", "deploy", "env="),
+			stdoutput: "env=dev\nenv=prod\n:4\n",
+			erroutput: "Completion ended with directive: ShellCompDirectiveNoFileComp\n",
+		},
+		{
+			name:      "enum values of flag",
+			input:     args("-p", "testdata/typed-args", "__complete", "run", "deploy", "--env", ""),
+			stdoutput: "dev\nprod\n:4\n",
+			erroutput: "Completion ended with directive: ShellCompDirectiveNoFileComp\n",
+		},
+		{
+			name:      "no values of int flag",
+			input:     args("-p", "testdata/typed-args", "__complete", "run", "deploy", "--replicas", ""),
+			stdoutput: ":4\n",
+			erroutput: "Completion ended with directive: ShellCompDirectiveNoFileComp\n",
+		},
+	}
+	executeTestCases(t, testCases)
+}
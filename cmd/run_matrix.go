@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	stdcontext "context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	shuttleerrors "github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// matrixFlags configure running a script for combinations of argument values
+type matrixFlags struct {
+	values      []string
+	concurrency int
+	disabled    bool
+}
+
+// addMatrixFlags adds the flags for running a script across a matrix of
+// arguments to runCmd.
+func addMatrixFlags(runCmd *cobra.Command, flags *matrixFlags) {
+	runCmd.PersistentFlags().
+		StringArrayVar(&flags.values, "matrix", nil, "Run the script for every value of an argument on the form <argument>=<value>,<value>, eg. env=dev,prod. Can be repeated to run every combination and replaces the matrix of the script for the argument")
+	runCmd.PersistentFlags().
+		IntVar(&flags.concurrency, "matrix-concurrency", 1, "Number of matrix entries the script is run with at the same time")
+	runCmd.PersistentFlags().
+		BoolVar(&flags.disabled, "no-matrix", false, "Run the script once ignoring its matrix")
+}
+
+// matrixDimension is an argument of a script and the values it is run with
+type matrixDimension struct {
+	name   string
+	values []string
+}
+
+// matrixEntry is a combination of argument values of a matrix
+type matrixEntry struct {
+	// label is the arguments separated by spaces, eg. env=dev region=eu
+	label string
+	// args are the arguments on the form <argument>=<value>
+	args []string
+}
+
+// parseMatrix returns the dimensions of the matrix of the script with those
+// of flags replacing the ones of the script for the same argument. Dimensions
+// of the script are ordered by argument followed by those of flags in order.
+func parseMatrix(script string, value config.ShuttlePlanScript, flags []string) ([]matrixDimension, error) {
+	var fromFlags []matrixDimension
+	replaced := make(map[string]bool)
+	for _, flag := range flags {
+		name, rawValues, ok := strings.Cut(flag, "=")
+		values := splitMatrixValues(rawValues)
+		if !ok || name == "" || len(values) == 0 {
+			return nil, shuttleerrors.NewExitCode(2, "Matrix '%s' is invalid: must be on the form <argument>=<value>,<value>", flag)
+		}
+		if replaced[name] {
+			return nil, shuttleerrors.NewExitCode(2, "Matrix argument '%s' is given more than once", name)
+		}
+		replaced[name] = true
+		fromFlags = append(fromFlags, matrixDimension{name: name, values: values})
+	}
+
+	names := make([]string, 0, len(value.Matrix))
+	for name := range value.Matrix {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var dimensions []matrixDimension
+	for _, name := range names {
+		if replaced[name] {
+			continue
+		}
+		if len(value.Matrix[name]) == 0 {
+			return nil, shuttleerrors.NewExitCode(2, "Matrix of script '%s' is invalid: argument '%s' has no values", script, name)
+		}
+		dimensions = append(dimensions, matrixDimension{name: name, values: value.Matrix[name]})
+	}
+	return append(dimensions, fromFlags...), nil
+}
+
+// splitMatrixValues returns the comma separated values of raw without empty
+// values.
+func splitMatrixValues(raw string) []string {
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// matrixEntries returns every combination of the values of dimensions. The
+// values of the last dimension vary the fastest.
+func matrixEntries(dimensions []matrixDimension) []matrixEntry {
+	entries := []matrixEntry{{}}
+	for _, dimension := range dimensions {
+		var next []matrixEntry
+		for _, entry := range entries {
+			for _, value := range dimension.values {
+				arg := fmt.Sprintf("%s=%s", dimension.name, value)
+				args := append(append([]string(nil), entry.args...), arg)
+				next = append(next, matrixEntry{label: strings.Join(args, " "), args: args})
+			}
+		}
+		entries = next
+	}
+	return entries
+}
+
+// matrixRunner runs a script with the arguments of a matrix entry writing its
+// output to stdout and stderr.
+type matrixRunner func(ctx stdcontext.Context, args []string, stdout, stderr io.Writer) error
+
+// runInMatrix runs script for every entry of the matrix of dimensions by
+// invoking shuttle for each of them. Flags of the current invocation are
+// forwarded and inputArgs are passed along with the arguments of the entry.
+func runInMatrix(
+	cmd *cobra.Command,
+	uii *ui.UI,
+	context config.ShuttleProjectContext,
+	flags matrixFlags,
+	keepGoing bool,
+	script string,
+	dimensions []matrixDimension,
+	inputArgs map[string]*string,
+) error {
+	if flag := junitFlag(cmd); flag != "" {
+		return shuttleerrors.NewExitCode(2, "%s cannot be used with a matrix", flag)
+	}
+	if cmd.Flags().Changed("watch") {
+		return shuttleerrors.NewExitCode(2, "--watch cannot be used with a matrix")
+	}
+	for _, dimension := range dimensions {
+		if value, ok := inputArgs[dimension.name]; ok && *value != "" {
+			return shuttleerrors.NewExitCode(2, "Argument '%s' cannot be given as it is set by the matrix", dimension.name)
+		}
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locate shuttle executable: %w", err)
+	}
+	projectPath, err := filepath.Abs(context.ProjectPath)
+	if err != nil {
+		return fmt.Errorf("resolve project path: %w", err)
+	}
+	forwarded := forwardedFlags(cmd)
+	scriptArgs := projectScriptArgs(inputArgs)
+	run := func(ctx stdcontext.Context, args []string, stdout, stderr io.Writer) error {
+		// the entries run the script once each
+		runArgs := append([]string{script, "--no-matrix"}, scriptArgs...)
+		runArgs = append(runArgs, args...)
+		return runShuttle(ctx, executable, projectPath, forwarded, runArgs, stdout, stderr)
+	}
+
+	ctx, cancel := withSignal(cmd.Context(), uii)
+	defer cancel()
+	return runMatrix(ctx, uii, script, matrixEntries(dimensions), flags.concurrency, keepGoing, run)
+}
+
+// runMatrix runs script for every entry with at most concurrency entries at a
+// time. Output is prefixed by the arguments of the entry. Unless keepGoing is
+// set no new entries are started once an entry failed. A summary of all
+// entries is printed once they complete.
+func runMatrix(
+	ctx stdcontext.Context,
+	uii *ui.UI,
+	script string,
+	entries []matrixEntry,
+	concurrency int,
+	keepGoing bool,
+	run matrixRunner,
+) error {
+	if concurrency < 1 {
+		return shuttleerrors.NewExitCode(2, "--matrix-concurrency must be at least 1 but was %d", concurrency)
+	}
+	labels := make([]string, len(entries))
+	args := make(map[string][]string, len(entries))
+	for i, entry := range entries {
+		labels[i] = entry.label
+		args[entry.label] = entry.args
+	}
+	kind := fanOut{noun: "matrix entries", title: "with", prefixed: true}
+	return runFanOut(ctx, uii, script, kind, labels, concurrency, keepGoing, func(ctx stdcontext.Context, label string, stdout, stderr io.Writer) error {
+		return run(ctx, args[label], stdout, stderr)
+	})
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bytes"
+	stdcontext "context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	shuttleerrors "github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestParseMatrix(t *testing.T) {
+	tt := []struct {
+		name       string
+		matrix     map[string][]string
+		flags      []string
+		dimensions []matrixDimension
+		err        string
+	}{
+		{
+			name: "no matrix",
+		},
+		{
+			name:   "script matrix",
+			matrix: map[string][]string{"region": {"eu", "us"}, "env": {"dev"}},
+			dimensions: []matrixDimension{
+				{name: "env", values: []string{"dev"}},
+				{name: "region", values: []string{"eu", "us"}},
+			},
+		},
+		{
+			name:   "flags replace script matrix",
+			matrix: map[string][]string{"region": {"eu", "us"}, "env": {"dev"}},
+			flags:  []string{"env=staging, prod", "tier=web"},
+			dimensions: []matrixDimension{
+				{name: "region", values: []string{"eu", "us"}},
+				{name: "env", values: []string{"staging", "prod"}},
+				{name: "tier", values: []string{"web"}},
+			},
+		},
+		{
+			name:  "flag without values",
+			flags: []string{"env="},
+			err:   "exit code 2 - Matrix 'env=' is invalid: must be on the form <argument>=<value>,<value>",
+		},
+		{
+			name:  "flag without argument",
+			flags: []string{"dev,prod"},
+			err:   "exit code 2 - Matrix 'dev,prod' is invalid: must be on the form <argument>=<value>,<value>",
+		},
+		{
+			name:  "repeated argument",
+			flags: []string{"env=dev", "env=prod"},
+			err:   "exit code 2 - Matrix argument 'env' is given more than once",
+		},
+		{
+			name:   "script matrix without values",
+			matrix: map[string][]string{"env": {}},
+			err:    "exit code 2 - Matrix of script 'deploy' is invalid: argument 'env' has no values",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			dimensions, err := parseMatrix("deploy", config.ShuttlePlanScript{Matrix: tc.matrix}, tc.flags)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.dimensions, dimensions)
+		})
+	}
+}
+
+func TestMatrixEntries(t *testing.T) {
+	entries := matrixEntries([]matrixDimension{
+		{name: "env", values: []string{"dev", "prod"}},
+		{name: "region", values: []string{"eu", "us"}},
+	})
+
+	assert.Equal(t, []matrixEntry{
+		{label: "env=dev region=eu", args: []string{"env=dev", "region=eu"}},
+		{label: "env=dev region=us", args: []string{"env=dev", "region=us"}},
+		{label: "env=prod region=eu", args: []string{"env=prod", "region=eu"}},
+		{label: "env=prod region=us", args: []string{"env=prod", "region=us"}},
+	}, entries)
+}
+
+func TestRunMatrix(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	entries := matrixEntries([]matrixDimension{{name: "env", values: []string{"dev", "staging", "prod"}}})
+	run := func(_ stdcontext.Context, args []string, out, _ io.Writer) error {
+		fmt.Fprintf(out, "deploying %s\n", strings.Join(args, " "))
+		if args[0] == "env=staging" {
+			return shuttleerrors.NewExitCode(4, "shuttle exited with code 4")
+		}
+		return nil
+	}
+
+	err := runMatrix(stdcontext.Background(), ui.Create(&stdout, &stderr), "deploy", entries, 2, true, run)
+
+	assert.EqualError(t, err, "exit code 4 - Script 'deploy' failed in 1 of 3 matrix entries: env=staging")
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	assert.ElementsMatch(t, []string{
+		"[env=dev] deploying env=dev",
+		"[env=staging] deploying env=staging",
+		"[env=prod] deploying env=prod",
+	}, lines)
+	for _, line := range []string{
+		"Script 'deploy' in 3 matrix entries:",
+		"  env=dev: passed\n",
+		"  env=staging: failed: exit code 4 - shuttle exited with code 4\n",
+		"  env=prod: passed\n",
+	} {
+		assert.Contains(t, stderr.String(), line)
+	}
+}
+
+func TestRunMatrix_invalidConcurrency(t *testing.T) {
+	err := runMatrix(stdcontext.Background(), ui.Create(io.Discard, io.Discard), "deploy", nil, 0, false, nil)
+
+	assert.EqualError(t, err, "exit code 2 - --matrix-concurrency must be at least 1 but was 0")
+}
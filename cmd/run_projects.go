@@ -0,0 +1,349 @@
+package cmd
+
+import (
+	"bytes"
+	stdcontext "context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	shuttleerrors "github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// projectsFlags configure running a script across multiple projects
+type projectsFlags struct {
+	pattern     string
+	concurrency int
+	keepGoing   bool
+}
+
+// addProjectsFlags adds the flags for running a script across multiple
+// projects to runCmd.
+func addProjectsFlags(runCmd *cobra.Command, flags *projectsFlags) {
+	runCmd.PersistentFlags().
+		StringVar(&flags.pattern, "projects", "", "Run the script in every project matching this glob pattern, eg. 'services/*', instead of the current project")
+	runCmd.PersistentFlags().
+		IntVar(&flags.concurrency, "projects-concurrency", 1, "Number of projects the script is run in at the same time with --projects")
+	runCmd.PersistentFlags().
+		BoolVar(&flags.keepGoing, "keep-going", false, "Continue running the script in the remaining projects or matrix entries after one failed with --projects or a matrix")
+}
+
+// projectRunner runs a script in project writing its output to stdout and
+// stderr.
+type projectRunner func(ctx stdcontext.Context, project string, stdout, stderr io.Writer) error
+
+// Statuses of a script run in a project
+const (
+	projectStatusPassed  = "passed"
+	projectStatusFailed  = "failed"
+	projectStatusSkipped = "skipped"
+)
+
+type projectResult struct {
+	project string
+	status  string
+	err     error
+}
+
+// runInProjects runs script in all projects matching the --projects pattern
+// by invoking shuttle for each of them. Each invocation resolves its own
+// project, plan and environment. Flags of the current invocation are
+// forwarded except those selecting projects.
+func runInProjects(cmd *cobra.Command, uii *ui.UI, flags projectsFlags, script string, scriptArgs []string) error {
+	projects, err := matchProjects(flags.pattern)
+	if err != nil {
+		return err
+	}
+	if flag := junitFlag(cmd); flag != "" {
+		return shuttleerrors.NewExitCode(2, "%s cannot be used with --projects", flag)
+	}
+	if cmd.Flags().Changed("watch") {
+		return shuttleerrors.NewExitCode(2, "--watch cannot be used with --projects")
+	}
+	if cmd.Flags().Changed("matrix") {
+		return shuttleerrors.NewExitCode(2, "--matrix cannot be used with --projects")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locate shuttle executable: %w", err)
+	}
+	forwarded := forwardedFlags(cmd)
+	run := func(ctx stdcontext.Context, project string, stdout, stderr io.Writer) error {
+		projectPath, err := filepath.Abs(project)
+		if err != nil {
+			return fmt.Errorf("resolve project path: %w", err)
+		}
+		runArgs := append([]string{script}, scriptArgs...)
+		return runShuttle(ctx, executable, projectPath, forwarded, runArgs, stdout, stderr)
+	}
+
+	ctx, cancel := withSignal(cmd.Context(), uii)
+	defer cancel()
+	return runProjects(ctx, uii, script, projects, flags.concurrency, flags.keepGoing, run)
+}
+
+// runShuttle invokes shuttle in the project at projectPath with flags and
+// runArgs given to shuttle run, ie. the script and its arguments.
+func runShuttle(
+	ctx stdcontext.Context,
+	executable, projectPath string,
+	flags, runArgs []string,
+	stdout, stderr io.Writer,
+) error {
+	args := append([]string{"--project", projectPath}, flags...)
+	args = append(args, "run")
+	args = append(args, runArgs...)
+	child := exec.CommandContext(ctx, executable, args...)
+	child.Dir = projectPath
+	child.Env = os.Environ()
+	child.Stdout = stdout
+	child.Stderr = stderr
+	// let shuttle in the project stop its actions gracefully
+	child.Cancel = func() error {
+		return child.Process.Signal(os.Interrupt)
+	}
+	err := child.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return shuttleerrors.NewExitCode(exitErr.ExitCode(), "shuttle exited with code %d", exitErr.ExitCode())
+	}
+	return err
+}
+
+// matchProjects returns the directories matching pattern that contain a
+// shuttle.yaml file in lexical order.
+func matchProjects(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, shuttleerrors.NewExitCode(2, "Projects pattern '%s' is invalid: %v", pattern, err)
+	}
+	var projects []string
+	for _, match := range matches {
+		if fileExists(filepath.Join(match, "shuttle.yaml")) {
+			projects = append(projects, match)
+		}
+	}
+	if len(projects) == 0 {
+		return nil, shuttleerrors.NewExitCode(2, "No projects with a shuttle.yaml file match '%s'", pattern)
+	}
+	sort.Strings(projects)
+	return projects, nil
+}
+
+// forwardedFlags returns the changed flags of cmd shared by all scripts, eg.
+// --verbose and --skip-pull, on the form --name=value.
+func forwardedFlags(cmd *cobra.Command) []string {
+	skip := map[string]bool{
+		"project":              true,
+		"projects":             true,
+		"projects-concurrency": true,
+		"keep-going":           true,
+		"matrix":               true,
+		"matrix-concurrency":   true,
+		"no-matrix":            true,
+		"help":                 true,
+	}
+	local := cmd.LocalNonPersistentFlags()
+	var forwarded []string
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if skip[flag.Name] || local.Lookup(flag.Name) != nil {
+			return
+		}
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			for _, value := range slice.GetSlice() {
+				forwarded = append(forwarded, fmt.Sprintf("--%s=%s", flag.Name, value))
+			}
+			return
+		}
+		forwarded = append(forwarded, fmt.Sprintf("--%s=%s", flag.Name, flag.Value.String()))
+	})
+	return forwarded
+}
+
+// runProjects runs script in projects with at most concurrency projects at a
+// time. Unless keepGoing is set no new projects are started once a project
+// failed. A summary of all projects is printed once they complete.
+func runProjects(
+	ctx stdcontext.Context,
+	uii *ui.UI,
+	script string,
+	projects []string,
+	concurrency int,
+	keepGoing bool,
+	run projectRunner,
+) error {
+	if concurrency < 1 {
+		return shuttleerrors.NewExitCode(2, "--projects-concurrency must be at least 1 but was %d", concurrency)
+	}
+	return runFanOut(ctx, uii, script, fanOut{noun: "projects", title: "in"}, projects, concurrency, keepGoing, run)
+}
+
+// fanOut describes what a script is run across in output
+type fanOut struct {
+	// noun names the entries, eg. projects
+	noun string
+	// title precedes an entry in the title of its run, eg. in
+	title string
+	// prefixed prefixes output with the entry even if entries are run one at
+	// a time
+	prefixed bool
+}
+
+// runFanOut runs script for each of entries with at most concurrency entries
+// at a time. Unless keepGoing is set no new entries are started once an entry
+// failed. A summary of all entries is printed once they complete.
+func runFanOut(
+	ctx stdcontext.Context,
+	uii *ui.UI,
+	script string,
+	kind fanOut,
+	entries []string,
+	concurrency int,
+	keepGoing bool,
+	run projectRunner,
+) error {
+	results := make([]projectResult, len(entries))
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed bool
+	)
+	// output of concurrent entries is prefixed line by line to be told apart
+	var outputLock sync.Mutex
+	slots := make(chan struct{}, concurrency)
+	for i, entry := range entries {
+		results[i] = projectResult{project: entry, status: projectStatusSkipped}
+
+		slots <- struct{}{}
+		mu.Lock()
+		stop := (failed && !keepGoing) || ctx.Err() != nil
+		mu.Unlock()
+		if stop {
+			<-slots
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, entry string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			var stdout, stderr io.Writer = uii.Out, uii.Err
+			if concurrency > 1 || kind.prefixed {
+				prefix := fmt.Sprintf("[%s] ", entry)
+				stdoutPrefixed := newPrefixWriter(uii.Out, prefix, &outputLock)
+				stderrPrefixed := newPrefixWriter(uii.Err, prefix, &outputLock)
+				defer stdoutPrefixed.Flush()
+				defer stderrPrefixed.Flush()
+				stdout, stderr = stdoutPrefixed, stderrPrefixed
+			} else {
+				uii.Titleln("Running script '%s' %s %s", script, kind.title, entry)
+			}
+
+			err := run(ctx, entry, stdout, stderr)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed = true
+				results[i].status = projectStatusFailed
+				results[i].err = err
+				return
+			}
+			results[i].status = projectStatusPassed
+		}(i, entry)
+	}
+	wg.Wait()
+
+	return projectsSummary(uii, script, kind, results)
+}
+
+// projectsSummary prints the result of each entry and returns an error if
+// any entry failed. The exit code is that of the first failed entry.
+func projectsSummary(uii *ui.UI, script string, kind fanOut, results []projectResult) error {
+	var failedProjects []string
+	var firstErr error
+	uii.Titleln("Script '%s' in %d %s:", script, len(results), kind.noun)
+	for _, result := range results {
+		if result.err != nil {
+			uii.Infoln("  %s: %s: %v", result.project, result.status, result.err)
+			failedProjects = append(failedProjects, result.project)
+			if firstErr == nil {
+				firstErr = result.err
+			}
+			continue
+		}
+		uii.Infoln("  %s: %s", result.project, result.status)
+	}
+	if firstErr == nil {
+		return nil
+	}
+
+	code := 4
+	var exitCode *shuttleerrors.ExitCode
+	if errors.As(firstErr, &exitCode) {
+		code = exitCode.Code
+	}
+	return shuttleerrors.NewExitCode(
+		code,
+		"Script '%s' failed in %d of %d %s: %s",
+		script,
+		len(failedProjects),
+		len(results),
+		kind.noun,
+		strings.Join(failedProjects, ", "),
+	)
+}
+
+// prefixWriter prefixes every line written to it. Complete lines are written
+// while holding lock such that lines of concurrent writers are not mixed.
+type prefixWriter struct {
+	out    io.Writer
+	prefix string
+	lock   *sync.Mutex
+	buf    bytes.Buffer
+}
+
+func newPrefixWriter(out io.Writer, prefix string, lock *sync.Mutex) *prefixWriter {
+	return &prefixWriter{out: out, prefix: prefix, lock: lock}
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadBytes('\n')
+		if err != nil {
+			// keep the incomplete line until it is completed
+			w.buf.Reset()
+			w.buf.Write(line)
+			return len(p), nil
+		}
+		w.writeLine(line)
+	}
+}
+
+// Flush writes any incomplete line.
+func (w *prefixWriter) Flush() {
+	if w.buf.Len() == 0 {
+		return
+	}
+	w.writeLine(append(w.buf.Bytes(), '\n'))
+	w.buf.Reset()
+}
+
+func (w *prefixWriter) writeLine(line []byte) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	fmt.Fprintf(w.out, "%s%s", w.prefix, line)
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"bytes"
+	stdcontext "context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	shuttleerrors "github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestMatchProjects(t *testing.T) {
+	t.Run("matching projects", func(t *testing.T) {
+		projects, err := matchProjects("testdata/project-*")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"testdata/project-git",
+			"testdata/project-git-branched",
+			"testdata/project-plan-inside",
+		}, projects)
+	})
+
+	t.Run("no projects", func(t *testing.T) {
+		_, err := matchProjects("testdata/*.tmpl")
+
+		assert.EqualError(t, err, "exit code 2 - No projects with a shuttle.yaml file match 'testdata/*.tmpl'")
+	})
+}
+
+func TestRunProjects(t *testing.T) {
+	projects := []string{"services/a", "services/b", "services/c"}
+	// failingRunner fails in the projects in failing and records the projects
+	// it is run in
+	failingRunner := func(ran *[]string, failing ...string) projectRunner {
+		var mu sync.Mutex
+		return func(_ stdcontext.Context, project string, stdout, _ io.Writer) error {
+			mu.Lock()
+			*ran = append(*ran, project)
+			mu.Unlock()
+			fmt.Fprintf(stdout, "building %s\n", project)
+			for _, f := range failing {
+				if f == project {
+					return shuttleerrors.NewExitCode(3, "shuttle exited with code 3")
+				}
+			}
+			return nil
+		}
+	}
+
+	tt := []struct {
+		name      string
+		keepGoing bool
+		failing   []string
+		ran       []string
+		stdout    string
+		summary   []string
+		err       string
+	}{
+		{
+			name:   "all pass",
+			ran:    projects,
+			stdout: "building services/a\nbuilding services/b\nbuilding services/c\n",
+			summary: []string{
+				"  services/a: passed\n",
+				"  services/b: passed\n",
+				"  services/c: passed\n",
+			},
+		},
+		{
+			name:    "stops after failure",
+			failing: []string{"services/b"},
+			ran:     []string{"services/a", "services/b"},
+			stdout:  "building services/a\nbuilding services/b\n",
+			summary: []string{
+				"  services/a: passed\n",
+				"  services/b: failed: exit code 3 - shuttle exited with code 3\n",
+				"  services/c: skipped\n",
+			},
+			err: "exit code 3 - Script 'build' failed in 1 of 3 projects: services/b",
+		},
+		{
+			name:      "keep going",
+			keepGoing: true,
+			failing:   []string{"services/a", "services/b"},
+			ran:       projects,
+			stdout:    "building services/a\nbuilding services/b\nbuilding services/c\n",
+			summary: []string{
+				"  services/c: passed\n",
+			},
+			err: "exit code 3 - Script 'build' failed in 2 of 3 projects: services/a, services/b",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			var ran []string
+
+			err := runProjects(
+				stdcontext.Background(),
+				ui.Create(&stdout, &stderr),
+				"build",
+				projects,
+				1,
+				tc.keepGoing,
+				failingRunner(&ran, tc.failing...),
+			)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.ran, ran)
+			assert.Equal(t, tc.stdout, stdout.String())
+			for _, line := range tc.summary {
+				assert.Contains(t, stderr.String(), line)
+			}
+		})
+	}
+}
+
+func TestRunProjects_concurrency(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	var running, maxRunning int
+	var mu sync.Mutex
+	run := func(_ stdcontext.Context, project string, out, _ io.Writer) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		fmt.Fprintf(out, "first\nsecond")
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	}
+
+	err := runProjects(stdcontext.Background(), ui.Create(&stdout, &stderr), "build", []string{"a", "b", "c", "d"}, 2, false, run)
+
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, maxRunning, 2)
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	assert.Len(t, lines, 8)
+	for _, project := range []string{"a", "b", "c", "d"} {
+		assert.Contains(t, lines, fmt.Sprintf("[%s] first", project))
+		assert.Contains(t, lines, fmt.Sprintf("[%s] second", project))
+	}
+}
+
+func TestRunProjects_invalidConcurrency(t *testing.T) {
+	err := runProjects(stdcontext.Background(), ui.Create(io.Discard, io.Discard), "build", []string{"a"}, 0, false, nil)
+
+	assert.EqualError(t, err, "exit code 2 - --projects-concurrency must be at least 1 but was 0")
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	shuttleerrors "github.com/lunarway/shuttle/pkg/errors"
+)
+
+// reportFlags configure the reports written of a run
+type reportFlags struct {
+	values  []string
+	junit   string
+	timings bool
+}
+
+// addReportFlags adds the flags for writing reports of a run to runCmd.
+func addReportFlags(runCmd *cobra.Command, flags *reportFlags) {
+	runCmd.PersistentFlags().
+		StringArrayVar(&flags.values, "report", nil, "Write a report of the run on the form <format>[=<path>]. 'junit=<path>' writes a JUnit XML report of the action results to path and 'timings' a JSON timing report to .shuttle/reports, see 'shuttle report last'. Can be repeated")
+	runCmd.PersistentFlags().
+		StringVar(&flags.junit, "junit", "", "Write a JUnit XML report of the action results to this file. Same as --report junit=<path>")
+	runCmd.PersistentFlags().
+		BoolVar(&flags.timings, "timings", false, "Print how long the plan, scripts and actions took once the script completes")
+}
+
+// runReports are the reports to write of a run
+type runReports struct {
+	// junit is the path of the JUnit XML report if any
+	junit string
+	// timings is set if a timing report is written to .shuttle/reports
+	timings bool
+}
+
+// parseReports returns the reports requested by flags.
+func parseReports(flags reportFlags) (runReports, error) {
+	reports := runReports{junit: flags.junit}
+	for _, value := range flags.values {
+		format, path, hasPath := strings.Cut(value, "=")
+		switch {
+		case format == "junit" && path != "":
+			if reports.junit != "" && reports.junit != path {
+				return runReports{}, shuttleerrors.NewExitCode(2, "Only one JUnit report can be written but both '%s' and '%s' are given", reports.junit, path)
+			}
+			reports.junit = path
+		case format == "timings" && !hasPath:
+			reports.timings = true
+		default:
+			return runReports{}, shuttleerrors.NewExitCode(2, "Report '%s' is invalid: must be 'junit=<path>' or 'timings'", value)
+		}
+	}
+	return reports, nil
+}
+
+// junitFlag returns the flag a JUnit report is requested with, or an empty
+// string if none is.
+func junitFlag(cmd *cobra.Command) string {
+	if cmd.Flags().Changed("junit") {
+		return "--junit"
+	}
+	values, _ := cmd.Flags().GetStringArray("report")
+	for _, value := range values {
+		if strings.HasPrefix(value, "junit=") {
+			return "--report junit"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReports(t *testing.T) {
+	tt := []struct {
+		name    string
+		flags   reportFlags
+		reports runReports
+		err     string
+	}{
+		{
+			name: "no reports",
+		},
+		{
+			name:    "junit flag",
+			flags:   reportFlags{junit: "report.xml"},
+			reports: runReports{junit: "report.xml"},
+		},
+		{
+			name:    "junit and timings reports",
+			flags:   reportFlags{values: []string{"junit=report.xml", "timings"}},
+			reports: runReports{junit: "report.xml", timings: true},
+		},
+		{
+			name:    "same junit report as flag",
+			flags:   reportFlags{junit: "report.xml", values: []string{"junit=report.xml"}},
+			reports: runReports{junit: "report.xml"},
+		},
+		{
+			name:  "different junit reports",
+			flags: reportFlags{junit: "report.xml", values: []string{"junit=other.xml"}},
+			err:   "exit code 2 - Only one JUnit report can be written but both 'report.xml' and 'other.xml' are given",
+		},
+		{
+			name:  "junit without path",
+			flags: reportFlags{values: []string{"junit"}},
+			err:   "exit code 2 - Report 'junit' is invalid: must be 'junit=<path>' or 'timings'",
+		},
+		{
+			name:  "timings with path",
+			flags: reportFlags{values: []string{"timings=report.json"}},
+			err:   "exit code 2 - Report 'timings=report.json' is invalid: must be 'junit=<path>' or 'timings'",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			reports, err := parseReports(tc.flags)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.reports, reports)
+		})
+	}
+}
@@ -2,9 +2,14 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRun(t *testing.T) {
@@ -44,6 +49,92 @@ func TestRun(t *testing.T) {
 				"exit code 4 - Failed executing script `exit_1`: shell script `exit 1`\nExit code: 1",
 			),
 		},
+		{
+			name:      "exit 1 preserved",
+			input:     args("-p", "testdata/project", "run", "--preserve-exit-code", "exit_1"),
+			stdoutput: ``,
+			erroutput: "Error: exit code 1 - Failed executing script `exit_1`: shell script `exit 1`\nExit code: 1\n",
+			err: errors.New(
+				"exit code 1 - Failed executing script `exit_1`: shell script `exit 1`\nExit code: 1",
+			),
+		},
+		{
+			name:      "prefixed output",
+			input:     args("-p", "testdata/project", "--output", "prefixed", "run", "hello_stdout"),
+			stdoutput: "[hello_stdout/0] Hello stdout\n",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "invalid output format",
+			input:     args("-p", "testdata/project", "--output", "yaml", "run", "hello_stdout"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - Invalid --output: output format 'yaml' is invalid: must be one of text, json, prefixed, plain\n",
+			err:       errors.New("exit code 2 - Invalid --output: output format 'yaml' is invalid: must be one of text, json, prefixed, plain"),
+		},
+		{
+			name:      "projects without matches",
+			input:     args("-p", "testdata/project", "run", "--projects", "testdata/none-*", "hello_stdout"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - No projects with a shuttle.yaml file match 'testdata/none-*'\n",
+			err:       errors.New("exit code 2 - No projects with a shuttle.yaml file match 'testdata/none-*'"),
+		},
+		{
+			name:      "projects with junit",
+			input:     args("-p", "testdata/project", "run", "--projects", "testdata/project*", "--junit", "report.xml", "hello_stdout"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - --junit cannot be used with --projects\n",
+			err:       errors.New("exit code 2 - --junit cannot be used with --projects"),
+		},
+		{
+			name:      "projects with junit report",
+			input:     args("-p", "testdata/project", "run", "--projects", "testdata/project*", "--report", "junit=report.xml", "hello_stdout"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - --report junit cannot be used with --projects\n",
+			err:       errors.New("exit code 2 - --report junit cannot be used with --projects"),
+		},
+		{
+			name:      "invalid report",
+			input:     args("-p", "testdata/project", "run", "--report", "html", "hello_stdout"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - Report 'html' is invalid: must be 'junit=<path>' or 'timings'\n",
+			err:       errors.New("exit code 2 - Report 'html' is invalid: must be 'junit=<path>' or 'timings'"),
+		},
+		{
+			name:      "projects with watch",
+			input:     args("-p", "testdata/project", "run", "--projects", "testdata/project*", "--watch", "hello_stdout"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - --watch cannot be used with --projects\n",
+			err:       errors.New("exit code 2 - --watch cannot be used with --projects"),
+		},
+		{
+			name:      "projects with matrix",
+			input:     args("-p", "testdata/project", "run", "--projects", "testdata/project*", "--matrix", "env=dev,prod", "hello_stdout"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - --matrix cannot be used with --projects\n",
+			err:       errors.New("exit code 2 - --matrix cannot be used with --projects"),
+		},
+		{
+			name:      "invalid matrix",
+			input:     args("-p", "testdata/project", "run", "--matrix", "env", "hello_stdout"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - Matrix 'env' is invalid: must be on the form <argument>=<value>,<value>\n",
+			err:       errors.New("exit code 2 - Matrix 'env' is invalid: must be on the form <argument>=<value>,<value>"),
+		},
+		{
+			name:      "matrix argument given",
+			input:     args("-p", "testdata/project", "run", "--matrix", "foo=a,b", "required_arg", "foo=bar"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - Argument 'foo' cannot be given as it is set by the matrix\n",
+			err:       errors.New("exit code 2 - Argument 'foo' cannot be given as it is set by the matrix"),
+		},
+		{
+			name:      "invalid watch glob",
+			input:     args("-p", "testdata/project", "run", "--watch", "--watch-glob", "[a-", "hello_stdout"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - Watch pattern '[a-' is invalid: must be a project relative glob pattern\n",
+			err:       errors.New("exit code 2 - Watch pattern '[a-' is invalid: must be a project relative glob pattern"),
+		},
 		{
 			name:      "project with absolute path",
 			input:     args("-p", filepath.Join(pwd, "testdata/project"), "run", "hello_stdout"),
@@ -68,6 +159,79 @@ func TestRun(t *testing.T) {
 `,
 			err: errors.New(`required flag(s) "foo" not set`),
 		},
+		{
+			name:      "typed arguments with default",
+			input:     args("-p", "testdata/typed-args", "run", "deploy", "replicas=3", "tag=v1"),
+			stdoutput: "dev 3 v1\n",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "invalid typed arguments",
+			input:     args("-p", "testdata/typed-args", "run", "deploy", "env=staging", "replicas=three", "tag=latest"),
+			stdoutput: "",
+			erroutput: `Error: exit code 2 - Arguments not valid:
+ 'env' must be one of dev, prod but was 'staging'
+ 'replicas' must be an integer but was 'three'
+ 'tag' must match pattern '^v[0-9]+$' but was 'latest'
+
+Script 'deploy' accepts the following arguments:
+  env (enum: dev|prod, default: dev)  Environment to deploy to
+  replicas (int)
+  tag (pattern: ^v[0-9]+$)
+`,
+			err: errors.New(`exit code 2 - Arguments not valid:
+ 'env' must be one of dev, prod but was 'staging'
+ 'replicas' must be an integer but was 'three'
+ 'tag' must match pattern '^v[0-9]+$' but was 'latest'
+
+Script 'deploy' accepts the following arguments:
+  env (enum: dev|prod, default: dev)  Environment to deploy to
+  replicas (int)
+  tag (pattern: ^v[0-9]+$)`),
+		},
+		{
+			name:      "invalid argument declarations",
+			input:     args("-p", "testdata/invalid-arg-types", "run", "deploy"),
+			stdoutput: "",
+			erroutput: `Error: exit code 1 - Script arguments are invalid:
+  deploy.args.dry has invalid default: must be true or false but was 'yes'
+  deploy.args.env is of type enum but has no values
+  deploy.args.replicas has invalid type 'integer': must be one of string, int, bool, enum or path
+`,
+			initErr: errors.New(`exit code 1 - Script arguments are invalid:
+  deploy.args.dry has invalid default: must be true or false but was 'yes'
+  deploy.args.env is of type enum but has no values
+  deploy.args.replicas has invalid type 'integer': must be one of string, int, bool, enum or path`),
+		},
+		{
+			name:      "profile",
+			input:     args("-p", "testdata/profiles", "--profile", "prod", "run", "deploy"),
+			stdoutput: "prod\n",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "no profile",
+			input:     args("-p", "testdata/profiles", "run", "deploy"),
+			stdoutput: "\n",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "profile plan",
+			input:     args("-p", "testdata/profiles", "--profile", "staging", "run", "release"),
+			stdoutput: "releasing staging\n",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "unknown profile",
+			input:     args("-p", "testdata/profiles", "--profile", "test", "run", "deploy"),
+			stdoutput: "",
+			erroutput: "",
+			initErr:   errors.New("exit code 2 - Profile 'test' is not defined: must be one of prod, staging"),
+		},
 		{
 			name:      "script succeeds with required argument",
 			input:     args("-p", "testdata/project", "run", "required_arg", "foo=bar"),
@@ -111,6 +275,21 @@ func TestRun(t *testing.T) {
 `,
 			err: errors.New(`unknown flag: --a b`),
 		},
+		{
+			name: "script help with template",
+			input: args(
+				"-p",
+				"testdata/project",
+				"run",
+				"required_arg",
+				"--help",
+				"--template",
+				"{{ range .Args }}{{ .Name }} {{ .Flag }}{{ end }}",
+			),
+			stdoutput: "foo --foo",
+			erroutput: "",
+			err:       nil,
+		},
 		{
 			name:      "branched git plan",
 			input:     args("-p", "testdata/project-git-branched", "run", "say"),
@@ -146,6 +325,106 @@ func TestRun(t *testing.T) {
 			erroutput: "Using overloaded plan ./testdata/project-local/plan\n",
 			err:       nil,
 		},
+		{
+			name:  "plan inside project",
+			input: args("-p", "testdata/project-plan-inside", "run", "paths"),
+			stdoutput: fmt.Sprintf(
+				"plan=%[1]s/testdata/project-plan-inside/.shuttle/plan\nsource=%[1]s/testdata/project-plan-inside/plan\nproject=%[1]s/testdata/project-plan-inside\n",
+				pwd,
+			),
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "script of base plan",
+			input:     args("-p", "testdata/overlays", "run", "hello-base"),
+			stdoutput: "Hello from base plan\n",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "script of overlay plan",
+			input:     args("-p", "testdata/overlays", "run", "hello-team"),
+			stdoutput: "Hello from team plan\n",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:      "script of project overriding plans",
+			input:     args("-p", "testdata/overlays", "run", "hello-project"),
+			stdoutput: "Hello from project\n",
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name:  "plan directory flag",
+			input: args("-p", "testdata/project-plan-inside", "--plan-dir", "plan", "run", "paths"),
+			stdoutput: fmt.Sprintf(
+				"plan=%[1]s/testdata/project-plan-inside/.shuttle/plan\nsource=%[1]s/testdata/project-plan-inside/plan\nproject=%[1]s/testdata/project-plan-inside\n",
+				pwd,
+			),
+			erroutput: fmt.Sprintf("Using overloaded plan %s/testdata/project-plan-inside/plan\n", pwd),
+			err:       nil,
+		},
+		{
+			name:    "plan directory flag outside project",
+			input:   args("-p", "testdata/project-local/service", "--plan-dir", "../plan", "run", "hello-plan"),
+			initErr: errors.New("exit code 2 - Plan directory '../plan' must be relative to and within the project"),
+		},
+		{
+			name: "plan directory flag with plan flag",
+			input: args(
+				"-p",
+				"testdata/project-plan-inside",
+				"--plan-dir",
+				"plan",
+				"--plan",
+				"./testdata/project-local/plan",
+				"run",
+				"paths",
+			),
+			initErr: errors.New("exit code 2 - --plan and --plan-dir cannot be used together"),
+		},
+		{
+			name: "require clean plan warns on local plan",
+			input: args(
+				"--project",
+				"./testdata/project-local/service",
+				"--plan",
+				"./testdata/project-local/plan",
+				"run",
+				"--require-clean-plan",
+				"hello-plan",
+			),
+			stdoutput: "Hello from plan\n",
+			erroutput: fmt.Sprintf(
+				"Using overloaded plan ./testdata/project-local/plan\n\x1b[032;1mPlan at '%s/testdata/project-local/service/.shuttle/plan' is not a git repository. Skipping clean plan check\x1b[0m\n",
+				pwd,
+			),
+			err: nil,
+		},
+		{
+			name: "strict require clean plan fails on local plan",
+			input: args(
+				"--project",
+				"./testdata/project-local/service",
+				"--plan",
+				"./testdata/project-local/plan",
+				"run",
+				"--require-clean-plan",
+				"--strict-clean-plan",
+				"hello-plan",
+			),
+			stdoutput: "",
+			erroutput: fmt.Sprintf(
+				"Using overloaded plan ./testdata/project-local/plan\nError: exit code 2 - Plan at '%s/testdata/project-local/service/.shuttle/plan' is not a git repository so it cannot be verified to be clean\n",
+				pwd,
+			),
+			err: fmt.Errorf(
+				"exit code 2 - Plan at '%s/testdata/project-local/service/.shuttle/plan' is not a git repository so it cannot be verified to be clean",
+				pwd,
+			),
+		},
 		// FIXME: This case actually hits a bug as we do not support fetching specific commits
 		// {
 		// 	name:      "sha git plan",
@@ -180,3 +459,83 @@ func TestRun(t *testing.T) {
 	}
 	executeTestContainsCases(t, testContainsCases)
 }
+
+func TestRun_jsonOutput(t *testing.T) {
+	testCases := []testCase{
+		{
+			name:  "std out echo",
+			input: args("-p", "testdata/project", "--output", "json", "run", "hello_stdout"),
+			stdoutput: `{"level":"info","script":"hello_stdout","stream":"stderr","event":"script-started","message":"started"}
+{"level":"info","script":"hello_stdout","action":0,"stream":"stderr","event":"action-started","message":"started"}
+{"level":"info","script":"hello_stdout","action":0,"stream":"stdout","message":"Hello stdout"}
+{"level":"info","script":"hello_stdout","action":0,"stream":"stderr","event":"action-exited","exitCode":0,"message":"exited with code 0"}
+{"level":"info","script":"hello_stdout","stream":"stderr","event":"script-exited","exitCode":0,"message":"exited with code 0"}
+`,
+		},
+		{
+			name:  "exit 1",
+			input: args("-p", "testdata/project", "--output", "json", "run", "exit_1"),
+			stdoutput: `{"level":"info","script":"exit_1","stream":"stderr","event":"script-started","message":"started"}
+{"level":"info","script":"exit_1","action":0,"stream":"stderr","event":"action-started","message":"started"}
+{"level":"info","script":"exit_1","action":0,"stream":"stderr","event":"action-exited","exitCode":4,"message":"exited with code 4"}
+{"level":"info","script":"exit_1","stream":"stderr","event":"script-exited","exitCode":4,"message":"exited with code 4"}
+`,
+			err: errors.New("exit code 4 - Failed executing script `exit_1`: shell script `exit 1`\nExit code: 1"),
+		},
+	}
+	// timestamps and durations vary between runs
+	varying := regexp.MustCompile(`"timestamp":"[^"]+",|"durationMs":[0-9]+,`)
+	executeTestCasesWithCustomAssertion(
+		t,
+		testCases,
+		func(t *testing.T, tc testCase, stdout, stderr string) {
+			assert.Equal(t, tc.stdoutput, varying.ReplaceAllString(stdout, ""), "std output not as expected")
+		},
+	)
+}
+
+func TestRun_requirements(t *testing.T) {
+	plan := t.TempDir()
+	err := os.WriteFile(filepath.Join(plan, "plan.yaml"), []byte(`requires:
+  tools:
+  - name: shuttle-missing-tool
+scripts:
+  hello:
+    actions:
+    - shell: echo hello
+`), 0o644)
+	require.NoError(t, err)
+	project := t.TempDir()
+	err = os.WriteFile(filepath.Join(project, "shuttle.yaml"), []byte("plan: "+plan+"\n"), 0o644)
+	require.NoError(t, err)
+
+	executeTestContainsCases(t, []testCase{
+		{
+			name:  "unmet",
+			input: args("-p", project, "run", "hello"),
+			err: errors.New(`exit code 4 - Requirements of the plan are not met:
+- shuttle-missing-tool: not found on PATH
+  → Install shuttle-missing-tool and add it to PATH`),
+		},
+	})
+}
+
+func TestPromptForArgs(t *testing.T) {
+	tt := []struct {
+		name     string
+		flags    runFlags
+		terminal bool
+		prompt   bool
+	}{
+		{name: "terminal", terminal: true, prompt: true},
+		{name: "no terminal", terminal: false, prompt: false},
+		{name: "interactive without terminal", flags: runFlags{interactive: true}, prompt: true},
+		{name: "no input with terminal", flags: runFlags{noInput: true}, terminal: true, prompt: false},
+		{name: "no input overrides interactive", flags: runFlags{interactive: true, noInput: true}, terminal: true, prompt: false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.prompt, promptForArgs(&tc.flags, tc.terminal))
+		})
+	}
+}
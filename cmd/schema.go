@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func newSchema(uii *ui.UI) *cobra.Command {
+	schemaCmd := &cobra.Command{
+		Use:   "schema [project|plan]",
+		Short: "Print the JSON Schema of shuttle.yaml or plan.yaml",
+		Long: `Print the JSON Schema of shuttle.yaml or plan.yaml.
+
+The schema of shuttle.yaml is printed by default. Save it and reference it from
+the top of the file to get completion and validation in editors using
+yaml-language-server, eg.
+
+  # yaml-language-server: $schema=./shuttle.schema.json`,
+		ValidArgs: []string{"project", "plan"},
+		Args: func(cmd *cobra.Command, args []string) error {
+			if cobra.MaximumNArgs(1)(cmd, args) != nil || cobra.OnlyValidArgs(cmd, args) != nil {
+				return fmt.Errorf("only %v arguments are allowed", cmd.ValidArgs)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uii.SetContext(ui.LevelSilent)
+			schema := config.ProjectSchema()
+			if len(args) == 1 && args[0] == "plan" {
+				schema = config.PlanSchema()
+			}
+			output, err := json.MarshalIndent(schema, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), string(output))
+			return nil
+		},
+	}
+
+	return schemaCmd
+}
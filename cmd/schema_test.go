@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema(t *testing.T) {
+	tt := []struct {
+		name  string
+		input []string
+		title string
+	}{
+		{
+			name:  "project by default",
+			input: args("-p", "testdata/project", "schema"),
+			title: "shuttle.yaml",
+		},
+		{
+			name:  "plan",
+			input: args("-p", "testdata/project", "schema", "plan"),
+			title: "plan.yaml",
+		},
+		{
+			name:  "outside a project",
+			input: args("-p", "testdata", "schema", "project"),
+			title: "shuttle.yaml",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			rootCmd, _, err := initializedRootFromArgs(&stdout, &stderr, tc.input)
+			require.NoError(t, err)
+			rootCmd.SetArgs(tc.input)
+
+			require.NoError(t, rootCmd.Execute())
+
+			var schema struct {
+				Title      string                 `json:"title"`
+				Properties map[string]interface{} `json:"properties"`
+			}
+			require.NoError(t, json.Unmarshal(stdout.Bytes(), &schema))
+			assert.Equal(t, tc.title, schema.Title)
+			assert.Contains(t, schema.Properties, "scripts")
+			assert.Empty(t, stderr.String())
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/spf13/cobra"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/selfupdate"
+	"github.com/lunarway/shuttle/pkg/signature"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// Environment variables configuring self-update
+const (
+	// releasesFeedEnv is the URL of the releases feed, eg. of a mirror. It
+	// defaults to the GitHub releases of shuttle.
+	releasesFeedEnv = "SHUTTLE_RELEASES_URL"
+	// updatePublicKeyFileEnv is the path of the public key the checksums of
+	// releases must be signed by.
+	updatePublicKeyFileEnv = "SHUTTLE_UPDATE_PUBLIC_KEY_FILE"
+)
+
+func newSelfUpdate(uii *ui.UI) *cobra.Command {
+	var (
+		channel       string
+		check         bool
+		publicKeyFile string
+	)
+
+	selfUpdateCmd := &cobra.Command{
+		Use:   "self-update",
+		Short: "Update shuttle to its latest release",
+		Long: `Update shuttle to the latest release of a channel, stable by default or
+prerelease to include prereleases.
+
+The downloaded binary is verified against the checksums of the release and
+replaces the running executable atomically. With a public key, from
+--public-key-file or SHUTTLE_UPDATE_PUBLIC_KEY_FILE, the checksums must be
+signed by it as well.`,
+		Example: `  shuttle self-update
+  shuttle self-update --channel prerelease
+  shuttle self-update --check`,
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if channel != selfupdate.ChannelStable && channel != selfupdate.ChannelPrerelease {
+				return errors.NewExitCode(2, "--channel '%s' is invalid: must be %s or %s", channel, selfupdate.ChannelStable, selfupdate.ChannelPrerelease)
+			}
+			if publicKeyFile == "" {
+				publicKeyFile = os.Getenv(updatePublicKeyFileEnv)
+			}
+			var key signature.PublicKey
+			if publicKeyFile != "" {
+				raw, err := os.ReadFile(publicKeyFile)
+				if err != nil {
+					return errors.NewExitCode(2, "Failed to read public key: %v", err)
+				}
+				key, err = signature.ParsePublicKey(string(raw))
+				if err != nil {
+					return errors.NewExitCode(2, "Failed to load public key %s: %v", publicKeyFile, err)
+				}
+			}
+			feed := os.Getenv(releasesFeedEnv)
+			if feed == "" {
+				feed = selfupdate.DefaultFeed
+			}
+
+			ctx, cancel := withSignal(cmd.Context(), uii)
+			defer cancel()
+			release, err := selfupdate.LatestRelease(ctx, feed, channel)
+			if err != nil {
+				return errors.NewExitCode(1, "Failed to find the latest release of shuttle: %v", err)
+			}
+			latest, _ := release.Version()
+			// development builds are always updated
+			current, err := semver.NewVersion(version)
+			if err == nil && !latest.GreaterThan(current) {
+				uii.Infoln("shuttle %s is the latest %s release", version, channel)
+				return nil
+			}
+			if check {
+				uii.Output("shuttle %s is available, this is %s. Run 'shuttle self-update' to update", release.TagName, version)
+				return nil
+			}
+
+			executable, err := selfupdate.Executable()
+			if err != nil {
+				return errors.NewExitCode(1, "Failed to find the shuttle executable: %v", err)
+			}
+			// the binary is downloaded next to the executable such that it is
+			// on the same file system and can be renamed into its place
+			binary, err := os.CreateTemp(filepath.Dir(executable), ".shuttle-update-*")
+			if err != nil {
+				return errors.NewExitCode(1, "Failed to update %s: %v\n\nMake sure you can write to its directory, eg. with sudo.", executable, err)
+			}
+			binary.Close()
+			defer os.Remove(binary.Name())
+
+			uii.Infoln("Downloading shuttle %s...", release.TagName)
+			err = selfupdate.Download(ctx, release, selfupdate.AssetName(runtime.GOOS, runtime.GOARCH), key, binary.Name())
+			if err != nil {
+				return errors.NewExitCode(1, "Failed to download shuttle %s: %v", release.TagName, err)
+			}
+			if err := selfupdate.Replace(executable, binary.Name()); err != nil {
+				return errors.NewExitCode(1, "Failed to replace %s: %v", executable, err)
+			}
+			uii.Infoln("Updated shuttle from %s to %s", version, release.TagName)
+			return nil
+		},
+	}
+
+	selfUpdateCmd.Flags().StringVar(&channel, "channel", selfupdate.ChannelStable, "Release channel to update from, stable or prerelease")
+	selfUpdateCmd.Flags().BoolVar(&check, "check", false, "Only print whether a newer release is available")
+	selfUpdateCmd.Flags().StringVar(&publicKeyFile, "public-key-file", "", "Minisign or cosign public key the checksums of the release must be signed by")
+
+	return selfUpdateCmd
+}
+
+// isSelfUpdateRequest returns whether shuttle is invoked to update itself.
+func isSelfUpdateRequest(rootCmd *cobra.Command) bool {
+	args := rootCmd.Flags().Args()
+	return len(args) > 0 && args[0] == "self-update"
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/selfupdate"
+)
+
+func TestSelfUpdate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]selfupdate.Release{
+			{TagName: "v1.1.0"},
+			{TagName: "v1.2.0-rc.1", Prerelease: true},
+		})
+	}))
+	defer server.Close()
+	t.Setenv("SHUTTLE_RELEASES_URL", server.URL)
+	missingKey := filepath.Join(t.TempDir(), "missing.pub")
+
+	executeTestContainsCases(t, []testCase{
+		{
+			name:      "check stable",
+			input:     args("self-update", "--check"),
+			stdoutput: "shuttle v1.1.0 is available, this is <dev-version>. Run 'shuttle self-update' to update",
+		},
+		{
+			name:      "check prerelease",
+			input:     args("self-update", "--check", "--channel", "prerelease"),
+			stdoutput: "shuttle v1.2.0-rc.1 is available, this is <dev-version>. Run 'shuttle self-update' to update",
+		},
+		{
+			name:  "invalid channel",
+			input: args("self-update", "--channel", "nightly"),
+			err:   errors.New("exit code 2 - --channel 'nightly' is invalid: must be stable or prerelease"),
+		},
+		{
+			name:  "missing public key",
+			input: args("self-update", "--public-key-file", missingKey),
+			err:   errors.New("exit code 2 - Failed to read public key: open " + missingKey + ": no such file or directory"),
+		},
+	})
+}
+
+func TestShuttleVersion(t *testing.T) {
+	released := version
+	version = "v1.4.2"
+	t.Cleanup(func() {
+		version = released
+	})
+
+	planDir := t.TempDir()
+	plan := func(name, constraint string) string {
+		dir := filepath.Join(planDir, name)
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "plan.yaml"), []byte("shuttleVersion: '"+constraint+"'\nscripts:\n  hello:\n    actions:\n    - shell: echo hello\n"), 0o644))
+		project := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(project, "shuttle.yaml"), []byte("plan: "+dir+"\n"), 0o644))
+		return project
+	}
+	outdated := plan("outdated", ">= 1.5.0")
+	invalid := plan("invalid", "latest")
+
+	executeTestContainsCases(t, []testCase{
+		{
+			name:      "satisfied",
+			input:     args("-p", plan("satisfied", ">= 1.4.0"), "run", "hello"),
+			stdoutput: "hello",
+		},
+		{
+			name:  "outdated",
+			input: args("-p", outdated, "run", "hello"),
+			err: errors.New(`exit code 4 - Requirements of the plan are not met:
+- shuttle >= 1.5.0: this is shuttle v1.4.2
+  → Update shuttle with 'shuttle self-update'`),
+		},
+		{
+			name:  "invalid constraint",
+			input: args("-p", invalid, "run", "hello"),
+			err: errors.New(`exit code 4 - Requirements of the plan are not met:
+- shuttle latest: invalid version constraint: improper constraint: latest
+  → Fix requires.shuttle or shuttleVersion of the plan`),
+		},
+	})
+}
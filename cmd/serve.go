@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	stdcontext "context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	shuttleerrors "github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/shuttle"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// serveShutdownTimeout is how long runs in progress are given to exit when
+// the server is stopped
+const serveShutdownTimeout = 10 * time.Second
+
+func newServe(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	var (
+		listen string
+		token  string
+	)
+
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve an API for running scripts of the project",
+		Long: `Serve an HTTP API for listing and running scripts of the project, streaming
+their output and cancelling them.
+
+	GET  /scripts          list scripts
+	GET  /runs             list runs
+	POST /runs             start a run, eg. {"script": "build", "args": {"tag": "v1"}}
+	GET  /runs/<id>        describe a run
+	GET  /runs/<id>/logs   stream the output of a run as JSON lines
+	POST /runs/<id>/cancel cancel a run
+
+Runs are identified by their telemetry run ID. Requests must send the token of
+--token or SHUTTLE_SERVE_TOKEN as a bearer token. A random token is generated
+and printed if neither is set. Runs must be started with a JSON body and
+requests from web pages of other origins are rejected.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := contextProvider()
+			if err != nil {
+				return err
+			}
+			if token == "" {
+				token = os.Getenv("SHUTTLE_SERVE_TOKEN")
+			}
+			if token == "" {
+				token, err = generateServeToken()
+				if err != nil {
+					return shuttleerrors.NewExitCode(1, "Failed to generate a token: %v", err)
+				}
+				uii.Infoln("Authenticate requests with the bearer token %s", token)
+			}
+
+			listener, err := net.Listen("tcp", listen)
+			if err != nil {
+				return shuttleerrors.NewExitCode(2, "Failed to listen on '%s': %v", listen, err)
+			}
+
+			ctx, cancel := withSignal(cmd.Context(), uii)
+			defer cancel()
+			return serve(ctx, uii, listener, shuttle.NewServer(shuttle.NewProjectRunner(context, shuttle.Options{ShuttleVersion: version}), token))
+		},
+	}
+
+	serveCmd.Flags().StringVar(&listen, "listen", "127.0.0.1:7433", "Address to serve the API on")
+	serveCmd.Flags().StringVar(&token, "token", "", "Token requests must authenticate with as a bearer token. Defaults to SHUTTLE_SERVE_TOKEN or a random token")
+
+	return serveCmd
+}
+
+// serve serves server on listener until ctx is done after which runs in
+// progress are cancelled.
+func serve(ctx stdcontext.Context, uii *ui.UI, listener net.Listener, server *shuttle.Server) error {
+	httpServer := &http.Server{Handler: server}
+	served := make(chan error, 1)
+	go func() {
+		served <- httpServer.Serve(listener)
+	}()
+	uii.Infoln("Serving on http://%s", listener.Addr())
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+	uii.Infoln("Stopping server")
+	shutdownCtx, cancel := stdcontext.WithTimeout(stdcontext.Background(), serveShutdownTimeout)
+	defer cancel()
+	// runs are cancelled first as streamed logs are open until they exit
+	runsErr := server.Shutdown(shutdownCtx)
+	err := httpServer.Shutdown(shutdownCtx)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	if runsErr != nil {
+		return shuttleerrors.NewExitCode(1, "Runs in progress did not exit within %s", serveShutdownTimeout)
+	}
+	return shuttleerrors.NewCancellation(ctx)
+}
+
+// generateServeToken returns a random token for authenticating requests to
+// the server.
+func generateServeToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -12,7 +12,9 @@ func trace(
 	name string,
 	args []string,
 ) (stdcontext.Context, func(options ...telemetry.TelemetryOption), func(err error, options ...telemetry.TelemetryOption), func()) {
-	ctx = telemetry.WithContextID(ctx)
+	if telemetry.ContextIDFrom(ctx) == "" {
+		ctx = telemetry.WithContextID(ctx)
+	}
 	ctx = telemetry.WithRunID(ctx)
 	ctx = WithRunTelemetry(ctx, name, args)
 
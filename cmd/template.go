@@ -22,114 +22,159 @@ type context struct {
 	ProjectPath string
 }
 
+// templateFlags are the flags of shuttle template and shuttle template render
+type templateFlags struct {
+	output                 string
+	leftDelim              string
+	rightDelim             string
+	delims                 string
+	ignoreProjectOverrides bool
+	check                  bool
+}
+
 func newTemplate(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
-	var templateOutput, leftDelimArg, rightDelimArg, delimsArg string
-	var ignoreProjectOverrides bool
+	var flags templateFlags
 
 	templateCmd := &cobra.Command{
 		Use:   "template [template]",
 		Short: "Execute a template",
 		Args:  cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			templateName := args[0]
-			ctx := cmd.Context()
-			ctx, _, _, traceEnd := trace(ctx, "template", args)
-			defer traceEnd()
-
-			projectContext, err := contextProvider()
-			if err != nil {
-				return err
-			}
-
-			namedArgs := map[string]string{}
-			for _, arg := range args[1:] {
-				parts := strings.SplitN(arg, "=", 2)
-				namedArgs[parts[0]] = parts[1]
-			}
-
-			planPaths := []string{
-				path.Join(projectContext.LocalPlanPath, "templates", templateName),
-				path.Join(projectContext.LocalPlanPath, templateName),
-			}
-
-			projectPaths := []string{
-				path.Join(projectContext.ProjectPath, "templates", templateName),
-				path.Join(projectContext.ProjectPath, templateName),
-			}
-
-			var paths []string
-			if ignoreProjectOverrides {
-				paths = planPaths
-			} else {
-				paths = append(projectPaths, planPaths...)
-			}
-
-			templatePath := resolveFirstPath(paths)
-			if templatePath == "" {
-				return fmt.Errorf("template `%s` not found", templateName)
-			}
-
-			leftDelim, rightDelim, err := parseDelims(leftDelimArg, rightDelimArg, delimsArg)
-			if err != nil {
-				return err
-			}
-
-			tmpl, err := template.New(templateName).
-				Delims(leftDelim, rightDelim).
-				Funcs(tmplFuncs.GetFuncMap()).
-				ParseFiles(templatePath)
-			if err != nil {
-				uii.Errorln("Parse template file failed\nFile: %s", templatePath)
-				return err
-			}
-
-			context := context{
-				Args:        namedArgs,
-				Vars:        projectContext.Config.Variables,
-				PlanPath:    projectContext.LocalPlanPath,
-				ProjectPath: projectContext.ProjectPath,
-			}
-			var output io.Writer
-			if templateOutput == "" {
-				output = cmd.OutOrStdout()
-			} else {
-				// TODO: This is probably not the right place to initialize the temp dir?
-				os.MkdirAll(projectContext.TempDirectoryPath, os.ModePerm)
-				templateOutputPath := path.Join(projectContext.TempDirectoryPath, templateOutput)
-				file, err := os.Create(templateOutputPath)
-				if err != nil {
-					return errors.WithMessagef(err, "create template output file '%s'", templateOutputPath)
-				}
-				output = file
-			}
-
-			err = tmpl.ExecuteTemplate(output, path.Base(templatePath), context)
-			if err != nil {
-				uii.Errorln(
-					"Failed to execute template\nPlan: %s\nProject: %s",
-					context.PlanPath,
-					context.ProjectPath,
-				)
-				return err
-			}
-			return nil
+			return renderTemplate(cmd, uii, contextProvider, flags, args)
 		},
 	}
-
-	templateCmd.Flags().
-		StringVarP(&templateOutput, "output", "o", "", "Select filename to output file to in temporary directory")
-	templateCmd.Flags().
-		StringVarP(&delimsArg, "delims", "", "", "Select delims for templating. Split by ','. If ',' is in the delims, then use --left-delim and --right-delim instead")
-	templateCmd.Flags().
-		StringVarP(&leftDelimArg, "left-delim", "", "", "Select delims for templating. Defaults to '{{'")
-	templateCmd.Flags().
-		StringVarP(&rightDelimArg, "right-delim", "", "", "Select delims for templating. Defaults to '}}'")
-	templateCmd.Flags().
-		BoolVarP(&ignoreProjectOverrides, "ignore-project-overrides", "", false, "Set flag to ignore template files located in the project folder")
+	templateCmd.AddCommand(&cobra.Command{
+		Use:   "render <template>",
+		Short: "Execute a template",
+		Long: `Execute a template like shuttle template. Use --check to fail on keys missing
+from the variables and arguments instead of rendering them empty.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return renderTemplate(cmd, uii, contextProvider, flags, args)
+		},
+	})
+
+	templateCmd.PersistentFlags().
+		StringVarP(&flags.output, "output", "o", "", "Select filename to output file to in temporary directory")
+	templateCmd.PersistentFlags().
+		StringVarP(&flags.delims, "delims", "", "", "Select delims for templating. Split by ','. If ',' is in the delims, then use --left-delim and --right-delim instead")
+	templateCmd.PersistentFlags().
+		StringVarP(&flags.leftDelim, "left-delim", "", "", "Select delims for templating. Defaults to '{{'")
+	templateCmd.PersistentFlags().
+		StringVarP(&flags.rightDelim, "right-delim", "", "", "Select delims for templating. Defaults to '}}'")
+	templateCmd.PersistentFlags().
+		BoolVarP(&flags.ignoreProjectOverrides, "ignore-project-overrides", "", false, "Set flag to ignore template files located in the project folder")
+	templateCmd.PersistentFlags().
+		BoolVar(&flags.check, "check", false, "Fail on keys missing from the variables and arguments instead of rendering them empty")
 
 	return templateCmd
 }
 
+// renderTemplate renders the template named by the first of args with the
+// remaining args on the form <name>=<value>.
+func renderTemplate(cmd *cobra.Command, uii *ui.UI, contextProvider contextProvider, flags templateFlags, args []string) error {
+	templateName := args[0]
+	ctx := cmd.Context()
+	ctx, _, _, traceEnd := trace(ctx, "template", args)
+	defer traceEnd()
+
+	projectContext, err := contextProvider()
+	if err != nil {
+		return err
+	}
+
+	namedArgs := map[string]string{}
+	for _, arg := range args[1:] {
+		parts := strings.SplitN(arg, "=", 2)
+		namedArgs[parts[0]] = parts[1]
+	}
+
+	planPaths := []string{
+		path.Join(projectContext.LocalPlanPath, "templates", templateName),
+		path.Join(projectContext.LocalPlanPath, templateName),
+	}
+
+	projectPaths := []string{
+		path.Join(projectContext.ProjectPath, "templates", templateName),
+		path.Join(projectContext.ProjectPath, templateName),
+	}
+
+	var paths []string
+	if flags.ignoreProjectOverrides {
+		paths = planPaths
+	} else {
+		paths = append(projectPaths, planPaths...)
+	}
+
+	templatePath := resolveFirstPath(paths)
+	if templatePath == "" {
+		return fmt.Errorf("template `%s` not found", templateName)
+	}
+
+	leftDelim, rightDelim, err := parseDelims(flags.leftDelim, flags.rightDelim, flags.delims)
+	if err != nil {
+		return err
+	}
+
+	// functions of the project replace those of the plan
+	var functionPaths []string
+	if projectContext.LocalPlanPath != "" {
+		functionPaths = append(functionPaths, path.Join(projectContext.LocalPlanPath, "templates", tmplFuncs.FunctionsFile))
+	}
+	if !flags.ignoreProjectOverrides {
+		functionPaths = append(functionPaths, path.Join(projectContext.ProjectPath, "templates", tmplFuncs.FunctionsFile))
+	}
+	funcs, err := tmplFuncs.LoadFunctions(functionPaths...)
+	if err != nil {
+		return err
+	}
+
+	missingKey := "missingkey=default"
+	if flags.check {
+		missingKey = "missingkey=error"
+	}
+	tmpl, err := template.New(templateName).
+		Delims(leftDelim, rightDelim).
+		Funcs(funcs).
+		Option(missingKey).
+		ParseFiles(templatePath)
+	if err != nil {
+		uii.Errorln("Parse template file failed\nFile: %s", templatePath)
+		return err
+	}
+
+	context := context{
+		Args:        namedArgs,
+		Vars:        projectContext.Config.Variables,
+		PlanPath:    projectContext.LocalPlanPath,
+		ProjectPath: projectContext.ProjectPath,
+	}
+	var output io.Writer
+	if flags.output == "" {
+		output = cmd.OutOrStdout()
+	} else {
+		// TODO: This is probably not the right place to initialize the temp dir?
+		os.MkdirAll(projectContext.TempDirectoryPath, os.ModePerm)
+		templateOutputPath := path.Join(projectContext.TempDirectoryPath, flags.output)
+		file, err := os.Create(templateOutputPath)
+		if err != nil {
+			return errors.WithMessagef(err, "create template output file '%s'", templateOutputPath)
+		}
+		output = file
+	}
+
+	err = tmpl.ExecuteTemplate(output, path.Base(templatePath), context)
+	if err != nil {
+		uii.Errorln(
+			"Failed to execute template\nPlan: %s\nProject: %s",
+			context.PlanPath,
+			context.ProjectPath,
+		)
+		return err
+	}
+	return nil
+}
+
 func resolveFirstPath(paths []string) string {
 	for _, templatePath := range paths {
 		if fileAvailable(templatePath) {
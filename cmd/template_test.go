@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -33,6 +34,36 @@ FROM golang:1.17-alpine
 			),
 			stdoutput: `FROM golang:1.17-alpine
 LABEL svc=shuttle
+`,
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name: "plan and project functions",
+			input: args(
+				"-p",
+				"testdata/template-functions",
+				"template",
+				"deployment.tmpl",
+				"tag=v1",
+			),
+			stdoutput: `image: registry.local/api:v1
+replicas: <no value>
+`,
+			erroutput: "",
+			err:       nil,
+		},
+		{
+			name: "render",
+			input: args(
+				"-p",
+				"testdata/template-functions",
+				"template",
+				"render",
+				"deployment.tmpl",
+			),
+			stdoutput: `image: registry.local/api:latest
+replicas: <no value>
 `,
 			erroutput: "",
 			err:       nil,
@@ -40,3 +71,22 @@ LABEL svc=shuttle
 	}
 	executeTestCases(t, testCases)
 }
+
+func TestTemplate_check(t *testing.T) {
+	testCases := []testCase{
+		{
+			name: "missing key",
+			input: args(
+				"-p",
+				"testdata/template-functions",
+				"template",
+				"render",
+				"deployment.tmpl",
+				"--check",
+				"tag=v1",
+			),
+			err: errors.New(`map has no entry for key "replicas"`),
+		},
+	}
+	executeTestContainsCases(t, testCases)
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/executors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func newValidate(uii *ui.UI, contextProvider contextProvider) *cobra.Command {
+	var flagArgs []string
+
+	validateCmd := &cobra.Command{
+		Use:   "validate [script] [argument=value...]",
+		Short: "Validate actions and arguments of scripts without running them",
+		Long: `Validate actions and arguments of scripts without running them.
+
+shuttle.yaml and the plan.yaml of the plan and overlays are first checked
+against the schema of shuttle, see 'shuttle schema', reporting unknown fields,
+values of the wrong type and missing required fields by their file and line.
+
+Without a script the actions of all scripts are checked for problems otherwise
+only found when they are run, eg. actions without a shell, invalid durations,
+interpreters missing from PATH and working directories outside the project.
+
+With a script its actions and arguments are validated. Arguments are checked
+for being known, required arguments being supplied and mutually exclusive
+arguments. All problems are reported at once.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := contextProvider()
+			if err != nil {
+				return err
+			}
+
+			err = validateSchemas(context)
+			if err != nil {
+				return err
+			}
+
+			registry := executors.NewDefaultRegistry()
+			if len(args) == 0 {
+				err = registry.ValidateActions(context)
+				if err != nil {
+					return err
+				}
+				uii.Infoln("Actions of all scripts are valid")
+				return nil
+			}
+
+			script := args[0]
+			err = registry.ValidateActions(context, script)
+			if err != nil {
+				return err
+			}
+			scriptArgs := append(append([]string{}, flagArgs...), args[1:]...)
+			err = executors.Validate(context, script, scriptArgs)
+			if err != nil {
+				return err
+			}
+			uii.Infoln("Actions and arguments of script '%s' are valid", script)
+			return nil
+		},
+	}
+
+	validateCmd.Flags().
+		StringArrayVar(&flagArgs, "arg", nil, "Argument on the form <argument>=<value> to validate. Can be repeated")
+
+	return validateCmd
+}
+
+// validateSchemas returns an error listing the problems of the files of the
+// project against their schema.
+func validateSchemas(context config.ShuttleProjectContext) error {
+	problems, err := context.SchemaProblems()
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	var s strings.Builder
+	s.WriteString("Configuration not valid:")
+	for _, problem := range problems {
+		fmt.Fprintf(&s, "\n %s", problem)
+	}
+	return errors.NewExitCode(1, "%s", s.String())
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []testCase{
+		{
+			name:      "valid arguments",
+			input:     args("-p", "testdata/project", "validate", "required_arg", "foo=bar"),
+			stdoutput: "",
+			erroutput: "Actions and arguments of script 'required_arg' are valid\n",
+			err:       nil,
+		},
+		{
+			name:      "valid arguments as flags",
+			input:     args("-p", "testdata/project", "validate", "required_arg", "--arg", "foo=bar"),
+			stdoutput: "",
+			erroutput: "Actions and arguments of script 'required_arg' are valid\n",
+			err:       nil,
+		},
+		{
+			name:      "all problems are reported",
+			input:     args("-p", "testdata/project", "validate", "required_arg", "bar=baz", "--arg", "baz"),
+			stdoutput: "",
+			erroutput: `Error: exit code 2 - Arguments not valid:
+ 'bar' unknown
+ 'baz' not <argument>=<value>
+ 'foo' not supplied but is required
+
+Script 'required_arg' accepts the following arguments:
+  foo (required)
+`,
+			err: errors.New(`exit code 2 - Arguments not valid:
+ 'bar' unknown
+ 'baz' not <argument>=<value>
+ 'foo' not supplied but is required
+
+Script 'required_arg' accepts the following arguments:
+  foo (required)`),
+		},
+		{
+			name:      "unknown script",
+			input:     args("-p", "testdata/project", "validate", "unknown"),
+			stdoutput: "",
+			erroutput: "Error: exit code 2 - Script 'unknown' not found\n",
+			err:       errors.New("exit code 2 - Script 'unknown' not found"),
+		},
+	}
+	executeTestCases(t, testCases)
+}
+
+func TestValidate_actions(t *testing.T) {
+	invalidActions := `exit code 1 - Actions not valid:
+ Action 1 of script ` + "`build`" + ` has an invalid timeout 'soon': must be a positive duration, eg. 30s
+ Action 2 of script ` + "`build`" + ` has no shell, powershell, docker, task or wasm to run
+ Interpreter 'shuttle-missing-interpreter' of script ` + "`deploy`" + ` was not found on PATH
+ Working directory '../outside' of script ` + "`deploy`" + ` action 0 must be within the project
+ Action 1 of script ` + "`deploy`" + ` cannot be retried as it runs in the background`
+	testCases := []testCase{
+		{
+			name:      "all scripts",
+			input:     args("-p", "testdata/invalid-actions", "validate"),
+			stdoutput: "",
+			erroutput: "Error: " + invalidActions + "\n",
+			err:       errors.New(invalidActions),
+		},
+		{
+			name:      "single script",
+			input:     args("-p", "testdata/invalid-actions", "validate", "valid"),
+			stdoutput: "",
+			erroutput: "Actions and arguments of script 'valid' are valid\n",
+			err:       nil,
+		},
+		{
+			name:      "all scripts valid",
+			input:     args("-p", "testdata/project", "validate"),
+			stdoutput: "",
+			erroutput: "Actions of all scripts are valid\n",
+			err:       nil,
+		},
+		{
+			name:      "run fails before running any action",
+			input:     args("-p", "testdata/invalid-actions", "run", "build"),
+			stdoutput: "",
+			erroutput: `Error: exit code 1 - Actions not valid:
+ Action 1 of script ` + "`build`" + ` has an invalid timeout 'soon': must be a positive duration, eg. 30s
+ Action 2 of script ` + "`build`" + ` has no shell, powershell, docker, task or wasm to run
+`,
+			err: errors.New(`exit code 1 - Actions not valid:
+ Action 1 of script ` + "`build`" + ` has an invalid timeout 'soon': must be a positive duration, eg. 30s
+ Action 2 of script ` + "`build`" + ` has no shell, powershell, docker, task or wasm to run`),
+		},
+	}
+	executeTestCases(t, testCases)
+}
+
+func TestValidate_schema(t *testing.T) {
+	invalidSchema := `exit code 1 - Configuration not valid:
+ plan/plan.yaml:5:9: 'scripts.build.args.0' is missing required 'name'
+ plan/plan.yaml:8:17: 'scripts.build.actions.0.output' must be one of streaming, buffered but is 'fancy'
+ plan/plan.yaml:10:13: 'scripts.build.actions.0.upload.0' is missing required 'url'`
+	testCases := []testCase{
+		{
+			name:      "all scripts",
+			input:     args("-p", "testdata/invalid-schema", "validate"),
+			stdoutput: "",
+			erroutput: "Error: " + invalidSchema + "\n",
+			err:       errors.New(invalidSchema),
+		},
+		{
+			name:      "single script",
+			input:     args("-p", "testdata/invalid-schema", "validate", "build"),
+			stdoutput: "",
+			erroutput: "Error: " + invalidSchema + "\n",
+			err:       errors.New(invalidSchema),
+		},
+	}
+	executeTestCases(t, testCases)
+}
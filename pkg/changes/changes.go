@@ -0,0 +1,151 @@
+// Package changes detects the files of a git repository changed compared to a
+// base ref such that work on unchanged parts of a monorepo can be skipped.
+package changes
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	go_cmd "github.com/go-cmd/cmd"
+)
+
+// BaseEnv is the environment variable holding the ref changes are compared to
+// instead of the default branch, eg. origin/develop
+const BaseEnv = "SHUTTLE_CHANGED_BASE"
+
+// ErrNotRepository is returned when changes are detected outside a git
+// repository.
+var ErrNotRepository = errors.New("not a git repository")
+
+// defaultBranch is the branch changes are compared to if origin has no
+// default branch
+const defaultBranch = "main"
+
+// Shallow clones are deepened deepenCommits at a time up to maxDeepens times
+// until the merge base is found before they are fetched completely.
+const (
+	deepenCommits = 100
+	maxDeepens    = 5
+)
+
+// gitArgs runs git with args in dir.
+func gitArgs(dir string, args ...string) go_cmd.Status {
+	execCmd := go_cmd.NewCmdOptions(go_cmd.Options{Buffered: true}, "git", args...)
+	execCmd.Dir = dir
+	return <-execCmd.Start()
+}
+
+// Base returns the ref changes in the repository at dir are compared to.
+// It is the ref of SHUTTLE_CHANGED_BASE or the default branch of origin, eg.
+// origin/main, falling back to main for repositories without origin.
+func Base(dir string) string {
+	if base := os.Getenv(BaseEnv); base != "" {
+		return base
+	}
+	head := gitArgs(dir, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD")
+	if head.Exit == 0 && len(head.Stdout) != 0 {
+		return head.Stdout[0]
+	}
+	if hasRemote(dir, "origin") {
+		return "origin/" + defaultBranch
+	}
+	return defaultBranch
+}
+
+// Files returns the paths, relative to dir, of the files within dir
+// changed since base including uncommitted and untracked files. Changes are
+// compared to the merge base of HEAD and base, eg. origin/main, such that
+// commits of base not in HEAD are not changes. Only the uncommitted changes
+// are returned if base is empty. If pathspecs are given only files matching
+// them are returned, eg. services/api or '*.go'.
+//
+// A remote base missing in the repository, eg. of a CI clone of a single
+// branch, is fetched and shallow clones are deepened until the merge base is
+// found. If dir is not within a git repository ErrNotRepository is returned.
+func Files(dir, base string, pathspecs ...string) ([]string, error) {
+	if status := gitArgs(dir, "rev-parse", "--is-inside-work-tree"); status.Exit != 0 {
+		return nil, ErrNotRepository
+	}
+	commit := "HEAD"
+	if base != "" {
+		var err error
+		commit, err = mergeBase(dir, base)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	diff := gitArgs(dir, append([]string{"diff", "--name-only", "--relative", commit, "--"}, pathspecs...)...)
+	if diff.Exit != 0 {
+		return nil, fmt.Errorf("diff against %s: %s", commit, strings.Join(diff.Stderr, "\n"))
+	}
+	untracked := gitArgs(dir, append([]string{"ls-files", "--others", "--exclude-standard", "--"}, pathspecs...)...)
+	if untracked.Exit != 0 {
+		return nil, fmt.Errorf("list untracked files: %s", strings.Join(untracked.Stderr, "\n"))
+	}
+	var files []string
+	for _, file := range append(diff.Stdout, untracked.Stdout...) {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// mergeBase returns the merge base of HEAD and base fetching base and history
+// of shallow clones as needed.
+func mergeBase(dir, base string) (string, error) {
+	remote, branch := remoteBranch(dir, base)
+	if remote != "" && gitArgs(dir, "rev-parse", "--verify", "--quiet", base+"^{commit}").Exit != 0 {
+		args := []string{"fetch", "--quiet", "--no-tags"}
+		if isShallow(dir) {
+			args = append(args, fmt.Sprintf("--depth=%d", deepenCommits))
+		}
+		args = append(args, remote, fmt.Sprintf("+refs/heads/%s:refs/remotes/%s/%s", branch, remote, branch))
+		if fetch := gitArgs(dir, args...); fetch.Exit != 0 {
+			return "", fmt.Errorf("fetch base '%s': %s", base, strings.Join(fetch.Stderr, "\n"))
+		}
+	}
+	for deepens := 0; ; deepens++ {
+		status := gitArgs(dir, "merge-base", "HEAD", base)
+		if status.Exit == 0 && len(status.Stdout) != 0 {
+			return status.Stdout[0], nil
+		}
+		if remote == "" || !isShallow(dir) {
+			return "", fmt.Errorf("no common commit of HEAD and '%s': %s", base, strings.Join(status.Stderr, "\n"))
+		}
+		args := []string{"fetch", "--quiet", "--no-tags", fmt.Sprintf("--deepen=%d", deepenCommits), remote, branch}
+		if deepens == maxDeepens {
+			args = []string{"fetch", "--quiet", "--no-tags", "--unshallow", remote, branch}
+		}
+		if fetch := gitArgs(dir, args...); fetch.Exit != 0 {
+			return "", fmt.Errorf("fetch history of '%s': %s", base, strings.Join(fetch.Stderr, "\n"))
+		}
+	}
+}
+
+// remoteBranch returns the remote and branch of ref if it is a branch of a
+// remote of the repository at dir, eg. origin and main of origin/main.
+func remoteBranch(dir, ref string) (string, string) {
+	remote, branch, ok := strings.Cut(strings.TrimPrefix(ref, "refs/remotes/"), "/")
+	if !ok || branch == "" || !hasRemote(dir, remote) {
+		return "", ""
+	}
+	return remote, branch
+}
+
+func hasRemote(dir, name string) bool {
+	for _, remote := range gitArgs(dir, "remote").Stdout {
+		if remote == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isShallow(dir string) bool {
+	status := gitArgs(dir, "rev-parse", "--is-shallow-repository")
+	return status.Exit == 0 && len(status.Stdout) != 0 && status.Stdout[0] == "true"
+}
@@ -0,0 +1,145 @@
+package changes
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFiles(t *testing.T) {
+	t.Run("not a repository", func(t *testing.T) {
+		_, err := Files(t.TempDir(), "")
+
+		assert.ErrorIs(t, err, ErrNotRepository)
+	})
+
+	t.Run("uncommitted and untracked files", func(t *testing.T) {
+		dir := initRepository(t)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "plan.yaml"), []byte("changed"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "new.sh"), []byte("new"), 0o644))
+
+		files, err := Files(dir, "")
+
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"plan.yaml", "new.sh"}, files)
+	})
+
+	t.Run("commits since base", func(t *testing.T) {
+		dir := initRepository(t)
+		gitCommand(t, dir, "branch", "base")
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "services", "api"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "services", "api", "main.go"), []byte("package main"), 0o644))
+		gitCommand(t, dir, "add", ".")
+		gitCommand(t, dir, "-c", "user.name=shuttle", "-c", "user.email=shuttle@example.com", "commit", "--quiet", "-m", "api")
+
+		files, err := Files(dir, "base")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"services/api/main.go"}, files)
+
+		files, err = Files(filepath.Join(dir, "services"), "base")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"api/main.go"}, files)
+
+		files, err = Files(dir, "")
+		assert.NoError(t, err)
+		assert.Empty(t, files)
+	})
+
+	t.Run("pathspecs", func(t *testing.T) {
+		dir := initRepository(t)
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "services", "api"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "services", "api", "main.go"), []byte("package main"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "plan.yaml"), []byte("changed"), 0o644))
+
+		files, err := Files(dir, "", "services/api")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"services/api/main.go"}, files)
+
+		files, err = Files(dir, "", "*.go")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"services/api/main.go"}, files)
+
+		files, err = Files(dir, "", "services/web")
+		assert.NoError(t, err)
+		assert.Empty(t, files)
+	})
+
+	t.Run("shallow clone of a branch", func(t *testing.T) {
+		origin := initRepository(t)
+		gitCommand(t, origin, "branch", "-M", "main")
+		for i := 0; i < 3; i++ {
+			commitFile(t, origin, fmt.Sprintf("main-%d.txt", i))
+		}
+		gitCommand(t, origin, "checkout", "--quiet", "-b", "feature", "HEAD~1")
+		commitFile(t, origin, "feature.txt")
+		clone := t.TempDir()
+		gitCommand(t, clone, "clone", "--quiet", "--depth=1", "--branch=feature", "--single-branch", "file://"+origin, ".")
+
+		files, err := Files(clone, "origin/main")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"feature.txt"}, files)
+	})
+
+	t.Run("unknown base", func(t *testing.T) {
+		dir := initRepository(t)
+
+		_, err := Files(dir, "missing")
+
+		assert.ErrorContains(t, err, "no common commit of HEAD and 'missing'")
+	})
+}
+
+func TestBase(t *testing.T) {
+	t.Run("environment", func(t *testing.T) {
+		t.Setenv(BaseEnv, "origin/develop")
+
+		assert.Equal(t, "origin/develop", Base(initRepository(t)))
+	})
+
+	t.Run("without origin", func(t *testing.T) {
+		t.Setenv(BaseEnv, "")
+
+		assert.Equal(t, "main", Base(initRepository(t)))
+	})
+
+	t.Run("default branch of origin", func(t *testing.T) {
+		t.Setenv(BaseEnv, "")
+		origin := initRepository(t)
+		gitCommand(t, origin, "branch", "-M", "trunk")
+		clone := t.TempDir()
+		gitCommand(t, clone, "clone", "--quiet", origin, ".")
+
+		assert.Equal(t, "origin/trunk", Base(clone))
+	})
+}
+
+func commitFile(t *testing.T, dir, name string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644))
+	gitCommand(t, dir, "add", name)
+	gitCommand(t, dir, "-c", "user.name=shuttle", "-c", "user.email=shuttle@example.com", "commit", "--quiet", "-m", name)
+}
+
+func gitCommand(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
+func initRepository(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "plan.yaml"), []byte("scripts: {}"), 0o644))
+	gitCommand(t, dir, "init", "--quiet")
+	gitCommand(t, dir, "add", ".")
+	gitCommand(t, dir, "-c", "user.name=shuttle", "-c", "user.email=shuttle@example.com", "commit", "--quiet", "-m", "initial")
+	return dir
+}
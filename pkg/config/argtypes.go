@@ -0,0 +1,155 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// Types of script arguments
+const (
+	ArgTypeString = "string"
+	ArgTypeInt    = "int"
+	ArgTypeBool   = "bool"
+	ArgTypeEnum   = "enum"
+	ArgTypePath   = "path"
+)
+
+// Constraints returns a description of the type, default and pattern of the
+// argument, eg. "enum: dev|prod, default: dev", or an empty string for string
+// arguments without any.
+func (a ShuttleScriptArgs) Constraints() string {
+	var constraints []string
+	switch a.Type {
+	case "", ArgTypeString:
+	case ArgTypeEnum:
+		constraints = append(constraints, fmt.Sprintf("enum: %s", strings.Join(a.Values, "|")))
+	default:
+		constraints = append(constraints, a.Type)
+	}
+	if a.Default != "" {
+		constraints = append(constraints, fmt.Sprintf("default: %s", a.Default))
+	}
+	if a.Pattern != "" {
+		constraints = append(constraints, fmt.Sprintf("pattern: %s", a.Pattern))
+	}
+	return strings.Join(constraints, ", ")
+}
+
+// ValueProblem returns a description of why value is not valid for the
+// argument or an empty string if it is. Relative paths are resolved from
+// projectPath.
+func (a ShuttleScriptArgs) ValueProblem(value, projectPath string) string {
+	switch a.Type {
+	case ArgTypeInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Sprintf("must be an integer but was '%s'", value)
+		}
+	case ArgTypeBool:
+		if value != "true" && value != "false" {
+			return fmt.Sprintf("must be true or false but was '%s'", value)
+		}
+	case ArgTypeEnum:
+		if !contains(a.Values, value) {
+			return fmt.Sprintf("must be one of %s but was '%s'", strings.Join(a.Values, ", "), value)
+		}
+	case ArgTypePath:
+		path := value
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(projectPath, path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Sprintf("must be an existing path but '%s' does not exist", value)
+		}
+	}
+	if a.Pattern != "" {
+		// patterns are validated when the plan is loaded
+		re, err := regexp.Compile(a.Pattern)
+		if err == nil && !re.MatchString(value) {
+			return fmt.Sprintf("must match pattern '%s' but was '%s'", a.Pattern, value)
+		}
+	}
+	return ""
+}
+
+// declarationProblem returns a description of why the declaration of the
+// argument is invalid or an empty string if it is valid.
+func (a ShuttleScriptArgs) declarationProblem() string {
+	switch a.Type {
+	case "", ArgTypeString, ArgTypeInt, ArgTypeBool, ArgTypePath:
+		if len(a.Values) != 0 {
+			return "has values but is not of type enum"
+		}
+	case ArgTypeEnum:
+		if len(a.Values) == 0 {
+			return "is of type enum but has no values"
+		}
+	default:
+		return fmt.Sprintf(
+			"has invalid type '%s': must be one of %s, %s, %s, %s or %s",
+			a.Type,
+			ArgTypeString,
+			ArgTypeInt,
+			ArgTypeBool,
+			ArgTypeEnum,
+			ArgTypePath,
+		)
+	}
+	if a.Pattern != "" {
+		if _, err := regexp.Compile(a.Pattern); err != nil {
+			return fmt.Sprintf("has invalid pattern '%s': %v", a.Pattern, err)
+		}
+	}
+	if a.Default != "" {
+		if a.Required {
+			return "is required and cannot have a default"
+		}
+		// paths are only checked once the script is run as the default may be
+		// created by another script
+		if a.Type != ArgTypePath {
+			if problem := a.ValueProblem(a.Default, ""); problem != "" {
+				return fmt.Sprintf("has invalid default: %s", problem)
+			}
+		}
+	}
+	return ""
+}
+
+// validateArgDeclarations returns an error listing every script argument with
+// an invalid type, default or pattern.
+func validateArgDeclarations(scripts map[string]ShuttlePlanScript) error {
+	var problems []string
+	for scriptName, script := range scripts {
+		for _, arg := range script.Args {
+			if problem := arg.declarationProblem(); problem != "" {
+				problems = append(problems, fmt.Sprintf("%s.args.%s%s %s", scriptName, arg.Name, script.sourceSuffix(), problem))
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return errors.NewExitCode(1, "Script arguments are invalid:\n  %s", strings.Join(problems, "\n  "))
+}
+
+// ArgsWithDefaults returns args with the default of every argument of the
+// script that is not supplied, ie. missing or empty.
+func (s ShuttlePlanScript) ArgsWithDefaults(args map[string]string) map[string]string {
+	resolved := make(map[string]string, len(args))
+	for name, value := range args {
+		resolved[name] = value
+	}
+	for _, arg := range s.Args {
+		if arg.Default != "" && resolved[arg.Name] == "" {
+			resolved[arg.Name] = arg.Default
+		}
+	}
+	return resolved
+}
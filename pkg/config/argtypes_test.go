@@ -0,0 +1,62 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShuttleScriptArgs_ValueProblem(t *testing.T) {
+	tt := []struct {
+		name    string
+		arg     ShuttleScriptArgs
+		value   string
+		problem string
+	}{
+		{name: "string", arg: ShuttleScriptArgs{}, value: "anything"},
+		{name: "int", arg: ShuttleScriptArgs{Type: ArgTypeInt}, value: "-3"},
+		{name: "invalid int", arg: ShuttleScriptArgs{Type: ArgTypeInt}, value: "3.5", problem: "must be an integer but was '3.5'"},
+		{name: "bool", arg: ShuttleScriptArgs{Type: ArgTypeBool}, value: "false"},
+		{name: "invalid bool", arg: ShuttleScriptArgs{Type: ArgTypeBool}, value: "1", problem: "must be true or false but was '1'"},
+		{name: "enum", arg: ShuttleScriptArgs{Type: ArgTypeEnum, Values: []string{"dev", "prod"}}, value: "prod"},
+		{name: "invalid enum", arg: ShuttleScriptArgs{Type: ArgTypeEnum, Values: []string{"dev", "prod"}}, value: "qa", problem: "must be one of dev, prod but was 'qa'"},
+		{name: "path relative to project", arg: ShuttleScriptArgs{Type: ArgTypePath}, value: "argtypes.go"},
+		{name: "missing path", arg: ShuttleScriptArgs{Type: ArgTypePath}, value: "missing.go", problem: "must be an existing path but 'missing.go' does not exist"},
+		{name: "pattern", arg: ShuttleScriptArgs{Type: ArgTypeInt, Pattern: "^[1-9]$"}, value: "10", problem: "must match pattern '^[1-9]$' but was '10'"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.problem, tc.arg.ValueProblem(tc.value, "."))
+		})
+	}
+}
+
+func TestShuttleScriptArgs_declarationProblem(t *testing.T) {
+	tt := []struct {
+		name    string
+		arg     ShuttleScriptArgs
+		problem string
+	}{
+		{name: "untyped", arg: ShuttleScriptArgs{Default: "x"}},
+		{name: "values without enum", arg: ShuttleScriptArgs{Type: ArgTypeString, Values: []string{"a"}}, problem: "has values but is not of type enum"},
+		{name: "invalid pattern", arg: ShuttleScriptArgs{Pattern: "[a-"}, problem: "has invalid pattern '[a-': error parsing regexp: missing closing ]: `[a-`"},
+		{name: "required with default", arg: ShuttleScriptArgs{Required: true, Default: "x"}, problem: "is required and cannot have a default"},
+		{name: "invalid enum default", arg: ShuttleScriptArgs{Type: ArgTypeEnum, Values: []string{"dev"}, Default: "prod"}, problem: "has invalid default: must be one of dev but was 'prod'"},
+		{name: "path default not checked", arg: ShuttleScriptArgs{Type: ArgTypePath, Default: "dist"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.problem, tc.arg.declarationProblem())
+		})
+	}
+}
+
+func TestShuttlePlanScript_ArgsWithDefaults(t *testing.T) {
+	script := ShuttlePlanScript{Args: []ShuttleScriptArgs{
+		{Name: "env", Default: "dev"},
+		{Name: "region", Default: "eu"},
+		{Name: "tag"},
+	}}
+
+	assert.Equal(t, map[string]string{"env": "dev", "region": "us", "tag": ""}, script.ArgsWithDefaults(map[string]string{"env": "", "region": "us", "tag": ""}))
+}
@@ -0,0 +1,28 @@
+package config
+
+import "fmt"
+
+// ShuttleRunCheck describes a precondition verified before any action of a
+// script is run. Exactly one of URL or Host must be set.
+type ShuttleRunCheck struct {
+	Name string `yaml:"name"`
+	// URL is requested with a GET request and must respond with one of Status.
+	URL string `yaml:"url"`
+	// Status lists the accepted status codes of URL. Defaults to any 2xx
+	// status code.
+	Status []int `yaml:"status"`
+	// Host is a host:port address that must accept TCP connections.
+	Host string `yaml:"host"`
+	// Timeout is the duration, eg. 5s, the check may take. Defaults to 10s.
+	Timeout string `yaml:"timeout"`
+}
+
+func (c ShuttleRunCheck) String() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	if c.URL != "" {
+		return fmt.Sprintf("GET %s", c.URL)
+	}
+	return fmt.Sprintf("connect %s", c.Host)
+}
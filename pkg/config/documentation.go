@@ -7,6 +7,7 @@ import (
 
 	"github.com/lunarway/shuttle/pkg/errors"
 	"github.com/lunarway/shuttle/pkg/git"
+	"github.com/lunarway/shuttle/pkg/oci"
 )
 
 // DocumentationURL returns a URL pointing to plan documentation if any is
@@ -28,6 +29,8 @@ func (p *ShuttleProjectContext) DocumentationURL() (string, error) {
 		return normalizeGitPlan(git.ParsePlan(ref))
 	case isHTTPSPlan(ref):
 		return ref, nil
+	case oci.IsPlan(ref):
+		return "", errors.NewExitCode(2, "OCI plan has no documentation")
 	case filepath.IsAbs(ref), strings.HasPrefix(ref, "./"), strings.HasPrefix(ref, "../"):
 		return "", errors.NewExitCode(2, "Local plan has no documentation")
 	default:
@@ -52,6 +52,13 @@ func TestShuttleProjectContext_Documentation(t *testing.T) {
 			result:  "https://github.com/lunarway/shuttle",
 			err:     nil,
 		},
+		{
+			name:    "no explicit docs and oci plan",
+			planRef: "oci://ghcr.io/lunarway/plan:v1.2.3",
+			docsRef: "",
+			result:  "",
+			err:     errors.New("exit code 2 - OCI plan has no documentation"),
+		},
 		{
 			name:    "no explicit docs and git plan ssh reference",
 			planRef: "git://git@github.com:lunarway/shuttle-example-go-plan.git",
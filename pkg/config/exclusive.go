@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// ShuttleExclusiveArgs describes a group of script arguments of which at most
+// one may be supplied. If Required is set exactly one must be supplied.
+type ShuttleExclusiveArgs struct {
+	Args     []string `yaml:"args"`
+	Required bool     `yaml:"required"`
+}
+
+// ValidateExclusiveArgs checks that args satisfy the exclusive argument groups
+// of the script. An argument is considered supplied if it has a non-empty
+// value.
+func (s ShuttlePlanScript) ValidateExclusiveArgs(script string, args map[string]string) error {
+	problems, err := s.ExclusiveArgsProblems(script, args)
+	if err != nil {
+		return err
+	}
+	if len(problems) != 0 {
+		return errors.NewExitCode(2, "%s", problems[0])
+	}
+	return nil
+}
+
+// ExclusiveArgsProblems returns a description of each exclusive argument group
+// of the script that args do not satisfy. An error is returned if the groups
+// themselves are invalid.
+func (s ShuttlePlanScript) ExclusiveArgsProblems(script string, args map[string]string) ([]string, error) {
+	var problems []string
+	for _, group := range s.Exclusive {
+		var supplied []string
+		for _, name := range group.Args {
+			if !s.hasArg(name) {
+				return nil, errors.NewExitCode(
+					1,
+					"Exclusive arguments of script '%s'%s reference unknown argument '%s'",
+					script,
+					s.sourceSuffix(),
+					name,
+				)
+			}
+			if args[name] != "" {
+				supplied = append(supplied, name)
+			}
+		}
+
+		switch {
+		case len(supplied) > 1:
+			problems = append(problems, fmt.Sprintf(
+				"Arguments %s of script '%s' are mutually exclusive. Supply only one of them",
+				quoteArgs(supplied, "and"),
+				script,
+			))
+		case len(supplied) == 0 && group.Required:
+			problems = append(problems, fmt.Sprintf(
+				"One of the arguments %s of script '%s' is required",
+				quoteArgs(group.Args, "or"),
+				script,
+			))
+		}
+	}
+	return problems, nil
+}
+
+func (s ShuttlePlanScript) hasArg(name string) bool {
+	for _, arg := range s.Args {
+		if arg.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteArgs formats names as a quoted list, eg. 'a', 'b' and 'c'.
+func quoteArgs(names []string, conjunction string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "'" + name + "'"
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " " + conjunction + " " + quoted[len(quoted)-1]
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShuttlePlanScript_ValidateExclusiveArgs(t *testing.T) {
+	script := func(required bool, group ...string) ShuttlePlanScript {
+		return ShuttlePlanScript{
+			Args: []ShuttleScriptArgs{
+				{Name: "from-tag"},
+				{Name: "from-branch"},
+				{Name: "from-commit"},
+			},
+			Exclusive: []ShuttleExclusiveArgs{
+				{Args: group, Required: required},
+			},
+		}
+	}
+
+	tt := []struct {
+		name   string
+		script ShuttlePlanScript
+		args   map[string]string
+		err    error
+	}{
+		{
+			name:   "no groups",
+			script: ShuttlePlanScript{},
+			args:   map[string]string{"from-tag": "v1"},
+			err:    nil,
+		},
+		{
+			name:   "single argument supplied",
+			script: script(false, "from-tag", "from-branch"),
+			args:   map[string]string{"from-tag": "v1", "from-branch": ""},
+			err:    nil,
+		},
+		{
+			name:   "none supplied in optional group",
+			script: script(false, "from-tag", "from-branch"),
+			args:   map[string]string{"from-tag": "", "from-branch": ""},
+			err:    nil,
+		},
+		{
+			name:   "two arguments supplied",
+			script: script(false, "from-tag", "from-branch"),
+			args:   map[string]string{"from-tag": "v1", "from-branch": "main"},
+			err: errors.New(
+				"exit code 2 - Arguments 'from-tag' and 'from-branch' of script 'deploy' are mutually exclusive. Supply only one of them",
+			),
+		},
+		{
+			name:   "three arguments supplied",
+			script: script(false, "from-tag", "from-branch", "from-commit"),
+			args:   map[string]string{"from-tag": "v1", "from-branch": "main", "from-commit": "abc"},
+			err: errors.New(
+				"exit code 2 - Arguments 'from-tag', 'from-branch' and 'from-commit' of script 'deploy' are mutually exclusive. Supply only one of them",
+			),
+		},
+		{
+			name:   "none supplied in required group",
+			script: script(true, "from-tag", "from-branch"),
+			args:   map[string]string{},
+			err: errors.New(
+				"exit code 2 - One of the arguments 'from-tag' or 'from-branch' of script 'deploy' is required",
+			),
+		},
+		{
+			name:   "unknown argument in group",
+			script: script(false, "from-tag", "from-release"),
+			args:   map[string]string{},
+			err: errors.New(
+				"exit code 1 - Exclusive arguments of script 'deploy' reference unknown argument 'from-release'",
+			),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.script.ValidateExclusiveArgs("deploy", tc.args)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
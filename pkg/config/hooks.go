@@ -0,0 +1,37 @@
+package config
+
+// ShuttleHooks are actions run around every script of a plan or project, eg.
+// to notify about failures or record audit log entries.
+type ShuttleHooks struct {
+	// PreRun actions run before the script. A failing preRun action fails the
+	// run without running the script.
+	PreRun []ShuttleAction `yaml:"preRun"`
+	// PostRun actions run after the script whether it succeeded or not.
+	PostRun []ShuttleAction `yaml:"postRun"`
+	// OnFailure actions run after the script if it failed.
+	OnFailure []ShuttleAction `yaml:"onFailure"`
+	// OnCancel actions run after the script if it was cancelled.
+	OnCancel []ShuttleAction `yaml:"onCancel"`
+}
+
+// IsEmpty returns true if no hooks are defined.
+func (h ShuttleHooks) IsEmpty() bool {
+	return len(h.PreRun) == 0 && len(h.PostRun) == 0 && len(h.OnFailure) == 0 && len(h.OnCancel) == 0
+}
+
+// append returns the hooks of h followed by those of o.
+func (h ShuttleHooks) append(o ShuttleHooks) ShuttleHooks {
+	return ShuttleHooks{
+		PreRun:    appendActions(h.PreRun, o.PreRun),
+		PostRun:   appendActions(h.PostRun, o.PostRun),
+		OnFailure: appendActions(h.OnFailure, o.OnFailure),
+		OnCancel:  appendActions(h.OnCancel, o.OnCancel),
+	}
+}
+
+func appendActions(actions, other []ShuttleAction) []ShuttleAction {
+	if len(actions)+len(other) == 0 {
+		return nil
+	}
+	return append(append([]ShuttleAction{}, actions...), other...)
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// InitDirectory is the plan relative directory of the templates rendered into
+// projects initialized with the plan unless its init manifest lists files.
+const InitDirectory = "init"
+
+// ShuttlePlanInit describes how shuttle init scaffolds projects using the
+// plan.
+type ShuttlePlanInit struct {
+	// Vars are the variables of shuttle.yaml prompted for. Their type,
+	// values, default and pattern work like those of script arguments.
+	Vars []ShuttleScriptArgs `yaml:"vars"`
+	// Files are the templates rendered into the project. Defaults to all
+	// files in the init directory of the plan.
+	Files []ShuttleInitFile `yaml:"files"`
+}
+
+// ShuttleInitFile is a template of the plan rendered into a new project.
+type ShuttleInitFile struct {
+	// Template is the plan relative path of the template.
+	Template string `yaml:"template"`
+	// Path is the project relative path the template is rendered to. It is a
+	// template itself, eg. cmd/{{.Vars.service}}/main.go. Defaults to the
+	// path of the template within the init directory without a .tmpl suffix.
+	Path string `yaml:"path"`
+}
+
+// InitFiles returns the files rendered into projects initialized with the
+// plan at planPath sorted by path.
+func (i ShuttlePlanInit) InitFiles(planPath string) ([]ShuttleInitFile, error) {
+	files := append([]ShuttleInitFile{}, i.Files...)
+	if len(files) == 0 {
+		root := filepath.Join(planPath, InitDirectory)
+		err := filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			template, err := filepath.Rel(planPath, file)
+			if err != nil {
+				return err
+			}
+			files = append(files, ShuttleInitFile{Template: filepath.ToSlash(template)})
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errors.NewExitCode(1, "Failed to read init templates of plan: %v", err)
+		}
+	}
+
+	for index, file := range files {
+		if !withinDirectory(file.Template) {
+			return nil, errors.NewExitCode(1, "Init template '%s' must be relative to and within the plan directory", file.Template)
+		}
+		if file.Path == "" {
+			file.Path = strings.TrimSuffix(strings.TrimPrefix(path.Clean(file.Template), InitDirectory+"/"), ".tmpl")
+		}
+		files[index] = file
+	}
+	sort.SliceStable(files, func(a, b int) bool { return files[a].Path < files[b].Path })
+	return files, nil
+}
+
+// withinDirectory returns whether the slash separated path is relative to and
+// within its directory.
+func withinDirectory(name string) bool {
+	return name != "" && !filepath.IsAbs(name) && !path.IsAbs(name) &&
+		path.Clean(name) != ".." && !strings.HasPrefix(path.Clean(name), "../")
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShuttlePlanInit_InitFiles(t *testing.T) {
+	planPath := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(planPath, "init", "deploy"), 0o755))
+	for _, name := range []string{"README.md.tmpl", "deploy/values.yaml", ".gitignore"} {
+		require.NoError(t, os.WriteFile(filepath.Join(planPath, "init", name), nil, 0o644))
+	}
+
+	tt := []struct {
+		name     string
+		planPath string
+		init     ShuttlePlanInit
+		files    []ShuttleInitFile
+		err      string
+	}{
+		{
+			name:     "init directory",
+			planPath: planPath,
+			files: []ShuttleInitFile{
+				{Template: "init/.gitignore", Path: ".gitignore"},
+				{Template: "init/README.md.tmpl", Path: "README.md"},
+				{Template: "init/deploy/values.yaml", Path: "deploy/values.yaml"},
+			},
+		},
+		{
+			name:     "no init directory",
+			planPath: t.TempDir(),
+			files:    []ShuttleInitFile{},
+		},
+		{
+			name:     "listed files",
+			planPath: planPath,
+			init: ShuttlePlanInit{Files: []ShuttleInitFile{
+				{Template: "templates/main.go.tmpl", Path: "cmd/{{.Vars.service}}/main.go"},
+				{Template: "init/README.md.tmpl"},
+			}},
+			files: []ShuttleInitFile{
+				{Template: "init/README.md.tmpl", Path: "README.md"},
+				{Template: "templates/main.go.tmpl", Path: "cmd/{{.Vars.service}}/main.go"},
+			},
+		},
+		{
+			name:     "template outside plan",
+			planPath: planPath,
+			init:     ShuttlePlanInit{Files: []ShuttleInitFile{{Template: "../secrets"}}},
+			err:      "exit code 1 - Init template '../secrets' must be relative to and within the plan directory",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			files, err := tc.init.InitFiles(tc.planPath)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.files, files)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// PlanLockFile is the name of the file in the project pinning its plan
+const PlanLockFile = "shuttle.lock"
+
+// PlanLock pins the plan of a project to an exact revision such that all
+// commands use the same plan until it is locked again.
+type PlanLock struct {
+	// Plan is the plan of shuttle.yaml the lock applies to
+	Plan string `yaml:"plan"`
+	// Commit is the git commit of a git plan
+	Commit string `yaml:"commit,omitempty"`
+	// Digest is the manifest digest of an OCI plan
+	Digest string `yaml:"digest,omitempty"`
+}
+
+const planLockHeader = "# Generated by shuttle plan lock. Do not edit by hand.\n"
+
+// ReadPlanLock returns the plan lock of the project or nil if it has none.
+func ReadPlanLock(projectPath string) (*PlanLock, error) {
+	lockPath := path.Join(projectPath, PlanLockFile)
+	content, err := os.ReadFile(lockPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read plan lock: %w", err)
+	}
+	var lock PlanLock
+	err = yaml.UnmarshalStrict(content, &lock)
+	if err != nil || lock.Plan == "" || (lock.Commit == "") == (lock.Digest == "") {
+		return nil, errors.NewExitCode(
+			2,
+			"Failed to load %s: it must have a plan and either a commit or a digest\n\nRun 'shuttle plan lock' to generate it again.",
+			PlanLockFile,
+		)
+	}
+	return &lock, nil
+}
+
+// WritePlanLock writes lock to the project.
+func WritePlanLock(projectPath string, lock PlanLock) error {
+	content, err := yaml.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("marshal plan lock: %w", err)
+	}
+	lockPath := path.Join(projectPath, PlanLockFile)
+	err = os.WriteFile(lockPath, append([]byte(planLockHeader), content...), 0o644)
+	if err != nil {
+		return fmt.Errorf("write plan lock '%s': %w", lockPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestPlanLock_readWrite(t *testing.T) {
+	projectPath := t.TempDir()
+
+	lock, err := ReadPlanLock(projectPath)
+	require.NoError(t, err)
+	assert.Nil(t, lock, "missing lock")
+
+	written := PlanLock{Plan: "https://github.com/lunarway/shuttle-example-go-plan.git", Commit: "46ce3cc"}
+	require.NoError(t, WritePlanLock(projectPath, written))
+	lock, err = ReadPlanLock(projectPath)
+	require.NoError(t, err)
+	assert.Equal(t, &written, lock)
+
+	require.NoError(t, os.WriteFile(path.Join(projectPath, PlanLockFile), []byte("plan: x\n"), 0o644))
+	_, err = ReadPlanLock(projectPath)
+	assert.EqualError(t, err, "exit code 2 - Failed to load shuttle.lock: it must have a plan and either a commit or a digest\n\nRun 'shuttle plan lock' to generate it again.")
+}
+
+func TestShuttleProjectContext_planLock(t *testing.T) {
+	const plan = "https://github.com/lunarway/shuttle-example-go-plan.git"
+	projectPath := t.TempDir()
+	require.NoError(t, WritePlanLock(projectPath, PlanLock{Plan: plan, Commit: "46ce3cc"}))
+
+	tt := []struct {
+		name         string
+		plan         string
+		planArgument string
+		updatePlan   bool
+		locked       bool
+	}{
+		{name: "locked", plan: plan, locked: true},
+		{name: "update plan", plan: plan, updatePlan: true, locked: false},
+		{name: "overloaded plan", plan: plan, planArgument: "#main", locked: false},
+		{name: "other plan", plan: "https://github.com/lunarway/other-plan.git", locked: false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := ShuttleProjectContext{ProjectPath: projectPath, Config: ShuttleConfig{Plan: tc.plan}}
+
+			lock, err := c.planLock(ui.Create(&bytes.Buffer{}, &bytes.Buffer{}), tc.planArgument, tc.updatePlan)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.locked, lock != nil)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+
+	"github.com/lunarway/shuttle/pkg/git"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// OriginProject is the origin of scripts defined in shuttle.yaml
+const OriginProject = "shuttle.yaml"
+
+// ShuttlePlanOverlay is a plan listed after the first plan of a project. Its
+// configuration is merged onto the plans before it.
+type ShuttlePlanOverlay struct {
+	// Plan is the plan as written in shuttle.yaml
+	Plan          string
+	LocalPlanPath string
+}
+
+// parsePlans returns the plans of the plan field of shuttle.yaml which is
+// either a single plan or a list of plans merged in order.
+func parsePlans(raw interface{}) ([]string, error) {
+	switch raw := raw.(type) {
+	case string:
+		return []string{raw}, nil
+	case []interface{}:
+		if len(raw) == 0 {
+			return nil, errors.New("the list of plans is empty")
+		}
+		plans := make([]string, len(raw))
+		seen := make(map[string]bool, len(raw))
+		for i, plan := range raw {
+			s, ok := plan.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("plan %d of the list of plans must be a non-empty string", i+1)
+			}
+			if seen[s] {
+				return nil, fmt.Errorf("plan '%s' is listed more than once", s)
+			}
+			seen[s] = true
+			plans[i] = s
+		}
+		return plans, nil
+	default:
+		return nil, errors.New("plan must be a plan, a list of plans or false")
+	}
+}
+
+// overlayShuttleDirectoryPath returns the directory an overlay plan is fetched
+// into. It is named by the plan such that reordering the plans of a project
+// does not fetch one plan on top of another.
+func overlayShuttleDirectoryPath(localShuttleDirectoryPath, plan string) string {
+	sum := sha256.Sum256([]byte(plan))
+	return path.Join(localShuttleDirectoryPath, "overlays", hex.EncodeToString(sum[:])[:12])
+}
+
+// fetchOverlays fetches the overlay plans of the project. Overlays are never
+// locked or overloaded.
+func (c *ShuttleProjectContext) fetchOverlays(uii *ui.UI, skipGitPlanPulling bool, cache git.PlanCache) error {
+	c.Overlays = nil
+	for _, plan := range c.Config.Overlays {
+		localPlanPath, err := FetchPlan(
+			plan,
+			c.ProjectPath,
+			overlayShuttleDirectoryPath(c.LocalShuttleDirectoryPath, plan),
+			uii,
+			skipGitPlanPulling,
+			"",
+			cache,
+			nil,
+		)
+		if err != nil {
+			return err
+		}
+		c.Overlays = append(c.Overlays, ShuttlePlanOverlay{Plan: plan, LocalPlanPath: localPlanPath})
+	}
+	return nil
+}
+
+// loadOverlays loads the overlay plans and merges them onto the plan in order.
+func (c *ShuttleProjectContext) loadOverlays() error {
+	for _, overlay := range c.Overlays {
+		var o ShuttlePlanConfiguration
+		_, err := o.Load(overlay.LocalPlanPath)
+		if err != nil {
+			return err
+		}
+		c.Plan.overlay(o, overlay.Plan, overlay.LocalPlanPath)
+	}
+	return nil
+}
+
+// overlay merges the configuration o of the plan at planPath onto p. Scripts,
+// variables and secrets of o replace those of p with the same name, guards and
+// requirements are appended, the shuttleVersion constraints must both be
+// satisfied and the policy, documentation, env and env file of o replace
+// those of p if set. Golang actions and the init of o are not used as shuttle
+// init initializes projects with a single plan.
+func (p *ShuttlePlanConfiguration) overlay(o ShuttlePlanConfiguration, origin, planPath string) {
+	if p.Scripts == nil {
+		p.Scripts = make(map[string]ShuttlePlanScript, len(o.Scripts))
+	}
+	for name, script := range o.Scripts {
+		script.Origin = origin
+		script.PlanPath = planPath
+		p.Scripts[name] = script
+	}
+	if p.Vars == nil {
+		p.Vars = make(map[string]interface{}, len(o.Vars))
+	}
+	for name, value := range o.Vars {
+		p.Vars[name] = value
+	}
+	if p.Secrets == nil && len(o.Secrets) > 0 {
+		p.Secrets = make(map[string]ShuttleSecret, len(o.Secrets))
+	}
+	for name, secret := range o.Secrets {
+		p.Secrets[name] = secret
+	}
+	p.Guards = append(p.Guards, o.Guards...)
+	p.Hooks = p.Hooks.append(o.Hooks)
+	p.Requires = p.Requires.append(o.Requires)
+	p.ShuttleVersion = joinConstraints(p.ShuttleVersion, o.ShuttleVersion)
+	for _, entry := range o.Path {
+		// entries are relative to the overlay and not the first plan
+		if entry != "" && !filepath.IsAbs(entry) {
+			entry = filepath.Join(planPath, entry)
+		}
+		p.Path = append(p.Path, entry)
+	}
+	if o.Policy.VariableNames != nil {
+		p.Policy = o.Policy
+	}
+	if o.Documentation != "" {
+		p.Documentation = o.Documentation
+	}
+	if o.Env.Inherit != "" {
+		p.Env = o.Env
+	}
+	if o.EnvFile != "" {
+		p.EnvFile = o.EnvFile
+	}
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePlans(t *testing.T) {
+	tt := []struct {
+		name  string
+		raw   interface{}
+		plans []string
+		err   string
+	}{
+		{name: "single plan", raw: "./plan", plans: []string{"./plan"}},
+		{name: "list of plans", raw: []interface{}{"./base", "./team"}, plans: []string{"./base", "./team"}},
+		{name: "empty list", raw: []interface{}{}, err: "the list of plans is empty"},
+		{name: "non-string plan", raw: []interface{}{"./base", 1}, err: "plan 2 of the list of plans must be a non-empty string"},
+		{name: "duplicate plan", raw: []interface{}{"./base", "./base"}, err: "plan './base' is listed more than once"},
+		{name: "map", raw: map[interface{}]interface{}{"a": "b"}, err: "plan must be a plan, a list of plans or false"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			plans, err := parsePlans(tc.raw)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.plans, plans)
+		})
+	}
+}
+
+func TestShuttlePlanConfiguration_overlay(t *testing.T) {
+	base := ShuttlePlanConfiguration{
+		Vars:          map[string]interface{}{"language": "go", "team": "platform"},
+		Documentation: "https://base.example.com",
+		EnvFile:       "base.env",
+		Scripts: map[string]ShuttlePlanScript{
+			"build": {Description: "Build from base", Origin: "base"},
+			"test":  {Description: "Test from base", Origin: "base"},
+		},
+		Guards:         []ShuttlePreflightCheck{{Name: "base"}},
+		Hooks:          ShuttleHooks{PostRun: []ShuttleAction{{Shell: "./base-audit.sh"}}},
+		Path:           []string{"bin"},
+		ShuttleVersion: ">= 0.24",
+		Requires: ShuttlePlanRequirements{
+			Shuttle:   ">= 0.25",
+			Executors: []string{ExecutorDocker},
+		},
+	}
+
+	base.overlay(ShuttlePlanConfiguration{
+		Vars: map[string]interface{}{"team": "payments"},
+		Scripts: map[string]ShuttlePlanScript{
+			"test": {Description: "Test from team"},
+		},
+		Guards:         []ShuttlePreflightCheck{{Name: "team"}},
+		Hooks:          ShuttleHooks{PostRun: []ShuttleAction{{Shell: "./team-audit.sh"}}, OnFailure: []ShuttleAction{{Shell: "./notify.sh"}}},
+		Path:           []string{"tools", "/usr/local/bin"},
+		ShuttleVersion: "< 2",
+		Requires: ShuttlePlanRequirements{
+			Shuttle: "< 1",
+			Tools:   []ShuttleToolRequirement{{Name: "kubectl", Version: ">= 1.28"}},
+		},
+	}, "team", "/plans/team")
+
+	assert.Equal(t, ShuttlePlanConfiguration{
+		Vars:          map[string]interface{}{"language": "go", "team": "payments"},
+		Documentation: "https://base.example.com",
+		EnvFile:       "base.env",
+		Scripts: map[string]ShuttlePlanScript{
+			"build": {Description: "Build from base", Origin: "base"},
+			"test":  {Description: "Test from team", Origin: "team", PlanPath: "/plans/team"},
+		},
+		Guards: []ShuttlePreflightCheck{{Name: "base"}, {Name: "team"}},
+		Hooks: ShuttleHooks{
+			PostRun:   []ShuttleAction{{Shell: "./base-audit.sh"}, {Shell: "./team-audit.sh"}},
+			OnFailure: []ShuttleAction{{Shell: "./notify.sh"}},
+		},
+		Path:           []string{"bin", "/plans/team/tools", "/usr/local/bin"},
+		ShuttleVersion: ">= 0.24, < 2",
+		Requires: ShuttlePlanRequirements{
+			Shuttle:   ">= 0.25, < 1",
+			Executors: []string{ExecutorDocker},
+			Tools:     []ShuttleToolRequirement{{Name: "kubectl", Version: ">= 1.28"}},
+		},
+	}, base)
+}
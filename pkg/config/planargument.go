@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/lunarway/shuttle/pkg/git"
+	"github.com/lunarway/shuttle/pkg/oci"
 )
 
 func isPlanArgumentAFilePlan(planArgument string) bool {
@@ -14,7 +15,7 @@ func isPlanArgumentAFilePlan(planArgument string) bool {
 }
 
 func isPlanArgumentAPlan(planArgument string) bool {
-	return planArgument != "" && (git.IsPlan(planArgument) || isPlanArgumentAFilePlan(planArgument))
+	return planArgument != "" && (git.IsPlan(planArgument) || oci.IsPlan(planArgument) || isPlanArgumentAFilePlan(planArgument))
 }
 
 func getPlanFromPlanArgument(planArgument string) string {
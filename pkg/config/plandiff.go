@@ -0,0 +1,173 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+)
+
+// PlanDiff describes how the scripts of a plan changed between two revisions.
+type PlanDiff struct {
+	// Added are the names of scripts only in the new revision
+	Added []string
+	// Removed are the names of scripts only in the old revision
+	Removed []string
+	// Changed are the scripts of both revisions that differ
+	Changed []ScriptDiff
+}
+
+// ScriptDiff describes how a script changed between two revisions of a plan.
+type ScriptDiff struct {
+	Name        string
+	AddedArgs   []ShuttleScriptArgs
+	RemovedArgs []ShuttleScriptArgs
+	ChangedArgs []ArgDiff
+	// Fields are the names of other changed fields of the script, eg. actions
+	// or description
+	Fields []string
+}
+
+// ArgDiff is an argument of a script that changed between two revisions.
+type ArgDiff struct {
+	From ShuttleScriptArgs
+	To   ShuttleScriptArgs
+}
+
+// Empty returns whether no scripts changed.
+func (d PlanDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Breaking returns whether invocations of scripts that work with the old
+// revision may fail with the new one, ie. scripts or arguments are removed or
+// arguments became stricter.
+func (d PlanDiff) Breaking() bool {
+	if len(d.Removed) != 0 {
+		return true
+	}
+	for _, script := range d.Changed {
+		if script.Breaking() {
+			return true
+		}
+	}
+	return false
+}
+
+// Breaking returns whether invocations of the script that work with the old
+// revision may fail with the new one.
+func (d ScriptDiff) Breaking() bool {
+	if len(d.RemovedArgs) != 0 {
+		return true
+	}
+	for _, arg := range d.AddedArgs {
+		if arg.Required && arg.Default == "" {
+			return true
+		}
+	}
+	for _, arg := range d.ChangedArgs {
+		if arg.Breaking() {
+			return true
+		}
+	}
+	return false
+}
+
+// Breaking returns whether values accepted by the old argument may be
+// rejected by the new one. Only the removal of enum values keeps the type
+// from being stricter.
+func (d ArgDiff) Breaking() bool {
+	if d.To.Required && d.To.Default == "" && !(d.From.Required && d.From.Default == "") {
+		return true
+	}
+	if d.From.Type != d.To.Type || d.From.Pattern != d.To.Pattern {
+		return true
+	}
+	values := make(map[string]bool, len(d.To.Values))
+	for _, value := range d.To.Values {
+		values[value] = true
+	}
+	for _, value := range d.From.Values {
+		if !values[value] {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffPlans returns how the scripts of plan from changed in plan to. Scripts
+// and arguments are sorted by name.
+func DiffPlans(from, to ShuttlePlanConfiguration) PlanDiff {
+	var diff PlanDiff
+	for name := range to.Scripts {
+		if _, ok := from.Scripts[name]; !ok {
+			diff.Added = append(diff.Added, name)
+		}
+	}
+	for name, fromScript := range from.Scripts {
+		toScript, ok := to.Scripts[name]
+		if !ok {
+			diff.Removed = append(diff.Removed, name)
+			continue
+		}
+		scriptDiff := diffScripts(name, fromScript, toScript)
+		if len(scriptDiff.AddedArgs) != 0 || len(scriptDiff.RemovedArgs) != 0 || len(scriptDiff.ChangedArgs) != 0 || len(scriptDiff.Fields) != 0 {
+			diff.Changed = append(diff.Changed, scriptDiff)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		return diff.Changed[i].Name < diff.Changed[j].Name
+	})
+	return diff
+}
+
+func diffScripts(name string, from, to ShuttlePlanScript) ScriptDiff {
+	diff := ScriptDiff{Name: name}
+
+	fromArgs := make(map[string]ShuttleScriptArgs, len(from.Args))
+	for _, arg := range from.Args {
+		fromArgs[arg.Name] = arg
+	}
+	toArgs := make(map[string]ShuttleScriptArgs, len(to.Args))
+	for _, arg := range to.Args {
+		toArgs[arg.Name] = arg
+		fromArg, ok := fromArgs[arg.Name]
+		switch {
+		case !ok:
+			diff.AddedArgs = append(diff.AddedArgs, arg)
+		case !reflect.DeepEqual(fromArg, arg):
+			diff.ChangedArgs = append(diff.ChangedArgs, ArgDiff{From: fromArg, To: arg})
+		}
+	}
+	for _, arg := range from.Args {
+		if _, ok := toArgs[arg.Name]; !ok {
+			diff.RemovedArgs = append(diff.RemovedArgs, arg)
+		}
+	}
+	sort.Slice(diff.AddedArgs, func(i, j int) bool { return diff.AddedArgs[i].Name < diff.AddedArgs[j].Name })
+	sort.Slice(diff.RemovedArgs, func(i, j int) bool { return diff.RemovedArgs[i].Name < diff.RemovedArgs[j].Name })
+	sort.Slice(diff.ChangedArgs, func(i, j int) bool { return diff.ChangedArgs[i].To.Name < diff.ChangedArgs[j].To.Name })
+
+	for _, field := range []struct {
+		name     string
+		from, to interface{}
+	}{
+		{"description", from.Description, to.Description},
+		{"actions", from.Actions, to.Actions},
+		{"shell", from.Shell, to.Shell},
+		{"exclusive", from.Exclusive, to.Exclusive},
+		{"deprecated", from.Deprecated, to.Deprecated},
+		{"when", from.When, to.When},
+		{"checks", from.Checks, to.Checks},
+		{"needs", from.Needs, to.Needs},
+		{"inputs", from.Inputs, to.Inputs},
+		{"outputs", from.Outputs, to.Outputs},
+		{"matrix", from.Matrix, to.Matrix},
+		{"lock", from.Lock, to.Lock},
+	} {
+		if !reflect.DeepEqual(field.from, field.to) {
+			diff.Fields = append(diff.Fields, field.name)
+		}
+	}
+	return diff
+}
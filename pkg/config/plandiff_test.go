@@ -0,0 +1,136 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffPlans(t *testing.T) {
+	build := ShuttlePlanScript{
+		Description: "Build the project",
+		Actions:     []ShuttleAction{{Shell: "go build ./..."}},
+		Args: []ShuttleScriptArgs{
+			{Name: "env", Type: ArgTypeEnum, Values: []string{"dev", "prod"}},
+			{Name: "verbose", Type: ArgTypeBool},
+		},
+	}
+	withArgs := func(script ShuttlePlanScript, args ...ShuttleScriptArgs) ShuttlePlanScript {
+		script.Args = args
+		return script
+	}
+
+	tt := []struct {
+		name     string
+		from     map[string]ShuttlePlanScript
+		to       map[string]ShuttlePlanScript
+		diff     PlanDiff
+		breaking bool
+	}{
+		{
+			name: "unchanged",
+			from: map[string]ShuttlePlanScript{"build": build},
+			to:   map[string]ShuttlePlanScript{"build": build},
+			diff: PlanDiff{},
+		},
+		{
+			name: "added and removed scripts",
+			from: map[string]ShuttlePlanScript{"build": build, "lint": {}},
+			to:   map[string]ShuttlePlanScript{"build": build, "test": {}, "deploy": {}},
+			diff: PlanDiff{
+				Added:   []string{"deploy", "test"},
+				Removed: []string{"lint"},
+			},
+			breaking: true,
+		},
+		{
+			name: "changed actions and description",
+			from: map[string]ShuttlePlanScript{"build": build},
+			to: map[string]ShuttlePlanScript{"build": {
+				Description: "Build everything",
+				Actions:     []ShuttleAction{{Shell: "go build -v ./..."}},
+				Args:        build.Args,
+			}},
+			diff: PlanDiff{
+				Changed: []ScriptDiff{{Name: "build", Fields: []string{"description", "actions"}}},
+			},
+		},
+		{
+			name: "added optional argument",
+			from: map[string]ShuttlePlanScript{"build": build},
+			to:   map[string]ShuttlePlanScript{"build": withArgs(build, append(build.Args, ShuttleScriptArgs{Name: "race"})...)},
+			diff: PlanDiff{
+				Changed: []ScriptDiff{{Name: "build", AddedArgs: []ShuttleScriptArgs{{Name: "race"}}}},
+			},
+		},
+		{
+			name: "added required argument",
+			from: map[string]ShuttlePlanScript{"build": build},
+			to:   map[string]ShuttlePlanScript{"build": withArgs(build, append(build.Args, ShuttleScriptArgs{Name: "target", Required: true})...)},
+			diff: PlanDiff{
+				Changed: []ScriptDiff{{Name: "build", AddedArgs: []ShuttleScriptArgs{{Name: "target", Required: true}}}},
+			},
+			breaking: true,
+		},
+		{
+			name: "removed argument",
+			from: map[string]ShuttlePlanScript{"build": build},
+			to:   map[string]ShuttlePlanScript{"build": withArgs(build, build.Args[0])},
+			diff: PlanDiff{
+				Changed: []ScriptDiff{{Name: "build", RemovedArgs: []ShuttleScriptArgs{build.Args[1]}}},
+			},
+			breaking: true,
+		},
+		{
+			name: "added enum value",
+			from: map[string]ShuttlePlanScript{"build": build},
+			to: map[string]ShuttlePlanScript{"build": withArgs(build,
+				ShuttleScriptArgs{Name: "env", Type: ArgTypeEnum, Values: []string{"dev", "staging", "prod"}},
+				build.Args[1],
+			)},
+			diff: PlanDiff{
+				Changed: []ScriptDiff{{Name: "build", ChangedArgs: []ArgDiff{{
+					From: build.Args[0],
+					To:   ShuttleScriptArgs{Name: "env", Type: ArgTypeEnum, Values: []string{"dev", "staging", "prod"}},
+				}}}},
+			},
+		},
+		{
+			name: "removed enum value",
+			from: map[string]ShuttlePlanScript{"build": build},
+			to: map[string]ShuttlePlanScript{"build": withArgs(build,
+				ShuttleScriptArgs{Name: "env", Type: ArgTypeEnum, Values: []string{"prod"}},
+				build.Args[1],
+			)},
+			diff: PlanDiff{
+				Changed: []ScriptDiff{{Name: "build", ChangedArgs: []ArgDiff{{
+					From: build.Args[0],
+					To:   ShuttleScriptArgs{Name: "env", Type: ArgTypeEnum, Values: []string{"prod"}},
+				}}}},
+			},
+			breaking: true,
+		},
+		{
+			name: "argument became required with default",
+			from: map[string]ShuttlePlanScript{"build": build},
+			to: map[string]ShuttlePlanScript{"build": withArgs(build,
+				build.Args[0],
+				ShuttleScriptArgs{Name: "verbose", Type: ArgTypeBool, Required: true, Default: "false"},
+			)},
+			diff: PlanDiff{
+				Changed: []ScriptDiff{{Name: "build", ChangedArgs: []ArgDiff{{
+					From: build.Args[1],
+					To:   ShuttleScriptArgs{Name: "verbose", Type: ArgTypeBool, Required: true, Default: "false"},
+				}}}},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			diff := DiffPlans(ShuttlePlanConfiguration{Scripts: tc.from}, ShuttlePlanConfiguration{Scripts: tc.to})
+
+			assert.Equal(t, tc.diff, diff)
+			assert.Equal(t, tc.breaking, diff.Breaking(), "breaking")
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// defaultVariableNamePattern is lowercase snake_case
+const defaultVariableNamePattern = `^[a-z][a-z0-9_]*$`
+
+const (
+	PolicyEnforcementWarn  = "warn"
+	PolicyEnforcementError = "error"
+)
+
+// ShuttlePlanPolicy describes conventions a plan enforces on the scripts
+// available to projects using it
+type ShuttlePlanPolicy struct {
+	VariableNames *ShuttleNamingPolicy `yaml:"variableNames"`
+}
+
+// ShuttleNamingPolicy describes a naming convention
+type ShuttleNamingPolicy struct {
+	// Pattern is a regular expression names must match. Defaults to lowercase
+	// snake_case.
+	Pattern string `yaml:"pattern"`
+	// Enforcement is either "warn" (default) or "error"
+	Enforcement string `yaml:"enforcement"`
+}
+
+// validate checks the names of script arguments against the policy. Violations
+// are either reported as warnings to the UI or returned as an error depending
+// on the enforcement of the policy.
+func (p ShuttlePlanPolicy) validate(uii *ui.UI, scripts map[string]ShuttlePlanScript) error {
+	if p.VariableNames == nil {
+		return nil
+	}
+
+	pattern := p.VariableNames.Pattern
+	if pattern == "" {
+		pattern = defaultVariableNamePattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return errors.NewExitCode(1, "Plan policy variable name pattern '%s' is invalid: %v", pattern, err)
+	}
+
+	enforcement := p.VariableNames.Enforcement
+	switch enforcement {
+	case "", PolicyEnforcementWarn, PolicyEnforcementError:
+	default:
+		return errors.NewExitCode(
+			1,
+			"Plan policy enforcement '%s' is invalid: must be one of '%s' or '%s'",
+			enforcement,
+			PolicyEnforcementWarn,
+			PolicyEnforcementError,
+		)
+	}
+
+	var violations []string
+	for scriptName, script := range scripts {
+		for _, arg := range script.Args {
+			if !re.MatchString(arg.Name) {
+				violations = append(
+					violations,
+					fmt.Sprintf("%s.args.%s%s", scriptName, arg.Name, script.sourceSuffix()),
+				)
+			}
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	sort.Strings(violations)
+
+	var s strings.Builder
+	fmt.Fprintf(&s, "Variable names not matching plan naming policy '%s':\n", pattern)
+	for _, v := range violations {
+		fmt.Fprintf(&s, "  %s\n", v)
+	}
+
+	if enforcement == PolicyEnforcementError {
+		return errors.NewExitCode(2, "%s", strings.TrimSuffix(s.String(), "\n"))
+	}
+	uii.EmphasizeInfoln("%s", strings.TrimSuffix(s.String(), "\n"))
+	return nil
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/lunarway/shuttle/pkg/ui"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShuttlePlanPolicy_validate(t *testing.T) {
+	scripts := map[string]ShuttlePlanScript{
+		"build": {
+			Args: []ShuttleScriptArgs{
+				{Name: "tag"},
+				{Name: "dockerImage"},
+			},
+		},
+		"deploy": {
+			Args: []ShuttleScriptArgs{
+				{Name: "target_env"},
+				{Name: "DRY-RUN"},
+			},
+		},
+	}
+	tt := []struct {
+		name   string
+		policy ShuttlePlanPolicy
+		err    error
+		stderr string
+	}{
+		{
+			name:   "no policy",
+			policy: ShuttlePlanPolicy{},
+		},
+		{
+			name: "default pattern warns",
+			policy: ShuttlePlanPolicy{
+				VariableNames: &ShuttleNamingPolicy{},
+			},
+			stderr: "\x1b[032;1mVariable names not matching plan naming policy '^[a-z][a-z0-9_]*$':\n  build.args.dockerImage\n  deploy.args.DRY-RUN\x1b[0m\n",
+		},
+		{
+			name: "error enforcement",
+			policy: ShuttlePlanPolicy{
+				VariableNames: &ShuttleNamingPolicy{
+					Pattern:     "^[a-z]+$",
+					Enforcement: "error",
+				},
+			},
+			err: errors.New("exit code 2 - Variable names not matching plan naming policy '^[a-z]+$':\n  build.args.dockerImage\n  deploy.args.DRY-RUN\n  deploy.args.target_env"),
+		},
+		{
+			name: "pattern with formatting verbs",
+			policy: ShuttlePlanPolicy{
+				VariableNames: &ShuttleNamingPolicy{
+					Pattern:     "^[a-z%s]+$",
+					Enforcement: "error",
+				},
+			},
+			err: errors.New("exit code 2 - Variable names not matching plan naming policy '^[a-z%s]+$':\n  build.args.dockerImage\n  deploy.args.DRY-RUN\n  deploy.args.target_env"),
+		},
+		{
+			name: "custom pattern matching all",
+			policy: ShuttlePlanPolicy{
+				VariableNames: &ShuttleNamingPolicy{
+					Pattern:     ".*",
+					Enforcement: "error",
+				},
+			},
+		},
+		{
+			name: "invalid pattern",
+			policy: ShuttlePlanPolicy{
+				VariableNames: &ShuttleNamingPolicy{
+					Pattern: "[",
+				},
+			},
+			err: errors.New("exit code 1 - Plan policy variable name pattern '[' is invalid: error parsing regexp: missing closing ]: `[`"),
+		},
+		{
+			name: "invalid enforcement",
+			policy: ShuttlePlanPolicy{
+				VariableNames: &ShuttleNamingPolicy{
+					Enforcement: "panic",
+				},
+			},
+			err: errors.New("exit code 1 - Plan policy enforcement 'panic' is invalid: must be one of 'warn' or 'error'"),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stderr := &bytes.Buffer{}
+
+			err := tc.policy.validate(ui.Create(&bytes.Buffer{}, stderr), scripts)
+
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.stderr, stderr.String())
+		})
+	}
+}
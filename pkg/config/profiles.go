@@ -0,0 +1,109 @@
+package config
+
+import (
+	"sort"
+	"strings"
+
+	shuttleerrors "github.com/lunarway/shuttle/pkg/errors"
+)
+
+// ProfileVariable is the variable the name of the active profile is available
+// as to templates and actions.
+const ProfileVariable = "shuttle_profile"
+
+// ShuttleProfile overrides the plan and variables of a project when it is
+// active, eg. to deploy to another environment.
+type ShuttleProfile struct {
+	// PlanRaw replaces the plan of the project if set. It takes the same
+	// values as plan.
+	PlanRaw interface{} `yaml:"plan"`
+	// Variables are merged onto the variables of the project. Nested
+	// variables are merged as well.
+	Variables DynamicYaml `yaml:"vars"`
+}
+
+// applyProfile applies the profile named name to c. Nothing is changed if name
+// is empty.
+func (c *ShuttleConfig) applyProfile(name string) error {
+	if name == "" {
+		return nil
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return shuttleerrors.NewExitCode(2, "Profile '%s' is not defined: shuttle.yaml has no profiles", name)
+		}
+		return shuttleerrors.NewExitCode(
+			2,
+			"Profile '%s' is not defined: must be one of %s",
+			name,
+			strings.Join(c.ProfileNames(), ", "),
+		)
+	}
+	c.Profile = name
+	if profile.PlanRaw != nil {
+		c.PlanRaw = profile.PlanRaw
+	}
+	c.Variables = mergeVariables(c.Variables, profile.Variables)
+	if c.Variables == nil {
+		c.Variables = make(DynamicYaml, 1)
+	}
+	c.Variables[ProfileVariable] = name
+	return nil
+}
+
+// ProfileNames returns the names of the profiles of c in alphabetical order.
+func (c *ShuttleConfig) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mergeVariables returns the variables of base with those of override on top.
+// Maps present in both are merged recursively while other values of override
+// replace those of base. base is not modified.
+func mergeVariables(base, override DynamicYaml) DynamicYaml {
+	if override == nil {
+		return base
+	}
+	merged := make(DynamicYaml, len(base)+len(override))
+	for name, value := range base {
+		merged[name] = value
+	}
+	for name, value := range override {
+		merged[name] = mergeVariable(merged[name], value)
+	}
+	return merged
+}
+
+func mergeVariable(base, override interface{}) interface{} {
+	baseMap, ok := base.(map[interface{}]interface{})
+	if !ok {
+		return override
+	}
+	overrideMap, ok := override.(map[interface{}]interface{})
+	if !ok {
+		return override
+	}
+	merged := make(map[interface{}]interface{}, len(baseMap)+len(overrideMap))
+	for key, value := range baseMap {
+		merged[key] = value
+	}
+	for key, value := range overrideMap {
+		merged[key] = mergeVariable(merged[key], value)
+	}
+	return merged
+}
+
+// variableNames returns the names of vars in alphabetical order.
+func variableNames(vars DynamicYaml) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
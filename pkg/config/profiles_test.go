@@ -0,0 +1,90 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShuttleConfig_applyProfile(t *testing.T) {
+	newConfig := func() ShuttleConfig {
+		return ShuttleConfig{
+			PlanRaw: "git://github.com/lunarway/shuttle-example-go-plan.git",
+			Variables: DynamicYaml{
+				"env": "dev",
+				"k8s": map[interface{}]interface{}{"namespace": "dev", "replicas": 1},
+			},
+			Profiles: map[string]ShuttleProfile{
+				"prod": {
+					Variables: DynamicYaml{
+						"env": "prod",
+						"k8s": map[interface{}]interface{}{"replicas": 3},
+					},
+				},
+				"local": {PlanRaw: "../plan"},
+			},
+		}
+	}
+	tt := []struct {
+		name      string
+		profile   string
+		plan      interface{}
+		variables DynamicYaml
+		err       string
+	}{
+		{
+			name:    "no profile",
+			profile: "",
+			plan:    "git://github.com/lunarway/shuttle-example-go-plan.git",
+			variables: DynamicYaml{
+				"env": "dev",
+				"k8s": map[interface{}]interface{}{"namespace": "dev", "replicas": 1},
+			},
+		},
+		{
+			name:    "merged variables",
+			profile: "prod",
+			plan:    "git://github.com/lunarway/shuttle-example-go-plan.git",
+			variables: DynamicYaml{
+				"env":             "prod",
+				"k8s":             map[interface{}]interface{}{"namespace": "dev", "replicas": 3},
+				"shuttle_profile": "prod",
+			},
+		},
+		{
+			name:    "plan",
+			profile: "local",
+			plan:    "../plan",
+			variables: DynamicYaml{
+				"env":             "dev",
+				"k8s":             map[interface{}]interface{}{"namespace": "dev", "replicas": 1},
+				"shuttle_profile": "local",
+			},
+		},
+		{
+			name:    "unknown profile",
+			profile: "staging",
+			err:     "exit code 2 - Profile 'staging' is not defined: must be one of local, prod",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newConfig()
+			err := c.applyProfile(tc.profile)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.profile, c.Profile)
+			assert.Equal(t, tc.plan, c.PlanRaw)
+			assert.Equal(t, tc.variables, c.Variables)
+		})
+	}
+}
+
+func TestShuttleConfig_applyProfile_noProfiles(t *testing.T) {
+	c := ShuttleConfig{}
+	err := c.applyProfile("prod")
+	assert.EqualError(t, err, "exit code 2 - Profile 'prod' is not defined: shuttle.yaml has no profiles")
+}
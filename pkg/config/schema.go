@@ -0,0 +1,173 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Schema is a JSON Schema describing shuttle.yaml or plan.yaml, eg. for
+// editors through yaml-language-server. Only the keywords used by shuttle are
+// supported.
+type Schema struct {
+	Schema     string             `json:"$schema,omitempty"`
+	Title      string             `json:"title,omitempty"`
+	Ref        string             `json:"$ref,omitempty"`
+	Type       schemaTypes        `json:"type,omitempty"`
+	Enum       []interface{}      `json:"enum,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	// AdditionalProperties is either false or the schema of the values of
+	// keys not in Properties.
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// schemaTypes are the JSON types a value may have. A single type is encoded
+// as a string.
+type schemaTypes []string
+
+func (t schemaTypes) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return []byte(`"` + t[0] + `"`), nil
+	}
+	return []byte(`["` + strings.Join(t, `","`) + `"]`), nil
+}
+
+// planSchema is the schema of the plan field of shuttle.yaml and profiles: a
+// plan, a list of plans or false.
+var planSchema = &Schema{
+	OneOf: []*Schema{
+		{Type: schemaTypes{"string"}},
+		{Type: schemaTypes{"array"}, Items: &Schema{Type: schemaTypes{"string"}}},
+		{Type: schemaTypes{"boolean"}, Enum: []interface{}{false}},
+	},
+}
+
+// schemaOverrides replace the generated schema of fields by their
+// <type>.<field> name, eg. fields decoded from several types of values.
+var schemaOverrides = map[string]*Schema{
+	"ShuttleConfig.PlanRaw":           planSchema,
+	"ShuttleProfile.PlanRaw":          planSchema,
+	"ShuttleScriptArgs.Type":          enumSchema(ArgTypeString, ArgTypeInt, ArgTypeBool, ArgTypeEnum, ArgTypePath),
+	"ShuttleAction.Output":            enumSchema("streaming", "buffered"),
+	"ShuttleAction.RetryBackoff":      enumSchema("constant", "exponential"),
+	"ShuttleAction.Cwd":               enumSchema("project", "invocation"),
+	"ShuttleAction.Stdin":             enumSchema("inherit", "none"),
+	"ShuttleConfig.Shell":             enumSchema(ShellSh, ShellBash, ShellPwsh, ShellCmd),
+	"ShuttlePlanScript.Shell":         enumSchema(ShellSh, ShellBash, ShellPwsh, ShellCmd),
+	"ShuttleNamingPolicy.Enforcement": enumSchema(PolicyEnforcementWarn, PolicyEnforcementError),
+	"ShuttleLocks.Backend":            enumSchema(LockBackendFile, LockBackendHTTP),
+	"ShuttleEnv.Inherit":              enumSchema(EnvInheritAll, EnvInheritNone, EnvInheritAllowlist),
+	"ShuttlePlanRequirements.Executors": {
+		Type:  schemaTypes{"array"},
+		Items: enumSchema(ExecutorGolang, ExecutorDocker),
+	},
+}
+
+// schemaExtraProperties are properties of types decoded by a custom
+// UnmarshalYAML besides their fields.
+var schemaExtraProperties = map[string]map[string]*Schema{
+	"ShuttleAction": {"pwsh": stringSchema()},
+}
+
+// schemaRequired are the properties types must have.
+var schemaRequired = map[string][]string{
+	"ShuttleScriptArgs":      {"name"},
+	"ShuttleExclusiveArgs":   {"args"},
+	"ShuttleToolRequirement": {"name"},
+	"ShuttleUpload":          {"path", "url"},
+}
+
+func enumSchema(values ...string) *Schema {
+	enum := make([]interface{}, len(values))
+	for i, value := range values {
+		enum[i] = value
+	}
+	return &Schema{Type: schemaTypes{"string"}, Enum: enum}
+}
+
+// stringSchema is the schema of string fields. Any scalar is accepted like
+// when shuttle decodes them.
+func stringSchema() *Schema {
+	return &Schema{Type: schemaTypes{"string", "number", "boolean"}}
+}
+
+// ProjectSchema returns the JSON Schema of shuttle.yaml.
+func ProjectSchema() *Schema {
+	return newSchema("shuttle.yaml", reflect.TypeOf(ShuttleConfig{}))
+}
+
+// PlanSchema returns the JSON Schema of plan.yaml.
+func PlanSchema() *Schema {
+	return newSchema("plan.yaml", reflect.TypeOf(ShuttlePlanConfiguration{}))
+}
+
+func newSchema(title string, t reflect.Type) *Schema {
+	defs := make(map[string]*Schema)
+	schema := structSchema(t, defs)
+	schema.Schema = "https://json-schema.org/draft/2020-12/schema"
+	schema.Title = title
+	schema.Defs = defs
+	return schema
+}
+
+// typeSchema returns the schema of values of t. Structs besides the root are
+// added to defs and referenced.
+func typeSchema(t reflect.Type, defs map[string]*Schema) *Schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem(), defs)
+	case reflect.Struct:
+		if _, ok := defs[t.Name()]; !ok {
+			// reserve the name before generating the schema of the fields
+			// for recursive types
+			defs[t.Name()] = nil
+			defs[t.Name()] = structSchema(t, defs)
+		}
+		return &Schema{Ref: "#/$defs/" + t.Name()}
+	case reflect.Map:
+		return &Schema{Type: schemaTypes{"object"}, AdditionalProperties: typeSchema(t.Elem(), defs)}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: schemaTypes{"array"}, Items: typeSchema(t.Elem(), defs)}
+	case reflect.String:
+		return stringSchema()
+	case reflect.Bool:
+		return &Schema{Type: schemaTypes{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: schemaTypes{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: schemaTypes{"number"}}
+	default:
+		// any value
+		return &Schema{}
+	}
+}
+
+// structSchema returns the schema of the YAML fields of the struct t.
+func structSchema(t reflect.Type, defs map[string]*Schema) *Schema {
+	schema := &Schema{
+		Type:                 schemaTypes{"object"},
+		Properties:           make(map[string]*Schema),
+		AdditionalProperties: false,
+		Required:             schemaRequired[t.Name()],
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		if override, ok := schemaOverrides[t.Name()+"."+field.Name]; ok {
+			schema.Properties[name] = override
+			continue
+		}
+		schema.Properties[name] = typeSchema(field.Type, defs)
+	}
+	for name, property := range schemaExtraProperties[t.Name()] {
+		schema.Properties[name] = property
+	}
+	return schema
+}
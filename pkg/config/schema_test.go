@@ -0,0 +1,159 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectSchema(t *testing.T) {
+	schema := ProjectSchema()
+
+	assert.Equal(t, "shuttle.yaml", schema.Title)
+	assert.Equal(t, false, schema.AdditionalProperties, "unknown fields are not allowed")
+	assert.Equal(t, planSchema, schema.Properties["plan"])
+	assert.Equal(t, &Schema{
+		Type:                 schemaTypes{"object"},
+		AdditionalProperties: &Schema{Ref: "#/$defs/ShuttlePlanScript"},
+	}, schema.Properties["scripts"])
+
+	args := schema.Defs["ShuttleScriptArgs"]
+	require.NotNil(t, args)
+	assert.Equal(t, []string{"name"}, args.Required)
+	assert.Equal(t, []interface{}{"string", "int", "bool", "enum", "path"}, args.Properties["type"].Enum)
+	assert.Contains(t, schema.Defs["ShuttleAction"].Properties, "pwsh")
+
+	for name, def := range schema.Defs {
+		assert.NotNil(t, def, "definition %s is generated", name)
+	}
+}
+
+func TestSchema_MarshalJSON(t *testing.T) {
+	output, err := json.Marshal(&Schema{
+		Type:                 schemaTypes{"object"},
+		AdditionalProperties: false,
+		Properties: map[string]*Schema{
+			"name": {Type: schemaTypes{"string", "number"}},
+			"vars": {Type: schemaTypes{"object"}, AdditionalProperties: &Schema{}},
+		},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"additionalProperties": false,
+		"properties": {
+			"name": {"type": ["string", "number"]},
+			"vars": {"type": "object", "additionalProperties": {}}
+		}
+	}`, string(output))
+}
+
+func TestValidateSchemaFile(t *testing.T) {
+	tt := []struct {
+		name     string
+		schema   *Schema
+		content  string
+		problems []string
+	}{
+		{
+			name:   "valid project",
+			schema: ProjectSchema(),
+			content: `plan: false
+vars:
+  service: api
+  replicas: 3
+scripts:
+  build:
+    args:
+      - name: dry
+        type: bool
+        default: yes
+    actions:
+      - shell: echo build
+        retries: 2
+`,
+			problems: nil,
+		},
+		{
+			name:   "unknown fields",
+			schema: ProjectSchema(),
+			content: `plan: false
+script:
+  build: {}
+scripts:
+  build:
+    actions:
+      - shell: echo build
+        timout: 5s
+`,
+			problems: []string{
+				"shuttle.yaml:2:1: 'script' is not allowed",
+				"shuttle.yaml:8:9: 'scripts.build.actions.0.timout' is not allowed",
+			},
+		},
+		{
+			name:   "wrong types",
+			schema: ProjectSchema(),
+			content: `plan:
+  repo: ../plan
+scripts:
+  build:
+    actions:
+      - shell: echo build
+        retries: often
+        background: maybe
+        path: ./bin
+`,
+			problems: []string{
+				"shuttle.yaml:2:3: 'plan' must be a string or an array of strings or false but is an object",
+				"shuttle.yaml:7:18: 'scripts.build.actions.0.retries' must be an integer but is a string",
+				"shuttle.yaml:8:21: 'scripts.build.actions.0.background' must be a boolean but is a string",
+				"shuttle.yaml:9:15: 'scripts.build.actions.0.path' must be an array of strings but is a string",
+			},
+		},
+		{
+			name:   "missing required fields and invalid enums",
+			schema: PlanSchema(),
+			content: `scripts:
+  deploy:
+    args:
+      - name: env
+        type: choice
+      - description: The tag to deploy
+    actions:
+      - shell: ./deploy.sh
+        output: fancy
+`,
+			problems: []string{
+				"shuttle.yaml:5:15: 'scripts.deploy.args.0.type' must be one of string, int, bool, enum, path but is 'choice'",
+				"shuttle.yaml:6:9: 'scripts.deploy.args.1' is missing required 'name'",
+				"shuttle.yaml:9:17: 'scripts.deploy.actions.0.output' must be one of streaming, buffered but is 'fancy'",
+			},
+		},
+		{
+			name:     "not a mapping",
+			schema:   ProjectSchema(),
+			content:  "plan\n",
+			problems: []string{"shuttle.yaml:1:1: document must be an object but is a string"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "shuttle.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o644))
+
+			problems, err := ValidateSchemaFile(tc.schema, path, "shuttle.yaml")
+			require.NoError(t, err)
+
+			var actual []string
+			for _, problem := range problems {
+				actual = append(actual, problem.String())
+			}
+			assert.Equal(t, tc.problems, actual)
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaProblem is a value of a YAML file not matching its schema.
+type SchemaProblem struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (p SchemaProblem) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", p.File, p.Line, p.Column, p.Message)
+}
+
+// ValidateSchemaFile returns the problems of the YAML file at path with the
+// schema. Problems are reported with name as the file and ordered by their
+// position in it.
+func ValidateSchemaFile(schema *Schema, path, name string) ([]SchemaProblem, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	v := schemaValidator{root: schema, file: name}
+	v.validate(schema, doc.Content[0], "")
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		if v.problems[i].Line != v.problems[j].Line {
+			return v.problems[i].Line < v.problems[j].Line
+		}
+		return v.problems[i].Column < v.problems[j].Column
+	})
+	return v.problems, nil
+}
+
+type schemaValidator struct {
+	root     *Schema
+	file     string
+	problems []SchemaProblem
+}
+
+func (v *schemaValidator) report(node *yaml.Node, format string, args ...interface{}) {
+	v.problems = append(v.problems, SchemaProblem{
+		File:    v.file,
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// validate validates node at key against schema.
+func (v *schemaValidator) validate(schema *Schema, node *yaml.Node, key string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	// empty values are decoded as if they were left out
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	if schema.Ref != "" {
+		schema = v.root.Defs[strings.TrimPrefix(schema.Ref, "#/$defs/")]
+	}
+
+	if len(schema.OneOf) > 0 {
+		for _, option := range schema.OneOf {
+			if v.matches(option, node) {
+				return
+			}
+		}
+		descriptions := make([]string, len(schema.OneOf))
+		for i, option := range schema.OneOf {
+			descriptions[i] = describeSchema(option)
+		}
+		v.report(node, "%s must be %s but is %s", keyName(key), strings.Join(descriptions, " or "), describeNode(node))
+		return
+	}
+
+	if len(schema.Type) > 0 && !hasType(schema.Type, node) {
+		v.report(node, "%s must be %s but is %s", keyName(key), describeSchema(schema), describeNode(node))
+		return
+	}
+	if len(schema.Enum) > 0 {
+		values := make([]string, len(schema.Enum))
+		for i, value := range schema.Enum {
+			values[i] = fmt.Sprint(value)
+			if values[i] == node.Value {
+				return
+			}
+		}
+		v.report(node, "%s must be one of %s but is '%s'", keyName(key), strings.Join(values, ", "), node.Value)
+		return
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		set := make(map[string]bool, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			name := node.Content[i].Value
+			set[name] = true
+			childKey := joinKey(key, name)
+			if property, ok := schema.Properties[name]; ok {
+				v.validate(property, node.Content[i+1], childKey)
+				continue
+			}
+			switch additional := schema.AdditionalProperties.(type) {
+			case bool:
+				if !additional {
+					v.report(node.Content[i], "%s is not allowed", keyName(childKey))
+				}
+			case *Schema:
+				v.validate(additional, node.Content[i+1], childKey)
+			}
+		}
+		for _, name := range schema.Required {
+			if !set[name] {
+				v.report(node, "%s is missing required '%s'", keyName(key), name)
+			}
+		}
+	case yaml.SequenceNode:
+		if schema.Items == nil {
+			return
+		}
+		for i, item := range node.Content {
+			v.validate(schema.Items, item, joinKey(key, fmt.Sprint(i)))
+		}
+	}
+}
+
+// matches returns true if node is valid against schema.
+func (v *schemaValidator) matches(schema *Schema, node *yaml.Node) bool {
+	check := schemaValidator{root: v.root, file: v.file}
+	check.validate(schema, node, "")
+	return len(check.problems) == 0
+}
+
+// hasType returns true if node is of one of types. Any number is accepted as
+// a number and yes/no spelled booleans are accepted as booleans like when
+// shuttle decodes them.
+func hasType(types schemaTypes, node *yaml.Node) bool {
+	actual := nodeType(node)
+	for _, t := range types {
+		switch {
+		case t == actual:
+			return true
+		case t == "number" && actual == "integer":
+			return true
+		case t == "boolean" && actual == "string" && isYAML11Bool(node.Value):
+			return true
+		}
+	}
+	return false
+}
+
+func isYAML11Bool(value string) bool {
+	switch strings.ToLower(value) {
+	case "yes", "no", "on", "off", "y", "n":
+		return true
+	default:
+		return false
+	}
+}
+
+func nodeType(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+	switch node.Tag {
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	case "!!bool":
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+func describeNode(node *yaml.Node) string {
+	switch t := nodeType(node); t {
+	case "object", "array", "integer":
+		return "an " + t
+	default:
+		return "a " + t
+	}
+}
+
+// describeSchema describes the values schema accepts, eg. "an array of
+// strings".
+func describeSchema(schema *Schema) string {
+	if len(schema.Enum) > 0 {
+		values := make([]string, len(schema.Enum))
+		for i, value := range schema.Enum {
+			values[i] = fmt.Sprint(value)
+		}
+		return strings.Join(values, " or ")
+	}
+	if schema.Ref != "" || len(schema.Type) == 0 {
+		return "an object"
+	}
+	// strings accept any scalar
+	if schema.Type[0] == "string" {
+		return "a string"
+	}
+	switch schema.Type[0] {
+	case "array":
+		if schema.Items != nil && len(schema.Items.Type) > 0 {
+			return "an array of " + schema.Items.Type[0] + "s"
+		}
+		return "an array"
+	case "object", "integer":
+		return "an " + schema.Type[0]
+	default:
+		return "a " + schema.Type[0]
+	}
+}
+
+func joinKey(key, name string) string {
+	if key == "" {
+		return name
+	}
+	return key + "." + name
+}
+
+func keyName(key string) string {
+	if key == "" {
+		return "document"
+	}
+	return fmt.Sprintf("'%s'", key)
+}
+
+// schemaError returns an error listing problems one per line.
+func schemaError(problems []SchemaProblem) error {
+	lines := make([]string, len(problems))
+	for i, problem := range problems {
+		lines[i] = problem.String()
+	}
+	return fmt.Errorf("schema errors:\n  %s", strings.Join(lines, "\n  "))
+}
+
+// SchemaProblems returns the problems of shuttle.yaml and the plan.yaml of the
+// plan and overlays of the project against their schema. Files are named
+// relative to the project and local plans by their source.
+func (c *ShuttleProjectContext) SchemaProblems() ([]SchemaProblem, error) {
+	type schemaFile struct {
+		schema *Schema
+		path   string
+	}
+	files := []schemaFile{{ProjectSchema(), path.Join(c.ProjectPath, "shuttle.yaml")}}
+	planPath := c.LocalPlanPath
+	if c.PlanSourcePath != "" {
+		planPath = c.PlanSourcePath
+	}
+	if planPath != "" {
+		files = append(files, schemaFile{PlanSchema(), path.Join(planPath, "plan.yaml")})
+	}
+	for _, overlay := range c.Overlays {
+		files = append(files, schemaFile{PlanSchema(), path.Join(overlay.LocalPlanPath, "plan.yaml")})
+	}
+
+	var problems []SchemaProblem
+	for _, file := range files {
+		name := file.path
+		if relative, err := filepath.Rel(c.ProjectPath, file.path); err == nil && !strings.HasPrefix(relative, "..") {
+			name = relative
+		}
+		fileProblems, err := ValidateSchemaFile(file.schema, file.path, name)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, fileProblems...)
+	}
+	return problems, nil
+}
@@ -0,0 +1,272 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/templates"
+)
+
+// ShuttleScriptTemplate describes a parameterized script that is instantiated
+// into concrete scripts when the plan is loaded. String fields of Script and
+// Name are golang templates with the parameters available as fields, eg.
+// {{ .name }}.
+type ShuttleScriptTemplate struct {
+	Params []string `yaml:"params"`
+	// Name is the name of instantiated scripts. Defaults to the template name
+	// followed by the parameter values.
+	Name   string            `yaml:"name"`
+	Script ShuttlePlanScript `yaml:"script"`
+}
+
+var templateInstanceRegexp = regexp.MustCompile(`^([A-Za-z0-9_.-]+)\((.*)\)$`)
+
+// expandTemplates instantiates the script templates of the plan for each
+// instance and adds the resulting scripts to the plan.
+func (p *ShuttlePlanConfiguration) expandTemplates() error {
+	for _, instance := range p.Instances {
+		templateName, params, err := parseTemplateInstance(instance)
+		if err != nil {
+			return err
+		}
+		tmpl, ok := p.Templates[templateName]
+		if !ok {
+			return errors.NewExitCode(
+				1,
+				"Script template instance '%s' references unknown template '%s'",
+				instance,
+				templateName,
+			)
+		}
+		err = tmpl.validateParams(instance, params)
+		if err != nil {
+			return err
+		}
+
+		name, script, err := tmpl.instantiate(templateName, params)
+		if err != nil {
+			return errors.NewExitCode(1, "Failed to instantiate script template '%s': %v", instance, err)
+		}
+		if _, ok := p.Scripts[name]; ok {
+			return errors.NewExitCode(
+				1,
+				"Script '%s' generated by template instance '%s' is already defined",
+				name,
+				instance,
+			)
+		}
+		if p.Scripts == nil {
+			p.Scripts = make(map[string]ShuttlePlanScript)
+		}
+		p.Scripts[name] = script
+	}
+	return nil
+}
+
+// parseTemplateInstance parses instances on the form template(a=1, b=2).
+func parseTemplateInstance(instance string) (string, map[string]string, error) {
+	matches := templateInstanceRegexp.FindStringSubmatch(strings.TrimSpace(instance))
+	if matches == nil {
+		return "", nil, errors.NewExitCode(
+			1,
+			"Script template instance '%s' is not valid: expected <template>(<param>=<value>, ...)",
+			instance,
+		)
+	}
+
+	params := make(map[string]string)
+	if strings.TrimSpace(matches[2]) == "" {
+		return matches[1], params, nil
+	}
+	for _, pair := range strings.Split(matches[2], ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return "", nil, errors.NewExitCode(
+				1,
+				"Script template instance '%s' is not valid: parameter '%s' is not <param>=<value>",
+				instance,
+				strings.TrimSpace(pair),
+			)
+		}
+		if _, ok := params[key]; ok {
+			return "", nil, errors.NewExitCode(
+				1,
+				"Script template instance '%s' sets parameter '%s' more than once",
+				instance,
+				key,
+			)
+		}
+		params[key] = strings.TrimSpace(value)
+	}
+	return matches[1], params, nil
+}
+
+func (t ShuttleScriptTemplate) validateParams(instance string, params map[string]string) error {
+	var missing, unknown []string
+	for _, param := range t.Params {
+		if _, ok := params[param]; !ok {
+			missing = append(missing, param)
+		}
+	}
+	for param := range params {
+		if !contains(t.Params, param) {
+			unknown = append(unknown, param)
+		}
+	}
+	sort.Strings(unknown)
+
+	switch {
+	case len(missing) != 0:
+		return errors.NewExitCode(
+			1,
+			"Script template instance '%s' is missing parameters: %s",
+			instance,
+			strings.Join(missing, ", "),
+		)
+	case len(unknown) != 0:
+		return errors.NewExitCode(
+			1,
+			"Script template instance '%s' has unknown parameters: %s",
+			instance,
+			strings.Join(unknown, ", "),
+		)
+	}
+	return nil
+}
+
+func (t ShuttleScriptTemplate) instantiate(templateName string, params map[string]string) (string, ShuttlePlanScript, error) {
+	render := func(text string) (string, error) {
+		t, err := template.New(templateName).
+			Funcs(templates.GetFuncMap()).
+			Option("missingkey=error").
+			Parse(text)
+		if err != nil {
+			return "", err
+		}
+		var s strings.Builder
+		err = t.Execute(&s, params)
+		if err != nil {
+			return "", err
+		}
+		return s.String(), nil
+	}
+
+	nameTemplate := t.Name
+	if nameTemplate == "" {
+		parts := []string{templateName}
+		for _, param := range t.Params {
+			parts = append(parts, fmt.Sprintf("{{ index . %q }}", param))
+		}
+		nameTemplate = strings.Join(parts, "-")
+	}
+	name, err := render(nameTemplate)
+	if err != nil {
+		return "", ShuttlePlanScript{}, err
+	}
+
+	script, err := renderCopy(reflect.ValueOf(t.Script), render)
+	if err != nil {
+		return "", ShuttlePlanScript{}, err
+	}
+	return name, script.Interface().(ShuttlePlanScript), nil
+}
+
+// renderCopy returns a deep copy of v with all strings reachable from it
+// rendered. Nothing is shared with v such that rendering one instance of a
+// template never modifies the template or other instances. Keys of maps are
+// not rendered.
+func renderCopy(v reflect.Value, render func(string) (string, error)) (reflect.Value, error) {
+	switch v.Kind() {
+	case reflect.String:
+		if !strings.Contains(v.String(), "{{") {
+			return v, nil
+		}
+		rendered, err := render(v.String())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.SetString(rendered)
+		return out, nil
+	case reflect.Struct:
+		// unexported fields are copied as is
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if !out.Field(i).CanSet() {
+				continue
+			}
+			field, err := renderCopy(v.Field(i), render)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.Field(i).Set(field)
+		}
+		return out, nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return v, nil
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			element, err := renderCopy(v.Index(i), render)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.Index(i).Set(element)
+		}
+		return out, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return v, nil
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			value, err := renderCopy(iter.Value(), render)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.SetMapIndex(iter.Key(), value)
+		}
+		return out, nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v, nil
+		}
+		element, err := renderCopy(v.Elem(), render)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(element)
+		return out, nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, nil
+		}
+		element, err := renderCopy(v.Elem(), render)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(element)
+		return out, nil
+	}
+	return v, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShuttlePlanConfiguration_expandTemplates(t *testing.T) {
+	deployTemplate := ShuttleScriptTemplate{
+		Params: []string{"name"},
+		Name:   "deploy-{{ .name }}",
+		Script: ShuttlePlanScript{
+			Description: "Deploy {{ .name }}",
+			Actions: []ShuttleAction{
+				{Shell: "kubectl apply -f k8s/{{ .name }}"},
+			},
+		},
+	}
+
+	tt := []struct {
+		name      string
+		templates map[string]ShuttleScriptTemplate
+		scripts   map[string]ShuttlePlanScript
+		instances []string
+		output    map[string]ShuttlePlanScript
+		err       error
+	}{
+		{
+			name:      "instantiates scripts",
+			templates: map[string]ShuttleScriptTemplate{"deploy-service": deployTemplate},
+			instances: []string{"deploy-service(name=api)", "deploy-service( name = worker )"},
+			output: map[string]ShuttlePlanScript{
+				"deploy-api": {
+					Description: "Deploy api",
+					Actions:     []ShuttleAction{{Shell: "kubectl apply -f k8s/api"}},
+				},
+				"deploy-worker": {
+					Description: "Deploy worker",
+					Actions:     []ShuttleAction{{Shell: "kubectl apply -f k8s/worker"}},
+				},
+			},
+		},
+		{
+			name: "default name",
+			templates: map[string]ShuttleScriptTemplate{
+				"deploy": {
+					Params: []string{"name", "region"},
+					Script: ShuttlePlanScript{
+						Actions: []ShuttleAction{{Shell: "echo {{ .region }}"}},
+					},
+				},
+			},
+			instances: []string{"deploy(region=eu, name=api)"},
+			output: map[string]ShuttlePlanScript{
+				"deploy-api-eu": {
+					Actions: []ShuttleAction{{Shell: "echo eu"}},
+				},
+			},
+		},
+		{
+			name:      "collision with existing script",
+			templates: map[string]ShuttleScriptTemplate{"deploy-service": deployTemplate},
+			scripts:   map[string]ShuttlePlanScript{"deploy-api": {}},
+			instances: []string{"deploy-service(name=api)"},
+			err: errors.New(
+				"exit code 1 - Script 'deploy-api' generated by template instance 'deploy-service(name=api)' is already defined",
+			),
+		},
+		{
+			name:      "collision between instances",
+			templates: map[string]ShuttleScriptTemplate{"deploy-service": deployTemplate},
+			instances: []string{"deploy-service(name=api)", "deploy-service(name=api)"},
+			err: errors.New(
+				"exit code 1 - Script 'deploy-api' generated by template instance 'deploy-service(name=api)' is already defined",
+			),
+		},
+		{
+			name:      "unknown template",
+			instances: []string{"deploy-service(name=api)"},
+			err: errors.New(
+				"exit code 1 - Script template instance 'deploy-service(name=api)' references unknown template 'deploy-service'",
+			),
+		},
+		{
+			name:      "missing parameter",
+			templates: map[string]ShuttleScriptTemplate{"deploy-service": deployTemplate},
+			instances: []string{"deploy-service()"},
+			err: errors.New(
+				"exit code 1 - Script template instance 'deploy-service()' is missing parameters: name",
+			),
+		},
+		{
+			name:      "unknown parameter",
+			templates: map[string]ShuttleScriptTemplate{"deploy-service": deployTemplate},
+			instances: []string{"deploy-service(name=api, replicas=2)"},
+			err: errors.New(
+				"exit code 1 - Script template instance 'deploy-service(name=api, replicas=2)' has unknown parameters: replicas",
+			),
+		},
+		{
+			name:      "duplicate parameter",
+			templates: map[string]ShuttleScriptTemplate{"deploy-service": deployTemplate},
+			instances: []string{"deploy-service(name=api, name=worker)"},
+			err: errors.New(
+				"exit code 1 - Script template instance 'deploy-service(name=api, name=worker)' sets parameter 'name' more than once",
+			),
+		},
+		{
+			name:      "invalid instance",
+			templates: map[string]ShuttleScriptTemplate{"deploy-service": deployTemplate},
+			instances: []string{"deploy-service name=api"},
+			err: errors.New(
+				"exit code 1 - Script template instance 'deploy-service name=api' is not valid: expected <template>(<param>=<value>, ...)",
+			),
+		},
+		{
+			name: "undeclared parameter in template",
+			templates: map[string]ShuttleScriptTemplate{
+				"deploy": {
+					Params: []string{"name"},
+					Script: ShuttlePlanScript{
+						Actions: []ShuttleAction{{Shell: "echo {{ .region }}"}},
+					},
+				},
+			},
+			instances: []string{"deploy(name=api)"},
+			err: errors.New(
+				`exit code 1 - Failed to instantiate script template 'deploy(name=api)': template: deploy:1:8: executing "deploy" at <.region>: map has no entry for key "region"`,
+			),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := ShuttlePlanConfiguration{
+				Templates: tc.templates,
+				Scripts:   tc.scripts,
+				Instances: tc.instances,
+			}
+
+			err := p.expandTemplates()
+
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.output, p.Scripts)
+			assert.Equal(t, "Deploy {{ .name }}", deployTemplate.Script.Description, "template must not be modified")
+			assert.Equal(t, "kubectl apply -f k8s/{{ .name }}", deployTemplate.Script.Actions[0].Shell, "template must not be modified")
+		})
+	}
+}
+
+// TestShuttlePlanConfiguration_expandTemplates_instances tests that every
+// field of an instance is rendered and that instances share nothing with the
+// template or each other.
+func TestShuttlePlanConfiguration_expandTemplates_instances(t *testing.T) {
+	template := func() ShuttleScriptTemplate {
+		return ShuttleScriptTemplate{
+			Params: []string{"name"},
+			Name:   "deploy-{{ .name }}",
+			Script: ShuttlePlanScript{
+				Needs:   []string{"build-{{ .name }}"},
+				Outputs: []string{"dist/{{ .name }}"},
+				Inputs:  &ShuttleScriptInputs{Files: []string{"cmd/{{ .name }}/**"}},
+				Matrix:  map[string][]string{"region": {"{{ .name }}-eu"}},
+				Args:    []ShuttleScriptArgs{{Name: "env", Values: []string{"{{ .name }}-prod"}}},
+				Actions: []ShuttleAction{
+					{
+						Shell:              "deploy {{ .name }}",
+						Docker:             &ShuttleDocker{Image: "{{ .name }}:latest", Mounts: []string{"{{ .name }}:/src"}},
+						RepeatUntilSuccess: &ShuttleRepeat{Interval: "{{ .name }}"},
+						Path:               []string{"bin/{{ .name }}"},
+						Tools:              []ShuttleToolRequirement{{Name: "{{ .name }}ctl"}},
+					},
+				},
+			},
+		}
+	}
+	instance := func(name string) ShuttlePlanScript {
+		return ShuttlePlanScript{
+			Needs:   []string{"build-" + name},
+			Outputs: []string{"dist/" + name},
+			Inputs:  &ShuttleScriptInputs{Files: []string{"cmd/" + name + "/**"}},
+			Matrix:  map[string][]string{"region": {name + "-eu"}},
+			Args:    []ShuttleScriptArgs{{Name: "env", Values: []string{name + "-prod"}}},
+			Actions: []ShuttleAction{
+				{
+					Shell:              "deploy " + name,
+					Docker:             &ShuttleDocker{Image: name + ":latest", Mounts: []string{name + ":/src"}},
+					RepeatUntilSuccess: &ShuttleRepeat{Interval: name},
+					Path:               []string{"bin/" + name},
+					Tools:              []ShuttleToolRequirement{{Name: name + "ctl"}},
+				},
+			},
+		}
+	}
+	p := ShuttlePlanConfiguration{
+		Templates: map[string]ShuttleScriptTemplate{"deploy": template()},
+		Instances: []string{"deploy(name=api)", "deploy(name=web)"},
+	}
+
+	err := p.expandTemplates()
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]ShuttlePlanScript{
+		"deploy-api": instance("api"),
+		"deploy-web": instance("web"),
+	}, p.Scripts)
+	assert.Equal(t, template(), p.Templates["deploy"], "template must not be modified")
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	shuttleerrors "github.com/lunarway/shuttle/pkg/errors"
+)
+
+// Providers of secrets
+const (
+	SecretProviderEnv   = "env"
+	SecretProviderFile  = "file"
+	SecretProviderSops  = "sops"
+	SecretProviderVault = "vault"
+	SecretProviderSSM   = "ssm"
+)
+
+// ShuttleSecret describes where the value of a secret environment variable of
+// actions is read from. Exactly one provider must be set.
+type ShuttleSecret struct {
+	// Env is the name of an environment variable of shuttle holding the
+	// secret.
+	Env string `yaml:"env"`
+	// File is a project relative file holding the secret.
+	File string `yaml:"file"`
+	// Sops is a project relative SOPS encrypted file decrypted with the sops
+	// CLI.
+	Sops string `yaml:"sops"`
+	// Vault is the path of a secret read with the vault CLI, eg. secret/ci.
+	Vault string `yaml:"vault"`
+	// SSM is the name of an AWS SSM parameter read with the aws CLI.
+	SSM string `yaml:"ssm"`
+	// Key selects a field of a Vault secret or a value of a SOPS document
+	// with dots separating nested keys, eg. db.password. It is required for
+	// Vault secrets.
+	Key string `yaml:"key"`
+}
+
+// Provider returns the provider of the secret and its reference, eg. the name
+// of the environment variable. An empty provider is returned if none or more
+// than one provider is set.
+func (s ShuttleSecret) Provider() (string, string) {
+	var provider, reference string
+	for _, candidate := range []struct{ provider, reference string }{
+		{SecretProviderEnv, s.Env},
+		{SecretProviderFile, s.File},
+		{SecretProviderSops, s.Sops},
+		{SecretProviderVault, s.Vault},
+		{SecretProviderSSM, s.SSM},
+	} {
+		if candidate.reference == "" {
+			continue
+		}
+		if provider != "" {
+			return "", ""
+		}
+		provider, reference = candidate.provider, candidate.reference
+	}
+	return provider, reference
+}
+
+func (s ShuttleSecret) validate() error {
+	provider, _ := s.Provider()
+	switch {
+	case provider == "":
+		return errors.New("it must have exactly one of env, file, sops, vault or ssm")
+	case provider == SecretProviderVault && s.Key == "":
+		return errors.New("vault secrets must have a key")
+	case s.Key != "" && provider != SecretProviderVault && provider != SecretProviderSops:
+		return fmt.Errorf("key is only supported by sops and vault secrets and not %s", provider)
+	}
+	return nil
+}
+
+// secretNameRegexp matches valid names of environment variables
+var secretNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// mergeSecrets returns the secrets of the plan overridden by those of the
+// project with the same name. An error is returned if any of them is invalid.
+func mergeSecrets(plan, project map[string]ShuttleSecret) (map[string]ShuttleSecret, error) {
+	secrets := make(map[string]ShuttleSecret, len(plan)+len(project))
+	for name, secret := range plan {
+		secrets[name] = secret
+	}
+	for name, secret := range project {
+		secrets[name] = secret
+	}
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var problems []string
+	for _, name := range names {
+		if !secretNameRegexp.MatchString(name) {
+			problems = append(problems, fmt.Sprintf("Secret '%s' is invalid: its name must be a valid environment variable name", name))
+			continue
+		}
+		if err := secrets[name].validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("Secret '%s' is invalid: %v", name, err))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, shuttleerrors.NewExitCode(2, "%s", strings.Join(problems, "\n"))
+	}
+	return secrets, nil
+}
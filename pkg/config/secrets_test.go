@@ -0,0 +1,54 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeSecrets(t *testing.T) {
+	tt := []struct {
+		name    string
+		plan    map[string]ShuttleSecret
+		project map[string]ShuttleSecret
+		secrets map[string]ShuttleSecret
+		err     string
+	}{
+		{
+			name:    "project overrides plan",
+			plan:    map[string]ShuttleSecret{"TOKEN": {Env: "PLAN_TOKEN"}, "DB_PASSWORD": {SSM: "/db/password"}},
+			project: map[string]ShuttleSecret{"TOKEN": {File: ".token"}},
+			secrets: map[string]ShuttleSecret{"TOKEN": {File: ".token"}, "DB_PASSWORD": {SSM: "/db/password"}},
+		},
+		{
+			name:    "no secrets",
+			secrets: map[string]ShuttleSecret{},
+		},
+		{
+			name: "invalid secrets",
+			project: map[string]ShuttleSecret{
+				"NONE":      {},
+				"TWO":       {Env: "A", File: "b"},
+				"VAULT":     {Vault: "secret/ci"},
+				"ENV_KEY":   {Env: "A", Key: "b"},
+				"not-valid": {Env: "A"},
+			},
+			err: "exit code 2 - Secret 'ENV_KEY' is invalid: key is only supported by sops and vault secrets and not env\n" +
+				"Secret 'NONE' is invalid: it must have exactly one of env, file, sops, vault or ssm\n" +
+				"Secret 'TWO' is invalid: it must have exactly one of env, file, sops, vault or ssm\n" +
+				"Secret 'VAULT' is invalid: vault secrets must have a key\n" +
+				"Secret 'not-valid' is invalid: its name must be a valid environment variable name",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			secrets, err := mergeSecrets(tc.plan, tc.project)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.secrets, secrets)
+		})
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 
 	shuttleerrors "github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/git"
 	"github.com/lunarway/shuttle/pkg/ui"
 	"gopkg.in/yaml.v2"
 )
@@ -17,10 +19,110 @@ type DynamicYaml = map[string]interface{}
 
 // ShuttleConfig describes the actual config for each project
 type ShuttleConfig struct {
-	Plan      string                       `yaml:"-"`
+	// Plan is the first plan of the project.
+	Plan string `yaml:"-"`
+	// Overlays are the plans listed after the first plan. They are merged onto
+	// the first plan in order.
+	Overlays  []string                     `yaml:"-"`
 	PlanRaw   interface{}                  `yaml:"plan"`
 	Variables DynamicYaml                  `yaml:"vars"`
 	Scripts   map[string]ShuttlePlanScript `yaml:"scripts"`
+	// GolangActions is the project relative directory of golang actions.
+	// Defaults to actions.
+	GolangActions string `yaml:"golangActions"`
+	// Guards must all pass once before any script of the project is run. They
+	// run after the guards of the plan.
+	Guards []ShuttlePreflightCheck `yaml:"guards"`
+	// Hooks are actions run around every script of the project. They run
+	// after the hooks of the plan.
+	Hooks ShuttleHooks `yaml:"hooks"`
+	// PlanTTL is how long a fetched git plan is used before it is fetched
+	// again, eg. 1h. It takes precedence over SHUTTLE_CACHE_DURATION_MIN.
+	PlanTTL string `yaml:"planTTL"`
+	// PlanPublicKey is a minisign or PEM encoded cosign public key the plan
+	// must be signed by. Plans are not verified if it is not set.
+	PlanPublicKey string `yaml:"planPublicKey"`
+	// Secrets are environment variables of all actions read from secret
+	// providers. They override secrets of the plan with the same name.
+	Secrets map[string]ShuttleSecret `yaml:"secrets"`
+	// Profiles override the plan and variables when activated with --profile
+	// or SHUTTLE_PROFILE.
+	Profiles map[string]ShuttleProfile `yaml:"profiles"`
+	// Profile is the name of the active profile if any.
+	Profile string `yaml:"-"`
+	// RunLogs configures the log files written for every run of a script.
+	RunLogs ShuttleRunLogs `yaml:"runLogs"`
+	// History configures the history of runs written to .shuttle/history.jsonl.
+	History ShuttleHistory `yaml:"history"`
+	// Locks configures where the locks of scripts with a lock are held.
+	Locks ShuttleLocks `yaml:"locks"`
+	// Artifacts configures the artifacts collected from actions.
+	Artifacts ShuttleArtifacts `yaml:"artifacts"`
+	// StopGracePeriod is how long shell actions may clean up after they are
+	// signalled to stop before they are killed, eg. 30s. The stopGracePeriod
+	// of actions and SHUTTLE_STOP_GRACE_PERIOD take precedence.
+	StopGracePeriod string `yaml:"stopGracePeriod"`
+	// Shell runs the shell snippets of all actions of the project, one of sh,
+	// bash, pwsh or cmd. Defaults to sh.
+	Shell string `yaml:"shell"`
+	// Env limits the environment of shuttle inherited by all actions of the
+	// project. It takes precedence over the env of the plan.
+	Env ShuttleEnv `yaml:"env"`
+}
+
+// ShuttleRunLogs configures the log files of every run written to
+// .shuttle/logs. The oldest logs are removed when a run starts.
+type ShuttleRunLogs struct {
+	// Disabled stops writing run logs.
+	Disabled bool `yaml:"disabled"`
+	// Keep is the number of logs kept. Defaults to 50.
+	Keep int `yaml:"keep"`
+	// MaxAge is how long logs are kept, eg. 168h. Logs are kept regardless of
+	// their age by default.
+	MaxAge string `yaml:"maxAge"`
+}
+
+// ShuttleHistory configures the history of runs of scripts, shown by shuttle
+// history, and the audit endpoint runs are sent to.
+type ShuttleHistory struct {
+	// Disabled stops recording runs.
+	Disabled bool `yaml:"disabled"`
+	// Keep is the number of runs kept. Defaults to 1000.
+	Keep int `yaml:"keep"`
+	// Endpoint is an HTTP URL every run is posted to as JSON, eg. an audit
+	// log. SHUTTLE_HISTORY_ENDPOINT takes precedence.
+	Endpoint string `yaml:"endpoint"`
+}
+
+// Backends holding the locks of scripts
+const (
+	// LockBackendFile holds locks in files of .shuttle/locks such that runs
+	// of the project on the same machine are mutually exclusive
+	LockBackendFile = "file"
+	// LockBackendHTTP holds locks in an HTTP lock service such that runs on
+	// all machines are mutually exclusive
+	LockBackendHTTP = "http"
+)
+
+// ShuttleLocks configures the backend holding the locks of scripts.
+type ShuttleLocks struct {
+	// Backend is file or http. Defaults to file unless an endpoint is set.
+	Backend string `yaml:"backend"`
+	// Endpoint is the URL of the HTTP lock service locks are acquired with
+	// PUT <endpoint>/<lock> and released with DELETE requests.
+	// SHUTTLE_LOCK_ENDPOINT takes precedence.
+	Endpoint string `yaml:"endpoint"`
+}
+
+// ShuttleArtifacts configures the artifacts of runs collected into
+// .shuttle/artifacts and where they are uploaded to.
+type ShuttleArtifacts struct {
+	// Keep is the number of runs whose artifacts are kept. Defaults to 20.
+	Keep int `yaml:"keep"`
+	// Upload is the location artifacts are uploaded to under the ID of their
+	// run, an s3:// or gs:// bucket and prefix or an HTTP URL artifacts are
+	// PUT to. SHUTTLE_ARTIFACTS_UPLOAD takes precedence.
+	Upload string `yaml:"upload"`
 }
 
 // ShuttleProjectContext describes the context of the project using shuttle
@@ -30,9 +132,41 @@ type ShuttleProjectContext struct {
 	TempDirectoryPath         string
 	Config                    ShuttleConfig
 	LocalPlanPath             string
-	Plan                      ShuttlePlanConfiguration
-	Scripts                   map[string]ShuttlePlanScript
-	UI                        *ui.UI
+	// PlanSourcePath is the directory a local plan is copied to LocalPlanPath
+	// from. It is empty for git plans.
+	PlanSourcePath string
+	// Overlays are the fetched overlay plans in the order they are merged.
+	Overlays []ShuttlePlanOverlay
+	Plan     ShuttlePlanConfiguration
+	Scripts  map[string]ShuttlePlanScript
+	// Secrets are the secrets of the plan and the project. They are resolved
+	// when scripts are run.
+	Secrets map[string]ShuttleSecret
+	UI      *ui.UI
+}
+
+// Guards returns the guards of the plan followed by those of the project.
+func (c *ShuttleProjectContext) Guards() []ShuttlePreflightCheck {
+	guards := append([]ShuttlePreflightCheck{}, c.Plan.Guards...)
+	return append(guards, c.Config.Guards...)
+}
+
+// Hooks returns the hooks of the plan followed by those of the project.
+func (c *ShuttleProjectContext) Hooks() ShuttleHooks {
+	return c.Plan.Hooks.append(c.Config.Hooks)
+}
+
+// PlanInProject returns true if the plan is a local plan within the project
+// directory, eg. a plan in a subdirectory of the project repository.
+func (c *ShuttleProjectContext) PlanInProject() bool {
+	if c.PlanSourcePath == "" || c.ProjectPath == "" {
+		return false
+	}
+	rel, err := filepath.Rel(c.ProjectPath, c.PlanSourcePath)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
 }
 
 // Setup the ShuttleProjectContext for a specific path
@@ -41,13 +175,27 @@ func (c *ShuttleProjectContext) Setup(
 	uii *ui.UI,
 	clean bool,
 	skipGitPlanPulling bool,
+	refreshPlans bool,
+	offline bool,
 	planArgument string,
 	strictConfigLookup bool,
+	updatePlan bool,
+	insecureSkipVerify bool,
+	profile string,
 ) (*ShuttleProjectContext, error) {
-	projectPath, err := c.Config.getConf(projectPath, strictConfigLookup)
+	projectPath, err := c.Config.getConf(projectPath, strictConfigLookup, profile)
 	if err != nil {
 		return nil, err
 	}
+	if c.Config.Profile != "" {
+		uii.Verboseln("Using profile '%s'", c.Config.Profile)
+		if c.Config.Profiles[c.Config.Profile].PlanRaw != nil {
+			uii.Verboseln("- plan: %v", c.Config.Profiles[c.Config.Profile].PlanRaw)
+		}
+		for _, name := range variableNames(c.Config.Profiles[c.Config.Profile].Variables) {
+			uii.Verboseln("- vars.%s: %v", name, c.Config.Variables[name])
+		}
+	}
 	c.UI = uii
 	c.ProjectPath = projectPath
 	c.LocalShuttleDirectoryPath = path.Join(c.ProjectPath, ".shuttle")
@@ -65,6 +213,15 @@ func (c *ShuttleProjectContext) Setup(
 	}
 
 	c.TempDirectoryPath = path.Join(c.LocalShuttleDirectoryPath, "temp")
+	lock, err := c.planLock(uii, planArgument, updatePlan)
+	if err != nil {
+		return nil, err
+	}
+	cache := git.PlanCache{
+		TTL:     c.Config.PlanTTL,
+		Refresh: refreshPlans,
+		Offline: offline,
+	}
 	c.LocalPlanPath, err = FetchPlan(
 		c.Config.Plan,
 		projectPath,
@@ -72,27 +229,92 @@ func (c *ShuttleProjectContext) Setup(
 		uii,
 		skipGitPlanPulling,
 		planArgument,
+		cache,
+		lock,
 	)
 	if err != nil {
 		return nil, err
 	}
+	err = c.fetchOverlays(uii, skipGitPlanPulling, cache)
+	if err != nil {
+		return nil, err
+	}
+	c.PlanSourcePath = localPlanSource(c.Config.Plan, projectPath, planArgument)
+	if c.PlanInProject() {
+		uii.Verboseln(
+			"Plan at '%s' is inside the project and is used from a copy at '%s'",
+			c.PlanSourcePath,
+			c.LocalPlanPath,
+		)
+	}
+	err = c.verifyPlan(uii, insecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
 	_, err = c.Plan.Load(c.LocalPlanPath)
 	if err != nil {
 		return nil, err
 	}
+	for scriptName, script := range c.Plan.Scripts {
+		script.Origin = c.Config.Plan
+		c.Plan.Scripts[scriptName] = script
+	}
+	err = c.loadOverlays()
+	if err != nil {
+		return nil, err
+	}
 
 	c.Scripts = make(map[string]ShuttlePlanScript)
 	for scriptName, script := range c.Plan.Scripts {
 		c.Scripts[scriptName] = script
 	}
 	for scriptName, script := range c.Config.Scripts {
+		script.Origin = OriginProject
 		c.Scripts[scriptName] = script
 	}
+
+	c.Secrets, err = mergeSecrets(c.Plan.Secrets, c.Config.Secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.Plan.Policy.validate(uii, c.Scripts)
+	if err != nil {
+		return nil, err
+	}
+	err = validateArgDeclarations(c.Scripts)
+	if err != nil {
+		return nil, err
+	}
 	return c, nil
 }
 
-// getConf loads the ShuttleConfig from yaml file in the project path
-func (c *ShuttleConfig) getConf(projectPath string, strictConfigLookup bool) (string, error) {
+// planLock returns the lock of the plan of the project if it is to be honored.
+// Locks are ignored when the plan is updated or overloaded and when they lock
+// another plan than the one of shuttle.yaml.
+func (c *ShuttleProjectContext) planLock(uii *ui.UI, planArgument string, updatePlan bool) (*PlanLock, error) {
+	if c.ProjectPath == "" || updatePlan || planArgument != "" || c.Config.Plan == "" {
+		return nil, nil
+	}
+	lock, err := ReadPlanLock(c.ProjectPath)
+	if err != nil || lock == nil {
+		return nil, err
+	}
+	if lock.Plan != c.Config.Plan {
+		uii.EmphasizeInfoln(
+			"Ignoring %s as it locks plan '%s' and not '%s'. Run 'shuttle plan lock' to lock the plan again.",
+			PlanLockFile,
+			lock.Plan,
+			c.Config.Plan,
+		)
+		return nil, nil
+	}
+	return lock, nil
+}
+
+// getConf loads the ShuttleConfig from yaml file in the project path with the
+// profile applied if set
+func (c *ShuttleConfig) getConf(projectPath string, strictConfigLookup bool, profile string) (string, error) {
 	if projectPath == "" {
 		return projectPath, nil
 	}
@@ -111,6 +333,10 @@ func (c *ShuttleConfig) getConf(projectPath string, strictConfigLookup bool) (st
 	decoder.SetStrict(true)
 	err = decoder.Decode(c)
 	if err != nil {
+		// the schema pinpoints the problems by their key and position
+		if problems, _ := ValidateSchemaFile(ProjectSchema(), file.Name(), file.Name()); len(problems) > 0 {
+			err = schemaError(problems)
+		}
 		return "", shuttleerrors.NewExitCode(
 			2,
 			"Failed to parse shuttle configuration: %s\n\nMake sure your 'shuttle.yaml' is valid.",
@@ -118,6 +344,11 @@ func (c *ShuttleConfig) getConf(projectPath string, strictConfigLookup bool) (st
 		)
 	}
 
+	err = c.applyProfile(profile)
+	if err != nil {
+		return "", err
+	}
+
 	if c.PlanRaw == nil {
 		return "", shuttleerrors.NewExitCode(
 			2,
@@ -130,7 +361,18 @@ func (c *ShuttleConfig) getConf(projectPath string, strictConfigLookup bool) (st
 	case false:
 		// no plan
 	default:
-		c.Plan = c.PlanRaw.(string)
+		plans, err := parsePlans(c.PlanRaw)
+		if err != nil {
+			return "", shuttleerrors.NewExitCode(
+				2,
+				"Failed to parse shuttle configuration: %s\n\nMake sure your 'shuttle.yaml' is valid.",
+				err,
+			)
+		}
+		c.Plan = plans[0]
+		if len(plans) > 1 {
+			c.Overlays = plans[1:]
+		}
 	}
 
 	// return the path where the shuttle.yaml file was found
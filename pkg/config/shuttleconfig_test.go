@@ -24,7 +24,7 @@ func TestShuttleConfig_getConf(t *testing.T) {
 			name:  "unknown field",
 			input: "testdata/unknown_field",
 			err: errors.New(
-				"exit code 2 - Failed to parse shuttle configuration: yaml: unmarshal errors:\n  line 1: field nothing not found in type config.ShuttleConfig\n\nMake sure your 'shuttle.yaml' is valid.",
+				"exit code 2 - Failed to parse shuttle configuration: schema errors:\n  testdata/unknown_field/shuttle.yaml:1:1: 'nothing' is not allowed\n\nMake sure your 'shuttle.yaml' is valid.",
 			),
 		},
 		{
@@ -100,7 +100,7 @@ func TestShuttleConfig_getConf(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			c := &ShuttleConfig{}
 
-			path, err := c.getConf(tc.input, tc.strictMode)
+			path, err := c.getConf(tc.input, tc.strictMode, "")
 
 			if tc.err != nil {
 				assert.EqualError(t, err, tc.err.Error(), "error not as expected")
@@ -112,3 +112,68 @@ func TestShuttleConfig_getConf(t *testing.T) {
 		})
 	}
 }
+
+func TestShuttleProjectContext_PlanInProject(t *testing.T) {
+	tt := []struct {
+		name         string
+		plan         string
+		planArgument string
+		source       string
+		inProject    bool
+	}{
+		{
+			name:      "no plan",
+			plan:      "",
+			source:    "",
+			inProject: false,
+		},
+		{
+			name:      "git plan",
+			plan:      "https://github.com/lunarway/shuttle-example-go-plan.git",
+			source:    "",
+			inProject: false,
+		},
+		{
+			name:      "oci plan",
+			plan:      "oci://ghcr.io/lunarway/plan:v1.2.3",
+			source:    "",
+			inProject: false,
+		},
+		{
+			name:      "subdirectory of project",
+			plan:      "./plan",
+			source:    "/repo/service/plan",
+			inProject: true,
+		},
+		{
+			name:      "sibling of project",
+			plan:      "../plan",
+			source:    "/repo/plan",
+			inProject: false,
+		},
+		{
+			name:      "absolute path inside project",
+			plan:      "/repo/service/tools/plan",
+			source:    "/repo/service/tools/plan",
+			inProject: true,
+		},
+		{
+			name:         "overloaded with absolute path",
+			plan:         "../plan",
+			planArgument: "/repo/service/plan",
+			source:       "/repo/service/plan",
+			inProject:    true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := ShuttleProjectContext{
+				ProjectPath:    "/repo/service",
+				PlanSourcePath: localPlanSource(tc.plan, "/repo/service", tc.planArgument),
+			}
+
+			assert.Equal(t, tc.source, c.PlanSourcePath, "source")
+			assert.Equal(t, tc.inProject, c.PlanInProject(), "in project")
+		})
+	}
+}
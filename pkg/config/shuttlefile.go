@@ -0,0 +1,228 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+
+	shuttleerrors "github.com/lunarway/shuttle/pkg/errors"
+	yamlv2 "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ShuttleFile is a shuttle.yaml edited in place. Comments and the order of
+// keys are kept when it is written.
+type ShuttleFile struct {
+	// Path is the path of the shuttle.yaml.
+	Path string
+	doc  yaml.Node
+}
+
+// OpenShuttleFile opens the shuttle.yaml of the project at projectPath. Parent
+// directories are searched for it unless strictConfigLookup is set like when
+// loading the project.
+func OpenShuttleFile(projectPath string, strictConfigLookup bool) (*ShuttleFile, error) {
+	file, err := locateShuttleConfigurationFile(projectPath, strictConfigLookup)
+	if err != nil {
+		return nil, shuttleerrors.NewExitCode(2, "Failed to load shuttle configuration: %s", err)
+	}
+	defer file.Close()
+
+	f := &ShuttleFile{Path: file.Name()}
+	err = yaml.NewDecoder(file).Decode(&f.doc)
+	if err != nil {
+		return nil, shuttleerrors.NewExitCode(2, "Failed to parse shuttle configuration: %s", err)
+	}
+	if f.root().Kind != yaml.MappingNode {
+		return nil, shuttleerrors.NewExitCode(2, "Failed to parse shuttle configuration: %s is not a mapping", f.Path)
+	}
+	return f, nil
+}
+
+func (f *ShuttleFile) root() *yaml.Node {
+	return f.doc.Content[0]
+}
+
+// Get returns the value of key, eg. vars.docker.image. Keys are dot separated
+// and elements of lists are selected by their index, eg. scripts.build.actions.0.
+// Scalars are returned as is and other values as YAML.
+func (f *ShuttleFile) Get(key string) (string, error) {
+	node, err := f.lookup(key)
+	if err != nil {
+		return "", err
+	}
+	if node.Kind == yaml.ScalarNode {
+		return node.Value, nil
+	}
+	var output bytes.Buffer
+	encoder := yaml.NewEncoder(&output)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(output.String(), "\n"), nil
+}
+
+// Set sets key to value. value is parsed as YAML, eg. 3 is a number and [a, b]
+// a list, unless asString is set. Missing parent keys are added as mappings.
+func (f *ShuttleFile) Set(key, value string, asString bool) error {
+	valueNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	if !asString {
+		var parsed yaml.Node
+		if err := yaml.Unmarshal([]byte(value), &parsed); err == nil && len(parsed.Content) == 1 {
+			valueNode = parsed.Content[0]
+		}
+	}
+
+	path := strings.Split(key, ".")
+	node := f.root()
+	for i, name := range path {
+		last := i == len(path)-1
+		// empty keys, eg. vars:, are set to a mapping
+		if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+			node.Kind = yaml.MappingNode
+			node.Tag = "!!map"
+			node.Value = ""
+		}
+		switch node.Kind {
+		case yaml.MappingNode:
+			index := mappingIndex(node, name)
+			if index == -1 {
+				child := valueNode
+				if !last {
+					child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, child)
+				node = child
+				continue
+			}
+			if last {
+				node.Content[index+1] = keepComments(node.Content[index+1], valueNode)
+				continue
+			}
+			node = node.Content[index+1]
+		case yaml.SequenceNode:
+			index, err := sequenceIndex(node, name, strings.Join(path[:i], "."))
+			if err != nil {
+				return err
+			}
+			if last {
+				node.Content[index] = keepComments(node.Content[index], valueNode)
+				continue
+			}
+			node = node.Content[index]
+		default:
+			return shuttleerrors.NewExitCode(1, "Key '%s' cannot be set as '%s' is not a mapping or a list", key, strings.Join(path[:i], "."))
+		}
+	}
+	return nil
+}
+
+// Unset removes key. It returns false if key is not set.
+func (f *ShuttleFile) Unset(key string) (bool, error) {
+	path := strings.Split(key, ".")
+	parentKey := strings.Join(path[:len(path)-1], ".")
+	parent := f.root()
+	if parentKey != "" {
+		var err error
+		parent, err = f.lookup(parentKey)
+		if err != nil {
+			return false, nil
+		}
+	}
+	name := path[len(path)-1]
+	switch parent.Kind {
+	case yaml.MappingNode:
+		index := mappingIndex(parent, name)
+		if index == -1 {
+			return false, nil
+		}
+		parent.Content = append(parent.Content[:index], parent.Content[index+2:]...)
+		return true, nil
+	case yaml.SequenceNode:
+		index, err := strconv.Atoi(name)
+		if err != nil || index < 0 || index >= len(parent.Content) {
+			return false, nil
+		}
+		parent.Content = append(parent.Content[:index], parent.Content[index+1:]...)
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// Write validates the file like when loading the project and writes it.
+func (f *ShuttleFile) Write() error {
+	var output bytes.Buffer
+	encoder := yaml.NewEncoder(&output)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&f.doc); err != nil {
+		return err
+	}
+	var c ShuttleConfig
+	if err := yamlv2.UnmarshalStrict(output.Bytes(), &c); err != nil {
+		return shuttleerrors.NewExitCode(1, "Change makes shuttle.yaml invalid: %v", err)
+	}
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(f.Path, output.Bytes(), info.Mode())
+}
+
+// lookup returns the node of key.
+func (f *ShuttleFile) lookup(key string) (*yaml.Node, error) {
+	path := strings.Split(key, ".")
+	node := f.root()
+	for i, name := range path {
+		switch node.Kind {
+		case yaml.MappingNode:
+			index := mappingIndex(node, name)
+			if index == -1 {
+				return nil, shuttleerrors.NewExitCode(1, "Key '%s' is not set in shuttle.yaml", key)
+			}
+			node = node.Content[index+1]
+		case yaml.SequenceNode:
+			index, err := sequenceIndex(node, name, strings.Join(path[:i], "."))
+			if err != nil {
+				return nil, err
+			}
+			node = node.Content[index]
+		default:
+			return nil, shuttleerrors.NewExitCode(1, "Key '%s' is not set in shuttle.yaml", key)
+		}
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node, nil
+}
+
+// mappingIndex returns the index of the key name in the content of the
+// mapping node or -1 if it is not set.
+func mappingIndex(node *yaml.Node, name string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// sequenceIndex returns the element name of the list of key selects.
+func sequenceIndex(node *yaml.Node, name, key string) (int, error) {
+	index, err := strconv.Atoi(name)
+	if err != nil || index < 0 || index >= len(node.Content) {
+		return 0, shuttleerrors.NewExitCode(1, "Key '%s' is a list of %d elements: '%s' is not an index of it", key, len(node.Content), name)
+	}
+	return index, nil
+}
+
+// keepComments returns value with the comments of the node it replaces.
+func keepComments(replaced, value *yaml.Node) *yaml.Node {
+	value.HeadComment = replaced.HeadComment
+	value.LineComment = replaced.LineComment
+	value.FootComment = replaced.FootComment
+	return value
+}
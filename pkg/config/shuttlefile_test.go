@@ -0,0 +1,134 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const shuttleFileContent = `# the plan of the squad
+plan: false
+vars:
+  squad: aura # owning squad
+  docker:
+    image: earth-united/moon-base
+  empty:
+  regions: [eu, us]
+scripts:
+  build:
+    actions:
+      - shell: echo build
+`
+
+func newShuttleFile(t *testing.T) *ShuttleFile {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "shuttle.yaml"), []byte(shuttleFileContent), 0o644))
+	f, err := OpenShuttleFile(dir, true)
+	require.NoError(t, err)
+	return f
+}
+
+func TestShuttleFile_Get(t *testing.T) {
+	tt := []struct {
+		name  string
+		key   string
+		value string
+		err   string
+	}{
+		{name: "scalar", key: "vars.squad", value: "aura"},
+		{name: "mapping", key: "vars.docker", value: "image: earth-united/moon-base"},
+		{name: "list element", key: "scripts.build.actions.0.shell", value: "echo build"},
+		{name: "missing key", key: "vars.missing", err: "exit code 1 - Key 'vars.missing' is not set in shuttle.yaml"},
+		{name: "invalid index", key: "vars.regions.2", err: "exit code 1 - Key 'vars.regions' is a list of 2 elements: '2' is not an index of it"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			value, err := newShuttleFile(t).Get(tc.key)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.value, value)
+		})
+	}
+}
+
+func TestShuttleFile_Set(t *testing.T) {
+	f := newShuttleFile(t)
+
+	require.NoError(t, f.Set("vars.docker.image", "foo", false))
+	require.NoError(t, f.Set("vars.replicas", "3", false))
+	require.NoError(t, f.Set("vars.version", "1.10", true))
+	require.NoError(t, f.Set("vars.empty.key", "value", false))
+	require.NoError(t, f.Set("vars.regions.1", "ap", false))
+	require.NoError(t, f.Set("vars.k8s.namespace", "aura", false))
+	require.NoError(t, f.Write())
+
+	content, err := os.ReadFile(f.Path)
+	require.NoError(t, err)
+	assert.Equal(t, `# the plan of the squad
+plan: false
+vars:
+  squad: aura # owning squad
+  docker:
+    image: foo
+  empty:
+    key: value
+  regions: [eu, ap]
+  replicas: 3
+  version: "1.10"
+  k8s:
+    namespace: aura
+scripts:
+  build:
+    actions:
+      - shell: echo build
+`, string(content))
+}
+
+func TestShuttleFile_Set_invalid(t *testing.T) {
+	f := newShuttleFile(t)
+
+	assert.EqualError(t, f.Set("vars.squad.name", "aura", false), "exit code 1 - Key 'vars.squad.name' cannot be set as 'vars.squad' is not a mapping or a list")
+
+	require.NoError(t, f.Set("unknown", "value", false))
+	assert.EqualError(t, f.Write(), "exit code 1 - Change makes shuttle.yaml invalid: yaml: unmarshal errors:\n  line 13: field unknown not found in type config.ShuttleConfig")
+	content, err := os.ReadFile(f.Path)
+	require.NoError(t, err)
+	assert.Equal(t, shuttleFileContent, string(content), "invalid changes are not written")
+}
+
+func TestShuttleFile_Unset(t *testing.T) {
+	f := newShuttleFile(t)
+
+	for _, key := range []string{"vars.docker.image", "vars.regions.0", "vars.empty"} {
+		removed, err := f.Unset(key)
+		require.NoError(t, err)
+		assert.True(t, removed, key)
+	}
+	for _, key := range []string{"vars.missing", "vars.missing.key", "vars.regions.5"} {
+		removed, err := f.Unset(key)
+		require.NoError(t, err)
+		assert.False(t, removed, key)
+	}
+	require.NoError(t, f.Write())
+
+	content, err := os.ReadFile(f.Path)
+	require.NoError(t, err)
+	assert.Equal(t, `# the plan of the squad
+plan: false
+vars:
+  squad: aura # owning squad
+  docker: {}
+  regions: [us]
+scripts:
+  build:
+    actions:
+      - shell: echo build
+`, string(content))
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path"
@@ -11,15 +12,71 @@ import (
 	"github.com/lunarway/shuttle/pkg/copy"
 	"github.com/lunarway/shuttle/pkg/errors"
 	"github.com/lunarway/shuttle/pkg/git"
+	"github.com/lunarway/shuttle/pkg/oci"
 	"github.com/lunarway/shuttle/pkg/ui"
 	"gopkg.in/yaml.v2"
 )
 
+// Shells running the shell snippets of actions
+const (
+	ShellSh   = "sh"
+	ShellBash = "bash"
+	ShellPwsh = "pwsh"
+	ShellCmd  = "cmd"
+)
+
 // ShuttlePlanScript is a ShuttlePlan sub-element
 type ShuttlePlanScript struct {
 	Description string              `yaml:"description"`
 	Actions     []ShuttleAction     `yaml:"actions"`
 	Args        []ShuttleScriptArgs `yaml:"args"`
+	// Shell runs the shell snippets of the actions of the script, one of sh,
+	// bash, pwsh or cmd. It takes precedence over the shell of shuttle.yaml.
+	Shell string `yaml:"shell"`
+	// Exclusive lists groups of arguments that cannot be supplied together.
+	Exclusive []ShuttleExclusiveArgs `yaml:"exclusive"`
+	// Deprecated marks the script as deprecated with a notice, eg. what to use
+	// instead, printed when the script is run.
+	Deprecated string `yaml:"deprecated"`
+	// When is a condition, like the when of actions, skipping the script if it
+	// is false, eg. changed("services/api/**").
+	When string `yaml:"when"`
+	// Checks must all pass before any action of the script is run.
+	Checks []ShuttleRunCheck `yaml:"checks"`
+	// Needs lists scripts run before the script, eg. to generate code before
+	// building it.
+	Needs []string `yaml:"needs"`
+	// Inputs opts the script into caching. The script is skipped if its
+	// inputs are unchanged since it last succeeded.
+	Inputs *ShuttleScriptInputs `yaml:"inputs"`
+	// Outputs are project relative files, or glob patterns, produced by the
+	// script. They are restored from the cache when the script is skipped.
+	Outputs []string `yaml:"outputs"`
+	// Matrix runs the script once for every combination of the values of its
+	// arguments, eg. env: [dev, prod].
+	Matrix map[string][]string `yaml:"matrix"`
+	// Lock is the name of a lock held while the script runs such that runs
+	// with the same lock, eg. deploy-$env, are mutually exclusive. It is
+	// expanded with the environment of the script.
+	Lock string `yaml:"lock"`
+	// Source is the plan relative path of the file the script was included
+	// from. It is empty for scripts defined in plan.yaml or shuttle.yaml.
+	Source string `yaml:"-"`
+	// Origin is the plan contributing the script as written in shuttle.yaml
+	// or shuttle.yaml for scripts of the project.
+	Origin string `yaml:"-"`
+	// PlanPath is the local directory of the overlay plan the script is
+	// defined in. It is empty for scripts of the first plan and the project.
+	PlanPath string `yaml:"-"`
+}
+
+// sourceSuffix returns a description of the file the script was included
+// from for use in error messages.
+func (s ShuttlePlanScript) sourceSuffix() string {
+	if s.Source == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (from %s)", s.Source)
 }
 
 // ShuttleScriptArgs describes an arguments that a script accepts
@@ -27,13 +84,30 @@ type ShuttleScriptArgs struct {
 	Name        string `yaml:"name"`
 	Required    bool   `yaml:"required"`
 	Description string `yaml:"description"`
+	// Secret masks the value of the argument in the output of actions.
+	Secret bool `yaml:"secret"`
+	// Type is one of string, the default, int, bool, enum and path.
+	Type string `yaml:"type"`
+	// Values are the values an enum argument accepts.
+	Values []string `yaml:"values"`
+	// Default is the value of the argument if it is not supplied.
+	Default string `yaml:"default"`
+	// Pattern is a regular expression values of the argument must match.
+	Pattern string `yaml:"pattern"`
 }
 
 func (a ShuttleScriptArgs) String() string {
 	var s strings.Builder
 	s.WriteString(a.Name)
+	var details []string
 	if a.Required {
-		s.WriteString(" (required)")
+		details = append(details, "required")
+	}
+	if constraints := a.Constraints(); constraints != "" {
+		details = append(details, constraints)
+	}
+	if len(details) != 0 {
+		fmt.Fprintf(&s, " (%s)", strings.Join(details, ", "))
 	}
 	if len(a.Description) != 0 {
 		fmt.Fprintf(&s, "  %s", a.Description)
@@ -46,6 +120,239 @@ type ShuttleAction struct {
 	Shell      string `yaml:"shell"`
 	Dockerfile string `yaml:"dockerfile"`
 	Task       string `yaml:"task"`
+	// PowerShell is a script run with PowerShell instead of a POSIX shell. It
+	// supports the options of shell actions except sudo and background. It can
+	// be set with pwsh as well.
+	PowerShell string `yaml:"powershell"`
+	// Docker runs the action in a container instead of on the host.
+	Docker *ShuttleDocker `yaml:"docker"`
+	// Wasm is the path of a WebAssembly module compiled for WASI run instead
+	// of a script. It only has access to the project and temp directories.
+	Wasm string `yaml:"wasm"`
+	// Interpreter is the POSIX compatible shell running the shell action, eg.
+	// bash. Defaults to sh.
+	Interpreter string `yaml:"interpreter"`
+	// Encoding is the character encoding of the output produced by the action.
+	// Defaults to UTF-8. Use "raw" to pass output through untouched.
+	Encoding string `yaml:"encoding"`
+	// Output is either "streaming", the default, or "buffered" in which case
+	// output is printed once the action completes.
+	Output string `yaml:"output"`
+	// MergeStderr redirects stderr to stdout such that output is forwarded as
+	// one ordered stream, like 2>&1 in a shell.
+	MergeStderr bool `yaml:"mergeStderr"`
+	// Timeout bounds the duration of the action including its preflight
+	// checks, eg. 30s.
+	Timeout string `yaml:"timeout"`
+	// StopGracePeriod is how long the action may clean up after it is
+	// signalled to stop before it is killed, eg. 10s.
+	StopGracePeriod string `yaml:"stopGracePeriod"`
+	// Cleanup is a shell script run after the action however it completes,
+	// eg. to remove containers it started.
+	Cleanup string `yaml:"cleanup"`
+	// CleanupTimeout bounds the duration of the cleanup, eg. 10s. Defaults to
+	// 30s.
+	CleanupTimeout string `yaml:"cleanupTimeout"`
+	// Heartbeat is how long the action may be silent before a line is printed
+	// telling it is still running, eg. 1m. Disabled by default.
+	Heartbeat string `yaml:"heartbeat"`
+	// CaptureOutput names a variable the trimmed stdout of the action is
+	// stored in when it succeeds. Later actions of the script get it as an
+	// environment variable.
+	CaptureOutput string `yaml:"captureOutput"`
+	// Preflight checks must all pass before the action is run.
+	Preflight []ShuttlePreflightCheck `yaml:"preflight"`
+	// KeepTmp exempts the temporary directory of the action from cleaning.
+	KeepTmp bool `yaml:"keepTmp"`
+	// Background starts the action without waiting for it to complete. Its
+	// output is available through shuttle logs.
+	Background bool `yaml:"background"`
+	// Always runs the action even if an earlier action failed or the run was
+	// cancelled.
+	Always bool `yaml:"always"`
+	// Parallel runs the action concurrently with the adjacent actions also
+	// marked parallel. The script continues once all of them have completed.
+	Parallel bool `yaml:"parallel"`
+	// Interactive runs the shell action in a pseudo terminal connected to the
+	// terminal of shuttle, eg. for commands prompting for input. Its output is
+	// not masked, prefixed, captured or logged.
+	Interactive bool `yaml:"interactive"`
+	// Label replaces the script name and action index in the prefix of output
+	// lines when output is prefixed.
+	Label string `yaml:"label"`
+	// When is a condition on the variables of the script, eg. branch == "main".
+	// The action is skipped if it is false.
+	When string `yaml:"when"`
+	// Idempotent marks the action as safe to re-run without side effects.
+	Idempotent bool `yaml:"idempotent"`
+	// Sudo runs the shell action with elevated privileges.
+	Sudo bool `yaml:"sudo"`
+	// Upload lists artifacts uploaded once the action succeeds.
+	Upload []ShuttleUpload `yaml:"upload"`
+	// Artifacts are project relative files, or glob patterns, collected into
+	// .shuttle/artifacts/<run-id> once the action completes, eg. coverage
+	// reports or binaries.
+	Artifacts []string `yaml:"artifacts"`
+	// RepeatUntilSuccess runs the action until it succeeds, eg. to poll for a
+	// service to become ready.
+	RepeatUntilSuccess *ShuttleRepeat `yaml:"repeatUntilSuccess"`
+	// Retries is the number of times a shell action exiting with a non-zero
+	// code is run again before it fails, eg. for flaky network steps.
+	Retries int `yaml:"retries"`
+	// RetryDelay is the time to wait before a retry, eg. 2s. Defaults to 1s.
+	RetryDelay string `yaml:"retryDelay"`
+	// RetryBackoff is either "constant", the default, or "exponential" in
+	// which case the delay is doubled after every retry.
+	RetryBackoff string `yaml:"retryBackoff"`
+	// RetryOn limits retries to the listed exit codes. Any non-zero exit code
+	// is retried when empty.
+	RetryOn []int `yaml:"retryOn"`
+	// Tools lists versions of tools required by the action. They are checked
+	// before the action is run.
+	Tools []ShuttleToolRequirement `yaml:"tools"`
+	// EnvFile is a project relative dotenv file with environment variables of
+	// the action.
+	EnvFile string `yaml:"envFile"`
+	// Path lists project relative directories prepended to PATH of the
+	// action, eg. with vendored tools.
+	Path []string `yaml:"path"`
+	// WorkDir is the project relative directory the action is run from.
+	// Defaults to the project directory.
+	WorkDir string `yaml:"workDir"`
+	// Cwd is either "project", the default, running the action from the
+	// project directory or "invocation" running it from the directory
+	// shuttle is invoked from.
+	Cwd string `yaml:"cwd"`
+	// Stdin is either "inherit", the default, connecting the stdin of
+	// shuttle to the action or "none" running it without stdin.
+	Stdin string `yaml:"stdin"`
+	// LogFile is a project relative file the output of the action is written
+	// to besides the terminal. It overrides the --log-file flag.
+	LogFile string `yaml:"logFile"`
+	// Env limits the environment of shuttle inherited by the action. It takes
+	// precedence over the env of shuttle.yaml and plan.yaml.
+	Env ShuttleEnv `yaml:"env"`
+}
+
+// Modes of inheriting the environment of shuttle
+const (
+	EnvInheritAll       = "all"
+	EnvInheritNone      = "none"
+	EnvInheritAllowlist = "allowlist"
+)
+
+// ShuttleEnv describes which variables of the environment shuttle is run in
+// are inherited by actions. Variables set by shuttle, eg. arguments, and
+// those prefixed SHUTTLE_ are always set.
+type ShuttleEnv struct {
+	// Inherit is either "all", the default, "none" or "allowlist" inheriting
+	// only the variables of Allow.
+	Inherit string `yaml:"inherit"`
+	// Allow lists the names of variables inherited with allowlist. A * matches
+	// any characters, eg. CI_*.
+	Allow []string `yaml:"allow"`
+}
+
+// ShuttleToolRequirement describes a tool that must be available in a version
+// satisfying a constraint.
+type ShuttleToolRequirement struct {
+	// Name of the tool executable, eg. kubectl.
+	Name string `yaml:"name"`
+	// Version is a semver constraint, eg. ">= 1.28".
+	Version string `yaml:"version"`
+	// Command prints the version of the tool. Defaults to a built-in command
+	// for known tools and "<name> --version" otherwise.
+	Command string `yaml:"command"`
+	// Pattern is a regular expression extracting the version from the output
+	// of Command. The first capture group is used if any.
+	Pattern string `yaml:"pattern"`
+}
+
+// ShuttleRepeat describes how an action is repeated until it succeeds.
+type ShuttleRepeat struct {
+	// MaxAttempts is the number of times the action is run before it fails.
+	MaxAttempts int `yaml:"maxAttempts"`
+	// Interval is the time to wait between attempts, eg. 2s. Defaults to 1s.
+	Interval string `yaml:"interval"`
+}
+
+// ShuttleScriptInputs describes what the result of a script depends on
+// besides its definition and arguments.
+type ShuttleScriptInputs struct {
+	// Files are project relative files, or glob patterns where ** matches any
+	// number of directories, eg. **/*.go.
+	Files []string `yaml:"files"`
+	// Env are names of environment variables.
+	Env []string `yaml:"env"`
+}
+
+// ShuttleDocker describes the container an action is run in.
+type ShuttleDocker struct {
+	// Image is the image the container is created from, eg. golang:1.21.
+	Image string `yaml:"image"`
+	// Entrypoint replaces the entrypoint of the image for actions without a
+	// shell script.
+	Entrypoint string `yaml:"entrypoint"`
+	// Mounts are additional bind mounts on the form source:target[:options].
+	// Sources are relative to the project directory.
+	Mounts []string `yaml:"mounts"`
+}
+
+// ShuttleUpload describes an artifact uploaded with an HTTP PUT request.
+type ShuttleUpload struct {
+	// Path is the artifact file relative to the project directory.
+	Path string `yaml:"path"`
+	// URL is the destination of the artifact, eg. a presigned S3 URL.
+	URL         string `yaml:"url"`
+	ContentType string `yaml:"contentType"`
+	// TokenEnv is the name of a variable in the environment of the action
+	// holding a bearer token used to authenticate the upload.
+	TokenEnv string `yaml:"tokenEnv"`
+}
+
+// UnmarshalYAML decodes the action accepting pwsh as a short name of
+// powershell.
+func (a *ShuttleAction) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain ShuttleAction
+	var action struct {
+		plain `yaml:",inline"`
+		Pwsh  string `yaml:"pwsh"`
+	}
+	if err := unmarshal(&action); err != nil {
+		return err
+	}
+	if action.Pwsh != "" {
+		if action.PowerShell != "" {
+			return fmt.Errorf("action cannot have both pwsh and powershell")
+		}
+		action.PowerShell = action.Pwsh
+	}
+	*a = ShuttleAction(action.plain)
+	return nil
+}
+
+// Idempotent returns true if all actions of the script are idempotent.
+func (s ShuttlePlanScript) Idempotent() bool {
+	for _, action := range s.Actions {
+		if !action.Idempotent {
+			return false
+		}
+	}
+	return true
+}
+
+// ShuttlePreflightCheck describes a precondition that must be met before an
+// action is run
+type ShuttlePreflightCheck struct {
+	Name  string `yaml:"name"`
+	Shell string `yaml:"shell"`
+}
+
+func (c ShuttlePreflightCheck) String() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Shell
 }
 
 // ShuttlePlanConfiguration is a ShuttlePlan sub-element
@@ -53,6 +360,107 @@ type ShuttlePlanConfiguration struct {
 	Vars          map[string]interface{}       `yaml:"vars"`
 	Documentation string                       `yaml:"documentation"`
 	Scripts       map[string]ShuttlePlanScript `yaml:"scripts"`
+	Policy        ShuttlePlanPolicy            `yaml:"policy"`
+	// Include lists plan relative files, or glob patterns, with additional
+	// scripts.
+	Include []string `yaml:"include"`
+	// Templates are parameterized scripts instantiated by Instances.
+	Templates map[string]ShuttleScriptTemplate `yaml:"templates"`
+	// Instances instantiate templates on the form template(param=value).
+	Instances []string `yaml:"instances"`
+	// GolangActions is the plan relative directory of golang actions.
+	// Defaults to actions.
+	GolangActions string `yaml:"golangActions"`
+	// Guards must all pass once before any script of the plan is run.
+	Guards []ShuttlePreflightCheck `yaml:"guards"`
+	// Hooks are actions run around every script of the plan.
+	Hooks ShuttleHooks `yaml:"hooks"`
+	// EnvFile is a project relative dotenv file with environment variables of
+	// all shell actions of the plan.
+	EnvFile string `yaml:"envFile"`
+	// Path lists plan relative directories prepended to PATH of all actions
+	// of the plan, eg. with tools shipped by the plan.
+	Path []string `yaml:"path"`
+	// Secrets are environment variables of all actions read from secret
+	// providers.
+	Secrets map[string]ShuttleSecret `yaml:"secrets"`
+	// Init describes the variables and files of projects initialized with
+	// the plan by shuttle init.
+	Init ShuttlePlanInit `yaml:"init"`
+	// ShuttleVersion is a semver constraint on the versions of shuttle the
+	// plan can be used with, eg. ">= 0.25". It is checked together with
+	// Requires.Shuttle, see Requirements.
+	//
+	// Deprecated: use Requires.Shuttle.
+	ShuttleVersion string `yaml:"shuttleVersion"`
+	// Requires are the versions of shuttle, executors and tools the scripts
+	// of the plan require. They are checked before any script is run.
+	Requires ShuttlePlanRequirements `yaml:"requires"`
+	// Env limits the environment of shuttle inherited by all actions of the
+	// plan.
+	Env ShuttleEnv `yaml:"env"`
+}
+
+// Executors plans can require
+const (
+	ExecutorGolang = "golang"
+	ExecutorDocker = "docker"
+)
+
+// ShuttlePlanRequirements describes what must be available to run the scripts
+// of a plan.
+type ShuttlePlanRequirements struct {
+	// Shuttle is a semver constraint on the version of shuttle, eg. ">= 0.25".
+	Shuttle string `yaml:"shuttle"`
+	// Executors lists the executors of actions the plan uses, golang or
+	// docker.
+	Executors []string `yaml:"executors"`
+	// Tools lists versions of tools required on PATH.
+	Tools []ShuttleToolRequirement `yaml:"tools"`
+}
+
+// append returns the requirements of r and o. Both shuttle constraints must
+// be satisfied.
+func (r ShuttlePlanRequirements) append(o ShuttlePlanRequirements) ShuttlePlanRequirements {
+	// slices are capped such that appending never writes to those of r
+	return ShuttlePlanRequirements{
+		Shuttle:   joinConstraints(r.Shuttle, o.Shuttle),
+		Executors: append(r.Executors[:len(r.Executors):len(r.Executors)], o.Executors...),
+		Tools:     append(r.Tools[:len(r.Tools):len(r.Tools)], o.Tools...),
+	}
+}
+
+// Requirements returns the requirements of the plan with the shuttleVersion
+// constraint added to that of Requires.Shuttle.
+func (p ShuttlePlanConfiguration) Requirements() ShuttlePlanRequirements {
+	requires := p.Requires
+	requires.Shuttle = joinConstraints(p.ShuttleVersion, requires.Shuttle)
+	return requires
+}
+
+// joinConstraints returns a version constraint satisfied by the versions
+// satisfying both a and b. Empty constraints are satisfied by any version.
+// Alternatives separated by || bind looser than the conjunction so every
+// alternative of a is combined with every alternative of b.
+func joinConstraints(a, b string) string {
+	switch {
+	case strings.TrimSpace(a) == "":
+		return b
+	case strings.TrimSpace(b) == "":
+		return a
+	}
+	var joined []string
+	for _, left := range strings.Split(a, "||") {
+		for _, right := range strings.Split(b, "||") {
+			joined = append(joined, strings.TrimSpace(left)+", "+strings.TrimSpace(right))
+		}
+	}
+	return strings.Join(joined, " || ")
+}
+
+// shuttlePlanInclude is the content of a file included by a plan
+type shuttlePlanInclude struct {
+	Scripts map[string]ShuttlePlanScript `yaml:"scripts"`
 }
 
 // ShuttlePlan struct describes a plan
@@ -84,6 +492,9 @@ func (p *ShuttlePlanConfiguration) Load(planPath string) (*ShuttlePlanConfigurat
 	decoder.SetStrict(true)
 	err = decoder.Decode(p)
 	if err != nil {
+		if problems, _ := ValidateSchemaFile(PlanSchema(), configPath, configPath); len(problems) > 0 {
+			err = schemaError(problems)
+		}
 		return p, errors.NewExitCode(
 			1,
 			"Failed to load plan configuration from '%s': %s\n\nThis is likely an issue with the referenced plan. Please, contact the plan maintainers.",
@@ -92,10 +503,97 @@ func (p *ShuttlePlanConfiguration) Load(planPath string) (*ShuttlePlanConfigurat
 		)
 	}
 
+	err = p.loadIncludes(planPath)
+	if err != nil {
+		return p, err
+	}
+
+	err = p.expandTemplates()
+	if err != nil {
+		return p, err
+	}
+
 	return p, nil
 }
 
-// FetchPlan so it exists locally and return path to that plan
+// loadIncludes merges the scripts of included files into the plan. Scripts
+// must be uniquely named across the plan and all included files.
+func (p *ShuttlePlanConfiguration) loadIncludes(planPath string) error {
+	for _, include := range p.Include {
+		if filepath.IsAbs(include) || strings.HasPrefix(path.Clean(include), "..") {
+			return errors.NewExitCode(
+				1,
+				"Plan include '%s' must be relative to and within the plan directory",
+				include,
+			)
+		}
+		matches, err := filepath.Glob(filepath.Join(planPath, include))
+		if err != nil {
+			return errors.NewExitCode(1, "Plan include '%s' is not a valid pattern: %v", include, err)
+		}
+		if len(matches) == 0 {
+			return errors.NewExitCode(1, "Plan include '%s' does not match any files", include)
+		}
+
+		for _, match := range matches {
+			source, err := filepath.Rel(planPath, match)
+			if err != nil {
+				return err
+			}
+			err = p.loadInclude(match, filepath.ToSlash(source))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *ShuttlePlanConfiguration) loadInclude(includePath, source string) error {
+	file, err := os.Open(includePath)
+	if err != nil {
+		return errors.NewExitCode(1, "Failed to open plan include '%s': %v", source, err)
+	}
+	defer file.Close()
+
+	var include shuttlePlanInclude
+	decoder := yaml.NewDecoder(file)
+	decoder.SetStrict(true)
+	err = decoder.Decode(&include)
+	if err != nil {
+		return errors.NewExitCode(
+			1,
+			"Failed to load plan include '%s': %s\n\nThis is likely an issue with the referenced plan. Please, contact the plan maintainers.",
+			source,
+			err,
+		)
+	}
+
+	if p.Scripts == nil {
+		p.Scripts = make(map[string]ShuttlePlanScript, len(include.Scripts))
+	}
+	for name, script := range include.Scripts {
+		if existing, ok := p.Scripts[name]; ok {
+			existingSource := existing.Source
+			if existingSource == "" {
+				existingSource = "plan.yaml"
+			}
+			return errors.NewExitCode(
+				1,
+				"Script '%s' in plan include '%s' is already defined in '%s'",
+				name,
+				source,
+				existingSource,
+			)
+		}
+		script.Source = source
+		p.Scripts[name] = script
+	}
+	return nil
+}
+
+// FetchPlan so it exists locally and return path to that plan. If lock is set
+// git and OCI plans are fetched at the locked revision.
 func FetchPlan(
 	plan string,
 	projectPath string,
@@ -103,6 +601,8 @@ func FetchPlan(
 	uii *ui.UI,
 	skipGitPlanPulling bool,
 	planArgument string,
+	cache git.PlanCache,
+	lock *PlanLock,
 ) (string, error) {
 	if isPlanArgumentAPlan(planArgument) {
 		uii.Infoln("Using overloaded plan %v", planArgument)
@@ -113,8 +613,20 @@ func FetchPlan(
 			uii,
 			skipGitPlanPulling,
 			"",
+			cache,
+			nil,
 		)
 	}
+	var lockedCommit string
+	if lock != nil {
+		lockedCommit = lock.Commit
+		if lock.Digest != "" && oci.IsPlan(plan) {
+			if ref, err := oci.ParseReference(plan); err == nil {
+				ref.Digest = lock.Digest
+				plan = ref.String()
+			}
+		}
+	}
 
 	switch {
 	case plan == "":
@@ -128,12 +640,24 @@ func FetchPlan(
 			uii,
 			skipGitPlanPulling,
 			planArgument,
+			cache,
+			lockedCommit,
+		)
+	case oci.IsPlan(plan):
+		uii.Verboseln("Using OCI plan at '%s'", plan)
+		return oci.GetPlan(
+			context.Background(),
+			plan,
+			localShuttleDirectoryPath,
+			uii,
+			skipGitPlanPulling,
+			planArgument,
 		)
 	case isHTTPSPlan(plan):
 		panic(fmt.Sprintf("Plan '%v' is not valid: non-git http/https is not supported yet", plan))
 	case isFilePath(plan, true):
 		uii.Verboseln("Using local plan at '%s'", plan)
-		plan, err := handleFilePath(plan, projectPath)
+		plan, err := handleFilePath(plan, localShuttleDirectoryPath)
 		if err != nil {
 			return "", err
 		}
@@ -141,7 +665,7 @@ func FetchPlan(
 	case isFilePath(plan, false):
 		uii.Verboseln("Using local plan at '%s'", plan)
 		plan := path.Join(projectPath, plan)
-		plan, err := handleFilePath(plan, projectPath)
+		plan, err := handleFilePath(plan, localShuttleDirectoryPath)
 		if err != nil {
 			return "", err
 		}
@@ -151,8 +675,25 @@ func FetchPlan(
 	}
 }
 
-func handleFilePath(plan string, projectPath string) (string, error) {
-	toPath := path.Join(projectPath, "/.shuttle/plan")
+// localPlanSource returns the directory of a local plan resolved the same way
+// as FetchPlan does. An empty string is returned for git and OCI plans and projects
+// without a plan.
+func localPlanSource(plan, projectPath, planArgument string) string {
+	if isPlanArgumentAPlan(planArgument) {
+		return localPlanSource(getPlanFromPlanArgument(planArgument), projectPath, "")
+	}
+	switch {
+	case plan == "", git.IsPlan(plan), oci.IsPlan(plan), isHTTPSPlan(plan):
+		return ""
+	case isFilePath(plan, true):
+		return path.Clean(plan)
+	default:
+		return path.Join(projectPath, plan)
+	}
+}
+
+func handleFilePath(plan string, localShuttleDirectoryPath string) (string, error) {
+	toPath := path.Join(localShuttleDirectoryPath, "plan")
 	ignorelist := []string{".git", ".shuttle"}
 	err := copy.Dir(plan, toPath, ignorelist)
 	if err != nil {
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestShuttlePlanConfiguration_Load(t *testing.T) {
@@ -22,7 +24,7 @@ func TestShuttlePlanConfiguration_Load(t *testing.T) {
 			name:  "unknown field",
 			input: "testdata/unknown_field",
 			err: errors.New(
-				"exit code 1 - Failed to load plan configuration from 'testdata/unknown_field/plan.yaml': yaml: unmarshal errors:\n  line 1: field unknown not found in type config.ShuttlePlanConfiguration\n\nThis is likely an issue with the referenced plan. Please, contact the plan maintainers.",
+				"exit code 1 - Failed to load plan configuration from 'testdata/unknown_field/plan.yaml': schema errors:\n  testdata/unknown_field/plan.yaml:1:1: 'unknown' is not allowed\n\nThis is likely an issue with the referenced plan. Please, contact the plan maintainers.",
 			),
 		},
 		{
@@ -52,6 +54,84 @@ func TestShuttlePlanConfiguration_Load(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "pwsh",
+			input: "testdata/pwsh",
+			config: ShuttlePlanConfiguration{
+				Scripts: map[string]ShuttlePlanScript{
+					"hello": {
+						Actions: []ShuttleAction{
+							{
+								PowerShell: `Write-Output "Hello world"`,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "pwsh and powershell",
+			input: "testdata/pwsh_and_powershell",
+			err: errors.New(
+				"exit code 1 - Failed to load plan configuration from 'testdata/pwsh_and_powershell/plan.yaml': action cannot have both pwsh and powershell\n\nThis is likely an issue with the referenced plan. Please, contact the plan maintainers.",
+			),
+			config: ShuttlePlanConfiguration{
+				Scripts: map[string]ShuttlePlanScript{},
+			},
+		},
+		{
+			name:  "includes",
+			input: "testdata/includes",
+			err:   nil,
+			config: ShuttlePlanConfiguration{
+				Include: []string{"scripts/*.yaml"},
+				Scripts: map[string]ShuttlePlanScript{
+					"hello": {
+						Actions: []ShuttleAction{
+							{
+								Shell: `echo "Hello world"`,
+							},
+						},
+					},
+					"build": {
+						Description: "Build the project",
+						Actions: []ShuttleAction{
+							{
+								Shell: "go build ./...",
+							},
+						},
+						Source: "scripts/build.yaml",
+					},
+					"deploy": {
+						Actions: []ShuttleAction{
+							{
+								Shell: "./deploy.sh",
+							},
+						},
+						Source: "scripts/deploy.yaml",
+					},
+				},
+			},
+		},
+		{
+			name:  "duplicate script in include",
+			input: "testdata/include_duplicate",
+			err: errors.New(
+				"exit code 1 - Script 'hello' in plan include 'other.yaml' is already defined in 'plan.yaml'",
+			),
+			config: ShuttlePlanConfiguration{
+				Include: []string{"other.yaml"},
+				Scripts: map[string]ShuttlePlanScript{
+					"hello": {
+						Actions: []ShuttleAction{
+							{
+								Shell: `echo "Hello world"`,
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
@@ -68,3 +148,42 @@ func TestShuttlePlanConfiguration_Load(t *testing.T) {
 		})
 	}
 }
+
+func TestJoinConstraints(t *testing.T) {
+	tt := []struct {
+		name   string
+		a      string
+		b      string
+		joined string
+	}{
+		{name: "both empty", joined: ""},
+		{name: "empty a", b: "< 2", joined: "< 2"},
+		{name: "empty b", a: ">= 0.24", joined: ">= 0.24"},
+		{name: "conjunctions", a: ">= 0.24", b: "< 2", joined: ">= 0.24, < 2"},
+		{name: "alternatives", a: "^0.24 || ^1.2", b: "!= 1.2.3", joined: "^0.24, != 1.2.3 || ^1.2, != 1.2.3"},
+		{name: "alternatives of both", a: "1.x || 3.x", b: "< 1.5 || > 3.2", joined: "1.x, < 1.5 || 1.x, > 3.2 || 3.x, < 1.5 || 3.x, > 3.2"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.joined, joinConstraints(tc.a, tc.b))
+		})
+	}
+}
+
+// TestJoinConstraints_semantics tests that joined constraints are satisfied by
+// exactly the versions satisfying both constraints.
+func TestJoinConstraints_semantics(t *testing.T) {
+	a := "< 0.20 || >= 1.0"
+	b := ">= 0.10, < 1.5"
+	joined, err := semver.NewConstraint(joinConstraints(a, b))
+	require.NoError(t, err)
+	constraintA, err := semver.NewConstraint(a)
+	require.NoError(t, err)
+	constraintB, err := semver.NewConstraint(b)
+	require.NoError(t, err)
+
+	for _, raw := range []string{"0.5.0", "0.15.0", "0.25.0", "1.2.0", "1.6.0"} {
+		version := semver.MustParse(raw)
+		assert.Equal(t, constraintA.Check(version) && constraintB.Check(version), joined.Check(version), raw)
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/signature"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// planPublicKeyFileKey is the environment variable with the path of a public
+// key trusted for plans of projects which do not configure one themselves,
+// eg. the key of an organization.
+const planPublicKeyFileKey = "SHUTTLE_PLAN_PUBLIC_KEY_FILE"
+
+// verifyPlan returns an error unless the fetched plan and its overlays are
+// signed by the trusted public key of the project. Plans are not verified
+// without a trusted key or if skipVerify is set.
+func (c *ShuttleProjectContext) verifyPlan(uii *ui.UI, skipVerify bool) error {
+	if c.LocalPlanPath == "" {
+		return nil
+	}
+	raw, source, err := c.planPublicKey()
+	if err != nil || raw == "" {
+		return err
+	}
+	if skipVerify {
+		uii.EmphasizeInfoln("Skipping verification of the plan signature as --insecure-skip-verify is set")
+		return nil
+	}
+	key, err := signature.ParsePublicKey(raw)
+	if err != nil {
+		return errors.NewExitCode(2, "Failed to load the plan public key of %s: %v", source, err)
+	}
+	plans := append([]ShuttlePlanOverlay{{Plan: c.Config.Plan, LocalPlanPath: c.LocalPlanPath}}, c.Overlays...)
+	for _, plan := range plans {
+		err = signature.VerifyPlan(plan.LocalPlanPath, key)
+		if err != nil {
+			return errors.NewExitCode(
+				1,
+				"Failed to verify plan '%s': %v\n\nThe plan is not signed by the trusted public key of %s. Use --insecure-skip-verify to use it anyway.",
+				plan.Plan,
+				err,
+				source,
+			)
+		}
+	}
+	uii.Verboseln("Verified the plan signature with the public key of %s", source)
+	return nil
+}
+
+// planPublicKey returns the public key trusted for the plan and where it is
+// configured. The key of shuttle.yaml takes precedence over the one of
+// SHUTTLE_PLAN_PUBLIC_KEY_FILE.
+func (c *ShuttleProjectContext) planPublicKey() (string, string, error) {
+	if c.Config.PlanPublicKey != "" {
+		return c.Config.PlanPublicKey, "shuttle.yaml", nil
+	}
+	file := os.Getenv(planPublicKeyFileKey)
+	if file == "" {
+		return "", "", nil
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", "", errors.NewExitCode(2, "Failed to read %s: %v", planPublicKeyFileKey, err)
+	}
+	return string(content), planPublicKeyFileKey, nil
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/signature"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestShuttleProjectContext_verifyPlan(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+	trustedKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	signedPlan := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(signedPlan, "plan.yaml"), []byte("scripts: {}\n"), 0o644))
+	digest, err := signature.Digest(signedPlan)
+	require.NoError(t, err)
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, signature.Message(digest)))
+	require.NoError(t, os.WriteFile(path.Join(signedPlan, signature.CosignFile), []byte(sig), 0o644))
+
+	unsignedPlan := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(unsignedPlan, "plan.yaml"), []byte("scripts: {}\n"), 0o644))
+
+	keyFile := path.Join(t.TempDir(), "plan.pub")
+	require.NoError(t, os.WriteFile(keyFile, []byte(trustedKey), 0o644))
+
+	tt := []struct {
+		name       string
+		planPath   string
+		publicKey  string
+		keyFile    string
+		skipVerify bool
+		err        string
+	}{
+		{name: "no public key", planPath: unsignedPlan},
+		{name: "no plan", publicKey: trustedKey},
+		{name: "signed plan", planPath: signedPlan, publicKey: trustedKey},
+		{name: "signed plan with key file", planPath: signedPlan, keyFile: keyFile},
+		{
+			name:      "unsigned plan",
+			planPath:  unsignedPlan,
+			publicKey: trustedKey,
+			err:       "exit code 1 - Failed to verify plan 'plan': the plan has no signature in plan.digest.sig\n\nThe plan is not signed by the trusted public key of shuttle.yaml. Use --insecure-skip-verify to use it anyway.",
+		},
+		{name: "unsigned plan skipping verification", planPath: unsignedPlan, publicKey: trustedKey, skipVerify: true},
+		{
+			name:      "invalid public key",
+			planPath:  signedPlan,
+			publicKey: "not a key",
+			err:       "exit code 2 - Failed to load the plan public key of shuttle.yaml: public key must be a minisign or PEM encoded cosign public key",
+		},
+		{
+			name:     "missing key file",
+			planPath: signedPlan,
+			keyFile:  path.Join(t.TempDir(), "missing.pub"),
+			err:      "exit code 2 - Failed to read SHUTTLE_PLAN_PUBLIC_KEY_FILE",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(planPublicKeyFileKey, tc.keyFile)
+			c := ShuttleProjectContext{
+				Config:        ShuttleConfig{Plan: "plan", PlanPublicKey: tc.publicKey},
+				LocalPlanPath: tc.planPath,
+			}
+
+			err := c.verifyPlan(ui.Create(&bytes.Buffer{}, &bytes.Buffer{}), tc.skipVerify)
+
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
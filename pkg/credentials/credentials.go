@@ -0,0 +1,76 @@
+// Package credentials finds the credentials plans are fetched with from git
+// servers and OCI registries. Credentials are looked up by host in the OS
+// keychain, a GitHub App, netrc, the docker configuration and GitHub token
+// environment variables such that private plans work without setting up git
+// credential helpers.
+package credentials
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+)
+
+// Credential authenticates to a host with basic authentication. Tokens are
+// used as passwords.
+type Credential struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Source describes where the credential was found, eg. netrc
+	Source string `json:"-"`
+}
+
+// BasicAuth returns the value of an Authorization header authenticating with
+// the credential.
+func (c Credential) BasicAuth() string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Password))
+}
+
+// tokenUsername is the username tokens are used with. GitHub, GitLab and most
+// other git servers ignore the username of token authentication.
+const tokenUsername = "x-access-token"
+
+// source looks up the credential of a host.
+type source func(host string) (Credential, bool, error)
+
+// sources are the sources credentials are looked up in by priority.
+var sources = []source{
+	keychainCredential,
+	githubAppCredential,
+	netrcCredential,
+	dockerCredential,
+	githubTokenCredential,
+}
+
+// Lookup returns the credential of host, eg. github.com or ghcr.io, from the
+// first source with one. false is returned if no source has a credential for
+// the host.
+func Lookup(host string) (Credential, bool, error) {
+	host = Host(host)
+	if host == "" {
+		return Credential{}, false, nil
+	}
+	for _, lookup := range sources {
+		credential, ok, err := lookup(host)
+		if err != nil {
+			return Credential{}, false, err
+		}
+		if ok {
+			return credential, true, nil
+		}
+	}
+	return Credential{}, false, nil
+}
+
+// Host returns the lower cased host of an address which may be a URL or a
+// host followed by a path, eg. github.com of https://github.com/lunarway or
+// github.com/lunarway/plan.git. Ports are kept.
+func Host(address string) string {
+	if strings.Contains(address, "://") {
+		if u, err := url.Parse(address); err == nil {
+			return strings.ToLower(u.Host)
+		}
+	}
+	host, _, _ := strings.Cut(address, "/")
+	return strings.ToLower(host)
+}
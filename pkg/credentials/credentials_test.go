@@ -0,0 +1,129 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+)
+
+// isolate makes lookups of the test independent of the credentials of the
+// machine running it.
+func isolate(t *testing.T) string {
+	t.Helper()
+	keyring.MockInit()
+	dir := t.TempDir()
+	t.Setenv("NETRC", filepath.Join(dir, "netrc"))
+	t.Setenv("DOCKER_CONFIG", dir)
+	for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN", githubAppIDEnv, githubHostEnv, githubAPIURLEnv} {
+		t.Setenv(name, "")
+	}
+	return dir
+}
+
+func TestHost(t *testing.T) {
+	tt := []struct {
+		address string
+		host    string
+	}{
+		{address: "github.com", host: "github.com"},
+		{address: "GitHub.com/lunarway/plan.git", host: "github.com"},
+		{address: "https://github.com/lunarway", host: "github.com"},
+		{address: "localhost:5000", host: "localhost:5000"},
+		{address: "https://index.docker.io/v1/", host: "index.docker.io"},
+		{address: "", host: ""},
+	}
+	for _, tc := range tt {
+		t.Run(tc.address, func(t *testing.T) {
+			assert.Equal(t, tc.host, Host(tc.address))
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	t.Run("no credentials", func(t *testing.T) {
+		isolate(t)
+
+		_, ok, err := Lookup("github.com")
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("sources by priority", func(t *testing.T) {
+		dir := isolate(t)
+		t.Setenv("GH_TOKEN", "env-token")
+		lookup := func() Credential {
+			credential, ok, err := Lookup("https://github.com/lunarway/plan.git")
+			require.NoError(t, err)
+			require.True(t, ok)
+			return credential
+		}
+
+		assert.Equal(t, Credential{Username: tokenUsername, Password: "env-token", Source: "GH_TOKEN"}, lookup())
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"auths":{"github.com":{"auth":"ZG9ja2VyOnNlY3JldA=="}}}`), 0o600))
+		assert.Equal(t, Credential{Username: "docker", Password: "secret", Source: "docker config"}, lookup())
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "netrc"), []byte("machine github.com login netrc password netrc-token\n"), 0o600))
+		assert.Equal(t, Credential{Username: "netrc", Password: "netrc-token", Source: "netrc"}, lookup())
+
+		require.NoError(t, Store("github.com", Credential{Password: "stored-token"}))
+		assert.Equal(t, Credential{Username: tokenUsername, Password: "stored-token", Source: "keychain"}, lookup())
+
+		require.NoError(t, Remove("github.com"))
+		assert.ErrorIs(t, Remove("github.com"), ErrNotStored)
+		assert.Equal(t, "netrc", lookup().Source)
+	})
+
+	t.Run("GitHub tokens only for GitHub", func(t *testing.T) {
+		isolate(t)
+		t.Setenv("GITHUB_TOKEN", "token")
+
+		_, ok, err := Lookup("gitlab.com")
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestParseNetrc(t *testing.T) {
+	const content = `machine gitlab.com login first password one
+default login fallback password any
+
+machine github.com
+  login octocat
+  password two
+macdef init
+  machine github.com login ignored password ignored
+
+machine example.com login third account x password three
+`
+	tt := []struct {
+		host       string
+		credential Credential
+		ok         bool
+	}{
+		{host: "gitlab.com", credential: Credential{Username: "first", Password: "one"}, ok: true},
+		{host: "github.com", credential: Credential{Username: "octocat", Password: "two"}, ok: true},
+		{host: "example.com", credential: Credential{Username: "third", Password: "three"}, ok: true},
+		{host: "unknown.com", credential: Credential{Username: "fallback", Password: "any"}, ok: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.host, func(t *testing.T) {
+			credential, ok := parseNetrc(content, tc.host)
+
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.credential, credential)
+		})
+	}
+
+	t.Run("no default", func(t *testing.T) {
+		_, ok := parseNetrc("machine github.com login a password b", "gitlab.com")
+
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,119 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// dockerConfig is the part of the docker configuration file holding registry
+// credentials.
+type dockerConfig struct {
+	Auths map[string]struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"auths"`
+	CredHelpers map[string]string `json:"credHelpers"`
+	CredsStore  string            `json:"credsStore"`
+}
+
+// dockerConfigPath returns the path of the docker configuration file in
+// DOCKER_CONFIG or the .docker directory in the home directory.
+func dockerConfigPath() string {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	return filepath.Join(dir, "config.json")
+}
+
+// dockerCredential returns the credential of the registry host like docker
+// login stores it. Credential helpers of the host take precedence over auths
+// which take precedence over the credential store.
+func dockerCredential(host string) (Credential, bool, error) {
+	path := dockerConfigPath()
+	if path == "" {
+		return Credential{}, false, nil
+	}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Credential{}, false, nil
+	}
+	if err != nil {
+		return Credential{}, false, fmt.Errorf("read docker config: %w", err)
+	}
+	var config dockerConfig
+	if err := json.Unmarshal(content, &config); err != nil {
+		return Credential{}, false, fmt.Errorf("docker config '%s' is invalid: %w", path, err)
+	}
+
+	for key, helper := range config.CredHelpers {
+		if dockerHost(key) == host {
+			return dockerHelperCredential(helper, key)
+		}
+	}
+	for key, auth := range config.Auths {
+		if dockerHost(key) != host {
+			continue
+		}
+		credential := Credential{Username: auth.Username, Password: auth.Password, Source: "docker config"}
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return Credential{}, false, fmt.Errorf("docker config auth of %s is invalid: %w", key, err)
+			}
+			credential.Username, credential.Password, _ = strings.Cut(string(decoded), ":")
+		}
+		if credential.Username != "" || credential.Password != "" {
+			return credential, true, nil
+		}
+		// with a credential store auths only record the registries logged in
+		// to
+		if config.CredsStore != "" {
+			return dockerHelperCredential(config.CredsStore, key)
+		}
+	}
+	return Credential{}, false, nil
+}
+
+// dockerHost returns the host of a registry of the docker configuration where
+// Docker Hub is recorded as https://index.docker.io/v1/.
+func dockerHost(registry string) string {
+	host := Host(registry)
+	switch host {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+	return host
+}
+
+// dockerHelperCredential returns the credential of the registry from the docker
+// credential helper, eg. docker-credential-osxkeychain. A helper that fails or
+// has no credential for the registry means no credential.
+func dockerHelperCredential(helper string, registry string) (Credential, bool, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(registry)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return Credential{}, false, nil
+	}
+	var response struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return Credential{}, false, fmt.Errorf("docker credential helper %s returned invalid output: %w", helper, err)
+	}
+	return Credential{Username: response.Username, Password: response.Secret, Source: "docker-credential-" + helper}, true, nil
+}
@@ -0,0 +1,102 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerCredential(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("credential helper is a shell script")
+	}
+	bin := t.TempDir()
+	helper := `#!/bin/sh
+read registry
+if [ "$registry" = "ghcr.io" ]; then
+  echo '{"ServerURL":"ghcr.io","Username":"helper","Secret":"helper-secret"}'
+else
+  echo "credentials not found in native keychain"
+  exit 1
+fi
+`
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "docker-credential-test"), []byte(helper), 0o755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tt := []struct {
+		name       string
+		config     string
+		host       string
+		credential Credential
+		ok         bool
+		err        string
+	}{
+		{
+			name:       "auth",
+			config:     `{"auths":{"registry.example.com":{"auth":"dXNlcjpwYXNz"}}}`,
+			host:       "registry.example.com",
+			credential: Credential{Username: "user", Password: "pass", Source: "docker config"},
+			ok:         true,
+		},
+		{
+			name:       "docker hub",
+			config:     `{"auths":{"https://index.docker.io/v1/":{"username":"user","password":"pass"}}}`,
+			host:       "docker.io",
+			credential: Credential{Username: "user", Password: "pass", Source: "docker config"},
+			ok:         true,
+		},
+		{
+			name:       "credential helper of host",
+			config:     `{"auths":{"ghcr.io":{"auth":"dXNlcjpwYXNz"}},"credHelpers":{"ghcr.io":"test"}}`,
+			host:       "ghcr.io",
+			credential: Credential{Username: "helper", Password: "helper-secret", Source: "docker-credential-test"},
+			ok:         true,
+		},
+		{
+			name:       "credential store",
+			config:     `{"auths":{"ghcr.io":{}},"credsStore":"test"}`,
+			host:       "ghcr.io",
+			credential: Credential{Username: "helper", Password: "helper-secret", Source: "docker-credential-test"},
+			ok:         true,
+		},
+		{
+			name:   "credential store without credential",
+			config: `{"auths":{"quay.io":{}},"credsStore":"test"}`,
+			host:   "quay.io",
+			ok:     false,
+		},
+		{
+			name:   "other registry",
+			config: `{"auths":{"ghcr.io":{"auth":"dXNlcjpwYXNz"}}}`,
+			host:   "quay.io",
+			ok:     false,
+		},
+		{
+			name:   "invalid",
+			config: `{"auths":[]}`,
+			host:   "quay.io",
+			err:    "docker config",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("DOCKER_CONFIG", dir)
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte(tc.config), 0o600))
+
+			credential, ok, err := dockerCredential(tc.host)
+
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.credential, credential)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package credentials
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables configuring a GitHub App whose installation tokens
+// authenticate to GitHub.
+const (
+	githubAppIDEnv             = "SHUTTLE_GITHUB_APP_ID"
+	githubAppInstallationIDEnv = "SHUTTLE_GITHUB_APP_INSTALLATION_ID"
+	githubAppPrivateKeyEnv     = "SHUTTLE_GITHUB_APP_PRIVATE_KEY"
+	// githubHostEnv is the host of GitHub Enterprise servers
+	githubHostEnv = "SHUTTLE_GITHUB_HOST"
+	// githubAPIURLEnv is the API URL of GitHub Enterprise servers if not
+	// https://<host>/api/v3
+	githubAPIURLEnv = "SHUTTLE_GITHUB_API_URL"
+)
+
+// installationTokens caches installation tokens of GitHub Apps by installation
+// for the lifetime of the process.
+var installationTokens = struct {
+	sync.Mutex
+	tokens map[string]installationToken
+}{tokens: map[string]installationToken{}}
+
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// githubHost returns the host of GitHub, github.com unless SHUTTLE_GITHUB_HOST
+// is set.
+func githubHost() string {
+	if host := os.Getenv(githubHostEnv); host != "" {
+		return Host(host)
+	}
+	return "github.com"
+}
+
+// githubAPIURL returns the URL of the REST API of the GitHub host.
+func githubAPIURL(host string) string {
+	if apiURL := os.Getenv(githubAPIURLEnv); apiURL != "" {
+		return strings.TrimSuffix(apiURL, "/")
+	}
+	if host == "github.com" {
+		return "https://api.github.com"
+	}
+	return "https://" + host + "/api/v3"
+}
+
+// githubTokenCredential returns the token of GH_TOKEN or GITHUB_TOKEN, eg. of
+// GitHub Actions, for the GitHub host.
+func githubTokenCredential(host string) (Credential, bool, error) {
+	if host != githubHost() {
+		return Credential{}, false, nil
+	}
+	for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN"} {
+		if token := os.Getenv(name); token != "" {
+			return Credential{Username: tokenUsername, Password: token, Source: name}, true, nil
+		}
+	}
+	return Credential{}, false, nil
+}
+
+// githubAppCredential returns an installation token of the GitHub App of
+// SHUTTLE_GITHUB_APP_ID for the GitHub host. Tokens are requested once and
+// reused until shortly before they expire.
+func githubAppCredential(host string) (Credential, bool, error) {
+	appID := os.Getenv(githubAppIDEnv)
+	if appID == "" || host != githubHost() {
+		return Credential{}, false, nil
+	}
+	installationID := os.Getenv(githubAppInstallationIDEnv)
+	privateKey := os.Getenv(githubAppPrivateKeyEnv)
+	if installationID == "" || privateKey == "" {
+		return Credential{}, false, fmt.Errorf(
+			"GitHub App %s requires %s and %s to be set",
+			appID, githubAppInstallationIDEnv, githubAppPrivateKeyEnv,
+		)
+	}
+
+	installationTokens.Lock()
+	defer installationTokens.Unlock()
+	cacheKey := host + "/" + installationID
+	token, ok := installationTokens.tokens[cacheKey]
+	if !ok || time.Until(token.ExpiresAt) < time.Minute {
+		var err error
+		token, err = requestInstallationToken(githubAPIURL(host), appID, installationID, privateKey, time.Now())
+		if err != nil {
+			return Credential{}, false, fmt.Errorf("GitHub App %s: %w", appID, err)
+		}
+		installationTokens.tokens[cacheKey] = token
+	}
+	return Credential{Username: tokenUsername, Password: token.Token, Source: "GitHub App"}, true, nil
+}
+
+// requestInstallationToken requests an installation token of the GitHub App
+// authenticating with a JWT signed by its private key.
+func requestInstallationToken(apiURL, appID, installationID, privateKey string, now time.Time) (installationToken, error) {
+	jwt, err := githubAppJWT(appID, privateKey, now)
+	if err != nil {
+		return installationToken{}, err
+	}
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/app/installations/%s/access_tokens", apiURL, installationID), nil)
+	if err != nil {
+		return installationToken{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return installationToken{}, fmt.Errorf("request installation token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return installationToken{}, fmt.Errorf("read installation token: %w", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return installationToken{}, fmt.Errorf("request installation token: unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var token installationToken
+	if err := json.Unmarshal(body, &token); err != nil || token.Token == "" {
+		return installationToken{}, fmt.Errorf("installation token response is invalid: %s", body)
+	}
+	return token, nil
+}
+
+// githubAppJWT returns a JWT authenticating as the GitHub App signed with its
+// PEM encoded RSA private key. It is valid for the 10 minutes GitHub allows
+// issued a minute in the past to allow for clock drift.
+func githubAppJWT(appID string, privateKey string, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return "", fmt.Errorf("%s is not a PEM encoded private key", githubAppPrivateKeyEnv)
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, pkcs8Err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if pkcs8Err != nil || !ok {
+			return "", fmt.Errorf("%s is not an RSA private key: %w", githubAppPrivateKeyEnv, err)
+		}
+		key = rsaKey
+	}
+
+	encode := func(v interface{}) string {
+		raw, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(raw)
+	}
+	unsigned := encode(map[string]string{"alg": "RS256", "typ": "JWT"}) + "." + encode(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": appID,
+	})
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("sign GitHub App JWT: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
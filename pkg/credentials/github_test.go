@@ -0,0 +1,77 @@
+package credentials
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGithubAppCredential(t *testing.T) {
+	isolate(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/app/installations/42/access_tokens", r.URL.Path)
+		jwt := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		parts := strings.Split(jwt, ".")
+		require.Len(t, parts, 3)
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		require.NoError(t, err)
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+		claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+		require.NoError(t, err)
+		assert.Contains(t, string(claims), `"iss":"1234"`)
+
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(installationToken{Token: "installation-token", ExpiresAt: time.Now().Add(time.Hour)})
+	}))
+	defer server.Close()
+	t.Setenv(githubAPIURLEnv, server.URL)
+	t.Setenv(githubAppIDEnv, "1234")
+	t.Setenv(githubAppInstallationIDEnv, "42")
+	t.Setenv(githubAppPrivateKeyEnv, string(privateKey))
+
+	for i := 0; i < 2; i++ {
+		credential, ok, err := Lookup("github.com")
+
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, Credential{Username: tokenUsername, Password: "installation-token", Source: "GitHub App"}, credential)
+	}
+	assert.Equal(t, 1, requests, "installation tokens must be reused")
+
+	t.Run("incomplete configuration", func(t *testing.T) {
+		t.Setenv(githubAppPrivateKeyEnv, "")
+
+		_, _, err := Lookup("github.com")
+
+		assert.EqualError(t, err, "GitHub App 1234 requires SHUTTLE_GITHUB_APP_INSTALLATION_ID and SHUTTLE_GITHUB_APP_PRIVATE_KEY to be set")
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		t.Setenv(githubAppInstallationIDEnv, "43")
+		t.Setenv(githubAppPrivateKeyEnv, "not a key")
+
+		_, _, err := Lookup("github.com")
+
+		assert.EqualError(t, err, "GitHub App 1234: SHUTTLE_GITHUB_APP_PRIVATE_KEY is not a PEM encoded private key")
+	})
+}
@@ -0,0 +1,62 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
+// keychainService is the service credentials are stored under in the OS
+// keychain.
+const keychainService = "shuttle"
+
+// ErrNotStored is returned when removing the credential of a host that has
+// none stored.
+var ErrNotStored = errors.New("no credential stored")
+
+// Store stores the credential of host in the OS keychain, ie. the macOS
+// Keychain, the Windows Credential Manager or the Secret Service on Linux.
+func Store(host string, credential Credential) error {
+	if credential.Username == "" {
+		credential.Username = tokenUsername
+	}
+	value, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+	err = keyring.Set(keychainService, Host(host), string(value))
+	if err != nil {
+		return fmt.Errorf("store credential in keychain: %w", err)
+	}
+	return nil
+}
+
+// Remove removes the credential of host from the OS keychain. ErrNotStored is
+// returned if it has none.
+func Remove(host string) error {
+	err := keyring.Delete(keychainService, Host(host))
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrNotStored
+	}
+	if err != nil {
+		return fmt.Errorf("remove credential from keychain: %w", err)
+	}
+	return nil
+}
+
+// keychainCredential returns the credential stored for host. Keychains are
+// often unavailable, eg. in CI, so errors reading them mean no credential.
+func keychainCredential(host string) (Credential, bool, error) {
+	value, err := keyring.Get(keychainService, host)
+	if err != nil {
+		return Credential{}, false, nil
+	}
+	var credential Credential
+	if err := json.Unmarshal([]byte(value), &credential); err != nil {
+		return Credential{}, false, fmt.Errorf("credential of %s in keychain is invalid: %w", host, err)
+	}
+	credential.Source = "keychain"
+	return credential, true, nil
+}
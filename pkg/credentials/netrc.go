@@ -0,0 +1,102 @@
+package credentials
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// netrcPath returns the path of the netrc file, NETRC if set or .netrc, or
+// _netrc on Windows, in the home directory.
+func netrcPath() string {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	name := ".netrc"
+	if runtime.GOOS == "windows" {
+		name = "_netrc"
+	}
+	return filepath.Join(home, name)
+}
+
+// netrcCredential returns the login and password of the machine of host in
+// the netrc file or of its default entry.
+func netrcCredential(host string) (Credential, bool, error) {
+	path := netrcPath()
+	if path == "" {
+		return Credential{}, false, nil
+	}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Credential{}, false, nil
+	}
+	if err != nil {
+		return Credential{}, false, fmt.Errorf("read netrc: %w", err)
+	}
+	credential, ok := parseNetrc(string(content), host)
+	credential.Source = "netrc"
+	return credential, ok, nil
+}
+
+// parseNetrc returns the credential of the machine host in a netrc file
+// falling back to the default entry.
+func parseNetrc(content string, host string) (Credential, bool) {
+	var (
+		machine, fallback Credential
+		found, hasDefault bool
+		current           *Credential
+	)
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		for j := 0; j < len(fields); j++ {
+			value := ""
+			if j+1 < len(fields) {
+				value = fields[j+1]
+			}
+			switch fields[j] {
+			case "machine":
+				current = nil
+				if !found && strings.EqualFold(value, host) {
+					found = true
+					current = &machine
+				}
+				j++
+			case "default":
+				current = nil
+				if !hasDefault {
+					hasDefault = true
+					current = &fallback
+				}
+			case "login":
+				if current != nil {
+					current.Username = value
+				}
+				j++
+			case "password":
+				if current != nil {
+					current.Password = value
+				}
+				j++
+			case "account":
+				j++
+			case "macdef":
+				// macros run until the next blank line
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+				}
+				j = len(fields)
+			}
+		}
+	}
+	if found {
+		return machine, true
+	}
+	return fallback, hasDefault
+}
@@ -0,0 +1,64 @@
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"os"
+)
+
+// ErrInterrupted is the cancellation cause of contexts cancelled by the user,
+// eg. with Ctrl-C.
+var ErrInterrupted = stderrors.New("interrupted")
+
+// NewInterrupt returns the cancellation cause of contexts cancelled as signal
+// was received. It matches ErrInterrupted with errors.Is.
+func NewInterrupt(signal os.Signal) error {
+	return &interruptError{signal: signal}
+}
+
+type interruptError struct {
+	signal os.Signal
+}
+
+func (e *interruptError) Error() string {
+	return fmt.Sprintf("interrupted by %v", e.signal)
+}
+
+func (e *interruptError) Is(target error) bool {
+	return target == ErrInterrupted
+}
+
+// InterruptSignal returns the signal that cancelled ctx if it was cancelled by
+// one.
+func InterruptSignal(ctx context.Context) (os.Signal, bool) {
+	var interrupt *interruptError
+	if stderrors.As(context.Cause(ctx), &interrupt) {
+		return interrupt.signal, true
+	}
+	return nil, false
+}
+
+const (
+	// ExitCodeInterrupted follows the shell convention of 128+SIGINT.
+	ExitCodeInterrupted = 130
+	// ExitCodeTimeout follows the convention of the timeout(1) utility.
+	ExitCodeTimeout = 124
+	// ExitCodeCancelled is used for cancellations of unknown cause.
+	ExitCodeCancelled = 2
+)
+
+// NewCancellation returns an error describing why ctx was cancelled.
+// Cancellations by the user and deadlines are reported with distinct messages
+// and exit codes.
+func NewCancellation(ctx context.Context) error {
+	cause := context.Cause(ctx)
+	switch {
+	case stderrors.Is(cause, ErrInterrupted):
+		return NewExitCode(ExitCodeInterrupted, "Cancelled by user")
+	case stderrors.Is(cause, context.DeadlineExceeded):
+		return NewExitCode(ExitCodeTimeout, "Timed out")
+	default:
+		return NewExitCode(ExitCodeCancelled, "Operation cancelled")
+	}
+}
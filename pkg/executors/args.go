@@ -0,0 +1,78 @@
+package executors
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// protectedVariables are environment variables a script argument of the same
+// name must not overwrite as the shell or the commands of the action depend on
+// them
+var protectedVariables = []string{
+	"PATH",
+	"HOME",
+	"SHELL",
+	"IFS",
+	"PWD",
+	"USER",
+	"ENV",
+	"BASH_ENV",
+	"LD_PRELOAD",
+	"LD_LIBRARY_PATH",
+}
+
+// argumentEnvironment returns the script arguments as environment variables on
+// the form name=value. Every argument is available as SHUTTLE_ARG_<NAME> and,
+// for backwards compatibility, by its own name unless that would overwrite a
+// protected variable.
+func argumentEnvironment(context ActionExecutionContext) []string {
+	names := make([]string, 0, len(context.ScriptContext.Args))
+	for name := range context.ScriptContext.Args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var env []string
+	for _, name := range names {
+		value := context.ScriptContext.Args[name]
+		env = append(env, fmt.Sprintf("%s=%s", argumentVariableName(name), value))
+		if isProtectedVariable(name) {
+			context.ScriptContext.Project.UI.Verboseln(
+				"Argument '%s' of script `%s` is only available as %s as it would overwrite the environment variable",
+				name,
+				context.ScriptContext.ScriptName,
+				argumentVariableName(name),
+			)
+			continue
+		}
+		env = append(env, fmt.Sprintf("%s=%s", name, value))
+	}
+	return env
+}
+
+// argumentVariableName returns the namespaced environment variable of the
+// argument name, eg. SHUTTLE_ARG_IMAGE_TAG for image-tag.
+func argumentVariableName(name string) string {
+	return "SHUTTLE_ARG_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// isProtectedVariable reports whether name is a protected variable. Names are
+// case insensitive on Windows like its environment.
+func isProtectedVariable(name string) bool {
+	for _, protected := range protectedVariables {
+		if name == protected || goos == "windows" && strings.EqualFold(name, protected) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_argumentEnvironment(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: t.TempDir(),
+		UI:          ui.Create(stdout, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Args: []config.ShuttleScriptArgs{{Name: "image-tag"}, {Name: "env"}, {Name: "PATH"}},
+				Actions: []config.ShuttleAction{{
+					Shell: `echo "$SHUTTLE_ARG_IMAGE_TAG $SHUTTLE_ARG_ENV $env $SHUTTLE_ARG_PATH"; test "$PATH" != "/evil" && echo "path kept"`,
+				}},
+			},
+		},
+	}, "test", map[string]string{"image-tag": "v1", "env": "prod", "PATH": "/evil"}, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "v1 prod prod /evil\npath kept\n", stdout.String())
+}
+
+func TestArgumentEnvironment(t *testing.T) {
+	tt := []struct {
+		name   string
+		goos   string
+		args   map[string]string
+		output []string
+	}{
+		{
+			name:   "no arguments",
+			goos:   "linux",
+			output: nil,
+		},
+		{
+			name:   "bare and namespaced",
+			goos:   "linux",
+			args:   map[string]string{"env": "prod", "image.tag": "v1"},
+			output: []string{"SHUTTLE_ARG_ENV=prod", "env=prod", "SHUTTLE_ARG_IMAGE_TAG=v1", "image.tag=v1"},
+		},
+		{
+			name:   "protected variable",
+			goos:   "linux",
+			args:   map[string]string{"HOME": "/tmp", "PATH": "/evil"},
+			output: []string{"SHUTTLE_ARG_HOME=/tmp", "SHUTTLE_ARG_PATH=/evil"},
+		},
+		{
+			name:   "lower case protected variable",
+			goos:   "linux",
+			args:   map[string]string{"path": "src"},
+			output: []string{"SHUTTLE_ARG_PATH=src", "path=src"},
+		},
+		{
+			name:   "lower case protected variable on windows",
+			goos:   "windows",
+			args:   map[string]string{"path": "src"},
+			output: []string{"SHUTTLE_ARG_PATH=src"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func(previous string) { goos = previous }(goos)
+			goos = tc.goos
+
+			output := argumentEnvironment(ActionExecutionContext{
+				ScriptContext: ScriptExecutionContext{
+					ScriptName: "test",
+					Project:    config.ShuttleProjectContext{UI: ui.Create(&bytes.Buffer{}, &bytes.Buffer{})},
+					Args:       tc.args,
+				},
+			})
+
+			assert.Equal(t, tc.output, output)
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	cp "github.com/otiai10/copy"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/telemetry"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// defaultArtifactRunsKept is the number of runs whose artifacts are kept
+// unless configured with artifacts.keep.
+const defaultArtifactRunsKept = 20
+
+// Environment variables configuring where artifacts are uploaded to.
+const (
+	artifactsUploadEnv = "SHUTTLE_ARTIFACTS_UPLOAD"
+	artifactsTokenEnv  = "SHUTTLE_ARTIFACTS_TOKEN"
+)
+
+// localRunID is the directory of artifacts collected outside of a shuttle
+// invocation with a run ID, eg. when shuttle is used as a library.
+const localRunID = "local"
+
+// ArtifactsDirectory returns the directory artifacts of the project are
+// collected in given its shuttle directory, eg. .shuttle.
+func ArtifactsDirectory(localShuttleDirectory string) string {
+	return filepath.Join(localShuttleDirectory, "artifacts")
+}
+
+// collectArtifacts copies the files matching the artifacts of the action into
+// the artifacts directory of the run and uploads them if an upload location
+// is configured. Patterns are expanded with the environment of the action.
+// Runs beyond the retention of the project are removed.
+func collectArtifacts(ctx context.Context, ui *ui.UI, context ActionExecutionContext) error {
+	scriptContext := context.ScriptContext
+	project := scriptContext.Project
+	keep := project.Config.Artifacts.Keep
+	if keep == 0 {
+		keep = defaultArtifactRunsKept
+	}
+	if keep < 0 {
+		return errors.NewExitCode(1, "artifacts.keep must be positive but was %d", keep)
+	}
+
+	shellEnv, err := shellEnvironment(context)
+	if err != nil {
+		return err
+	}
+	env := environmentMap(shellEnv)
+	patterns := make([]string, 0, len(context.Action.Artifacts))
+	for _, pattern := range context.Action.Artifacts {
+		patterns = append(patterns, os.Expand(pattern, func(name string) string {
+			return env[name]
+		}))
+	}
+	files, err := matchProjectFiles(scriptContext, patterns)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		ui.EmphasizeInfoln("No artifacts of action %d of script `%s` found matching %s", context.ActionIndex, scriptContext.ScriptName, strings.Join(patterns, ", "))
+		return nil
+	}
+
+	runID := telemetry.RunIDFrom(ctx)
+	if runID == "" {
+		runID = localRunID
+	}
+	dir := ArtifactsDirectory(project.LocalShuttleDirectoryPath)
+	runDir := filepath.Join(dir, runID)
+	for _, file := range files {
+		source := filepath.Join(project.ProjectPath, filepath.FromSlash(file))
+		if err := cp.Copy(source, filepath.Join(runDir, filepath.FromSlash(file))); err != nil {
+			return fmt.Errorf("collect artifact '%s': %w", file, err)
+		}
+	}
+	ui.Infoln("Collected %d artifacts of action %d into %s", len(files), context.ActionIndex, runDir)
+	if err := pruneArtifactRuns(dir, keep, runID); err != nil {
+		ui.EmphasizeInfoln("Failed to remove artifacts of old runs: %v", err)
+	}
+
+	upload := project.Config.Artifacts.Upload
+	if env := os.Getenv(artifactsUploadEnv); env != "" {
+		upload = env
+	}
+	if upload == "" {
+		return nil
+	}
+	for _, file := range files {
+		err := uploadArtifactFile(ctx, filepath.Join(runDir, filepath.FromSlash(file)), upload, path.Join(runID, file))
+		if err != nil {
+			return errors.NewExitCode(4, "Failed to upload artifact '%s' of script `%s`: %v", file, scriptContext.ScriptName, err)
+		}
+	}
+	ui.Infoln("Uploaded %d artifacts to %s", len(files), redactURL(strings.TrimSuffix(upload, "/")+"/"+runID))
+	return nil
+}
+
+// uploadArtifactFile uploads the artifact at artifactPath to name, a slash
+// separated path, under location. S3 and GCS buckets are uploaded to with the
+// aws and gcloud CLIs such that their usual configuration and authentication
+// applies. Other locations are HTTP URLs the artifact is PUT to.
+func uploadArtifactFile(ctx context.Context, artifactPath, location, name string) error {
+	target := strings.TrimSuffix(location, "/") + "/" + name
+	switch {
+	case strings.HasPrefix(location, "s3://"):
+		return runUploadCLI(ctx, "aws", "s3", "cp", "--only-show-errors", artifactPath, target)
+	case strings.HasPrefix(location, "gs://"):
+		return runUploadCLI(ctx, "gcloud", "storage", "cp", "--quiet", artifactPath, target)
+	}
+	var upload config.ShuttleUpload
+	token := os.Getenv(artifactsTokenEnv)
+	if token != "" {
+		upload.TokenEnv = artifactsTokenEnv
+	}
+	return uploadArtifact(ctx, artifactPath, target, upload, map[string]string{artifactsTokenEnv: token})
+}
+
+// runUploadCLI runs a CLI uploading an artifact. Its output is part of the
+// error if it fails.
+func runUploadCLI(ctx context.Context, name string, args ...string) error {
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(output.String()); message != "" {
+			return fmt.Errorf("%s: %w: %s", name, err, message)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// ArtifactRun is a run with collected artifacts.
+type ArtifactRun struct {
+	RunID string
+	// Path is the directory of the artifacts of the run.
+	Path string
+	// CollectedAt is when the latest artifact of the run was collected.
+	CollectedAt time.Time
+	// Files are the sorted slash separated paths of the artifacts relative to
+	// Path.
+	Files []string
+}
+
+// ListArtifacts returns the runs with artifacts in dir from oldest to latest.
+func ListArtifacts(dir string) ([]ArtifactRun, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var runs []ArtifactRun
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		run := ArtifactRun{RunID: entry.Name(), Path: filepath.Join(dir, entry.Name())}
+		err := filepath.WalkDir(run.Path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			if info.ModTime().After(run.CollectedAt) {
+				run.CollectedAt = info.ModTime()
+			}
+			relative, err := filepath.Rel(run.Path, file)
+			if err != nil {
+				return err
+			}
+			run.Files = append(run.Files, filepath.ToSlash(relative))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(run.Files)
+		runs = append(runs, run)
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].CollectedAt.Before(runs[j].CollectedAt)
+	})
+	return runs, nil
+}
+
+// pruneArtifactRuns removes the artifacts of the oldest runs of dir such that
+// at most keep are left. The artifacts of current are never removed.
+func pruneArtifactRuns(dir string, keep int, current string) error {
+	runs, err := ListArtifacts(dir)
+	if err != nil {
+		return err
+	}
+	for i, run := range runs {
+		if len(runs)-i <= keep {
+			break
+		}
+		if run.RunID == current {
+			continue
+		}
+		if err := os.RemoveAll(run.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
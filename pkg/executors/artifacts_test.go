@@ -0,0 +1,184 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/telemetry"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_artifacts(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		uploaded = make(map[string]string)
+		tokens   []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		uploaded[r.URL.Path] = string(body)
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	t.Setenv("SHUTTLE_ARTIFACTS_UPLOAD", "")
+	t.Setenv("SHUTTLE_ARTIFACTS_TOKEN", "")
+
+	projectPath := t.TempDir()
+	shuttleDir := filepath.Join(projectPath, ".shuttle")
+	run := func(t *testing.T, settings config.ShuttleArtifacts, action config.ShuttleAction) (string, string, error) {
+		t.Helper()
+		ctx := telemetry.WithRunID(context.Background())
+		var stderr bytes.Buffer
+		err := NewRegistry(ShellExecutor).Execute(ctx, config.ShuttleProjectContext{
+			ProjectPath:               projectPath,
+			LocalShuttleDirectoryPath: shuttleDir,
+			Config:                    config.ShuttleConfig{Artifacts: settings},
+			UI:                        ui.Create(&bytes.Buffer{}, &stderr),
+			Scripts: map[string]config.ShuttlePlanScript{"build": {
+				Actions: []config.ShuttleAction{action},
+			}},
+		}, "build", nil, true)
+		return telemetry.RunIDFrom(ctx), stderr.String(), err
+	}
+	artifact := func(t *testing.T, runID, name string) string {
+		t.Helper()
+		content, err := os.ReadFile(filepath.Join(ArtifactsDirectory(shuttleDir), runID, filepath.FromSlash(name)))
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	t.Run("collects matching files", func(t *testing.T) {
+		runID, stderr, err := run(t, config.ShuttleArtifacts{}, config.ShuttleAction{
+			Shell:     `mkdir -p dist/bin; echo app > dist/bin/app; echo cover > coverage.out; echo src > main.go`,
+			Artifacts: []string{"coverage.out", "dist/**"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "cover\n", artifact(t, runID, "coverage.out"))
+		assert.Equal(t, "app\n", artifact(t, runID, "dist/bin/app"))
+		assert.NoFileExists(t, filepath.Join(ArtifactsDirectory(shuttleDir), runID, "main.go"))
+		assert.Contains(t, stderr, "Collected 2 artifacts of action 0 into ")
+	})
+
+	t.Run("collects from failed actions", func(t *testing.T) {
+		runID, _, err := run(t, config.ShuttleArtifacts{}, config.ShuttleAction{
+			Shell:     `echo failed > report.xml; exit 1`,
+			Artifacts: []string{"report.xml"},
+		})
+		assert.Error(t, err)
+		assert.Equal(t, "failed\n", artifact(t, runID, "report.xml"))
+	})
+
+	t.Run("warns when nothing matches", func(t *testing.T) {
+		_, stderr, err := run(t, config.ShuttleArtifacts{}, config.ShuttleAction{
+			Shell:     `true`,
+			Artifacts: []string{"missing/*.xml"},
+		})
+		require.NoError(t, err)
+		assert.Contains(t, stderr, "No artifacts of action 0 of script `build` found matching missing/*.xml")
+	})
+
+	t.Run("uploads to http", func(t *testing.T) {
+		t.Setenv("SHUTTLE_ARTIFACTS_TOKEN", "secret")
+		runID, stderr, err := run(t, config.ShuttleArtifacts{Upload: server.URL + "/artifacts/"}, config.ShuttleAction{
+			Shell:     `echo cover > coverage.out`,
+			Artifacts: []string{"coverage.out"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"/artifacts/" + runID + "/coverage.out": "cover\n"}, uploaded)
+		assert.Equal(t, []string{"Bearer secret"}, tokens)
+		assert.Contains(t, stderr, "Uploaded 1 artifacts to "+server.URL+"/artifacts/"+runID)
+	})
+
+	t.Run("failed upload", func(t *testing.T) {
+		t.Setenv("SHUTTLE_ARTIFACTS_UPLOAD", "http://127.0.0.1:0/artifacts")
+		_, _, err := run(t, config.ShuttleArtifacts{}, config.ShuttleAction{
+			Shell:     `echo cover > coverage.out`,
+			Artifacts: []string{"coverage.out"},
+		})
+		assert.EqualError(t, err, "exit code 4 - Failed to upload artifact 'coverage.out' of script `build`: request to http://127.0.0.1:0/artifacts/"+lastRunID(t, shuttleDir)+"/coverage.out failed")
+	})
+
+	t.Run("keeps the latest runs", func(t *testing.T) {
+		runID, _, err := run(t, config.ShuttleArtifacts{Keep: 1}, config.ShuttleAction{
+			Shell:     `echo cover > coverage.out`,
+			Artifacts: []string{"coverage.out"},
+		})
+		require.NoError(t, err)
+		runs, err := ListArtifacts(ArtifactsDirectory(shuttleDir))
+		require.NoError(t, err)
+		require.Len(t, runs, 1)
+		assert.Equal(t, runID, runs[0].RunID)
+	})
+
+	t.Run("invalid keep", func(t *testing.T) {
+		_, _, err := run(t, config.ShuttleArtifacts{Keep: -1}, config.ShuttleAction{
+			Shell:     `true`,
+			Artifacts: []string{"coverage.out"},
+		})
+		assert.EqualError(t, err, "exit code 1 - artifacts.keep must be positive but was -1")
+	})
+}
+
+// lastRunID returns the ID of the run which collected artifacts last.
+func lastRunID(t *testing.T, shuttleDir string) string {
+	t.Helper()
+	runs, err := ListArtifacts(ArtifactsDirectory(shuttleDir))
+	require.NoError(t, err)
+	require.NotEmpty(t, runs)
+	return runs[len(runs)-1].RunID
+}
+
+func TestListArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, modTime time.Time) {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o755))
+		require.NoError(t, os.WriteFile(file, []byte(name), 0o644))
+		require.NoError(t, os.Chtimes(file, modTime, modTime))
+	}
+	now := time.Now().Truncate(time.Second)
+	write("b/coverage.out", now.Add(-2*time.Hour))
+	write("a/dist/app", now.Add(-time.Hour))
+	write("a/coverage.out", now.Add(-3*time.Hour))
+	write("c/report.xml", now.Add(-30*time.Minute))
+
+	runs, err := ListArtifacts(dir)
+	require.NoError(t, err)
+	var ids []string
+	for _, run := range runs {
+		ids = append(ids, run.RunID)
+	}
+	assert.Equal(t, []string{"b", "a", "c"}, ids)
+	assert.Equal(t, []string{"coverage.out", "dist/app"}, runs[1].Files)
+	assert.Equal(t, now.Add(-time.Hour), runs[1].CollectedAt.Local())
+
+	require.NoError(t, pruneArtifactRuns(dir, 1, "b"))
+	runs, err = ListArtifacts(dir)
+	require.NoError(t, err)
+	ids = nil
+	for _, run := range runs {
+		ids = append(ids, run.RunID)
+	}
+	sort.Strings(ids)
+	assert.Equal(t, []string{"b", "c"}, ids, "the current run must be kept")
+
+	runs, err = ListArtifacts(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+}
@@ -0,0 +1,182 @@
+package executors
+
+import (
+	stdcontext "context"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// BackgroundState describes the files tracking a background action.
+type BackgroundState struct {
+	// LogPath is the combined stdout and stderr of the action
+	LogPath string
+	// PIDPath holds the process ID of the action
+	PIDPath string
+	// ExitPath holds the exit code of the action once it has completed
+	ExitPath string
+}
+
+// BackgroundStateFor returns the state files of background actions for a
+// script in project.
+func BackgroundStateFor(project config.ShuttleProjectContext, scriptName string) BackgroundState {
+	dir := path.Join(project.LocalShuttleDirectoryPath, "background", scriptName)
+	return BackgroundState{
+		LogPath:  path.Join(dir, "output.log"),
+		PIDPath:  path.Join(dir, "pid"),
+		ExitPath: path.Join(dir, "exit"),
+	}
+}
+
+// ExitCode returns the exit code of the background action and whether it has
+// completed.
+func (s BackgroundState) ExitCode() (int, bool) {
+	content, err := os.ReadFile(s.ExitPath)
+	if err != nil {
+		return 0, false
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 0, false
+	}
+	return code, true
+}
+
+// PID returns the process ID of the background action and whether it is
+// known.
+func (s BackgroundState) PID() (int, bool) {
+	content, err := os.ReadFile(s.PIDPath)
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 0, false
+	}
+	return pid, true
+}
+
+// Running returns whether the process of the background action is still
+// running. If the process ID is unknown it is assumed to be running.
+func (s BackgroundState) Running() bool {
+	pid, ok := s.PID()
+	if !ok {
+		return true
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return !stderrors.Is(err, os.ErrProcessDone)
+}
+
+// inProgress returns the process ID of a previous run of the background action
+// that has not exited yet.
+func (s BackgroundState) inProgress() (int, bool) {
+	pid, ok := s.PID()
+	if !ok {
+		return 0, false
+	}
+	if _, done := s.ExitCode(); done {
+		return 0, false
+	}
+	return pid, s.Running()
+}
+
+// startBackgroundShell starts script in the background with output written to
+// the state log file. It returns as soon as the process is started. The
+// process is detached from shuttle such that it is not stopped by signals of
+// the terminal, eg. Ctrl-C. Starting the script while a previous run is still
+// running is refused as they would share the state files.
+func startBackgroundShell(ctx stdcontext.Context, context ActionExecutionContext, script string) error {
+	state := BackgroundStateFor(context.ScriptContext.Project, context.ScriptContext.ScriptName)
+	workDir, err := actionWorkingDirectory(context)
+	if err != nil {
+		return err
+	}
+	cd, cdEnv, err := changeDirectory(workDir)
+	if err != nil {
+		return err
+	}
+	interpreter, err := shellInterpreter(context)
+	if err != nil {
+		return err
+	}
+	env, err := shellEnvironment(context)
+	if err != nil {
+		return err
+	}
+	env = append(env, telemetryEnvironment(ctx)...)
+	env = append(env, cdEnv...)
+
+	// the exit code is written by the shell itself as shuttle is not around to
+	// wait for the process
+	execCmd := exec.Command(
+		interpreter,
+		"-c",
+		fmt.Sprintf(
+			"%s; ( %s ); echo $? > %s",
+			cd,
+			script,
+			singleQuote(shellPath(state.ExitPath)),
+		),
+	)
+	execCmd.Env = env
+	detachProcess(execCmd)
+	if context.ScriptContext.DryRun {
+		printDryRun(context, execCmd.Args, env)
+		return nil
+	}
+
+	if pid, running := state.inProgress(); running {
+		return errors.NewExitCode(
+			1,
+			"Script '%s' is already running in the background (pid %d). Stop it before starting it again",
+			context.ScriptContext.ScriptName,
+			pid,
+		)
+	}
+
+	err = os.MkdirAll(path.Dir(state.LogPath), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("create background state directory: %w", err)
+	}
+	err = os.Remove(state.ExitPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove previous exit state: %w", err)
+	}
+
+	logFile, err := os.Create(state.LogPath)
+	if err != nil {
+		return fmt.Errorf("create background log file: %w", err)
+	}
+	defer logFile.Close()
+	execCmd.Stdout = logFile
+	execCmd.Stderr = logFile
+
+	err = execCmd.Start()
+	if err != nil {
+		return fmt.Errorf("start background script: %w", err)
+	}
+
+	err = os.WriteFile(state.PIDPath, []byte(fmt.Sprintf("%d\n", execCmd.Process.Pid)), 0o644)
+	if err != nil {
+		return fmt.Errorf("write background pid file: %w", err)
+	}
+	context.ScriptContext.Project.UI.Infoln(
+		"Started script '%s' in the background (pid %d). Follow its output with 'shuttle logs %s'",
+		context.ScriptContext.ScriptName,
+		execCmd.Process.Pid,
+		context.ScriptContext.ScriptName,
+	)
+	return execCmd.Process.Release()
+}
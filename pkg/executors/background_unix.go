@@ -0,0 +1,14 @@
+//go:build !windows
+
+package executors
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachProcess starts c in a session of its own such that it is not sent the
+// signals of the terminal of shuttle.
+func detachProcess(c *exec.Cmd) {
+	c.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,69 @@
+//go:build !windows
+
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// TestExecute_backgroundDetached tests that background actions are started in
+// a session of their own such that signals of the terminal do not stop them
+// and that they are not started again while running.
+func TestExecute_backgroundDetached(t *testing.T) {
+	marker := t.TempDir() + "/stop"
+	project := config.ShuttleProjectContext{
+		ProjectPath:               ".",
+		LocalShuttleDirectoryPath: t.TempDir(),
+		UI:                        ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"serve": {
+				Actions: []config.ShuttleAction{
+					{
+						Shell:      `echo started; while [ ! -f "` + marker + `" ]; do sleep 0.05; done`,
+						Background: true,
+					},
+				},
+			},
+		},
+	}
+	registry := NewRegistry(ShellExecutor)
+	state := BackgroundStateFor(project, "serve")
+	t.Cleanup(func() {
+		os.WriteFile(marker, nil, 0o644)
+	})
+
+	err := registry.Execute(context.Background(), project, "serve", nil, true)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(state.PIDPath)
+	require.NoError(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	require.NoError(t, err)
+	pgid, err := syscall.Getpgid(pid)
+	require.NoError(t, err)
+	assert.Equal(t, pid, pgid, "expected the action to lead a session of its own")
+
+	err = registry.Execute(context.Background(), project, "serve", nil, true)
+	assert.EqualError(t, err, "exit code 1 - Script 'serve' is already running in the background (pid "+strconv.Itoa(pid)+"). Stop it before starting it again")
+
+	require.NoError(t, os.WriteFile(marker, nil, 0o644))
+	assert.Eventually(t, func() bool {
+		_, done := state.ExitCode()
+		return done
+	}, 5*time.Second, 10*time.Millisecond)
+	err = registry.Execute(context.Background(), project, "serve", nil, true)
+	assert.NoError(t, err, "expected the action to be started again once it exited")
+}
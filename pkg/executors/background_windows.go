@@ -0,0 +1,12 @@
+package executors
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachProcess starts c in a process group of its own such that it is not
+// sent the Ctrl-C of the console of shuttle.
+func detachProcess(c *exec.Cmd) {
+	c.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
@@ -0,0 +1,229 @@
+package executors
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cp "github.com/otiai10/copy"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// WithNoCache runs scripts with inputs even if their inputs are unchanged
+// since they last succeeded.
+func WithNoCache(noCache bool) ExecuteOption {
+	return func(c *ScriptExecutionContext) {
+		c.NoCache = noCache
+	}
+}
+
+// CacheDirectory returns the directory scripts of a project are cached in
+// given its shuttle directory, eg. .shuttle.
+func CacheDirectory(shuttleDirectory string) string {
+	return filepath.Join(shuttleDirectory, "cache")
+}
+
+// scriptCache is the cache of a script with inputs for a given cache key
+type scriptCache struct {
+	// dir holds the key of the last successful run and copies of its outputs
+	dir string
+	key string
+}
+
+// cacheKeyFile is the file in the cache directory of a script holding the key
+// of its last successful run. It is written last and marks the cache as
+// complete.
+const cacheKeyFile = "key"
+
+// newScriptCache returns the cache of the script of scriptContext with the key
+// of its current inputs, or nil if the script is not cached.
+func newScriptCache(scriptContext ScriptExecutionContext) (*scriptCache, error) {
+	script := scriptContext.Script
+	shuttleDirectory := scriptContext.Project.LocalShuttleDirectoryPath
+	if script.Inputs == nil || scriptContext.NoCache || scriptContext.DryRun || shuttleDirectory == "" {
+		return nil, nil
+	}
+	key, err := scriptCacheKey(scriptContext)
+	if err != nil {
+		return nil, err
+	}
+	return &scriptCache{
+		dir: filepath.Join(CacheDirectory(shuttleDirectory), scriptContext.ScriptName),
+		key: key,
+	}, nil
+}
+
+// scriptCacheKey returns a hash of the definition and arguments of the script
+// along with its input files and environment variables.
+func scriptCacheKey(scriptContext ScriptExecutionContext) (string, error) {
+	script := scriptContext.Script
+	hash := sha256.New()
+	definition, err := yaml.Marshal(script)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(hash, "script %s\n%s\n", scriptContext.ScriptName, definition)
+
+	var args []string
+	for name, value := range scriptContext.Args {
+		args = append(args, name+"="+value)
+	}
+	sort.Strings(args)
+	for _, arg := range args {
+		fmt.Fprintf(hash, "arg %q\n", arg)
+	}
+	for _, name := range script.Inputs.Env {
+		value, ok := os.LookupEnv(name)
+		fmt.Fprintf(hash, "env %s %t %q\n", name, ok, value)
+	}
+
+	projectPath := scriptContext.Project.ProjectPath
+	files, err := matchProjectFiles(scriptContext, script.Inputs.Files)
+	if err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		digest, err := fileDigest(filepath.Join(projectPath, filepath.FromSlash(file)))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "file %s %s\n", file, digest)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func fileDigest(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// hit returns whether the script last succeeded with the same key.
+func (c *scriptCache) hit() bool {
+	key, err := os.ReadFile(filepath.Join(c.dir, cacheKeyFile))
+	return err == nil && string(key) == c.key
+}
+
+// restore copies the cached outputs of the script into the project.
+func (c *scriptCache) restore(projectPath string) error {
+	outputs := filepath.Join(c.dir, "outputs")
+	if _, err := os.Stat(outputs); os.IsNotExist(err) {
+		return nil
+	}
+	return cp.Copy(outputs, projectPath)
+}
+
+// store records a successful run of the script with copies of its outputs.
+func (c *scriptCache) store(scriptContext ScriptExecutionContext) error {
+	if err := os.RemoveAll(c.dir); err != nil {
+		return err
+	}
+	projectPath := scriptContext.Project.ProjectPath
+	outputs, err := matchProjectFiles(scriptContext, scriptContext.Script.Outputs)
+	if err != nil {
+		return err
+	}
+	for _, output := range outputs {
+		source := filepath.Join(projectPath, filepath.FromSlash(output))
+		err := cp.Copy(source, filepath.Join(c.dir, "outputs", filepath.FromSlash(output)))
+		if err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(c.dir, os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(c.dir, cacheKeyFile), []byte(c.key), 0o644)
+}
+
+// matchProjectFiles returns the sorted slash separated paths, relative to the
+// project directory, of the files matching any of patterns. The .git and
+// .shuttle directories are never matched.
+func matchProjectFiles(scriptContext ScriptExecutionContext, patterns []string) ([]string, error) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil || path.IsAbs(pattern) {
+			return nil, errors.NewExitCode(
+				1,
+				"Pattern '%s' of script `%s` is invalid: must be a project relative glob pattern",
+				pattern,
+				scriptContext.ScriptName,
+			)
+		}
+	}
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	projectPath := scriptContext.Project.ProjectPath
+	var files []string
+	err := filepath.WalkDir(projectPath, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(projectPath, file)
+		if err != nil {
+			return err
+		}
+		relative = filepath.ToSlash(relative)
+		if entry.IsDir() {
+			if relative == ".git" || relative == ".shuttle" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		for _, pattern := range patterns {
+			if matchGlob(pattern, relative) {
+				files = append(files, relative)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// matchGlob returns whether the slash separated name matches pattern. ** in
+// pattern matches any number of directories.
+func matchGlob(pattern, name string) bool {
+	return matchGlobSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchGlobSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchGlobSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
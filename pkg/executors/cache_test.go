@@ -0,0 +1,119 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tt := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{pattern: "go.mod", name: "go.mod", match: true},
+		{pattern: "*.go", name: "main.go", match: true},
+		{pattern: "*.go", name: "cmd/main.go", match: false},
+		{pattern: "**/*.go", name: "main.go", match: true},
+		{pattern: "**/*.go", name: "cmd/run/main.go", match: true},
+		{pattern: "cmd/**", name: "cmd/run/main.go", match: true},
+		{pattern: "cmd/**/main.go", name: "cmd/main.go", match: true},
+		{pattern: "cmd/**/main.go", name: "pkg/main.go", match: false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.pattern+" "+tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.match, matchGlob(tc.pattern, tc.name))
+		})
+	}
+}
+
+func TestExecute_cache(t *testing.T) {
+	projectPath := t.TempDir()
+	log := filepath.Join(t.TempDir(), "log")
+	input := filepath.Join(projectPath, "src", "input.txt")
+	output := filepath.Join(projectPath, "out", "result")
+	require.NoError(t, os.MkdirAll(filepath.Dir(input), os.ModePerm))
+	require.NoError(t, os.WriteFile(input, []byte("first"), 0o644))
+	t.Setenv("log", log)
+	t.Setenv("SHUTTLE_TEST_CACHE_ENV", "first")
+
+	script := config.ShuttlePlanScript{
+		Inputs:  &config.ShuttleScriptInputs{Files: []string{"src/**"}, Env: []string{"SHUTTLE_TEST_CACHE_ENV"}},
+		Outputs: []string{"out/*"},
+		Actions: []config.ShuttleAction{
+			{Shell: `echo run >> "$log"; mkdir -p out; cat src/input.txt > out/result`},
+		},
+	}
+	run := func(t *testing.T, options ...ExecuteOption) {
+		t.Helper()
+		err := NewRegistry(ShellExecutor).Execute(context.Background(), config.ShuttleProjectContext{
+			ProjectPath:               projectPath,
+			LocalShuttleDirectoryPath: filepath.Join(projectPath, ".shuttle"),
+			UI:                        ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+			Scripts:                   map[string]config.ShuttlePlanScript{"build": script},
+		}, "build", nil, true, options...)
+		require.NoError(t, err)
+	}
+	runs := func(t *testing.T) string {
+		t.Helper()
+		content, _ := os.ReadFile(log)
+		return string(content)
+	}
+
+	run(t)
+	run(t)
+	assert.Equal(t, "run\n", runs(t), "unchanged inputs must be cached")
+
+	require.NoError(t, os.Remove(output))
+	run(t)
+	assert.Equal(t, "run\n", runs(t), "removed outputs must not run the script")
+	result, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(result), "outputs must be restored")
+
+	require.NoError(t, os.WriteFile(input, []byte("second"), 0o644))
+	run(t)
+	assert.Equal(t, "run\nrun\n", runs(t), "changed input file must run the script")
+
+	t.Setenv("SHUTTLE_TEST_CACHE_ENV", "second")
+	run(t)
+	assert.Equal(t, "run\nrun\nrun\n", runs(t), "changed input environment must run the script")
+
+	run(t, WithNoCache(true))
+	assert.Equal(t, "run\nrun\nrun\nrun\n", runs(t), "no cache must run the script")
+}
+
+func TestExecute_cacheFailure(t *testing.T) {
+	projectPath := t.TempDir()
+	log := filepath.Join(t.TempDir(), "log")
+	t.Setenv("log", log)
+	registry := NewRegistry(ShellExecutor)
+	project := config.ShuttleProjectContext{
+		ProjectPath:               projectPath,
+		LocalShuttleDirectoryPath: filepath.Join(projectPath, ".shuttle"),
+		UI:                        ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"build": {
+				Inputs:  &config.ShuttleScriptInputs{},
+				Actions: []config.ShuttleAction{{Shell: `echo run >> "$log"; exit 1`}},
+			},
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		err := registry.Execute(context.Background(), project, "build", nil, true)
+		assert.Error(t, err)
+	}
+
+	content, _ := os.ReadFile(log)
+	assert.Equal(t, "run\nrun\n", string(content), "failures must not be cached")
+}
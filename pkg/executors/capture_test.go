@@ -0,0 +1,90 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_captureOutput(t *testing.T) {
+	tt := []struct {
+		name    string
+		actions []config.ShuttleAction
+		stdout  string
+		stderr  string
+		err     string
+	}{
+		{
+			name: "captured output is available to later actions",
+			actions: []config.ShuttleAction{
+				{Shell: "echo '  1.2.3  '; echo building >&2", CaptureOutput: "version"},
+				{Shell: `echo "version=$version"`},
+			},
+			stdout: "version=1.2.3\n",
+			stderr: "building\n",
+		},
+		{
+			name: "multiple lines",
+			actions: []config.ShuttleAction{
+				{Shell: "echo a; echo b", CaptureOutput: "lines"},
+				{Shell: `echo "$lines" | wc -l | tr -d ' '`},
+			},
+			stdout: "2\n",
+		},
+		{
+			name: "last capture wins",
+			actions: []config.ShuttleAction{
+				{Shell: "echo first", CaptureOutput: "value"},
+				{Shell: "echo second", CaptureOutput: "value"},
+				{Shell: `echo "$value"`},
+			},
+			stdout: "second\n",
+		},
+		{
+			name: "failed action does not capture",
+			actions: []config.ShuttleAction{
+				{Shell: "echo first", CaptureOutput: "value"},
+				{Shell: "echo second; exit 1", CaptureOutput: "value"},
+				{Shell: `echo "$value"`, Always: true},
+			},
+			stdout: "first\n",
+			err:    "exit code 4 - Failed executing script `test`: shell script `echo second; exit 1`\nExit code: 1",
+		},
+		{
+			name: "invalid name",
+			actions: []config.ShuttleAction{
+				{Shell: "echo 1", CaptureOutput: "my-version"},
+			},
+			err: "exit code 1 - Action 0 of script `test` has an invalid captureOutput 'my-version': must be a valid environment variable name",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(stdout, stderr),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {Actions: tc.actions},
+				},
+			}, "test", nil, true)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.stdout, stdout.String())
+			if tc.stderr != "" {
+				assert.Equal(t, tc.stderr, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package executors
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// defaultCheckTimeout is the duration a run check may take if it does not
+// specify a timeout
+const defaultCheckTimeout = 10 * time.Second
+
+// runChecks verifies all checks of the script concurrently before any action
+// is run. All checks are completed and failures are reported together. URLs
+// and hosts are expanded with the environment of the script.
+func runChecks(ctx context.Context, scriptContext ScriptExecutionContext) error {
+	checks := scriptContext.Script.Checks
+	if len(checks) == 0 {
+		return nil
+	}
+	timeouts := make([]time.Duration, len(checks))
+	for i, check := range checks {
+		timeout, err := checkTimeout(scriptContext.ScriptName, check)
+		if err != nil {
+			return err
+		}
+		timeouts[i] = timeout
+	}
+
+	shellEnv, err := shellEnvironment(ActionExecutionContext{ScriptContext: scriptContext})
+	if err != nil {
+		return err
+	}
+	env := environmentMap(shellEnv)
+	expand := func(s string) string {
+		return os.Expand(s, func(name string) string {
+			return env[name]
+		})
+	}
+
+	failures := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check config.ShuttleRunCheck) {
+			defer wg.Done()
+			scriptContext.Project.UI.Verboseln("Running check '%s'", check)
+			checkCtx, cancel := context.WithTimeout(ctx, timeouts[i])
+			defer cancel()
+			if check.URL != "" {
+				failures[i] = checkURL(checkCtx, expand(check.URL), check.Status)
+			} else {
+				failures[i] = checkHost(checkCtx, expand(check.Host))
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return errors.NewCancellation(ctx)
+	}
+
+	var report strings.Builder
+	failed := 0
+	for i, err := range failures {
+		if err == nil {
+			continue
+		}
+		failed++
+		fmt.Fprintf(&report, "\n  %s: %v", checks[i], err)
+	}
+	if failed == 0 {
+		return nil
+	}
+	return errors.NewExitCode(
+		4,
+		"%d of %d checks failed for script `%s`:%s",
+		failed,
+		len(checks),
+		scriptContext.ScriptName,
+		report.String(),
+	)
+}
+
+// checkTimeout validates check and returns its timeout.
+func checkTimeout(script string, check config.ShuttleRunCheck) (time.Duration, error) {
+	if (check.URL == "") == (check.Host == "") {
+		return 0, errors.NewExitCode(
+			1,
+			"Check '%s' of script `%s` must specify exactly one of 'url' or 'host'",
+			check,
+			script,
+		)
+	}
+	if check.Timeout == "" {
+		return defaultCheckTimeout, nil
+	}
+	timeout, err := time.ParseDuration(check.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, errors.NewExitCode(
+			1,
+			"Check '%s' of script `%s` has an invalid timeout '%s': must be a positive duration, eg. 5s",
+			check,
+			script,
+			check.Timeout,
+		)
+	}
+	return timeout, nil
+}
+
+func checkURL(ctx context.Context, target string, accepted []int) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", redactURL(target), unwrapURLError(err))
+	}
+	resp.Body.Close()
+	if !acceptedStatus(resp.StatusCode, accepted) {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// unwrapURLError removes the url.Error wrapping as it repeats the unredacted
+// URL.
+func unwrapURLError(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
+
+func acceptedStatus(status int, accepted []int) bool {
+	if len(accepted) == 0 {
+		return status >= 200 && status < 300
+	}
+	for _, a := range accepted {
+		if status == a {
+			return true
+		}
+	}
+	return false
+}
+
+func checkHost(ctx context.Context, host string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
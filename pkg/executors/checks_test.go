@@ -0,0 +1,142 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunChecks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			w.WriteHeader(http.StatusOK)
+		case "/moved":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedHost := closedListener.Addr().String()
+	closedListener.Close()
+
+	tt := []struct {
+		name   string
+		checks []config.ShuttleRunCheck
+		args   map[string]string
+		err    string
+	}{
+		{
+			name:   "no checks",
+			checks: nil,
+		},
+		{
+			name: "passing checks",
+			checks: []config.ShuttleRunCheck{
+				{URL: server.URL + "/healthz"},
+				{URL: server.URL + "/moved", Status: []int{204}},
+				{Host: listener.Addr().String()},
+			},
+		},
+		{
+			name: "url expanded with arguments",
+			checks: []config.ShuttleRunCheck{
+				{URL: server.URL + "/$path"},
+			},
+			args: map[string]string{"path": "healthz"},
+		},
+		{
+			name: "failing checks are reported together",
+			checks: []config.ShuttleRunCheck{
+				{Name: "api", URL: server.URL + "/unavailable"},
+				{URL: server.URL + "/healthz"},
+				{Name: "database", Host: closedHost},
+			},
+			err: "exit code 4 - 2 of 3 checks failed for script `deploy`:\n  api: unexpected status 503 Service Unavailable\n  database: dial tcp " + closedHost + ": connect: connection refused",
+		},
+		{
+			name: "unexpected status",
+			checks: []config.ShuttleRunCheck{
+				{Name: "api", URL: server.URL + "/healthz", Status: []int{204}},
+			},
+			err: "exit code 4 - 1 of 1 checks failed for script `deploy`:\n  api: unexpected status 200 OK",
+		},
+		{
+			name: "both url and host",
+			checks: []config.ShuttleRunCheck{
+				{Name: "api", URL: server.URL, Host: listener.Addr().String()},
+			},
+			err: "exit code 1 - Check 'api' of script `deploy` must specify exactly one of 'url' or 'host'",
+		},
+		{
+			name: "invalid timeout",
+			checks: []config.ShuttleRunCheck{
+				{Name: "api", URL: server.URL, Timeout: "soon"},
+			},
+			err: "exit code 1 - Check 'api' of script `deploy` has an invalid timeout 'soon': must be a positive duration, eg. 5s",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := runChecks(context.Background(), ScriptExecutionContext{
+				ScriptName: "deploy",
+				Script:     config.ShuttlePlanScript{Checks: tc.checks},
+				Project: config.ShuttleProjectContext{
+					UI: ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+				},
+				Args: tc.args,
+			})
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestExecute_checksFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	marker := filepath.Join(t.TempDir(), "marker")
+	registry := NewRegistry(ShellExecutor)
+	var summary RunSummary
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: ".",
+		UI:          ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"deploy": {
+				Checks: []config.ShuttleRunCheck{{Name: "api", URL: server.URL}},
+				Actions: []config.ShuttleAction{
+					{Shell: "touch " + marker},
+				},
+			},
+		},
+	}, "deploy", nil, true, WithSummary(&summary))
+
+	assert.EqualError(t, err, "exit code 4 - 1 of 1 checks failed for script `deploy`:\n  api: unexpected status 503 Service Unavailable")
+	assert.NoFileExists(t, marker)
+	if assert.Len(t, summary.Actions, 1) {
+		assert.Equal(t, ActionStatusSkipped, summary.Actions[0].Status)
+	}
+}
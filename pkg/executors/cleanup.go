@@ -0,0 +1,86 @@
+package executors
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// defaultCleanupTimeout bounds the duration of cleanups without a
+// cleanupTimeout
+const defaultCleanupTimeout = 30 * time.Second
+
+// cleanupTimeout returns how long the cleanup of the action may run.
+func cleanupTimeout(context ActionExecutionContext) (time.Duration, error) {
+	raw := context.Action.CleanupTimeout
+	if raw == "" {
+		return defaultCleanupTimeout, nil
+	}
+	timeout, err := time.ParseDuration(raw)
+	if err != nil || timeout <= 0 {
+		return 0, errors.NewExitCode(
+			1,
+			"Action %d of script `%s` has an invalid cleanupTimeout '%s': must be a positive duration, eg. 30s",
+			context.ActionIndex,
+			context.ScriptContext.ScriptName,
+			raw,
+		)
+	}
+	return timeout, nil
+}
+
+// runCleanup runs the cleanup of the action, if any, once the action has
+// completed however it completed. The cleanup runs with the environment of the
+// action, even if ctx is cancelled, until its own timeout. Its output is
+// prefixed with the action and cleanup, eg. [build/0 cleanup], and failures are
+// printed as warnings as the result of the action is what is reported.
+func runCleanup(ctx context.Context, actionContext ActionExecutionContext) {
+	if actionContext.Action.Cleanup == "" {
+		return
+	}
+	ui := actionContext.ScriptContext.Project.UI
+	timeout, err := cleanupTimeout(actionContext)
+	if err != nil {
+		ui.EmphasizeInfoln("Cleanup of action %d of script `%s` was not run: %v", actionContext.ActionIndex, actionContext.ScriptContext.ScriptName, err)
+		return
+	}
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	cleanupContext := actionContext
+	cleanupContext.ScriptContext.PrefixOutput = true
+	cleanupContext.Action.Label = actionLabel(actionContext) + " cleanup"
+	cleanupContext.Action.Interactive = false
+	if cleanupContext.Action.Docker != nil {
+		// cleanups of docker actions are run on the host, eg. to remove
+		// resources of the container
+		cleanupContext.Action.Docker = nil
+		cleanupContext.Action.Interpreter = ""
+	}
+	exitCode, err := runShellCommand(cleanupCtx, cleanupContext, actionContext.Action.Cleanup, false, nil)
+	switch {
+	case err != nil && stderrors.Is(cleanupCtx.Err(), context.DeadlineExceeded):
+		ui.EmphasizeInfoln(
+			"Cleanup of action %d of script `%s` timed out after %s",
+			actionContext.ActionIndex,
+			actionContext.ScriptContext.ScriptName,
+			timeout,
+		)
+	case err != nil:
+		ui.EmphasizeInfoln(
+			"Cleanup of action %d of script `%s` failed: %v",
+			actionContext.ActionIndex,
+			actionContext.ScriptContext.ScriptName,
+			err,
+		)
+	case exitCode != 0:
+		ui.EmphasizeInfoln(
+			"Cleanup of action %d of script `%s` exited with code %d",
+			actionContext.ActionIndex,
+			actionContext.ScriptContext.ScriptName,
+			exitCode,
+		)
+	}
+}
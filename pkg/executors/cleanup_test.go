@@ -0,0 +1,107 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_cleanup(t *testing.T) {
+	tt := []struct {
+		name   string
+		action config.ShuttleAction
+		stdout string
+		stderr string
+		err    string
+	}{
+		{
+			name:   "success",
+			action: config.ShuttleAction{Shell: "echo main", Cleanup: `echo "cleaning $token"`},
+			stdout: "main\n[test/0 cleanup] cleaning ***\n",
+		},
+		{
+			name:   "failed action",
+			action: config.ShuttleAction{Shell: "echo main; exit 3", Cleanup: "echo cleaning"},
+			stdout: "main\n[test/0 cleanup] cleaning\n",
+			err:    "exit code 4 - Failed executing script `test`: shell script `echo main; exit 3`\nExit code: 3",
+		},
+		{
+			name:   "failed cleanup",
+			action: config.ShuttleAction{Shell: "echo main", Cleanup: "exit 5"},
+			stdout: "main\n",
+			stderr: "\x1b[032;1mCleanup of action 0 of script `test` exited with code 5\x1b[0m\n",
+		},
+		{
+			name:   "cleanup timeout",
+			action: config.ShuttleAction{Shell: "echo main", Cleanup: "sleep 5", CleanupTimeout: "100ms"},
+			stdout: "main\n",
+			stderr: "\x1b[032;1mCleanup of action 0 of script `test` timed out after 100ms\x1b[0m\n",
+		},
+		{
+			name:   "action timeout",
+			action: config.ShuttleAction{Shell: "sleep 5", Timeout: "100ms", Cleanup: "echo cleaning"},
+			stdout: "[test/0 cleanup] cleaning\n",
+			err:    "exit code 124 - Action 0 of script `test` timed out after 100ms",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: t.TempDir(),
+				UI:          ui.Create(stdout, stderr),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Args:    []config.ShuttleScriptArgs{{Name: "token", Secret: true}},
+						Actions: []config.ShuttleAction{tc.action},
+					},
+				},
+			}, "test", map[string]string{"token": "s3cr3t-value"}, true)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.stdout, stdout.String())
+			assert.Equal(t, tc.stderr, stderr.String())
+		})
+	}
+}
+
+func TestExecute_cleanupCancelled(t *testing.T) {
+	projectPath := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(ctx, config.ShuttleProjectContext{
+		ProjectPath: projectPath,
+		UI:          ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Actions: []config.ShuttleAction{{
+					Shell:   "touch resource; sleep 5",
+					Cleanup: "rm resource; touch cleaned",
+				}},
+			},
+		},
+	}, "test", nil, true)
+
+	assert.EqualError(t, err, "exit code 2 - Operation cancelled")
+	assert.NoFileExists(t, filepath.Join(projectPath, "resource"))
+	_, statErr := os.Stat(filepath.Join(projectPath, "cleaned"))
+	require.NoError(t, statErr, "cleanup must run after cancellation")
+}
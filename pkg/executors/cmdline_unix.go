@@ -0,0 +1,10 @@
+//go:build !windows
+
+package executors
+
+import "os/exec"
+
+// rawCommandLine is only needed on Windows where cmd.exe is available.
+func rawCommandLine(cmdArgs []string) func(*exec.Cmd) {
+	return nil
+}
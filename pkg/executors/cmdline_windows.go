@@ -0,0 +1,23 @@
+package executors
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// rawCommandLine returns a function passing the script of a cmd command to
+// cmd.exe as is as it does not understand how Go escapes arguments, eg.
+// quotes. It returns nil for all other commands.
+func rawCommandLine(cmdArgs []string) func(*exec.Cmd) {
+	if len(cmdArgs) != 5 || cmdArgs[0] != cmdInterpreter {
+		return nil
+	}
+	return func(c *exec.Cmd) {
+		if c.SysProcAttr == nil {
+			c.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		// /s strips the outer quotes and keeps the script as it is
+		c.SysProcAttr.CmdLine = fmt.Sprintf(`%s %s %s %s "%s"`, cmdArgs[0], cmdArgs[1], cmdArgs[2], cmdArgs[3], cmdArgs[4])
+	}
+}
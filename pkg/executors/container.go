@@ -0,0 +1,166 @@
+package executors
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// DockerExecutor runs actions in a docker container. They share the
+// implementation of shell actions except for how the command is started.
+func DockerExecutor(action config.ShuttleAction) (Executor, bool) {
+	return executeShell, action.Docker != nil
+}
+
+// validateDockerAction returns an error if the docker configuration of the
+// action is incomplete or the action uses options only supported on the host.
+func validateDockerAction(context ActionExecutionContext) error {
+	action := context.Action
+	if action.Docker.Image == "" {
+		return errors.NewExitCode(
+			1,
+			"Action %d of script `%s` has no docker image to run",
+			context.ActionIndex,
+			context.ScriptContext.ScriptName,
+		)
+	}
+	if action.Docker.Entrypoint != "" && action.Shell != "" {
+		return errors.NewExitCode(
+			1,
+			"Action %d of script `%s` cannot have both a docker entrypoint and a shell",
+			context.ActionIndex,
+			context.ScriptContext.ScriptName,
+		)
+	}
+	for _, mount := range action.Docker.Mounts {
+		source, target, _ := strings.Cut(mount, ":")
+		if source == "" || target == "" {
+			return errors.NewExitCode(
+				1,
+				"Action %d of script `%s` has an invalid docker mount '%s': must be on the form source:target[:options]",
+				context.ActionIndex,
+				context.ScriptContext.ScriptName,
+				mount,
+			)
+		}
+	}
+	option := ""
+	switch {
+	case action.PowerShell != "":
+		option = "powershell"
+	case action.Sudo:
+		option = "sudo"
+	case action.Background:
+		option = "background"
+	case action.Cwd == CwdInvocation:
+		option = "cwd: invocation"
+	case action.MergeStderr && action.Shell == "":
+		option = "mergeStderr without a shell"
+	default:
+		return nil
+	}
+	return errors.NewExitCode(
+		1,
+		"Action %d of script `%s` cannot use %s as it is a docker action",
+		context.ActionIndex,
+		context.ScriptContext.ScriptName,
+		option,
+	)
+}
+
+// dockerCommand returns the command running script in a container of the
+// image of the action. The project and plan directories are mounted at the
+// same paths as on the host so paths in the environment stay valid. Without a
+// script the container runs the command of the image.
+func dockerCommand(ctx context.Context, context ActionExecutionContext, script string) ([]string, []string, string, error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return nil, nil, "", errors.NewExitCode(
+			4,
+			"Docker required by script `%s` was not found on PATH",
+			context.ScriptContext.ScriptName,
+		)
+	}
+	docker := context.Action.Docker
+	project := context.ScriptContext.Project
+	workDir, err := actionWorkingDirectory(context)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	env, err := shellEnvironment(context)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	env = append(env, telemetryEnvironment(ctx)...)
+
+	cmdArgs := []string{"docker", "run", "--rm", "-i", "-v", project.ProjectPath + ":" + project.ProjectPath}
+	if plan := project.LocalPlanPath; plan != "" && !withinDirectory(project.ProjectPath, plan) {
+		cmdArgs = append(cmdArgs, "-v", plan+":"+plan)
+	}
+	for _, mount := range docker.Mounts {
+		source, rest, _ := strings.Cut(mount, ":")
+		if !filepath.IsAbs(source) {
+			source = filepath.Join(project.ProjectPath, source)
+		}
+		cmdArgs = append(cmdArgs, "-v", source+":"+rest)
+	}
+	cmdArgs = append(cmdArgs, "-w", workDir)
+	// values are passed through the environment of docker itself to keep them
+	// out of the arguments
+	for _, name := range containerVariables(env) {
+		cmdArgs = append(cmdArgs, "-e", name)
+	}
+	if script == "" {
+		if docker.Entrypoint != "" {
+			cmdArgs = append(cmdArgs, "--entrypoint", docker.Entrypoint)
+		}
+		return append(cmdArgs, docker.Image), env, "", nil
+	}
+
+	merge, err := mergeStderr(context)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if merge {
+		script = "exec 2>&1; " + script
+	}
+	// the interpreter is looked up in the container and not on the host
+	interpreter := context.Action.Interpreter
+	if interpreter == "" {
+		interpreter = defaultInterpreter
+	}
+	return append(cmdArgs, "--entrypoint", interpreter, docker.Image, "-c", script), env, "", nil
+}
+
+// containerVariables returns the sorted names of the variables of env set by
+// shuttle, ie. those not inherited unchanged from the environment of shuttle
+// itself. PATH is left to the image.
+func containerVariables(env []string) []string {
+	inherited := make(map[string]bool)
+	for _, entry := range os.Environ() {
+		inherited[entry] = true
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for _, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		if inherited[entry] || name == "PATH" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// withinDirectory returns whether path is dir or a path within it.
+func withinDirectory(dir, path string) bool {
+	relative, err := filepath.Rel(dir, path)
+	return err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,132 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// fakeDocker prints its arguments except the names of environment variables
+// other than project and the value of project as docker would pass it on. It
+// fails for the image failing.
+const fakeDocker = `#!/bin/sh
+for arg in "$@"; do
+  case "$arg" in failing) status=3;; esac
+done
+while [ $# -gt 0 ]; do
+  if [ "$1" = "-e" ]; then
+    [ "$2" = "project" ] && echo "-e project"
+    shift 2
+    continue
+  fi
+  echo "$1"
+  shift
+done
+echo "project=$project"
+exit ${status:-0}
+`
+
+func TestExecute_docker(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake docker is a shell script")
+	}
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "docker"), []byte(fakeDocker), 0o755)
+	require.NoError(t, err)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	projectPath := t.TempDir()
+	run := "run\n--rm\n-i\n-v\n" + projectPath + ":" + projectPath + "\n"
+
+	tt := []struct {
+		name   string
+		action config.ShuttleAction
+		stdout string
+		err    string
+	}{
+		{
+			name:   "shell script",
+			action: config.ShuttleAction{Shell: "go build ./...", Docker: &config.ShuttleDocker{Image: "golang"}},
+			stdout: run + "-w\n" + projectPath + "\n-e project\n--entrypoint\nsh\ngolang\n-c\ngo build ./...\nproject=" + projectPath + "\n",
+		},
+		{
+			name:   "interpreter and merged stderr",
+			action: config.ShuttleAction{Shell: "go build ./...", Interpreter: "bash", MergeStderr: true, Docker: &config.ShuttleDocker{Image: "golang"}},
+			stdout: run + "-w\n" + projectPath + "\n-e project\n--entrypoint\nbash\ngolang\n-c\nexec 2>&1; go build ./...\nproject=" + projectPath + "\n",
+		},
+		{
+			name:   "entrypoint and mounts",
+			action: config.ShuttleAction{Docker: &config.ShuttleDocker{Image: "linter", Entrypoint: "lint", Mounts: []string{"cache:/cache", "/etc/ssl:/etc/ssl:ro"}}},
+			stdout: run + "-v\n" + filepath.Join(projectPath, "cache") + ":/cache\n-v\n/etc/ssl:/etc/ssl:ro\n-w\n" + projectPath + "\n-e project\n--entrypoint\nlint\nlinter\nproject=" + projectPath + "\n",
+		},
+		{
+			name:   "failing image",
+			action: config.ShuttleAction{Docker: &config.ShuttleDocker{Image: "failing"}},
+			stdout: run + "-w\n" + projectPath + "\n-e project\nfailing\nproject=" + projectPath + "\n",
+			err:    "exit code 4 - Failed executing script `test`: docker image `failing`\nExit code: 3",
+		},
+		{
+			name:   "no image",
+			action: config.ShuttleAction{Shell: "go build ./...", Docker: &config.ShuttleDocker{}},
+			err:    "exit code 1 - Action 0 of script `test` has no docker image to run",
+		},
+		{
+			name:   "entrypoint and shell",
+			action: config.ShuttleAction{Shell: "go build ./...", Docker: &config.ShuttleDocker{Image: "golang", Entrypoint: "go"}},
+			err:    "exit code 1 - Action 0 of script `test` cannot have both a docker entrypoint and a shell",
+		},
+		{
+			name:   "invalid mount",
+			action: config.ShuttleAction{Shell: "go build ./...", Docker: &config.ShuttleDocker{Image: "golang", Mounts: []string{"cache"}}},
+			err:    "exit code 1 - Action 0 of script `test` has an invalid docker mount 'cache': must be on the form source:target[:options]",
+		},
+		{
+			name:   "background",
+			action: config.ShuttleAction{Shell: "go build ./...", Background: true, Docker: &config.ShuttleDocker{Image: "golang"}},
+			err:    "exit code 1 - Action 0 of script `test` cannot use background as it is a docker action",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			registry := NewRegistry(DockerExecutor, ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: projectPath,
+				UI:          ui.Create(stdout, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {Actions: []config.ShuttleAction{tc.action}},
+				},
+			}, "test", nil, true)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.stdout, stdout.String())
+		})
+	}
+}
+
+func TestContainerVariables(t *testing.T) {
+	t.Setenv("SHUTTLE_TEST_INHERITED", "value")
+
+	names := containerVariables([]string{
+		"SHUTTLE_TEST_INHERITED=value",
+		"project=/src/app",
+		"PATH=/usr/bin",
+		"plan=/src/plan",
+		"project=/src/other",
+	})
+
+	assert.Equal(t, []string{"plan", "project"}, names)
+}
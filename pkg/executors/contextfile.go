@@ -0,0 +1,95 @@
+package executors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/telemetry"
+)
+
+// contextFileEnv is the environment variable with the path of the context
+// file of an action.
+const contextFileEnv = "SHUTTLE_CONTEXT_FILE"
+
+// contextFileName is the name of the context file in the temporary directory
+// of an action.
+const contextFileName = "context.json"
+
+// RunMetadata describes the invocation of shuttle running scripts. It is part
+// of the context file of every action.
+type RunMetadata struct {
+	// ShuttleVersion is the version of the running shuttle.
+	ShuttleVersion string
+	// PlanRevision is the checked out commit of a git plan or the digest of an
+	// OCI plan.
+	PlanRevision string
+	// StartedAt is when the invocation started. Defaults to when the script
+	// was executed.
+	StartedAt time.Time
+}
+
+// WithRunMetadata sets the metadata of the invocation written to the context
+// files of actions.
+func WithRunMetadata(metadata RunMetadata) ExecuteOption {
+	return func(c *ScriptExecutionContext) {
+		c.RunMetadata = metadata
+	}
+}
+
+// ActionContextFile is the content of the context file of an action at
+// SHUTTLE_CONTEXT_FILE.
+type ActionContextFile struct {
+	Script          string            `json:"script"`
+	Action          int               `json:"action"`
+	Args            map[string]string `json:"args"`
+	SelectedScripts []string          `json:"selectedScripts"`
+	Plan            string            `json:"plan"`
+	PlanRevision    string            `json:"planRevision,omitempty"`
+	Profile         string            `json:"profile,omitempty"`
+	RunID           string            `json:"runId,omitempty"`
+	ContextID       string            `json:"contextId,omitempty"`
+	StartedAt       time.Time         `json:"startedAt"`
+	ShuttleVersion  string            `json:"shuttleVersion,omitempty"`
+}
+
+// writeContextFile writes the context file of the action of context to its
+// temporary directory dir and returns its path. Values of secret arguments are
+// replaced like in the history and the file is only readable by the current
+// user as it holds the values of the other arguments.
+func writeContextFile(ctx context.Context, context ActionExecutionContext, dir string) (string, error) {
+	scriptContext := context.ScriptContext
+	args := make(map[string]string, len(scriptContext.Args))
+	for name, value := range scriptContext.Args {
+		args[name] = value
+	}
+	for _, arg := range scriptContext.Script.Args {
+		if _, ok := args[arg.Name]; ok && arg.Secret {
+			args[arg.Name] = secretMask
+		}
+	}
+	content, err := json.MarshalIndent(ActionContextFile{
+		Script:          scriptContext.ScriptName,
+		Action:          context.ActionIndex,
+		Args:            args,
+		SelectedScripts: scriptContext.SelectedScripts,
+		Plan:            scriptContext.Project.Config.Plan,
+		PlanRevision:    scriptContext.RunMetadata.PlanRevision,
+		Profile:         scriptContext.Project.Config.Profile,
+		RunID:           telemetry.RunIDFrom(ctx),
+		ContextID:       telemetry.ContextIDFrom(ctx),
+		StartedAt:       scriptContext.RunMetadata.StartedAt.UTC(),
+		ShuttleVersion:  scriptContext.RunMetadata.ShuttleVersion,
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	contextFile := filepath.Join(dir, contextFileName)
+	if err := os.WriteFile(contextFile, append(content, '\n'), 0o600); err != nil {
+		return "", fmt.Errorf("write context file: %w", err)
+	}
+	return contextFile, nil
+}
@@ -0,0 +1,87 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/telemetry"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_contextFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	stdout := &bytes.Buffer{}
+	ctx := telemetry.WithRunID(telemetry.WithContextID(context.Background()))
+	startedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	registry := NewRegistry(ShellExecutor)
+
+	projectPath := t.TempDir()
+	err := registry.Execute(ctx, config.ShuttleProjectContext{
+		ProjectPath:       projectPath,
+		TempDirectoryPath: tmpDir,
+		UI:                ui.Create(stdout, &bytes.Buffer{}),
+		Config:            config.ShuttleConfig{Plan: "git://github.com/lunarway/shuttle-example-go-plan.git", Profile: "ci"},
+		Scripts: map[string]config.ShuttlePlanScript{
+			"deploy": {
+				Args: []config.ShuttleScriptArgs{{Name: "env"}, {Name: "token", Secret: true}},
+				Actions: []config.ShuttleAction{
+					{Shell: "true"},
+					// the output of the action is masked so the file is copied
+					{Shell: `cp "$SHUTTLE_CONTEXT_FILE" context.json`},
+				},
+			},
+		},
+	}, "deploy", map[string]string{"env": "prod", "token": "s3cret"}, true,
+		WithRunMetadata(RunMetadata{ShuttleVersion: "0.25.0", PlanRevision: "0b6c3a0", StartedAt: startedAt}))
+
+	require.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(projectPath, "context.json"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "s3cret")
+	var contextFile ActionContextFile
+	require.NoError(t, json.Unmarshal(content, &contextFile))
+	assert.Equal(t, ActionContextFile{
+		Script:          "deploy",
+		Action:          1,
+		Args:            map[string]string{"env": "prod", "token": "***"},
+		SelectedScripts: []string{"deploy"},
+		Plan:            "git://github.com/lunarway/shuttle-example-go-plan.git",
+		PlanRevision:    "0b6c3a0",
+		Profile:         "ci",
+		RunID:           telemetry.RunIDFrom(ctx),
+		ContextID:       telemetry.ContextIDFrom(ctx),
+		StartedAt:       startedAt,
+		ShuttleVersion:  "0.25.0",
+	}, contextFile)
+	assert.NoFileExists(t, filepath.Join(tmpDir, "actions", "deploy", "1", "context.json"), "context file must be removed after the action")
+}
+
+func TestExecute_contextFileDefaults(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	before := time.Now().Add(-time.Second)
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath:       t.TempDir(),
+		TempDirectoryPath: t.TempDir(),
+		UI:                ui.Create(stdout, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"build": {Actions: []config.ShuttleAction{{Shell: `cat "$SHUTTLE_CONTEXT_FILE"`}}},
+		},
+	}, "build", nil, true)
+
+	require.NoError(t, err)
+	var contextFile ActionContextFile
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &contextFile))
+	assert.Equal(t, map[string]string{}, contextFile.Args)
+	assert.True(t, contextFile.StartedAt.After(before), "started at must default to the start of the run")
+}
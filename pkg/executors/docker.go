@@ -12,7 +12,7 @@ import (
 // Build builds the docker image from a shuttle plan
 func executeDocker(ctx context.Context, context ActionExecutionContext) error {
 	dockerFilePath := path.Join(
-		context.ScriptContext.Project.LocalPlanPath,
+		context.PlanPath(),
 		context.Action.Dockerfile,
 	)
 	projectPath := context.ScriptContext.Project.ProjectPath
@@ -0,0 +1,347 @@
+package executors
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/credentials"
+	"github.com/lunarway/shuttle/pkg/executors/golang/discover"
+	"github.com/lunarway/shuttle/pkg/git"
+)
+
+// Statuses of diagnoses
+const (
+	DiagnosisOK   = "ok"
+	DiagnosisWarn = "warn"
+	DiagnosisFail = "fail"
+)
+
+// planReachableTimeout is how long reaching the repository of a git plan may
+// take before it is reported as unreachable.
+const planReachableTimeout = 30 * time.Second
+
+// Diagnosis is the result of checking a part of the environment scripts are
+// run in.
+type Diagnosis struct {
+	Check  string `json:"check"`
+	Status string `json:"status"`
+	// Detail is what was found, eg. the version of a tool.
+	Detail string `json:"detail"`
+	// Remediation describes how to fix a warning or failure.
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// Diagnose checks the environment the scripts of the project are run in: the
+// shell, git, the executors and tools the actions and plan of the project
+// require, whether its plans can be reached and whether the .shuttle directory
+// is writable. A diagnosis is returned per check.
+func Diagnose(ctx context.Context, p config.ShuttleProjectContext) []Diagnosis {
+	actions := projectActions(p)
+	var (
+		docker     bool
+		powerShell bool
+		tools      []config.ShuttleToolRequirement
+	)
+	for _, action := range actions {
+		docker = docker || action.Docker != nil || action.Dockerfile != ""
+		powerShell = powerShell || action.PowerShell != ""
+		tools = append(tools, action.Tools...)
+	}
+	requiresGolang := false
+	for _, executor := range p.Plan.Requires.Executors {
+		docker = docker || executor == config.ExecutorDocker
+		requiresGolang = requiresGolang || executor == config.ExecutorGolang
+	}
+	tools = append(tools, p.Plan.Requires.Tools...)
+	var gitPlans []string
+	for _, plan := range append([]string{p.Config.Plan}, p.Config.Overlays...) {
+		if git.IsPlan(plan) {
+			gitPlans = append(gitPlans, plan)
+		}
+	}
+
+	shells := projectShells(p)
+	powerShell = powerShell || shells[config.ShellPwsh]
+
+	var diagnoses []Diagnosis
+	// Git Bash is not needed by projects running all shell actions with pwsh
+	// or cmd
+	if len(shells) == 0 || shells[config.ShellSh] || shells[config.ShellBash] {
+		diagnoses = append(diagnoses, diagnoseShell(shells[config.ShellBash])...)
+	}
+	if shells[config.ShellCmd] {
+		diagnoses = append(diagnoses, diagnosePath(cmdInterpreter, DiagnosisFail, `Add the System32 directory of Windows, eg. C:\Windows\System32, to PATH`))
+	}
+	diagnoses = append(diagnoses, diagnoseGit(ctx, len(gitPlans) > 0))
+
+	golangActions := requiresGolang || hasGolangActions(ctx, p)
+	if golangActions {
+		diagnosis := diagnoseCommand(ctx, "go", builtinVersionCommands["go"], DiagnosisWarn,
+			"Install go from https://go.dev/dl to build golang actions without docker")
+		// golang actions are built in a container without go
+		docker = docker || diagnosis.Status != DiagnosisOK
+		diagnoses = append(diagnoses, diagnosis)
+	}
+	if docker {
+		diagnosis := diagnoseCommand(ctx, "docker", builtinVersionCommands["docker"], DiagnosisFail,
+			"Install docker from https://docs.docker.com/get-docker and make sure it is running")
+		diagnoses = append(diagnoses, diagnosis)
+	}
+	if powerShell {
+		diagnoses = append(diagnoses, diagnosePowerShell())
+	}
+	diagnoses = append(diagnoses, diagnoseTools(ctx, tools)...)
+
+	for _, plan := range gitPlans {
+		diagnoses = append(diagnoses, diagnosePlan(ctx, plan))
+	}
+	diagnoses = append(diagnoses, diagnoseShuttleDirectory(p.LocalShuttleDirectoryPath))
+	return diagnoses
+}
+
+// projectActions returns the actions of all scripts and hooks of p.
+func projectActions(p config.ShuttleProjectContext) []config.ShuttleAction {
+	names := make([]string, 0, len(p.Scripts))
+	for name := range p.Scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var actions []config.ShuttleAction
+	for _, name := range names {
+		actions = append(actions, p.Scripts[name].Actions...)
+	}
+	hooks := p.Hooks()
+	for _, hookActions := range [][]config.ShuttleAction{hooks.PreRun, hooks.PostRun, hooks.OnFailure, hooks.OnCancel} {
+		actions = append(actions, hookActions...)
+	}
+	return actions
+}
+
+// projectShells returns the shells running the shell snippets of the actions
+// of p. Actions with an interpreter are counted as run with sh.
+func projectShells(p config.ShuttleProjectContext) map[string]bool {
+	shells := make(map[string]bool)
+	add := func(script config.ShuttlePlanScript, actions []config.ShuttleAction) {
+		for _, action := range actions {
+			if action.Shell == "" || action.Docker != nil {
+				continue
+			}
+			shell, err := actionShell(ActionExecutionContext{
+				ScriptContext: ScriptExecutionContext{Script: script, Project: p},
+				Action:        action,
+			})
+			if err != nil {
+				continue
+			}
+			if action.Interpreter != "" {
+				shell = config.ShellSh
+			}
+			shells[shell] = true
+		}
+	}
+	for _, script := range p.Scripts {
+		add(script, script.Actions)
+	}
+	hooks := p.Hooks()
+	for _, hookActions := range [][]config.ShuttleAction{hooks.PreRun, hooks.PostRun, hooks.OnFailure, hooks.OnCancel} {
+		add(config.ShuttlePlanScript{}, hookActions)
+	}
+	return shells
+}
+
+func hasGolangActions(ctx context.Context, p config.ShuttleProjectContext) bool {
+	if p.ProjectPath == "" {
+		return false
+	}
+	discovered, err := discover.Discover(ctx, path.Join(p.ProjectPath, "shuttle.yaml"), &p)
+	if err != nil {
+		return false
+	}
+	for _, actions := range []*discover.ActionsDiscovered{discovered.Local, discovered.Plan} {
+		if actions != nil && len(actions.Files) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// diagnoseShell checks the shell running shell actions and bash if it is the
+// shell of some. On Windows they are the shells of Git Bash which paths are
+// converted with cygpath for.
+func diagnoseShell(bash bool) []Diagnosis {
+	if goos != "windows" {
+		diagnoses := []Diagnosis{diagnosePath("sh", DiagnosisFail, "Install a POSIX shell available as sh on PATH")}
+		if bash {
+			diagnoses = append(diagnoses, diagnosePath("bash", DiagnosisFail, "Install bash and add it to PATH"))
+		}
+		return diagnoses
+	}
+	remediation := `Install Git for Windows from https://git-scm.com/download/win and add its usr\bin directory to PATH`
+	return []Diagnosis{
+		diagnosePath("sh", DiagnosisFail, remediation),
+		diagnosePath("bash", DiagnosisFail, remediation),
+		diagnosePath("cygpath", DiagnosisFail, remediation),
+	}
+}
+
+// diagnoseGit checks git which is required to fetch git plans.
+func diagnoseGit(ctx context.Context, required bool) Diagnosis {
+	status := DiagnosisWarn
+	if required {
+		status = DiagnosisFail
+	}
+	return diagnoseCommand(ctx, "git", "git --version", status, "Install git from https://git-scm.com/downloads")
+}
+
+func diagnosePowerShell() Diagnosis {
+	for _, interpreter := range powerShellInterpreters {
+		if found, err := exec.LookPath(interpreter); err == nil {
+			return Diagnosis{Check: "powershell", Status: DiagnosisOK, Detail: found}
+		}
+	}
+	return Diagnosis{
+		Check:       "powershell",
+		Status:      DiagnosisFail,
+		Detail:      "pwsh or powershell.exe not found on PATH",
+		Remediation: "Install PowerShell from https://aka.ms/powershell",
+	}
+}
+
+// diagnosePath checks that name is available on PATH. status is the status
+// if it is not.
+func diagnosePath(name, status, remediation string) Diagnosis {
+	found, err := exec.LookPath(name)
+	if err != nil {
+		return Diagnosis{Check: name, Status: status, Detail: "not found on PATH", Remediation: remediation}
+	}
+	return Diagnosis{Check: name, Status: DiagnosisOK, Detail: found}
+}
+
+// diagnoseCommand checks that name is available on PATH and reports its
+// version printed by command. status is the status if it is not available.
+func diagnoseCommand(ctx context.Context, name, command, status, remediation string) Diagnosis {
+	diagnosis := diagnosePath(name, status, remediation)
+	if diagnosis.Status != DiagnosisOK {
+		return diagnosis
+	}
+	output, err := exec.CommandContext(ctx, "sh", "-c", command).CombinedOutput()
+	if err != nil {
+		return Diagnosis{
+			Check:       name,
+			Status:      status,
+			Detail:      fmt.Sprintf("`%s` failed: %v: %s", command, err, strings.TrimSpace(string(output))),
+			Remediation: remediation,
+		}
+	}
+	version := extractVersion(defaultVersionPattern, string(output))
+	if version == "" {
+		version = strings.TrimSpace(string(output))
+	}
+	diagnosis.Detail = version
+	return diagnosis
+}
+
+// diagnoseTools checks the tools required by actions once each.
+func diagnoseTools(ctx context.Context, tools []config.ShuttleToolRequirement) []Diagnosis {
+	var diagnoses []Diagnosis
+	seen := make(map[string]bool)
+	for _, tool := range tools {
+		key := tool.Name + " " + tool.Version
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		diagnoses = append(diagnoses, diagnoseTool(ctx, tool))
+	}
+	return diagnoses
+}
+
+func diagnoseTool(ctx context.Context, tool config.ShuttleToolRequirement) Diagnosis {
+	check := strings.TrimSpace(tool.Name + " " + tool.Version)
+	remediation := fmt.Sprintf("Install %s and add it to PATH", check)
+	fail := func(detail, remediation string) Diagnosis {
+		return Diagnosis{Check: check, Status: DiagnosisFail, Detail: detail, Remediation: remediation}
+	}
+	diagnosis := diagnosePath(tool.Name, DiagnosisFail, remediation)
+	diagnosis.Check = check
+	if diagnosis.Status != DiagnosisOK || tool.Version == "" {
+		return diagnosis
+	}
+
+	constraint, err := semver.NewConstraint(tool.Version)
+	if err != nil {
+		return fail(fmt.Sprintf("invalid version constraint: %v", err), "Fix the version constraint of the tool in the plan")
+	}
+	pattern := defaultVersionPattern
+	if tool.Pattern != "" {
+		pattern, err = regexp.Compile(tool.Pattern)
+		if err != nil {
+			return fail(fmt.Sprintf("invalid pattern: %v", err), "Fix the version pattern of the tool in the plan")
+		}
+	}
+	command := versionCommand(tool)
+	output, err := exec.CommandContext(ctx, "sh", "-c", command).CombinedOutput()
+	if err != nil {
+		return fail(fmt.Sprintf("`%s` failed: %v", command, err), remediation)
+	}
+	version, err := semver.NewVersion(extractVersion(pattern, string(output)))
+	if err != nil {
+		return fail(fmt.Sprintf("no version found in output of `%s`", command), remediation)
+	}
+	if !constraint.Check(version) {
+		return fail(fmt.Sprintf("found version %s", version), remediation)
+	}
+	return Diagnosis{Check: check, Status: DiagnosisOK, Detail: version.String()}
+}
+
+// diagnosePlan checks that the repository of the git plan can be reached.
+func diagnosePlan(ctx context.Context, plan string) Diagnosis {
+	ctx, cancel := context.WithTimeout(ctx, planReachableTimeout)
+	defer cancel()
+	err := git.Reachable(ctx, plan)
+	if err != nil {
+		remediation := "Check your network connection and SSH access to the repository, eg. with ssh -T"
+		if parsedPlan := git.ParsePlan(plan); parsedPlan.Protocol == "https" {
+			remediation = fmt.Sprintf(
+				"Check your network connection and that the credentials shown by shuttle auth status %s grant access to the repository",
+				credentials.Host(parsedPlan.Repository),
+			)
+		}
+		return Diagnosis{
+			Check:       "plan",
+			Status:      DiagnosisFail,
+			Detail:      fmt.Sprintf("%s is not reachable: %v", plan, err),
+			Remediation: remediation,
+		}
+	}
+	return Diagnosis{Check: "plan", Status: DiagnosisOK, Detail: fmt.Sprintf("%s is reachable", plan)}
+}
+
+// diagnoseShuttleDirectory checks that plans, caches and binaries can be
+// written to the .shuttle directory.
+func diagnoseShuttleDirectory(dir string) Diagnosis {
+	remediation := fmt.Sprintf("Make %s writable by the current user or remove it to have shuttle create it again", dir)
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err == nil {
+		var file *os.File
+		file, err = os.CreateTemp(dir, "doctor-")
+		if err == nil {
+			file.Close()
+			err = os.Remove(file.Name())
+		}
+	}
+	if err != nil {
+		return Diagnosis{Check: ".shuttle", Status: DiagnosisFail, Detail: fmt.Sprintf("not writable: %v", err), Remediation: remediation}
+	}
+	return Diagnosis{Check: ".shuttle", Status: DiagnosisOK, Detail: "writable"}
+}
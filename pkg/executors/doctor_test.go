@@ -0,0 +1,126 @@
+package executors
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+)
+
+func TestDiagnose(t *testing.T) {
+	fakeTool(t, "git", "git version 2.43.0")
+	fakeTool(t, "docker", "24.0.7")
+	fakeTool(t, "kubectl", "Client Version: v1.27.3")
+
+	project := config.ShuttleProjectContext{
+		LocalShuttleDirectoryPath: filepath.Join(t.TempDir(), ".shuttle"),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"build": {Actions: []config.ShuttleAction{
+				{Shell: "make", Docker: &config.ShuttleDocker{Image: "golang"}},
+			}},
+			"deploy": {Actions: []config.ShuttleAction{
+				{Shell: "./deploy.sh", Tools: []config.ShuttleToolRequirement{
+					{Name: "kubectl", Version: ">= 1.28"},
+					{Name: "shuttle-missing-tool"},
+				}},
+				{Shell: "./verify.sh", Tools: []config.ShuttleToolRequirement{
+					{Name: "kubectl", Version: ">= 1.28"},
+				}},
+			}},
+		},
+	}
+
+	diagnoses := Diagnose(context.Background(), project)
+
+	var checks []string
+	for _, diagnosis := range diagnoses {
+		checks = append(checks, diagnosis.Check)
+	}
+	assert.Equal(t, []string{"sh", "git", "docker", "kubectl >= 1.28", "shuttle-missing-tool", ".shuttle"}, checks)
+	assert.Equal(t, DiagnosisOK, diagnoses[0].Status)
+	assert.Equal(t, Diagnosis{Check: "git", Status: DiagnosisOK, Detail: "2.43.0"}, diagnoses[1])
+	assert.Equal(t, Diagnosis{Check: "docker", Status: DiagnosisOK, Detail: "24.0.7"}, diagnoses[2])
+	assert.Equal(t, Diagnosis{
+		Check:       "kubectl >= 1.28",
+		Status:      DiagnosisFail,
+		Detail:      "found version 1.27.3",
+		Remediation: "Install kubectl >= 1.28 and add it to PATH",
+	}, diagnoses[3])
+	assert.Equal(t, Diagnosis{
+		Check:       "shuttle-missing-tool",
+		Status:      DiagnosisFail,
+		Detail:      "not found on PATH",
+		Remediation: "Install shuttle-missing-tool and add it to PATH",
+	}, diagnoses[4])
+	assert.Equal(t, Diagnosis{Check: ".shuttle", Status: DiagnosisOK, Detail: "writable"}, diagnoses[5])
+}
+
+func TestDiagnose_windows(t *testing.T) {
+	defer func(previous string) { goos = previous }(goos)
+	goos = "windows"
+	fakeTool(t, "bash", "")
+
+	diagnoses := Diagnose(context.Background(), config.ShuttleProjectContext{
+		LocalShuttleDirectoryPath: filepath.Join(t.TempDir(), ".shuttle"),
+	})
+
+	statuses := make(map[string]string)
+	for _, diagnosis := range diagnoses {
+		statuses[diagnosis.Check] = diagnosis.Status
+	}
+	assert.Equal(t, DiagnosisOK, statuses["sh"])
+	assert.Equal(t, DiagnosisOK, statuses["bash"])
+	assert.Equal(t, DiagnosisFail, statuses["cygpath"])
+}
+
+func TestDiagnose_windowsNativeShells(t *testing.T) {
+	defer func(previous string) { goos = previous }(goos)
+	goos = "windows"
+	fakeTool(t, "cmd.exe", "")
+	fakeTool(t, "pwsh", "")
+
+	diagnoses := Diagnose(context.Background(), config.ShuttleProjectContext{
+		LocalShuttleDirectoryPath: filepath.Join(t.TempDir(), ".shuttle"),
+		Config:                    config.ShuttleConfig{Shell: config.ShellCmd},
+		Scripts: map[string]config.ShuttlePlanScript{
+			"build": {Actions: []config.ShuttleAction{{Shell: "build.cmd"}}},
+			"test":  {Shell: config.ShellPwsh, Actions: []config.ShuttleAction{{Shell: "./test.ps1"}}},
+		},
+	})
+
+	statuses := make(map[string]string)
+	for _, diagnosis := range diagnoses {
+		statuses[diagnosis.Check] = diagnosis.Status
+	}
+	assert.NotContains(t, statuses, "cygpath", "Git Bash is not needed")
+	assert.Equal(t, DiagnosisOK, statuses["cmd.exe"])
+	assert.Equal(t, DiagnosisOK, statuses["powershell"])
+}
+
+func TestDiagnoseShuttleDirectory(t *testing.T) {
+	t.Run("writable", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), ".shuttle")
+
+		diagnosis := diagnoseShuttleDirectory(dir)
+
+		assert.Equal(t, DiagnosisOK, diagnosis.Status)
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries, "no files are left behind")
+	})
+
+	t.Run("not writable", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(file, nil, 0o644))
+
+		diagnosis := diagnoseShuttleDirectory(filepath.Join(file, ".shuttle"))
+
+		assert.Equal(t, DiagnosisFail, diagnosis.Status)
+		assert.Contains(t, diagnosis.Detail, "not writable")
+	})
+}
@@ -0,0 +1,93 @@
+package executors
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// WithDryRun prints the commands of actions with their environment instead of
+// running them.
+func WithDryRun(dryRun bool) ExecuteOption {
+	return func(c *ScriptExecutionContext) {
+		c.DryRun = dryRun
+	}
+}
+
+// redactedValue replaces values that may be secrets in dry run output
+const redactedValue = "[redacted]"
+
+// secretVariableName matches names of environment variables commonly holding
+// secrets
+var secretVariableName = regexp.MustCompile(`(?i)(secret|token|passw(or)?d|credential|api_?key|private_?key)`)
+
+// printDryRun prints the command of the action and the environment variables
+// set by shuttle, ie. those not inherited unchanged from the environment of
+// shuttle itself. Values that may be secrets are redacted.
+func printDryRun(context ActionExecutionContext, cmdArgs []string, env []string) {
+	ui := context.ScriptContext.Project.UI
+	allow := context.ScriptContext.SecretDetection.Allow
+	masker := newSecretMasker(context.ScriptContext)
+
+	var variables []string
+	for _, entry := range shuttleVariables(env) {
+		name, value, _ := strings.Cut(entry, "=")
+		if secretVariableName.MatchString(name) {
+			value = redactedValue
+		}
+		variables = append(variables, name+"="+redactSecrets(allow, masker.Mask(value)))
+	}
+
+	ui.Output("Dry run of action %d of script `%s`:", context.ActionIndex, context.ScriptContext.ScriptName)
+	ui.Output("  command: %s", redactSecrets(allow, masker.Mask(strings.Join(cmdArgs, " "))))
+	for _, variable := range variables {
+		ui.Output("  env: %s", variable)
+	}
+}
+
+// shuttleVariables returns the entries of env set by shuttle, ie. those not
+// inherited unchanged from the environment of shuttle itself, sorted by name.
+// Later entries take precedence so only the last of each name is returned.
+func shuttleVariables(env []string) []string {
+	inherited := make(map[string]bool)
+	for _, entry := range os.Environ() {
+		inherited[entry] = true
+	}
+	var variables []string
+	seen := make(map[string]bool)
+	for i := len(env) - 1; i >= 0; i-- {
+		name, _, _ := strings.Cut(env[i], "=")
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if inherited[env[i]] {
+			continue
+		}
+		variables = append(variables, env[i])
+	}
+	sort.Strings(variables)
+	return variables
+}
+
+// redactSecrets replaces parts of s detected as possible secrets like secret
+// detection of action output does. Matches of allow are kept.
+func redactSecrets(allow []*regexp.Regexp, s string) string {
+	scanner := &secretScanner{detection: SecretDetection{Allow: allow}}
+	redact := func(match string) string {
+		if scanner.allowed(match) {
+			return match
+		}
+		return redactedValue
+	}
+	for _, p := range secretPatterns {
+		s = p.pattern.ReplaceAllStringFunc(s, redact)
+	}
+	return entropyTokenRegexp.ReplaceAllStringFunc(s, func(token string) string {
+		if shannonEntropy(token) < minTokenEntropy {
+			return token
+		}
+		return redact(token)
+	})
+}
@@ -0,0 +1,51 @@
+package executors
+
+import (
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+const (
+	encodingUTF8 = "utf-8"
+	encodingRaw  = "raw"
+)
+
+// outputDecoder converts a line of output from an action into a string
+// suitable for display in the UI.
+type outputDecoder func(line string) string
+
+// newOutputDecoder returns an outputDecoder for the named encoding.
+//
+// An empty encoding is treated as UTF-8 where invalid byte sequences are
+// replaced with the Unicode replacement character. The "raw" encoding passes
+// output through untouched. Any other name is looked up in the WHATWG
+// encoding index, eg. "windows-1252" or "iso-8859-1".
+func newOutputDecoder(encoding string) (outputDecoder, error) {
+	name := strings.ToLower(strings.TrimSpace(encoding))
+	switch name {
+	case "", encodingUTF8, "utf8":
+		return func(line string) string {
+			return strings.ToValidUTF8(line, "�")
+		}, nil
+	case encodingRaw:
+		return func(line string) string {
+			return line
+		}, nil
+	}
+
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, errors.NewExitCode(2, "Unsupported output encoding '%s': %v", encoding, err)
+	}
+	decoder := enc.NewDecoder()
+	return func(line string) string {
+		decoded, err := decoder.String(line)
+		if err != nil {
+			return strings.ToValidUTF8(line, "�")
+		}
+		return decoded
+	}, nil
+}
@@ -0,0 +1,64 @@
+package executors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOutputDecoder(t *testing.T) {
+	tt := []struct {
+		name     string
+		encoding string
+		input    string
+		output   string
+		err      string
+	}{
+		{
+			name:     "default utf-8",
+			encoding: "",
+			input:    "hello wörld",
+			output:   "hello wörld",
+		},
+		{
+			name:     "invalid utf-8 is replaced",
+			encoding: "utf-8",
+			input:    "caf\xe9",
+			output:   "caf�",
+		},
+		{
+			name:     "raw is untouched",
+			encoding: "raw",
+			input:    "caf\xe9",
+			output:   "caf\xe9",
+		},
+		{
+			name:     "windows-1252",
+			encoding: "windows-1252",
+			input:    "caf\xe9 \x80",
+			output:   "café €",
+		},
+		{
+			name:     "case insensitive name",
+			encoding: "CP1252",
+			input:    "caf\xe9",
+			output:   "café",
+		},
+		{
+			name:     "unknown encoding",
+			encoding: "not-an-encoding",
+			err:      "exit code 2 - Unsupported output encoding 'not-an-encoding': htmlindex: invalid encoding name",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			decode, err := newOutputDecoder(tc.encoding)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.output, decode(tc.input))
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package executors
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// envFileEnvironment returns the variables of the env files of the plan and
+// the action on the form name=value. Variables of the action env file take
+// precedence over those of the plan.
+func envFileEnvironment(context ActionExecutionContext) ([]string, error) {
+	project := context.ScriptContext.Project
+	var env []string
+	for _, envFile := range []string{project.Plan.EnvFile, context.Action.EnvFile} {
+		if envFile == "" {
+			continue
+		}
+		envFilePath := envFile
+		if !filepath.IsAbs(envFilePath) {
+			envFilePath = filepath.Join(project.ProjectPath, envFilePath)
+		}
+		variables, err := readEnvFile(envFilePath)
+		if os.IsNotExist(err) {
+			return nil, errors.NewExitCode(
+				1,
+				"Env file '%s' of script `%s` was not found at %s",
+				envFile,
+				context.ScriptContext.ScriptName,
+				envFilePath,
+			)
+		}
+		if err != nil {
+			return nil, errors.NewExitCode(
+				1,
+				"Env file '%s' of script `%s` is invalid: %v",
+				envFile,
+				context.ScriptContext.ScriptName,
+				err,
+			)
+		}
+		for _, variable := range variables {
+			value := variable.value
+			if !nativePaths(context) {
+				value = projectShellPath(project.ProjectPath, value)
+			}
+			env = append(env, fmt.Sprintf("%s=%s", variable.name, value))
+		}
+	}
+	return env, nil
+}
+
+// projectShellPath converts value with shellPath if it is a path within
+// projectPath. Other values are returned as is.
+func projectShellPath(projectPath, value string) string {
+	if projectPath == "" || !strings.HasPrefix(value, projectPath) {
+		return value
+	}
+	return shellPath(value)
+}
+
+type envFileVariable struct {
+	name  string
+	value string
+}
+
+// readEnvFile reads the variables of the dotenv file at path in the order
+// they are defined.
+func readEnvFile(path string) ([]envFileVariable, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseEnvFile(content)
+}
+
+// parseEnvFile parses variables on the form NAME=value, optionally prefixed
+// by export. Values can be single quoted, taken literally, or double quoted
+// with \n, \", \\ and \$ escapes. Blank lines and lines starting with # are
+// ignored as are comments after unquoted values.
+func parseEnvFile(content []byte) ([]envFileVariable, error) {
+	var variables []envFileVariable
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		name, rawValue, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected NAME=value", lineNumber)
+		}
+		name = strings.TrimSpace(name)
+		if !validVariableName.MatchString(name) {
+			return nil, fmt.Errorf("line %d: '%s' is not a valid variable name", lineNumber, name)
+		}
+		value, err := parseEnvFileValue(strings.TrimSpace(rawValue))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		variables = append(variables, envFileVariable{name: name, value: value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return variables, nil
+}
+
+func parseEnvFileValue(raw string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+	switch raw[0] {
+	case '\'':
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single quoted value")
+		}
+		if err := trailingComment(raw[end+2:]); err != nil {
+			return "", err
+		}
+		return raw[1 : end+1], nil
+	case '"':
+		var b strings.Builder
+		for i := 1; i < len(raw); i++ {
+			c := raw[i]
+			switch {
+			case c == '"':
+				if err := trailingComment(raw[i+1:]); err != nil {
+					return "", err
+				}
+				return b.String(), nil
+			case c == '\\' && i+1 < len(raw):
+				i++
+				switch raw[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case '"', '\\', '$':
+					b.WriteByte(raw[i])
+				default:
+					b.WriteByte('\\')
+					b.WriteByte(raw[i])
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", fmt.Errorf("unterminated double quoted value")
+	default:
+		if comment := strings.Index(raw, " #"); comment >= 0 {
+			raw = raw[:comment]
+		}
+		return strings.TrimSpace(raw), nil
+	}
+}
+
+// trailingComment returns an error unless rest, what follows a quoted value,
+// is empty or a comment.
+func trailingComment(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest == "" || strings.HasPrefix(rest, "#") {
+		return nil
+	}
+	return fmt.Errorf("unexpected '%s' after quoted value", rest)
+}
@@ -0,0 +1,153 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	tt := []struct {
+		name      string
+		content   string
+		variables []envFileVariable
+		errorMsg  string
+	}{
+		{
+			name: "values",
+			content: `# comment
+PLAIN=value
+export EXPORTED=exported
+
+SPACED = spaced value # trailing comment
+SINGLE='single $HOME # kept'
+DOUBLE="double \"quoted\"\nline" # comment
+EMPTY=
+`,
+			variables: []envFileVariable{
+				{name: "PLAIN", value: "value"},
+				{name: "EXPORTED", value: "exported"},
+				{name: "SPACED", value: "spaced value"},
+				{name: "SINGLE", value: "single $HOME # kept"},
+				{name: "DOUBLE", value: "double \"quoted\"\nline"},
+				{name: "EMPTY", value: ""},
+			},
+		},
+		{
+			name:     "missing equals",
+			content:  "A=1\nMALFORMED\n",
+			errorMsg: "line 2: expected NAME=value",
+		},
+		{
+			name:     "invalid name",
+			content:  "MY-VAR=1\n",
+			errorMsg: "line 1: 'MY-VAR' is not a valid variable name",
+		},
+		{
+			name:     "unterminated single quote",
+			content:  "A='value\n",
+			errorMsg: "line 1: unterminated single quoted value",
+		},
+		{
+			name:     "unterminated double quote",
+			content:  `A="value`,
+			errorMsg: "line 1: unterminated double quoted value",
+		},
+		{
+			name:     "text after quoted value",
+			content:  `A="value" more`,
+			errorMsg: "line 1: unexpected 'more' after quoted value",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			variables, err := parseEnvFile([]byte(tc.content))
+			if tc.errorMsg != "" {
+				assert.EqualError(t, err, tc.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.variables, variables)
+		})
+	}
+}
+
+func TestExecute_envFile(t *testing.T) {
+	projectPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(projectPath, "plan.env"), []byte("FROM_PLAN=plan\nOVERRIDDEN=plan\nname=plan\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(projectPath, "action.env"), []byte("OVERRIDDEN=action\nname=action\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(projectPath, "invalid.env"), []byte("MALFORMED\n"), 0o644))
+
+	tt := []struct {
+		name    string
+		envFile string
+		stdout  string
+		err     string
+	}{
+		{
+			name:    "precedence",
+			envFile: "action.env",
+			stdout:  "plan action arg\n",
+		},
+		{
+			name:   "plan only",
+			stdout: "plan plan arg\n",
+		},
+		{
+			name:    "missing",
+			envFile: "missing.env",
+			err:     "exit code 1 - Env file 'missing.env' of script `test` was not found at " + filepath.Join(projectPath, "missing.env"),
+		},
+		{
+			name:    "malformed",
+			envFile: "invalid.env",
+			err:     "exit code 1 - Env file 'invalid.env' of script `test` is invalid: line 1: expected NAME=value",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: projectPath,
+				UI:          ui.Create(stdout, &bytes.Buffer{}),
+				Plan: config.ShuttlePlanConfiguration{
+					EnvFile: "plan.env",
+				},
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Args: []config.ShuttleScriptArgs{{Name: "name"}},
+						Actions: []config.ShuttleAction{
+							{Shell: `echo "$FROM_PLAN $OVERRIDDEN $name"`, EnvFile: tc.envFile},
+						},
+					},
+				},
+			}, "test", map[string]string{"name": "arg"}, true)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.stdout, stdout.String())
+		})
+	}
+}
+
+func TestProjectShellPath(t *testing.T) {
+	defer func(previous string) { goos = previous }(goos)
+	goos = "windows"
+
+	assert.Equal(t, "/c/src/app/bin", projectShellPath(`C:\src\app`, `C:\src\app\bin`))
+	assert.Equal(t, `D:\tools`, projectShellPath(`C:\src\app`, `D:\tools`))
+	assert.Equal(t, "value", projectShellPath(`C:\src\app`, "value"))
+}
@@ -2,12 +2,19 @@ package executors
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
+	"io"
+	"os"
+	"path"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/lunarway/shuttle/pkg/config"
 	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/telemetry"
 	"github.com/lunarway/shuttle/pkg/ui"
 )
 
@@ -26,12 +33,112 @@ func NewRegistry(executors ...Matcher) *Registry {
 	}
 }
 
+// NewDefaultRegistry returns a registry of every executor of shuttle.
+func NewDefaultRegistry() *Registry {
+	return NewRegistry(DockerExecutor, ShellExecutor, PowerShellExecutor, TaskExecutor, WasmExecutor)
+}
+
 // ScriptExecutionContext gives context to the execution of a plan script
 type ScriptExecutionContext struct {
 	ScriptName string
 	Script     config.ShuttlePlanScript
 	Project    config.ShuttleProjectContext
 	Args       map[string]string
+	// SelectedScripts are the names of all scripts executed in this invocation
+	SelectedScripts []string
+	// CleanTmp removes the temporary directory of each action once it
+	// completes unless the action is marked to keep it
+	CleanTmp bool
+	// ConfirmRerun is called before running actions that are not idempotent
+	// when the script is re-run. If nil the script is not a re-run.
+	ConfirmRerun RerunConfirmer
+	// Summary records the outcome of the script if set
+	Summary *RunSummary
+	// SecretDetection configures scanning of action output for secrets
+	SecretDetection SecretDetection
+	// Interactive allows prompting the user, eg. for a sudo password
+	Interactive bool
+	// Outputs are the variables captured from the output of actions with
+	// captureOutput or written to SHUTTLE_OUTPUT. They are shared by all
+	// actions of the script and the scripts needing it.
+	Outputs map[string]string
+	// Secrets are the resolved values of the secrets of the project. They
+	// are environment variables of all actions and masked in their output.
+	Secrets map[string]string
+	// MaskedEnv are patterns of names of environment variables of shuttle
+	// whose values are masked in the output of actions
+	MaskedEnv []string
+	// DryRun prints the commands of actions instead of running them
+	DryRun bool
+	// Stdin is connected to the stdin of shell actions if set
+	Stdin io.Reader
+	// PrefixOutput prefixes every output line of shell actions with the
+	// action producing it
+	PrefixOutput bool
+	// LogFile is a file the output of shell actions is written to besides
+	// the terminal if set. It may contain {script} and {action} placeholders.
+	LogFile string
+	// PreserveExitCode fails shell actions with the exit code of their script
+	// instead of 4
+	PreserveExitCode bool
+	// NoCache runs scripts with inputs even if their inputs are unchanged
+	NoCache bool
+	// NoWaitLock fails the script if its lock is held by another run instead
+	// of waiting for it
+	NoWaitLock bool
+	// RunLog records the commands and output of shell actions if set
+	RunLog *RunLog
+	// RunMetadata describes the invocation of shuttle in the context files of
+	// actions
+	RunMetadata RunMetadata
+	// logFiles are the log files opened by the run
+	logFiles *logFiles
+}
+
+// RerunConfirmer confirms that an action that is not idempotent may be run
+// again.
+type RerunConfirmer func(ActionExecutionContext) (bool, error)
+
+// ExecuteOption configures the execution of a script
+type ExecuteOption func(*ScriptExecutionContext)
+
+// WithCleanTmp removes the temporary directory of actions after they complete
+// unless they are configured with keepTmp.
+func WithCleanTmp(clean bool) ExecuteOption {
+	return func(c *ScriptExecutionContext) {
+		c.CleanTmp = clean
+	}
+}
+
+// WithInteractive allows actions to prompt the user for input.
+func WithInteractive(interactive bool) ExecuteOption {
+	return func(c *ScriptExecutionContext) {
+		c.Interactive = interactive
+	}
+}
+
+// WithOutputPrefix prefixes every output line of shell actions with the script
+// and index of the action, eg. [build/0], or the label of the action.
+func WithOutputPrefix(prefix bool) ExecuteOption {
+	return func(c *ScriptExecutionContext) {
+		c.PrefixOutput = prefix
+	}
+}
+
+// WithPreserveExitCode fails shell actions with the exit code of their script
+// instead of 4 such that shuttle exits with it.
+func WithPreserveExitCode(preserve bool) ExecuteOption {
+	return func(c *ScriptExecutionContext) {
+		c.PreserveExitCode = preserve
+	}
+}
+
+// WithRerun marks the execution as a re-run of a previous execution. Actions
+// that are not idempotent are only run if confirm returns true.
+func WithRerun(confirm RerunConfirmer) ExecuteOption {
+	return func(c *ScriptExecutionContext) {
+		c.ConfirmRerun = confirm
+	}
 }
 
 // ActionExecutionContext gives context to the execution of Actions in a script
@@ -39,6 +146,36 @@ type ActionExecutionContext struct {
 	ScriptContext ScriptExecutionContext
 	Action        config.ShuttleAction
 	ActionIndex   int
+	// output keeps the last output lines of the action for the run summary
+	output *outputTail
+	// outputFile is the file the action writes its outputs to if any
+	outputFile string
+	// contextFile is the file describing the run to the action if any
+	contextFile string
+}
+
+// TempDirectoryPath returns the temporary directory scoped to the action. If
+// the project has no temporary directory an empty string is returned.
+func (c ActionExecutionContext) TempDirectoryPath() string {
+	if c.ScriptContext.Project.TempDirectoryPath == "" {
+		return ""
+	}
+	return path.Join(
+		c.ScriptContext.Project.TempDirectoryPath,
+		"actions",
+		c.ScriptContext.ScriptName,
+		strconv.Itoa(c.ActionIndex),
+	)
+}
+
+// PlanPath returns the local directory of the plan defining the script of the
+// action, ie. the overlay plan it is defined in or the first plan of the
+// project.
+func (c ActionExecutionContext) PlanPath() string {
+	if c.ScriptContext.Script.PlanPath != "" {
+		return c.ScriptContext.Script.PlanPath
+	}
+	return c.ScriptContext.Project.LocalPlanPath
 }
 
 // Execute is the command executor for the plan files
@@ -48,71 +185,513 @@ func (r *Registry) Execute(
 	command string,
 	args map[string]string,
 	validateArgs bool,
+	options ...ExecuteOption,
 ) error {
-	script, ok := p.Scripts[command]
-	if !ok {
+	if _, ok := p.Scripts[command]; !ok {
 		return errors.NewExitCode(2, "Script '%s' not found", command)
 	}
+	args, err := resolveArgs(p, command, args, validateArgs)
+	if err != nil {
+		return err
+	}
+	prerequisites, err := scriptPrerequisites(p.Scripts, command)
+	if err != nil {
+		return err
+	}
+	run := scriptRun{
+		selected:  append(prerequisites, command),
+		logFiles:  newLogFiles(),
+		outputs:   map[string]string{},
+		secrets:   &runSecrets{},
+		startedAt: time.Now(),
+	}
+	hooks := p.Hooks()
+	if hooks.IsEmpty() {
+		return r.executeRun(ctx, p, command, args, prerequisites, run, options...)
+	}
+	return r.executeWithHooks(ctx, p, hooks, command, args, run, func(ctx context.Context) error {
+		return r.executeRun(ctx, p, command, args, prerequisites, run, options...)
+	}, options...)
+}
+
+// Run runs script command like shuttle run. The requirements of the plan are
+// checked against the shuttle version of WithRunMetadata and the guards of the
+// project are run before the script is executed.
+func (r *Registry) Run(
+	ctx context.Context,
+	p config.ShuttleProjectContext,
+	command string,
+	args map[string]string,
+	validateArgs bool,
+	options ...ExecuteOption,
+) error {
+	var scriptContext ScriptExecutionContext
+	for _, option := range options {
+		option(&scriptContext)
+	}
+	err := CheckRequirements(ctx, p, scriptContext.RunMetadata.ShuttleVersion)
+	if err != nil {
+		return err
+	}
+	err = RunGuards(ctx, p, command, args, options...)
+	if err != nil {
+		return err
+	}
+	return r.Execute(ctx, p, command, args, validateArgs, options...)
+}
 
+// executeRun executes the prerequisites of script command followed by the
+// script itself as part of run.
+func (r *Registry) executeRun(
+	ctx context.Context,
+	p config.ShuttleProjectContext,
+	command string,
+	args map[string]string,
+	prerequisites []string,
+	run scriptRun,
+	options ...ExecuteOption,
+) error {
+	if len(prerequisites) == 0 {
+		return r.executeScript(ctx, p, command, args, run, options...)
+	}
+
+	// fail fast on misconfigured actions of any of the scripts rather than
+	// after running the prerequisites
+	if err := r.validateActions(p, false, run.selected...); err != nil {
+		return err
+	}
+	for _, prerequisite := range prerequisites {
+		prerequisiteArgs, err := neededScriptArgs(p, prerequisite, command, args)
+		if err != nil {
+			return err
+		}
+		prerequisiteArgs = p.Scripts[prerequisite].ArgsWithDefaults(prerequisiteArgs)
+		p.UI.Infoln("Running script '%s' needed by script '%s'", prerequisite, command)
+		prerequisiteRun := run
+		prerequisiteRun.prerequisite = true
+		err = r.executeScript(ctx, p, prerequisite, prerequisiteArgs, prerequisiteRun, options...)
+		if err != nil {
+			return err
+		}
+	}
+	return r.executeScript(ctx, p, command, args, run, options...)
+}
+
+// scriptRun describes the invocation a script is executed in
+type scriptRun struct {
+	// selected are the names of all scripts executed in the invocation
+	selected []string
+	// prerequisite is set for scripts needed by the invoked script. They are
+	// not recorded in the summary of the run.
+	prerequisite bool
+	// logFiles are shared by all scripts of the invocation
+	logFiles *logFiles
+	// outputs are shared by all scripts of the invocation such that scripts
+	// can use the outputs of the scripts they need
+	outputs map[string]string
+	// secrets are resolved once for all scripts of the invocation
+	secrets *runSecrets
+	// startedAt is when the invocation started
+	startedAt time.Time
+}
+
+// executeScript executes the actions of script command as part of run.
+func (r *Registry) executeScript(
+	ctx context.Context,
+	p config.ShuttleProjectContext,
+	command string,
+	args map[string]string,
+	run scriptRun,
+	options ...ExecuteOption,
+) (err error) {
+	script := p.Scripts[command]
 	scriptContext := ScriptExecutionContext{
-		ScriptName: command,
-		Script:     script,
-		Project:    p,
-		Args:       args,
+		ScriptName:      command,
+		Script:          script,
+		Project:         p,
+		Args:            args,
+		SelectedScripts: run.selected,
+		Outputs:         run.outputs,
+		logFiles:        run.logFiles,
+	}
+	for _, option := range options {
+		option(&scriptContext)
+	}
+	if scriptContext.RunMetadata.StartedAt.IsZero() {
+		scriptContext.RunMetadata.StartedAt = run.startedAt
+	}
+	if run.prerequisite {
+		scriptContext.Summary = nil
+	}
+
+	summary := scriptContext.Summary
+	if summary != nil {
+		summary.RunID = telemetry.RunIDFrom(ctx)
+		summary.Script = command
+		summary.Actions = nil
+	}
+	p.UI.ScriptStarted(command)
+	scriptContext.RunLog.Printf("script %s started", command)
+	start := time.Now()
+	ctx, endSpan := telemetry.StartSpan(ctx, "shuttle.script", map[string]string{
+		telemetry.TelemetryScript: command,
+	})
+	defer func() {
+		endSpan(err)
+		duration := time.Since(start)
+		p.UI.ScriptExited(command, actionExitCode(err), duration)
+		scriptContext.RunLog.Printf("script %s exited with code %d after %s", command, actionExitCode(err), duration.Round(time.Millisecond))
+		if summary != nil {
+			summary.Duration = duration
+		}
+	}()
+
+	if script.Deprecated != "" {
+		p.UI.EmphasizeInfoln("Script '%s' is deprecated: %s", command, script.Deprecated)
+		telemetry.TraceDeprecation(ctx, command, scriptSource(p, command), script.Deprecated)
+	}
+
+	// fail fast on misconfigured actions rather than after running those
+	// before them
+	if err := actionProblemsError(r.validateScriptActions(scriptContext, false)); err != nil {
+		return err
+	}
+
+	enabled, err := scriptCondition(scriptContext)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		p.UI.Infoln("Skipped script `%s` as when '%s' is false", command, script.When)
+		for actionIndex, action := range script.Actions {
+			summary.skipAction(actionIndex, action)
+		}
+		return nil
+	}
+
+	cache, err := newScriptCache(scriptContext)
+	if err != nil {
+		return err
+	}
+	if cache != nil && cache.hit() {
+		if err := cache.restore(p.ProjectPath); err != nil {
+			return fmt.Errorf("restore cached outputs of script '%s': %w", command, err)
+		}
+		p.UI.Infoln("Skipped script `%s` as its inputs are unchanged since it last succeeded", command)
+		return nil
+	}
+
+	gracePeriod, err := alwaysGracePeriod(script)
+	if err != nil {
+		return err
+	}
+	unlock, err := acquireScriptLock(ctx, scriptContext)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	scriptContext.Secrets, err = run.secrets.resolve(ctx, scriptContext)
+	if err != nil {
+		return err
+	}
+
+	runErr := r.executeActions(ctx, scriptContext, runChecks(ctx, scriptContext), gracePeriod)
+	if runErr == nil && cache != nil {
+		if err := cache.store(scriptContext); err != nil {
+			p.UI.EmphasizeInfoln("Failed to cache script `%s`: %v", command, err)
+		}
 	}
+	return runErr
+}
 
-	for actionIndex, action := range script.Actions {
-		actionContext := ActionExecutionContext{
-			ScriptContext: scriptContext,
-			Action:        action,
-			ActionIndex:   actionIndex,
+// executeActions executes the actions of the script in order with parallel
+// actions run together. runErr is the first failure of the run. Once set
+// remaining actions are skipped except those marked always. The first failure
+// is returned.
+func (r *Registry) executeActions(
+	ctx context.Context,
+	scriptContext ScriptExecutionContext,
+	runErr error,
+	gracePeriod time.Duration,
+) error {
+	for _, group := range actionGroups(scriptContext.Script.Actions) {
+		if runErr == nil && ctx.Err() != nil {
+			runErr = errors.NewCancellation(ctx)
 		}
-		err := r.executeAction(ctx, p.UI, actionContext)
+		if len(group) > 1 {
+			err := r.executeParallelActions(ctx, scriptContext, group, runErr != nil, gracePeriod)
+			if runErr == nil {
+				runErr = err
+			}
+			continue
+		}
+
+		actionIndex := group[0]
+		err := r.executeScriptAction(ctx, scriptContext, actionIndex, runErr != nil, gracePeriod)
+		if err == nil {
+			continue
+		}
+		if runErr != nil {
+			// a failing always action must not mask the primary failure
+			scriptContext.Project.UI.Errorln("Always action %d of script '%s' failed: %v", actionIndex, scriptContext.ScriptName, err)
+			continue
+		}
+		runErr = err
+	}
+	return runErr
+}
+
+// executeScriptAction executes the action at actionIndex of the script. It is
+// skipped if it is not an always action and failed is set as an earlier action
+// failed or if its condition is false.
+func (r *Registry) executeScriptAction(
+	ctx context.Context,
+	scriptContext ScriptExecutionContext,
+	actionIndex int,
+	failed bool,
+	gracePeriod time.Duration,
+) error {
+	action := scriptContext.Script.Actions[actionIndex]
+	summary := scriptContext.Summary
+	if failed && !action.Always {
+		summary.skipAction(actionIndex, action)
+		return nil
+	}
+
+	run, err := actionCondition(ActionExecutionContext{
+		ScriptContext: scriptContext,
+		Action:        action,
+		ActionIndex:   actionIndex,
+	})
+	if err != nil {
+		return err
+	}
+	if !run {
+		scriptContext.Project.UI.Infoln("Skipped action %d of script `%s` as when '%s' is false", actionIndex, scriptContext.ScriptName, action.When)
+		summary.skipAction(actionIndex, action)
+		return nil
+	}
+	actionCtx, cancel := ctx, context.CancelFunc(func() {})
+	if ctx.Err() != nil {
+		// always actions get a grace period to run after cancellation
+		actionCtx, cancel = context.WithTimeout(context.WithoutCancel(ctx), gracePeriod)
+	}
+	defer cancel()
+	return r.executeRecordedAction(actionCtx, scriptContext, actionIndex, action)
+}
+
+// defaultAlwaysGracePeriod is how long always actions may run after a run is
+// cancelled.
+const defaultAlwaysGracePeriod = 30 * time.Second
+
+// alwaysGracePeriod returns how long always actions of script may run after
+// the run is cancelled. It can be changed with SHUTTLE_ALWAYS_GRACE_PERIOD.
+func alwaysGracePeriod(script config.ShuttlePlanScript) (time.Duration, error) {
+	raw := os.Getenv("SHUTTLE_ALWAYS_GRACE_PERIOD")
+	if raw == "" {
+		return defaultAlwaysGracePeriod, nil
+	}
+	hasAlways := false
+	for _, action := range script.Actions {
+		hasAlways = hasAlways || action.Always
+	}
+	if !hasAlways {
+		return defaultAlwaysGracePeriod, nil
+	}
+	gracePeriod, err := time.ParseDuration(raw)
+	if err != nil || gracePeriod <= 0 {
+		return 0, errors.NewExitCode(
+			1,
+			"SHUTTLE_ALWAYS_GRACE_PERIOD value '%s' is invalid: must be a positive duration, eg. 30s",
+			raw,
+		)
+	}
+	return gracePeriod, nil
+}
+
+// executeRecordedAction executes action and records its result in the summary
+// of the script if any.
+func (r *Registry) executeRecordedAction(
+	ctx context.Context,
+	scriptContext ScriptExecutionContext,
+	actionIndex int,
+	action config.ShuttleAction,
+) error {
+	actionContext := ActionExecutionContext{
+		ScriptContext: scriptContext,
+		Action:        action,
+		ActionIndex:   actionIndex,
+	}
+	actionUI := scriptContext.Project.UI.WithAction(scriptContext.ScriptName, actionIndex)
+	actionContext.ScriptContext.Project.UI = actionUI
+	summary := scriptContext.Summary
+	if summary != nil {
+		actionContext.output = newOutputTail(summaryOutputLines)
+	}
+
+	actionUI.ActionStarted()
+	actionStart := time.Now()
+	err := r.confirmAndExecuteAction(ctx, actionUI, actionContext)
+	actionUI.ActionExited(actionExitCode(err), time.Since(actionStart))
+	if summary != nil {
+		result := ActionResult{
+			Index:       actionIndex,
+			Description: actionDescription(action),
+			Status:      ActionStatusPassed,
+			Duration:    time.Since(actionStart),
+			Output:      actionContext.output.Lines(),
+			Stdout:      actionContext.output.StreamLines("stdout"),
+			Stderr:      actionContext.output.StreamLines("stderr"),
+		}
+		if err != nil {
+			result.Status = ActionStatusFailed
+			result.Err = err
+			result.ExitCode = actionExitCode(err)
+		}
+		summary.Actions = append(summary.Actions, result)
+	}
+	return err
+}
+
+// actionExitCode returns the exit code shuttle would exit with if an action
+// failed with err.
+func actionExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitCode *errors.ExitCode
+	if stderrors.As(err, &exitCode) {
+		return exitCode.Code
+	}
+	if stderrors.Is(err, context.Canceled) {
+		return errors.ExitCodeCancelled
+	}
+	if stderrors.Is(err, context.DeadlineExceeded) {
+		return errors.ExitCodeTimeout
+	}
+	return 1
+}
+
+// scriptSource returns the file script command is defined in.
+func scriptSource(p config.ShuttleProjectContext, command string) string {
+	if source := p.Scripts[command].Source; source != "" {
+		return source
+	}
+	if _, ok := p.Config.Scripts[command]; ok {
+		return "shuttle.yaml"
+	}
+	return "plan.yaml"
+}
+
+// confirmAndExecuteAction executes the action once a re-run of it has been
+// confirmed.
+func (r *Registry) confirmAndExecuteAction(
+	ctx context.Context,
+	ui *ui.UI,
+	context ActionExecutionContext,
+) error {
+	confirm := context.ScriptContext.ConfirmRerun
+	// nothing is run again in a dry run
+	if confirm != nil && !context.Action.Idempotent && !context.ScriptContext.DryRun {
+		confirmed, err := confirm(context)
 		if err != nil {
 			return err
 		}
+		if !confirmed {
+			return errors.NewExitCode(
+				2,
+				"Action %d of script '%s' is not idempotent and re-running it was not confirmed. Use --confirm-rerun to run it anyway",
+				context.ActionIndex,
+				context.ScriptContext.ScriptName,
+			)
+		}
+	}
+	return r.executeAction(ctx, ui, context)
+}
+
+// Validate validates args, on the form <argument>=<value>, against the
+// arguments of script command without executing it. All problems are reported
+// at once.
+func Validate(p config.ShuttleProjectContext, command string, args []string) error {
+	script, ok := p.Scripts[command]
+	if !ok {
+		return errors.NewExitCode(2, "Script '%s' not found", command)
 	}
-	return nil
+	_, err := validateArguments(p, command, script, args, true)
+	return err
 }
 
 // validateArguments parses and validates args against available arguments in
-// scriptArgs.
+// script.
 //
 // All detectable constraints are checked before reporting to the UI.
 func validateArguments(
 	p config.ShuttleProjectContext,
 	command string,
-	scriptArgs []config.ShuttleScriptArgs,
+	script config.ShuttlePlanScript,
 	args []string,
 	validateArgs bool,
 ) (map[string]string, error) {
 	var validationErrors []validationError
+	scriptArgs := script.Args
 
 	namedArgs, parsingErrors := validateArgFormat(args)
 	validationErrors = append(validationErrors, parsingErrors...)
 	if validateArgs {
 		validationErrors = append(validationErrors, validateRequiredArgs(scriptArgs, namedArgs)...)
 		validationErrors = append(validationErrors, validateUnknownArgs(scriptArgs, namedArgs)...)
+		problems, err := script.ExclusiveArgsProblems(command, namedArgs)
+		if err != nil {
+			return nil, err
+		}
+		for _, problem := range problems {
+			validationErrors = append(validationErrors, validationError{err: problem})
+		}
+		validationErrors = append(validationErrors, validateArgValues(p.ProjectPath, scriptArgs, script.ArgsWithDefaults(namedArgs))...)
 	}
 	if len(validationErrors) != 0 {
-		sortValidationErrors(validationErrors)
-		var s strings.Builder
-		s.WriteString("Arguments not valid:\n")
-		for _, e := range validationErrors {
-			fmt.Fprintf(&s, " %s\n", e)
-		}
-		fmt.Fprintf(&s, "\n%s", expectedArgumentsHelp(command, scriptArgs))
-		return nil, errors.NewExitCode(2, s.String())
+		return nil, argumentsError(command, scriptArgs, validationErrors)
 	}
 	return namedArgs, nil
 }
 
+// resolveArgs returns args with the defaults of the arguments of script
+// command that are not supplied. If validate is set the values are validated
+// against the types and patterns of the arguments.
+func resolveArgs(p config.ShuttleProjectContext, command string, args map[string]string, validate bool) (map[string]string, error) {
+	script := p.Scripts[command]
+	resolved := script.ArgsWithDefaults(args)
+	if !validate {
+		return resolved, nil
+	}
+	if validationErrors := validateArgValues(p.ProjectPath, script.Args, resolved); len(validationErrors) != 0 {
+		return nil, argumentsError(command, script.Args, validationErrors)
+	}
+	return resolved, nil
+}
+
+// argumentsError returns an error listing validationErrors along with the
+// arguments script command accepts.
+func argumentsError(command string, scriptArgs []config.ShuttleScriptArgs, validationErrors []validationError) error {
+	sortValidationErrors(validationErrors)
+	var s strings.Builder
+	s.WriteString("Arguments not valid:\n")
+	for _, e := range validationErrors {
+		fmt.Fprintf(&s, " %s\n", e)
+	}
+	fmt.Fprintf(&s, "\n%s", expectedArgumentsHelp(command, scriptArgs))
+	return errors.NewExitCode(2, "%s", s.String())
+}
+
 type validationError struct {
 	arg string
 	err string
 }
 
 func (v validationError) String() string {
+	if v.arg == "" {
+		return v.err
+	}
 	return fmt.Sprintf("'%s' %s", v.arg, v.err)
 }
 
@@ -172,8 +751,31 @@ func validateUnknownArgs(
 	return validationErrors
 }
 
+// validateArgValues validates the supplied values of args against the types
+// and patterns of scriptArgs.
+func validateArgValues(
+	projectPath string,
+	scriptArgs []config.ShuttleScriptArgs,
+	args map[string]string,
+) []validationError {
+	var validationErrors []validationError
+	for _, arg := range scriptArgs {
+		value, ok := args[arg.Name]
+		if !ok || value == "" {
+			continue
+		}
+		if problem := arg.ValueProblem(value, projectPath); problem != "" {
+			validationErrors = append(validationErrors, validationError{
+				arg: arg.Name,
+				err: problem,
+			})
+		}
+	}
+	return validationErrors
+}
+
 func sortValidationErrors(errs []validationError) {
-	sort.Slice(errs, func(i, j int) bool {
+	sort.SliceStable(errs, func(i, j int) bool {
 		return errs[i].arg < errs[j].arg
 	})
 }
@@ -200,7 +802,72 @@ func (r *Registry) executeAction(
 	for _, executor := range r.executors {
 		handler, ok := executor(context.Action)
 		if ok {
-			return handler(ctx, ui, context)
+			tmpDir := context.TempDirectoryPath()
+			if tmpDir != "" {
+				err := os.MkdirAll(tmpDir, os.ModePerm)
+				if err != nil {
+					return fmt.Errorf("create action temp directory '%s': %w", tmpDir, err)
+				}
+				if !context.ScriptContext.DryRun {
+					context.contextFile, err = writeContextFile(ctx, context, tmpDir)
+					if err != nil {
+						return err
+					}
+					// background actions may still read the file
+					if !context.Action.Background {
+						defer os.Remove(context.contextFile)
+					}
+				}
+			}
+
+			err := checkTools(ctx, context)
+			if err != nil {
+				return err
+			}
+			err = repeatUntilSuccess(ctx, ui, context, func() error {
+				// every attempt gets the full timeout of the action
+				actionCtx, cancel, err := withActionTimeout(ctx, context)
+				if err != nil {
+					return err
+				}
+				defer cancel()
+				return actionTimeoutError(actionCtx, context, handler(actionCtx, ui, context))
+			})
+			// report failures caused by cancellation consistently across
+			// executors
+			if err != nil && ctx.Err() != nil {
+				err = errors.NewCancellation(ctx)
+			}
+			// background actions have not produced their artifacts yet
+			if err == nil && len(context.Action.Upload) != 0 && !context.Action.Background {
+				if context.ScriptContext.DryRun {
+					ui.Output("Dry run: skipping upload of %d artifacts of action %d", len(context.Action.Upload), context.ActionIndex)
+				} else {
+					err = uploadArtifacts(ctx, ui, context)
+				}
+			}
+			// artifacts, eg. test reports, are collected from failed actions
+			// as well
+			if len(context.Action.Artifacts) != 0 && !context.Action.Background && ctx.Err() == nil {
+				if context.ScriptContext.DryRun {
+					ui.Output("Dry run: skipping collection of artifacts of action %d", context.ActionIndex)
+				} else if collectErr := collectArtifacts(ctx, ui, context); collectErr != nil {
+					if err == nil {
+						err = collectErr
+					} else {
+						ui.Errorln("Failed to collect artifacts of action %d of script `%s`: %v", context.ActionIndex, context.ScriptContext.ScriptName, collectErr)
+					}
+				}
+			}
+
+			if tmpDir != "" && context.ScriptContext.CleanTmp {
+				if context.Action.KeepTmp {
+					ui.Verboseln("Keeping action temp directory %s", tmpDir)
+				} else if rmErr := os.RemoveAll(tmpDir); rmErr != nil {
+					ui.Errorln("Failed to clean action temp directory '%s': %v", tmpDir, rmErr)
+				}
+			}
+			return err
 		}
 	}
 
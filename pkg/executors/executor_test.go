@@ -3,12 +3,17 @@ package executors
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/go-cmd/cmd"
 	"github.com/lunarway/shuttle/pkg/config"
+	shuttleerrors "github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/telemetry"
 	"github.com/lunarway/shuttle/pkg/ui"
 	"github.com/stretchr/testify/assert"
 )
@@ -106,6 +111,77 @@ func TestValidateUnknownArgs(t *testing.T) {
 	}
 }
 
+func TestValidate(t *testing.T) {
+	project := config.ShuttleProjectContext{
+		Scripts: map[string]config.ShuttlePlanScript{
+			"deploy": {
+				Args: []config.ShuttleScriptArgs{
+					{Name: "env", Required: true},
+					{Name: "from-tag"},
+					{Name: "from-branch"},
+				},
+				Exclusive: []config.ShuttleExclusiveArgs{
+					{Args: []string{"from-tag", "from-branch"}, Required: true},
+				},
+			},
+		},
+	}
+	tt := []struct {
+		name    string
+		command string
+		args    []string
+		err     string
+	}{
+		{
+			name:    "valid",
+			command: "deploy",
+			args:    []string{"env=prod", "from-tag=v1"},
+		},
+		{
+			name:    "all problems",
+			command: "deploy",
+			args:    []string{"from-tag=v1", "from-branch=main", "region=eu"},
+			err: `exit code 2 - Arguments not valid:
+ Arguments 'from-tag' and 'from-branch' of script 'deploy' are mutually exclusive. Supply only one of them
+ 'env' not supplied but is required
+ 'region' unknown
+
+Script 'deploy' accepts the following arguments:
+  env (required)
+  from-tag
+  from-branch`,
+		},
+		{
+			name:    "required exclusive group",
+			command: "deploy",
+			args:    []string{"env=prod"},
+			err: `exit code 2 - Arguments not valid:
+ One of the arguments 'from-tag' or 'from-branch' of script 'deploy' is required
+
+Script 'deploy' accepts the following arguments:
+  env (required)
+  from-tag
+  from-branch`,
+		},
+		{
+			name:    "unknown script",
+			command: "build",
+			err:     "exit code 2 - Script 'build' not found",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(project, tc.command, tc.args)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestSortValidationErrors(t *testing.T) {
 	tt := []struct {
 		name   string
@@ -158,6 +234,11 @@ func TestExecute(t *testing.T) {
 			script: "cat testdata/large.log",
 			err:    nil,
 		},
+		{
+			name:   "selected actions are available",
+			script: `test "$SHUTTLE_SELECTED_ACTIONS" = "test"`,
+			err:    nil,
+		},
 	}
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
@@ -189,6 +270,448 @@ func TestExecute(t *testing.T) {
 	}
 }
 
+func TestExecute_preflight(t *testing.T) {
+	tt := []struct {
+		name      string
+		preflight []config.ShuttlePreflightCheck
+		output    string
+		err       error
+	}{
+		{
+			name: "all checks pass",
+			preflight: []config.ShuttlePreflightCheck{
+				{Name: "first", Shell: "true"},
+				{Name: "second", Shell: "exit 0"},
+			},
+			output: "main\n",
+			err:    nil,
+		},
+		{
+			name: "failing check does not run main body",
+			preflight: []config.ShuttlePreflightCheck{
+				{Name: "first", Shell: "true"},
+				{Name: "kubectl context", Shell: "exit 3"},
+				{Name: "third", Shell: "echo third"},
+			},
+			output: "",
+			err: errors.New(
+				"exit code 4 - Preflight check 'kubectl context' failed for script `test`: shell script `exit 3`\nExit code: 3",
+			),
+		},
+		{
+			name: "unnamed check is reported by its shell",
+			preflight: []config.ShuttlePreflightCheck{
+				{Shell: "exit 1"},
+			},
+			output: "",
+			err: errors.New(
+				"exit code 4 - Preflight check 'exit 1' failed for script `test`: shell script `exit 1`\nExit code: 1",
+			),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(stdout, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{
+							{
+								Shell:     "echo main",
+								Preflight: tc.preflight,
+							},
+						},
+					},
+				},
+			}, "test", nil, true)
+
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.output, stdout.String())
+		})
+	}
+}
+
+func TestExecute_cleanTmp(t *testing.T) {
+	tt := []struct {
+		name     string
+		cleanTmp bool
+		keepTmp  bool
+		exists   bool
+	}{
+		{
+			name:     "kept without clean",
+			cleanTmp: false,
+			keepTmp:  false,
+			exists:   true,
+		},
+		{
+			name:     "removed on clean",
+			cleanTmp: true,
+			keepTmp:  false,
+			exists:   false,
+		},
+		{
+			name:     "kept on clean with keepTmp",
+			cleanTmp: true,
+			keepTmp:  true,
+			exists:   true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath:       ".",
+				TempDirectoryPath: tmpDir,
+				UI:                ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{
+							{
+								Shell:   `echo debug > "$SHUTTLE_ACTION_TMP/debug.log"`,
+								KeepTmp: tc.keepTmp,
+							},
+						},
+					},
+				},
+			}, "test", nil, true, WithCleanTmp(tc.cleanTmp))
+
+			assert.NoError(t, err)
+			if tc.exists {
+				assert.FileExists(t, filepath.Join(tmpDir, "actions", "test", "0", "debug.log"))
+			} else {
+				assert.NoDirExists(t, filepath.Join(tmpDir, "actions", "test", "0"))
+			}
+		})
+	}
+}
+
+func TestExecute_output(t *testing.T) {
+	tt := []struct {
+		name   string
+		output string
+		env    string
+		lines  []string
+		err    error
+	}{
+		{
+			name:   "streaming",
+			output: "streaming",
+			lines:  []string{"out 1", "err 1", "out 2"},
+		},
+		{
+			name:   "buffered",
+			output: "buffered",
+			lines:  []string{"out 1", "out 2", "err 1"},
+		},
+		{
+			name:  "buffered globally",
+			env:   "buffered",
+			lines: []string{"out 1", "out 2", "err 1"},
+		},
+		{
+			name:   "action overrides global mode",
+			output: "streaming",
+			env:    "buffered",
+			lines:  []string{"out 1", "err 1", "out 2"},
+		},
+		{
+			name:   "buffered exit code",
+			output: "buffered",
+			lines:  []string{"out 1", "out 2", "err 1"},
+			err: errors.New(
+				"exit code 4 - Failed executing script `test`: shell script `echo out 1; sleep 0.1; >&2 echo err 1; sleep 0.1; echo out 2; exit 3`\nExit code: 3",
+			),
+		},
+		{
+			name:   "invalid mode",
+			output: "batch",
+			err: errors.New(
+				"exit code 1 - Output mode 'batch' of script `test` is invalid: must be one of 'streaming' or 'buffered'",
+			),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("SHUTTLE_SHELL_OUTPUT", tc.env)
+			var combined bytes.Buffer
+			registry := NewRegistry(ShellExecutor)
+			script := "echo out 1; sleep 0.1; >&2 echo err 1; sleep 0.1; echo out 2"
+			if tc.err != nil && tc.lines != nil {
+				script += "; exit 3"
+			}
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(&combined, &combined),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{
+							{
+								Shell:  script,
+								Output: tc.output,
+							},
+						},
+					},
+				},
+			}, "test", nil, true)
+
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			var expected string
+			for _, line := range tc.lines {
+				expected += line + "\n"
+			}
+			assert.Equal(t, expected, combined.String())
+		})
+	}
+}
+
+func TestExecute_mergeStderr(t *testing.T) {
+	tt := []struct {
+		name   string
+		merge  bool
+		env    string
+		stdout string
+		stderr string
+		err    error
+	}{
+		{
+			name:   "split by default",
+			stdout: "out 1\nout 2\n",
+			stderr: "err 1\n",
+		},
+		{
+			name:   "merged",
+			merge:  true,
+			stdout: "out 1\nerr 1\nout 2\n",
+		},
+		{
+			name:   "merged globally",
+			env:    "true",
+			stdout: "out 1\nerr 1\nout 2\n",
+		},
+		{
+			name: "invalid global value",
+			env:  "sometimes",
+			err: errors.New(
+				"exit code 1 - SHUTTLE_SHELL_MERGE_STDERR value 'sometimes' is invalid: must be true or false",
+			),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("SHUTTLE_SHELL_MERGE_STDERR", tc.env)
+			var stdout, stderr bytes.Buffer
+			registry := NewRegistry(ShellExecutor)
+
+			// buffered output would otherwise print stdout before stderr
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(&stdout, &stderr),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{
+							{
+								Shell:       "echo out 1; >&2 echo err 1; echo out 2",
+								Output:      OutputBuffered,
+								MergeStderr: tc.merge,
+							},
+						},
+					},
+				},
+			}, "test", nil, true)
+
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.stdout, stdout.String(), "stdout")
+			assert.Equal(t, tc.stderr, stderr.String(), "stderr")
+		})
+	}
+}
+
+func TestExecute_rerun(t *testing.T) {
+	tt := []struct {
+		name       string
+		idempotent bool
+		confirmed  bool
+		output     string
+		err        error
+	}{
+		{
+			name:       "idempotent action is not confirmed",
+			idempotent: true,
+			confirmed:  false,
+			output:     "run\n",
+			err:        nil,
+		},
+		{
+			name:       "confirmed action is run",
+			idempotent: false,
+			confirmed:  true,
+			output:     "run\n",
+			err:        nil,
+		},
+		{
+			name:       "unconfirmed action is not run",
+			idempotent: false,
+			confirmed:  false,
+			output:     "",
+			err: errors.New(
+				"exit code 2 - Action 0 of script 'test' is not idempotent and re-running it was not confirmed. Use --confirm-rerun to run it anyway",
+			),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+			var asked []int
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(stdout, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{
+							{
+								Shell:      "echo run",
+								Idempotent: tc.idempotent,
+							},
+						},
+					},
+				},
+			}, "test", nil, true, WithRerun(func(c ActionExecutionContext) (bool, error) {
+				asked = append(asked, c.ActionIndex)
+				return tc.confirmed, nil
+			}))
+
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.output, stdout.String())
+			if tc.idempotent {
+				assert.Empty(t, asked, "idempotent actions must not be confirmed")
+			} else {
+				assert.Equal(t, []int{0}, asked)
+			}
+		})
+	}
+}
+
+func TestExecute_background(t *testing.T) {
+	localDir := t.TempDir()
+	project := config.ShuttleProjectContext{
+		ProjectPath:               ".",
+		LocalShuttleDirectoryPath: localDir,
+		UI:                        ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"serve": {
+				Actions: []config.ShuttleAction{
+					{
+						Shell:      "echo started; exit 3",
+						Background: true,
+					},
+				},
+			},
+		},
+	}
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), project, "serve", nil, true)
+
+	assert.NoError(t, err)
+	state := BackgroundStateFor(project, "serve")
+	assert.Eventually(t, func() bool {
+		_, done := state.ExitCode()
+		return done
+	}, 5*time.Second, 10*time.Millisecond)
+	code, _ := state.ExitCode()
+	assert.Equal(t, 3, code)
+	assert.FileExists(t, state.PIDPath)
+	output, err := os.ReadFile(state.LogPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "started\n", string(output))
+}
+
+func TestExecute_cancellationCause(t *testing.T) {
+	tt := []struct {
+		name   string
+		cancel func(context.Context) (context.Context, func())
+		err    string
+	}{
+		{
+			name: "interrupted by user",
+			cancel: func(ctx context.Context) (context.Context, func()) {
+				ctx, cancel := context.WithCancelCause(ctx)
+				return ctx, func() { cancel(shuttleerrors.ErrInterrupted) }
+			},
+			err: "exit code 130 - Cancelled by user",
+		},
+		{
+			name: "deadline exceeded",
+			cancel: func(ctx context.Context) (context.Context, func()) {
+				ctx, cancel := context.WithDeadline(ctx, time.Now().Add(50*time.Millisecond))
+				return ctx, func() {
+					<-ctx.Done()
+					cancel()
+				}
+			},
+			err: "exit code 124 - Timed out",
+		},
+		{
+			name: "cancelled",
+			cancel: func(ctx context.Context) (context.Context, func()) {
+				return context.WithCancel(ctx)
+			},
+			err: "exit code 2 - Operation cancelled",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := tc.cancel(context.Background())
+			registry := NewRegistry(ShellExecutor)
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				cancel()
+			}()
+
+			err := registry.Execute(ctx, config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{
+							{Shell: "sleep 10"},
+							{Shell: "echo not run"},
+						},
+					},
+				},
+			}, "test", nil, true)
+
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
 // TestExecute_contextCancellation tests that scripts are closed when the
 // context is cancelled.
 func TestExecute_contextCancellation(t *testing.T) {
@@ -221,7 +744,7 @@ func TestExecute_contextCancellation(t *testing.T) {
 	registry := NewRegistry(ShellExecutor)
 
 	err := registry.Execute(ctx, projectContext, "serve", nil, true)
-	assert.EqualError(t, err, context.Canceled.Error())
+	assert.EqualError(t, err, "exit code 2 - Operation cancelled")
 
 	// sadly we need to give the docker some time before "docker ps" shows the
 	// containers
@@ -248,3 +771,207 @@ func runningDockerImages(t *testing.T, imageName string) []string {
 	}
 	return nil
 }
+
+func TestExecute_runID(t *testing.T) {
+	ctx := telemetry.WithRunID(context.Background())
+	runID := telemetry.RunIDFrom(ctx)
+	output := filepath.Join(t.TempDir(), "run-ids")
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(ctx, config.ShuttleProjectContext{
+		ProjectPath: ".",
+		UI:          ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Actions: []config.ShuttleAction{
+					{Shell: fmt.Sprintf(`echo "$SHUTTLE_RUN_ID" >> '%s'`, output)},
+					{Shell: fmt.Sprintf(`echo "$SHUTTLE_RUN_ID" >> '%s'`, output)},
+				},
+			},
+		},
+	}, "test", nil, true)
+
+	assert.NoError(t, err)
+	content, err := os.ReadFile(output)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, runID)
+	assert.Equal(t, runID+"\n"+runID+"\n", string(content))
+}
+
+func TestExecute_deprecated(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: ".",
+		UI:          ui.Create(&stdout, &stderr),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"deploy": {
+				Deprecated: "Use deploy-v2 instead",
+				Actions: []config.ShuttleAction{
+					{Shell: "echo deploying"},
+				},
+			},
+		},
+	}, "deploy", nil, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "deploying\n", stdout.String())
+	assert.Equal(t, "\x1b[032;1mScript 'deploy' is deprecated: Use deploy-v2 instead\x1b[0m\n", stderr.String())
+}
+
+func TestScriptSource(t *testing.T) {
+	p := config.ShuttleProjectContext{
+		Config: config.ShuttleConfig{
+			Scripts: map[string]config.ShuttlePlanScript{
+				"local": {},
+			},
+		},
+		Scripts: map[string]config.ShuttlePlanScript{
+			"local":    {},
+			"plan":     {},
+			"included": {Source: "scripts/deploy.yaml"},
+		},
+	}
+
+	assert.Equal(t, "shuttle.yaml", scriptSource(p, "local"))
+	assert.Equal(t, "plan.yaml", scriptSource(p, "plan"))
+	assert.Equal(t, "scripts/deploy.yaml", scriptSource(p, "included"))
+}
+
+func TestExecute_always(t *testing.T) {
+	tt := []struct {
+		name     string
+		cancel   bool
+		actions  []config.ShuttleAction
+		ran      string
+		statuses []ActionStatus
+		stderr   string
+		err      string
+	}{
+		{
+			name: "all actions succeed",
+			actions: []config.ShuttleAction{
+				{Shell: "echo 0 >> $out"},
+				{Shell: "echo 1 >> $out", Always: true},
+			},
+			ran:      "0\n1\n",
+			statuses: []ActionStatus{ActionStatusPassed, ActionStatusPassed},
+		},
+		{
+			name: "always actions run in order after a failure",
+			actions: []config.ShuttleAction{
+				{Shell: "echo 0 >> $out; exit 1"},
+				{Shell: "echo 1 >> $out"},
+				{Shell: "echo 2 >> $out", Always: true},
+				{Shell: "echo 3 >> $out"},
+				{Shell: "echo 4 >> $out", Always: true},
+			},
+			ran:      "0\n2\n4\n",
+			statuses: []ActionStatus{ActionStatusFailed, ActionStatusSkipped, ActionStatusPassed, ActionStatusSkipped, ActionStatusPassed},
+			err:      "exit code 4 - Failed executing script `test`: shell script `echo 0 >> $out; exit 1`\nExit code: 1",
+		},
+		{
+			name: "failing always action does not mask the primary failure",
+			actions: []config.ShuttleAction{
+				{Shell: "echo 0 >> $out; exit 1"},
+				{Shell: "echo 1 >> $out; exit 2", Always: true},
+			},
+			ran:      "0\n1\n",
+			statuses: []ActionStatus{ActionStatusFailed, ActionStatusFailed},
+			stderr:   "\x1b[31;1mAlways action 1 of script 'test' failed: exit code 4 - Failed executing script `test`: shell script `echo 1 >> $out; exit 2`\nExit code: 2\x1b[0m\n",
+			err:      "exit code 4 - Failed executing script `test`: shell script `echo 0 >> $out; exit 1`\nExit code: 1",
+		},
+		{
+			name: "failing always action is the primary failure if nothing else failed",
+			actions: []config.ShuttleAction{
+				{Shell: "echo 0 >> $out; exit 2", Always: true},
+				{Shell: "echo 1 >> $out"},
+			},
+			ran:      "0\n",
+			statuses: []ActionStatus{ActionStatusFailed, ActionStatusSkipped},
+			err:      "exit code 4 - Failed executing script `test`: shell script `echo 0 >> $out; exit 2`\nExit code: 2",
+		},
+		{
+			name:   "always actions run after cancellation",
+			cancel: true,
+			actions: []config.ShuttleAction{
+				{Shell: "echo 0 >> $out"},
+				{Shell: "echo 1 >> $out", Always: true},
+			},
+			ran:      "1\n",
+			statuses: []ActionStatus{ActionStatusSkipped, ActionStatusPassed},
+			err:      "exit code 2 - Operation cancelled",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "ran")
+			var stderr bytes.Buffer
+			var summary RunSummary
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancel {
+				cancel()
+			}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(ctx, config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(&bytes.Buffer{}, &stderr),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Args:    []config.ShuttleScriptArgs{{Name: "out"}},
+						Actions: tc.actions,
+					},
+				},
+			}, "test", map[string]string{"out": out}, true, WithSummary(&summary))
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			ran, _ := os.ReadFile(out)
+			assert.Equal(t, tc.ran, string(ran), "ran actions")
+			var statuses []ActionStatus
+			for _, action := range summary.Actions {
+				statuses = append(statuses, action.Status)
+			}
+			assert.Equal(t, tc.statuses, statuses, "statuses")
+			assert.Equal(t, tc.stderr, stderr.String(), "stderr")
+		})
+	}
+}
+
+func TestAlwaysGracePeriod(t *testing.T) {
+	always := config.ShuttlePlanScript{
+		Actions: []config.ShuttleAction{{Always: true}},
+	}
+
+	t.Run("default", func(t *testing.T) {
+		t.Setenv("SHUTTLE_ALWAYS_GRACE_PERIOD", "")
+		gracePeriod, err := alwaysGracePeriod(always)
+		assert.NoError(t, err)
+		assert.Equal(t, 30*time.Second, gracePeriod)
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		t.Setenv("SHUTTLE_ALWAYS_GRACE_PERIOD", "5s")
+		gracePeriod, err := alwaysGracePeriod(always)
+		assert.NoError(t, err)
+		assert.Equal(t, 5*time.Second, gracePeriod)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Setenv("SHUTTLE_ALWAYS_GRACE_PERIOD", "soon")
+		_, err := alwaysGracePeriod(always)
+		assert.EqualError(t, err, "exit code 1 - SHUTTLE_ALWAYS_GRACE_PERIOD value 'soon' is invalid: must be a positive duration, eg. 30s")
+	})
+
+	t.Run("invalid without always actions", func(t *testing.T) {
+		t.Setenv("SHUTTLE_ALWAYS_GRACE_PERIOD", "soon")
+		_, err := alwaysGracePeriod(config.ShuttlePlanScript{})
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,184 @@
+package cmder
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lunarway/shuttle/pkg/executors/golang/executer"
+)
+
+// argsTag is the struct tag of fields of struct parameters and results, eg.
+// `shuttle:"env,required,enum=dev|prod"` or `shuttle:"replicas,default=1"`.
+const argsTag = "shuttle"
+
+// flag is a flag of a command bound to a string parameter or to a field of a
+// struct parameter of its function.
+type flag struct {
+	executer.ActionArg
+	// param is the index of the parameter of the function after the context
+	param int
+	// field is the index of the field of a struct parameter or -1 for string
+	// parameters
+	field int
+	value string
+}
+
+// commandFlags returns the flags of the parameters of the function of cmd.
+// String parameters are required flags named by their argument. Struct
+// parameters are bound field by field with the shuttle tag of the field.
+func commandFlags(cmd *Cmd) ([]*flag, error) {
+	funcType := reflect.TypeOf(cmd.Func)
+	var flags []*flag
+	for i, arg := range cmd.Args {
+		if i+1 >= funcType.NumIn() {
+			return nil, fmt.Errorf("action %s has no parameter for argument %s", cmd.Name, arg.Name)
+		}
+		paramType := funcType.In(i + 1)
+		if paramType.Kind() != reflect.Struct {
+			flags = append(flags, &flag{ActionArg: executer.ActionArg{Name: arg.Name}, param: i, field: -1})
+			continue
+		}
+		for j := 0; j < paramType.NumField(); j++ {
+			field := paramType.Field(j)
+			if !field.IsExported() {
+				continue
+			}
+			actionArg, err := fieldArg(field)
+			if err != nil {
+				return nil, fmt.Errorf("argument %s of action %s: %w", field.Name, cmd.Name, err)
+			}
+			flags = append(flags, &flag{ActionArg: actionArg, param: i, field: j})
+		}
+	}
+	return flags, nil
+}
+
+// fieldArg returns the argument of a field of a struct parameter from its type
+// and shuttle tag. Arguments are named by the lower cased field name unless
+// the tag names them.
+func fieldArg(field reflect.StructField) (executer.ActionArg, error) {
+	name, options := fieldName(field)
+	arg := executer.ActionArg{Name: name}
+	switch field.Type.Kind() {
+	case reflect.String:
+		arg.Type = "string"
+	case reflect.Bool:
+		arg.Type = "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		arg.Type = "int"
+	default:
+		return arg, fmt.Errorf("type %s is not supported: must be a string, bool or integer", field.Type)
+	}
+	for _, option := range options {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "required":
+			arg.Required = true
+		case "default":
+			arg.Default = value
+		case "enum":
+			if arg.Type != "string" {
+				return arg, fmt.Errorf("enum is only supported for strings")
+			}
+			arg.Type = "enum"
+			arg.Values = strings.Split(value, "|")
+		default:
+			return arg, fmt.Errorf("tag option '%s' is invalid: must be one of required, default or enum", key)
+		}
+	}
+	if arg.Required && arg.Default != "" {
+		return arg, fmt.Errorf("required arguments cannot have a default")
+	}
+	if arg.Default != "" {
+		if _, err := parseValue(field.Type, arg, arg.Default); err != nil {
+			return arg, fmt.Errorf("default: %w", err)
+		}
+	}
+	return arg, nil
+}
+
+// fieldName returns the name of a field of a struct parameter or result and
+// the options of its shuttle tag.
+func fieldName(field reflect.StructField) (string, []string) {
+	parts := strings.Split(field.Tag.Get(argsTag), ",")
+	name := parts[0]
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name, parts[1:]
+}
+
+// addFlags adds the flags to cobracmd. Required flags must be set and others
+// get their default.
+func addFlags(cobracmd *cobra.Command, flags []*flag) {
+	for _, f := range flags {
+		cobracmd.Flags().StringVar(&f.value, f.Name, f.Default, "")
+		if f.Required || f.Type == "" {
+			_ = cobracmd.MarkFlagRequired(f.Name)
+		}
+	}
+}
+
+// bindFlags returns the parameters of the function of cmd after the context
+// from the values of flags.
+func bindFlags(cmd *Cmd, cobracmd *cobra.Command, flags []*flag) ([]reflect.Value, error) {
+	funcType := reflect.TypeOf(cmd.Func)
+	params := make([]reflect.Value, len(cmd.Args))
+	for i := range params {
+		params[i] = reflect.New(funcType.In(i + 1)).Elem()
+	}
+	for _, f := range flags {
+		if f.field < 0 {
+			params[f.param].SetString(f.value)
+			continue
+		}
+		// optional arguments without a default keep their zero value
+		if !cobracmd.Flags().Changed(f.Name) && f.Default == "" {
+			continue
+		}
+		field := params[f.param].Field(f.field)
+		value, err := parseValue(field.Type(), f.ActionArg, f.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of --%s: %w", f.Name, err)
+		}
+		field.Set(value)
+	}
+	return params, nil
+}
+
+// parseValue parses raw as a value of the argument with type t.
+func parseValue(t reflect.Type, arg executer.ActionArg, raw string) (reflect.Value, error) {
+	value := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		if arg.Type == "enum" && !slices.Contains(arg.Values, raw) {
+			return value, fmt.Errorf("'%s' must be one of %s", raw, strings.Join(arg.Values, ", "))
+		}
+		value.SetString(raw)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return value, fmt.Errorf("'%s' must be true or false", raw)
+		}
+		value.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 10, t.Bits())
+		if err != nil {
+			return value, fmt.Errorf("'%s' must be an integer", raw)
+		}
+		value.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(raw, 10, t.Bits())
+		if err != nil {
+			return value, fmt.Errorf("'%s' must be a positive integer", raw)
+		}
+		value.SetUint(parsed)
+	}
+	return value, nil
+}
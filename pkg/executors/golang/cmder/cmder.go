@@ -59,12 +59,14 @@ func (rc *RootCmd) TryExecute(args []string) error {
 			Run: func(cmd *cobra.Command, args []string) {
 				actions := executer.NewActions()
 				for _, cmd := range rc.Cmds {
+					flags, err := commandFlags(cmd)
+					if err != nil {
+						log.Fatal(err)
+					}
 					args := make([]executer.ActionArg, 0)
 
-					for _, arg := range cmd.Args {
-						args = append(args, executer.ActionArg{
-							Name: arg.Name,
-						})
+					for _, flag := range flags {
+						args = append(args, flag.ActionArg)
 					}
 
 					actions.Actions[cmd.Name] = executer.Action{
@@ -90,7 +92,10 @@ func (rc *RootCmd) TryExecute(args []string) error {
 
 	for _, cmd := range rc.Cmds {
 		cmd := cmd
-		parameters := make([]string, len(cmd.Args))
+		flags, err := commandFlags(cmd)
+		if err != nil {
+			return err
+		}
 
 		cobracmd := &cobra.Command{
 			Use: cmd.Name,
@@ -104,11 +109,15 @@ func (rc *RootCmd) TryExecute(args []string) error {
 					return ErrNoHelp
 				}
 
+				parameters, err := bindFlags(cmd, cobracmd, flags)
+				if err != nil {
+					fmt.Fprintln(cobracmd.ErrOrStderr(), err)
+					return ErrNoHelp
+				}
+
 				inputs := make([]reflect.Value, 0, len(cmd.Args)+1)
 				inputs = append(inputs, reflect.ValueOf(context.Background()))
-				for _, arg := range parameters {
-					inputs = append(inputs, reflect.ValueOf(arg))
-				}
+				inputs = append(inputs, parameters...)
 
 				returnValues := reflect.
 					ValueOf(cmd.Func).
@@ -128,13 +137,16 @@ func (rc *RootCmd) TryExecute(args []string) error {
 					}
 				}
 
+				// a struct result is written as outputs of the action
+				if err := writeOutputs(returnValues[0]); err != nil {
+					fmt.Fprintln(cobracmd.ErrOrStderr(), err)
+					return ErrNoHelp
+				}
+
 				return nil
 			},
 		}
-		for i, arg := range cmd.Args {
-			cobracmd.Flags().StringVar(&parameters[i], arg.Name, "", "")
-			_ = cobracmd.MarkFlagRequired(arg.Name)
-		}
+		addFlags(cobracmd, flags)
 
 		rootcmd.AddCommand(cobracmd)
 	}
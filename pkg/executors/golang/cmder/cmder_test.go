@@ -3,9 +3,12 @@ package cmder_test
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/lunarway/shuttle/pkg/executors/golang/cmder"
 )
@@ -79,3 +82,100 @@ func TestCmderWithMultipeReturnsErroringInAnyPlace(t *testing.T) {
 
 	assert.ErrorIs(t, err, cmder.ErrNoHelp)
 }
+
+type deployArgs struct {
+	Env      string `shuttle:"env,required,enum=dev|prod"`
+	Replicas int    `shuttle:",default=1"`
+	DryRun   bool   `shuttle:"dry-run"`
+	internal string
+}
+
+func TestCmderWithStructArgs(t *testing.T) {
+	tt := []struct {
+		name string
+		args []string
+		want deployArgs
+		err  bool
+	}{
+		{name: "defaults", args: []string{"deploy", "--env", "dev"}, want: deployArgs{Env: "dev", Replicas: 1}},
+		{name: "all set", args: []string{"deploy", "--env", "prod", "--replicas", "3", "--dry-run", "true"}, want: deployArgs{Env: "prod", Replicas: 3, DryRun: true}},
+		{name: "missing required", args: []string{"deploy"}, err: true},
+		{name: "invalid enum", args: []string{"deploy", "--env", "test"}, err: true},
+		{name: "invalid int", args: []string{"deploy", "--env", "dev", "--replicas", "many"}, err: true},
+		{name: "invalid bool", args: []string{"deploy", "--env", "dev", "--dry-run", "maybe"}, err: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var got deployArgs
+			called := false
+			testFunc := cmder.WithArgs(cmder.NewCmd("deploy", func(ctx context.Context, args deployArgs) error {
+				got, called = args, true
+				return nil
+			}), "args")
+
+			err := cmder.NewRoot().AddCmds(testFunc).TryExecute(tc.args)
+
+			if tc.err {
+				assert.Error(t, err)
+				assert.False(t, called)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestCmderWithInvalidStructArgs(t *testing.T) {
+	testFunc := cmder.WithArgs(cmder.NewCmd("test", func(ctx context.Context, args struct {
+		Name string `shuttle:",required,default=x"`
+	}) error {
+		return nil
+	}), "args")
+
+	err := cmder.NewRoot().AddCmds(testFunc).TryExecute([]string{"test"})
+
+	assert.EqualError(t, err, "argument Name of action test: required arguments cannot have a default")
+}
+
+type buildResult struct {
+	Image   string
+	Tags    []string `shuttle:"tags"`
+	Message string   `shuttle:"message"`
+}
+
+func TestCmderWithStructResult(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "output")
+	require.NoError(t, os.WriteFile(outputFile, []byte("earlier=1\n"), 0o644))
+	t.Setenv("SHUTTLE_OUTPUT", outputFile)
+	testFunc := cmder.NewCmd("build", func(ctx context.Context) (*buildResult, error) {
+		return &buildResult{Image: "app:1.0", Tags: []string{"1.0", "latest"}, Message: "say \"$hi\"\n"}, nil
+	})
+
+	err := cmder.NewRoot().AddCmds(testFunc).TryExecute([]string{"build"})
+
+	require.NoError(t, err)
+	outputs, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	assert.Equal(t, `earlier=1
+image="app:1.0"
+tags="[\"1.0\",\"latest\"]"
+message="say \"\$hi\"\n"
+`, string(outputs))
+}
+
+func TestCmderWithStructResultErroring(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "output")
+	require.NoError(t, os.WriteFile(outputFile, nil, 0o644))
+	t.Setenv("SHUTTLE_OUTPUT", outputFile)
+	testFunc := cmder.NewCmd("build", func(ctx context.Context) (buildResult, error) {
+		return buildResult{Image: "app:1.0"}, errors.New("some-error")
+	})
+
+	err := cmder.NewRoot().AddCmds(testFunc).TryExecute([]string{"build"})
+
+	assert.ErrorIs(t, err, cmder.ErrNoHelp)
+	outputs, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	assert.Empty(t, string(outputs))
+}
@@ -0,0 +1,79 @@
+package cmder
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// outputFileEnv is the environment variable holding the path of the file the
+// outputs of the action are written to
+const outputFileEnv = "SHUTTLE_OUTPUT"
+
+// outputEscaper escapes values for the double quoted values of the output
+// file read like an env file
+var outputEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`)
+
+// writeOutputs appends the exported fields of result to the output file of
+// the action, if any, such that they become outputs for the following actions.
+// Results which are not structs or pointers to structs are ignored. Fields are
+// named like struct parameters and values of other types than strings, bools
+// and numbers are written as JSON.
+func writeOutputs(result reflect.Value) error {
+	if result.Kind() == reflect.Pointer {
+		if result.IsNil() {
+			return nil
+		}
+		result = result.Elem()
+	}
+	if result.Kind() != reflect.Struct {
+		return nil
+	}
+	path := os.Getenv(outputFileEnv)
+	if path == "" {
+		return nil
+	}
+
+	var outputs strings.Builder
+	for i := 0; i < result.NumField(); i++ {
+		field := result.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _ := fieldName(field)
+		value, err := outputValue(result.Field(i))
+		if err != nil {
+			return fmt.Errorf("output %s: %w", name, err)
+		}
+		fmt.Fprintf(&outputs, "%s=\"%s\"\n", name, outputEscaper.Replace(value))
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("write outputs: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(outputs.String()); err != nil {
+		return fmt.Errorf("write outputs: %w", err)
+	}
+	return nil
+}
+
+// outputValue returns the value of a field of a result as written to the
+// output file.
+func outputValue(value reflect.Value) (string, error) {
+	switch value.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(value.Interface()), nil
+	}
+	raw, err := json.Marshal(value.Interface())
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
@@ -2,27 +2,30 @@ package codegen
 
 import (
 	"context"
-	"log"
+	"fmt"
 	"os"
 	"os/exec"
 	"path"
+	"strings"
 
 	"github.com/lunarway/shuttle/pkg/ui"
 )
 
-func CompileBinary(ctx context.Context, ui *ui.UI, shuttlelocaldir string) (string, error) {
-	cmd := exec.Command("go", "build")
-	cmd.Env = os.Environ()
+// CompileBinary builds the generated actions module into the binary named
+// output. The output of the compiler is part of the returned error if the
+// build fails.
+func CompileBinary(ctx context.Context, ui *ui.UI, shuttlelocaldir string, env []string, output string) (string, error) {
+	cmd := exec.Command("go", "build", "-o", output)
+	cmd.Env = append(os.Environ(), env...)
 	// We need to set workspaces off, as we don't want users to have to add the golang modules to their go.work
 	cmd.Env = append(cmd.Env, "GOWORK=off")
 
 	cmd.Dir = path.Join(shuttlelocaldir, "tmp")
 
-	output, err := cmd.CombinedOutput()
+	compilerOutput, err := cmd.CombinedOutput()
 	if err != nil {
-		log.Printf("compile-binary output: %s", string(output))
-		return "", err
+		return "", fmt.Errorf("%w\n%s", err, strings.TrimSpace(string(compilerOutput)))
 	}
 
-	return path.Join(shuttlelocaldir, "tmp", "actions"), nil
+	return path.Join(shuttlelocaldir, "tmp", output), nil
 }
@@ -2,15 +2,17 @@ package codegen
 
 import (
 	"context"
+	"os"
 	"os/exec"
 	"path"
 
 	"github.com/lunarway/shuttle/pkg/ui"
 )
 
-func ModTidy(ctx context.Context, ui *ui.UI, shuttlelocaldir string) error {
+func ModTidy(ctx context.Context, ui *ui.UI, shuttlelocaldir string, env []string) error {
 	cmd := exec.Command("go", "mod", "tidy")
 	cmd.Dir = path.Join(shuttlelocaldir, "tmp")
+	cmd.Env = append(os.Environ(), env...)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -0,0 +1,99 @@
+package codegen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TidyKeyFile is the file of the tmp directory holding the key of the last
+// successful go mod tidy.
+const TidyKeyFile = ".tidy"
+
+// TidyKey returns a key of the inputs of go mod tidy of the generated actions
+// module: go.mod and go.sum of the actions, the imports of all go files of
+// the tmp directory and go.mod and go.work of rootDir which the module is
+// patched with. go mod tidy makes the same go.mod and go.sum as long as the
+// key is the same.
+func TidyKey(rootDir, actionsDir, shuttlelocaldir string) (string, error) {
+	hash := sha256.New()
+	for _, file := range []string{
+		path.Join(actionsDir, "go.mod"),
+		path.Join(actionsDir, "go.sum"),
+		path.Join(rootDir, "go.mod"),
+		path.Join(rootDir, "go.work"),
+	} {
+		content, err := os.ReadFile(file)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s %d\n%s\n", path.Base(file), len(content), content)
+	}
+
+	tmpdir := path.Join(shuttlelocaldir, "tmp")
+	imports := map[string]bool{}
+	err := filepath.WalkDir(tmpdir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && file != tmpdir && (entry.Name() == "vendor" || entry.Name() == "testdata") {
+			return filepath.SkipDir
+		}
+		if entry.IsDir() || !strings.HasSuffix(file, ".go") {
+			return nil
+		}
+		parsed, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly)
+		if err != nil {
+			return err
+		}
+		for _, spec := range parsed.Imports {
+			imports[spec.Path.Value] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sorted := make([]string, 0, len(imports))
+	for imp := range imports {
+		sorted = append(sorted, imp)
+	}
+	sort.Strings(sorted)
+	fmt.Fprintf(hash, "imports\n%s\n", strings.Join(sorted, "\n"))
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// IsTidy returns whether go mod tidy last succeeded in the tmp directory of
+// shuttlelocaldir with key and go.mod is still there.
+func IsTidy(shuttlelocaldir, key string) bool {
+	tmpdir := path.Join(shuttlelocaldir, "tmp")
+	if _, err := os.Stat(path.Join(tmpdir, "go.mod")); err != nil {
+		return false
+	}
+	stored, err := os.ReadFile(path.Join(tmpdir, TidyKeyFile))
+	return err == nil && string(stored) == key
+}
+
+// SetTidy records key as that of the last go mod tidy of the tmp directory of
+// shuttlelocaldir. An empty key removes the record, eg. before go.mod is
+// changed.
+func SetTidy(shuttlelocaldir, key string) error {
+	file := path.Join(shuttlelocaldir, "tmp", TidyKeyFile)
+	if key == "" {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(file, []byte(key), 0o644)
+}
@@ -0,0 +1,58 @@
+package codegen
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTidyKey(t *testing.T) {
+	rootDir := t.TempDir()
+	actionsDir := path.Join(rootDir, "actions")
+	shuttlelocaldir := path.Join(rootDir, ".shuttle", "actions")
+	tmpdir := path.Join(shuttlelocaldir, "tmp")
+	require.NoError(t, os.MkdirAll(actionsDir, 0o755))
+	require.NoError(t, os.MkdirAll(tmpdir, 0o755))
+	write := func(t *testing.T, name, content string) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(name, []byte(content), 0o644))
+	}
+	key := func(t *testing.T) string {
+		t.Helper()
+		key, err := TidyKey(rootDir, actionsDir, shuttlelocaldir)
+		require.NoError(t, err)
+		return key
+	}
+	write(t, path.Join(actionsDir, "go.mod"), "module actions\n")
+	write(t, path.Join(tmpdir, "build.go"), "package main\n\nimport \"fmt\"\n\nfunc Build() { fmt.Println(1) }\n")
+
+	initial := key(t)
+	write(t, path.Join(tmpdir, "build.go"), "package main\n\nimport \"fmt\"\n\nfunc Build() { fmt.Println(2) }\n")
+	assert.Equal(t, initial, key(t), "changes of code must not change the key")
+
+	write(t, path.Join(tmpdir, "build.go"), "package main\n\nimport \"os\"\n\nfunc Build() { os.Exit(2) }\n")
+	imports := key(t)
+	assert.NotEqual(t, initial, imports, "changes of imports must change the key")
+
+	write(t, path.Join(rootDir, "go.mod"), "module project\n")
+	assert.NotEqual(t, imports, key(t), "changes of the project module must change the key")
+}
+
+func TestIsTidy(t *testing.T) {
+	shuttlelocaldir := t.TempDir()
+	tmpdir := path.Join(shuttlelocaldir, "tmp")
+	require.NoError(t, os.MkdirAll(tmpdir, 0o755))
+
+	require.NoError(t, SetTidy(shuttlelocaldir, "key"))
+	assert.False(t, IsTidy(shuttlelocaldir, "key"), "go.mod must exist")
+
+	require.NoError(t, os.WriteFile(path.Join(tmpdir, "go.mod"), []byte("module actions\n"), 0o644))
+	assert.True(t, IsTidy(shuttlelocaldir, "key"))
+	assert.False(t, IsTidy(shuttlelocaldir, "other"))
+
+	require.NoError(t, SetTidy(shuttlelocaldir, ""))
+	assert.False(t, IsTidy(shuttlelocaldir, "key"))
+}
@@ -0,0 +1,30 @@
+package codegen
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// ModVerify verifies the dependencies of the generated actions module in the
+// module cache against go.sum. Dependencies downloaded by the build are
+// checked against go.sum by the go command itself.
+func ModVerify(ctx context.Context, ui *ui.UI, shuttlelocaldir string, env []string) error {
+	cmd := exec.Command("go", "mod", "verify")
+	cmd.Dir = path.Join(shuttlelocaldir, "tmp")
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Env = append(cmd.Env, "GOWORK=off")
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, strings.TrimSpace(string(output)))
+	}
+	ui.Verboseln("go mod verify: %s", strings.TrimSpace(string(output)))
+
+	return nil
+}
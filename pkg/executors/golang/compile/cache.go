@@ -0,0 +1,46 @@
+package compile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// goCacheEnv returns environment variables pointing the go build and module
+// caches at the directory in SHUTTLE_GOLANG_ACTIONS_CACHE_DIR. This allows
+// ephemeral environments, such as CI runners, to reuse a persistent cache
+// between compilations. If the variable is not set no variables are returned.
+func goCacheEnv() ([]string, error) {
+	cacheDir := os.Getenv("SHUTTLE_GOLANG_ACTIONS_CACHE_DIR")
+	if cacheDir == "" {
+		return nil, nil
+	}
+
+	cacheDir, err := filepath.Abs(cacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve golang actions cache dir: %w", err)
+	}
+
+	if err := ensureWritable(cacheDir); err != nil {
+		return nil, fmt.Errorf("golang actions cache dir '%s' is not writable: %w", cacheDir, err)
+	}
+
+	return []string{
+		fmt.Sprintf("GOCACHE=%s", filepath.Join(cacheDir, "build")),
+		fmt.Sprintf("GOMODCACHE=%s", filepath.Join(cacheDir, "mod")),
+	}, nil
+}
+
+func ensureWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(dir, ".shuttle-write-check-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+
+	return os.Remove(file.Name())
+}
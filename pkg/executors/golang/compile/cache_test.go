@@ -0,0 +1,48 @@
+package compile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoCacheEnv(t *testing.T) {
+	t.Run("not set", func(t *testing.T) {
+		t.Setenv("SHUTTLE_GOLANG_ACTIONS_CACHE_DIR", "")
+
+		env, err := goCacheEnv()
+
+		require.NoError(t, err)
+		assert.Nil(t, env)
+	})
+
+	t.Run("creates missing cache dir", func(t *testing.T) {
+		cacheDir := filepath.Join(t.TempDir(), "cache")
+		t.Setenv("SHUTTLE_GOLANG_ACTIONS_CACHE_DIR", cacheDir)
+
+		env, err := goCacheEnv()
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"GOCACHE=" + filepath.Join(cacheDir, "build"),
+			"GOMODCACHE=" + filepath.Join(cacheDir, "mod"),
+		}, env)
+		assert.DirExists(t, cacheDir)
+		entries, err := os.ReadDir(cacheDir)
+		require.NoError(t, err)
+		assert.Empty(t, entries, "write check file must be removed")
+	})
+
+	t.Run("not writable", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(file, nil, 0o644))
+		t.Setenv("SHUTTLE_GOLANG_ACTIONS_CACHE_DIR", file)
+
+		_, err := goCacheEnv()
+
+		assert.ErrorContains(t, err, "golang actions cache dir '"+file+"' is not writable")
+	})
+}
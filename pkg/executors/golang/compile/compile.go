@@ -9,6 +9,7 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	"dagger.io/dagger"
 	"github.com/lunarway/shuttle/pkg/executors/golang/codegen"
@@ -17,6 +18,7 @@ import (
 	golangerrors "github.com/lunarway/shuttle/pkg/executors/golang/errors"
 	"github.com/lunarway/shuttle/pkg/executors/golang/parser"
 	"github.com/lunarway/shuttle/pkg/executors/golang/shuttlefolder"
+	"github.com/lunarway/shuttle/pkg/telemetry"
 	"github.com/lunarway/shuttle/pkg/ui"
 	"golang.org/x/sync/errgroup"
 )
@@ -45,14 +47,24 @@ type Binaries struct {
 // 2.2. Generate main file
 //
 // 3. Move binary to .shuttle/actions/binary-<hash>
+//
+// Compilation is serialized across shuttle processes by a lock of the
+// .shuttle/actions directory.
 func Compile(ctx context.Context, ui *ui.UI, discovered *discover.Discovered) (*Binaries, error) {
+	return CompileFor(ctx, ui, discovered, shuttlefolder.HostTarget())
+}
+
+// CompileFor compiles the discovered actions like Compile but for target.
+// Binaries of other targets than the host cannot be run by shuttle but are
+// meant to be run elsewhere, eg. inside a container.
+func CompileFor(ctx context.Context, ui *ui.UI, discovered *discover.Discovered, target shuttlefolder.Target) (*Binaries, error) {
 	egrp, ctx := errgroup.WithContext(ctx)
 	binaries := &Binaries{}
 	if discovered.Local != nil {
 		egrp.Go(func() error {
 			ui.Verboseln("compiling golang actions binary for: %s", discovered.Local.DirPath)
 
-			path, err := compile(ctx, ui, discovered.Local)
+			path, err := compile(ctx, ui, discovered.Local, target)
 			if err != nil {
 				return err
 			}
@@ -65,7 +77,7 @@ func Compile(ctx context.Context, ui *ui.UI, discovered *discover.Discovered) (*
 		egrp.Go(func() error {
 			ui.Verboseln("compiling golang actions binary for: %s", discovered.Plan.DirPath)
 
-			path, err := compile(ctx, ui, discovered.Plan)
+			path, err := compile(ctx, ui, discovered.Plan, target)
 			if err != nil {
 				return err
 			}
@@ -82,13 +94,24 @@ func Compile(ctx context.Context, ui *ui.UI, discovered *discover.Discovered) (*
 	return binaries, nil
 }
 
-func compile(ctx context.Context, ui *ui.UI, actions *discover.ActionsDiscovered) (string, error) {
+// compile compiles actions for target recording the compilation as a span.
+func compile(ctx context.Context, ui *ui.UI, actions *discover.ActionsDiscovered, target shuttlefolder.Target) (string, error) {
+	ctx, endSpan := telemetry.StartSpan(ctx, "shuttle.golang.compile", map[string]string{
+		telemetry.TelemetryGolangActions: actions.DirPath,
+		telemetry.TelemetryGolangTarget:  target.String(),
+	})
+	binaryPath, err := compileActions(ctx, ui, actions, target)
+	endSpan(err)
+	return binaryPath, err
+}
+
+func compileActions(ctx context.Context, ui *ui.UI, actions *discover.ActionsDiscovered, target shuttlefolder.Target) (string, error) {
 	hash, err := matcher.GetHash(ctx, actions)
 	if err != nil {
 		return "", err
 	}
 
-	binaryPath, ok, err := matcher.BinaryMatches(ctx, ui, hash, actions)
+	binaryPath, ok, err := matcher.BinaryMatches(ctx, ui, hash, target, actions)
 	if err != nil {
 		return "", err
 	}
@@ -101,10 +124,49 @@ func compile(ctx context.Context, ui *ui.UI, actions *discover.ActionsDiscovered
 
 	shuttlelocaldir := path.Join(actions.ParentDir, ".shuttle/actions")
 
+	unlock, err := shuttlefolder.Lock(ctx, shuttlelocaldir, func() {
+		ui.Verboseln("waiting for another shuttle compiling golang actions in: %s", shuttlelocaldir)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to lock golang actions directory: %w", err)
+	}
+	defer unlock()
+
+	// another shuttle may have compiled the binary while waiting for the lock
+	binaryPath, ok, err = matcher.BinaryMatches(ctx, ui, hash, target, actions)
+	if err != nil {
+		return "", err
+	}
+	if ok && !alwaysBuild {
+		ui.Verboseln("file already compiled by another shuttle continueing")
+		return binaryPath, nil
+	}
+
+	maxAge, err := BinaryMaxAge()
+	if err != nil {
+		return "", err
+	}
+	// binaries are only added below so unused binaries are removed now
+	collectBinaries(ui, shuttlelocaldir, hash, maxAge, time.Now())
+
+	finalBinaryPath := shuttlefolder.CalculateBinaryPath(shuttlelocaldir, hash, target)
+	if usePrebuiltBinary(ui, actions, hash, target, finalBinaryPath) {
+		return finalBinaryPath, nil
+	}
+	remote, err := remoteCacheFromEnv()
+	if err != nil {
+		return "", err
+	}
+	if remote != nil && downloadBinary(ctx, ui, remote, shuttlelocaldir, hash, target) {
+		return finalBinaryPath, nil
+	}
+
 	if err = shuttlefolder.GenerateTmpDir(ctx, shuttlelocaldir); err != nil {
 		return "", err
 	}
-	if err = shuttlefolder.CopyFiles(ctx, shuttlelocaldir, actions); err != nil {
+	// go.mod and go.sum are tidied in tmp so they are only replaced when the
+	// inputs of go mod tidy change
+	if err = shuttlefolder.SyncFiles(ctx, shuttlelocaldir, actions, "main.go", "go.mod", "go.sum", codegen.TidyKeyFile); err != nil {
 		return "", err
 	}
 
@@ -118,26 +180,76 @@ func compile(ctx context.Context, ui *ui.UI, actions *discover.ActionsDiscovered
 	}
 
 	var binarypath string
+	output := "actions" + target.ExeSuffix()
+
+	mode, err := goModMode(actions)
+	if err != nil {
+		return "", err
+	}
+	ui.Verboseln("resolving golang actions dependencies with -mod=%s", mode)
 
-	if err := codegen.NewPatcher().Patch(ctx, actions.ParentDir, shuttlelocaldir); err != nil {
-		return "", fmt.Errorf("failed to patch generated go.mod: %w", err)
+	tidyKey, tidy := "", true
+	if mode == modMod {
+		tidyKey, err = codegen.TidyKey(actions.ParentDir, actions.DirPath, shuttlelocaldir)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash go mod tidy inputs: %w", err)
+		}
+		tidy = !codegen.IsTidy(shuttlelocaldir, tidyKey)
+	}
+	if tidy {
+		if err := codegen.SetTidy(shuttlelocaldir, ""); err != nil {
+			return "", err
+		}
+		if err := shuttlefolder.CopyModuleFiles(shuttlelocaldir, actions); err != nil {
+			return "", err
+		}
+		if mode == modVendor {
+			// replace directives must match vendor/modules.txt so go.mod is
+			// left as is and the vendored copies are used instead
+			if err := verifyVendored(actions.DirPath); err != nil {
+				return "", err
+			}
+		} else if err := codegen.NewPatcher().Patch(ctx, actions.ParentDir, shuttlelocaldir); err != nil {
+			return "", fmt.Errorf("failed to patch generated go.mod: %w", err)
+		}
+	} else {
+		ui.Verboseln("go.mod already tidy, skipping go mod tidy")
 	}
 
 	if goInstalled() {
-		if err = codegen.ModTidy(ctx, ui, shuttlelocaldir); err != nil {
-			return "", fmt.Errorf("go mod tidy failed: %w", err)
+		env, err := goCacheEnv()
+		if err != nil {
+			return "", err
+		}
+		env = append(env, mode.env()...)
+
+		switch mode {
+		case modMod:
+			if tidy {
+				if err = codegen.ModTidy(ctx, ui, shuttlelocaldir, env); err != nil {
+					return "", fmt.Errorf("go mod tidy failed: %w", err)
+				}
+				if err = codegen.SetTidy(shuttlelocaldir, tidyKey); err != nil {
+					return "", err
+				}
+			}
+		case modReadonly:
+			if err = codegen.ModVerify(ctx, ui, shuttlelocaldir, env); err != nil {
+				return "", fmt.Errorf("go mod verify failed: %w", err)
+			}
 		}
 
-		if err = codegen.Format(ctx, ui, shuttlelocaldir); err != nil {
+		if err = codegen.Format(ctx, ui, shuttlelocaldir, env); err != nil {
 			return "", fmt.Errorf("go fmt failed: %w", err)
 		}
 
-		binarypath, err = codegen.CompileBinary(ctx, ui, shuttlelocaldir)
+		// the build targets the host unless cross compiling
+		binarypath, err = codegen.CompileBinary(ctx, ui, shuttlelocaldir, append(env, target.Env()...), output)
 		if err != nil {
-			return "", fmt.Errorf("go build failed: %w", err)
+			return "", fmt.Errorf("go build for %s failed: %w", target, err)
 		}
 	} else if goDaggerFallback() {
-		binarypath, err = compileWithDagger(ctx, ui, shuttlelocaldir)
+		binarypath, err = compileWithDagger(ctx, ui, shuttlelocaldir, target, mode, output)
 		if err != nil {
 			return "", fmt.Errorf("failed to compile with dagger: %w", err)
 		}
@@ -145,15 +257,52 @@ func compile(ctx context.Context, ui *ui.UI, actions *discover.ActionsDiscovered
 		return "", golangerrors.ErrGolangActionNoBuilder
 	}
 
-	finalBinaryPath := shuttlefolder.CalculateBinaryPath(shuttlelocaldir, hash)
+	// the binary is built in the tmp directory and renamed into place such that
+	// a partially written binary is never matched
 	if err := shuttlefolder.Move(binarypath, finalBinaryPath); err != nil {
 		return "", fmt.Errorf("failed to remove actions binary to final destination: %w", err)
 	}
 
+	if remote != nil && remoteCacheUpload() {
+		name := shuttlefolder.BinaryName(hash, target)
+		if err := remote.Upload(ctx, name, finalBinaryPath); err != nil {
+			ui.Errorln("Ignoring failed upload to golang actions remote cache: %v", err)
+		}
+	}
+
 	return finalBinaryPath, nil
 }
 
-func compileWithDagger(ctx context.Context, ui *ui.UI, shuttlelocaldir string) (string, error) {
+// downloadBinary downloads the binary of hash for target from the remote cache
+// and returns whether it did. Failures are printed and the binary is compiled
+// instead as the remote cache must never fail a run.
+func downloadBinary(
+	ctx context.Context,
+	ui *ui.UI,
+	remote RemoteCache,
+	shuttlelocaldir string,
+	hash string,
+	target shuttlefolder.Target,
+) bool {
+	binaryPath := shuttlefolder.CalculateBinaryPath(shuttlelocaldir, hash, target)
+	if err := os.MkdirAll(path.Dir(binaryPath), 0o755); err != nil {
+		ui.Errorln("Ignoring golang actions remote cache: %v", err)
+		return false
+	}
+	ok, err := remote.Download(ctx, shuttlefolder.BinaryName(hash, target), binaryPath)
+	if err != nil {
+		ui.Errorln("Ignoring failed download from golang actions remote cache: %v", err)
+		return false
+	}
+	if !ok {
+		ui.Verboseln("binary not in remote cache, compiling...")
+		return false
+	}
+	ui.Verboseln("downloaded binary from remote cache")
+	return true
+}
+
+func compileWithDagger(ctx context.Context, ui *ui.UI, shuttlelocaldir string, target shuttlefolder.Target, mode modMode, output string) (string, error) {
 	client, err := dagger.Connect(ctx, dagger.WithLogOutput(os.Stderr))
 	if err != nil {
 		return "", fmt.Errorf("failed to start dagger: %w", err)
@@ -178,21 +327,33 @@ func compileWithDagger(ctx context.Context, ui *ui.UI, shuttlelocaldir string) (
 	nakedShuttleDir := strings.TrimPrefix(strings.TrimPrefix(shuttlelocaldir, dir), "/")
 	log.Printf("nakedShuttleDir: %s", nakedShuttleDir)
 
-	shuttleBinary := client.Container().
+	container := client.Container().
 		From(getGolangImage()).
 		WithWorkdir("/app").
 		WithDirectory(".", src).
-		WithWorkdir(path.Join(nakedShuttleDir, "tmp")).
-		WithExec([]string{
-			"go", "mod", "tidy",
-		}).
+		WithWorkdir(path.Join(nakedShuttleDir, "tmp"))
+	for _, env := range mode.env() {
+		name, value, _ := strings.Cut(env, "=")
+		container = container.WithEnvVariable(name, value)
+	}
+	switch mode {
+	case modMod:
+		container = container.WithExec([]string{"go", "mod", "tidy"})
+	case modReadonly:
+		container = container.WithExec([]string{"go", "mod", "verify"})
+	}
+
+	shuttleBinary := container.
 		WithExec([]string{
 			"go", "fmt", "./...",
 		}).
+		WithEnvVariable("GOOS", target.GOOS).
+		WithEnvVariable("GOARCH", target.GOARCH).
 		WithExec([]string{
 			"go",
 			"build",
-			// TODO: add cross compilation
+			"-o",
+			output,
 		})
 
 	_, err = shuttleBinary.Sync(ctx)
@@ -200,8 +361,8 @@ func compileWithDagger(ctx context.Context, ui *ui.UI, shuttlelocaldir string) (
 		return "", fmt.Errorf("dagger failed to build binary, see shuttle ls -v to see error output: %w", err)
 	}
 
-	shuttleActionsDirectory := shuttleBinary.File("actions")
-	exported, err := shuttleActionsDirectory.Export(ctx, path.Join(shuttlelocaldir, "tmp", "actions"))
+	shuttleActionsDirectory := shuttleBinary.File(output)
+	exported, err := shuttleActionsDirectory.Export(ctx, path.Join(shuttlelocaldir, "tmp", output))
 	if err != nil {
 		return "", fmt.Errorf("could not export dagger shuttle actions binary, err: %w", err)
 	}
@@ -209,7 +370,7 @@ func compileWithDagger(ctx context.Context, ui *ui.UI, shuttlelocaldir string) (
 		return "", fmt.Errorf("failed to export binary")
 	}
 
-	return path.Join(shuttlelocaldir, "tmp", "actions"), nil
+	return path.Join(shuttlelocaldir, "tmp", output), nil
 }
 
 func goInstalled() bool {
@@ -240,6 +401,19 @@ func getGolangImage() string {
 	return golangImage
 }
 
+// remoteCacheUpload returns whether compiled binaries are uploaded to the
+// remote cache. Uploads are disabled with
+// SHUTTLE_GOLANG_ACTIONS_REMOTE_CACHE_UPLOAD=false, eg. for environments only
+// allowed to read the cache.
+func remoteCacheUpload() bool {
+	upload, err := strconv.ParseBool(os.Getenv("SHUTTLE_GOLANG_ACTIONS_REMOTE_CACHE_UPLOAD"))
+	if err != nil {
+		return true
+	}
+
+	return upload
+}
+
 func goDaggerFallback() bool {
 	daggerFallback := os.Getenv("SHUTTLE_GOLANG_ACTIONS_DAGGER_FALLBACK")
 
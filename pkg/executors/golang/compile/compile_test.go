@@ -3,13 +3,19 @@ package compile_test
 import (
 	"context"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/lunarway/shuttle/pkg/config"
 	"github.com/lunarway/shuttle/pkg/executors/golang/compile"
+	"github.com/lunarway/shuttle/pkg/executors/golang/compile/matcher"
 	"github.com/lunarway/shuttle/pkg/executors/golang/discover"
+	"github.com/lunarway/shuttle/pkg/executors/golang/shuttlefolder"
 	"github.com/lunarway/shuttle/pkg/ui"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCompile(t *testing.T) {
@@ -28,3 +34,99 @@ func TestCompile(t *testing.T) {
 
 	assert.Contains(t, path.Local.Path, "testdata/simple/.shuttle/actions/binaries/actions-")
 }
+
+func TestCompileFor_crossCompile(t *testing.T) {
+	ctx := context.Background()
+	discovered, err := discover.Discover(
+		ctx,
+		"testdata/simple/shuttle.yaml",
+		&config.ShuttleProjectContext{},
+	)
+	assert.NoError(t, err)
+
+	uiout := ui.Create(os.Stdout, os.Stderr)
+	target := shuttlefolder.Target{GOOS: "linux", GOARCH: "arm64"}
+	if target.IsHost() {
+		target = shuttlefolder.Target{GOOS: "linux", GOARCH: "amd64"}
+	}
+
+	host, err := compile.Compile(ctx, uiout, discovered)
+	assert.NoError(t, err)
+	cross, err := compile.CompileFor(ctx, uiout, discovered, target)
+	assert.NoError(t, err)
+
+	assert.Contains(t, cross.Local.Path, "testdata/simple/.shuttle/actions/binaries/actions-"+target.GOOS+"-"+target.GOARCH+"-")
+	assert.NotEqual(t, host.Local.Path, cross.Local.Path)
+	assert.FileExists(t, host.Local.Path, "cross compiling must not replace the host binary")
+	assert.FileExists(t, cross.Local.Path)
+}
+
+func TestCompile_concurrent(t *testing.T) {
+	ctx := context.Background()
+	discovered, err := discover.Discover(
+		ctx,
+		"testdata/simple/shuttle.yaml",
+		&config.ShuttleProjectContext{},
+	)
+	require.NoError(t, err)
+	hash, err := matcher.GetHash(ctx, discovered.Local)
+	require.NoError(t, err)
+	expected := shuttlefolder.CalculateBinaryPath("testdata/simple/.shuttle/actions", hash, shuttlefolder.HostTarget())
+	if err := os.Remove(expected); !os.IsNotExist(err) {
+		require.NoError(t, err)
+	}
+
+	uiout := ui.Create(os.Stdout, os.Stderr)
+	const compilations = 4
+	paths := make([]string, compilations)
+	var wg sync.WaitGroup
+	for i := 0; i < compilations; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			binaries, err := compile.Compile(ctx, uiout, discovered)
+			if assert.NoError(t, err) {
+				paths[i] = binaries.Local.Path
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, path := range paths {
+		assert.Equal(t, expected, path, "all compilations must result in the same binary")
+	}
+	output, err := exec.Command(expected, "lsjson").CombinedOutput()
+	assert.NoError(t, err, "binary must run: %s", output)
+}
+
+func TestPrebuild(t *testing.T) {
+	ctx := context.Background()
+	discovered, err := discover.Discover(
+		ctx,
+		"testdata/simple/shuttle.yaml",
+		&config.ShuttleProjectContext{},
+	)
+	require.NoError(t, err)
+	hash, err := matcher.GetHash(ctx, discovered.Local)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "actions-linux-amd64-0000")
+	require.NoError(t, os.WriteFile(stale, []byte("stale"), 0o755))
+	other := filepath.Join(dir, "README.md")
+	require.NoError(t, os.WriteFile(other, []byte("binaries"), 0o644))
+
+	uiout := ui.Create(os.Stdout, os.Stderr)
+	targets := []shuttlefolder.Target{{GOOS: "linux", GOARCH: "amd64"}, {GOOS: "windows", GOARCH: "amd64"}}
+	paths, err := compile.Prebuild(ctx, uiout, discovered.Local, targets, dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		filepath.Join(dir, shuttlefolder.PrebuiltName(hash, targets[0])),
+		filepath.Join(dir, shuttlefolder.PrebuiltName(hash, targets[1])),
+	}, paths)
+	for _, path := range paths {
+		assert.FileExists(t, path)
+	}
+	assert.NoFileExists(t, stale, "binaries of other sources must be removed")
+	assert.FileExists(t, other, "other files must be left alone")
+}
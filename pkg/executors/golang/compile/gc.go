@@ -0,0 +1,51 @@
+package compile
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/executors/golang/shuttlefolder"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// defaultBinaryMaxAge is how long binaries are kept after their last use
+// unless configured with SHUTTLE_GOLANG_ACTIONS_BINARY_MAX_AGE.
+const defaultBinaryMaxAge = 30 * 24 * time.Hour
+
+// BinaryMaxAge returns how long compiled binaries are kept after they were
+// last used. It is set with SHUTTLE_GOLANG_ACTIONS_BINARY_MAX_AGE and 0
+// disables removing binaries when compiling.
+func BinaryMaxAge() (time.Duration, error) {
+	raw := os.Getenv("SHUTTLE_GOLANG_ACTIONS_BINARY_MAX_AGE")
+	if raw == "" {
+		return defaultBinaryMaxAge, nil
+	}
+	maxAge, err := time.ParseDuration(raw)
+	if err != nil || maxAge < 0 {
+		return 0, fmt.Errorf("SHUTTLE_GOLANG_ACTIONS_BINARY_MAX_AGE '%s' is invalid: must be a duration, eg. 720h", raw)
+	}
+	return maxAge, nil
+}
+
+// collectBinaries removes the binaries of shuttlelocaldir not used for
+// maxAge. Binaries of the current sources with hash are kept. Binaries of
+// earlier sources are kept until then such that switching back to them, eg.
+// by checking out another branch, does not compile the actions again.
+// Failures are printed as they must never fail a run.
+func collectBinaries(ui *ui.UI, shuttlelocaldir, hash string, maxAge time.Duration, now time.Time) {
+	if maxAge == 0 {
+		return
+	}
+	binaries, err := shuttlefolder.Binaries(shuttlelocaldir)
+	if err != nil {
+		ui.Errorln("Could not list golang actions binaries: %v", err)
+		return
+	}
+	for _, binary := range shuttlefolder.StaleBinaries(binaries, hash, maxAge, now) {
+		ui.Verboseln("removing unused binary: %s", binary.Path)
+		if err := os.Remove(binary.Path); err != nil && !os.IsNotExist(err) {
+			ui.Errorln("Could not remove unused golang actions binary: %v", err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package compile
+
+import (
+	"io"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/executors/golang/shuttlefolder"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestBinaryMaxAge(t *testing.T) {
+	tt := []struct {
+		name   string
+		value  string
+		maxAge time.Duration
+		err    string
+	}{
+		{name: "default", maxAge: 30 * 24 * time.Hour},
+		{name: "set", value: "168h", maxAge: 168 * time.Hour},
+		{name: "disabled", value: "0", maxAge: 0},
+		{name: "negative", value: "-1h", err: "SHUTTLE_GOLANG_ACTIONS_BINARY_MAX_AGE '-1h' is invalid: must be a duration, eg. 720h"},
+		{name: "invalid", value: "a month", err: "SHUTTLE_GOLANG_ACTIONS_BINARY_MAX_AGE 'a month' is invalid: must be a duration, eg. 720h"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("SHUTTLE_GOLANG_ACTIONS_BINARY_MAX_AGE", tc.value)
+
+			maxAge, err := BinaryMaxAge()
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.maxAge, maxAge)
+		})
+	}
+}
+
+func TestCollectBinaries(t *testing.T) {
+	now := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	shuttlelocaldir := t.TempDir()
+	binarydir := path.Join(shuttlelocaldir, shuttlefolder.TaskBinaryDir)
+	require.NoError(t, os.MkdirAll(binarydir, 0o755))
+	binary := func(t *testing.T, hash string, lastUsed time.Time) string {
+		t.Helper()
+		name := path.Join(binarydir, shuttlefolder.BinaryName(hash, shuttlefolder.HostTarget()))
+		require.NoError(t, os.WriteFile(name, nil, 0o755))
+		require.NoError(t, os.Chtimes(name, lastUsed, lastUsed))
+		return name
+	}
+	current := binary(t, "h1:current", now.Add(-60*24*time.Hour))
+	unused := binary(t, "h1:unused", now.Add(-8*24*time.Hour))
+	recent := binary(t, "h1:recent", now.Add(-24*time.Hour))
+	uiout := ui.Create(io.Discard, io.Discard)
+
+	collectBinaries(uiout, shuttlelocaldir, "h1:current", 0, now)
+	assert.FileExists(t, unused, "nothing is removed without a max age")
+
+	collectBinaries(uiout, shuttlelocaldir, "h1:current", 7*24*time.Hour, now)
+	assert.FileExists(t, current, "binaries of the current sources must be kept")
+	assert.NoFileExists(t, unused)
+	assert.FileExists(t, recent, "recently used binaries of earlier sources must be kept")
+}
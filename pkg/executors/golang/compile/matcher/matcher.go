@@ -3,14 +3,14 @@ package matcher
 import (
 	"bytes"
 	"context"
-	"encoding/hex"
 	"errors"
-	"fmt"
 	"io"
 	"os"
 	"path"
+	"time"
 
 	"github.com/lunarway/shuttle/pkg/executors/golang/discover"
+	"github.com/lunarway/shuttle/pkg/executors/golang/shuttlefolder"
 	"github.com/lunarway/shuttle/pkg/ui"
 	"golang.org/x/exp/slices"
 	"golang.org/x/mod/sumdb/dirhash"
@@ -20,6 +20,7 @@ func BinaryMatches(
 	ctx context.Context,
 	ui *ui.UI,
 	hash string,
+	target shuttlefolder.Target,
 	actions *discover.ActionsDiscovered,
 ) (string, bool, error) {
 	shuttlebindir := path.Join(actions.ParentDir, ".shuttle/actions/binaries")
@@ -29,37 +30,43 @@ func BinaryMatches(
 		return "", false, nil
 	}
 
-	entries, err := os.ReadDir(shuttlebindir)
-	if err != nil {
-		return "", false, err
+	// The folder holds a binary per target, so only the one of the wanted
+	// target is considered
+	expectedPath := path.Join(shuttlebindir, shuttlefolder.BinaryName(hash, target))
+	_, err := os.Stat(expectedPath)
+	if errors.Is(err, os.ErrNotExist) {
+		ui.Verboseln("binary does not match, rebuilding... (expected=%s)", expectedPath)
+		return "", false, nil
 	}
-
-	if len(entries) == 0 {
+	if err != nil {
 		return "", false, err
 	}
+	// the modification time of binaries is the time they were last used such
+	// that unused binaries are removed. Failures are ignored, eg. read-only
+	// file systems.
+	now := time.Now()
+	_ = os.Chtimes(expectedPath, now, now)
 
-	// We only expect a single binary in the folder, so we just take the first entry if it exists
-	binary := entries[0]
-
-	expectedPath := fmt.Sprintf("actions-%s", hex.EncodeToString([]byte(hash)[:16]))
-	actualName := binary.Name()
-	if actualName == expectedPath {
-		return path.Join(shuttlebindir, binary.Name()), true, nil
-	} else {
-		ui.Verboseln("binary does not match, rebuilding... (actual=%s, expected=%s)", actualName, expectedPath)
-		return "", false, nil
-	}
+	return expectedPath, true, nil
 }
 
+// moduleFiles are the files of the actions module recording its dependencies.
+var moduleFiles = []string{"go.mod", "go.sum", "vendor/modules.txt"}
+
 func GetHash(ctx context.Context, actions *discover.ActionsDiscovered) (string, error) {
+	// names are relative to the actions directory such that the hash is the
+	// same wherever the actions are, eg. prebuilt binaries of plans
 	entries := make([]string, len(actions.Files))
-
-	for i, task := range actions.Files {
-		entries[i] = path.Join(actions.DirPath, task)
+	copy(entries, actions.Files)
+	// the binary is rebuilt when the dependencies of the actions change
+	for _, moduleFile := range moduleFiles {
+		if _, err := os.Stat(path.Join(actions.DirPath, moduleFile)); err == nil {
+			entries = append(entries, moduleFile)
+		}
 	}
 
 	open := func(name string) (io.ReadCloser, error) {
-		b, err := os.ReadFile(name)
+		b, err := os.ReadFile(path.Join(actions.DirPath, name))
 		if err != nil {
 			return nil, err
 		}
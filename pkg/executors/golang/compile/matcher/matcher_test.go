@@ -0,0 +1,91 @@
+package matcher
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/executors/golang/discover"
+	"github.com/lunarway/shuttle/pkg/executors/golang/shuttlefolder"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestGetHash_distinctSources(t *testing.T) {
+	actions := func(source string) *discover.ActionsDiscovered {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "build.go"), []byte(source), 0o644))
+		return &discover.ActionsDiscovered{Files: []string{"build.go"}, DirPath: dir, ParentDir: dir}
+	}
+	nameOf := func(actions *discover.ActionsDiscovered) string {
+		hash, err := GetHash(context.Background(), actions)
+		require.NoError(t, err)
+		return shuttlefolder.BinaryName(hash, shuttlefolder.HostTarget())
+	}
+
+	build := actions("package main\n\nfunc Build() error { return nil }\n")
+	otherBuild := actions("package main\n\nfunc Build() error { return errBuild }\n")
+
+	assert.NotEqual(t, nameOf(build), nameOf(otherBuild))
+}
+
+func TestGetHash_dependencies(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "build.go"), []byte("package main\n"), 0o644))
+	actions := &discover.ActionsDiscovered{Files: []string{"build.go"}, DirPath: dir, ParentDir: dir}
+	hash := func() string {
+		hash, err := GetHash(context.Background(), actions)
+		require.NoError(t, err)
+		return hash
+	}
+
+	withoutSum := hash()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.sum"), []byte("github.com/a/b v1.0.0 h1:abc=\n"), 0o644))
+	withSum := hash()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "vendor"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vendor", "modules.txt"), []byte("# github.com/a/b v1.0.0\n"), 0o644))
+	vendored := hash()
+
+	assert.NotEqual(t, withoutSum, withSum, "go.sum must be part of the hash")
+	assert.NotEqual(t, withSum, vendored, "vendor/modules.txt must be part of the hash")
+}
+
+func TestGetHash_independentOfDirectory(t *testing.T) {
+	actions := func() *discover.ActionsDiscovered {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "build.go"), []byte("package main\n"), 0o644))
+		return &discover.ActionsDiscovered{Files: []string{"build.go"}, DirPath: dir, ParentDir: dir}
+	}
+
+	hash, err := GetHash(context.Background(), actions())
+	require.NoError(t, err)
+	otherHash, err := GetHash(context.Background(), actions())
+	require.NoError(t, err)
+
+	assert.Equal(t, hash, otherHash, "binaries must be shared by copies of the same actions, eg. prebuilt binaries of plans")
+}
+
+func TestBinaryMatches_marksUse(t *testing.T) {
+	dir := t.TempDir()
+	actions := &discover.ActionsDiscovered{DirPath: dir, ParentDir: dir}
+	target := shuttlefolder.HostTarget()
+	binary := filepath.Join(dir, ".shuttle/actions/binaries", shuttlefolder.BinaryName("h1:abc", target))
+	require.NoError(t, os.MkdirAll(filepath.Dir(binary), 0o755))
+	require.NoError(t, os.WriteFile(binary, nil, 0o755))
+	lastUsed := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(binary, lastUsed, lastUsed))
+
+	path, ok, err := BinaryMatches(context.Background(), ui.Create(io.Discard, io.Discard), "h1:abc", target, actions)
+
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, binary, path)
+	info, err := os.Stat(binary)
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().After(lastUsed), "the binary must be marked as used")
+}
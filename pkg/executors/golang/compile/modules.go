@@ -0,0 +1,128 @@
+package compile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/executors/golang/discover"
+)
+
+// cmderPackage is the package imported by the generated main file. It must be
+// vendored along with the dependencies of the actions.
+const cmderPackage = "github.com/lunarway/shuttle/pkg/executors/golang/cmder"
+
+// modMode is how the dependencies of golang actions are resolved when
+// compiling them. The modes match the -mod flag of the go command.
+type modMode string
+
+const (
+	// modMod tidies go.mod and go.sum of the actions before compiling such
+	// that missing dependencies are downloaded.
+	modMod modMode = "mod"
+	// modReadonly compiles with go.mod and go.sum as they are after verifying
+	// the downloaded modules against go.sum.
+	modReadonly modMode = "readonly"
+	// modVendor compiles with the dependencies in the vendor directory of the
+	// actions without network access.
+	modVendor modMode = "vendor"
+)
+
+// goModMode returns how the dependencies of actions are resolved. It is set
+// with SHUTTLE_GOLANG_ACTIONS_MOD or the -mod flag of GOFLAGS. Otherwise
+// actions with a vendor directory are compiled with it and all others are
+// tidied.
+func goModMode(actions *discover.ActionsDiscovered) (modMode, error) {
+	vendored, err := isVendored(actions.DirPath)
+	if err != nil {
+		return "", err
+	}
+
+	mode, source := modMode(os.Getenv("SHUTTLE_GOLANG_ACTIONS_MOD")), "SHUTTLE_GOLANG_ACTIONS_MOD"
+	if mode == "" {
+		mode, source = goFlagsModMode(os.Getenv("GOFLAGS")), "GOFLAGS"
+	}
+	switch mode {
+	case "":
+		if vendored {
+			return modVendor, nil
+		}
+		return modMod, nil
+	case modMod, modReadonly:
+		return mode, nil
+	case modVendor:
+		if !vendored {
+			return "", fmt.Errorf("golang actions in '%s' are compiled with -mod=vendor by %s but are not vendored: run 'go mod vendor' in the directory", actions.DirPath, source)
+		}
+		return mode, nil
+	default:
+		return "", fmt.Errorf("-mod=%s of %s is invalid: must be one of 'mod', 'readonly' or 'vendor'", mode, source)
+	}
+}
+
+// goFlagsModMode returns the value of the last -mod flag of goFlags if any.
+func goFlagsModMode(goFlags string) modMode {
+	var mode modMode
+	for _, flag := range strings.Fields(goFlags) {
+		if value, ok := strings.CutPrefix(strings.TrimLeft(flag, "-"), "mod="); ok {
+			mode = modMode(value)
+		}
+	}
+	return mode
+}
+
+// env returns the environment variables passed to the go command to resolve
+// dependencies with the mode. Other flags of GOFLAGS are kept. Vendored
+// actions are compiled with GOPROXY=off such that nothing is downloaded.
+func (m modMode) env() []string {
+	flags := []string{"-mod=" + string(m)}
+	for _, flag := range strings.Fields(os.Getenv("GOFLAGS")) {
+		if goFlagsModMode(flag) == "" {
+			flags = append(flags, flag)
+		}
+	}
+	env := []string{"GOFLAGS=" + strings.Join(flags, " ")}
+	if m == modVendor {
+		env = append(env, "GOPROXY=off")
+	}
+	return env
+}
+
+// isVendored returns whether the actions in dir have a vendor directory made
+// by go mod vendor.
+func isVendored(dir string) (bool, error) {
+	_, err := os.Stat(filepath.Join(dir, "vendor", "modules.txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// verifyVendored returns an error if the cmder package imported by the
+// generated main file is not vendored in dir. go mod vendor only copies the
+// packages imported by the actions so it must be imported, eg. in a tools.go
+// file, before vendoring.
+func verifyVendored(dir string) error {
+	file, err := os.Open(filepath.Join(dir, "vendor", "modules.txt"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if scanner.Text() == cmderPackage {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("%s is not vendored in '%s': import it with _ \"%s\" in the actions and run 'go mod vendor'", cmderPackage, dir, cmderPackage)
+}
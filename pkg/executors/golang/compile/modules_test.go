@@ -0,0 +1,87 @@
+package compile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/executors/golang/discover"
+)
+
+func TestGoModMode(t *testing.T) {
+	newActions := func(t *testing.T, modulesTxt string) *discover.ActionsDiscovered {
+		dir := t.TempDir()
+		if modulesTxt != "" {
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, "vendor"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "vendor", "modules.txt"), []byte(modulesTxt), 0o644))
+		}
+		return &discover.ActionsDiscovered{DirPath: dir, ParentDir: dir}
+	}
+	tt := []struct {
+		name     string
+		vendored bool
+		mod      string
+		goFlags  string
+		mode     modMode
+		err      string
+	}{
+		{name: "default", mode: modMod},
+		{name: "vendored", vendored: true, mode: modVendor},
+		{name: "readonly", mod: "readonly", mode: modReadonly},
+		{name: "vendored but tidied", vendored: true, mod: "mod", mode: modMod},
+		{name: "goflags", goFlags: "-trimpath -mod=readonly", mode: modReadonly},
+		{name: "shuttle variable takes precedence", mod: "mod", goFlags: "-mod=readonly", mode: modMod},
+		{name: "vendor without vendor directory", goFlags: "-mod=vendor", err: "are compiled with -mod=vendor by GOFLAGS but are not vendored: run 'go mod vendor' in the directory"},
+		{name: "invalid", mod: "offline", err: "-mod=offline of SHUTTLE_GOLANG_ACTIONS_MOD is invalid: must be one of 'mod', 'readonly' or 'vendor'"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("SHUTTLE_GOLANG_ACTIONS_MOD", tc.mod)
+			t.Setenv("GOFLAGS", tc.goFlags)
+			modulesTxt := ""
+			if tc.vendored {
+				modulesTxt = "# github.com/lunarway/shuttle v0.24.0\n"
+			}
+
+			mode, err := goModMode(newActions(t, modulesTxt))
+
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.mode, mode)
+		})
+	}
+}
+
+func TestModModeEnv(t *testing.T) {
+	t.Setenv("GOFLAGS", "-trimpath -mod=mod")
+
+	assert.Equal(t, []string{"GOFLAGS=-mod=readonly -trimpath"}, modReadonly.env())
+	assert.Equal(t, []string{"GOFLAGS=-mod=vendor -trimpath", "GOPROXY=off"}, modVendor.env())
+}
+
+func TestVerifyVendored(t *testing.T) {
+	write := func(t *testing.T, modulesTxt string) string {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "vendor"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "vendor", "modules.txt"), []byte(modulesTxt), 0o644))
+		return dir
+	}
+
+	t.Run("cmder vendored", func(t *testing.T) {
+		dir := write(t, "# github.com/lunarway/shuttle v0.24.0\n## explicit; go 1.21\ngithub.com/lunarway/shuttle\n"+cmderPackage+"\n")
+
+		assert.NoError(t, verifyVendored(dir))
+	})
+
+	t.Run("cmder missing", func(t *testing.T) {
+		dir := write(t, "# github.com/lunarway/shuttle v0.24.0\n## explicit; go 1.21\ngithub.com/lunarway/shuttle\n")
+
+		assert.EqualError(t, verifyVendored(dir), cmderPackage+" is not vendored in '"+dir+"': import it with _ \""+cmderPackage+"\" in the actions and run 'go mod vendor'")
+	})
+}
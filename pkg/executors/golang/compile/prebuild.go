@@ -0,0 +1,106 @@
+package compile
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/executors/golang/compile/matcher"
+	"github.com/lunarway/shuttle/pkg/executors/golang/discover"
+	"github.com/lunarway/shuttle/pkg/executors/golang/shuttlefolder"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// Prebuild compiles actions for each of targets and copies the binaries to
+// dir named by shuttlefolder.PrebuiltName. Shipped in the PrebuiltDir of a
+// plan the binaries are used instead of compiling the actions. Prebuilt
+// binaries of other sources in dir are removed. The paths of the copied
+// binaries are returned.
+func Prebuild(ctx context.Context, ui *ui.UI, actions *discover.ActionsDiscovered, targets []shuttlefolder.Target, dir string) ([]string, error) {
+	hash, err := matcher.GetHash(ctx, actions)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, target := range targets {
+		ui.Verboseln("prebuilding golang actions for: %s", target)
+		binaryPath, err := compile(ctx, ui, actions, target)
+		if err != nil {
+			return nil, err
+		}
+		prebuiltPath := path.Join(dir, shuttlefolder.PrebuiltName(hash, target))
+		if err := copyBinary(binaryPath, prebuiltPath); err != nil {
+			return nil, fmt.Errorf("failed to copy prebuilt binary for %s: %w", target, err)
+		}
+		paths = append(paths, prebuiltPath)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !strings.HasPrefix(name, shuttlefolder.TaskBinaryPrefix+"-") || shuttlefolder.IsBinaryOf(name, hash) {
+			continue
+		}
+		if err := os.Remove(path.Join(dir, name)); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// usePrebuiltBinary copies the prebuilt binary of hash for target next to
+// the actions into binaryPath and returns whether it did. Failures are
+// printed and the actions are compiled instead.
+func usePrebuiltBinary(ui *ui.UI, actions *discover.ActionsDiscovered, hash string, target shuttlefolder.Target, binaryPath string) bool {
+	prebuiltPath := shuttlefolder.PrebuiltPath(actions.ParentDir, hash, target)
+	if _, err := os.Stat(prebuiltPath); err != nil {
+		return false
+	}
+	if err := os.MkdirAll(path.Dir(binaryPath), 0o755); err != nil {
+		ui.Errorln("Ignoring prebuilt golang actions binary: %v", err)
+		return false
+	}
+	if err := copyBinary(prebuiltPath, binaryPath); err != nil {
+		ui.Errorln("Ignoring prebuilt golang actions binary: %v", err)
+		return false
+	}
+	ui.Verboseln("using prebuilt binary: %s", prebuiltPath)
+	return true
+}
+
+// copyBinary copies the binary src to dest. It is written next to dest and
+// renamed into place such that a partially written binary is never run.
+func copyBinary(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(path.Dir(dest), ".prebuilt-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(out.Name(), 0o755); err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), dest)
+}
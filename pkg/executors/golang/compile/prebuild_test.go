@@ -0,0 +1,43 @@
+package compile
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/executors/golang/discover"
+	"github.com/lunarway/shuttle/pkg/executors/golang/shuttlefolder"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestUsePrebuiltBinary(t *testing.T) {
+	plan := t.TempDir()
+	actions := &discover.ActionsDiscovered{DirPath: filepath.Join(plan, "actions"), ParentDir: plan}
+	target := shuttlefolder.Target{GOOS: "linux", GOARCH: "amd64"}
+	binaryPath := filepath.Join(plan, ".shuttle", "actions", "binaries", "actions-linux-amd64-abc")
+	uiout := ui.Create(io.Discard, io.Discard)
+
+	t.Run("missing", func(t *testing.T) {
+		assert.False(t, usePrebuiltBinary(uiout, actions, "h1:abc", target, binaryPath))
+		assert.NoFileExists(t, binaryPath)
+	})
+
+	t.Run("prebuilt", func(t *testing.T) {
+		prebuilt := shuttlefolder.PrebuiltPath(plan, "h1:abc", target)
+		require.NoError(t, os.MkdirAll(filepath.Dir(prebuilt), 0o755))
+		require.NoError(t, os.WriteFile(prebuilt, []byte("binary"), 0o644))
+
+		assert.True(t, usePrebuiltBinary(uiout, actions, "h1:abc", target, binaryPath))
+
+		content, err := os.ReadFile(binaryPath)
+		require.NoError(t, err)
+		assert.Equal(t, "binary", string(content))
+		info, err := os.Stat(binaryPath)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o755), info.Mode().Perm(), "binary must be executable")
+	})
+}
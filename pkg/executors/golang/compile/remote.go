@@ -0,0 +1,264 @@
+package compile
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RemoteCache is a cache of compiled actions binaries shared between
+// environments, eg. ephemeral CI runners. Binaries are stored by their name
+// which holds the hash of their sources and their target.
+type RemoteCache interface {
+	// Download writes the binary called name to dest and returns whether the
+	// cache holds it at all.
+	Download(ctx context.Context, name, dest string) (bool, error)
+	// Upload stores the binary at src as name.
+	Upload(ctx context.Context, name, src string) error
+}
+
+// remoteCacheFromEnv returns the remote cache configured by
+// SHUTTLE_GOLANG_ACTIONS_REMOTE_CACHE or nil if no cache is configured. The
+// location is either an http(s) URL, a gs://bucket/prefix or a
+// s3://bucket/prefix location.
+func remoteCacheFromEnv() (RemoteCache, error) {
+	raw := os.Getenv("SHUTTLE_GOLANG_ACTIONS_REMOTE_CACHE")
+	if raw == "" {
+		return nil, nil
+	}
+	location, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("golang actions remote cache '%s' is invalid: %w", raw, err)
+	}
+	token := os.Getenv("SHUTTLE_GOLANG_ACTIONS_REMOTE_CACHE_TOKEN")
+	switch location.Scheme {
+	case "http", "https":
+		return &httpCache{client: http.DefaultClient, baseURL: strings.TrimSuffix(raw, "/"), token: token}, nil
+	case "gs":
+		// the XML API of cloud storage accepts plain GET and PUT requests with
+		// an OAuth access token
+		return &httpCache{
+			client:  http.DefaultClient,
+			baseURL: strings.TrimSuffix("https://storage.googleapis.com/"+location.Host+location.Path, "/"),
+			token:   token,
+		}, nil
+	case "s3":
+		return newS3Cache(location.Host, strings.Trim(location.Path, "/"))
+	default:
+		return nil, fmt.Errorf("golang actions remote cache '%s' is invalid: must be an http, https, gs or s3 location", raw)
+	}
+}
+
+// httpCache stores binaries as objects below a base URL with GET and PUT
+// requests like most object stores and cache servers support.
+type httpCache struct {
+	client  *http.Client
+	baseURL string
+	// token is sent as a bearer token if set
+	token string
+	// sign signs requests if set instead of sending token
+	sign func(req *http.Request, now time.Time)
+}
+
+func (c *httpCache) Download(ctx context.Context, name, dest string) (bool, error) {
+	req, err := c.request(ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("download %s: unexpected status %s", name, resp.Status)
+	}
+
+	// the binary is written next to its destination and renamed into place
+	// such that a partially downloaded binary is never run
+	file, err := os.CreateTemp(path.Dir(dest), ".download-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(file.Name())
+	_, err = io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, fmt.Errorf("download %s: %w", name, err)
+	}
+	if err := os.Chmod(file.Name(), 0o755); err != nil {
+		return false, err
+	}
+	if err := os.Rename(file.Name(), dest); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *httpCache) Upload(ctx context.Context, name, src string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	req, err := c.request(ctx, http.MethodPut, name, file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("upload %s: unexpected status %s", name, resp.Status)
+	}
+	return nil
+}
+
+func (c *httpCache) request(ctx context.Context, method, name string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/"+url.PathEscape(name), body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c.sign != nil:
+		c.sign(req, time.Now())
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// newS3Cache returns a cache storing binaries below prefix in an S3 bucket.
+// Credentials and region are read from the standard AWS environment
+// variables. AWS_ENDPOINT_URL_S3 selects another S3 compatible endpoint which
+// is addressed with path style URLs.
+func newS3Cache(bucket, prefix string) (RemoteCache, error) {
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("golang actions remote cache s3://%s requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY", bucket)
+	}
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	baseURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, region)
+	if endpoint := os.Getenv("AWS_ENDPOINT_URL_S3"); endpoint != "" {
+		baseURL = strings.TrimSuffix(endpoint, "/") + "/" + bucket
+	}
+	if prefix != "" {
+		baseURL += "/" + prefix
+	}
+	signer := s3Signer{
+		accessKey:    accessKey,
+		secretKey:    secretKey,
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		region:       region,
+	}
+	return &httpCache{client: http.DefaultClient, baseURL: baseURL, sign: signer.sign}, nil
+}
+
+// s3Signer signs requests with AWS signature version 4. Payloads are not
+// signed so binaries can be streamed.
+type s3Signer struct {
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	region       string
+}
+
+func (s s3Signer) sign(req *http.Request, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	scope := date + "/" + s.region + "/s3/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256(canonicalRequest),
+	}, "\n")
+
+	key := []byte("AWS4" + s.secretKey)
+	for _, part := range []string{date, s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hexSHA256(data string) string {
+	digest := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(digest[:])
+}
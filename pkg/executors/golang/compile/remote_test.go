@@ -0,0 +1,165 @@
+package compile
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteCacheFromEnv(t *testing.T) {
+	tt := []struct {
+		name     string
+		location string
+		env      map[string]string
+		baseURL  string
+		err      string
+	}{
+		{name: "not set", location: ""},
+		{name: "http", location: "https://cache.example.com/shuttle/", baseURL: "https://cache.example.com/shuttle"},
+		{name: "gs", location: "gs://bucket/shuttle", baseURL: "https://storage.googleapis.com/bucket/shuttle"},
+		{
+			name:     "s3",
+			location: "s3://bucket/shuttle",
+			env:      map[string]string{"AWS_ACCESS_KEY_ID": "id", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_REGION": "eu-west-1"},
+			baseURL:  "https://bucket.s3.eu-west-1.amazonaws.com/shuttle",
+		},
+		{
+			name:     "s3 endpoint",
+			location: "s3://bucket",
+			env:      map[string]string{"AWS_ACCESS_KEY_ID": "id", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_ENDPOINT_URL_S3": "http://localhost:9000/"},
+			baseURL:  "http://localhost:9000/bucket",
+		},
+		{
+			name:     "s3 without credentials",
+			location: "s3://bucket",
+			err:      "golang actions remote cache s3://bucket requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY",
+		},
+		{
+			name:     "unknown scheme",
+			location: "ftp://cache",
+			err:      "golang actions remote cache 'ftp://cache' is invalid: must be an http, https, gs or s3 location",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, name := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_REGION", "AWS_DEFAULT_REGION", "AWS_ENDPOINT_URL_S3"} {
+				t.Setenv(name, tc.env[name])
+			}
+			t.Setenv("SHUTTLE_GOLANG_ACTIONS_REMOTE_CACHE", tc.location)
+
+			remote, err := remoteCacheFromEnv()
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			if tc.baseURL == "" {
+				assert.Nil(t, remote)
+				return
+			}
+			assert.Equal(t, tc.baseURL, remote.(*httpCache).baseURL)
+		})
+	}
+}
+
+// objectServer is an object store holding objects in memory
+type objectServer struct {
+	lock    sync.Mutex
+	objects map[string][]byte
+	auth    []string
+}
+
+func (s *objectServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.auth = append(s.auth, r.Header.Get("Authorization"))
+	switch r.Method {
+	case http.MethodGet:
+		object, ok := s.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(object)
+	case http.MethodPut:
+		object, _ := io.ReadAll(r.Body)
+		s.objects[r.URL.Path] = object
+	}
+}
+
+func TestHTTPCache(t *testing.T) {
+	server := &objectServer{objects: map[string][]byte{}}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	cache := &httpCache{client: httpServer.Client(), baseURL: httpServer.URL + "/shuttle", token: "token"}
+	ctx := context.Background()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dest := filepath.Join(dir, "dest")
+	require.NoError(t, os.WriteFile(src, []byte("binary"), 0o755))
+
+	ok, err := cache.Download(ctx, "actions-abc", dest)
+	require.NoError(t, err)
+	assert.False(t, ok, "missing binary must not be downloaded")
+	assert.NoFileExists(t, dest)
+
+	require.NoError(t, cache.Upload(ctx, "actions-abc", src))
+	assert.Equal(t, []byte("binary"), server.objects["/shuttle/actions-abc"])
+
+	ok, err = cache.Download(ctx, "actions-abc", dest)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	content, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, "binary", string(content))
+	info, err := os.Stat(dest)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm(), "downloaded binary must be executable")
+
+	assert.Equal(t, []string{"Bearer token", "Bearer token", "Bearer token"}, server.auth)
+}
+
+func TestHTTPCache_unexpectedStatus(t *testing.T) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer httpServer.Close()
+	cache := &httpCache{client: httpServer.Client(), baseURL: httpServer.URL}
+	dest := filepath.Join(t.TempDir(), "dest")
+
+	_, err := cache.Download(context.Background(), "actions-abc", dest)
+
+	assert.EqualError(t, err, "download actions-abc: unexpected status 403 Forbidden")
+	assert.NoFileExists(t, dest)
+}
+
+func TestS3Signer(t *testing.T) {
+	signer := s3Signer{accessKey: "id", secretKey: "secret", sessionToken: "session", region: "eu-west-1"}
+	now := time.Date(2024, 5, 24, 10, 30, 0, 0, time.UTC)
+	signature := func(now time.Time) string {
+		req, err := http.NewRequest(http.MethodGet, "https://bucket.s3.eu-west-1.amazonaws.com/shuttle/actions-abc", nil)
+		require.NoError(t, err)
+		signer.sign(req, now)
+		assert.Equal(t, "20240524T103000Z", req.Header.Get("X-Amz-Date"))
+		assert.Equal(t, "UNSIGNED-PAYLOAD", req.Header.Get("X-Amz-Content-Sha256"))
+		assert.Equal(t, "session", req.Header.Get("X-Amz-Security-Token"))
+		return req.Header.Get("Authorization")
+	}
+
+	auth := signature(now)
+
+	assert.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=id/20240524/eu-west-1/s3/aws4_request, "+
+		"SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token, Signature="), auth)
+	assert.Equal(t, auth, signature(now), "signatures must be deterministic")
+}
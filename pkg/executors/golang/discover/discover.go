@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/lunarway/shuttle/pkg/config"
+	shuttleerrors "github.com/lunarway/shuttle/pkg/errors"
 )
 
 var InvalidShuttlePathFile = errors.New("shuttle path did not point ot a shuttle.yaml file")
@@ -51,7 +52,7 @@ func Discover(
 	}
 
 	localdir := path.Dir(shuttlepath)
-	localPlan, err := discoverPlan(localdir)
+	localPlan, err := discoverPlan(localdir, c.Config.GolangActions, shuttlefilename)
 	if err != nil {
 		return nil, err
 	}
@@ -62,7 +63,7 @@ func Discover(
 
 	if c.Config.Plan != "" {
 		planShuttleFile := path.Join(localdir, ".shuttle/plan")
-		parentPlan, err := discoverPlan(planShuttleFile)
+		parentPlan, err := discoverPlan(planShuttleFile, c.Plan.GolangActions, "plan.yaml")
 		if err != nil {
 			return nil, err
 		}
@@ -73,11 +74,44 @@ func Discover(
 	return &discovered, nil
 }
 
-func discoverPlan(localdir string) (*ActionsDiscovered, error) {
+// DiscoverPlan returns the golang actions of the plan in planDir with the
+// actions directory golangActions of its plan.yaml. It is nil if the plan has
+// no golang actions.
+func DiscoverPlan(planDir, golangActions string) (*ActionsDiscovered, error) {
+	return discoverPlan(planDir, golangActions, "plan.yaml")
+}
+
+// discoverPlan collects the golang actions of the directory actionsDir relative
+// to localdir. If actionsDir is empty the conventional actions directory is
+// used. A configured directory must exist while the conventional one is
+// optional. source is the file actionsDir is configured in.
+func discoverPlan(localdir, actionsDir, source string) (*ActionsDiscovered, error) {
 	localshuttledirentries := make([]string, 0)
 
-	actionspath := path.Join(localdir, actionsdir)
-	if fs, err := os.Stat(actionspath); err == nil {
+	configured := actionsDir != ""
+	if !configured {
+		actionsDir = actionsdir
+	}
+	if path.IsAbs(actionsDir) || strings.HasPrefix(path.Clean(actionsDir), "..") {
+		return nil, shuttleerrors.NewExitCode(
+			1,
+			"Golang actions directory '%s' in %s must be relative to and within its directory",
+			actionsDir,
+			source,
+		)
+	}
+
+	actionspath := path.Join(localdir, actionsDir)
+	fs, err := os.Stat(actionspath)
+	if configured && (err != nil || !fs.IsDir()) {
+		return nil, shuttleerrors.NewExitCode(
+			1,
+			"Golang actions directory '%s' in %s does not exist or is not a directory",
+			actionsDir,
+			source,
+		)
+	}
+	if err == nil {
 		// list all local files
 		if fs.IsDir() {
 			entries, err := os.ReadDir(actionspath)
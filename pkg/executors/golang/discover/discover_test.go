@@ -30,6 +30,70 @@ func TestDiscover(t *testing.T) {
 	}, *discovered)
 }
 
+func TestDiscover_golangActionsDirectory(t *testing.T) {
+	t.Run("custom directory", func(t *testing.T) {
+		discovered, err := discover.Discover(
+			context.Background(),
+			"testdata/custom/shuttle.yaml",
+			&config.ShuttleProjectContext{
+				Config: config.ShuttleConfig{
+					GolangActions: "build/tasks",
+				},
+			},
+		)
+		assert.NoError(t, err)
+
+		assert.Equal(t, discover.Discovered{
+			Local: &discover.ActionsDiscovered{
+				Files: []string{
+					"deploy.go",
+				},
+				DirPath:   "testdata/custom/build/tasks",
+				ParentDir: "testdata/custom",
+			},
+		}, *discovered)
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := discover.Discover(
+			context.Background(),
+			"testdata/custom/shuttle.yaml",
+			&config.ShuttleProjectContext{
+				Config: config.ShuttleConfig{
+					GolangActions: "tasks",
+				},
+			},
+		)
+
+		assert.EqualError(t, err, "exit code 1 - Golang actions directory 'tasks' in shuttle.yaml does not exist or is not a directory")
+	})
+
+	t.Run("directory outside project", func(t *testing.T) {
+		_, err := discover.Discover(
+			context.Background(),
+			"testdata/custom/shuttle.yaml",
+			&config.ShuttleProjectContext{
+				Config: config.ShuttleConfig{
+					GolangActions: "../simple/actions",
+				},
+			},
+		)
+
+		assert.EqualError(t, err, "exit code 1 - Golang actions directory '../simple/actions' in shuttle.yaml must be relative to and within its directory")
+	})
+
+	t.Run("conventional directory is optional", func(t *testing.T) {
+		discovered, err := discover.Discover(
+			context.Background(),
+			"testdata/custom/shuttle.yaml",
+			&config.ShuttleProjectContext{},
+		)
+		assert.NoError(t, err)
+
+		assert.Equal(t, discover.Discovered{}, *discovered)
+	})
+}
+
 func TestDiscoverComplex(t *testing.T) {
 	shuttleCmd := exec.Command("shuttle", "ls", "--verbose")
 	shuttleCmd.Dir = "testdata/child/"
@@ -11,8 +11,15 @@ type (
 		Args []ActionArg `json:"args"`
 	}
 
+	// ActionArg is an argument of an action. Arguments of string parameters
+	// only have a name and are required. Arguments of fields of struct
+	// parameters have the type, default and allowed values of the field.
 	ActionArg struct {
-		Name string `json:"name"`
+		Name     string   `json:"name"`
+		Type     string   `json:"type,omitempty"`
+		Required bool     `json:"required,omitempty"`
+		Default  string   `json:"default,omitempty"`
+		Values   []string `json:"values,omitempty"`
 	}
 )
 
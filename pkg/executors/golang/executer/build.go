@@ -0,0 +1,38 @@
+package executer
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/executors/golang/compile"
+	golangerrors "github.com/lunarway/shuttle/pkg/executors/golang/errors"
+	"github.com/lunarway/shuttle/pkg/executors/golang/shuttlefolder"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// Build compiles the golang actions of the project and its plan for target
+// without running them. Cross compiled binaries are meant to be run elsewhere,
+// eg. inside a container of another platform.
+func Build(
+	ctx context.Context,
+	ui *ui.UI,
+	path string,
+	c *config.ShuttleProjectContext,
+	target shuttlefolder.Target,
+) (*compile.Binaries, error) {
+	if !isActionsEnabled() {
+		ui.Verboseln("shuttle golang actions disabled")
+		return &compile.Binaries{}, nil
+	}
+
+	binaries, err := prepareFor(ctx, ui, path, c, target)
+	if err != nil {
+		if errors.Is(err, golangerrors.ErrGolangActionNoBuilder) {
+			return &compile.Binaries{}, nil
+		}
+		return nil, err
+	}
+
+	return binaries, nil
+}
@@ -0,0 +1,75 @@
+package executer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/executors/golang/compile/matcher"
+	"github.com/lunarway/shuttle/pkg/executors/golang/discover"
+	"github.com/lunarway/shuttle/pkg/executors/golang/shuttlefolder"
+)
+
+// DryRunBinary is a binary a golang action may be run by.
+type DryRunBinary struct {
+	// Source is either project or plan
+	Source string
+	Path   string
+	// Compiled is set if the binary of the current sources exists
+	Compiled bool
+}
+
+// DryRun returns the binaries golang actions of the shuttle.yaml at path would
+// be run by without compiling or running anything. The binary of the project
+// takes precedence over the one of the plan if both have the action. No
+// binaries are returned if golang actions are disabled or there is no
+// shuttle.yaml at path.
+func DryRun(ctx context.Context, c *config.ShuttleProjectContext, path string) ([]DryRunBinary, error) {
+	if !isActionsEnabled() {
+		return nil, nil
+	}
+
+	disc, err := discover.Discover(ctx, path, c)
+	if errors.Is(err, discover.InvalidShuttlePathFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover actions: %w", err)
+	}
+
+	var binaries []DryRunBinary
+	for _, source := range []struct {
+		name    string
+		actions *discover.ActionsDiscovered
+	}{
+		{"project", disc.Local},
+		{"plan", disc.Plan},
+	} {
+		if source.actions == nil || len(source.actions.Files) == 0 {
+			continue
+		}
+		binary, err := dryRunBinary(ctx, source.name, source.actions)
+		if err != nil {
+			return nil, err
+		}
+		binaries = append(binaries, binary)
+	}
+	return binaries, nil
+}
+
+func dryRunBinary(ctx context.Context, source string, actions *discover.ActionsDiscovered) (DryRunBinary, error) {
+	hash, err := matcher.GetHash(ctx, actions)
+	if err != nil {
+		return DryRunBinary{}, fmt.Errorf("failed to hash %s actions: %w", source, err)
+	}
+	binaryPath := shuttlefolder.CalculateBinaryPath(
+		path.Join(actions.ParentDir, ".shuttle/actions"),
+		hash,
+		shuttlefolder.HostTarget(),
+	)
+	_, err = os.Stat(binaryPath)
+	return DryRunBinary{Source: source, Path: binaryPath, Compiled: err == nil}, nil
+}
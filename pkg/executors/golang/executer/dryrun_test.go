@@ -0,0 +1,48 @@
+package executer_test
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/executors/golang/executer"
+)
+
+func TestDryRun(t *testing.T) {
+	t.Setenv("SHUTTLE_GOLANG_ACTIONS", "true")
+	projectPath := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(projectPath, "shuttle.yaml"), []byte("plan: false\n"), 0o644))
+	require.NoError(t, os.Mkdir(path.Join(projectPath, "actions"), 0o755))
+	require.NoError(t, os.WriteFile(path.Join(projectPath, "actions", "build.go"), []byte("package main\n\nfunc Build() error { return nil }\n"), 0o644))
+	c := &config.ShuttleProjectContext{}
+
+	binaries, err := executer.DryRun(context.Background(), c, path.Join(projectPath, "shuttle.yaml"))
+
+	require.NoError(t, err)
+	require.Len(t, binaries, 1)
+	assert.Equal(t, "project", binaries[0].Source)
+	assert.Equal(t, path.Join(projectPath, ".shuttle/actions/binaries"), path.Dir(binaries[0].Path))
+	assert.False(t, binaries[0].Compiled, "compiled")
+
+	require.NoError(t, os.MkdirAll(path.Dir(binaries[0].Path), 0o755))
+	require.NoError(t, os.WriteFile(binaries[0].Path, nil, 0o755))
+	binaries, err = executer.DryRun(context.Background(), c, path.Join(projectPath, "shuttle.yaml"))
+
+	require.NoError(t, err)
+	require.Len(t, binaries, 1)
+	assert.True(t, binaries[0].Compiled, "compiled")
+}
+
+func TestDryRun_noShuttleFile(t *testing.T) {
+	t.Setenv("SHUTTLE_GOLANG_ACTIONS", "true")
+
+	binaries, err := executer.DryRun(context.Background(), &config.ShuttleProjectContext{}, path.Join(t.TempDir(), "shuttle.yaml"))
+
+	assert.NoError(t, err)
+	assert.Empty(t, binaries)
+}
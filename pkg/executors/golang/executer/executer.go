@@ -6,15 +6,20 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"time"
 
 	"github.com/lunarway/shuttle/pkg/executors/golang/compile"
 	"github.com/lunarway/shuttle/pkg/telemetry"
 )
 
+// stopGracePeriod is how long a golang action may run after it is asked to
+// stop before it is killed
+const stopGracePeriod = 5 * time.Second
+
 // Executes an action based on which plan is used
 // Get a list of actions for each binary if they exist
 // Take child if available otherwise pick plan, else error
-func executeAction(ctx context.Context, binaries *compile.Binaries, args ...string) error {
+func executeAction(ctx context.Context, binaries *compile.Binaries, env []string, args ...string) error {
 	localInquire, err := inquire(ctx, &binaries.Local)
 	if err != nil {
 		return err
@@ -27,7 +32,7 @@ func executeAction(ctx context.Context, binaries *compile.Binaries, args ...stri
 	cmdToExecute := args[0]
 
 	ran, err := localInquire.Execute(cmdToExecute, func() error {
-		return executeBinaryAction(ctx, &binaries.Local, args...)
+		return executeBinaryAction(ctx, &binaries.Local, env, args...)
 	})
 	if err != nil {
 		return err
@@ -37,7 +42,7 @@ func executeAction(ctx context.Context, binaries *compile.Binaries, args ...stri
 	}
 
 	ran, err = planInquire.Execute(cmdToExecute, func() error {
-		return executeBinaryAction(ctx, &binaries.Plan, args...)
+		return executeBinaryAction(ctx, &binaries.Plan, env, args...)
 	})
 	if err != nil {
 		return err
@@ -49,8 +54,14 @@ func executeAction(ctx context.Context, binaries *compile.Binaries, args ...stri
 	return fmt.Errorf("no action available in commands, available options are available through shuttle run -h")
 }
 
-func executeBinaryAction(ctx context.Context, binary *compile.Binary, args ...string) error {
-	execmd := exec.Command(binary.Path, args...)
+func executeBinaryAction(ctx context.Context, binary *compile.Binary, env []string, args ...string) error {
+	execmd := exec.CommandContext(ctx, binary.Path, args...)
+	// the action is asked to stop once ctx is done, eg. on a timeout, and
+	// killed if it has not exited within the grace period
+	execmd.Cancel = func() error {
+		return execmd.Process.Signal(stopSignal)
+	}
+	execmd.WaitDelay = stopGracePeriod
 	execmd.Stdout = os.Stdout
 	execmd.Stderr = os.Stderr
 
@@ -59,7 +70,7 @@ func executeBinaryAction(ctx context.Context, binary *compile.Binary, args ...st
 		return err
 	}
 
-	execmd.Env = os.Environ()
+	execmd.Env = append([]string(nil), env...)
 	execmd.Env = append(execmd.Env, fmt.Sprintf("TASK_CONTEXT_DIR=%s", workdir))
 	execmd.Env = append(execmd.Env, "SHUTTLE_INTERACTIVE=default")
 	execmd.Env = append(
@@ -68,7 +79,14 @@ func executeBinaryAction(ctx context.Context, binary *compile.Binary, args ...st
 			"SHUTTLE_CONTEXT_ID",
 			telemetry.ContextIDFrom(ctx),
 		),
+		fmt.Sprintf("%s=%s",
+			"SHUTTLE_RUN_ID",
+			telemetry.RunIDFrom(ctx),
+		),
 	)
+	if traceParent := telemetry.TraceParent(ctx); traceParent != "" {
+		execmd.Env = append(execmd.Env, fmt.Sprintf("TRACEPARENT=%s", traceParent))
+	}
 
 	err = execmd.Run()
 
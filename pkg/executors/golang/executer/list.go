@@ -3,6 +3,7 @@ package executer
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"strconv"
 
@@ -62,3 +63,37 @@ func isActionsEnabled() bool {
 	return enabled
 
 }
+
+// AddScripts adds a script running the task of each golang action of the
+// project to c. The golang actions are compiled if needed.
+func AddScripts(ctx context.Context, ui *ui.UI, c *config.ShuttleProjectContext) error {
+	taskActions, err := List(ctx, ui, fmt.Sprintf("%s/shuttle.yaml", c.ProjectPath), c)
+	if err != nil {
+		return err
+	}
+
+	for name, action := range taskActions.Actions {
+		args := make([]config.ShuttleScriptArgs, 0)
+
+		for _, taskArg := range action.Args {
+			args = append(args, config.ShuttleScriptArgs{
+				Name:     taskArg.Name,
+				Required: taskArg.Required || taskArg.Type == "",
+				Type:     taskArg.Type,
+				Default:  taskArg.Default,
+				Values:   taskArg.Values,
+			})
+		}
+
+		c.Scripts[name] = config.ShuttlePlanScript{
+			Description: name,
+			Actions: []config.ShuttleAction{
+				{
+					Task: name,
+				},
+			},
+			Args: args,
+		}
+	}
+	return nil
+}
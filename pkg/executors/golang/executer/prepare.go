@@ -11,6 +11,7 @@ import (
 	"github.com/lunarway/shuttle/pkg/executors/golang/compile"
 	"github.com/lunarway/shuttle/pkg/executors/golang/discover"
 	golangerrors "github.com/lunarway/shuttle/pkg/executors/golang/errors"
+	"github.com/lunarway/shuttle/pkg/executors/golang/shuttlefolder"
 	"github.com/lunarway/shuttle/pkg/ui"
 )
 
@@ -19,6 +20,16 @@ func prepare(
 	ui *ui.UI,
 	path string,
 	c *config.ShuttleProjectContext,
+) (*compile.Binaries, error) {
+	return prepareFor(ctx, ui, path, c, shuttlefolder.HostTarget())
+}
+
+func prepareFor(
+	ctx context.Context,
+	ui *ui.UI,
+	path string,
+	c *config.ShuttleProjectContext,
+	target shuttlefolder.Target,
 ) (*compile.Binaries, error) {
 	ui.Verboseln("preparing shuttle golang actions")
 	start := time.Now()
@@ -26,10 +37,10 @@ func prepare(
 
 	disc, err := discover.Discover(ctx, path, c)
 	if err != nil {
-		return nil, fmt.Errorf("failed to discover actions: %v", err)
+		return nil, fmt.Errorf("failed to discover actions: %w", err)
 	}
 
-	binaries, err := compile.Compile(ctx, ui, disc)
+	binaries, err := compile.CompileFor(ctx, ui, disc, target)
 	if err != nil {
 		if errors.Is(err, golangerrors.ErrGolangActionNoBuilder) {
 			return nil, err
@@ -3,6 +3,7 @@ package executer
 import (
 	"context"
 	"errors"
+	"os"
 
 	"github.com/lunarway/shuttle/pkg/config"
 	golangerrors "github.com/lunarway/shuttle/pkg/executors/golang/errors"
@@ -15,6 +16,19 @@ func Run(
 	c *config.ShuttleProjectContext,
 	path string,
 	args ...string,
+) error {
+	return RunWithEnv(ctx, ui, c, path, os.Environ(), args...)
+}
+
+// RunWithEnv runs the action like Run with env as its environment instead of
+// the environment of shuttle.
+func RunWithEnv(
+	ctx context.Context,
+	ui *ui.UI,
+	c *config.ShuttleProjectContext,
+	path string,
+	env []string,
+	args ...string,
 ) error {
 	if !isActionsEnabled() {
 		ui.Verboseln("shuttle golang actions disabled")
@@ -32,7 +46,7 @@ func Run(
 	}
 
 	ui.Verboseln("executing shuttle golang actions")
-	if err := executeAction(ctx, binaries, args...); err != nil {
+	if err := executeAction(ctx, binaries, env, args...); err != nil {
 		return err
 	}
 
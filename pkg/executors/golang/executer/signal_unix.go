@@ -0,0 +1,8 @@
+//go:build !windows
+
+package executer
+
+import "syscall"
+
+// stopSignal is sent to golang actions to stop them
+var stopSignal = syscall.SIGTERM
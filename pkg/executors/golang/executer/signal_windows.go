@@ -0,0 +1,7 @@
+package executer
+
+import "os"
+
+// stopSignal is sent to golang actions to stop them. Windows cannot deliver
+// other signals so they are killed right away.
+var stopSignal = os.Kill
@@ -25,6 +25,9 @@ type Arg struct {
 
 type Output struct {
 	Error bool
+	// Result is true if the function returns a result before the error. The
+	// fields of struct results are written as outputs of the action.
+	Result bool
 }
 
 func GenerateAst(
@@ -66,22 +69,29 @@ func GenerateAst(
 					}
 					outputParam := param.Results
 					if outputParam != nil {
-						if len(outputParam.List) > 1 {
-							return nil, errors.New("only error is supported as an output param")
+						results := make([]string, 0, len(outputParam.List))
+						for _, param := range outputParam.List {
+							count := len(param.Names)
+							if count == 0 {
+								count = 1
+							}
+							for i := 0; i < count; i++ {
+								results = append(results, fmt.Sprintf("%s", param.Type))
+							}
+						}
+						if len(results) > 2 {
+							return nil, errors.New("only a result and an error are supported as output params")
 						}
-						if len(outputParam.List) == 0 {
+						if len(results) == 0 {
 							return nil, errors.New(
 								"output params are required, only error is supported",
 							)
 						}
-
-						for _, param := range outputParam.List {
-							if fmt.Sprintf("%s", param.Type) != "error" {
-								return nil, errors.New("output was not error")
-							}
+						if results[len(results)-1] != "error" {
+							return nil, errors.New("output was not error")
 						}
 
-						f.Output = Output{Error: true}
+						f.Output = Output{Error: true, Result: len(results) == 2}
 					}
 
 					funcs = append(funcs, &f)
@@ -1,20 +1,63 @@
 package shuttlefolder
 
 import (
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"path"
+	"strings"
 )
 
 const (
 	TaskBinaryDir    string = "binaries"
 	TaskBinaryPrefix        = "actions"
+	// PrebuiltDir is the directory of plans holding the binaries made by
+	// shuttle golang prebuild.
+	PrebuiltDir = "prebuilt"
 )
 
-func CalculateBinaryPath(shuttledir, hash string) string {
+// BinaryName returns the file name of the actions binary built from sources
+// with hash for target. It contains the complete sha256 digest of the hash
+// such that distinct sources never share a binary whatever the length of hash.
+// Binaries of other targets than the host are named by their target as well
+// such that host and cross compiled binaries are cached side by side.
+func BinaryName(hash string, target Target) string {
+	digest := hashDigest(hash)
+	if target.IsHost() {
+		return fmt.Sprintf("%s-%s%s", TaskBinaryPrefix, digest, target.ExeSuffix())
+	}
+	return fmt.Sprintf("%s-%s-%s-%s%s", TaskBinaryPrefix, target.GOOS, target.GOARCH, digest, target.ExeSuffix())
+}
+
+// PrebuiltName returns the file name of the prebuilt actions binary built
+// from sources with hash for target. Unlike BinaryName it always contains the
+// target as prebuilt binaries are used on any platform.
+func PrebuiltName(hash string, target Target) string {
+	return fmt.Sprintf("%s-%s-%s-%s%s", TaskBinaryPrefix, target.GOOS, target.GOARCH, hashDigest(hash), target.ExeSuffix())
+}
+
+// PrebuiltPath returns the path of the prebuilt binary of hash for target in
+// the directory parentDir holding the actions directory.
+func PrebuiltPath(parentDir, hash string, target Target) string {
+	return path.Join(parentDir, PrebuiltDir, PrebuiltName(hash, target))
+}
+
+// IsBinaryOf returns whether name is the binary built from sources with hash
+// for any target.
+func IsBinaryOf(name, hash string) bool {
+	name = strings.TrimSuffix(name, ".exe")
+	return strings.HasPrefix(name, TaskBinaryPrefix+"-") && strings.HasSuffix(name, "-"+hashDigest(hash))
+}
+
+func hashDigest(hash string) string {
+	digest := sha256.Sum256([]byte(hash))
+	return hex.EncodeToString(digest[:])
+}
+
+func CalculateBinaryPath(shuttledir, hash string, target Target) string {
 	return path.Join(
 		shuttledir,
-		"binaries",
-		fmt.Sprintf("%s-%s", TaskBinaryPrefix, hex.EncodeToString([]byte(hash)[:16])),
+		TaskBinaryDir,
+		BinaryName(hash, target),
 	)
 }
@@ -0,0 +1,134 @@
+package shuttlefolder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// crossTarget returns a target other than the host
+func crossTarget() Target {
+	if HostTarget().GOOS == "plan9" {
+		return Target{GOOS: "linux", GOARCH: "amd64"}
+	}
+	return Target{GOOS: "plan9", GOARCH: "amd64"}
+}
+
+func TestBinaryName(t *testing.T) {
+	host := HostTarget()
+
+	t.Run("complete digest", func(t *testing.T) {
+		name := strings.TrimSuffix(BinaryName("h1:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=", host), host.ExeSuffix())
+
+		assert.True(t, strings.HasPrefix(name, "actions-"), "name must keep the actions prefix: %s", name)
+		assert.Len(t, strings.TrimPrefix(name, "actions-"), 64)
+	})
+
+	t.Run("deterministic", func(t *testing.T) {
+		assert.Equal(t, BinaryName("h1:abc", host), BinaryName("h1:abc", host))
+	})
+
+	t.Run("short input", func(t *testing.T) {
+		assert.NotPanics(t, func() {
+			BinaryName("", host)
+			BinaryName("h1", host)
+		})
+	})
+
+	t.Run("distinct sources do not collide", func(t *testing.T) {
+		// hashes sharing a long common prefix collided when only the first
+		// 16 bytes of the hash were used
+		hashes := []string{
+			"h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+			"h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAB=",
+			"h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAC=",
+			"h1:AAAAAAAAAAAAA",
+		}
+		names := map[string]string{}
+		for _, hash := range hashes {
+			name := BinaryName(hash, host)
+			if other, ok := names[name]; ok {
+				t.Fatalf("hashes %s and %s share binary %s", other, hash, name)
+			}
+			names[name] = hash
+		}
+	})
+
+	t.Run("cross compiled", func(t *testing.T) {
+		target := crossTarget()
+		name := BinaryName("h1:abc", target)
+
+		assert.Equal(t, "actions-"+target.GOOS+"-"+target.GOARCH+"-"+hashDigest("h1:abc"), name)
+	})
+
+	t.Run("windows executable", func(t *testing.T) {
+		target := Target{GOOS: "windows", GOARCH: "arm64"}
+		if target.IsHost() {
+			t.Skip("windows/arm64 is the host")
+		}
+
+		assert.Equal(t, "actions-windows-arm64-"+hashDigest("h1:abc")+".exe", BinaryName("h1:abc", target))
+	})
+}
+
+func TestCalculateBinaryPath(t *testing.T) {
+	host := HostTarget()
+	cross := crossTarget()
+
+	assert.Equal(t, ".shuttle/actions/binaries/"+BinaryName("h1:abc", host), CalculateBinaryPath(".shuttle/actions", "h1:abc", host))
+	assert.NotEqual(t,
+		CalculateBinaryPath(".shuttle/actions", "h1:abc", host),
+		CalculateBinaryPath(".shuttle/actions", "h1:abc", cross),
+		"host and cross compiled binaries must not share a path",
+	)
+	assert.NotEqual(t,
+		CalculateBinaryPath(".shuttle/actions", "h1:abc", Target{GOOS: "linux", GOARCH: "arm64"}),
+		CalculateBinaryPath(".shuttle/actions", "h1:abc", Target{GOOS: "linux", GOARCH: "amd64"}),
+		"binaries of distinct targets must not share a path",
+	)
+}
+
+func TestIsBinaryOf(t *testing.T) {
+	assert.True(t, IsBinaryOf(BinaryName("h1:abc", HostTarget()), "h1:abc"))
+	assert.True(t, IsBinaryOf(BinaryName("h1:abc", crossTarget()), "h1:abc"))
+	assert.True(t, IsBinaryOf(BinaryName("h1:abc", Target{GOOS: "windows", GOARCH: "amd64"}), "h1:abc"))
+	assert.False(t, IsBinaryOf(BinaryName("h1:abd", HostTarget()), "h1:abc"))
+	assert.False(t, IsBinaryOf("other-"+hashDigest("h1:abc"), "h1:abc"))
+}
+
+func TestParseTarget(t *testing.T) {
+	tt := []struct {
+		input  string
+		target Target
+		err    string
+	}{
+		{input: "linux/amd64", target: Target{GOOS: "linux", GOARCH: "amd64"}},
+		{input: "darwin/arm64", target: Target{GOOS: "darwin", GOARCH: "arm64"}},
+		{input: "linux", err: "target 'linux' is invalid: must be on the form GOOS/GOARCH, eg. linux/amd64"},
+		{input: "linux/", err: "target 'linux/' is invalid: must be on the form GOOS/GOARCH, eg. linux/amd64"},
+		{input: "linux/amd64/v2", err: "target 'linux/amd64/v2' is invalid: must be on the form GOOS/GOARCH, eg. linux/amd64"},
+		{input: "Linux/AMD64", err: "target 'Linux/AMD64' is invalid: must be on the form GOOS/GOARCH, eg. linux/amd64"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.input, func(t *testing.T) {
+			target, err := ParseTarget(tc.input)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.target, target)
+			assert.Equal(t, tc.input, target.String())
+		})
+	}
+}
+
+func TestPrebuiltName(t *testing.T) {
+	host := HostTarget()
+
+	assert.Equal(t, "actions-"+host.GOOS+"-"+host.GOARCH+"-"+hashDigest("h1:abc")+host.ExeSuffix(), PrebuiltName("h1:abc", host), "prebuilt binaries of the host must be named by their target")
+	assert.Equal(t, "actions-windows-amd64-"+hashDigest("h1:abc")+".exe", PrebuiltName("h1:abc", Target{GOOS: "windows", GOARCH: "amd64"}))
+	assert.Equal(t, "plan/prebuilt/actions-linux-arm64-"+hashDigest("h1:abc"), PrebuiltPath("plan", "h1:abc", Target{GOOS: "linux", GOARCH: "arm64"}))
+}
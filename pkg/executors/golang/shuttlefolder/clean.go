@@ -0,0 +1,69 @@
+package shuttlefolder
+
+import (
+	"errors"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BinaryFile is a compiled actions binary
+type BinaryFile struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Binaries returns the compiled actions binaries in shuttledir sorted by path.
+// Only files of TaskBinaryDir named with TaskBinaryPrefix are returned such
+// that other content is never mistaken for a binary.
+func Binaries(shuttledir string) ([]BinaryFile, error) {
+	binarydir := path.Join(shuttledir, TaskBinaryDir)
+	entries, err := os.ReadDir(binarydir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var binaries []BinaryFile
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !strings.HasPrefix(entry.Name(), TaskBinaryPrefix+"-") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		binaries = append(binaries, BinaryFile{
+			Path:    path.Join(binarydir, entry.Name()),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+	sort.Slice(binaries, func(i, j int) bool {
+		return binaries[i].Path < binaries[j].Path
+	})
+	return binaries, nil
+}
+
+// StaleBinaries returns the binaries not built from the current sources with
+// hash for any target. If hash is empty all binaries are stale. If olderThan
+// is positive only binaries modified, ie. last used, more than olderThan
+// before now are returned.
+func StaleBinaries(binaries []BinaryFile, hash string, olderThan time.Duration, now time.Time) []BinaryFile {
+	var stale []BinaryFile
+	for _, binary := range binaries {
+		if hash != "" && IsBinaryOf(path.Base(binary.Path), hash) {
+			continue
+		}
+		if olderThan > 0 && now.Sub(binary.ModTime) < olderThan {
+			continue
+		}
+		stale = append(stale, binary)
+	}
+	return stale
+}
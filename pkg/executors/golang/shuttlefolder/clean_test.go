@@ -0,0 +1,87 @@
+package shuttlefolder
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinaries(t *testing.T) {
+	shuttledir := t.TempDir()
+	binarydir := path.Join(shuttledir, TaskBinaryDir)
+	require.NoError(t, os.MkdirAll(path.Join(binarydir, "actions-dir"), 0o755))
+	for name, content := range map[string]string{
+		"actions-b":   "bb",
+		"actions-a":   "a",
+		"other":       "not a binary",
+		"actionsfile": "not a binary",
+	} {
+		require.NoError(t, os.WriteFile(path.Join(binarydir, name), []byte(content), 0o755))
+	}
+
+	binaries, err := Binaries(shuttledir)
+
+	require.NoError(t, err)
+	require.Len(t, binaries, 2)
+	assert.Equal(t, path.Join(binarydir, "actions-a"), binaries[0].Path)
+	assert.Equal(t, int64(1), binaries[0].Size)
+	assert.Equal(t, path.Join(binarydir, "actions-b"), binaries[1].Path)
+	assert.Equal(t, int64(2), binaries[1].Size)
+}
+
+func TestBinaries_missingDirectory(t *testing.T) {
+	binaries, err := Binaries(path.Join(t.TempDir(), "missing"))
+
+	assert.NoError(t, err)
+	assert.Empty(t, binaries)
+}
+
+func TestStaleBinaries(t *testing.T) {
+	now := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	current := "binaries/" + BinaryName("h1:current", HostTarget())
+	currentCross := "binaries/" + BinaryName("h1:current", crossTarget())
+	old := "binaries/" + BinaryName("h1:old", HostTarget())
+	recent := "binaries/" + BinaryName("h1:recent", HostTarget())
+	binaries := []BinaryFile{
+		{Path: current, ModTime: now.Add(-30 * 24 * time.Hour)},
+		{Path: currentCross, ModTime: now.Add(-30 * 24 * time.Hour)},
+		{Path: old, ModTime: now.Add(-8 * 24 * time.Hour)},
+		{Path: recent, ModTime: now.Add(-time.Hour)},
+	}
+
+	tt := []struct {
+		name      string
+		hash      string
+		olderThan time.Duration
+		stale     []string
+	}{
+		{
+			name:  "all but current of any target",
+			hash:  "h1:current",
+			stale: []string{old, recent},
+		},
+		{
+			name:      "older than",
+			hash:      "h1:current",
+			olderThan: 7 * 24 * time.Hour,
+			stale:     []string{old},
+		},
+		{
+			name:  "no current sources",
+			stale: []string{current, currentCross, old, recent},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var stale []string
+			for _, binary := range StaleBinaries(binaries, tc.hash, tc.olderThan, now) {
+				stale = append(stale, binary.Path)
+			}
+			assert.Equal(t, tc.stale, stale)
+		})
+	}
+}
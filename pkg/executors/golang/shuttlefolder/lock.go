@@ -0,0 +1,53 @@
+package shuttlefolder
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/filelock"
+)
+
+// lockPollInterval is how often a held lock is tried again
+const lockPollInterval = 100 * time.Millisecond
+
+// Lock takes an exclusive lock of the golang actions directory shuttlelocaldir
+// such that only one shuttle process at a time generates and compiles its
+// sources. The sources of all hashes share the tmp directory so the lock
+// covers the directory rather than a single hash. waiting is called once if
+// the lock is held by another process. The returned function releases the
+// lock.
+func Lock(ctx context.Context, shuttlelocaldir string, waiting func()) (func() error, error) {
+	if err := os.MkdirAll(shuttlelocaldir, 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path.Join(shuttlelocaldir, "compile.lock"), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	notified := false
+	for {
+		locked, err := filelock.TryLock(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("lock %s: %w", file.Name(), err)
+		}
+		if locked {
+			// closing the file releases the lock
+			return file.Close, nil
+		}
+		if !notified && waiting != nil {
+			waiting()
+			notified = true
+		}
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package shuttlefolder
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock(t *testing.T) {
+	t.Run("waits for the lock to be released", func(t *testing.T) {
+		shuttledir := t.TempDir()
+		unlock, err := Lock(context.Background(), shuttledir, nil)
+		require.NoError(t, err)
+
+		var waited atomic.Bool
+		locked := make(chan error)
+		go func() {
+			unlock, err := Lock(context.Background(), shuttledir, func() { waited.Store(true) })
+			if err == nil {
+				err = unlock()
+			}
+			locked <- err
+		}()
+
+		select {
+		case err := <-locked:
+			t.Fatalf("lock taken while held: %v", err)
+		case <-time.After(3 * lockPollInterval):
+		}
+		assert.True(t, waited.Load(), "waiting must be called")
+		require.NoError(t, unlock())
+		assert.NoError(t, <-locked)
+	})
+
+	t.Run("cancelled while waiting", func(t *testing.T) {
+		shuttledir := t.TempDir()
+		unlock, err := Lock(context.Background(), shuttledir, nil)
+		require.NoError(t, err)
+		defer unlock()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*lockPollInterval)
+		defer cancel()
+
+		_, err = Lock(ctx, shuttledir, nil)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
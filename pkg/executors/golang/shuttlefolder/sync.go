@@ -0,0 +1,117 @@
+package shuttlefolder
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/lunarway/shuttle/pkg/executors/golang/discover"
+)
+
+// SyncFiles mirrors the actions into the tmp directory of shuttlelocaldir
+// which is kept between compilations. Only files whose content changed are
+// written and files removed from the actions are removed from tmp, so
+// unchanged files are left as they are for go to reuse its build cache. The
+// files of keep, relative to tmp, are neither written nor removed, eg. the
+// generated main file.
+func SyncFiles(
+	ctx context.Context,
+	shuttlelocaldir string,
+	actions *discover.ActionsDiscovered,
+	keep ...string,
+) error {
+	tmpdir := path.Join(shuttlelocaldir, "tmp")
+	kept := make(map[string]bool, len(keep))
+	for _, name := range keep {
+		kept[filepath.FromSlash(name)] = true
+	}
+
+	sources := map[string]bool{}
+	err := filepath.WalkDir(actions.DirPath, func(source string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(actions.DirPath, source)
+		if err != nil {
+			return err
+		}
+		sources[rel] = true
+		if kept[rel] {
+			return nil
+		}
+		dest := filepath.Join(tmpdir, rel)
+		if entry.IsDir() {
+			return os.MkdirAll(dest, 0o755)
+		}
+		return syncFile(source, dest)
+	})
+	if err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(tmpdir, func(dest string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			// removed with its parent directory
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(tmpdir, dest)
+		if err != nil {
+			return err
+		}
+		if rel == "." || sources[rel] || kept[rel] {
+			return nil
+		}
+		if err := os.RemoveAll(dest); err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// CopyModuleFiles copies go.mod and go.sum of the actions into the tmp
+// directory of shuttlelocaldir replacing those of an earlier compilation. A
+// go.sum in tmp is removed if the actions have none.
+func CopyModuleFiles(shuttlelocaldir string, actions *discover.ActionsDiscovered) error {
+	for _, name := range []string{"go.mod", "go.sum"} {
+		source := path.Join(actions.DirPath, name)
+		dest := path.Join(shuttlelocaldir, "tmp", name)
+		if _, err := os.Stat(source); errors.Is(err, os.ErrNotExist) {
+			if err := os.Remove(dest); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			continue
+		}
+		if err := syncFile(source, dest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncFile writes the content of source to dest unless it is already the
+// same.
+func syncFile(source, dest string) error {
+	content, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	existing, err := os.ReadFile(dest)
+	if err == nil && bytes.Equal(content, existing) {
+		return nil
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dest, content, info.Mode().Perm())
+}
@@ -0,0 +1,75 @@
+package shuttlefolder
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/executors/golang/discover"
+)
+
+func TestSyncFiles(t *testing.T) {
+	actionsDir := t.TempDir()
+	shuttlelocaldir := t.TempDir()
+	tmpdir := path.Join(shuttlelocaldir, "tmp")
+	actions := &discover.ActionsDiscovered{DirPath: actionsDir, ParentDir: actionsDir}
+	write := func(t *testing.T, name, content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(path.Dir(name), 0o755))
+		require.NoError(t, os.WriteFile(name, []byte(content), 0o644))
+	}
+	read := func(t *testing.T, name string) string {
+		t.Helper()
+		content, err := os.ReadFile(path.Join(tmpdir, name))
+		require.NoError(t, err)
+		return string(content)
+	}
+	write(t, path.Join(actionsDir, "build.go"), "package main\n")
+	write(t, path.Join(actionsDir, "test.go"), "package main\n")
+	write(t, path.Join(actionsDir, "go.mod"), "module actions\n")
+	write(t, path.Join(actionsDir, "internal", "util.go"), "package internal\n")
+	require.NoError(t, os.MkdirAll(tmpdir, 0o755))
+
+	require.NoError(t, SyncFiles(context.Background(), shuttlelocaldir, actions, "main.go", "go.mod"))
+	assert.Equal(t, "package main\n", read(t, "build.go"))
+	assert.Equal(t, "package internal\n", read(t, "internal/util.go"))
+	assert.NoFileExists(t, path.Join(tmpdir, "go.mod"), "kept files must not be written")
+
+	// mark the unchanged file to tell if it is written again
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(path.Join(tmpdir, "build.go"), old, old))
+	write(t, path.Join(tmpdir, "main.go"), "package main // generated\n")
+	write(t, path.Join(actionsDir, "test.go"), "package main\n\nfunc Test() {}\n")
+	require.NoError(t, os.Remove(path.Join(actionsDir, "internal", "util.go")))
+
+	require.NoError(t, SyncFiles(context.Background(), shuttlelocaldir, actions, "main.go", "go.mod"))
+	info, err := os.Stat(path.Join(tmpdir, "build.go"))
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(old), "unchanged files must not be written")
+	assert.Equal(t, "package main\n\nfunc Test() {}\n", read(t, "test.go"))
+	assert.NoFileExists(t, path.Join(tmpdir, "internal", "util.go"), "removed files must be removed")
+	assert.Equal(t, "package main // generated\n", read(t, "main.go"), "kept files must not be removed")
+}
+
+func TestCopyModuleFiles(t *testing.T) {
+	actionsDir := t.TempDir()
+	shuttlelocaldir := t.TempDir()
+	tmpdir := path.Join(shuttlelocaldir, "tmp")
+	require.NoError(t, os.MkdirAll(tmpdir, 0o755))
+	require.NoError(t, os.WriteFile(path.Join(actionsDir, "go.mod"), []byte("module actions\n"), 0o644))
+	require.NoError(t, os.WriteFile(path.Join(tmpdir, "go.mod"), []byte("module actions\n\nrequire tidied v1.0.0\n"), 0o644))
+	require.NoError(t, os.WriteFile(path.Join(tmpdir, "go.sum"), []byte("tidied v1.0.0 h1:abc=\n"), 0o644))
+
+	err := CopyModuleFiles(shuttlelocaldir, &discover.ActionsDiscovered{DirPath: actionsDir})
+
+	require.NoError(t, err)
+	content, err := os.ReadFile(path.Join(tmpdir, "go.mod"))
+	require.NoError(t, err)
+	assert.Equal(t, "module actions\n", string(content))
+	assert.NoFileExists(t, path.Join(tmpdir, "go.sum"), "go.sum must be removed as the actions have none")
+}
@@ -0,0 +1,54 @@
+package shuttlefolder
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// Target is the platform actions binaries are compiled for
+type Target struct {
+	GOOS   string
+	GOARCH string
+}
+
+// HostTarget returns the platform shuttle itself runs on.
+func HostTarget() Target {
+	return Target{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}
+}
+
+// targetPart matches a GOOS or GOARCH value
+var targetPart = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// ParseTarget parses a target on the form GOOS/GOARCH, eg. linux/amd64.
+func ParseTarget(raw string) (Target, error) {
+	goos, goarch, ok := strings.Cut(raw, "/")
+	if !ok || !targetPart.MatchString(goos) || !targetPart.MatchString(goarch) {
+		return Target{}, fmt.Errorf("target '%s' is invalid: must be on the form GOOS/GOARCH, eg. linux/amd64", raw)
+	}
+	return Target{GOOS: goos, GOARCH: goarch}, nil
+}
+
+func (t Target) String() string {
+	return t.GOOS + "/" + t.GOARCH
+}
+
+// IsHost returns whether t is the platform shuttle runs on, ie. whether
+// binaries of t can be run by shuttle.
+func (t Target) IsHost() bool {
+	return t == HostTarget()
+}
+
+// Env returns the environment variables selecting t for go build.
+func (t Target) Env() []string {
+	return []string{"GOOS=" + t.GOOS, "GOARCH=" + t.GOARCH}
+}
+
+// ExeSuffix returns the suffix of executables of t.
+func (t Target) ExeSuffix() string {
+	if t.GOOS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
@@ -0,0 +1,53 @@
+package executors
+
+import (
+	"context"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// RunGuards runs the guards of the project once before script command is
+// executed. Guards are shell commands run in order with the environment of the
+// script and the first failing guard aborts the run.
+func RunGuards(
+	ctx context.Context,
+	p config.ShuttleProjectContext,
+	command string,
+	args map[string]string,
+	options ...ExecuteOption,
+) error {
+	scriptContext := ScriptExecutionContext{
+		ScriptName:      command,
+		Script:          p.Scripts[command],
+		Project:         p,
+		Args:            args,
+		SelectedScripts: []string{command},
+	}
+	for _, option := range options {
+		option(&scriptContext)
+	}
+	context := ActionExecutionContext{
+		ScriptContext: scriptContext,
+	}
+
+	for _, guard := range p.Guards() {
+		p.UI.Verboseln("Running guard '%s'", guard)
+
+		exitCode, err := runShellCommand(ctx, context, guard.Shell, false, nil)
+		if err != nil {
+			return err
+		}
+		if exitCode > 0 {
+			return errors.NewExitCode(
+				4,
+				"Guard '%s' failed before running script `%s`: shell script `%s`\nExit code: %v",
+				guard,
+				command,
+				guard.Shell,
+				exitCode,
+			)
+		}
+	}
+	return nil
+}
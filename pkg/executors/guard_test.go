@@ -0,0 +1,72 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunGuards(t *testing.T) {
+	tt := []struct {
+		name       string
+		planGuards []config.ShuttlePreflightCheck
+		guards     []config.ShuttlePreflightCheck
+		stdout     string
+		err        error
+	}{
+		{
+			name:   "no guards",
+			stdout: "",
+		},
+		{
+			name: "passing guards run plan guards first",
+			planGuards: []config.ShuttlePreflightCheck{
+				{Name: "plan", Shell: "echo plan"},
+			},
+			guards: []config.ShuttlePreflightCheck{
+				{Name: "project", Shell: `echo "project $env"`},
+			},
+			stdout: "plan\nproject prod\n",
+		},
+		{
+			name: "failing guard stops later guards",
+			planGuards: []config.ShuttlePreflightCheck{
+				{Name: "kube context", Shell: "echo wrong context; exit 1"},
+			},
+			guards: []config.ShuttlePreflightCheck{
+				{Shell: "echo not run"},
+			},
+			stdout: "wrong context\n",
+			err: errors.New(
+				"exit code 4 - Guard 'kube context' failed before running script `deploy`: shell script `echo wrong context; exit 1`\nExit code: 1",
+			),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+
+			err := RunGuards(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(&stdout, &bytes.Buffer{}),
+				Plan:        config.ShuttlePlanConfiguration{Guards: tc.planGuards},
+				Config:      config.ShuttleConfig{Guards: tc.guards},
+				Scripts: map[string]config.ShuttlePlanScript{
+					"deploy": {},
+				},
+			}, "deploy", map[string]string{"env": "prod"})
+
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.stdout, stdout.String())
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package executors
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// heartbeatInterval returns how long the shell command of the action may be
+// silent before a heartbeat is printed. The interval of the action takes
+// precedence over SHUTTLE_HEARTBEAT. Zero disables heartbeats.
+func heartbeatInterval(context ActionExecutionContext) (time.Duration, error) {
+	raw := context.Action.Heartbeat
+	name := "heartbeat of script `" + context.ScriptContext.ScriptName + "`"
+	if raw == "" {
+		raw = os.Getenv("SHUTTLE_HEARTBEAT")
+		name = "SHUTTLE_HEARTBEAT"
+	}
+	if raw == "" {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(raw)
+	if err != nil || interval < 0 {
+		return 0, errors.NewExitCode(
+			1,
+			"%s value '%s' is invalid: must be a duration, eg. 1m",
+			name,
+			raw,
+		)
+	}
+	return interval, nil
+}
+
+// heartbeat prints a line whenever an action has been silent for its
+// interval, eg. to keep CI systems from killing jobs without output. A nil
+// heartbeat does nothing.
+type heartbeat struct {
+	reset chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// startHeartbeat starts printing heartbeats of the action until ctx is
+// cancelled or the heartbeat is stopped. It returns nil if interval is zero.
+func startHeartbeat(ctx context.Context, context ActionExecutionContext, interval time.Duration) *heartbeat {
+	if interval == 0 {
+		return nil
+	}
+	h := &heartbeat{
+		reset: make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	start := time.Now()
+	go func() {
+		defer close(h.done)
+		timer := time.NewTimer(interval)
+		defer timer.Stop()
+		for {
+			select {
+			case <-timer.C:
+				context.ScriptContext.Project.UI.Infoln(
+					"Still running action %d of script `%s` (%s)",
+					context.ActionIndex,
+					context.ScriptContext.ScriptName,
+					time.Since(start).Round(time.Second),
+				)
+				timer.Reset(interval)
+			case <-h.reset:
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(interval)
+			case <-h.stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return h
+}
+
+// Reset restarts the interval, eg. when the action printed a line.
+func (h *heartbeat) Reset() {
+	if h == nil {
+		return
+	}
+	select {
+	case h.reset <- struct{}{}:
+	default:
+		// a reset is already pending
+	}
+}
+
+// Stop stops the heartbeat and waits for it to print its last line, if any.
+func (h *heartbeat) Stop() {
+	if h == nil {
+		return
+	}
+	select {
+	case <-h.stop:
+	default:
+		close(h.stop)
+	}
+	<-h.done
+}
@@ -0,0 +1,98 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_heartbeat(t *testing.T) {
+	tt := []struct {
+		name       string
+		action     config.ShuttleAction
+		env        string
+		heartbeats bool
+	}{
+		{
+			name:       "disabled by default",
+			action:     config.ShuttleAction{Shell: "sleep 0.3"},
+			heartbeats: false,
+		},
+		{
+			name:       "silent action",
+			action:     config.ShuttleAction{Shell: "sleep 0.3", Heartbeat: "50ms"},
+			heartbeats: true,
+		},
+		{
+			name:       "environment",
+			action:     config.ShuttleAction{Shell: "sleep 0.3"},
+			env:        "50ms",
+			heartbeats: true,
+		},
+		{
+			name:       "buffered silent action",
+			action:     config.ShuttleAction{Shell: "sleep 0.3", Heartbeat: "50ms", Output: OutputBuffered},
+			heartbeats: true,
+		},
+		{
+			name: "output resets the interval",
+			action: config.ShuttleAction{
+				Shell:     "for i in 1 2 3 4 5 6; do echo $i; sleep 0.05; done",
+				Heartbeat: "1s",
+			},
+			heartbeats: false,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("SHUTTLE_HEARTBEAT", tc.env)
+			stderr := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: t.TempDir(),
+				UI:          ui.Create(&bytes.Buffer{}, stderr),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{tc.action},
+					},
+				},
+			}, "test", nil, true)
+
+			assert.NoError(t, err)
+			heartbeats := strings.Count(stderr.String(), "Still running action 0 of script `test`")
+			if tc.heartbeats {
+				assert.GreaterOrEqual(t, heartbeats, 1, "heartbeats in %s", stderr.String())
+			} else {
+				assert.Equal(t, 0, heartbeats, "heartbeats in %s", stderr.String())
+			}
+		})
+	}
+}
+
+func TestExecute_heartbeatInvalid(t *testing.T) {
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: t.TempDir(),
+		UI:          ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Actions: []config.ShuttleAction{{Shell: "true", Heartbeat: "often"}},
+			},
+		},
+	}, "test", nil, true)
+
+	var exitCode *errors.ExitCode
+	require.ErrorAs(t, err, &exitCode)
+	assert.Equal(t, 1, exitCode.Code)
+	assert.Equal(t, "heartbeat of script `test` value 'often' is invalid: must be a duration, eg. 1m", exitCode.Message)
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"io"
 
+	"github.com/iancoleman/strcase"
+
 	"github.com/lunarway/shuttle/pkg/config"
 	"github.com/lunarway/shuttle/pkg/ui"
 )
@@ -24,12 +26,24 @@ type scriptHelpTemplateData struct {
 	Description string
 	Args        []scriptHelpTemplateArg
 	Max         int
+	// Idempotent is true if all actions of the script can be safely re-run
+	Idempotent bool
 }
 
 type scriptHelpTemplateArg struct {
-	Name        string
+	Name string
+	// Flag is the command line flag used to set the argument, eg. --my-arg
+	Flag        string
 	Required    string
 	Description string
+	// Type is the type of the argument, eg. int. Values are the values of
+	// enum arguments.
+	Type    string
+	Values  []string
+	Default string
+	Pattern string
+	// Constraints describes the type, default and pattern of the argument
+	Constraints string
 }
 
 func Help(
@@ -49,6 +63,7 @@ func Help(
 		Description: s.Description,
 		Args:        templateArgs(s.Args),
 		Max:         maxLength(s.Args),
+		Idempotent:  s.Idempotent(),
 	})
 	if err != nil {
 		return err
@@ -74,8 +89,14 @@ func templateArgs(values []config.ShuttleScriptArgs) []scriptHelpTemplateArg {
 	for i := range values {
 		scriptArgs[i] = scriptHelpTemplateArg{
 			Name:        values[i].Name,
+			Flag:        "--" + strcase.ToKebab(values[i].Name),
 			Required:    required(values[i].Required),
 			Description: values[i].Description,
+			Type:        argType(values[i].Type),
+			Values:      values[i].Values,
+			Default:     values[i].Default,
+			Pattern:     values[i].Pattern,
+			Constraints: values[i].Constraints(),
 		}
 	}
 	return scriptArgs
@@ -87,3 +108,10 @@ func required(b bool) string {
 	}
 	return ""
 }
+
+func argType(t string) string {
+	if t == "" {
+		return config.ArgTypeString
+	}
+	return t
+}
@@ -0,0 +1,316 @@
+package executors
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/filelock"
+	"github.com/lunarway/shuttle/pkg/telemetry"
+)
+
+// defaultHistoryKept is the number of runs kept in the history unless
+// configured with history.keep.
+const defaultHistoryKept = 1000
+
+// Environment variables configuring the audit endpoint runs are sent to.
+const (
+	historyEndpointEnv = "SHUTTLE_HISTORY_ENDPOINT"
+	historyTokenEnv    = "SHUTTLE_HISTORY_TOKEN"
+)
+
+// Locking of the history shared by shuttle processes running in the project.
+const (
+	historyLockPollInterval = 10 * time.Millisecond
+	historyLockTimeout      = 5 * time.Second
+)
+
+// historyUploadTimeout is how long sending a run to the audit endpoint may
+// take before it is given up.
+const historyUploadTimeout = 5 * time.Second
+
+// HistoryEntry is a run of a script recorded in the history of a project.
+type HistoryEntry struct {
+	// RunID identifies the shuttle invocation, see SHUTTLE_RUN_ID
+	RunID string `json:"runId"`
+	// ContextID identifies the invocations of shuttle sharing a
+	// SHUTTLE_CONTEXT_ID, eg. in a CI pipeline
+	ContextID string `json:"contextId"`
+	Script    string `json:"script"`
+	// Args are the arguments of the run with secrets redacted
+	Args map[string]string `json:"args,omitempty"`
+	Plan string            `json:"plan,omitempty"`
+	// PlanRevision is the git commit or OCI digest of the plan
+	PlanRevision string    `json:"planRevision,omitempty"`
+	User         string    `json:"user"`
+	StartedAt    time.Time `json:"startedAt"`
+	DurationMs   int64     `json:"durationMs"`
+	ExitCode     int       `json:"exitCode"`
+}
+
+// HistoryPath returns the path of the history of runs of the project.
+func HistoryPath(localShuttleDirectory string) string {
+	return filepath.Join(localShuttleDirectory, "history.jsonl")
+}
+
+// NewHistoryEntry returns the history entry of a run of script with args that
+// started at start and failed with err if not nil. Values of secret arguments
+// are replaced and values that look like secrets are redacted.
+func NewHistoryEntry(ctx context.Context, projectContext config.ShuttleProjectContext, script string, args map[string]string, planRevision string, start time.Time, err error) HistoryEntry {
+	secrets := make(map[string]bool)
+	for _, arg := range projectContext.Scripts[script].Args {
+		if arg.Secret {
+			secrets[arg.Name] = true
+		}
+	}
+	var redacted map[string]string
+	for name, value := range args {
+		if value == "" {
+			continue
+		}
+		if redacted == nil {
+			redacted = make(map[string]string, len(args))
+		}
+		if secrets[name] {
+			redacted[name] = secretMask
+			continue
+		}
+		redacted[name] = redactSecrets(nil, value)
+	}
+	return HistoryEntry{
+		RunID:        telemetry.RunIDFrom(ctx),
+		ContextID:    telemetry.ContextIDFrom(ctx),
+		Script:       script,
+		Args:         redacted,
+		Plan:         projectContext.Config.Plan,
+		PlanRevision: planRevision,
+		User:         currentUser(),
+		StartedAt:    start.UTC(),
+		DurationMs:   time.Since(start).Milliseconds(),
+		ExitCode:     actionExitCode(err),
+	}
+}
+
+// currentUser returns the name of the user running shuttle.
+func currentUser() string {
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return current.Username
+	}
+	for _, name := range []string{"USER", "USERNAME"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// RecordHistory appends entry to the history at path. The oldest runs beyond
+// the retention of settings are removed once the history is well over it. The
+// history is locked while it is written such that concurrent runs of shuttle
+// do not lose each other's entries.
+func RecordHistory(path string, entry HistoryEntry, settings config.ShuttleHistory) error {
+	keep := settings.Keep
+	if keep == 0 {
+		keep = defaultHistoryKept
+	}
+	if keep < 0 {
+		return errors.NewExitCode(1, "history.keep must be positive but was %d", keep)
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	unlock, err := lockHistory(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return pruneHistory(path, keep)
+}
+
+// lockHistory takes the lock of the history at path. It is held in a file
+// next to the history as the history itself is replaced when it is pruned.
+// The returned function releases the lock.
+func lockHistory(path string) (func() error, error) {
+	file, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(historyLockTimeout)
+	for {
+		locked, err := filelock.TryLock(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("lock history: %w", err)
+		}
+		if locked {
+			// closing the file releases the lock
+			return file.Close, nil
+		}
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, fmt.Errorf("lock history: still locked by another shuttle process after %s", historyLockTimeout)
+		}
+		time.Sleep(historyLockPollInterval)
+	}
+}
+
+// pruneHistory removes the oldest lines of the history at path beyond keep
+// once there are a tenth more lines than keep. The history is replaced
+// atomically such that it is never read partly written.
+func pruneHistory(path string, keep int) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= keep+keep/10 {
+		return nil
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(bytes.Join(lines[len(lines)-keep:], nil))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
+
+// ReadHistory returns the runs recorded in the history at path from oldest
+// to latest. Lines that cannot be read, eg. as shuttle was killed while
+// writing them, are skipped.
+func ReadHistory(path string) ([]HistoryEntry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+	defer file.Close()
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+	return entries, nil
+}
+
+// HistoryFilter selects runs of the history.
+type HistoryFilter struct {
+	Script string
+	User   string
+	// Since leaves out runs started before it if set
+	Since time.Time
+	// Failed selects runs with a non-zero exit code only
+	Failed bool
+	// Limit is the number of latest runs selected. All runs are selected if
+	// it is 0.
+	Limit int
+}
+
+// FilterHistory returns the entries matching filter from oldest to latest.
+func FilterHistory(entries []HistoryEntry, filter HistoryFilter) []HistoryEntry {
+	var matches []HistoryEntry
+	for _, entry := range entries {
+		switch {
+		case filter.Script != "" && entry.Script != filter.Script,
+			filter.User != "" && entry.User != filter.User,
+			!filter.Since.IsZero() && entry.StartedAt.Before(filter.Since),
+			filter.Failed && entry.ExitCode == 0:
+			continue
+		}
+		matches = append(matches, entry)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].StartedAt.Before(matches[j].StartedAt)
+	})
+	if filter.Limit > 0 && len(matches) > filter.Limit {
+		matches = matches[len(matches)-filter.Limit:]
+	}
+	return matches
+}
+
+// HistoryEndpoint returns the URL of the audit endpoint runs are sent to,
+// SHUTTLE_HISTORY_ENDPOINT if set or history.endpoint of settings.
+func HistoryEndpoint(settings config.ShuttleHistory) string {
+	if endpoint := os.Getenv(historyEndpointEnv); endpoint != "" {
+		return endpoint
+	}
+	return settings.Endpoint
+}
+
+// SendHistoryEntry posts entry as JSON to the audit endpoint authenticated
+// with the bearer token of SHUTTLE_HISTORY_TOKEN if set.
+func SendHistoryEntry(ctx context.Context, endpoint string, entry HistoryEntry) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, historyUploadTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("send run to %s: %w", redactURL(endpoint), err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token := os.Getenv(historyTokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send run to %s: %w", redactURL(endpoint), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s responded with status %s", redactURL(endpoint), resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package executors
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/config"
+)
+
+// hookEvent is the point in the run of a script hooks are run at
+type hookEvent string
+
+const (
+	hookPreRun    hookEvent = "preRun"
+	hookPostRun   hookEvent = "postRun"
+	hookOnFailure hookEvent = "onFailure"
+	hookOnCancel  hookEvent = "onCancel"
+)
+
+// executeWithHooks runs the preRun hooks, the script command with execute and
+// then the onFailure or onCancel hooks depending on the outcome followed by the
+// postRun hooks. The script is not run if a preRun hook fails. Hooks run after
+// the script never change its outcome, their failures are only reported.
+func (r *Registry) executeWithHooks(
+	ctx context.Context,
+	p config.ShuttleProjectContext,
+	hooks config.ShuttleHooks,
+	command string,
+	args map[string]string,
+	run scriptRun,
+	execute func(ctx context.Context) error,
+	options ...ExecuteOption,
+) error {
+	start := time.Now()
+	err := r.runHooks(ctx, p, hookPreRun, hooks.PreRun, command, args, run, hookEnvironment(p, hookPreRun, command, args), options...)
+	if err == nil {
+		err = execute(ctx)
+	}
+
+	hookCtx := ctx
+	if ctx.Err() != nil {
+		// hooks get a grace period to run after cancellation like always
+		// actions
+		var cancel context.CancelFunc
+		hookCtx, cancel = context.WithTimeout(context.WithoutCancel(ctx), defaultAlwaysGracePeriod)
+		defer cancel()
+	}
+	status := "succeeded"
+	var events []hookEvent
+	switch {
+	case ctx.Err() != nil || stderrors.Is(err, context.Canceled):
+		status = "cancelled"
+		events = append(events, hookOnCancel)
+	case err != nil:
+		status = "failed"
+		events = append(events, hookOnFailure)
+	}
+	events = append(events, hookPostRun)
+	duration := time.Since(start)
+
+	for _, event := range events {
+		env := hookEnvironment(p, event, command, args)
+		env["SHUTTLE_HOOK_STATUS"] = status
+		env["SHUTTLE_HOOK_EXIT_CODE"] = strconv.Itoa(actionExitCode(err))
+		env["SHUTTLE_HOOK_DURATION_MS"] = strconv.FormatInt(duration.Milliseconds(), 10)
+		hookErr := r.runHooks(hookCtx, p, event, hooksOf(hooks, event), command, args, run, env, options...)
+		if hookErr != nil {
+			p.UI.Errorln("Hook %s of script '%s' failed: %v", event, command, hookErr)
+		}
+	}
+	return err
+}
+
+func hooksOf(hooks config.ShuttleHooks, event hookEvent) []config.ShuttleAction {
+	switch event {
+	case hookPreRun:
+		return hooks.PreRun
+	case hookPostRun:
+		return hooks.PostRun
+	case hookOnFailure:
+		return hooks.OnFailure
+	case hookOnCancel:
+		return hooks.OnCancel
+	default:
+		return nil
+	}
+}
+
+// runHooks runs actions of the hook event like the actions of a script with
+// the arguments of script command and env as environment variables. The first
+// failing action fails the hook and only always actions are run after it.
+func (r *Registry) runHooks(
+	ctx context.Context,
+	p config.ShuttleProjectContext,
+	event hookEvent,
+	actions []config.ShuttleAction,
+	command string,
+	args map[string]string,
+	run scriptRun,
+	env map[string]string,
+	options ...ExecuteOption,
+) error {
+	if len(actions) == 0 {
+		return nil
+	}
+	scriptContext := ScriptExecutionContext{
+		ScriptName: fmt.Sprintf("hooks/%s", event),
+		Script: config.ShuttlePlanScript{
+			Args:    p.Scripts[command].Args,
+			Actions: actions,
+		},
+		Project:         p,
+		Args:            args,
+		SelectedScripts: run.selected,
+		Outputs:         env,
+		logFiles:        run.logFiles,
+	}
+	for _, option := range options {
+		option(&scriptContext)
+	}
+	scriptContext.Summary = nil
+
+	if err := actionProblemsError(r.validateScriptActions(scriptContext, false)); err != nil {
+		return err
+	}
+	var err error
+	scriptContext.Secrets, err = run.secrets.resolve(ctx, scriptContext)
+	if err != nil {
+		return err
+	}
+	p.UI.Verboseln("Running %d %s hooks of script `%s`", len(actions), event, command)
+	return r.executeActions(ctx, scriptContext, nil, defaultAlwaysGracePeriod)
+}
+
+// hookEnvironment returns the environment variables describing the run of
+// script command to hooks of event. Values of secret arguments are masked.
+func hookEnvironment(p config.ShuttleProjectContext, event hookEvent, command string, args map[string]string) map[string]string {
+	secret := make(map[string]bool)
+	for _, arg := range p.Scripts[command].Args {
+		secret[arg.Name] = arg.Secret
+	}
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	formatted := make([]string, 0, len(names))
+	for _, name := range names {
+		value := args[name]
+		if secret[name] {
+			value = secretMask
+		}
+		formatted = append(formatted, fmt.Sprintf("%s=%s", name, value))
+	}
+	return map[string]string{
+		"SHUTTLE_HOOK":        string(event),
+		"SHUTTLE_HOOK_SCRIPT": command,
+		"SHUTTLE_HOOK_ARGS":   strings.Join(formatted, " "),
+	}
+}
@@ -0,0 +1,139 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecute_hooks(t *testing.T) {
+	printHook := config.ShuttleAction{Shell: `echo "$SHUTTLE_HOOK $SHUTTLE_HOOK_SCRIPT [$SHUTTLE_HOOK_ARGS] $SHUTTLE_HOOK_STATUS $SHUTTLE_HOOK_EXIT_CODE"`}
+	tt := []struct {
+		name      string
+		planHooks config.ShuttleHooks
+		hooks     config.ShuttleHooks
+		action    string
+		stdout    string
+		stderr    string
+		err       error
+	}{
+		{
+			name: "succeeding script",
+			planHooks: config.ShuttleHooks{
+				PreRun:    []config.ShuttleAction{printHook},
+				PostRun:   []config.ShuttleAction{printHook},
+				OnFailure: []config.ShuttleAction{printHook},
+			},
+			hooks: config.ShuttleHooks{
+				PostRun: []config.ShuttleAction{{Shell: "echo project $env"}},
+			},
+			action: "echo deploying",
+			stdout: "preRun deploy [env=prod token=***]  \ndeploying\npostRun deploy [env=prod token=***] succeeded 0\nproject prod\n",
+		},
+		{
+			name: "failing script",
+			planHooks: config.ShuttleHooks{
+				PostRun:   []config.ShuttleAction{printHook},
+				OnFailure: []config.ShuttleAction{printHook},
+				OnCancel:  []config.ShuttleAction{printHook},
+			},
+			action: "exit 1",
+			stdout: "onFailure deploy [env=prod token=***] failed 4\npostRun deploy [env=prod token=***] failed 4\n",
+			err:    errors.New("exit code 4 - Failed executing script `deploy`: shell script `exit 1`\nExit code: 1"),
+		},
+		{
+			name: "failing preRun hook",
+			planHooks: config.ShuttleHooks{
+				PreRun:    []config.ShuttleAction{{Shell: "exit 3"}},
+				OnFailure: []config.ShuttleAction{printHook},
+			},
+			action: "echo not run",
+			stdout: "onFailure deploy [env=prod token=***] failed 4\n",
+			err:    errors.New("exit code 4 - Failed executing script `hooks/preRun`: shell script `exit 3`\nExit code: 3"),
+		},
+		{
+			name: "failing postRun hook",
+			planHooks: config.ShuttleHooks{
+				PostRun: []config.ShuttleAction{{Shell: "exit 3"}, {Shell: "echo not run"}},
+			},
+			action: "echo deploying",
+			stdout: "deploying\n",
+			stderr: "Hook postRun of script 'deploy' failed",
+		},
+		{
+			name: "when and always of hooks",
+			planHooks: config.ShuttleHooks{
+				PostRun: []config.ShuttleAction{
+					{Shell: "echo skipped", When: `env == "dev"`},
+					{Shell: "echo $SHUTTLE_HOOK_STATUS", When: `env == "prod"`},
+					{Shell: "exit 3"},
+					{Shell: "echo not run"},
+					{Shell: "echo always", Always: true},
+				},
+			},
+			action: "echo deploying",
+			stdout: "deploying\nsucceeded\nalways\n",
+			stderr: "Hook postRun of script 'deploy' failed",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			err := NewRegistry(ShellExecutor).Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(&stdout, &stderr),
+				Plan:        config.ShuttlePlanConfiguration{Hooks: tc.planHooks},
+				Config:      config.ShuttleConfig{Hooks: tc.hooks},
+				Scripts: map[string]config.ShuttlePlanScript{
+					"deploy": {
+						Args: []config.ShuttleScriptArgs{
+							{Name: "env"},
+							{Name: "token", Secret: true},
+						},
+						Actions: []config.ShuttleAction{{Shell: tc.action}},
+					},
+				},
+			}, "deploy", map[string]string{"env": "prod", "token": "s3cr3t-token"}, true)
+
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.stdout, stdout.String())
+			assert.Contains(t, stderr.String(), tc.stderr)
+		})
+	}
+}
+
+func TestExecute_hooksOnCancel(t *testing.T) {
+	var stdout bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		cancel()
+	}()
+
+	err := NewRegistry(ShellExecutor).Execute(ctx, config.ShuttleProjectContext{
+		ProjectPath: ".",
+		UI:          ui.Create(&stdout, &bytes.Buffer{}),
+		Config: config.ShuttleConfig{Hooks: config.ShuttleHooks{
+			OnFailure: []config.ShuttleAction{{Shell: "echo failed"}},
+			OnCancel:  []config.ShuttleAction{{Shell: `echo "cancelled $SHUTTLE_HOOK_STATUS $SHUTTLE_HOOK_EXIT_CODE"`}},
+		}},
+		Scripts: map[string]config.ShuttlePlanScript{
+			"deploy": {Actions: []config.ShuttleAction{{Shell: "sleep 10"}}},
+		},
+	}, "deploy", nil, true)
+
+	assert.Error(t, err)
+	assert.Equal(t, "cancelled cancelled 2", strings.TrimSpace(stdout.String()), "onCancel hooks run after cancellation")
+}
@@ -0,0 +1,91 @@
+package executors
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// actionEnv returns how the action of context inherits the environment of
+// shuttle. The env of the action takes precedence over that of shuttle.yaml
+// which takes precedence over that of the plan.
+func actionEnv(context ActionExecutionContext) config.ShuttleEnv {
+	project := context.ScriptContext.Project
+	for _, env := range []config.ShuttleEnv{context.Action.Env, project.Config.Env, project.Plan.Env} {
+		if env.Inherit != "" {
+			return env
+		}
+	}
+	return config.ShuttleEnv{Inherit: config.EnvInheritAll}
+}
+
+// inheritedEnvironment returns the variables of the environment of shuttle
+// inherited by the action of context.
+func inheritedEnvironment(context ActionExecutionContext) ([]string, error) {
+	env := actionEnv(context)
+	switch env.Inherit {
+	case config.EnvInheritAll:
+		return os.Environ(), nil
+	case config.EnvInheritNone, config.EnvInheritAllowlist:
+	default:
+		return nil, errors.NewExitCode(
+			1,
+			"env.inherit '%s' of script `%s` is invalid: must be one of %s, %s or %s",
+			env.Inherit,
+			context.ScriptContext.ScriptName,
+			config.EnvInheritAll,
+			config.EnvInheritNone,
+			config.EnvInheritAllowlist,
+		)
+	}
+	for _, pattern := range env.Allow {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.NewExitCode(
+				1,
+				"env.allow '%s' of script `%s` is invalid: %v",
+				pattern,
+				context.ScriptContext.ScriptName,
+				err,
+			)
+		}
+	}
+	var inherited []string
+	for _, entry := range os.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		if inheritsVariable(env, name) {
+			inherited = append(inherited, entry)
+		}
+	}
+	return inherited, nil
+}
+
+// inheritsVariable returns whether the variable name of the environment of
+// shuttle is inherited with env. Variables prefixed SHUTTLE_ are inherited by
+// every action such that nested invocations of shuttle work. Names are case
+// insensitive on Windows.
+func inheritsVariable(env config.ShuttleEnv, name string) bool {
+	if env.Inherit == config.EnvInheritAll {
+		return true
+	}
+	if goos == "windows" {
+		name = strings.ToUpper(name)
+	}
+	if strings.HasPrefix(name, "SHUTTLE_") {
+		return true
+	}
+	if env.Inherit != config.EnvInheritAllowlist {
+		return false
+	}
+	for _, pattern := range env.Allow {
+		if goos == "windows" {
+			pattern = strings.ToUpper(pattern)
+		}
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_inheritedEnvironment(t *testing.T) {
+	t.Setenv("HOST_VARIABLE", "host")
+	t.Setenv("CI_JOB_ID", "42")
+	t.Setenv("SHUTTLE_TEST_VARIABLE", "shuttle")
+	shell := `echo "${HOST_VARIABLE}|${CI_JOB_ID}|${SHUTTLE_TEST_VARIABLE}|${project}"`
+
+	tt := []struct {
+		name       string
+		projectEnv config.ShuttleEnv
+		planEnv    config.ShuttleEnv
+		actionEnv  config.ShuttleEnv
+		output     string
+		err        string
+	}{
+		{
+			name:   "inherit all by default",
+			output: "host|42|shuttle|/project\n",
+		},
+		{
+			name:    "inherit none",
+			planEnv: config.ShuttleEnv{Inherit: config.EnvInheritNone},
+			output:  "||shuttle|/project\n",
+		},
+		{
+			name:    "inherit allowlist",
+			planEnv: config.ShuttleEnv{Inherit: config.EnvInheritAllowlist, Allow: []string{"CI_*"}},
+			output:  "|42|shuttle|/project\n",
+		},
+		{
+			name:       "project takes precedence over plan",
+			projectEnv: config.ShuttleEnv{Inherit: config.EnvInheritAll},
+			planEnv:    config.ShuttleEnv{Inherit: config.EnvInheritNone},
+			output:     "host|42|shuttle|/project\n",
+		},
+		{
+			name:       "action takes precedence over project",
+			projectEnv: config.ShuttleEnv{Inherit: config.EnvInheritNone},
+			actionEnv:  config.ShuttleEnv{Inherit: config.EnvInheritAllowlist, Allow: []string{"HOST_VARIABLE"}},
+			output:     "host||shuttle|/project\n",
+		},
+		{
+			name:      "invalid mode",
+			actionEnv: config.ShuttleEnv{Inherit: "some"},
+			err:       "exit code 1 - env.inherit 'some' of script `test` is invalid: must be one of all, none or allowlist",
+		},
+		{
+			name:      "invalid pattern",
+			actionEnv: config.ShuttleEnv{Inherit: config.EnvInheritAllowlist, Allow: []string{"CI_["}},
+			err:       "exit code 1 - env.allow 'CI_[' of script `test` is invalid: syntax error in pattern",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: "/project",
+				UI:          ui.Create(stdout, &bytes.Buffer{}),
+				Config:      config.ShuttleConfig{Env: tc.projectEnv},
+				Plan:        config.ShuttlePlanConfiguration{Env: tc.planEnv},
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{
+							{Shell: shell, Env: tc.actionEnv},
+						},
+					},
+				},
+			}, "test", nil, true)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.output, stdout.String())
+		})
+	}
+}
+
+func TestInheritsVariable(t *testing.T) {
+	allowlist := config.ShuttleEnv{Inherit: config.EnvInheritAllowlist, Allow: []string{"HOME", "CI_*"}}
+	tt := []struct {
+		name      string
+		env       config.ShuttleEnv
+		variable  string
+		windows   bool
+		inherited bool
+	}{
+		{name: "all", env: config.ShuttleEnv{Inherit: config.EnvInheritAll}, variable: "AWS_PROFILE", inherited: true},
+		{name: "none", env: config.ShuttleEnv{Inherit: config.EnvInheritNone}, variable: "HOME", inherited: false},
+		{name: "shuttle variable", env: config.ShuttleEnv{Inherit: config.EnvInheritNone}, variable: "SHUTTLE_RUN_ID", inherited: true},
+		{name: "allowed name", env: allowlist, variable: "HOME", inherited: true},
+		{name: "allowed pattern", env: allowlist, variable: "CI_COMMIT_SHA", inherited: true},
+		{name: "not allowed", env: allowlist, variable: "PATH", inherited: false},
+		{name: "case sensitive", env: allowlist, variable: "Home", inherited: false},
+		{name: "case insensitive on windows", env: allowlist, variable: "Home", windows: true, inherited: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.windows {
+				defer func(previous string) { goos = previous }(goos)
+				goos = "windows"
+			}
+			assert.Equal(t, tc.inherited, inheritsVariable(tc.env, tc.variable))
+		})
+	}
+}
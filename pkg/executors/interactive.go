@@ -0,0 +1,102 @@
+package executors
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// validateInteractiveAction returns an error if the action uses options that
+// are not supported when running in a pseudo terminal.
+func validateInteractiveAction(context ActionExecutionContext) error {
+	option := ""
+	switch {
+	case context.Action.Background:
+		option = "run in the background"
+	case context.Action.Parallel:
+		option = "run in parallel"
+	case context.Action.CaptureOutput != "":
+		option = "capture output"
+	case context.Action.Docker != nil:
+		option = "run in a docker container"
+	default:
+		return nil
+	}
+	return errors.NewExitCode(
+		1,
+		"Action %d of script `%s` cannot %s as it is interactive",
+		context.ActionIndex,
+		context.ScriptContext.ScriptName,
+		option,
+	)
+}
+
+// interactiveTerminal returns the terminal an interactive action is connected
+// to. Actions fall back to streaming their output if shuttle is not attached
+// to a terminal, eg. in CI, if output is written as JSON or if the platform
+// has no pseudo terminals.
+func interactiveTerminal(context ActionExecutionContext) (*os.File, bool) {
+	if !context.Action.Interactive {
+		return nil, false
+	}
+	scriptUI := context.ScriptContext.Project.UI
+	fallback := func(reason string) (*os.File, bool) {
+		scriptUI.Verboseln(
+			"Action %d of script `%s` is not run interactively as %s",
+			context.ActionIndex,
+			context.ScriptContext.ScriptName,
+			reason,
+		)
+		return nil, false
+	}
+	if !ptySupported {
+		return fallback("pseudo terminals are not supported on this platform")
+	}
+	if scriptUI.Format() == ui.FormatJSON {
+		return fallback("output is written as JSON")
+	}
+	terminal, ok := context.ScriptContext.Stdin.(*os.File)
+	if !ok || !term.IsTerminal(int(terminal.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return fallback("stdin or stdout is not a terminal")
+	}
+	return terminal, true
+}
+
+// runInteractiveShellCommand runs the shell command of an interactive action
+// in a pseudo terminal connected to terminal and the stdout of shuttle. Its
+// output is neither masked, prefixed, scanned for secrets nor logged.
+func runInteractiveShellCommand(
+	ctx context.Context,
+	context ActionExecutionContext,
+	cmdArgs, env []string,
+	dir string,
+	terminal *os.File,
+	gracePeriod time.Duration,
+) (exitCode int, err error) {
+	lifecycle := newShellLifecycle(context)
+	lifecycle.Constructed(cmdArgs, env)
+	// earlier output is written before the command takes over the terminal
+	context.ScriptContext.Project.UI.Flush()
+
+	start := time.Now()
+	defer func() {
+		_, kind := actionScript(context.Action)
+		traceActionSpan(ctx, context, kind, start, exitCode, err)
+	}()
+	lifecycle.Started()
+	exitCode, err = runInteractiveCommand(ctx, cmdArgs, env, dir, terminal, os.Stdout, gracePeriod)
+	lifecycle.Exited(exitCode, err)
+	if ctx.Err() != nil {
+		return 0, errors.NewCancellation(ctx)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("run interactive shell command: %w", err)
+	}
+	return exitCode, nil
+}
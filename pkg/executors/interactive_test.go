@@ -0,0 +1,82 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_interactiveWithoutTerminal(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: t.TempDir(),
+		UI:          ui.Create(stdout, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Actions: []config.ShuttleAction{{
+					Shell:       `read line; echo "got $line"`,
+					Interactive: true,
+				}},
+			},
+		},
+	}, "test", nil, true, WithStdin(strings.NewReader("foo\n")))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "got foo\n", stdout.String())
+}
+
+func TestValidateInteractiveAction(t *testing.T) {
+	tt := []struct {
+		name   string
+		action config.ShuttleAction
+		err    string
+	}{
+		{
+			name:   "shell",
+			action: config.ShuttleAction{Shell: "aws sso login", Interactive: true},
+		},
+		{
+			name:   "background",
+			action: config.ShuttleAction{Shell: "aws sso login", Interactive: true, Background: true},
+			err:    "exit code 1 - Action 1 of script `test` cannot run in the background as it is interactive",
+		},
+		{
+			name:   "parallel",
+			action: config.ShuttleAction{Shell: "aws sso login", Interactive: true, Parallel: true},
+			err:    "exit code 1 - Action 1 of script `test` cannot run in parallel as it is interactive",
+		},
+		{
+			name:   "capture output",
+			action: config.ShuttleAction{Shell: "aws sso login", Interactive: true, CaptureOutput: "TOKEN"},
+			err:    "exit code 1 - Action 1 of script `test` cannot capture output as it is interactive",
+		},
+		{
+			name:   "docker",
+			action: config.ShuttleAction{Docker: &config.ShuttleDocker{Image: "alpine"}, Interactive: true},
+			err:    "exit code 1 - Action 1 of script `test` cannot run in a docker container as it is interactive",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateInteractiveAction(ActionExecutionContext{
+				ScriptContext: ScriptExecutionContext{ScriptName: "test"},
+				Action:        tc.action,
+				ActionIndex:   1,
+			})
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
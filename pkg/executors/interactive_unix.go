@@ -0,0 +1,135 @@
+//go:build !windows
+
+package executors
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+const ptySupported = true
+
+// inputPollInterval is how often the copy of input checks whether it should
+// stop while terminal has no input.
+const inputPollInterval = 50 * time.Millisecond
+
+// runInteractiveCommand runs cmdArgs in a pseudo terminal connected to
+// terminal. Input is passed through unbuffered with terminal in raw mode and
+// the output of the command is copied to out as is. The pseudo terminal follows
+// the size of terminal.
+func runInteractiveCommand(
+	ctx context.Context,
+	cmdArgs, env []string,
+	dir string,
+	terminal *os.File,
+	out io.Writer,
+	gracePeriod time.Duration,
+) (int, error) {
+	execCmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	execCmd.Env = env
+	execCmd.Dir = dir
+	ptmx, err := pty.Start(execCmd)
+	if err != nil {
+		return 0, err
+	}
+	defer ptmx.Close()
+
+	pty.InheritSize(terminal, ptmx)
+	resize := make(chan os.Signal, 1)
+	resized := make(chan struct{})
+	signal.Notify(resize, syscall.SIGWINCH)
+	go func() {
+		defer close(resized)
+		for range resize {
+			pty.InheritSize(terminal, ptmx)
+		}
+	}()
+	// resizing is stopped before the pseudo terminal is closed
+	defer func() {
+		signal.Stop(resize)
+		close(resize)
+		<-resized
+	}()
+
+	if state, err := term.MakeRaw(int(terminal.Fd())); err == nil {
+		defer term.Restore(int(terminal.Fd()), state)
+	}
+
+	// the copy of input is stopped before terminal is restored so input after
+	// the command exits is left for the next reader of terminal
+	stopInput := make(chan struct{})
+	inputStopped := make(chan struct{})
+	go func() {
+		defer close(inputStopped)
+		copyInput(ptmx, terminal, stopInput)
+	}()
+	defer func() {
+		close(stopInput)
+		<-inputStopped
+	}()
+
+	waited := make(chan error, 1)
+	go func() {
+		// the output is exhausted once the command and every process sharing
+		// the pseudo terminal has exited
+		io.Copy(out, ptmx)
+		waited <- execCmd.Wait()
+	}()
+
+	select {
+	case err = <-waited:
+	case <-ctx.Done():
+		// pty.Start starts the command in its own session so its process
+		// group is signalled to reach all its children
+		stopProcessGroup(execCmd.Process.Pid, syscall.SIGTERM, gracePeriod)
+		err = <-waited
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	return 0, err
+}
+
+// copyInput copies terminal to dst until stop is closed. terminal is only read
+// once it has input so nothing is consumed after stop is closed.
+func copyInput(dst io.Writer, terminal *os.File, stop <-chan struct{}) {
+	fd := int(terminal.Fd())
+	buf := make([]byte, 32*1024)
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		readable := &unix.FdSet{}
+		readable.Set(fd)
+		timeout := unix.NsecToTimeval(inputPollInterval.Nanoseconds())
+		n, err := unix.Select(fd+1, readable, nil, nil, &timeout)
+		if errors.Is(err, unix.EINTR) || n == 0 && err == nil {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		read, err := terminal.Read(buf)
+		if read > 0 {
+			if _, err := dst.Write(buf[:read]); err != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
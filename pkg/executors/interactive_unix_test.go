@@ -0,0 +1,118 @@
+//go:build !windows
+
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunInteractiveCommand(t *testing.T) {
+	tt := []struct {
+		name     string
+		script   string
+		input    string
+		output   string
+		exitCode int
+	}{
+		{
+			name:   "prompt",
+			script: `[ -t 0 ] && [ -t 1 ] && read answer && echo "got $answer"`,
+			input:  "yes\r",
+			output: "got yes",
+		},
+		{
+			name:     "exit code",
+			script:   `exit 3`,
+			exitCode: 3,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// the terminal of shuttle
+			user, terminal, err := pty.Open()
+			require.NoError(t, err)
+			defer user.Close()
+			defer terminal.Close()
+			_, err = user.WriteString(tc.input)
+			require.NoError(t, err)
+			out := &bytes.Buffer{}
+
+			exitCode, err := runInteractiveCommand(
+				context.Background(),
+				[]string{"sh", "-c", tc.script},
+				os.Environ(),
+				t.TempDir(),
+				terminal,
+				out,
+				time.Second,
+			)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.exitCode, exitCode, "exit code")
+			assert.Contains(t, out.String(), tc.output)
+		})
+	}
+}
+
+func TestRunInteractiveCommand_cancelled(t *testing.T) {
+	user, terminal, err := pty.Open()
+	require.NoError(t, err)
+	defer user.Close()
+	defer terminal.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = runInteractiveCommand(
+		ctx,
+		[]string{"sh", "-c", "read answer"},
+		os.Environ(),
+		t.TempDir(),
+		terminal,
+		&bytes.Buffer{},
+		time.Second,
+	)
+
+	assert.NoError(t, err)
+}
+
+func TestRunInteractiveCommand_inputAfterExit(t *testing.T) {
+	user, terminal, err := pty.Open()
+	require.NoError(t, err)
+	defer user.Close()
+	defer terminal.Close()
+
+	_, err = runInteractiveCommand(
+		context.Background(),
+		[]string{"sh", "-c", "exit 0"},
+		os.Environ(),
+		t.TempDir(),
+		terminal,
+		&bytes.Buffer{},
+		time.Second,
+	)
+	require.NoError(t, err)
+
+	// input typed after the command exits is left for the next reader
+	_, err = user.WriteString("next\n")
+	require.NoError(t, err)
+	read := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 16)
+		n, _ := terminal.Read(buf)
+		read <- string(buf[:n])
+	}()
+	select {
+	case input := <-read:
+		assert.Equal(t, "next\n", input)
+	case <-time.After(time.Second):
+		t.Fatal("input after exit was consumed")
+	}
+}
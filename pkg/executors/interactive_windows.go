@@ -0,0 +1,24 @@
+package executors
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+const ptySupported = false
+
+// runInteractiveCommand is never called on Windows as pseudo terminals are not
+// supported.
+func runInteractiveCommand(
+	ctx context.Context,
+	cmdArgs, env []string,
+	dir string,
+	terminal *os.File,
+	out io.Writer,
+	gracePeriod time.Duration,
+) (int, error) {
+	return 0, errors.New("pseudo terminals are not supported on Windows")
+}
@@ -0,0 +1,126 @@
+package executors
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Time       string           `xml:"time,attr"`
+	Properties *junitProperties `xml:"properties,omitempty"`
+	Cases      []junitTestCase  `xml:"testcase"`
+}
+
+type junitProperties struct {
+	Properties []junitProperty `xml:"property"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Output  string `xml:",chardata"`
+}
+
+// WriteJUnit writes summary as a JUnit XML report with each action as a test
+// case. Failed test cases include the exit code along with the output of the
+// action on stdout and stderr.
+func WriteJUnit(w io.Writer, summary RunSummary) error {
+	suite := junitTestSuite{
+		Name:  summary.Script,
+		Tests: len(summary.Actions),
+		Time:  junitSeconds(summary.Duration),
+	}
+	if summary.RunID != "" {
+		suite.Properties = &junitProperties{
+			Properties: []junitProperty{
+				{Name: "shuttle.runID", Value: summary.RunID},
+			},
+		}
+	}
+	for _, action := range summary.Actions {
+		testCase := junitTestCase{
+			ClassName: summary.Script,
+			Name:      fmt.Sprintf("%d: %s", action.Index, action.Description),
+			Time:      junitSeconds(action.Duration),
+		}
+		switch action.Status {
+		case ActionStatusFailed:
+			suite.Failures++
+			message := ""
+			if action.Err != nil {
+				message = action.Err.Error()
+			}
+			testCase.Failure = &junitFailure{
+				Message: message,
+				Type:    fmt.Sprintf("exit code %d", action.ExitCode),
+				Output:  strings.Join(action.Output, "\n"),
+			}
+			testCase.SystemOut = strings.Join(action.Stdout, "\n")
+			testCase.SystemErr = strings.Join(action.Stderr, "\n")
+		case ActionStatusSkipped:
+			suite.Skipped++
+			testCase.Skipped = &struct{}{}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(junitTestSuites{Suites: []junitTestSuite{suite}})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// WriteJUnitFile writes summary as a JUnit XML report to path.
+func WriteJUnitFile(path string, summary RunSummary) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	err = WriteJUnit(file, summary)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
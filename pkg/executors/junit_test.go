@@ -0,0 +1,119 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	summary := RunSummary{
+		RunID:    "run-id",
+		Script:   "build",
+		Duration: 1500 * time.Millisecond,
+		Actions: []ActionResult{
+			{
+				Index:       0,
+				Description: "go build ./...",
+				Status:      ActionStatusPassed,
+				Duration:    time.Second,
+			},
+			{
+				Index:       1,
+				Description: "go test ./...",
+				Status:      ActionStatusFailed,
+				Duration:    500 * time.Millisecond,
+				Err:         assert.AnError,
+				ExitCode:    1,
+				Output:      []string{"--- FAIL: TestA", "FAIL", "exit status 1"},
+				Stdout:      []string{"--- FAIL: TestA", "FAIL"},
+				Stderr:      []string{"exit status 1"},
+			},
+			{
+				Index:       2,
+				Description: "docker push",
+				Status:      ActionStatusSkipped,
+			},
+		},
+	}
+	var out bytes.Buffer
+
+	err := WriteJUnit(&out, summary)
+
+	require.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="build" tests="3" failures="1" skipped="1" time="1.500">
+    <properties>
+      <property name="shuttle.runID" value="run-id"></property>
+    </properties>
+    <testcase classname="build" name="0: go build ./..." time="1.000"></testcase>
+    <testcase classname="build" name="1: go test ./..." time="0.500">
+      <failure message="assert.AnError general error for testing" type="exit code 1">--- FAIL: TestA&#xA;FAIL&#xA;exit status 1</failure>
+      <system-out>--- FAIL: TestA&#xA;FAIL</system-out>
+      <system-err>exit status 1</system-err>
+    </testcase>
+    <testcase classname="build" name="2: docker push" time="0.000">
+      <skipped></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, out.String())
+}
+
+func TestExecute_summary(t *testing.T) {
+	registry := NewRegistry(ShellExecutor)
+	var summary RunSummary
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: ".",
+		UI:          ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Actions: []config.ShuttleAction{
+					{Shell: "echo first"},
+					{Shell: "echo second; >&2 echo failing; exit 1"},
+					{Shell: "echo third"},
+				},
+			},
+		},
+	}, "test", nil, true, WithSummary(&summary))
+
+	require.Error(t, err)
+	assert.Equal(t, "test", summary.Script)
+	assert.True(t, summary.Failed())
+	require.Len(t, summary.Actions, 3)
+	assert.Equal(t, ActionStatusPassed, summary.Actions[0].Status)
+	assert.Equal(t, []string{"first"}, summary.Actions[0].Output)
+	assert.Equal(t, ActionStatusFailed, summary.Actions[1].Status)
+	assert.Equal(t, err, summary.Actions[1].Err)
+	assert.Equal(t, 4, summary.Actions[1].ExitCode, "exit code of failing actions")
+	assert.ElementsMatch(t, []string{"second", "failing"}, summary.Actions[1].Output)
+	assert.Equal(t, []string{"second"}, summary.Actions[1].Stdout)
+	assert.Equal(t, []string{"failing"}, summary.Actions[1].Stderr)
+	assert.Equal(t, ActionResult{
+		Index:       2,
+		Description: "echo third",
+		Status:      ActionStatusSkipped,
+	}, summary.Actions[2])
+}
+
+func TestOutputTail(t *testing.T) {
+	tail := newOutputTail(2)
+	tail.Add("stdout", "a")
+	tail.Add("stderr", "b")
+	tail.Add("stdout", "c")
+	assert.Equal(t, []string{"b", "c"}, tail.Lines())
+	assert.Equal(t, []string{"c"}, tail.StreamLines("stdout"))
+	assert.Equal(t, []string{"b"}, tail.StreamLines("stderr"))
+
+	var disabled *outputTail
+	disabled.Add("stdout", "a")
+	assert.Nil(t, disabled.Lines())
+}
@@ -0,0 +1,96 @@
+package executors
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// defaultLatencyThreshold is the gap between two output lines above which a
+// gap is recorded
+const defaultLatencyThreshold = time.Second
+
+// latencyRecorder records gaps between output lines of a script to diagnose
+// stalls. A nil recorder is disabled and records nothing.
+type latencyRecorder struct {
+	out       io.WriteCloser
+	threshold time.Duration
+	script    string
+	now       func() time.Time
+	last      time.Time
+	lines     int
+}
+
+// newLatencyRecorder returns a recorder writing to the file in
+// SHUTTLE_OUTPUT_LATENCY_LOG. Gaps larger than
+// SHUTTLE_OUTPUT_LATENCY_THRESHOLD, defaulting to 1s, are recorded. If no log
+// file is configured a nil recorder is returned.
+func newLatencyRecorder(script string) (*latencyRecorder, error) {
+	logPath := os.Getenv("SHUTTLE_OUTPUT_LATENCY_LOG")
+	if logPath == "" {
+		return nil, nil
+	}
+
+	threshold := defaultLatencyThreshold
+	if raw := os.Getenv("SHUTTLE_OUTPUT_LATENCY_THRESHOLD"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, errors.NewExitCode(
+				1,
+				"Invalid SHUTTLE_OUTPUT_LATENCY_THRESHOLD '%s': %v",
+				raw,
+				err,
+			)
+		}
+		threshold = parsed
+	}
+
+	file, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, errors.NewExitCode(1, "Failed to open output latency log: %v", err)
+	}
+
+	recorder := &latencyRecorder{
+		out:       file,
+		threshold: threshold,
+		script:    script,
+		now:       time.Now,
+	}
+	recorder.last = recorder.now()
+	return recorder, nil
+}
+
+// Record registers a line forwarded from stream and logs the time since the
+// previous line if it exceeds the threshold.
+func (r *latencyRecorder) Record(stream string) {
+	if r == nil {
+		return
+	}
+	now := r.now()
+	gap := now.Sub(r.last)
+	r.last = now
+	r.lines++
+	if gap < r.threshold {
+		return
+	}
+	fmt.Fprintf(
+		r.out,
+		"%s script=%s stream=%s line=%d gap=%s\n",
+		now.Format(time.RFC3339Nano),
+		r.script,
+		stream,
+		r.lines,
+		gap,
+	)
+}
+
+// Close closes the underlying log file.
+func (r *latencyRecorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	return r.out.Close()
+}
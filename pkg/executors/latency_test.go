@@ -0,0 +1,60 @@
+package executors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyRecorder(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		t.Setenv("SHUTTLE_OUTPUT_LATENCY_LOG", "")
+
+		recorder, err := newLatencyRecorder("build")
+
+		require.NoError(t, err)
+		assert.Nil(t, recorder)
+		// a disabled recorder must be safe to use
+		recorder.Record("stdout")
+		assert.NoError(t, recorder.Close())
+	})
+
+	t.Run("invalid threshold", func(t *testing.T) {
+		t.Setenv("SHUTTLE_OUTPUT_LATENCY_LOG", filepath.Join(t.TempDir(), "latency.log"))
+		t.Setenv("SHUTTLE_OUTPUT_LATENCY_THRESHOLD", "soon")
+
+		_, err := newLatencyRecorder("build")
+
+		assert.EqualError(t, err, `exit code 1 - Invalid SHUTTLE_OUTPUT_LATENCY_THRESHOLD 'soon': time: invalid duration "soon"`)
+	})
+
+	t.Run("records gaps above threshold", func(t *testing.T) {
+		logPath := filepath.Join(t.TempDir(), "latency.log")
+		t.Setenv("SHUTTLE_OUTPUT_LATENCY_LOG", logPath)
+		t.Setenv("SHUTTLE_OUTPUT_LATENCY_THRESHOLD", "500ms")
+
+		recorder, err := newLatencyRecorder("build")
+		require.NoError(t, err)
+
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		now := start
+		recorder.now = func() time.Time { return now }
+		recorder.last = start
+
+		now = now.Add(100 * time.Millisecond)
+		recorder.Record("stdout")
+		now = now.Add(2 * time.Second)
+		recorder.Record("stderr")
+		now = now.Add(10 * time.Millisecond)
+		recorder.Record("stdout")
+		require.NoError(t, recorder.Close())
+
+		content, err := os.ReadFile(logPath)
+		require.NoError(t, err)
+		assert.Equal(t, "2024-01-01T00:00:02.1Z script=build stream=stderr line=2 gap=2s\n", string(content))
+	})
+}
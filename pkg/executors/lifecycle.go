@@ -0,0 +1,92 @@
+package executors
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// shellLifecycle logs the lifecycle of a shell command as structured events
+// at verbose level. It is meant for debugging the executor itself, eg. why a
+// command was not stopped on cancellation, and not the output of the script.
+// Stops are requested concurrently with the start of the command.
+type shellLifecycle struct {
+	ui     *ui.UI
+	script string
+	action int
+	now    func() time.Time
+
+	mu    sync.Mutex
+	start time.Time
+}
+
+func newShellLifecycle(context ActionExecutionContext) *shellLifecycle {
+	return &shellLifecycle{
+		ui:     context.ScriptContext.Project.UI,
+		script: context.ScriptContext.ScriptName,
+		action: context.ActionIndex,
+		now:    time.Now,
+	}
+}
+
+// Constructed logs the command and the number of environment variables it is
+// started with. Values of the environment are never logged.
+func (l *shellLifecycle) Constructed(cmdArgs []string, env []string) {
+	l.log("constructed", "command", strings.Join(cmdArgs, " "), "env", strconv.Itoa(len(env)))
+}
+
+// Started logs the start time of the command.
+func (l *shellLifecycle) Started() {
+	l.mu.Lock()
+	l.start = l.now()
+	start := l.start
+	l.mu.Unlock()
+	l.log("started", "time", start.UTC().Format(time.RFC3339Nano))
+}
+
+// StopRequested logs that the command is being stopped as ctx is done and why.
+func (l *shellLifecycle) StopRequested(ctx context.Context) {
+	l.log("stop-requested", "reason", context.Cause(ctx).Error(), "elapsed", l.elapsed())
+}
+
+// Exited logs the exit status of the command.
+func (l *shellLifecycle) Exited(exitCode int, err error) {
+	fields := []string{"exit", strconv.Itoa(exitCode), "elapsed", l.elapsed()}
+	if err != nil {
+		fields = append(fields, "error", err.Error())
+	}
+	l.log("exited", fields...)
+}
+
+func (l *shellLifecycle) elapsed() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.start.IsZero() {
+		return "0s"
+	}
+	return l.now().Sub(l.start).String()
+}
+
+// log writes event with fields given as key value pairs on the form
+// 'shell lifecycle: event=<event> script=<script> action=<index> key=value'.
+// Values with spaces or quotes are quoted.
+func (l *shellLifecycle) log(event string, fields ...string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "shell lifecycle: event=%s script=%s action=%d", event, quoteValue(l.script), l.action)
+	for i := 0; i+1 < len(fields); i += 2 {
+		fmt.Fprintf(&b, " %s=%s", fields[i], quoteValue(fields[i+1]))
+	}
+	l.ui.Verboseln("%s", b.String())
+}
+
+func quoteValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		return strconv.Quote(value)
+	}
+	return value
+}
@@ -0,0 +1,93 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestShellLifecycle(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	verboseUI := ui.Create(&bytes.Buffer{}, stderr)
+	verboseUI.SetUserLevel(ui.LevelVerbose)
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	lifecycle := &shellLifecycle{
+		ui:     verboseUI,
+		script: "build",
+		action: 1,
+		now: func() time.Time {
+			return now
+		},
+	}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(errors.ErrInterrupted)
+
+	lifecycle.Constructed([]string{"sh", "-c", "echo hi"}, []string{"A=1", "B=2"})
+	lifecycle.Started()
+	now = now.Add(2 * time.Second)
+	lifecycle.StopRequested(ctx)
+	lifecycle.Exited(-1, nil)
+
+	assert.Equal(t, `shell lifecycle: event=constructed script=build action=1 command="sh -c echo hi" env=2
+shell lifecycle: event=started script=build action=1 time=2024-01-01T10:00:00Z
+shell lifecycle: event=stop-requested script=build action=1 reason=interrupted elapsed=2s
+shell lifecycle: event=exited script=build action=1 exit=-1 elapsed=2s
+`, stderr.String())
+}
+
+func TestExecute_shellLifecycle(t *testing.T) {
+	tt := []struct {
+		name     string
+		level    ui.Level
+		contains []string
+	}{
+		{
+			name:  "verbose",
+			level: ui.LevelVerbose,
+			contains: []string{
+				"shell lifecycle: event=constructed script=test action=0 command=",
+				"shell lifecycle: event=started script=test action=0 time=",
+				"shell lifecycle: event=exited script=test action=0 exit=3 elapsed=",
+			},
+		},
+		{
+			name:  "default",
+			level: ui.LevelInfo,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stderr := &bytes.Buffer{}
+			uii := ui.Create(&bytes.Buffer{}, stderr)
+			uii.SetUserLevel(tc.level)
+
+			registry := NewRegistry(ShellExecutor)
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          uii,
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{
+							{Shell: "exit 3"},
+						},
+					},
+				},
+			}, "test", nil, true)
+
+			assert.Error(t, err)
+			for _, line := range tc.contains {
+				assert.Contains(t, stderr.String(), line)
+			}
+			if len(tc.contains) == 0 {
+				assert.NotContains(t, stderr.String(), "shell lifecycle")
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package executors
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// WithLogFile writes the output of shell actions to the file at path besides
+// the terminal. The placeholders {script} and {action} are replaced with the
+// script name and action index, eg. logs/{script}-{action}.log, to write a
+// file per action.
+func WithLogFile(path string) ExecuteOption {
+	return func(c *ScriptExecutionContext) {
+		c.LogFile = path
+	}
+}
+
+// logFiles tracks the log files opened by a run. A file is truncated when it is
+// first opened and appended to by later actions and retries.
+type logFiles struct {
+	lock   sync.Mutex
+	opened map[string]bool
+}
+
+func newLogFiles() *logFiles {
+	return &logFiles{opened: make(map[string]bool)}
+}
+
+func (l *logFiles) open(path string) (*os.File, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if !l.opened[path] {
+		flags |= os.O_TRUNC
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	l.opened[path] = true
+	return file, nil
+}
+
+// actionLog writes output lines of an action to its log file. A nil
+// actionLog discards all lines.
+type actionLog struct {
+	file  *os.File
+	label string
+	err   error
+}
+
+// openActionLog opens the log file of the action, if any. The file of the
+// action is relative to the project while that of the run is relative to the
+// working directory of shuttle.
+func openActionLog(context ActionExecutionContext) (*actionLog, error) {
+	raw := context.Action.LogFile
+	base := context.ScriptContext.Project.ProjectPath
+	if raw == "" {
+		raw = context.ScriptContext.LogFile
+		base = ""
+	}
+	if raw == "" {
+		return nil, nil
+	}
+	path := strings.NewReplacer(
+		"{script}", context.ScriptContext.ScriptName,
+		"{action}", strconv.Itoa(context.ActionIndex),
+	).Replace(raw)
+	if !filepath.IsAbs(path) && base != "" {
+		path = filepath.Join(base, path)
+	}
+	files := context.ScriptContext.logFiles
+	if files == nil {
+		files = newLogFiles()
+	}
+	file, err := files.open(path)
+	if err != nil {
+		return nil, errors.NewExitCode(
+			1,
+			"Log file '%s' of script `%s` could not be opened: %v",
+			raw,
+			context.ScriptContext.ScriptName,
+			err,
+		)
+	}
+	return &actionLog{file: file, label: actionLabel(context)}, nil
+}
+
+// Write writes line with the action and the stream it was written to, eg.
+// "[build/0] stderr: line". The line is written immediately such that the
+// file is complete up to the last line if shuttle is killed.
+func (l *actionLog) Write(stream, line string) {
+	if l == nil || l.err != nil {
+		return
+	}
+	_, l.err = fmt.Fprintf(l.file, "[%s] %s: %s\n", l.label, stream, line)
+}
+
+// Close closes the log file and returns the first error writing to it.
+func (l *actionLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	err := l.file.Close()
+	if l.err != nil {
+		return l.err
+	}
+	return err
+}
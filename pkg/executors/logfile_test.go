@@ -0,0 +1,136 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_logFile(t *testing.T) {
+	actions := []config.ShuttleAction{
+		// lines of stdout and stderr of the same action may be reordered
+		{Shell: "echo problem >&2"},
+		{Shell: `echo "token $token"`, Label: "deploy"},
+	}
+	tt := []struct {
+		name    string
+		logFile string
+		actions []config.ShuttleAction
+		files   map[string]string
+	}{
+		{
+			name:    "shared",
+			logFile: "run.log",
+			actions: actions,
+			files: map[string]string{
+				"run.log": "[test/0] stderr: problem\n[deploy] stdout: token ***\n",
+			},
+		},
+		{
+			name:    "per action",
+			logFile: "logs/{script}-{action}.log",
+			actions: actions,
+			files: map[string]string{
+				"logs/test-0.log": "[test/0] stderr: problem\n",
+				"logs/test-1.log": "[deploy] stdout: token ***\n",
+			},
+		},
+		{
+			name:    "action overrides run",
+			logFile: "run.log",
+			actions: []config.ShuttleAction{
+				{Shell: "echo first"},
+				{Shell: "echo second", LogFile: "action.log"},
+			},
+			files: map[string]string{
+				"run.log":    "[test/0] stdout: first\n",
+				"action.log": "[test/1] stdout: second\n",
+			},
+		},
+		{
+			name: "truncated by the run",
+			actions: []config.ShuttleAction{
+				{Shell: "echo first", LogFile: "action.log"},
+				{Shell: "echo second", LogFile: "action.log"},
+			},
+			files: map[string]string{
+				"action.log": "[test/0] stdout: first\n[test/1] stdout: second\n",
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			projectPath := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(projectPath, "action.log"), []byte("previous run\n"), 0o644))
+			logFile := tc.logFile
+			if logFile != "" {
+				logFile = filepath.Join(projectPath, logFile)
+			}
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: projectPath,
+				UI:          ui.Create(stdout, stderr),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Args:    []config.ShuttleScriptArgs{{Name: "token", Secret: true}},
+						Actions: tc.actions,
+					},
+				},
+			}, "test", map[string]string{"token": "s3cr3t-value"}, false, WithLogFile(logFile))
+
+			assert.NoError(t, err)
+			var streamed []string
+			for name, expected := range tc.files {
+				content, err := os.ReadFile(filepath.Join(projectPath, name))
+				require.NoError(t, err)
+				assert.Equal(t, expected, string(content), name)
+				streamed = append(streamed, strings.Split(strings.TrimSpace(string(content)), "\n")...)
+			}
+			// every line streamed to the terminal is in a log file
+			for _, line := range strings.Split(strings.TrimSpace(stdout.String()+stderr.String()), "\n") {
+				assert.Condition(t, func() bool {
+					for _, logged := range streamed {
+						if strings.HasSuffix(logged, ": "+line) {
+							return true
+						}
+					}
+					return false
+				}, "line '%s' not logged", line)
+			}
+		})
+	}
+}
+
+func TestExecute_logFileNotOpened(t *testing.T) {
+	projectPath := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(projectPath, "logs"), 0o755))
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: projectPath,
+		UI:          ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Actions: []config.ShuttleAction{{Shell: "echo out", LogFile: "logs"}},
+			},
+		},
+	}, "test", nil, true)
+
+	var exitCode *errors.ExitCode
+	require.ErrorAs(t, err, &exitCode)
+	assert.Equal(t, 1, exitCode.Code)
+	assert.Contains(t, exitCode.Message, "Log file 'logs' of script `test` could not be opened")
+}
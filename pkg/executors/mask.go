@@ -0,0 +1,123 @@
+package executors
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// secretMask replaces values of secret arguments and secrets in output
+const secretMask = "***"
+
+// minMaskedSecretLength is the length secret values must have to be masked.
+// Shorter values would mask unrelated output, eg. every "1".
+const minMaskedSecretLength = 4
+
+// minMaskedEnvLength is the length values of environment variables matching
+// the masked patterns must have to be masked. It is longer than for secrets
+// as variables like USE_TOKEN=true are matched by name only.
+const minMaskedEnvLength = 8
+
+// DefaultMaskedEnv are the patterns of names of environment variables masked
+// in output by default
+var DefaultMaskedEnv = []string{"*_TOKEN", "*_PASSWORD", "*_SECRET"}
+
+// ParseMaskedEnv validates the patterns of names of environment variables
+// masked in output. Empty patterns are ignored such that masking can be
+// disabled.
+func ParseMaskedEnv(patterns []string) ([]string, error) {
+	var parsed []string
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.NewExitCode(2, "Masked environment variable pattern '%s' is invalid: %v", pattern, err)
+		}
+		parsed = append(parsed, strings.ToUpper(pattern))
+	}
+	return parsed, nil
+}
+
+// WithMaskedEnv masks the values of environment variables of shuttle with
+// names matching any of the patterns in the output of actions. Patterns are
+// matched case insensitively with path.Match, eg. *_TOKEN.
+func WithMaskedEnv(patterns []string) ExecuteOption {
+	return func(c *ScriptExecutionContext) {
+		c.MaskedEnv = patterns
+	}
+}
+
+// maskedEnvValues returns the values of environment variables with names
+// matching any of patterns.
+func maskedEnvValues(patterns []string) []string {
+	if len(patterns) == 0 {
+		return nil
+	}
+	var values []string
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if len(value) < minMaskedEnvLength {
+			continue
+		}
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, strings.ToUpper(name)); matched {
+				values = append(values, value)
+				break
+			}
+		}
+	}
+	return values
+}
+
+// secretMasker masks the values of secret arguments and secrets of a script in
+// output lines. A nil masker leaves lines untouched.
+type secretMasker struct {
+	replacer *strings.Replacer
+}
+
+// newSecretMasker returns a masker for the values of the arguments of the
+// script marked as secret, the secrets of the project and the environment
+// variables matching the masked patterns. Secrets are redacted already in dry
+// runs.
+func newSecretMasker(context ScriptExecutionContext) *secretMasker {
+	var values []string
+	for _, arg := range context.Script.Args {
+		value := context.Args[arg.Name]
+		if arg.Secret && len(value) >= minMaskedSecretLength {
+			values = append(values, value)
+		}
+	}
+	if !context.DryRun {
+		for _, value := range context.Secrets {
+			if len(value) >= minMaskedSecretLength {
+				values = append(values, value)
+			}
+		}
+	}
+	values = append(values, maskedEnvValues(context.MaskedEnv)...)
+	if len(values) == 0 {
+		return nil
+	}
+	// longer values are replaced first such that a secret containing another
+	// secret is masked entirely
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	pairs := make([]string, 0, 2*len(values))
+	for _, value := range values {
+		pairs = append(pairs, value, secretMask)
+	}
+	return &secretMasker{replacer: strings.NewReplacer(pairs...)}
+}
+
+// Mask replaces all occurrences of secret values in line.
+func (m *secretMasker) Mask(line string) string {
+	if m == nil {
+		return line
+	}
+	return m.replacer.Replace(line)
+}
@@ -0,0 +1,160 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestSecretMasker(t *testing.T) {
+	tt := []struct {
+		name   string
+		args   []config.ShuttleScriptArgs
+		values map[string]string
+		input  string
+		output string
+	}{
+		{
+			name:   "no secrets",
+			args:   []config.ShuttleScriptArgs{{Name: "token"}},
+			values: map[string]string{"token": "s3cr3t"},
+			input:  "token s3cr3t",
+			output: "token s3cr3t",
+		},
+		{
+			name:   "all occurrences",
+			args:   []config.ShuttleScriptArgs{{Name: "token", Secret: true}},
+			values: map[string]string{"token": "s3cr3t"},
+			input:  "s3cr3t and s3cr3t",
+			output: "*** and ***",
+		},
+		{
+			name:   "short values are not masked",
+			args:   []config.ShuttleScriptArgs{{Name: "pin", Secret: true}, {Name: "empty", Secret: true}},
+			values: map[string]string{"pin": "123", "empty": ""},
+			input:  "pin 123",
+			output: "pin 123",
+		},
+		{
+			name: "longest secret first",
+			args: []config.ShuttleScriptArgs{
+				{Name: "short", Secret: true},
+				{Name: "long", Secret: true},
+			},
+			values: map[string]string{"short": "pass", "long": "password123"},
+			input:  "password123 pass",
+			output: "*** ***",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			masker := newSecretMasker(ScriptExecutionContext{
+				Script: config.ShuttlePlanScript{Args: tc.args},
+				Args:   tc.values,
+			})
+			assert.Equal(t, tc.output, masker.Mask(tc.input))
+		})
+	}
+}
+
+func TestExecute_secretArgs(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: ".",
+		UI:          ui.Create(stdout, stderr),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"deploy": {
+				Args: []config.ShuttleScriptArgs{{Name: "token", Secret: true}},
+				Actions: []config.ShuttleAction{
+					{Shell: `echo "using $token"; echo "token=$token" >&2; [ "$token" = "s3cr3t-value" ] && echo "env ok"`},
+				},
+			},
+		},
+	}, "deploy", map[string]string{"token": "s3cr3t-value"}, true)
+
+	require.NoError(t, err)
+	assert.Equal(t, "using ***\nenv ok\n", stdout.String())
+	assert.Equal(t, "token=***\n", stderr.String())
+}
+
+func TestSecretMasker_maskedEnv(t *testing.T) {
+	t.Setenv("DEPLOY_TOKEN", "deploy-t0ken-value")
+	t.Setenv("db_password", "db-passw0rd-value")
+	t.Setenv("USE_TOKEN", "true")
+	t.Setenv("DEPLOY_USER", "deploy-user-value")
+
+	tt := []struct {
+		name     string
+		patterns []string
+		input    string
+		output   string
+	}{
+		{
+			name:     "default patterns",
+			patterns: DefaultMaskedEnv,
+			input:    "deploy-t0ken-value db-passw0rd-value deploy-user-value true",
+			output:   "*** *** deploy-user-value true",
+		},
+		{
+			name:     "custom patterns",
+			patterns: []string{"DEPLOY_*"},
+			input:    "deploy-t0ken-value db-passw0rd-value deploy-user-value",
+			output:   "*** db-passw0rd-value ***",
+		},
+		{
+			name:     "no patterns",
+			patterns: nil,
+			input:    "deploy-t0ken-value",
+			output:   "deploy-t0ken-value",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			patterns, err := ParseMaskedEnv(tc.patterns)
+			require.NoError(t, err)
+
+			masker := newSecretMasker(ScriptExecutionContext{MaskedEnv: patterns})
+
+			assert.Equal(t, tc.output, masker.Mask(tc.input))
+		})
+	}
+}
+
+func TestParseMaskedEnv(t *testing.T) {
+	patterns, err := ParseMaskedEnv([]string{"*_token", ""})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*_TOKEN"}, patterns)
+
+	_, err = ParseMaskedEnv([]string{"[*_TOKEN"})
+	assert.EqualError(t, err, "exit code 2 - Masked environment variable pattern '[*_TOKEN' is invalid: syntax error in pattern")
+}
+
+func TestExecute_maskedEnv(t *testing.T) {
+	t.Setenv("REGISTRY_TOKEN", "registry-t0ken-value")
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: ".",
+		UI:          ui.Create(stdout, stderr),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"login": {
+				Actions: []config.ShuttleAction{
+					{Shell: `echo "token $REGISTRY_TOKEN"; echo "token=$REGISTRY_TOKEN" >&2`},
+				},
+			},
+		},
+	}, "login", map[string]string{}, true, WithMaskedEnv(DefaultMaskedEnv))
+
+	require.NoError(t, err)
+	assert.Equal(t, "token ***\n", stdout.String())
+	assert.Equal(t, "token=***\n", stderr.String())
+}
@@ -0,0 +1,79 @@
+package executors
+
+import (
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// scriptPrerequisites returns the scripts needed by script command, directly
+// or through other scripts, in the order they must run. Every script is
+// returned once even if it is needed by several scripts.
+func scriptPrerequisites(scripts map[string]config.ShuttlePlanScript, command string) ([]string, error) {
+	var order []string
+	visited := make(map[string]bool)
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		for i, visiting := range path {
+			if visiting == name {
+				return errors.NewExitCode(
+					1,
+					"Script '%s' cannot be run as its needs form a cycle: %s",
+					command,
+					strings.Join(append(path[i:], name), " -> "),
+				)
+			}
+		}
+		if visited[name] {
+			return nil
+		}
+		path = append(path, name)
+		for _, needed := range scripts[name].Needs {
+			if _, ok := scripts[needed]; !ok {
+				return errors.NewExitCode(
+					1,
+					"Script '%s' needs script '%s' which is not defined",
+					name,
+					needed,
+				)
+			}
+			if err := visit(needed); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		visited[name] = true
+		order = append(order, name)
+		return nil
+	}
+	if err := visit(command); err != nil {
+		return nil, err
+	}
+	// the script itself is visited last
+	return order[:len(order)-1], nil
+}
+
+// neededScriptArgs returns the arguments of script prerequisite needed by
+// script command. Arguments of the same name are passed on from args.
+func neededScriptArgs(p config.ShuttleProjectContext, prerequisite, command string, args map[string]string) (map[string]string, error) {
+	prerequisiteArgs := make(map[string]string)
+	for _, arg := range p.Scripts[prerequisite].Args {
+		value, ok := args[arg.Name]
+		if !ok {
+			if arg.Required {
+				return nil, errors.NewExitCode(
+					2,
+					"Script '%s' needed by script '%s' requires argument '%s' which is not supplied",
+					prerequisite,
+					command,
+					arg.Name,
+				)
+			}
+			continue
+		}
+		prerequisiteArgs[arg.Name] = value
+	}
+	return prerequisiteArgs, nil
+}
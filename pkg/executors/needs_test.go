@@ -0,0 +1,149 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestScriptPrerequisites(t *testing.T) {
+	tt := []struct {
+		name          string
+		scripts       map[string]config.ShuttlePlanScript
+		prerequisites []string
+		err           string
+	}{
+		{
+			name:          "no needs",
+			scripts:       map[string]config.ShuttlePlanScript{"build": {}},
+			prerequisites: []string{},
+		},
+		{
+			name: "shared needs run once",
+			scripts: map[string]config.ShuttlePlanScript{
+				"build":    {Needs: []string{"generate", "lint"}},
+				"generate": {Needs: []string{"tools"}},
+				"lint":     {Needs: []string{"tools"}},
+				"tools":    {},
+			},
+			prerequisites: []string{"tools", "generate", "lint"},
+		},
+		{
+			name: "cycle",
+			scripts: map[string]config.ShuttlePlanScript{
+				"build":    {Needs: []string{"generate"}},
+				"generate": {Needs: []string{"lint"}},
+				"lint":     {Needs: []string{"generate"}},
+			},
+			err: "exit code 1 - Script 'build' cannot be run as its needs form a cycle: generate -> lint -> generate",
+		},
+		{
+			name: "needs itself",
+			scripts: map[string]config.ShuttlePlanScript{
+				"build": {Needs: []string{"build"}},
+			},
+			err: "exit code 1 - Script 'build' cannot be run as its needs form a cycle: build -> build",
+		},
+		{
+			name: "unknown script",
+			scripts: map[string]config.ShuttlePlanScript{
+				"build": {Needs: []string{"generate"}},
+			},
+			err: "exit code 1 - Script 'build' needs script 'generate' which is not defined",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			prerequisites, err := scriptPrerequisites(tc.scripts, "build")
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.prerequisites, prerequisites)
+		})
+	}
+}
+
+func TestExecute_needs(t *testing.T) {
+	tt := []struct {
+		name    string
+		scripts map[string]config.ShuttlePlanScript
+		args    map[string]string
+		ran     string
+		err     string
+	}{
+		{
+			name: "prerequisites first",
+			scripts: map[string]config.ShuttlePlanScript{
+				"build":    {Needs: []string{"generate", "lint"}, Actions: []config.ShuttleAction{{Shell: `echo build >> "$log"`}}},
+				"generate": {Needs: []string{"tools"}, Actions: []config.ShuttleAction{{Shell: `echo generate >> "$log"`}}},
+				"lint":     {Needs: []string{"tools"}, Actions: []config.ShuttleAction{{Shell: `echo lint >> "$log"`}}},
+				"tools":    {Actions: []config.ShuttleAction{{Shell: `echo tools >> "$log"`}}},
+			},
+			ran: "tools\ngenerate\nlint\nbuild\n",
+		},
+		{
+			name: "failing prerequisite",
+			scripts: map[string]config.ShuttlePlanScript{
+				"build":    {Needs: []string{"generate"}, Actions: []config.ShuttleAction{{Shell: `echo build >> "$log"`}}},
+				"generate": {Actions: []config.ShuttleAction{{Shell: "exit 2"}}},
+			},
+			err: "exit code 4 - Failed executing script `generate`: shell script `exit 2`\nExit code: 2",
+		},
+		{
+			name: "arguments passed on",
+			scripts: map[string]config.ShuttlePlanScript{
+				"build":    {Needs: []string{"generate"}, Args: []config.ShuttleScriptArgs{{Name: "version"}}},
+				"generate": {Args: []config.ShuttleScriptArgs{{Name: "version", Required: true}}, Actions: []config.ShuttleAction{{Shell: `echo "generate $version" >> "$log"`}}},
+			},
+			args: map[string]string{"version": "1.2.3"},
+			ran:  "generate 1.2.3\n",
+		},
+		{
+			name: "required argument not passed on",
+			scripts: map[string]config.ShuttlePlanScript{
+				"build":    {Needs: []string{"generate"}},
+				"generate": {Args: []config.ShuttleScriptArgs{{Name: "version", Required: true}}},
+			},
+			err: "exit code 2 - Script 'generate' needed by script 'build' requires argument 'version' which is not supplied",
+		},
+		{
+			name: "invalid action of the script",
+			scripts: map[string]config.ShuttlePlanScript{
+				"build":    {Needs: []string{"generate"}, Actions: []config.ShuttleAction{{}}},
+				"generate": {Actions: []config.ShuttleAction{{Shell: `echo generate >> "$log"`}}},
+			},
+			err: "exit code 1 - Action 0 of script `build` has no shell, powershell, docker, task or wasm to run",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			log := filepath.Join(t.TempDir(), "log")
+			t.Setenv("log", log)
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+				Scripts:     tc.scripts,
+			}, "build", tc.args, true)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			ran, _ := os.ReadFile(log)
+			assert.Equal(t, tc.ran, string(ran))
+		})
+	}
+}
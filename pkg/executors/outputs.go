@@ -0,0 +1,54 @@
+package executors
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// outputFileEnv is the environment variable holding the path of the file
+// shell and task actions write their outputs to
+const outputFileEnv = "SHUTTLE_OUTPUT"
+
+// createOutputFile creates the empty file the action writes its outputs to and
+// returns its path with a function removing it. It is created in the temp
+// directory of the action if any.
+func createOutputFile(context ActionExecutionContext) (string, func(), error) {
+	dir := context.TempDirectoryPath()
+	if dir != "" {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return "", nil, err
+		}
+	}
+	file, err := os.CreateTemp(dir, "output-")
+	if err != nil {
+		return "", nil, err
+	}
+	file.Close()
+	path, err := filepath.Abs(file.Name())
+	if err != nil {
+		os.Remove(file.Name())
+		return "", nil, err
+	}
+	return path, func() { os.Remove(path) }, nil
+}
+
+// readOutputs adds the outputs written by the action to path, on the form
+// NAME=value like env files, to the outputs of the script.
+func readOutputs(context ActionExecutionContext, path string) error {
+	variables, err := readEnvFile(path)
+	if err != nil {
+		return errors.NewExitCode(
+			1,
+			"Outputs of action %d of script `%s` are invalid: %v",
+			context.ActionIndex,
+			context.ScriptContext.ScriptName,
+			err,
+		)
+	}
+	for _, variable := range variables {
+		context.ScriptContext.Outputs[variable.name] = variable.value
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_outputs(t *testing.T) {
+	tt := []struct {
+		name    string
+		scripts map[string]config.ShuttlePlanScript
+		stdout  string
+		err     string
+	}{
+		{
+			name: "used by later actions",
+			scripts: map[string]config.ShuttlePlanScript{
+				"test": {Actions: []config.ShuttleAction{
+					{Shell: `echo "version=1.2.3" >> "$SHUTTLE_OUTPUT"; echo 'tag="v1 latest"' >> "$SHUTTLE_OUTPUT"`},
+					{Shell: `echo "$version $tag"`},
+				}},
+			},
+			stdout: "1.2.3 v1 latest\n",
+		},
+		{
+			name: "used by when",
+			scripts: map[string]config.ShuttlePlanScript{
+				"test": {Actions: []config.ShuttleAction{
+					{Shell: `echo "changed=false" >> "$SHUTTLE_OUTPUT"`},
+					{Shell: "echo deploy", When: "$changed == 'true'"},
+				}},
+			},
+		},
+		{
+			name: "used by scripts needing the script",
+			scripts: map[string]config.ShuttlePlanScript{
+				"version": {Actions: []config.ShuttleAction{{Shell: `echo "version=1.2.3" >> "$SHUTTLE_OUTPUT"`}}},
+				"test":    {Needs: []string{"version"}, Actions: []config.ShuttleAction{{Shell: `echo "$version"`}}},
+			},
+			stdout: "1.2.3\n",
+		},
+		{
+			name: "nothing from failing actions",
+			scripts: map[string]config.ShuttlePlanScript{
+				"test": {Actions: []config.ShuttleAction{
+					{Shell: `echo "version=1.2.3" >> "$SHUTTLE_OUTPUT"; exit 1`},
+					{Shell: `echo "version=$version"`, Always: true},
+				}},
+			},
+			stdout: "version=\n",
+			err:    "exit code 4 - Failed executing script `test`: shell script `echo \"version=1.2.3\" >> \"$SHUTTLE_OUTPUT\"; exit 1`\nExit code: 1",
+		},
+		{
+			name: "invalid outputs",
+			scripts: map[string]config.ShuttlePlanScript{
+				"test": {Actions: []config.ShuttleAction{{Shell: `echo "not an output" >> "$SHUTTLE_OUTPUT"`}}},
+			},
+			err: "exit code 1 - Outputs of action 0 of script `test` are invalid: line 1: expected NAME=value",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(stdout, &bytes.Buffer{}),
+				Scripts:     tc.scripts,
+			}, "test", nil, true)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.stdout, stdout.String())
+		})
+	}
+}
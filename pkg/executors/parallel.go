@@ -0,0 +1,146 @@
+package executors
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// actionGroups returns the indices of actions grouped such that adjacent
+// actions marked parallel share a group. All other actions are in a group of
+// their own.
+func actionGroups(actions []config.ShuttleAction) [][]int {
+	var groups [][]int
+	for i, action := range actions {
+		last := len(groups) - 1
+		if action.Parallel && i > 0 && actions[i-1].Parallel {
+			groups[last] = append(groups[last], i)
+			continue
+		}
+		groups = append(groups, []int{i})
+	}
+	return groups
+}
+
+// executeParallelActions executes the actions of group concurrently and waits
+// for all of them to complete. The first failure cancels the other actions of
+// the group and is returned. If failed is set only always actions are run and
+// their failures are printed instead as they must not mask the primary
+// failure.
+//
+// Output of the actions is prefixed with the action producing it, they have no
+// stdin and re-runs are confirmed one at a time. Every action reads and writes
+// outputs of its own which are merged in the order of the actions once all
+// have completed such that a later action of the group takes precedence.
+func (r *Registry) executeParallelActions(
+	ctx context.Context,
+	scriptContext ScriptExecutionContext,
+	group []int,
+	failed bool,
+	gracePeriod time.Duration,
+) error {
+	groupCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	groupContext := scriptContext
+	groupContext.PrefixOutput = true
+	groupContext.Stdin = nil
+	if confirm := scriptContext.ConfirmRerun; confirm != nil {
+		var lock sync.Mutex
+		groupContext.ConfirmRerun = func(context ActionExecutionContext) (bool, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			return confirm(context)
+		}
+	}
+
+	// actions record their results and outputs in summaries and outputs of
+	// their own which are merged in the order of the actions once all have
+	// completed
+	summaries := make([]*RunSummary, len(group))
+	outputs := make([]map[string]string, len(group))
+	errs := make([]error, len(group))
+	var (
+		lock  sync.Mutex
+		first error
+		wg    sync.WaitGroup
+	)
+	for i, actionIndex := range group {
+		actionContext := groupContext
+		if scriptContext.Summary != nil {
+			summaries[i] = &RunSummary{}
+			actionContext.Summary = summaries[i]
+		}
+		if scriptContext.Outputs != nil {
+			outputs[i] = make(map[string]string, len(scriptContext.Outputs))
+			for name, value := range scriptContext.Outputs {
+				outputs[i][name] = value
+			}
+			actionContext.Outputs = outputs[i]
+		}
+		wg.Add(1)
+		go func(i, actionIndex int, actionContext ScriptExecutionContext) {
+			defer wg.Done()
+			err := r.executeScriptAction(groupCtx, actionContext, actionIndex, failed, gracePeriod)
+			errs[i] = err
+			if err == nil {
+				return
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			if first == nil {
+				first = err
+				cancel()
+			}
+		}(i, actionIndex, actionContext)
+	}
+	wg.Wait()
+
+	if summary := scriptContext.Summary; summary != nil {
+		for _, actionSummary := range summaries {
+			summary.Actions = append(summary.Actions, actionSummary.Actions...)
+		}
+	}
+	if scriptContext.Outputs != nil {
+		// only the outputs written by the actions are merged such that an
+		// earlier action of the group is not overridden by values the later
+		// ones started with
+		base := make(map[string]string, len(scriptContext.Outputs))
+		for name, value := range scriptContext.Outputs {
+			base[name] = value
+		}
+		for _, actionOutputs := range outputs {
+			for name, value := range actionOutputs {
+				if previous, ok := base[name]; !ok || previous != value {
+					scriptContext.Outputs[name] = value
+				}
+			}
+		}
+	}
+	if !failed {
+		return first
+	}
+	for i, err := range errs {
+		if err != nil {
+			scriptContext.Project.UI.Errorln("Always action %d of script '%s' failed: %v", group[i], scriptContext.ScriptName, err)
+		}
+	}
+	return nil
+}
+
+// validateParallelAction returns an error if the action uses options that are
+// not supported when running in parallel.
+func validateParallelAction(context ActionExecutionContext) error {
+	if context.Action.CaptureOutput == "" {
+		return nil
+	}
+	return errors.NewExitCode(
+		1,
+		"Action %d of script `%s` cannot capture output as it runs in parallel",
+		context.ActionIndex,
+		context.ScriptContext.ScriptName,
+	)
+}
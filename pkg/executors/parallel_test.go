@@ -0,0 +1,197 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestActionGroups(t *testing.T) {
+	tt := []struct {
+		name    string
+		actions []config.ShuttleAction
+		groups  [][]int
+	}{
+		{
+			name:    "sequential",
+			actions: []config.ShuttleAction{{}, {}},
+			groups:  [][]int{{0}, {1}},
+		},
+		{
+			name:    "parallel",
+			actions: []config.ShuttleAction{{}, {Parallel: true}, {Parallel: true}, {}},
+			groups:  [][]int{{0}, {1, 2}, {3}},
+		},
+		{
+			name:    "single parallel",
+			actions: []config.ShuttleAction{{Parallel: true}, {}, {Parallel: true}},
+			groups:  [][]int{{0}, {1}, {2}},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.groups, actionGroups(tc.actions))
+		})
+	}
+}
+
+// lockedBuffer is a buffer safe for concurrent writes
+type lockedBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
+
+// waitFor waits for the other action of a group to create its file
+const waitFor = `touch "$dir/%[1]s"; i=0; while [ ! -f "$dir/%[2]s" ] && [ $i -lt 500 ]; do sleep 0.01; i=$((i+1)); done; test -f "$dir/%[2]s" && echo %[1]s`
+
+func TestExecute_parallel(t *testing.T) {
+	tt := []struct {
+		name    string
+		actions []config.ShuttleAction
+		stdout  []string
+		err     string
+	}{
+		{
+			name: "runs concurrently",
+			actions: []config.ShuttleAction{
+				{Shell: fmt.Sprintf(waitFor, "a", "b"), Parallel: true},
+				{Shell: fmt.Sprintf(waitFor, "b", "a"), Parallel: true},
+				{Shell: "echo after"},
+			},
+			stdout: []string{"[test/0] a", "[test/1] b", "after"},
+		},
+		{
+			name: "fails fast",
+			actions: []config.ShuttleAction{
+				{Shell: "exit 3", Parallel: true},
+				{Shell: "sleep 10; echo slept", Parallel: true},
+				{Shell: "echo after"},
+			},
+			err: "exit code 4 - Failed executing script `test`: shell script `exit 3`\nExit code: 3",
+		},
+		{
+			name: "always actions after failure",
+			actions: []config.ShuttleAction{
+				{Shell: "exit 3"},
+				{Shell: "echo skipped", Parallel: true},
+				{Shell: "echo always", Parallel: true, Always: true},
+			},
+			stdout: []string{"[test/2] always"},
+			err:    "exit code 4 - Failed executing script `test`: shell script `exit 3`\nExit code: 3",
+		},
+		{
+			name: "capture output",
+			actions: []config.ShuttleAction{
+				{Shell: "echo a", Parallel: true, CaptureOutput: "A"},
+				{Shell: "echo b", Parallel: true},
+			},
+			err: "exit code 1 - Action 0 of script `test` cannot capture output as it runs in parallel",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &lockedBuffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			start := time.Now()
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(stdout, &lockedBuffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Args:    []config.ShuttleScriptArgs{{Name: "dir"}},
+						Actions: tc.actions,
+					},
+				},
+			}, "test", map[string]string{"dir": t.TempDir()}, true)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			var lines []string
+			if output := strings.TrimSpace(stdout.String()); output != "" {
+				lines = strings.Split(output, "\n")
+			}
+			// actions of a group are not ordered
+			sort.Strings(lines)
+			sort.Strings(tc.stdout)
+			assert.Equal(t, tc.stdout, lines)
+			assert.Less(t, time.Since(start), 5*time.Second)
+		})
+	}
+}
+
+func TestExecute_parallelSummary(t *testing.T) {
+	summary := &RunSummary{}
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: ".",
+		UI:          ui.Create(&lockedBuffer{}, &lockedBuffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Actions: []config.ShuttleAction{
+					{Shell: "sleep 0.2", Parallel: true},
+					{Shell: "true", Parallel: true},
+					{Shell: "true"},
+				},
+			},
+		},
+	}, "test", nil, true, WithSummary(summary))
+
+	assert.NoError(t, err)
+	var indices []int
+	for _, action := range summary.Actions {
+		indices = append(indices, action.Index)
+	}
+	assert.Equal(t, []int{0, 1, 2}, indices)
+}
+
+func TestExecute_parallelOutputs(t *testing.T) {
+	stdout := &lockedBuffer{}
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: ".",
+		UI:          ui.Create(stdout, &lockedBuffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Actions: []config.ShuttleAction{
+					{Shell: `echo "base=0" >> "$SHUTTLE_OUTPUT"; echo "shared=base" >> "$SHUTTLE_OUTPUT"`},
+					// the first action completes last but the later action of
+					// the group takes precedence
+					{Shell: `sleep 0.2; echo "version=1" >> "$SHUTTLE_OUTPUT"; echo "shared=first" >> "$SHUTTLE_OUTPUT"`, Parallel: true},
+					{Shell: `echo "image=app" >> "$SHUTTLE_OUTPUT"; echo "shared=second" >> "$SHUTTLE_OUTPUT"`, Parallel: true},
+					{Shell: `echo "$base $version $image $shared"`},
+				},
+			},
+		},
+	}, "test", nil, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0 1 app second\n", stdout.String())
+}
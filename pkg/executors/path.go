@@ -0,0 +1,46 @@
+package executors
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// searchPath returns the PATH of an action. The directory of the shuttle
+// binary comes first followed by the path entries of the action, relative to
+// the project, and the path entries of the plan, relative to the plan, before
+// the PATH of shuttle itself if the action inherits it. Entries are converted
+// with shellPath when shellPaths is set.
+func searchPath(context ActionExecutionContext, shuttlePath string, shellPaths bool) string {
+	project := context.ScriptContext.Project
+	planPath := project.LocalPlanPath
+	if planPath == "" {
+		planPath = project.ProjectPath
+	}
+
+	entries := []string{shuttlePath}
+	for _, declared := range []struct {
+		base    string
+		entries []string
+	}{
+		{base: project.ProjectPath, entries: context.Action.Path},
+		{base: planPath, entries: project.Plan.Path},
+	} {
+		for _, entry := range declared.entries {
+			if entry == "" {
+				continue
+			}
+			if !filepath.IsAbs(entry) {
+				entry = filepath.Join(declared.base, entry)
+			}
+			if shellPaths {
+				entry = shellPath(entry)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	if inheritsVariable(actionEnv(context), "PATH") {
+		entries = append(entries, os.Getenv("PATH"))
+	}
+	return strings.Join(entries, string(os.PathListSeparator))
+}
@@ -0,0 +1,128 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_path(t *testing.T) {
+	projectPath := t.TempDir()
+	planPath := filepath.Join(projectPath, ".shuttle", "plan")
+	writeTool := func(dir, name, output string) {
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\necho "+output+"\n"), 0o755))
+	}
+	writeTool(filepath.Join(planPath, "bin"), "plan-tool", "from plan")
+	writeTool(filepath.Join(planPath, "bin"), "shared-tool", "shared from plan")
+	writeTool(filepath.Join(projectPath, "tools"), "shared-tool", "shared from action")
+	stdout := &bytes.Buffer{}
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath:   projectPath,
+		LocalPlanPath: planPath,
+		UI:            ui.Create(stdout, &bytes.Buffer{}),
+		Plan: config.ShuttlePlanConfiguration{
+			Path: []string{"bin"},
+		},
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Actions: []config.ShuttleAction{
+					{Shell: "plan-tool"},
+					{Shell: "shared-tool", Path: []string{"tools"}},
+				},
+			},
+		},
+	}, "test", nil, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "from plan\nshared from action\n", stdout.String())
+}
+
+func TestSearchPath(t *testing.T) {
+	t.Setenv("PATH", "/usr/bin")
+	sep := string(os.PathListSeparator)
+	tt := []struct {
+		name          string
+		localPlanPath string
+		actionPath    []string
+		planPath      []string
+		env           config.ShuttleEnv
+		windows       bool
+		output        string
+	}{
+		{
+			name:   "no entries",
+			output: strings.Join([]string{"/shuttle", "/usr/bin"}, sep),
+		},
+		{
+			name:          "action before plan",
+			localPlanPath: "/src/app/.shuttle/plan",
+			actionPath:    []string{"tools", "/opt/tools"},
+			planPath:      []string{"bin"},
+			output:        strings.Join([]string{"/shuttle", "/src/app/tools", "/opt/tools", "/src/app/.shuttle/plan/bin", "/usr/bin"}, sep),
+		},
+		{
+			name:     "plan relative to project without plan",
+			planPath: []string{"bin"},
+			output:   strings.Join([]string{"/shuttle", "/src/app/bin", "/usr/bin"}, sep),
+		},
+		{
+			name:       "empty entries are ignored",
+			actionPath: []string{""},
+			output:     strings.Join([]string{"/shuttle", "/usr/bin"}, sep),
+		},
+		{
+			name:       "PATH not inherited",
+			actionPath: []string{"tools"},
+			env:        config.ShuttleEnv{Inherit: config.EnvInheritNone},
+			output:     strings.Join([]string{"/shuttle", "/src/app/tools"}, sep),
+		},
+		{
+			name:   "PATH allowed",
+			env:    config.ShuttleEnv{Inherit: config.EnvInheritAllowlist, Allow: []string{"PATH"}},
+			output: strings.Join([]string{"/shuttle", "/usr/bin"}, sep),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			output := searchPath(ActionExecutionContext{
+				ScriptContext: ScriptExecutionContext{
+					Project: config.ShuttleProjectContext{
+						ProjectPath:   "/src/app",
+						LocalPlanPath: tc.localPlanPath,
+						Plan:          config.ShuttlePlanConfiguration{Path: tc.planPath},
+					},
+				},
+				Action: config.ShuttleAction{Path: tc.actionPath, Env: tc.env},
+			}, "/shuttle", true)
+
+			assert.Equal(t, tc.output, output)
+		})
+	}
+}
+
+func TestSearchPath_windows(t *testing.T) {
+	defer func(previous string) { goos = previous }(goos)
+	goos = "windows"
+	t.Setenv("PATH", "")
+	context := ActionExecutionContext{
+		ScriptContext: ScriptExecutionContext{
+			Project: config.ShuttleProjectContext{ProjectPath: `C:\src\app`},
+		},
+		Action: config.ShuttleAction{Path: []string{"bin"}},
+	}
+	sep := string(os.PathListSeparator)
+
+	assert.Equal(t, "/shuttle"+sep+"/c/src/app/bin"+sep, searchPath(context, "/shuttle", true))
+}
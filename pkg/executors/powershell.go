@@ -0,0 +1,107 @@
+package executors
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// PowerShellExecutor runs actions with a powershell script. They share the
+// implementation of shell actions except for how the command is started.
+func PowerShellExecutor(action config.ShuttleAction) (Executor, bool) {
+	return executeShell, action.PowerShell != ""
+}
+
+// powerShellInterpreters are the PowerShell executables used in order of
+// preference when the action has no interpreter
+var powerShellInterpreters = []string{"pwsh", "powershell.exe"}
+
+// actionScript returns the script of a shell, PowerShell or docker action and
+// the kind of script for use in messages.
+func actionScript(action config.ShuttleAction) (string, string) {
+	if action.Docker != nil {
+		return action.Shell, "docker"
+	}
+	if action.PowerShell != "" {
+		return action.PowerShell, "powershell"
+	}
+	return action.Shell, "shell"
+}
+
+// validatePowerShellAction returns an error if the action uses options only
+// supported by POSIX shell actions.
+func validatePowerShellAction(context ActionExecutionContext) error {
+	if context.Action.Shell != "" {
+		return errors.NewExitCode(
+			1,
+			"Action %d of script `%s` cannot have both shell and powershell",
+			context.ActionIndex,
+			context.ScriptContext.ScriptName,
+		)
+	}
+	option := ""
+	switch {
+	case context.Action.Sudo:
+		option = "sudo"
+	case context.Action.Background:
+		option = "background"
+	default:
+		return nil
+	}
+	return errors.NewExitCode(
+		1,
+		"Action %d of script `%s` cannot use %s as it is a powershell action",
+		context.ActionIndex,
+		context.ScriptContext.ScriptName,
+		option,
+	)
+}
+
+// powerShellCommand returns the command running script with PowerShell from
+// the working directory of the action. Unlike shell actions paths are kept in their native
+// format.
+func powerShellCommand(ctx context.Context, context ActionExecutionContext, script string) ([]string, []string, string, error) {
+	workDir, err := actionWorkingDirectory(context)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	interpreter, err := powerShellInterpreter(context)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	merge, err := mergeStderr(context)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if merge {
+		script = fmt.Sprintf("& {\n%s\n} 2>&1", script)
+	}
+	env, err := shellEnvironment(context)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	env = append(env, telemetryEnvironment(ctx)...)
+	cmdArgs := []string{interpreter, "-NoProfile", "-NonInteractive", "-Command", script}
+	return cmdArgs, env, workDir, nil
+}
+
+// powerShellInterpreter returns the interpreter of the action or the first
+// PowerShell executable available on PATH.
+func powerShellInterpreter(context ActionExecutionContext) (string, error) {
+	if context.Action.Interpreter != "" {
+		return shellInterpreter(context)
+	}
+	for _, interpreter := range powerShellInterpreters {
+		if _, err := exec.LookPath(interpreter); err == nil {
+			return interpreter, nil
+		}
+	}
+	return "", errors.NewExitCode(
+		4,
+		"PowerShell required by script `%s` was not found on PATH: install pwsh or powershell.exe",
+		context.ScriptContext.ScriptName,
+	)
+}
@@ -0,0 +1,144 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_powerShell(t *testing.T) {
+	if _, err := exec.LookPath("pwsh"); err != nil {
+		if _, err := exec.LookPath("powershell.exe"); err != nil {
+			t.Skip("PowerShell is not available")
+		}
+	}
+
+	tt := []struct {
+		name   string
+		script string
+		stdout string
+		err    string
+	}{
+		{
+			name:   "write output",
+			script: "Write-Output \"hello from $env:project\"",
+			stdout: "hello from " + mustAbs(t, ".") + "\n",
+		},
+		{
+			name:   "non-zero exit",
+			script: "exit 3",
+			err:    "exit code 4 - Failed executing script `test`: powershell script `exit 3`\nExit code: 3",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor, PowerShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: mustAbs(t, "."),
+				UI:          ui.Create(stdout, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {Actions: []config.ShuttleAction{{PowerShell: tc.script}}},
+				},
+			}, "test", nil, true)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.stdout, stdout.String())
+		})
+	}
+}
+
+func TestExecute_powerShellCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake PowerShell is a shell script")
+	}
+	// the fake PowerShell prints its arguments and working directory
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "pwsh"), []byte("#!/bin/sh\nfor arg in \"$@\"; do echo \"$arg\"; done\necho \"pwd=$(pwd)\"\n"), 0o755)
+	require.NoError(t, err)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	// pwd prints the resolved directory, eg. /private/var on macOS
+	projectPath, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+
+	tt := []struct {
+		name   string
+		action config.ShuttleAction
+		stdout string
+		err    string
+	}{
+		{
+			name:   "command",
+			action: config.ShuttleAction{PowerShell: "Write-Output hi"},
+			stdout: "-NoProfile\n-NonInteractive\n-Command\nWrite-Output hi\npwd=" + projectPath + "\n",
+		},
+		{
+			name:   "merged stderr",
+			action: config.ShuttleAction{PowerShell: "Write-Output hi", MergeStderr: true},
+			stdout: "-NoProfile\n-NonInteractive\n-Command\n& {\nWrite-Output hi\n} 2>&1\npwd=" + projectPath + "\n",
+		},
+		{
+			name:   "missing interpreter",
+			action: config.ShuttleAction{PowerShell: "Write-Output hi", Interpreter: "no-such-powershell"},
+			err:    "exit code 4 - Interpreter 'no-such-powershell' of script `test` was not found on PATH",
+		},
+		{
+			name:   "sudo",
+			action: config.ShuttleAction{PowerShell: "Write-Output hi", Sudo: true},
+			err:    "exit code 1 - Action 0 of script `test` cannot use sudo as it is a powershell action",
+		},
+		{
+			name:   "background",
+			action: config.ShuttleAction{PowerShell: "Write-Output hi", Background: true},
+			err:    "exit code 1 - Action 0 of script `test` cannot use background as it is a powershell action",
+		},
+		{
+			name:   "shell and powershell",
+			action: config.ShuttleAction{PowerShell: "Write-Output hi", Shell: "echo hi"},
+			err:    "exit code 1 - Action 0 of script `test` cannot have both shell and powershell",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor, PowerShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: projectPath,
+				UI:          ui.Create(stdout, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {Actions: []config.ShuttleAction{tc.action}},
+				},
+			}, "test", nil, true)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.stdout, stdout.String())
+		})
+	}
+}
+
+func mustAbs(t *testing.T, path string) string {
+	t.Helper()
+	abs, err := filepath.Abs(path)
+	require.NoError(t, err)
+	return abs
+}
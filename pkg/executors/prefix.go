@@ -0,0 +1,29 @@
+package executors
+
+import (
+	"fmt"
+
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// lineDecorator returns the decorator of output lines of the action. The
+// decorator selected with --output takes precedence. Otherwise lines are
+// prefixed with the label of the action if enabled, eg. "[build/0] ".
+func lineDecorator(context ActionExecutionContext) ui.LineDecorator {
+	if decorator := context.ScriptContext.Project.UI.LineDecorator(); decorator != nil {
+		return decorator
+	}
+	if context.ScriptContext.PrefixOutput {
+		return ui.NewPrefixedLines(false)
+	}
+	return ui.PlainLines{}
+}
+
+// actionLabel returns the label of the action or its script and index, eg.
+// build/0, if it has none.
+func actionLabel(context ActionExecutionContext) string {
+	if context.Action.Label != "" {
+		return context.Action.Label
+	}
+	return fmt.Sprintf("%s/%d", context.ScriptContext.ScriptName, context.ActionIndex)
+}
@@ -0,0 +1,142 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_outputPrefix(t *testing.T) {
+	tt := []struct {
+		name    string
+		prefix  bool
+		actions []config.ShuttleAction
+		args    map[string]string
+		stdout  string
+		stderr  string
+	}{
+		{
+			name:    "disabled",
+			prefix:  false,
+			actions: []config.ShuttleAction{{Shell: "echo out; echo err >&2"}},
+			stdout:  "out\n",
+			stderr:  "err\n",
+		},
+		{
+			name:   "script and action",
+			prefix: true,
+			actions: []config.ShuttleAction{
+				{Shell: "echo first; echo err >&2"},
+				{Shell: "echo second"},
+			},
+			stdout: "[test/0] first\n[test/1] second\n",
+			stderr: "[test/0] err\n",
+		},
+		{
+			name:    "label",
+			prefix:  true,
+			actions: []config.ShuttleAction{{Shell: "echo out", Label: "compile"}},
+			stdout:  "[compile] out\n",
+		},
+		{
+			name:    "buffered",
+			prefix:  true,
+			actions: []config.ShuttleAction{{Shell: "echo out", Output: OutputBuffered}},
+			stdout:  "[test/0] out\n",
+		},
+		{
+			name:    "masked secret",
+			prefix:  true,
+			actions: []config.ShuttleAction{{Shell: `echo "token $token"`}},
+			args:    map[string]string{"token": "s3cr3t-value"},
+			stdout:  "[test/0] token ***\n",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: t.TempDir(),
+				UI:          ui.Create(stdout, stderr),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Args:    []config.ShuttleScriptArgs{{Name: "token", Secret: true}},
+						Actions: tc.actions,
+					},
+				},
+			}, "test", tc.args, false, WithOutputPrefix(tc.prefix))
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.stdout, stdout.String())
+			assert.Equal(t, tc.stderr, stderr.String())
+		})
+	}
+}
+
+func TestExecute_outputPrefixJSON(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: t.TempDir(),
+		UI:          ui.Create(stdout, &bytes.Buffer{}).SetFormat(ui.FormatJSON),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Actions: []config.ShuttleAction{{Shell: "echo out"}},
+			},
+		},
+	}, "test", nil, true, WithOutputPrefix(true))
+
+	assert.NoError(t, err)
+	assert.Contains(t, stdout.String(), `"message":"out"`)
+	assert.NotContains(t, stdout.String(), "[test/0]")
+}
+
+func TestExecute_lineDecorator(t *testing.T) {
+	tt := []struct {
+		name      string
+		decorator ui.LineDecorator
+		prefix    bool
+		stdout    string
+	}{
+		{
+			name:      "prefixed",
+			decorator: ui.NewPrefixedLines(false),
+			prefix:    false,
+			stdout:    "[test/0] out\n",
+		},
+		{
+			name:      "plain overrides prefix",
+			decorator: ui.PlainLines{},
+			prefix:    true,
+			stdout:    "out\n",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: t.TempDir(),
+				UI:          ui.Create(stdout, &bytes.Buffer{}).SetLineDecorator(tc.decorator),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{{Shell: "echo out"}},
+					},
+				},
+			}, "test", nil, false, WithOutputPrefix(tc.prefix))
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.stdout, stdout.String())
+		})
+	}
+}
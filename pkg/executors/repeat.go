@@ -0,0 +1,105 @@
+package executors
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// defaultRepeatInterval is the time waited between attempts of an action
+// repeated until it succeeds
+const defaultRepeatInterval = time.Second
+
+// repeatUntilSuccess runs the action with run until it succeeds or the
+// maximum number of attempts of its repeatUntilSuccess configuration is
+// exhausted. Actions without the configuration are run once. Cancellation
+// stops waiting for the next attempt immediately.
+func repeatUntilSuccess(
+	ctx context.Context,
+	ui *ui.UI,
+	actionContext ActionExecutionContext,
+	run func() error,
+) error {
+	repeat := actionContext.Action.RepeatUntilSuccess
+	if repeat == nil {
+		return run()
+	}
+	scriptName := actionContext.ScriptContext.ScriptName
+	if repeat.MaxAttempts < 1 {
+		return errors.NewExitCode(
+			1,
+			"Action %d of script `%s` has an invalid maxAttempts '%d': must be at least 1",
+			actionContext.ActionIndex,
+			scriptName,
+			repeat.MaxAttempts,
+		)
+	}
+	interval := defaultRepeatInterval
+	if repeat.Interval != "" {
+		parsed, err := time.ParseDuration(repeat.Interval)
+		if err != nil || parsed < 0 {
+			return errors.NewExitCode(
+				1,
+				"Action %d of script `%s` has an invalid interval '%s': must be a duration, eg. 2s",
+				actionContext.ActionIndex,
+				scriptName,
+				repeat.Interval,
+			)
+		}
+		interval = parsed
+	}
+	if actionContext.Action.Background {
+		return errors.NewExitCode(
+			1,
+			"Action %d of script `%s` cannot be repeated until success as it runs in the background",
+			actionContext.ActionIndex,
+			scriptName,
+		)
+	}
+
+	var err error
+	for attempt := 1; attempt <= repeat.MaxAttempts; attempt++ {
+		err = run()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return errors.NewCancellation(ctx)
+		}
+		if attempt == repeat.MaxAttempts {
+			break
+		}
+		ui.Verboseln(
+			"Attempt %d of %d of action %d of script `%s` did not succeed, trying again in %s: %v",
+			attempt,
+			repeat.MaxAttempts,
+			actionContext.ActionIndex,
+			scriptName,
+			interval,
+			err,
+		)
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.NewCancellation(ctx)
+		case <-timer.C:
+		}
+	}
+	lastErr := err.Error()
+	var exitCode *errors.ExitCode
+	if stderrors.As(err, &exitCode) {
+		lastErr = exitCode.Message
+	}
+	return errors.NewExitCode(
+		4,
+		"Action %d of script `%s` did not succeed after %d attempts. Last attempt failed with: %s",
+		actionContext.ActionIndex,
+		scriptName,
+		repeat.MaxAttempts,
+		lastErr,
+	)
+}
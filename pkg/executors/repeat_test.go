@@ -0,0 +1,118 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_repeatUntilSuccess(t *testing.T) {
+	tt := []struct {
+		name     string
+		shell    string
+		repeat   *config.ShuttleRepeat
+		attempts string
+		err      string
+	}{
+		{
+			name:     "succeeds on first attempt",
+			shell:    `echo . >> "$counter"`,
+			repeat:   &config.ShuttleRepeat{MaxAttempts: 3, Interval: "1ms"},
+			attempts: ".\n",
+		},
+		{
+			name:     "succeeds on a later attempt",
+			shell:    `echo . >> "$counter"; test "$(wc -l < "$counter")" -ge 3`,
+			repeat:   &config.ShuttleRepeat{MaxAttempts: 5, Interval: "1ms"},
+			attempts: ".\n.\n.\n",
+		},
+		{
+			name:     "fails after exhausting attempts",
+			shell:    `echo . >> "$counter"; exit 1`,
+			repeat:   &config.ShuttleRepeat{MaxAttempts: 2, Interval: "1ms"},
+			attempts: ".\n.\n",
+			err:      "exit code 4 - Action 0 of script `test` did not succeed after 2 attempts. Last attempt failed with: Failed executing script `test`: shell script `echo . >> \"$counter\"; exit 1`\nExit code: 1",
+		},
+		{
+			name:     "not repeated",
+			shell:    `echo . >> "$counter"; exit 1`,
+			attempts: ".\n",
+			err:      "exit code 4 - Failed executing script `test`: shell script `echo . >> \"$counter\"; exit 1`\nExit code: 1",
+		},
+		{
+			name:   "invalid max attempts",
+			shell:  `echo . >> "$counter"`,
+			repeat: &config.ShuttleRepeat{},
+			err:    "exit code 1 - Action 0 of script `test` has an invalid maxAttempts '0': must be at least 1",
+		},
+		{
+			name:   "invalid interval",
+			shell:  `echo . >> "$counter"`,
+			repeat: &config.ShuttleRepeat{MaxAttempts: 2, Interval: "often"},
+			err:    "exit code 1 - Action 0 of script `test` has an invalid interval 'often': must be a duration, eg. 2s",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			counter := filepath.Join(t.TempDir(), "attempts")
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Args: []config.ShuttleScriptArgs{{Name: "counter"}},
+						Actions: []config.ShuttleAction{
+							{Shell: tc.shell, RepeatUntilSuccess: tc.repeat},
+						},
+					},
+				},
+			}, "test", map[string]string{"counter": counter}, true)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			attempts, _ := os.ReadFile(counter)
+			assert.Equal(t, tc.attempts, string(attempts))
+		})
+	}
+}
+
+func TestExecute_repeatUntilSuccessCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+	registry := NewRegistry(ShellExecutor)
+
+	start := time.Now()
+	err := registry.Execute(ctx, config.ShuttleProjectContext{
+		ProjectPath: ".",
+		UI:          ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Actions: []config.ShuttleAction{
+					{
+						Shell:              "exit 1",
+						RepeatUntilSuccess: &config.ShuttleRepeat{MaxAttempts: 10, Interval: "1m"},
+					},
+				},
+			},
+		},
+	}, "test", nil, true)
+
+	assert.EqualError(t, err, "exit code 2 - Operation cancelled")
+	assert.Less(t, time.Since(start), 10*time.Second, "cancellation must not wait for the interval")
+}
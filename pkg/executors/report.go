@@ -0,0 +1,230 @@
+package executors
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/telemetry"
+)
+
+// maxTimingReports is the number of timing reports kept in the reports
+// directory. The oldest are removed first.
+const maxTimingReports = 20
+
+// TimingReport is a breakdown of where a run of a script spent its time
+type TimingReport struct {
+	// RunID identifies the shuttle invocation, see SHUTTLE_RUN_ID
+	RunID      string       `json:"runId"`
+	Script     string       `json:"script"`
+	StartedAt  time.Time    `json:"startedAt"`
+	DurationMs int64        `json:"durationMs"`
+	ExitCode   int          `json:"exitCode"`
+	Steps      []TimingStep `json:"steps"`
+}
+
+// TimingStep is a step of a run, eg. fetching the plan, a script or an
+// action, along with the steps it consists of
+type TimingStep struct {
+	// Kind is one of plan, script, action and golang-compile
+	Kind       string       `json:"kind"`
+	Name       string       `json:"name"`
+	DurationMs int64        `json:"durationMs"`
+	Error      string       `json:"error,omitempty"`
+	Steps      []TimingStep `json:"steps,omitempty"`
+}
+
+// NewTimingReport returns the timing report of a run of script that started
+// at start and failed with err if not nil. The steps are made of the spans in
+// timings ordered by when they started. Paths are shown relative to
+// projectPath.
+func NewTimingReport(script, runID, projectPath string, timings []telemetry.Timing, start time.Time, err error) TimingReport {
+	sort.SliceStable(timings, func(i, j int) bool {
+		return timings[i].Start.Before(timings[j].Start)
+	})
+	for _, timing := range timings {
+		if timing.Start.Before(start) {
+			start = timing.Start
+		}
+	}
+
+	children := make(map[string][]telemetry.Timing)
+	ids := make(map[string]bool, len(timings))
+	for _, timing := range timings {
+		ids[timing.ID] = true
+	}
+	var roots []telemetry.Timing
+	for _, timing := range timings {
+		// spans of nested invocations have parents outside of timings
+		if timing.ParentID == "" || !ids[timing.ParentID] {
+			roots = append(roots, timing)
+			continue
+		}
+		children[timing.ParentID] = append(children[timing.ParentID], timing)
+	}
+	var steps func(timings []telemetry.Timing) []TimingStep
+	steps = func(timings []telemetry.Timing) []TimingStep {
+		var result []TimingStep
+		for _, timing := range timings {
+			step := timingStep(timing, projectPath)
+			step.Steps = steps(children[timing.ID])
+			result = append(result, step)
+		}
+		return result
+	}
+
+	return TimingReport{
+		RunID:      runID,
+		Script:     script,
+		StartedAt:  start,
+		DurationMs: time.Since(start).Milliseconds(),
+		ExitCode:   actionExitCode(err),
+		Steps:      steps(roots),
+	}
+}
+
+// timingStep returns the step recorded by timing.
+func timingStep(timing telemetry.Timing, projectPath string) TimingStep {
+	step := TimingStep{
+		Kind:       timing.Name,
+		DurationMs: timing.Duration().Milliseconds(),
+	}
+	if timing.Err != nil {
+		step.Error = timing.Err.Error()
+	}
+	switch timing.Name {
+	case "shuttle.plan":
+		step.Kind = "plan"
+		step.Name = timing.Attributes[telemetry.TelemetryPlan]
+		if step.Name == "" {
+			step.Name = "no plan"
+		}
+	case "shuttle.script":
+		step.Kind = "script"
+		step.Name = timing.Attributes[telemetry.TelemetryScript]
+	case "shuttle.action":
+		step.Kind = "action"
+		step.Name = fmt.Sprintf("%s (%s)", timing.Attributes[telemetry.TelemetryActionIndex], timing.Attributes[telemetry.TelemetryActionKind])
+	case "shuttle.golang.compile":
+		step.Kind = "golang-compile"
+		step.Name = timing.Attributes[telemetry.TelemetryGolangActions]
+		if relative, err := filepath.Rel(projectPath, step.Name); err == nil && !strings.HasPrefix(relative, "..") {
+			step.Name = relative
+		}
+	}
+	return step
+}
+
+// Print writes the report as an indented breakdown of the steps of the run
+// using print, eg. ui.Infoln.
+func (r TimingReport) Print(print func(format string, args ...interface{})) {
+	type line struct {
+		label    string
+		duration string
+	}
+	var lines []line
+	var add func(steps []TimingStep, indent string)
+	add = func(steps []TimingStep, indent string) {
+		for _, step := range steps {
+			label := fmt.Sprintf("%s%s %s", indent, step.Kind, step.Name)
+			if step.Error != "" {
+				label += " (failed)"
+			}
+			lines = append(lines, line{label: label, duration: reportDuration(step.DurationMs)})
+			add(step.Steps, indent+"  ")
+		}
+	}
+	add(r.Steps, "  ")
+	width := 0
+	for _, line := range lines {
+		if len(line.label) > width {
+			width = len(line.label)
+		}
+	}
+
+	print("Timings of script `%s` (%s):", r.Script, reportDuration(r.DurationMs))
+	for _, line := range lines {
+		print("%-*s  %s", width, line.label, line.duration)
+	}
+}
+
+func reportDuration(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+// TimingReportsDirectory returns the directory timing reports of the project
+// are written to.
+func TimingReportsDirectory(localShuttleDirectory string) string {
+	return filepath.Join(localShuttleDirectory, "reports")
+}
+
+// WriteTimingReport writes report as JSON to dir. Only the latest
+// maxTimingReports reports are kept.
+func WriteTimingReport(dir string, report TimingReport) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	// reports are named by their start such that they sort by age
+	name := fmt.Sprintf("%s-%s.json", report.StartedAt.UTC().Format("20060102T150405.000Z"), report.RunID)
+	if err := os.WriteFile(filepath.Join(dir, name), append(content, '\n'), 0o644); err != nil {
+		return err
+	}
+
+	names, err := timingReportNames(dir)
+	if err != nil {
+		return err
+	}
+	for len(names) > maxTimingReports {
+		if err := os.Remove(filepath.Join(dir, names[0])); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+// LastTimingReport returns the latest timing report written to dir.
+func LastTimingReport(dir string) (TimingReport, error) {
+	names, err := timingReportNames(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return TimingReport{}, err
+	}
+	if len(names) == 0 {
+		return TimingReport{}, errors.NewExitCode(1, "No timing reports found: run a script with --report timings to write one")
+	}
+	content, err := os.ReadFile(filepath.Join(dir, names[len(names)-1]))
+	if err != nil {
+		return TimingReport{}, err
+	}
+	var report TimingReport
+	if err := json.Unmarshal(content, &report); err != nil {
+		return TimingReport{}, errors.NewExitCode(1, "Timing report '%s' is invalid: %v", names[len(names)-1], err)
+	}
+	return report, nil
+}
+
+// timingReportNames returns the names of the reports in dir from oldest to
+// latest.
+func timingReportNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
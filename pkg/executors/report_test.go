@@ -0,0 +1,97 @@
+package executors
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTimingReport(t *testing.T) {
+	start := time.Date(2023, 7, 17, 15, 21, 27, 0, time.UTC)
+	at := func(ms int) time.Time {
+		return start.Add(time.Duration(ms) * time.Millisecond)
+	}
+	timings := []telemetry.Timing{
+		{ID: "action-0", ParentID: "script", Name: "shuttle.action", Start: at(120), End: at(1120), Attributes: map[string]string{telemetry.TelemetryActionIndex: "0", telemetry.TelemetryActionKind: "shell"}},
+		{ID: "action-1", ParentID: "script", Name: "shuttle.action", Start: at(1120), End: at(1620), Attributes: map[string]string{telemetry.TelemetryActionIndex: "1", telemetry.TelemetryActionKind: "task"}, Err: assert.AnError},
+		{ID: "script", Name: "shuttle.script", Start: at(110), End: at(1630), Attributes: map[string]string{telemetry.TelemetryScript: "build"}, Err: assert.AnError},
+		{ID: "compile", ParentID: "plan", Name: "shuttle.golang.compile", Start: at(10), End: at(90), Attributes: map[string]string{telemetry.TelemetryGolangActions: "/project/actions"}},
+		{ID: "plan", Name: "shuttle.plan", Start: at(0), End: at(100), Attributes: map[string]string{telemetry.TelemetryPlan: "git://github.com/lunarway/shuttle-example-go-plan.git"}},
+	}
+
+	report := NewTimingReport("build", "run-id", "/project", timings, at(105), assert.AnError)
+
+	assert.Equal(t, "run-id", report.RunID)
+	assert.Equal(t, start, report.StartedAt, "start of the earliest span")
+	assert.Equal(t, 1, report.ExitCode)
+	assert.Equal(t, []TimingStep{
+		{
+			Kind:       "plan",
+			Name:       "git://github.com/lunarway/shuttle-example-go-plan.git",
+			DurationMs: 100,
+			Steps: []TimingStep{
+				{Kind: "golang-compile", Name: "actions", DurationMs: 80},
+			},
+		},
+		{
+			Kind:       "script",
+			Name:       "build",
+			DurationMs: 1520,
+			Error:      assert.AnError.Error(),
+			Steps: []TimingStep{
+				{Kind: "action", Name: "0 (shell)", DurationMs: 1000},
+				{Kind: "action", Name: "1 (task)", DurationMs: 500, Error: assert.AnError.Error()},
+			},
+		},
+	}, report.Steps)
+}
+
+func TestTimingReport_Print(t *testing.T) {
+	report := TimingReport{
+		Script:     "build",
+		DurationMs: 1620,
+		Steps: []TimingStep{
+			{Kind: "plan", Name: "no plan", DurationMs: 0},
+			{Kind: "script", Name: "build", DurationMs: 1520, Error: "failed", Steps: []TimingStep{
+				{Kind: "action", Name: "0 (shell)", DurationMs: 1000},
+			}},
+		},
+	}
+	var lines []string
+	report.Print(func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	})
+
+	assert.Equal(t, []string{
+		"Timings of script `build` (1.62s):",
+		"  plan no plan           0s",
+		"  script build (failed)  1.52s",
+		"    action 0 (shell)     1s",
+	}, lines)
+}
+
+func TestWriteTimingReport(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "reports")
+	start := time.Date(2023, 7, 17, 15, 21, 27, 0, time.UTC)
+
+	_, err := LastTimingReport(dir)
+	assert.EqualError(t, err, "exit code 1 - No timing reports found: run a script with --report timings to write one")
+
+	for i := 0; i < maxTimingReports+2; i++ {
+		report := TimingReport{RunID: fmt.Sprintf("run-%d", i), Script: "build", StartedAt: start.Add(time.Duration(i) * time.Minute)}
+		require.NoError(t, WriteTimingReport(dir, report))
+	}
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, maxTimingReports, "oldest reports are removed")
+	report, err := LastTimingReport(dir)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("run-%d", maxTimingReports+1), report.RunID)
+}
@@ -0,0 +1,119 @@
+package executors
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// CheckRequirements verifies the requirements of the plan of p, including its
+// shuttleVersion, before any of its scripts is run. Every unmet requirement is
+// reported in a single error with how to fix it. shuttleVersion is the version of the running shuttle.
+// The shuttle constraint is not checked if it is empty or a development
+// version.
+func CheckRequirements(ctx context.Context, p config.ShuttleProjectContext, shuttleVersion string) error {
+	var unmet []Diagnosis
+	for _, diagnosis := range diagnoseRequirements(ctx, p.Plan.Requirements(), shuttleVersion) {
+		if diagnosis.Status == DiagnosisOK {
+			p.UI.Verboseln("Plan requirement %s is met: %s", diagnosis.Check, diagnosis.Detail)
+			continue
+		}
+		unmet = append(unmet, diagnosis)
+	}
+	if len(unmet) == 0 {
+		return nil
+	}
+	var message strings.Builder
+	message.WriteString("Requirements of the plan are not met:")
+	for _, diagnosis := range unmet {
+		fmt.Fprintf(&message, "\n- %s: %s", diagnosis.Check, diagnosis.Detail)
+		if diagnosis.Remediation != "" {
+			fmt.Fprintf(&message, "\n  → %s", diagnosis.Remediation)
+		}
+	}
+	return errors.NewExitCode(4, "%s", message.String())
+}
+
+// diagnoseRequirements checks the shuttle version, executors and tools of
+// requires. A diagnosis is returned per requirement.
+func diagnoseRequirements(ctx context.Context, requires config.ShuttlePlanRequirements, shuttleVersion string) []Diagnosis {
+	var diagnoses []Diagnosis
+	if requires.Shuttle != "" {
+		if diagnosis, ok := diagnoseShuttleVersion(requires.Shuttle, shuttleVersion); ok {
+			diagnoses = append(diagnoses, diagnosis)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, executor := range requires.Executors {
+		if seen[executor] {
+			continue
+		}
+		seen[executor] = true
+		diagnoses = append(diagnoses, diagnoseExecutor(ctx, executor))
+	}
+	return append(diagnoses, diagnoseTools(ctx, requires.Tools)...)
+}
+
+// diagnoseShuttleVersion checks shuttleVersion against constraint. It returns
+// false for development versions which satisfy any constraint.
+func diagnoseShuttleVersion(constraint, shuttleVersion string) (Diagnosis, bool) {
+	check := "shuttle " + constraint
+	parsed, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return Diagnosis{
+			Check:       check,
+			Status:      DiagnosisFail,
+			Detail:      fmt.Sprintf("invalid version constraint: %v", err),
+			Remediation: "Fix requires.shuttle or shuttleVersion of the plan",
+		}, true
+	}
+	version, err := semver.NewVersion(shuttleVersion)
+	if err != nil {
+		return Diagnosis{}, false
+	}
+	if !parsed.Check(version) {
+		return Diagnosis{
+			Check:       check,
+			Status:      DiagnosisFail,
+			Detail:      fmt.Sprintf("this is shuttle %s", shuttleVersion),
+			Remediation: "Update shuttle with 'shuttle self-update'",
+		}, true
+	}
+	return Diagnosis{Check: check, Status: DiagnosisOK, Detail: shuttleVersion}, true
+}
+
+// diagnoseExecutor checks that actions of executor can be run. Golang actions
+// are built with go or in a container if it is not available.
+func diagnoseExecutor(ctx context.Context, executor string) Diagnosis {
+	check := executor + " executor"
+	var diagnosis Diagnosis
+	switch executor {
+	case config.ExecutorGolang:
+		diagnosis = diagnoseCommand(ctx, "go", builtinVersionCommands["go"], DiagnosisFail,
+			"Install go from https://go.dev/dl, or docker to build golang actions in a container")
+		if diagnosis.Status != DiagnosisOK {
+			docker := diagnoseCommand(ctx, "docker", builtinVersionCommands["docker"], DiagnosisFail, "")
+			if docker.Status == DiagnosisOK {
+				diagnosis = Diagnosis{Status: DiagnosisOK, Detail: fmt.Sprintf("building in docker %s", docker.Detail)}
+			} else {
+				diagnosis.Detail = fmt.Sprintf("go %s and docker %s", diagnosis.Detail, docker.Detail)
+			}
+		}
+	case config.ExecutorDocker:
+		diagnosis = diagnoseCommand(ctx, "docker", builtinVersionCommands["docker"], DiagnosisFail,
+			"Install docker from https://docs.docker.com/get-docker and make sure it is running")
+	default:
+		diagnosis = Diagnosis{
+			Status:      DiagnosisFail,
+			Detail:      fmt.Sprintf("unknown executor: must be %s or %s", config.ExecutorGolang, config.ExecutorDocker),
+			Remediation: "Fix requires.executors of the plan",
+		}
+	}
+	diagnosis.Check = check
+	return diagnosis
+}
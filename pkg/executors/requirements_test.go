@@ -0,0 +1,115 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestCheckRequirements(t *testing.T) {
+	fakeTool(t, "kubectl", "Client Version: v1.29.1")
+	fakeTool(t, "helm", "v3.12.0+gc9f554d")
+	// go and docker are found but fail like a docker daemon that is not
+	// running
+	unavailable := t.TempDir()
+	for _, name := range []string{"go", "docker"} {
+		err := os.WriteFile(filepath.Join(unavailable, name), []byte("#!/bin/sh\necho unavailable >&2\nexit 1\n"), 0o755)
+		require.NoError(t, err)
+	}
+	t.Setenv("PATH", unavailable+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tt := []struct {
+		name           string
+		requires       config.ShuttlePlanRequirements
+		shuttleVersion string
+		version        string
+		err            string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name: "met",
+			requires: config.ShuttlePlanRequirements{
+				Shuttle: ">= 0.25",
+				Tools:   []config.ShuttleToolRequirement{{Name: "kubectl", Version: ">= 1.28"}},
+			},
+			version: "0.25.1",
+		},
+		{
+			name:     "development version",
+			requires: config.ShuttlePlanRequirements{Shuttle: ">= 0.25"},
+			version:  "<dev-version>",
+		},
+		{
+			name:           "shuttleVersion",
+			requires:       config.ShuttlePlanRequirements{Shuttle: "< 1"},
+			shuttleVersion: ">= 0.25",
+			version:        "0.24.0",
+			err: `exit code 4 - Requirements of the plan are not met:
+- shuttle >= 0.25, < 1: this is shuttle 0.24.0
+  → Update shuttle with 'shuttle self-update'`,
+		},
+		{
+			name: "unmet",
+			requires: config.ShuttlePlanRequirements{
+				Shuttle:   ">= 0.25",
+				Executors: []string{config.ExecutorDocker, config.ExecutorGolang, config.ExecutorDocker},
+				Tools: []config.ShuttleToolRequirement{
+					{Name: "kubectl", Version: ">= 1.28"},
+					{Name: "helm", Version: ">= 3.13"},
+					{Name: "shuttle-missing-tool"},
+				},
+			},
+			version: "0.24.0",
+			err: `exit code 4 - Requirements of the plan are not met:
+- shuttle >= 0.25: this is shuttle 0.24.0
+  → Update shuttle with 'shuttle self-update'
+- docker executor: ` + "`docker version --format '{{.Client.Version}}'`" + ` failed: exit status 1: unavailable
+  → Install docker from https://docs.docker.com/get-docker and make sure it is running
+- golang executor: go ` + "`go version`" + ` failed: exit status 1: unavailable and docker ` + "`docker version --format '{{.Client.Version}}'`" + ` failed: exit status 1: unavailable
+  → Install go from https://go.dev/dl, or docker to build golang actions in a container
+- helm >= 3.13: found version 3.12.0
+  → Install helm >= 3.13 and add it to PATH
+- shuttle-missing-tool: not found on PATH
+  → Install shuttle-missing-tool and add it to PATH`,
+		},
+		{
+			name: "invalid",
+			requires: config.ShuttlePlanRequirements{
+				Shuttle:   "latest",
+				Executors: []string{"kubernetes"},
+			},
+			version: "0.25.0",
+			err: `exit code 4 - Requirements of the plan are not met:
+- shuttle latest: invalid version constraint: improper constraint: latest
+  → Fix requires.shuttle or shuttleVersion of the plan
+- kubernetes executor: unknown executor: must be golang or docker
+  → Fix requires.executors of the plan`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			project := config.ShuttleProjectContext{
+				UI:   ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+				Plan: config.ShuttlePlanConfiguration{Requires: tc.requires, ShuttleVersion: tc.shuttleVersion},
+			}
+
+			err := CheckRequirements(context.Background(), project, tc.version)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package executors
+
+import (
+	"context"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// Backoffs of the delay between retries of shell actions
+const (
+	// RetryBackoffConstant waits retryDelay before every retry
+	RetryBackoffConstant = "constant"
+	// RetryBackoffExponential doubles the delay after every retry
+	RetryBackoffExponential = "exponential"
+)
+
+// defaultRetryDelay is the time waited before retrying a failed shell action
+const defaultRetryDelay = time.Second
+
+// shellRetry is the parsed retry configuration of a shell action
+type shellRetry struct {
+	retries     int
+	delay       time.Duration
+	exponential bool
+	// retryOn are the exit codes retried. All non-zero codes are retried when
+	// empty.
+	retryOn []int
+}
+
+// retriesExitCode returns whether an attempt exiting with exitCode is retried.
+func (r shellRetry) retriesExitCode(exitCode int) bool {
+	if len(r.retryOn) == 0 {
+		return true
+	}
+	for _, code := range r.retryOn {
+		if code == exitCode {
+			return true
+		}
+	}
+	return false
+}
+
+// delayBefore returns the time to wait before retry, counted from 1.
+func (r shellRetry) delayBefore(retry int) time.Duration {
+	if !r.exponential {
+		return r.delay
+	}
+	return r.delay << (retry - 1)
+}
+
+// parseShellRetry returns the retry configuration of the action.
+func parseShellRetry(context ActionExecutionContext) (shellRetry, error) {
+	action := context.Action
+	if action.Retries < 0 {
+		return shellRetry{}, errors.NewExitCode(
+			1,
+			"Action %d of script `%s` has an invalid retries '%d': must not be negative",
+			context.ActionIndex,
+			context.ScriptContext.ScriptName,
+			action.Retries,
+		)
+	}
+	retry := shellRetry{retries: action.Retries, delay: defaultRetryDelay}
+	if action.RetryDelay != "" {
+		delay, err := time.ParseDuration(action.RetryDelay)
+		if err != nil || delay < 0 {
+			return shellRetry{}, errors.NewExitCode(
+				1,
+				"Action %d of script `%s` has an invalid retryDelay '%s': must be a duration, eg. 2s",
+				context.ActionIndex,
+				context.ScriptContext.ScriptName,
+				action.RetryDelay,
+			)
+		}
+		retry.delay = delay
+	}
+	switch action.RetryBackoff {
+	case "", RetryBackoffConstant:
+	case RetryBackoffExponential:
+		retry.exponential = true
+	default:
+		return shellRetry{}, errors.NewExitCode(
+			1,
+			"Action %d of script `%s` has an invalid retryBackoff '%s': must be one of '%s' or '%s'",
+			context.ActionIndex,
+			context.ScriptContext.ScriptName,
+			action.RetryBackoff,
+			RetryBackoffConstant,
+			RetryBackoffExponential,
+		)
+	}
+	for _, code := range action.RetryOn {
+		if code <= 0 {
+			return shellRetry{}, errors.NewExitCode(
+				1,
+				"Action %d of script `%s` has an invalid retryOn exit code '%d': must be positive",
+				context.ActionIndex,
+				context.ScriptContext.ScriptName,
+				code,
+			)
+		}
+	}
+	retry.retryOn = action.RetryOn
+	return retry, nil
+}
+
+// retryShellCommand runs the script of the action with runShellCommand and
+// runs it again up to the configured number of retries while it exits with a
+// non-zero code listed in retryOn, if any. Errors other than exit codes, eg.
+// cancellation, are never retried and cancellation stops waiting for the next
+// retry immediately. The exit code of the last attempt is returned with the
+// number of attempts made.
+func retryShellCommand(ctx context.Context, context ActionExecutionContext, script string, capture *[]string) (int, int, error) {
+	retry, err := parseShellRetry(context)
+	if err != nil {
+		return 0, 0, err
+	}
+	for attempt := 0; ; attempt++ {
+		if capture != nil {
+			// only the output of the last attempt is captured
+			*capture = (*capture)[:0]
+		}
+		exitCode, err := runShellCommand(ctx, context, script, context.Action.Sudo, capture)
+		if err != nil || exitCode == 0 || attempt == retry.retries || !retry.retriesExitCode(exitCode) {
+			return exitCode, attempt + 1, err
+		}
+		delay := retry.delayBefore(attempt + 1)
+		context.ScriptContext.Project.UI.Infoln(
+			"Action %d of script `%s` exited with code %d, retry %d of %d in %s",
+			context.ActionIndex,
+			context.ScriptContext.ScriptName,
+			exitCode,
+			attempt+1,
+			retry.retries,
+			delay,
+		)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, attempt + 1, errors.NewCancellation(ctx)
+		case <-timer.C:
+		}
+	}
+}
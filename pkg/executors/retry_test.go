@@ -0,0 +1,150 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// failTwice fails the first two attempts and succeeds on the third
+const failTwice = `echo . >> "$counter"; test "$(wc -l < "$counter")" -ge 3`
+
+func TestExecute_retries(t *testing.T) {
+	tt := []struct {
+		name     string
+		action   config.ShuttleAction
+		attempts string
+		stderr   string
+		err      string
+	}{
+		{
+			name:     "succeeds after retries",
+			action:   config.ShuttleAction{Shell: failTwice, Retries: 2, RetryDelay: "1ms"},
+			attempts: ".\n.\n.\n",
+			stderr:   "Action 0 of script `test` exited with code 1, retry 1 of 2 in 1ms\nAction 0 of script `test` exited with code 1, retry 2 of 2 in 1ms\n",
+		},
+		{
+			name:     "exponential backoff",
+			action:   config.ShuttleAction{Shell: failTwice, Retries: 3, RetryDelay: "1ms", RetryBackoff: RetryBackoffExponential},
+			attempts: ".\n.\n.\n",
+			stderr:   "Action 0 of script `test` exited with code 1, retry 1 of 3 in 1ms\nAction 0 of script `test` exited with code 1, retry 2 of 3 in 2ms\n",
+		},
+		{
+			name:     "fails after exhausting retries",
+			action:   config.ShuttleAction{Shell: failTwice, Retries: 1, RetryDelay: "1ms"},
+			attempts: ".\n.\n",
+			stderr:   "Action 0 of script `test` exited with code 1, retry 1 of 1 in 1ms\n",
+			err:      "exit code 4 - Failed executing script `test`: shell script `" + failTwice + "`\nExit code: 1\nAttempts: 2",
+		},
+		{
+			name:     "retried on listed exit code",
+			action:   config.ShuttleAction{Shell: failTwice, Retries: 2, RetryDelay: "1ms", RetryOn: []int{1}},
+			attempts: ".\n.\n.\n",
+			stderr:   "Action 0 of script `test` exited with code 1, retry 1 of 2 in 1ms\nAction 0 of script `test` exited with code 1, retry 2 of 2 in 1ms\n",
+		},
+		{
+			name:     "not retried on other exit code",
+			action:   config.ShuttleAction{Shell: failTwice, Retries: 2, RetryDelay: "1ms", RetryOn: []int{75}},
+			attempts: ".\n",
+			err:      "exit code 4 - Failed executing script `test`: shell script `" + failTwice + "`\nExit code: 1",
+		},
+		{
+			name:     "not retried on success",
+			action:   config.ShuttleAction{Shell: `echo . >> "$counter"`, Retries: 2, RetryDelay: "1ms"},
+			attempts: ".\n",
+		},
+		{
+			name:     "not retried",
+			action:   config.ShuttleAction{Shell: failTwice},
+			attempts: ".\n",
+			err:      "exit code 4 - Failed executing script `test`: shell script `" + failTwice + "`\nExit code: 1",
+		},
+		{
+			name:   "negative retries",
+			action: config.ShuttleAction{Shell: failTwice, Retries: -1},
+			err:    "exit code 1 - Action 0 of script `test` has an invalid retries '-1': must not be negative",
+		},
+		{
+			name:   "invalid retry delay",
+			action: config.ShuttleAction{Shell: failTwice, Retries: 1, RetryDelay: "often"},
+			err:    "exit code 1 - Action 0 of script `test` has an invalid retryDelay 'often': must be a duration, eg. 2s",
+		},
+		{
+			name:   "invalid retry backoff",
+			action: config.ShuttleAction{Shell: failTwice, Retries: 1, RetryBackoff: "linear"},
+			err:    "exit code 1 - Action 0 of script `test` has an invalid retryBackoff 'linear': must be one of 'constant' or 'exponential'",
+		},
+		{
+			name:   "invalid retry on",
+			action: config.ShuttleAction{Shell: failTwice, Retries: 1, RetryOn: []int{0}},
+			err:    "exit code 1 - Action 0 of script `test` has an invalid retryOn exit code '0': must be positive",
+		},
+		{
+			name:   "background",
+			action: config.ShuttleAction{Shell: failTwice, Retries: 1, Background: true},
+			err:    "exit code 1 - Action 0 of script `test` cannot be retried as it runs in the background",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			counter := filepath.Join(t.TempDir(), "attempts")
+			stderr := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(&bytes.Buffer{}, stderr),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Args:    []config.ShuttleScriptArgs{{Name: "counter"}},
+						Actions: []config.ShuttleAction{tc.action},
+					},
+				},
+			}, "test", map[string]string{"counter": counter}, true)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			attempts, _ := os.ReadFile(counter)
+			assert.Equal(t, tc.attempts, string(attempts))
+			assert.Equal(t, tc.stderr, stderr.String())
+		})
+	}
+}
+
+func TestExecute_retriesCancelled(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "attempts")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	registry := NewRegistry(ShellExecutor)
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+
+	err := registry.Execute(ctx, config.ShuttleProjectContext{
+		ProjectPath: ".",
+		UI:          ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Args: []config.ShuttleScriptArgs{{Name: "counter"}},
+				Actions: []config.ShuttleAction{
+					{Shell: `echo . >> "$counter"; exit 1`, Retries: 3, RetryDelay: "1m"},
+				},
+			},
+		},
+	}, "test", map[string]string{"counter": counter}, true)
+
+	assert.EqualError(t, err, "exit code 2 - Operation cancelled")
+	assert.Less(t, time.Since(start), 30*time.Second, "must not wait for the next retry")
+	attempts, _ := os.ReadFile(counter)
+	assert.Equal(t, ".\n", string(attempts))
+}
@@ -0,0 +1,194 @@
+package executors
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// defaultRunLogsKept is the number of run logs kept unless configured with
+// runLogs.keep.
+const defaultRunLogsKept = 50
+
+// runLogTimeFormat is the format of the start of runs in the names of their
+// logs such that they sort by age.
+const runLogTimeFormat = "20060102T150405.000Z"
+
+// runLogLineTimeFormat is the format of the time lines of run logs are
+// prefixed with.
+const runLogLineTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// RunLogsDirectory returns the directory run logs of the project are written
+// to.
+func RunLogsDirectory(localShuttleDirectory string) string {
+	return filepath.Join(localShuttleDirectory, "logs")
+}
+
+// RunLog is the log of a run of a script. It holds the timestamped output of
+// all shell actions of the run along with their commands and the names of the
+// environment variables set by shuttle.
+type RunLog struct {
+	// Path is the path of the log file.
+	Path string
+
+	lock sync.Mutex
+	file *os.File
+	err  error
+	now  func() time.Time
+}
+
+// WithRunLog writes the commands and output of shell actions to log.
+func WithRunLog(log *RunLog) ExecuteOption {
+	return func(c *ScriptExecutionContext) {
+		c.RunLog = log
+	}
+}
+
+// CreateRunLog creates the log of a run of script started at start in dir,
+// named <start>-<script>.log. Logs beyond the retention of settings are
+// removed first.
+func CreateRunLog(dir, script string, start time.Time, settings config.ShuttleRunLogs) (*RunLog, error) {
+	keep := settings.Keep
+	if keep == 0 {
+		keep = defaultRunLogsKept
+	}
+	if keep < 0 {
+		return nil, errors.NewExitCode(1, "runLogs.keep must be positive but was %d", keep)
+	}
+	var maxAge time.Duration
+	if settings.MaxAge != "" {
+		var err error
+		maxAge, err = time.ParseDuration(settings.MaxAge)
+		if err != nil || maxAge <= 0 {
+			return nil, errors.NewExitCode(1, "runLogs.maxAge '%s' is invalid: must be a positive duration, eg. 168h", settings.MaxAge)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	// make room for the new log
+	if err := pruneRunLogs(dir, keep-1, maxAge, start); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("%s-%s.log", start.UTC().Format(runLogTimeFormat), strings.ReplaceAll(script, string(filepath.Separator), "_"))
+	path := filepath.Join(dir, name)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &RunLog{Path: path, file: file, now: time.Now}, nil
+}
+
+// Printf writes a line prefixed with the current time. Lines are written
+// immediately such that the log is complete up to the last line if shuttle is
+// killed. A nil RunLog discards all lines.
+func (l *RunLog) Printf(format string, args ...interface{}) {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.err != nil {
+		return
+	}
+	_, l.err = fmt.Fprintf(l.file, "%s %s\n", l.now().UTC().Format(runLogLineTimeFormat), fmt.Sprintf(format, args...))
+}
+
+// Close closes the log file and returns the first error writing to it.
+func (l *RunLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	err := l.file.Close()
+	if l.err != nil {
+		return l.err
+	}
+	return err
+}
+
+// logCommand writes the command of the action and the names of the
+// environment variables shuttle sets for it. Values are left out as they may
+// be secrets.
+func (l *RunLog) logCommand(context ActionExecutionContext, cmdArgs []string, env []string) {
+	if l == nil {
+		return
+	}
+	masker := newSecretMasker(context.ScriptContext)
+	allow := context.ScriptContext.SecretDetection.Allow
+	label := actionLabel(context)
+	l.Printf("[%s] command: %s", label, redactSecrets(allow, masker.Mask(strings.Join(cmdArgs, " "))))
+	var names []string
+	for _, entry := range shuttleVariables(env) {
+		name, _, _ := strings.Cut(entry, "=")
+		names = append(names, name)
+	}
+	l.Printf("[%s] env: %s", label, strings.Join(names, " "))
+}
+
+// RunLogFile is a log of a run in the logs directory.
+type RunLogFile struct {
+	Path      string
+	Script    string
+	StartedAt time.Time
+}
+
+// ListRunLogs returns the run logs in dir from oldest to latest. Files not
+// named like run logs are skipped.
+func ListRunLogs(dir string) ([]RunLogFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var logs []RunLogFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".log") {
+			continue
+		}
+		timestamp, script, ok := strings.Cut(strings.TrimSuffix(name, ".log"), "-")
+		if !ok {
+			continue
+		}
+		startedAt, err := time.Parse(runLogTimeFormat, timestamp)
+		if err != nil {
+			continue
+		}
+		logs = append(logs, RunLogFile{Path: filepath.Join(dir, name), Script: script, StartedAt: startedAt})
+	}
+	sort.Slice(logs, func(i, j int) bool {
+		return logs[i].StartedAt.Before(logs[j].StartedAt)
+	})
+	return logs, nil
+}
+
+// pruneRunLogs removes the oldest logs of dir such that at most keep are left
+// and those started more than maxAge before now. Logs are kept regardless of
+// their age if maxAge is zero.
+func pruneRunLogs(dir string, keep int, maxAge time.Duration, now time.Time) error {
+	logs, err := ListRunLogs(dir)
+	if err != nil {
+		return err
+	}
+	for i, log := range logs {
+		tooMany := len(logs)-i > keep
+		tooOld := maxAge > 0 && now.Sub(log.StartedAt) > maxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(log.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
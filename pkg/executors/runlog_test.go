@@ -0,0 +1,103 @@
+package executors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+)
+
+func TestCreateRunLog(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	writeLog := func(t *testing.T, dir string, startedAt time.Time, script string) string {
+		t.Helper()
+		path := filepath.Join(dir, startedAt.Format(runLogTimeFormat)+"-"+script+".log")
+		require.NoError(t, os.WriteFile(path, nil, 0o644))
+		return path
+	}
+	names := func(t *testing.T, dir string) []string {
+		t.Helper()
+		logs, err := ListRunLogs(dir)
+		require.NoError(t, err)
+		var names []string
+		for _, log := range logs {
+			names = append(names, filepath.Base(log.Path))
+		}
+		return names
+	}
+
+	t.Run("writes timestamped lines", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "logs")
+
+		log, err := CreateRunLog(dir, "build", start, config.ShuttleRunLogs{})
+		require.NoError(t, err)
+		log.now = func() time.Time { return start.Add(1500 * time.Millisecond) }
+		log.Printf("[build/0] stdout: %s", "hello")
+		require.NoError(t, log.Close())
+
+		assert.Equal(t, filepath.Join(dir, "20240301T123000.000Z-build.log"), log.Path)
+		content, err := os.ReadFile(log.Path)
+		require.NoError(t, err)
+		assert.Equal(t, "2024-03-01T12:30:01.500Z [build/0] stdout: hello\n", string(content))
+	})
+
+	t.Run("keeps the latest logs", func(t *testing.T) {
+		dir := t.TempDir()
+		writeLog(t, dir, start.Add(-3*time.Minute), "build")
+		writeLog(t, dir, start.Add(-2*time.Minute), "test")
+		writeLog(t, dir, start.Add(-time.Minute), "build")
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644))
+
+		log, err := CreateRunLog(dir, "deploy", start, config.ShuttleRunLogs{Keep: 2})
+		require.NoError(t, err)
+		require.NoError(t, log.Close())
+
+		assert.Equal(t, []string{
+			"20240301T122900.000Z-build.log",
+			"20240301T123000.000Z-deploy.log",
+		}, names(t, dir))
+		assert.FileExists(t, filepath.Join(dir, "notes.txt"), "other files are left alone")
+	})
+
+	t.Run("removes logs older than max age", func(t *testing.T) {
+		dir := t.TempDir()
+		writeLog(t, dir, start.Add(-48*time.Hour), "build")
+		writeLog(t, dir, start.Add(-time.Hour), "test")
+
+		log, err := CreateRunLog(dir, "build", start, config.ShuttleRunLogs{MaxAge: "24h"})
+		require.NoError(t, err)
+		require.NoError(t, log.Close())
+
+		assert.Equal(t, []string{
+			"20240301T113000.000Z-test.log",
+			"20240301T123000.000Z-build.log",
+		}, names(t, dir))
+	})
+
+	t.Run("invalid settings", func(t *testing.T) {
+		_, err := CreateRunLog(t.TempDir(), "build", start, config.ShuttleRunLogs{Keep: -1})
+		assert.EqualError(t, err, "exit code 1 - runLogs.keep must be positive but was -1")
+
+		_, err = CreateRunLog(t.TempDir(), "build", start, config.ShuttleRunLogs{MaxAge: "a week"})
+		assert.EqualError(t, err, "exit code 1 - runLogs.maxAge 'a week' is invalid: must be a positive duration, eg. 168h")
+	})
+}
+
+func TestListRunLogs_missingDirectory(t *testing.T) {
+	logs, err := ListRunLogs(filepath.Join(t.TempDir(), "logs"))
+
+	require.NoError(t, err)
+	assert.Empty(t, logs)
+}
+
+func TestRunLog_nil(t *testing.T) {
+	var log *RunLog
+
+	log.Printf("discarded")
+	assert.NoError(t, log.Close())
+}
@@ -0,0 +1,381 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/filelock"
+	"github.com/lunarway/shuttle/pkg/telemetry"
+)
+
+// scriptLockPollInterval is how often a lock held by another run is tried
+// again.
+const scriptLockPollInterval = 500 * time.Millisecond
+
+// Environment variables configuring the HTTP lock service.
+const (
+	lockEndpointEnv = "SHUTTLE_LOCK_ENDPOINT"
+	lockTokenEnv    = "SHUTTLE_LOCK_TOKEN"
+)
+
+// lockRequestTimeout is how long a request to the HTTP lock service may take.
+const lockRequestTimeout = 10 * time.Second
+
+// defaultLockLease is how long a lock of the HTTP lock service is held
+// without being renewed. Locks are renewed at a third of it such that a run
+// that is killed only holds its lock until the lease expires.
+const defaultLockLease = time.Minute
+
+// unsafeLockNameChars matches the characters of lock names replaced in the
+// names of lock files.
+var unsafeLockNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// WithNoWaitLock fails scripts whose lock is held by another run instead of
+// waiting for it to be released.
+func WithNoWaitLock(noWait bool) ExecuteOption {
+	return func(c *ScriptExecutionContext) {
+		c.NoWaitLock = noWait
+	}
+}
+
+// LockHolder describes the run holding a lock.
+type LockHolder struct {
+	User   string    `json:"user"`
+	Host   string    `json:"host"`
+	Script string    `json:"script"`
+	RunID  string    `json:"runId"`
+	Since  time.Time `json:"since"`
+	// ExpiresAt is when a lock of the HTTP lock service is released unless
+	// the holder renews it
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
+}
+
+func (h LockHolder) String() string {
+	if h.User == "" && h.Host == "" {
+		return "another run"
+	}
+	holder := fmt.Sprintf("%s@%s", h.User, h.Host)
+	if h.Script != "" {
+		holder += fmt.Sprintf(" running %s", h.Script)
+	}
+	if !h.Since.IsZero() {
+		holder += fmt.Sprintf(" since %s", h.Since.Local().Format(time.RFC3339))
+	}
+	return holder
+}
+
+// LockBackend holds the locks of scripts.
+type LockBackend interface {
+	// TryLock acquires the lock name for holder without waiting and returns a
+	// function releasing it. If the lock is held by another run, the holder
+	// of it is returned instead.
+	TryLock(ctx context.Context, name string, holder LockHolder) (release func() error, heldBy *LockHolder, err error)
+	// Release releases the lock name regardless of who holds it, eg. as the
+	// run holding it is stuck.
+	Release(ctx context.Context, name string) error
+}
+
+// newLockBackend returns the backend of the locks of project.
+func newLockBackend(project config.ShuttleProjectContext) (LockBackend, error) {
+	settings := project.Config.Locks
+	endpoint := settings.Endpoint
+	if env := os.Getenv(lockEndpointEnv); env != "" {
+		endpoint = env
+	}
+	backend := settings.Backend
+	if backend == "" {
+		backend = config.LockBackendFile
+		if endpoint != "" {
+			backend = config.LockBackendHTTP
+		}
+	}
+	switch backend {
+	case config.LockBackendFile:
+		return fileLockBackend{directory: filepath.Join(project.LocalShuttleDirectoryPath, "locks")}, nil
+	case config.LockBackendHTTP:
+		if endpoint == "" {
+			return nil, errors.NewExitCode(1, "locks.endpoint must be set with the http lock backend")
+		}
+		return httpLockBackend{endpoint: strings.TrimSuffix(endpoint, "/"), token: os.Getenv(lockTokenEnv)}, nil
+	}
+	return nil, errors.NewExitCode(1, "locks.backend '%s' is invalid: must be one of %s or %s", backend, config.LockBackendFile, config.LockBackendHTTP)
+}
+
+// ReleaseLock releases the lock name of project regardless of who holds it.
+func ReleaseLock(ctx context.Context, project config.ShuttleProjectContext, name string) error {
+	backend, err := newLockBackend(project)
+	if err != nil {
+		return err
+	}
+	if err := backend.Release(ctx, name); err != nil {
+		return errors.NewExitCode(1, "Failed to release lock '%s': %v", name, err)
+	}
+	return nil
+}
+
+// acquireScriptLock acquires the lock of the script of scriptContext if it has
+// one. It waits for the lock to be released by other runs unless NoWaitLock is
+// set. The returned function releases the lock. Dry runs take no locks.
+func acquireScriptLock(ctx context.Context, scriptContext ScriptExecutionContext) (func(), error) {
+	script := scriptContext.Script
+	if script.Lock == "" || scriptContext.DryRun {
+		return func() {}, nil
+	}
+	shellEnv, err := shellEnvironment(ActionExecutionContext{ScriptContext: scriptContext})
+	if err != nil {
+		return nil, err
+	}
+	env := environmentMap(shellEnv)
+	name := os.Expand(script.Lock, func(name string) string {
+		return env[name]
+	})
+	backend, err := newLockBackend(scriptContext.Project)
+	if err != nil {
+		return nil, err
+	}
+
+	ui := scriptContext.Project.UI
+	host, _ := os.Hostname()
+	holder := LockHolder{
+		User:   currentUser(),
+		Host:   host,
+		Script: scriptContext.ScriptName,
+		RunID:  telemetry.RunIDFrom(ctx),
+		Since:  time.Now().UTC(),
+	}
+	waiting := false
+	for {
+		release, heldBy, err := backend.TryLock(ctx, name, holder)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, errors.NewCancellation(ctx)
+			}
+			return nil, errors.NewExitCode(1, "Failed to acquire lock '%s' of script `%s`: %v", name, scriptContext.ScriptName, err)
+		}
+		if heldBy == nil {
+			if waiting {
+				ui.Infoln("Acquired lock '%s'", name)
+			} else {
+				ui.Verboseln("Acquired lock '%s'", name)
+			}
+			return func() {
+				if err := release(); err != nil {
+					ui.Errorln("Failed to release lock '%s' of script `%s`: %v", name, scriptContext.ScriptName, err)
+				}
+			}, nil
+		}
+		if scriptContext.NoWaitLock {
+			return nil, errors.NewExitCode(1, "Script `%s` cannot run as lock '%s' is held by %s", scriptContext.ScriptName, name, heldBy)
+		}
+		if !waiting {
+			ui.Infoln("Waiting for lock '%s' held by %s", name, heldBy)
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.NewCancellation(ctx)
+		case <-time.After(scriptLockPollInterval):
+		}
+	}
+}
+
+// fileLockBackend holds locks in files of directory. The holder of a lock is
+// written next to it.
+type fileLockBackend struct {
+	directory string
+}
+
+func (b fileLockBackend) TryLock(ctx context.Context, name string, holder LockHolder) (func() error, *LockHolder, error) {
+	if err := os.MkdirAll(b.directory, 0o755); err != nil {
+		return nil, nil, err
+	}
+	base := filepath.Join(b.directory, unsafeLockNameChars.ReplaceAllString(name, "_"))
+	file, err := os.OpenFile(base+".lock", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	locked, err := filelock.TryLock(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	holderPath := base + ".json"
+	if !locked {
+		file.Close()
+		heldBy := &LockHolder{}
+		if content, err := os.ReadFile(holderPath); err == nil {
+			_ = json.Unmarshal(content, heldBy)
+		}
+		return nil, heldBy, nil
+	}
+
+	content, err := json.Marshal(holder)
+	if err == nil {
+		err = os.WriteFile(holderPath, content, 0o644)
+	}
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return func() error {
+		os.Remove(holderPath)
+		// closing the file releases the lock
+		return file.Close()
+	}, nil, nil
+}
+
+// Release removes the files of the lock such that other runs lock new ones.
+// A run holding the lock keeps running but no longer excludes other runs.
+func (b fileLockBackend) Release(ctx context.Context, name string) error {
+	base := filepath.Join(b.directory, unsafeLockNameChars.ReplaceAllString(name, "_"))
+	for _, path := range []string{base + ".lock", base + ".json"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// httpLockBackend holds locks in an HTTP lock service. A lock is acquired with
+// a PUT request of the holder to <endpoint>/<name> which responds with 409
+// Conflict or 423 Locked and the holder of the lock if it is held by another
+// run. The holder renews the lock before it expires with the same request
+// with ?renew=true, which must not acquire the lock if it is no longer held by
+// the holder, and releases it with a DELETE request of the holder. A DELETE
+// request with ?force=true releases the lock regardless of its holder.
+type httpLockBackend struct {
+	endpoint string
+	token    string
+	// lease is how long locks are held without being renewed. It defaults to
+	// defaultLockLease.
+	lease time.Duration
+}
+
+func (b httpLockBackend) TryLock(ctx context.Context, name string, holder LockHolder) (func() error, *LockHolder, error) {
+	lease := b.lease
+	if lease == 0 {
+		lease = defaultLockLease
+	}
+	lockURL := b.endpoint + "/" + url.PathEscape(name)
+	holder.ExpiresAt = time.Now().UTC().Add(lease)
+	heldBy, err := b.put(ctx, lockURL, holder)
+	if err != nil {
+		return nil, nil, err
+	}
+	// a lock whose holder stopped renewing it is taken over in case the
+	// service does not expire locks itself
+	if heldBy != nil && !heldBy.ExpiresAt.IsZero() && heldBy.ExpiresAt.Before(time.Now()) {
+		if _, _, err := b.do(ctx, http.MethodDelete, lockURL, *heldBy); err != nil {
+			return nil, nil, err
+		}
+		heldBy, err = b.put(ctx, lockURL, holder)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if heldBy != nil {
+		return nil, heldBy, nil
+	}
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				renewed := holder
+				renewed.ExpiresAt = time.Now().UTC().Add(lease)
+				heldBy, err := b.put(context.WithoutCancel(ctx), lockURL+"?renew=true", renewed)
+				switch {
+				case err != nil:
+					// a failed renewal is retried on the next tick
+				case heldBy != nil:
+					// the lock was released by force or taken over
+					<-stop
+					return
+				default:
+					holder = renewed
+				}
+			}
+		}
+	}()
+	return func() error {
+		close(stop)
+		<-stopped
+		// the lock is released even if the run was cancelled
+		_, _, err := b.do(context.WithoutCancel(ctx), http.MethodDelete, lockURL, holder)
+		return err
+	}, nil, nil
+}
+
+func (b httpLockBackend) Release(ctx context.Context, name string) error {
+	lockURL := b.endpoint + "/" + url.PathEscape(name) + "?force=true"
+	_, _, err := b.do(ctx, http.MethodDelete, lockURL, LockHolder{})
+	return err
+}
+
+// put acquires or renews the lock at lockURL for holder. The holder of the
+// lock is returned if it is held by another run.
+func (b httpLockBackend) put(ctx context.Context, lockURL string, holder LockHolder) (*LockHolder, error) {
+	status, body, err := b.do(ctx, http.MethodPut, lockURL, holder)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusConflict || status == http.StatusLocked {
+		heldBy := &LockHolder{}
+		_ = json.Unmarshal(body, heldBy)
+		return heldBy, nil
+	}
+	return nil, nil
+}
+
+// do sends holder to lockURL with method and returns the status code and body
+// of the response. Responses other than 2xx, 409 and 423 are errors.
+func (b httpLockBackend) do(ctx context.Context, method, lockURL string, holder LockHolder) (int, []byte, error) {
+	body, err := json.Marshal(holder)
+	if err != nil {
+		return 0, nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, lockRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, lockURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s %s: %w", method, redactURL(lockURL), err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s %s: %w", method, redactURL(lockURL), err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s %s: %w", method, redactURL(lockURL), err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusConflict || resp.StatusCode == http.StatusLocked:
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return 0, nil, fmt.Errorf("%s responded with status %s", redactURL(lockURL), resp.Status)
+	}
+	return resp.StatusCode, respBody, nil
+}
@@ -0,0 +1,288 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_lock(t *testing.T) {
+	projectPath := t.TempDir()
+	shuttleDir := filepath.Join(projectPath, ".shuttle")
+	log := filepath.Join(t.TempDir(), "log")
+	t.Setenv("log", log)
+	t.Setenv("SHUTTLE_LOCK_ENDPOINT", "")
+
+	script := config.ShuttlePlanScript{
+		Lock: "deploy-$env",
+		Args: []config.ShuttleScriptArgs{{Name: "env"}},
+		Actions: []config.ShuttleAction{
+			{Shell: `echo "deployed $env" >> "$log"`},
+		},
+	}
+	run := func(env string, options ...ExecuteOption) (string, error) {
+		var stderr bytes.Buffer
+		err := NewRegistry(ShellExecutor).Execute(context.Background(), config.ShuttleProjectContext{
+			ProjectPath:               projectPath,
+			LocalShuttleDirectoryPath: shuttleDir,
+			UI:                        ui.Create(&bytes.Buffer{}, &stderr),
+			Scripts:                   map[string]config.ShuttlePlanScript{"deploy": script},
+		}, "deploy", map[string]string{"env": env}, true, options...)
+		return stderr.String(), err
+	}
+	runs := func() string {
+		content, _ := os.ReadFile(log)
+		return string(content)
+	}
+
+	backend := fileLockBackend{directory: filepath.Join(shuttleDir, "locks")}
+	release, heldBy, err := backend.TryLock(context.Background(), "deploy-prod", LockHolder{User: "alice", Host: "laptop", Script: "deploy"})
+	require.NoError(t, err)
+	require.Nil(t, heldBy)
+
+	_, err = run("prod", WithNoWaitLock(true))
+	assert.EqualError(t, err, "exit code 1 - Script `deploy` cannot run as lock 'deploy-prod' is held by alice@laptop running deploy")
+	_, err = run("dev", WithNoWaitLock(true))
+	require.NoError(t, err, "other locks must not be held")
+	assert.Equal(t, "deployed dev\n", runs())
+
+	type result struct {
+		stderr string
+		err    error
+	}
+	done := make(chan result)
+	go func() {
+		stderr, err := run("prod")
+		done <- result{stderr, err}
+	}()
+	select {
+	case r := <-done:
+		t.Fatalf("script run while its lock is held: %v", r.err)
+	case <-time.After(3 * scriptLockPollInterval):
+	}
+	assert.Equal(t, "deployed dev\n", runs())
+	require.NoError(t, release())
+
+	r := <-done
+	require.NoError(t, r.err)
+	assert.Contains(t, r.stderr, "Waiting for lock 'deploy-prod' held by alice@laptop running deploy")
+	assert.Contains(t, r.stderr, "Acquired lock 'deploy-prod'")
+	assert.Equal(t, "deployed dev\ndeployed prod\n", runs())
+
+	_, err = run("prod", WithNoWaitLock(true))
+	assert.NoError(t, err, "the lock must be released when the script completes")
+}
+
+func TestExecute_lockCancelled(t *testing.T) {
+	projectPath := t.TempDir()
+	shuttleDir := filepath.Join(projectPath, ".shuttle")
+	t.Setenv("SHUTTLE_LOCK_ENDPOINT", "")
+	backend := fileLockBackend{directory: filepath.Join(shuttleDir, "locks")}
+	release, _, err := backend.TryLock(context.Background(), "deploy", LockHolder{})
+	require.NoError(t, err)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*scriptLockPollInterval)
+	defer cancel()
+	err = NewRegistry(ShellExecutor).Execute(ctx, config.ShuttleProjectContext{
+		ProjectPath:               projectPath,
+		LocalShuttleDirectoryPath: shuttleDir,
+		UI:                        ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{"deploy": {
+			Lock:    "deploy",
+			Actions: []config.ShuttleAction{{Shell: "exit 1"}},
+		}},
+	}, "deploy", nil, true)
+
+	assert.EqualError(t, err, "exit code 124 - Timed out")
+}
+
+func TestHTTPLockBackend(t *testing.T) {
+	var (
+		mu            sync.Mutex
+		locks         = make(map[string]LockHolder)
+		renewals      int
+		authorization string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		authorization = r.Header.Get("Authorization")
+		name := strings.TrimPrefix(r.URL.Path, "/locks/")
+		if name == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var holder LockHolder
+		_ = json.NewDecoder(r.Body).Decode(&holder)
+		switch r.Method {
+		case http.MethodPut:
+			if current, ok := locks[name]; (!ok && r.URL.Query().Get("renew") == "true") || ok && current.RunID != holder.RunID {
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(current)
+				return
+			} else if ok {
+				renewals++
+			}
+			locks[name] = holder
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodDelete:
+			if current, ok := locks[name]; ok && r.URL.Query().Get("force") != "true" && current.RunID != holder.RunID {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			delete(locks, name)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	backend := httpLockBackend{endpoint: server.URL + "/locks", token: "secret", lease: 30 * time.Millisecond}
+	ctx := context.Background()
+	lock := func(name string) LockHolder {
+		mu.Lock()
+		defer mu.Unlock()
+		return locks[name]
+	}
+	held := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(locks)
+	}
+
+	release, heldBy, err := backend.TryLock(ctx, "deploy prod", LockHolder{User: "alice", Host: "laptop", RunID: "1"})
+	require.NoError(t, err)
+	require.Nil(t, heldBy)
+	assert.Equal(t, "Bearer secret", authorization)
+	assert.Equal(t, "alice", lock("deploy prod").User)
+
+	// the lock is renewed while it is held
+	time.Sleep(100 * time.Millisecond)
+	_, heldBy, err = backend.TryLock(ctx, "deploy prod", LockHolder{User: "bob", Host: "ci", RunID: "2"})
+	require.NoError(t, err)
+	require.NotNil(t, heldBy)
+	assert.Equal(t, "alice", heldBy.User)
+	assert.True(t, heldBy.ExpiresAt.After(time.Now()), "lease must be renewed")
+	mu.Lock()
+	assert.Greater(t, renewals, 0)
+	mu.Unlock()
+
+	require.NoError(t, release())
+	assert.Equal(t, 0, held())
+	release, heldBy, err = backend.TryLock(ctx, "deploy prod", LockHolder{User: "bob", Host: "ci", RunID: "2"})
+	require.NoError(t, err)
+	assert.Nil(t, heldBy)
+	require.NoError(t, release())
+
+	// an expired lock of a run that stopped renewing it is taken over
+	mu.Lock()
+	locks["stale"] = LockHolder{User: "carol", RunID: "3", ExpiresAt: time.Now().Add(-time.Minute)}
+	mu.Unlock()
+	release, heldBy, err = backend.TryLock(ctx, "stale", LockHolder{User: "bob", Host: "ci", RunID: "2"})
+	require.NoError(t, err)
+	assert.Nil(t, heldBy)
+	assert.Equal(t, "bob", lock("stale").User)
+
+	// a held lock is released by force and not acquired again by renewing it
+	require.NoError(t, backend.Release(ctx, "stale"))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, held())
+	require.NoError(t, release())
+
+	_, _, err = backend.TryLock(ctx, "broken", LockHolder{})
+	assert.EqualError(t, err, server.URL+"/locks/broken responded with status 500 Internal Server Error")
+}
+
+func TestFileLockBackend_Release(t *testing.T) {
+	backend := fileLockBackend{directory: t.TempDir()}
+	ctx := context.Background()
+
+	release, heldBy, err := backend.TryLock(ctx, "deploy", LockHolder{User: "alice"})
+	require.NoError(t, err)
+	require.Nil(t, heldBy)
+	defer release()
+
+	require.NoError(t, backend.Release(ctx, "deploy"))
+	otherRelease, heldBy, err := backend.TryLock(ctx, "deploy", LockHolder{User: "bob"})
+	require.NoError(t, err)
+	assert.Nil(t, heldBy, "released lock must be acquirable")
+	require.NoError(t, otherRelease())
+	assert.NoError(t, backend.Release(ctx, "missing"))
+}
+
+func TestNewLockBackend(t *testing.T) {
+	tt := []struct {
+		name     string
+		settings config.ShuttleLocks
+		env      string
+		backend  LockBackend
+		err      string
+	}{
+		{
+			name:    "file by default",
+			backend: fileLockBackend{directory: filepath.Join(".shuttle", "locks")},
+		},
+		{
+			name:     "http with endpoint",
+			settings: config.ShuttleLocks{Endpoint: "https://locks.example.com/"},
+			backend:  httpLockBackend{endpoint: "https://locks.example.com"},
+		},
+		{
+			name:     "endpoint from environment",
+			settings: config.ShuttleLocks{Endpoint: "https://locks.example.com"},
+			env:      "https://locks.internal",
+			backend:  httpLockBackend{endpoint: "https://locks.internal"},
+		},
+		{
+			name:     "file with endpoint",
+			settings: config.ShuttleLocks{Backend: "file", Endpoint: "https://locks.example.com"},
+			backend:  fileLockBackend{directory: filepath.Join(".shuttle", "locks")},
+		},
+		{
+			name:     "http without endpoint",
+			settings: config.ShuttleLocks{Backend: "http"},
+			err:      "exit code 1 - locks.endpoint must be set with the http lock backend",
+		},
+		{
+			name:     "unknown backend",
+			settings: config.ShuttleLocks{Backend: "redis"},
+			err:      "exit code 1 - locks.backend 'redis' is invalid: must be one of file or http",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("SHUTTLE_LOCK_ENDPOINT", tc.env)
+			t.Setenv("SHUTTLE_LOCK_TOKEN", "")
+			backend, err := newLockBackend(config.ShuttleProjectContext{
+				LocalShuttleDirectoryPath: ".shuttle",
+				Config:                    config.ShuttleConfig{Locks: tc.settings},
+			})
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.backend, backend)
+		})
+	}
+}
+
+func TestLockHolder_String(t *testing.T) {
+	since := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	assert.Equal(t, "another run", LockHolder{}.String())
+	assert.Equal(t, "alice@laptop", LockHolder{User: "alice", Host: "laptop"}.String())
+	assert.Equal(t, "alice@laptop running deploy since "+since.Local().Format(time.RFC3339), LockHolder{User: "alice", Host: "laptop", Script: "deploy", Since: since}.String())
+}
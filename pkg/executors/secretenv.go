@@ -0,0 +1,64 @@
+package executors
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/secrets"
+)
+
+// runSecrets are the secrets of the project shared by all scripts of an
+// invocation. They are resolved once before the first action is run.
+type runSecrets struct {
+	values   map[string]string
+	resolved bool
+}
+
+// resolve returns the values of the secrets of the project of context. In dry
+// runs providers are not used and all values are redacted.
+func (s *runSecrets) resolve(ctx context.Context, context ScriptExecutionContext) (map[string]string, error) {
+	if s.resolved {
+		return s.values, nil
+	}
+	project := context.Project
+	names := make([]string, 0, len(project.Secrets))
+	for name := range project.Secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make(map[string]string, len(names))
+	resolver := secrets.NewResolver(project.ProjectPath)
+	for _, name := range names {
+		if context.DryRun {
+			values[name] = redactedValue
+			continue
+		}
+		secret := project.Secrets[name]
+		value, err := resolver.Resolve(ctx, secret)
+		if err != nil {
+			provider, _ := secret.Provider()
+			return nil, errors.NewExitCode(1, "Failed to resolve secret '%s' from %s: %v", name, provider, err)
+		}
+		values[name] = value
+	}
+	s.values = values
+	s.resolved = true
+	return values, nil
+}
+
+// secretEnvironment returns the secrets of the script on the form name=value.
+func secretEnvironment(context ActionExecutionContext) []string {
+	names := make([]string, 0, len(context.ScriptContext.Secrets))
+	for name := range context.ScriptContext.Secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	env := make([]string, 0, len(names))
+	for _, name := range names {
+		env = append(env, fmt.Sprintf("%s=%s", name, context.ScriptContext.Secrets[name]))
+	}
+	return env
+}
@@ -0,0 +1,80 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_secrets(t *testing.T) {
+	t.Setenv("CI_DB_PASSWORD", "db-s3cr3t")
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: ".",
+		UI:          ui.Create(stdout, stderr),
+		Secrets: map[string]config.ShuttleSecret{
+			"DB_PASSWORD": {Env: "CI_DB_PASSWORD"},
+		},
+		Scripts: map[string]config.ShuttlePlanScript{
+			"migrate": {
+				Actions: []config.ShuttleAction{
+					{Shell: `echo "using $DB_PASSWORD"; echo "password=$DB_PASSWORD" >&2; [ "$DB_PASSWORD" = "db-s3cr3t" ] && echo "env ok"`},
+				},
+			},
+		},
+	}, "migrate", map[string]string{}, true)
+
+	require.NoError(t, err)
+	assert.Equal(t, "using ***\nenv ok\n", stdout.String())
+	assert.Equal(t, "password=***\n", stderr.String())
+}
+
+func TestExecute_secretsUnresolved(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: ".",
+		UI:          ui.Create(stdout, stderr),
+		Secrets: map[string]config.ShuttleSecret{
+			"DB_PASSWORD": {Env: "SHUTTLE_TEST_UNSET_SECRET"},
+		},
+		Scripts: map[string]config.ShuttlePlanScript{
+			"migrate": {
+				Actions: []config.ShuttleAction{{Shell: `echo "ran"`}},
+			},
+		},
+	}, "migrate", map[string]string{}, true)
+
+	assert.EqualError(t, err, "exit code 1 - Failed to resolve secret 'DB_PASSWORD' from env: environment variable SHUTTLE_TEST_UNSET_SECRET is not set")
+	assert.Empty(t, stdout.String(), "no actions are run")
+}
+
+func TestExecute_secretsDryRun(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: ".",
+		UI:          ui.Create(stdout, stderr),
+		Secrets: map[string]config.ShuttleSecret{
+			"DB_PASSWORD": {Env: "SHUTTLE_TEST_UNSET_SECRET"},
+		},
+		Scripts: map[string]config.ShuttlePlanScript{
+			"migrate": {
+				Actions: []config.ShuttleAction{{Shell: `echo "ran"`}},
+			},
+		},
+	}, "migrate", map[string]string{}, true, WithDryRun(true))
+
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "  env: DB_PASSWORD=[redacted]\n")
+}
@@ -0,0 +1,203 @@
+package executors
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sync"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// SecretDetectionMode controls what happens when a possible secret is found
+// in the output of an action.
+type SecretDetectionMode string
+
+const (
+	SecretDetectionOff  SecretDetectionMode = ""
+	SecretDetectionWarn SecretDetectionMode = "warn"
+	SecretDetectionFail SecretDetectionMode = "fail"
+)
+
+// maxSecretScanLength bounds the part of a line scanned for secrets
+const maxSecretScanLength = 64 * 1024
+
+// minEntropyTokenLength and minTokenEntropy are the thresholds for reporting
+// random looking tokens. Hex encoded values, eg. git SHAs, stay below the
+// entropy threshold.
+const (
+	minEntropyTokenLength = 32
+	minTokenEntropy       = 4.5
+)
+
+var secretPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{"AWS access key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"JSON web token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+}
+
+var entropyTokenRegexp = regexp.MustCompile(`[A-Za-z0-9+/=_-]{` + fmt.Sprint(minEntropyTokenLength) + `,}`)
+
+// SecretDetection configures scanning of action output for secrets.
+type SecretDetection struct {
+	Mode SecretDetectionMode
+	// Allow suppresses detections matching any of the patterns
+	Allow []*regexp.Regexp
+}
+
+// WithSecretDetection scans the output of actions for possible secrets.
+func WithSecretDetection(detection SecretDetection) ExecuteOption {
+	return func(c *ScriptExecutionContext) {
+		c.SecretDetection = detection
+	}
+}
+
+// ParseSecretDetection validates mode and the allowlist patterns.
+func ParseSecretDetection(mode string, allow []string) (SecretDetection, error) {
+	detection := SecretDetection{
+		Mode: SecretDetectionMode(mode),
+	}
+	switch detection.Mode {
+	case SecretDetectionOff, SecretDetectionWarn, SecretDetectionFail:
+	default:
+		return SecretDetection{}, errors.NewExitCode(
+			2,
+			"Secret detection mode '%s' is invalid: must be one of '%s' or '%s'",
+			mode,
+			SecretDetectionWarn,
+			SecretDetectionFail,
+		)
+	}
+	for _, pattern := range allow {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return SecretDetection{}, errors.NewExitCode(
+				2,
+				"Secret detection allowlist pattern '%s' is invalid: %v",
+				pattern,
+				err,
+			)
+		}
+		detection.Allow = append(detection.Allow, re)
+	}
+	return detection, nil
+}
+
+// secretFinding describes a possible secret found in output
+type secretFinding struct {
+	kind string
+	line int
+}
+
+// secretScanner scans the output lines of a single command. A nil scanner is
+// disabled.
+type secretScanner struct {
+	detection SecretDetection
+	mu        sync.Mutex
+	lines     int
+	first     *secretFinding
+}
+
+func newSecretScanner(detection SecretDetection) *secretScanner {
+	if detection.Mode == SecretDetectionOff {
+		return nil
+	}
+	return &secretScanner{detection: detection}
+}
+
+// Scan returns the kind of secret found in line and whether one was found.
+func (s *secretScanner) Scan(line string) (secretFinding, bool) {
+	if s == nil {
+		return secretFinding{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lines++
+
+	kind, ok := s.detect(line)
+	if !ok {
+		return secretFinding{}, false
+	}
+	finding := secretFinding{kind: kind, line: s.lines}
+	if s.first == nil {
+		s.first = &finding
+	}
+	return finding, true
+}
+
+// First returns the first finding of the scanner if any.
+func (s *secretScanner) First() (secretFinding, bool) {
+	if s == nil {
+		return secretFinding{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.first == nil {
+		return secretFinding{}, false
+	}
+	return *s.first, true
+}
+
+func (s *secretScanner) detect(line string) (string, bool) {
+	if len(line) > maxSecretScanLength {
+		line = line[:maxSecretScanLength]
+	}
+	for _, p := range secretPatterns {
+		for _, match := range p.pattern.FindAllString(line, -1) {
+			if !s.allowed(match) {
+				return p.kind, true
+			}
+		}
+	}
+	for _, token := range entropyTokenRegexp.FindAllString(line, -1) {
+		if shannonEntropy(token) >= minTokenEntropy && !s.allowed(token) {
+			return "high entropy string", true
+		}
+	}
+	return "", false
+}
+
+func (s *secretScanner) allowed(match string) bool {
+	for _, re := range s.detection.Allow {
+		if re.MatchString(match) {
+			return true
+		}
+	}
+	return false
+}
+
+// shannonEntropy returns the entropy of s in bits per character.
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	length := float64(len(s))
+	var entropy float64
+	for _, count := range counts {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// secretSuppressedLine replaces output lines containing a possible secret.
+func secretSuppressedLine(finding secretFinding) string {
+	return fmt.Sprintf("[shuttle: output line %d suppressed as it may contain a secret (%s)]", finding.line, finding.kind)
+}
+
+func secretDetectedError(context ActionExecutionContext, finding secretFinding) error {
+	return errors.NewExitCode(
+		4,
+		"Possible secret (%s) detected in output of script `%s` action %d on line %d.\nThe line was suppressed. Add a --detect-secrets-allow pattern if this is a false positive.",
+		finding.kind,
+		context.ScriptContext.ScriptName,
+		context.ActionIndex,
+		finding.line,
+	)
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-cmd/cmd"
 
@@ -16,127 +20,673 @@ import (
 )
 
 func ShellExecutor(action config.ShuttleAction) (Executor, bool) {
-	return executeShell, action.Shell != ""
+	return executeShell, action.Shell != "" && action.Docker == nil
 }
 
 // Build builds the docker image from a shuttle plan
 func executeShell(ctx context.Context, ui *ui.UI, context ActionExecutionContext) error {
+	err := runShellAction(ctx, context)
+	runCleanup(ctx, context)
+	return err
+}
+
+// runShellAction runs the preflight checks of the action followed by the
+// action itself.
+func runShellAction(ctx context.Context, context ActionExecutionContext) error {
+	if context.Action.Interactive {
+		if err := validateInteractiveAction(context); err != nil {
+			return err
+		}
+	}
+	// preflight checks are never interactive
+	checkContext := context
+	checkContext.Action.Interactive = false
+	for _, check := range context.Action.Preflight {
+		context.ScriptContext.Project.UI.Verboseln("Running preflight check '%s'", check)
+
+		exitCode, err := runShellCommand(ctx, checkContext, check.Shell, false, nil)
+		if err != nil {
+			return err
+		}
+		if exitCode > 0 {
+			return errors.NewExitCode(
+				4,
+				"Preflight check '%s' failed for script `%s`: shell script `%s`\nExit code: %v",
+				check,
+				context.ScriptContext.ScriptName,
+				check.Shell,
+				exitCode,
+			)
+		}
+	}
+
+	if context.Action.Docker != nil {
+		if err := validateDockerAction(context); err != nil {
+			return err
+		}
+	} else if context.Action.PowerShell != "" {
+		if err := validatePowerShellAction(context); err != nil {
+			return err
+		}
+	} else if err := validateActionShell(context); err != nil {
+		return err
+	}
+
+	if context.Action.Background {
+		if err := validateBackgroundAction(context); err != nil {
+			return err
+		}
+		return startBackgroundShell(ctx, context, context.Action.Shell)
+	}
+
+	var captured *[]string
+	if context.Action.CaptureOutput != "" {
+		if err := validateCaptureOutput(context); err != nil {
+			return err
+		}
+		captured = &[]string{}
+	}
+	if context.ScriptContext.Outputs != nil {
+		outputFile, remove, err := createOutputFile(context)
+		if err != nil {
+			return fmt.Errorf("create output file: %w", err)
+		}
+		defer remove()
+		context.outputFile = outputFile
+	}
+	script, kind := actionScript(context.Action)
+	exitCode, attempts, err := retryShellCommand(ctx, context, script, captured)
+	if err != nil {
+		return err
+	}
+	if exitCode > 0 {
+		shown := kind + " script"
+		if script == "" && context.Action.Docker != nil {
+			// the container ran the command of the image
+			shown, script = "docker image", context.Action.Docker.Image
+		}
+		message := fmt.Sprintf(
+			"Failed executing script `%s`: %s `%s`\nExit code: %v",
+			context.ScriptContext.ScriptName,
+			shown,
+			script,
+			exitCode,
+		)
+		if attempts > 1 {
+			message += fmt.Sprintf("\nAttempts: %d", attempts)
+		}
+		return errors.NewExitCode(actionFailureExitCode(context, exitCode), "%s", message)
+	}
+	if context.outputFile != "" {
+		if err := readOutputs(context, context.outputFile); err != nil {
+			return err
+		}
+	}
+	if captured != nil && context.ScriptContext.Outputs != nil {
+		// later captures of the same name replace earlier ones
+		context.ScriptContext.Outputs[context.Action.CaptureOutput] = strings.TrimSpace(strings.Join(*captured, "\n"))
+	}
+	return nil
+}
+
+// actionFailureExitCode returns the exit code shuttle exits with when the
+// script of the action exited with exitCode. It is 4 unless the exit code of
+// the script is preserved in which case it is clamped to 255, the largest exit
+// code of a process.
+func actionFailureExitCode(context ActionExecutionContext, exitCode int) int {
+	if !context.ScriptContext.PreserveExitCode || exitCode <= 0 {
+		return 4
+	}
+	if exitCode > 255 {
+		return 255
+	}
+	return exitCode
+}
+
+// validateBackgroundAction returns an error if the action uses options that
+// are not supported when running in the background or if an earlier action of
+// the script runs in the background as well.
+func validateBackgroundAction(context ActionExecutionContext) error {
+	// the state files of background actions are kept per script
+	for i, action := range context.ScriptContext.Script.Actions {
+		if i < context.ActionIndex && action.Background {
+			return errors.NewExitCode(
+				1,
+				"Action %d of script `%s` cannot run in the background as action %d already does",
+				context.ActionIndex,
+				context.ScriptContext.ScriptName,
+				i,
+			)
+		}
+	}
+	option := ""
+	switch {
+	case context.Action.Sudo:
+		option = "use sudo"
+	case context.Action.CaptureOutput != "":
+		option = "capture output"
+	case context.Action.Retries != 0:
+		option = "be retried"
+	case context.Action.Cleanup != "":
+		option = "have a cleanup"
+	default:
+		return nil
+	}
+	return errors.NewExitCode(
+		1,
+		"Action %d of script `%s` cannot %s as it runs in the background",
+		context.ActionIndex,
+		context.ScriptContext.ScriptName,
+		option,
+	)
+}
+
+// validateCaptureOutput returns an error if the variable the output of the
+// action is captured in is not a valid environment variable name.
+func validateCaptureOutput(context ActionExecutionContext) error {
+	if validVariableName.MatchString(context.Action.CaptureOutput) {
+		return nil
+	}
+	return errors.NewExitCode(
+		1,
+		"Action %d of script `%s` has an invalid captureOutput '%s': must be a valid environment variable name",
+		context.ActionIndex,
+		context.ScriptContext.ScriptName,
+		context.Action.CaptureOutput,
+	)
+}
+
+// validVariableName matches names usable as environment variables
+var validVariableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// runShellCommand runs script from the project directory with the shuttle
+// environment and streams its output to the UI. If elevated is set the script
+// is run with sudo. If capture is set stdout is appended to it instead of
+// being forwarded. The exit code of the script is returned.
+func runShellCommand(
+	ctx context.Context,
+	context ActionExecutionContext,
+	script string,
+	elevated bool,
+	capture *[]string,
+) (exitCode int, err error) {
+	decode, err := newOutputDecoder(context.Action.Encoding)
+	if err != nil {
+		return 0, err
+	}
+	latency, err := newLatencyRecorder(context.ScriptContext.ScriptName)
+	if err != nil {
+		return 0, err
+	}
+	defer latency.Close()
+	masker := newSecretMasker(context.ScriptContext)
+	decorator := lineDecorator(context)
+	secrets := newSecretScanner(context.ScriptContext.SecretDetection)
+	// scanLine replaces lines containing possible secrets before they are
+	// forwarded
+	scanLine := func(line string) string {
+		finding, found := secrets.Scan(line)
+		if !found {
+			return line
+		}
+		if context.ScriptContext.SecretDetection.Mode == SecretDetectionWarn {
+			context.ScriptContext.Project.UI.EmphasizeInfoln(
+				"Possible secret (%s) detected in output of script `%s` action %d on line %d",
+				finding.kind,
+				context.ScriptContext.ScriptName,
+				context.ActionIndex,
+				finding.line,
+			)
+		}
+		return secretSuppressedLine(finding)
+	}
+
+	buffered, err := bufferedOutput(context)
+	if err != nil {
+		return 0, err
+	}
+
 	cmdOptions := cmd.Options{
-		Buffered:  false,
-		Streaming: true,
+		Buffered:  buffered,
+		Streaming: !buffered,
 		// support large outputs from scripts
 		LineBufferSize: 512e3,
 	}
 
-	cmdArgs := []string{
-		"-c",
-		fmt.Sprintf("cd '%s'; %s", context.ScriptContext.Project.ProjectPath, context.Action.Shell),
+	gracePeriod, err := stopGracePeriod(context)
+	if err != nil {
+		return 0, err
 	}
-	execCmd := cmd.NewCmdOptions(cmdOptions, "sh", cmdArgs...)
 
-	context.ScriptContext.Project.UI.Verboseln(
-		"Starting shell command: %s %s",
-		execCmd.Name,
-		strings.Join(cmdArgs, " "),
-	)
+	interval, err := heartbeatInterval(context)
+	if err != nil {
+		return 0, err
+	}
 
-	setupCommandEnvironmentVariables(execCmd, context)
+	cmdArgs, env, dir, err := shellCommand(ctx, context, script, elevated)
+	if err != nil {
+		return 0, err
+	}
+	if context.ScriptContext.DryRun {
+		printDryRun(context, cmdArgs, env)
+		return 0, nil
+	}
+	runLog := context.ScriptContext.RunLog
+	runLog.logCommand(context, cmdArgs, env)
+	if terminal, ok := interactiveTerminal(context); ok {
+		return runInteractiveShellCommand(ctx, context, cmdArgs, env, dir, terminal, gracePeriod)
+	}
+	cmdOptions.BeforeExec = []func(*exec.Cmd){rawCommandLine(cmdArgs)}
+	execCmd := cmd.NewCmdOptions(cmdOptions, cmdArgs[0], cmdArgs[1:]...)
 
-	execCmd.Env = append(
-		execCmd.Env,
-		fmt.Sprintf("SHUTTLE_CONTEXT_ID=%s", telemetry.ContextIDFrom(ctx)),
-	)
+	lifecycle := newShellLifecycle(context)
+	lifecycle.Constructed(cmdArgs, env)
+
+	execCmd.Env = env
+	execCmd.Dir = dir
+
+	stdin, closeStdin, err := actionStdin(context)
+	if err != nil {
+		return 0, err
+	}
+	defer closeStdin()
+
+	log, err := openActionLog(context)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if closeErr := log.Close(); closeErr != nil {
+			context.ScriptContext.Project.UI.Errorln(
+				"Failed to write log file of script `%s` action %d: %v",
+				context.ScriptContext.ScriptName,
+				context.ActionIndex,
+				closeErr,
+			)
+		}
+	}()
+
+	beat := startHeartbeat(ctx, context, interval)
+	defer beat.Stop()
+
+	label := actionLabel(context)
+
+	forward := func(stream, line string) {
+		beat.Reset()
+		line = decode(line)
+		if capture != nil && stream == "stdout" {
+			// captured output is never printed so it is not scanned for
+			// secrets
+			*capture = append(*capture, line)
+			return
+		}
+		line = scanLine(masker.Mask(line))
+		context.output.Add(stream, line)
+		log.Write(stream, line)
+		runLog.Printf("[%s] %s: %s", label, stream, line)
+		context.ScriptContext.Project.UI.StepOutput(decorator, label, stream, line)
+	}
 
 	outputReadCompleted := make(chan struct{})
 
-	go func() {
-		defer close(outputReadCompleted)
-
-		for execCmd.Stdout != nil || execCmd.Stderr != nil {
-			select {
-			case line, open := <-execCmd.Stdout:
-				if !open {
-					execCmd.Stdout = nil
-					continue
-				}
-				context.ScriptContext.Project.UI.Output("%s", line)
-			case line, open := <-execCmd.Stderr:
-				if !open {
-					execCmd.Stderr = nil
-					continue
+	if buffered {
+		// output is forwarded once the command completes
+		close(outputReadCompleted)
+	} else {
+		go func() {
+			defer close(outputReadCompleted)
+
+			for execCmd.Stdout != nil || execCmd.Stderr != nil {
+				select {
+				case line, open := <-execCmd.Stdout:
+					if !open {
+						execCmd.Stdout = nil
+						continue
+					}
+					latency.Record("stdout")
+					forward("stdout", line)
+				case line, open := <-execCmd.Stderr:
+					if !open {
+						execCmd.Stderr = nil
+						continue
+					}
+					latency.Record("stderr")
+					forward("stderr", line)
 				}
-				context.ScriptContext.Project.UI.Infoln("%s", line)
 			}
-		}
-	}()
+		}()
+	}
 
-	// stop cmd if context is cancelled
+	tree, err := newProcessTree()
+	if err != nil {
+		return 0, err
+	}
+	defer tree.Close()
+
+	// stop cmd and its children if context is cancelled
+	commandCompleted := make(chan struct{})
+	defer close(commandCompleted)
 	go func() {
 		select {
 		case <-ctx.Done():
-			err := execCmd.Stop()
+			lifecycle.StopRequested(ctx)
+			closeStdin()
+			err := tree.Stop(ctx, execCmd, gracePeriod)
 			if err != nil {
 				context.ScriptContext.Project.UI.Errorln(
 					"Failed to stop script '%s': %v",
-					context.Action.Shell,
+					script,
 					err,
 				)
 			}
-		case <-outputReadCompleted:
+		case <-commandCompleted:
 		}
 	}()
 
+	start := time.Now()
+	// the span is recorded however the command completes
+	defer func() {
+		_, kind := actionScript(context.Action)
+		traceActionSpan(ctx, context, kind, start, exitCode, err)
+	}()
+	statusChan := execCmd.StartWithStdin(stdin)
+	tree.Track(execCmd)
+	lifecycle.Started()
 	select {
-	case status := <-execCmd.Start():
+	case status := <-statusChan:
+		lifecycle.Exited(status.Exit, status.Error)
 		<-outputReadCompleted
-		if status.Exit > 0 {
-			return errors.NewExitCode(
-				4,
-				"Failed executing script `%s`: shell script `%s`\nExit code: %v",
+		beat.Stop()
+		for _, line := range status.Stdout {
+			forward("stdout", line)
+		}
+		for _, line := range status.Stderr {
+			forward("stderr", line)
+		}
+		if ctx.Err() != nil {
+			return 0, errors.NewCancellation(ctx)
+		}
+		if status.Error != nil {
+			return status.Exit, fmt.Errorf("run shell command: %w", status.Error)
+		}
+		if finding, found := secrets.First(); found && context.ScriptContext.SecretDetection.Mode == SecretDetectionFail {
+			return status.Exit, secretDetectedError(context, finding)
+		}
+		return status.Exit, nil
+	case <-ctx.Done():
+		// wait for the stopped command such that the output goroutine is
+		// drained before returning. Output written while it cleans up during
+		// its grace period is forwarded as well.
+		select {
+		case status := <-statusChan:
+			<-outputReadCompleted
+			for _, line := range status.Stdout {
+				forward("stdout", line)
+			}
+			for _, line := range status.Stderr {
+				forward("stderr", line)
+			}
+		case <-time.After(gracePeriod + stopWaitTimeout):
+			context.ScriptContext.Project.UI.Verboseln(
+				"Script '%s' did not stop within %s",
 				context.ScriptContext.ScriptName,
-				context.Action.Shell,
-				status.Exit,
+				gracePeriod+stopWaitTimeout,
 			)
 		}
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
+		return 0, errors.NewCancellation(ctx)
+	}
+}
+
+// shellCommand returns the arguments, environment and working directory of the
+// command running script. Scripts of docker actions are run in a container,
+// scripts of PowerShell actions with PowerShell and all other scripts with the
+// shell of the action, a POSIX shell by default.
+func shellCommand(
+	ctx context.Context,
+	context ActionExecutionContext,
+	script string,
+	elevated bool,
+) ([]string, []string, string, error) {
+	if context.Action.Docker != nil {
+		return dockerCommand(ctx, context, script)
+	}
+	if context.Action.PowerShell != "" {
+		return powerShellCommand(ctx, context, script)
+	}
+	if shell, native := nativeShell(context); native {
+		if shell == config.ShellCmd {
+			return cmdCommand(ctx, context, script)
+		}
+		return powerShellCommand(ctx, context, script)
+	}
+	merge, err := mergeStderr(context)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if merge {
+		// redirecting in the shell itself keeps the lines in the order they are
+		// written as they share a single pipe
+		script = "exec 2>&1; " + script
+	}
+
+	workDir, err := actionWorkingDirectory(context)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	cd, cdEnv, err := changeDirectory(workDir)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	env, err := shellEnvironment(context)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	env = append(env, telemetryEnvironment(ctx)...)
+	env = append(env, cdEnv...)
+	var prefix []string
+	if elevated {
+		prefix, err = elevate(context, env)
+		if err != nil {
+			return nil, nil, "", err
+		}
 	}
+
+	interpreter, err := shellInterpreter(context)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	cmdArgs := append(prefix,
+		interpreter,
+		"-c",
+		fmt.Sprintf("%s; %s", cd, script),
+	)
+	return cmdArgs, env, "", nil
+}
+
+// stopWaitTimeout is how long a stopped shell command is waited for after its
+// grace period before giving up on it
+const stopWaitTimeout = 10 * time.Second
+
+// defaultInterpreter is the shell running shell actions without an
+// interpreter
+const defaultInterpreter = "sh"
+
+// shellInterpreter returns the shell running the action. It is the
+// interpreter of the action or bash if it is the shell of the action. It must
+// be available on PATH.
+func shellInterpreter(context ActionExecutionContext) (string, error) {
+	interpreter := context.Action.Interpreter
+	if interpreter == "" {
+		shell, err := actionShell(context)
+		if err != nil {
+			return "", err
+		}
+		if shell != config.ShellBash {
+			return defaultInterpreter, nil
+		}
+		interpreter = shell
+	}
+	_, err := exec.LookPath(interpreter)
+	if err != nil {
+		return "", errors.NewExitCode(
+			4,
+			"Interpreter '%s' of script `%s` was not found on PATH",
+			interpreter,
+			context.ScriptContext.ScriptName,
+		)
+	}
+	return interpreter, nil
+}
+
+// Output modes of shell actions
+const (
+	OutputStreaming = "streaming"
+	OutputBuffered  = "buffered"
+)
+
+// bufferedOutput returns whether the output of the action should be buffered
+// until it completes instead of streamed. The mode of the action takes
+// precedence over the global SHUTTLE_SHELL_OUTPUT mode.
+func bufferedOutput(context ActionExecutionContext) (bool, error) {
+	mode := context.Action.Output
+	if mode == "" {
+		mode = os.Getenv("SHUTTLE_SHELL_OUTPUT")
+	}
+	switch mode {
+	case "", OutputStreaming:
+		return false, nil
+	case OutputBuffered:
+		return true, nil
+	default:
+		return false, errors.NewExitCode(
+			1,
+			"Output mode '%s' of script `%s` is invalid: must be one of '%s' or '%s'",
+			mode,
+			context.ScriptContext.ScriptName,
+			OutputStreaming,
+			OutputBuffered,
+		)
+	}
+}
+
+// mergeStderr returns whether stderr of the action should be merged into
+// stdout. Merging is enabled by the action or globally with
+// SHUTTLE_SHELL_MERGE_STDERR.
+func mergeStderr(context ActionExecutionContext) (bool, error) {
+	if context.Action.MergeStderr {
+		return true, nil
+	}
+	raw := os.Getenv("SHUTTLE_SHELL_MERGE_STDERR")
+	if raw == "" {
+		return false, nil
+	}
+	merge, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, errors.NewExitCode(
+			1,
+			"SHUTTLE_SHELL_MERGE_STDERR value '%s' is invalid: must be true or false",
+			raw,
+		)
+	}
+	return merge, nil
+}
+
+// telemetryEnvironment returns the environment variables correlating actions
+// with the shuttle invocation. SHUTTLE_CONTEXT_ID is shared with nested shuttle
+// invocations while SHUTTLE_RUN_ID is unique to this invocation. TRACEPARENT
+// makes spans of nested invocations children of the span of the script when
+// OpenTelemetry spans are exported.
+func telemetryEnvironment(ctx context.Context) []string {
+	env := []string{
+		fmt.Sprintf("SHUTTLE_CONTEXT_ID=%s", telemetry.ContextIDFrom(ctx)),
+		fmt.Sprintf("SHUTTLE_RUN_ID=%s", telemetry.RunIDFrom(ctx)),
+	}
+	if traceParent := telemetry.TraceParent(ctx); traceParent != "" {
+		env = append(env, fmt.Sprintf("TRACEPARENT=%s", traceParent))
+	}
+	return env
 }
 
-func setupCommandEnvironmentVariables(execCmd *cmd.Cmd, context ActionExecutionContext) {
+// shellEnvironment returns the environment variables available to shell
+// actions. Variables of env files are overridden by secrets and the arguments
+// of the script.
+func shellEnvironment(context ActionExecutionContext) ([]string, error) {
 	shuttlePath, _ := filepath.Abs(filepath.Dir(os.Args[0]))
 
-	execCmd.Env = os.Environ()
-	for name, value := range context.ScriptContext.Args {
-		execCmd.Env = append(execCmd.Env, fmt.Sprintf("%s=%s", name, value))
+	envFileEnv, err := envFileEnvironment(context)
+	if err != nil {
+		return nil, err
+	}
+	env, err := inheritedEnvironment(context)
+	if err != nil {
+		return nil, err
+	}
+	env = append(env, envFileEnv...)
+	env = append(env, secretEnvironment(context)...)
+	env = append(env, argumentEnvironment(context)...)
+	for name, value := range context.ScriptContext.Outputs {
+		env = append(env, fmt.Sprintf("%s=%s", name, value))
 	}
-	execCmd.Env = append(
-		execCmd.Env,
-		fmt.Sprintf("plan=%s", context.ScriptContext.Project.LocalPlanPath),
+	env = append(
+		env,
+		fmt.Sprintf("plan=%s", context.PlanPath()),
 	)
-	execCmd.Env = append(
-		execCmd.Env,
+	env = append(
+		env,
+		fmt.Sprintf("SHUTTLE_PLAN_SOURCE=%s", context.ScriptContext.Project.PlanSourcePath),
+	)
+	env = append(
+		env,
 		fmt.Sprintf("tmp=%s", context.ScriptContext.Project.TempDirectoryPath),
 	)
-	execCmd.Env = append(
-		execCmd.Env,
+	env = append(
+		env,
 		fmt.Sprintf("project=%s", context.ScriptContext.Project.ProjectPath),
 	)
+	env = append(
+		env,
+		fmt.Sprintf("%s=%s", config.ProfileVariable, context.ScriptContext.Project.Config.Profile),
+	)
+	env = append(
+		env,
+		fmt.Sprintf("SHUTTLE_ACTION_TMP=%s", context.TempDirectoryPath()),
+	)
+	if context.outputFile != "" {
+		outputFile := context.outputFile
+		if !nativePaths(context) {
+			outputFile = shellPath(outputFile)
+		}
+		env = append(env, fmt.Sprintf("%s=%s", outputFileEnv, outputFile))
+	}
+	if context.contextFile != "" {
+		contextFile := context.contextFile
+		if !nativePaths(context) {
+			contextFile = shellPath(contextFile)
+		}
+		env = append(env, fmt.Sprintf("%s=%s", contextFileEnv, contextFile))
+	}
 	// TODO: Add project path as a shuttle specific ENV
-	execCmd.Env = append(
-		execCmd.Env,
-		fmt.Sprintf("PATH=%s", shuttlePath+string(os.PathListSeparator)+os.Getenv("PATH")),
+	env = append(
+		env,
+		fmt.Sprintf("PATH=%s", searchPath(context, shuttlePath, !nativePaths(context))),
 	)
-	execCmd.Env = append(
-		execCmd.Env,
+	env = append(
+		env,
 		fmt.Sprintf(
 			"SHUTTLE_PLANS_ALREADY_VALIDATED=%s",
 			context.ScriptContext.Project.LocalPlanPath,
 		),
 	)
-	execCmd.Env = append(
-		execCmd.Env,
+	env = append(
+		env,
 		"SHUTTLE_INTERACTIVE=default",
 	)
+	env = append(
+		env,
+		fmt.Sprintf(
+			"SHUTTLE_SELECTED_ACTIONS=%s",
+			strings.Join(context.ScriptContext.SelectedScripts, " "),
+		),
+	)
+	return env, nil
 }
@@ -0,0 +1,130 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_interpreter(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not available")
+	}
+
+	tt := []struct {
+		name        string
+		interpreter string
+		shell       string
+		stdout      string
+		err         string
+	}{
+		{
+			name:   "defaults to sh",
+			shell:  `echo "$0"`,
+			stdout: "sh\n",
+		},
+		{
+			name:        "bash",
+			interpreter: "bash",
+			shell:       `set -o pipefail; words=(a b); [[ ${#words[@]} == 2 ]] && echo "$0 ${words[1]}"`,
+			stdout:      "bash b\n",
+		},
+		{
+			name:        "missing interpreter",
+			interpreter: "shuttle-missing-shell",
+			shell:       "echo main",
+			err:         "exit code 4 - Interpreter 'shuttle-missing-shell' of script `test` was not found on PATH",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(stdout, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{
+							{Shell: tc.shell, Interpreter: tc.interpreter},
+						},
+					},
+				},
+			}, "test", nil, true)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.stdout, stdout.String())
+		})
+	}
+}
+
+func TestExecute_preserveExitCode(t *testing.T) {
+	tt := []struct {
+		name     string
+		preserve bool
+		err      string
+	}{
+		{
+			name:     "fixed",
+			preserve: false,
+			err:      "exit code 4 - Failed executing script `test`: shell script `exit 42`\nExit code: 42",
+		},
+		{
+			name:     "preserved",
+			preserve: true,
+			err:      "exit code 42 - Failed executing script `test`: shell script `exit 42`\nExit code: 42",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{{Shell: "exit 42"}},
+					},
+				},
+			}, "test", nil, true, WithPreserveExitCode(tc.preserve))
+
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestActionFailureExitCode(t *testing.T) {
+	tt := []struct {
+		name     string
+		preserve bool
+		exitCode int
+		output   int
+	}{
+		{name: "not preserved", preserve: false, exitCode: 42, output: 4},
+		{name: "preserved", preserve: true, exitCode: 42, output: 42},
+		{name: "largest", preserve: true, exitCode: 255, output: 255},
+		{name: "too large", preserve: true, exitCode: 3221225477, output: 255},
+		{name: "signalled", preserve: true, exitCode: -1, output: 4},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			output := actionFailureExitCode(ActionExecutionContext{
+				ScriptContext: ScriptExecutionContext{PreserveExitCode: tc.preserve},
+			}, tc.exitCode)
+
+			assert.Equal(t, tc.output, output)
+		})
+	}
+}
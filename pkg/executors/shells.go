@@ -0,0 +1,117 @@
+package executors
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// cmdInterpreter is the interpreter of actions run with the cmd shell
+const cmdInterpreter = "cmd.exe"
+
+// actionShell returns the shell running the shell snippet of the action. The
+// shell of the script takes precedence over the shell of shuttle.yaml.
+func actionShell(context ActionExecutionContext) (string, error) {
+	shell := context.ScriptContext.Script.Shell
+	source := "script `" + context.ScriptContext.ScriptName + "`"
+	if shell == "" {
+		shell = context.ScriptContext.Project.Config.Shell
+		source = "shuttle.yaml"
+	}
+	switch shell {
+	case "":
+		return config.ShellSh, nil
+	case config.ShellSh, config.ShellBash, config.ShellPwsh, config.ShellCmd:
+		return shell, nil
+	default:
+		return "", errors.NewExitCode(
+			1,
+			"Shell '%s' of %s is invalid: must be one of '%s', '%s', '%s' or '%s'",
+			shell,
+			source,
+			config.ShellSh,
+			config.ShellBash,
+			config.ShellPwsh,
+			config.ShellCmd,
+		)
+	}
+}
+
+// nativeShell returns the shell running the shell snippet of the action if it
+// is not a POSIX shell, ie. pwsh or cmd. An interpreter of the action takes
+// precedence over the shell and docker actions are run in their container.
+func nativeShell(context ActionExecutionContext) (string, bool) {
+	if context.Action.Interpreter != "" || context.Action.Docker != nil || context.Action.PowerShell != "" {
+		return "", false
+	}
+	shell, err := actionShell(context)
+	if err != nil || (shell != config.ShellPwsh && shell != config.ShellCmd) {
+		return "", false
+	}
+	return shell, true
+}
+
+// nativePaths returns true if the action is run by a shell understanding
+// native paths which are then not converted with shellPath.
+func nativePaths(context ActionExecutionContext) bool {
+	if context.Action.PowerShell != "" {
+		return true
+	}
+	_, native := nativeShell(context)
+	return native
+}
+
+// validateActionShell returns an error if the shell of the action is invalid
+// or does not support the options of the action.
+func validateActionShell(context ActionExecutionContext) error {
+	if _, err := actionShell(context); err != nil {
+		return err
+	}
+	shell, native := nativeShell(context)
+	if !native {
+		return nil
+	}
+	option := ""
+	switch {
+	case context.Action.Sudo:
+		option = "sudo"
+	case context.Action.Background:
+		option = "background"
+	default:
+		return nil
+	}
+	return errors.NewExitCode(
+		1,
+		"Action %d of script `%s` cannot use %s as it is run with shell %s",
+		context.ActionIndex,
+		context.ScriptContext.ScriptName,
+		option,
+		shell,
+	)
+}
+
+// cmdCommand returns the command running script with cmd from the working
+// directory of the action. Like PowerShell actions paths are kept in their
+// native format.
+func cmdCommand(ctx context.Context, context ActionExecutionContext, script string) ([]string, []string, string, error) {
+	workDir, err := actionWorkingDirectory(context)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	merge, err := mergeStderr(context)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if merge {
+		script = fmt.Sprintf("(%s) 2>&1", script)
+	}
+	env, err := shellEnvironment(context)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	env = append(env, telemetryEnvironment(ctx)...)
+	cmdArgs := []string{cmdInterpreter, "/d", "/s", "/c", script}
+	return cmdArgs, env, workDir, nil
+}
@@ -0,0 +1,146 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestActionShell(t *testing.T) {
+	tt := []struct {
+		name    string
+		project string
+		script  string
+		shell   string
+		err     string
+	}{
+		{name: "default", shell: config.ShellSh},
+		{name: "project", project: config.ShellCmd, shell: config.ShellCmd},
+		{name: "script takes precedence", project: config.ShellCmd, script: config.ShellPwsh, shell: config.ShellPwsh},
+		{name: "invalid project", project: "fish", err: "exit code 1 - Shell 'fish' of shuttle.yaml is invalid: must be one of 'sh', 'bash', 'pwsh' or 'cmd'"},
+		{name: "invalid script", script: "zsh", err: "exit code 1 - Shell 'zsh' of script `test` is invalid: must be one of 'sh', 'bash', 'pwsh' or 'cmd'"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			shell, err := actionShell(ActionExecutionContext{
+				ScriptContext: ScriptExecutionContext{
+					ScriptName: "test",
+					Script:     config.ShuttlePlanScript{Shell: tc.script},
+					Project: config.ShuttleProjectContext{
+						Config: config.ShuttleConfig{Shell: tc.project},
+					},
+				},
+			})
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.shell, shell)
+		})
+	}
+}
+
+func TestShellCommand_shell(t *testing.T) {
+	fakeTool(t, "pwsh", "")
+	projectPath := t.TempDir()
+	newContext := func(shell string, action config.ShuttleAction) ActionExecutionContext {
+		return ActionExecutionContext{
+			ScriptContext: ScriptExecutionContext{
+				ScriptName: "test",
+				Script:     config.ShuttlePlanScript{Shell: shell},
+				Project:    config.ShuttleProjectContext{ProjectPath: projectPath},
+			},
+			Action: action,
+		}
+	}
+
+	t.Run("cmd", func(t *testing.T) {
+		cmdArgs, _, dir, err := shellCommand(context.Background(), newContext(config.ShellCmd, config.ShuttleAction{Shell: `echo "hello"`}), `echo "hello"`, false)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"cmd.exe", "/d", "/s", "/c", `echo "hello"`}, cmdArgs)
+		assert.Equal(t, projectPath, dir)
+	})
+
+	t.Run("pwsh", func(t *testing.T) {
+		cmdArgs, _, dir, err := shellCommand(context.Background(), newContext(config.ShellPwsh, config.ShuttleAction{Shell: "Write-Output hello"}), "Write-Output hello", false)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"pwsh", "-NoProfile", "-NonInteractive", "-Command", "Write-Output hello"}, cmdArgs)
+		assert.Equal(t, projectPath, dir)
+	})
+
+	t.Run("interpreter takes precedence", func(t *testing.T) {
+		cmdArgs, _, _, err := shellCommand(context.Background(), newContext(config.ShellCmd, config.ShuttleAction{Shell: "echo hello", Interpreter: "bash"}), "echo hello", false)
+
+		require.NoError(t, err)
+		assert.Equal(t, "bash", cmdArgs[0])
+	})
+}
+
+func TestExecute_bashShell(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: t.TempDir(),
+		UI:          ui.Create(stdout, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Shell:   config.ShellBash,
+				Actions: []config.ShuttleAction{{Shell: `words=(a b); echo "${#words[@]} words"`}},
+			},
+		},
+	}, "test", nil, true)
+
+	require.NoError(t, err)
+	assert.Equal(t, "2 words\n", stdout.String())
+}
+
+func TestValidateActionShell(t *testing.T) {
+	context := ActionExecutionContext{
+		ScriptContext: ScriptExecutionContext{
+			ScriptName: "test",
+			Script:     config.ShuttlePlanScript{Shell: config.ShellCmd},
+		},
+		Action: config.ShuttleAction{Shell: "serve.cmd", Background: true},
+	}
+
+	err := validateActionShell(context)
+
+	assert.EqualError(t, err, "exit code 1 - Action 0 of script `test` cannot use background as it is run with shell cmd")
+}
+
+func TestNativePaths(t *testing.T) {
+	tt := []struct {
+		name   string
+		shell  string
+		action config.ShuttleAction
+		native bool
+	}{
+		{name: "sh", shell: config.ShellSh, action: config.ShuttleAction{Shell: "make"}, native: false},
+		{name: "bash", shell: config.ShellBash, action: config.ShuttleAction{Shell: "make"}, native: false},
+		{name: "cmd", shell: config.ShellCmd, action: config.ShuttleAction{Shell: "build.cmd"}, native: true},
+		{name: "pwsh", shell: config.ShellPwsh, action: config.ShuttleAction{Shell: "./build.ps1"}, native: true},
+		{name: "powershell action", action: config.ShuttleAction{PowerShell: "./build.ps1"}, native: true},
+		{name: "interpreter", shell: config.ShellCmd, action: config.ShuttleAction{Shell: "make", Interpreter: "bash"}, native: false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			native := nativePaths(ActionExecutionContext{
+				ScriptContext: ScriptExecutionContext{Script: config.ShuttlePlanScript{Shell: tc.shell}},
+				Action:        tc.action,
+			})
+
+			assert.Equal(t, tc.native, native)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package executors
+
+import (
+	"context"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/telemetry"
+)
+
+// traceAction records the span of an action. It is replaced in tests.
+var traceAction = telemetry.TraceAction
+
+// traceActionSpan records the span of the action of kind started at start. The
+// exit code of a failed action without an exit code of its own is the one
+// shuttle would exit with for err, eg. when it timed out or was cancelled.
+func traceActionSpan(ctx context.Context, context ActionExecutionContext, kind string, start time.Time, exitCode int, err error) {
+	err = actionTimeoutError(ctx, context, err)
+	if exitCode == 0 && err != nil {
+		exitCode = actionExitCode(err)
+	}
+	traceAction(ctx, telemetry.ActionSpan{
+		Script:   context.ScriptContext.ScriptName,
+		Action:   context.ActionIndex,
+		Kind:     kind,
+		Start:    start,
+		End:      time.Now(),
+		ExitCode: exitCode,
+	})
+}
@@ -0,0 +1,71 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/telemetry"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_actionSpan(t *testing.T) {
+	tt := []struct {
+		name     string
+		action   config.ShuttleAction
+		kind     string
+		exitCode int
+	}{
+		{
+			name:     "succeeded",
+			action:   config.ShuttleAction{Shell: "true"},
+			kind:     "shell",
+			exitCode: 0,
+		},
+		{
+			name:     "failed",
+			action:   config.ShuttleAction{Shell: "exit 3"},
+			kind:     "shell",
+			exitCode: 3,
+		},
+		{
+			name:     "timed out",
+			action:   config.ShuttleAction{Shell: "sleep 5", Timeout: "50ms"},
+			kind:     "shell",
+			exitCode: errors.ExitCodeTimeout,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var spans []telemetry.ActionSpan
+			defer func(previous func(context.Context, telemetry.ActionSpan)) { traceAction = previous }(traceAction)
+			traceAction = func(ctx context.Context, span telemetry.ActionSpan) {
+				spans = append(spans, span)
+			}
+			registry := NewRegistry(ShellExecutor)
+
+			registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: t.TempDir(),
+				UI:          ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{{Shell: "true"}, tc.action},
+					},
+				},
+			}, "test", nil, true)
+
+			require.Len(t, spans, 2)
+			span := spans[1]
+			assert.Equal(t, "test", span.Script)
+			assert.Equal(t, 1, span.Action)
+			assert.Equal(t, tc.kind, span.Kind)
+			assert.Equal(t, tc.exitCode, span.ExitCode)
+			assert.False(t, span.End.Before(span.Start), "span must not end before it starts")
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package executors
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// Stdin modes of shell actions
+const (
+	// StdinInherit connects the stdin of shuttle to the action
+	StdinInherit = "inherit"
+	// StdinNone runs the action without stdin
+	StdinNone = "none"
+)
+
+// WithStdin connects in to the stdin of shell actions, eg. os.Stdin for
+// actions to prompt the user or read piped input. Actions have no stdin
+// without it.
+func WithStdin(in io.Reader) ExecuteOption {
+	return func(c *ScriptExecutionContext) {
+		c.Stdin = in
+	}
+}
+
+// actionStdin returns the reader connected to the stdin of the action and a
+// function closing it once the action has exited or is stopped. The mode is
+// set by the action or globally with SHUTTLE_SHELL_STDIN and defaults to
+// inheriting the stdin of the script.
+func actionStdin(context ActionExecutionContext) (io.Reader, func(), error) {
+	mode, err := stdinMode(context)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mode == StdinNone {
+		return nil, func() {}, nil
+	}
+
+	in := context.ScriptContext.Stdin
+	if in == nil {
+		return nil, func() {}, nil
+	}
+	if file, ok := in.(*os.File); ok {
+		// files, eg. a terminal or a pipe, are inherited by the process
+		// directly so nothing is left to close
+		return file, func() {}, nil
+	}
+
+	// other readers are copied by shuttle through a pipe. The process would
+	// otherwise not be waited for until the reader is exhausted which may
+	// never happen.
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	go func() {
+		io.Copy(writer, in)
+		writer.Close()
+	}()
+	var once sync.Once
+	return reader, func() {
+		once.Do(func() {
+			reader.Close()
+			writer.Close()
+		})
+	}, nil
+}
+
+// stdinMode returns the stdin mode of the action. The mode of the action takes
+// precedence over the global SHUTTLE_SHELL_STDIN mode.
+func stdinMode(context ActionExecutionContext) (string, error) {
+	mode := context.Action.Stdin
+	if mode == "" {
+		mode = os.Getenv("SHUTTLE_SHELL_STDIN")
+	}
+	switch mode {
+	case "", StdinInherit:
+		return StdinInherit, nil
+	case StdinNone:
+		return StdinNone, nil
+	default:
+		return "", errors.NewExitCode(
+			1,
+			"Stdin mode '%s' of script `%s` is invalid: must be one of '%s' or '%s'",
+			mode,
+			context.ScriptContext.ScriptName,
+			StdinInherit,
+			StdinNone,
+		)
+	}
+}
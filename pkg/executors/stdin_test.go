@@ -0,0 +1,128 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_stdin(t *testing.T) {
+	tt := []struct {
+		name   string
+		mode   string
+		env    string
+		stdin  io.Reader
+		output string
+		err    string
+	}{
+		{
+			name:   "piped",
+			stdin:  strings.NewReader("foo\nbar\n"),
+			output: "got foo\nbar\n",
+		},
+		{
+			name:   "inherit",
+			mode:   StdinInherit,
+			stdin:  strings.NewReader("foo\n"),
+			output: "got foo\n",
+		},
+		{
+			name:   "none",
+			mode:   StdinNone,
+			stdin:  strings.NewReader("foo\n"),
+			output: "got \n",
+		},
+		{
+			name:   "none from environment",
+			env:    StdinNone,
+			stdin:  strings.NewReader("foo\n"),
+			output: "got \n",
+		},
+		{
+			name:   "action takes precedence over environment",
+			mode:   StdinInherit,
+			env:    StdinNone,
+			stdin:  strings.NewReader("foo\n"),
+			output: "got foo\n",
+		},
+		{
+			name:   "no stdin",
+			stdin:  nil,
+			output: "got \n",
+		},
+		{
+			name:  "invalid mode",
+			mode:  "tty",
+			stdin: strings.NewReader("foo\n"),
+			err:   "exit code 1 - Stdin mode 'tty' of script `test` is invalid: must be one of 'inherit' or 'none'",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("SHUTTLE_SHELL_STDIN", tc.env)
+			stdout := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: t.TempDir(),
+				UI:          ui.Create(stdout, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{{
+							Shell: `read line; echo "got $line"; cat`,
+							Stdin: tc.mode,
+						}},
+					},
+				},
+			}, "test", nil, true, WithStdin(tc.stdin))
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.output, stdout.String())
+		})
+	}
+}
+
+// blockingReader never returns from Read like a terminal nobody types in
+type blockingReader struct{}
+
+func (blockingReader) Read([]byte) (int, error) {
+	select {}
+}
+
+func TestExecute_stdinNotRead(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	registry := NewRegistry(ShellExecutor)
+	done := make(chan error)
+
+	go func() {
+		done <- registry.Execute(context.Background(), config.ShuttleProjectContext{
+			ProjectPath: t.TempDir(),
+			UI:          ui.Create(stdout, &bytes.Buffer{}),
+			Scripts: map[string]config.ShuttlePlanScript{
+				"test": {
+					Actions: []config.ShuttleAction{{Shell: "echo done"}},
+				},
+			},
+		}, "test", nil, true, WithStdin(blockingReader{}))
+	}()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+		assert.Equal(t, "done\n", stdout.String())
+	case <-time.After(5 * time.Second):
+		t.Fatal("script did not complete while stdin was not read")
+	}
+}
@@ -0,0 +1,42 @@
+package executors
+
+import (
+	"os"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// defaultStopGracePeriod is how long a shell command may handle the signal
+// stopping it before it is killed
+const defaultStopGracePeriod = 5 * time.Second
+
+// stopGracePeriod returns how long the shell command of the action may run
+// after it is signalled to stop before it is killed. The grace period of the
+// action takes precedence over SHUTTLE_STOP_GRACE_PERIOD which takes
+// precedence over the stopGracePeriod of shuttle.yaml.
+func stopGracePeriod(context ActionExecutionContext) (time.Duration, error) {
+	raw := context.Action.StopGracePeriod
+	name := "stopGracePeriod of script `" + context.ScriptContext.ScriptName + "`"
+	if raw == "" {
+		raw = os.Getenv("SHUTTLE_STOP_GRACE_PERIOD")
+		name = "SHUTTLE_STOP_GRACE_PERIOD"
+	}
+	if raw == "" {
+		raw = context.ScriptContext.Project.Config.StopGracePeriod
+		name = "stopGracePeriod of shuttle.yaml"
+	}
+	if raw == "" {
+		return defaultStopGracePeriod, nil
+	}
+	gracePeriod, err := time.ParseDuration(raw)
+	if err != nil || gracePeriod < 0 {
+		return 0, errors.NewExitCode(
+			1,
+			"%s value '%s' is invalid: must be a duration, eg. 5s",
+			name,
+			raw,
+		)
+	}
+	return gracePeriod, nil
+}
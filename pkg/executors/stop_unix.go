@@ -0,0 +1,83 @@
+//go:build !windows
+
+package executors
+
+import (
+	"context"
+	"syscall"
+	"time"
+
+	"github.com/go-cmd/cmd"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// processGroupPollInterval is how often a stopped process group is checked for
+// having exited
+const processGroupPollInterval = 20 * time.Millisecond
+
+// processTree is a shell command and every process started by it. Shell
+// commands are started in their own process group by go-cmd so the group
+// holds all their children unless they start a group of their own.
+type processTree struct{}
+
+func newProcessTree() (*processTree, error) {
+	return &processTree{}, nil
+}
+
+// Track adds the started execCmd to the tree. The process group is set up
+// when the command is started.
+func (t *processTree) Track(execCmd *cmd.Cmd) {}
+
+// Close releases the tree leaving processes still running alone.
+func (t *processTree) Close() {}
+
+// Stop forwards the signal cancelling ctx, or SIGTERM if it was not cancelled
+// by a signal, to the process group of execCmd. If any process of the group
+// has not exited once gracePeriod has passed the group is killed.
+func (t *processTree) Stop(ctx context.Context, execCmd *cmd.Cmd, gracePeriod time.Duration) error {
+	pid := execCmd.Status().PID
+	if pid <= 0 {
+		return execCmd.Stop()
+	}
+	signal := syscall.SIGTERM
+	if received, ok := errors.InterruptSignal(ctx); ok {
+		if s, ok := received.(syscall.Signal); ok {
+			signal = s
+		}
+	}
+	return stopProcessGroup(pid, signal, gracePeriod)
+}
+
+// stopProcessGroup sends signal to the process group pgid and waits for all
+// its processes to exit. The group is killed if any of them is still running
+// once gracePeriod has passed. The shell leading the group is not waited for
+// alone as children, eg. of docker build, often outlive it.
+func stopProcessGroup(pgid int, signal syscall.Signal, gracePeriod time.Duration) error {
+	err := syscall.Kill(-pgid, signal)
+	if err == syscall.ESRCH {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(gracePeriod)
+	for processGroupRunning(pgid) {
+		if time.Now().After(deadline) {
+			err := syscall.Kill(-pgid, syscall.SIGKILL)
+			if err == syscall.ESRCH {
+				return nil
+			}
+			return err
+		}
+		time.Sleep(processGroupPollInterval)
+	}
+	return nil
+}
+
+// processGroupRunning returns true if any process of the group pgid has not
+// exited yet.
+func processGroupRunning(pgid int) bool {
+	return syscall.Kill(-pgid, 0) != syscall.ESRCH
+}
@@ -0,0 +1,221 @@
+//go:build !windows
+
+package executors
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_stopSignal(t *testing.T) {
+	tt := []struct {
+		name        string
+		cause       error
+		trap        string
+		gracePeriod string
+		marker      string
+		err         string
+		maxTime     time.Duration
+	}{
+		{
+			name:    "cancellation sends SIGTERM",
+			trap:    `trap 'echo cleaned > "$marker"; exit 1' TERM`,
+			marker:  "cleaned\n",
+			err:     "exit code 2 - Operation cancelled",
+			maxTime: 5 * time.Second,
+		},
+		{
+			name:    "received signal is forwarded",
+			cause:   errors.NewInterrupt(syscall.SIGINT),
+			trap:    `trap 'echo interrupted > "$marker"; exit 1' INT`,
+			marker:  "interrupted\n",
+			err:     "exit code 130 - Cancelled by user",
+			maxTime: 5 * time.Second,
+		},
+		{
+			name:        "killed after grace period",
+			trap:        `trap '' TERM`,
+			gracePeriod: "200ms",
+			err:         "exit code 2 - Operation cancelled",
+			maxTime:     5 * time.Second,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			marker := filepath.Join(t.TempDir(), "marker")
+			ctx, cancel := context.WithCancelCause(context.Background())
+			defer cancel(nil)
+			go func() {
+				// let the script install its trap
+				time.Sleep(300 * time.Millisecond)
+				cancel(tc.cause)
+			}()
+			registry := NewRegistry(ShellExecutor)
+
+			start := time.Now()
+			err := registry.Execute(ctx, config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Args: []config.ShuttleScriptArgs{{Name: "marker"}},
+						Actions: []config.ShuttleAction{
+							{
+								Shell:           tc.trap + "; sleep 10",
+								StopGracePeriod: tc.gracePeriod,
+							},
+						},
+					},
+				},
+			}, "test", map[string]string{"marker": marker}, true)
+
+			assert.EqualError(t, err, tc.err)
+			assert.Less(t, time.Since(start), tc.maxTime)
+			content, _ := os.ReadFile(marker)
+			assert.Equal(t, tc.marker, string(content))
+		})
+	}
+}
+
+func TestExecute_stopForwardsOutput(t *testing.T) {
+	for _, output := range []string{OutputStreaming, OutputBuffered} {
+		t.Run(output, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				// let the script install its trap
+				time.Sleep(300 * time.Millisecond)
+				cancel()
+			}()
+			stdout := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(ctx, config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(stdout, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{
+							{
+								Shell:  `trap 'echo rolling back; sleep 0.2; echo rolled back; exit 1' TERM; echo deploying; sleep 10 & wait`,
+								Output: output,
+							},
+						},
+					},
+				},
+			}, "test", nil, true)
+
+			assert.EqualError(t, err, "exit code 2 - Operation cancelled")
+			assert.Equal(t, "deploying\nrolling back\nrolled back\n", stdout.String())
+		})
+	}
+}
+
+func TestExecute_stopProcessGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		// let the script start its child
+		time.Sleep(300 * time.Millisecond)
+		cancel()
+	}()
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(ctx, config.ShuttleProjectContext{
+		ProjectPath: ".",
+		UI:          ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Args: []config.ShuttleScriptArgs{{Name: "pid"}},
+				Actions: []config.ShuttleAction{
+					{
+						// the child ignores SIGTERM and outlives the shell
+						Shell:           `sh -c 'trap "" TERM; echo $$ > "$pid"; exec sleep 30' > /dev/null & trap 'exit 1' TERM; sleep 10`,
+						StopGracePeriod: "200ms",
+					},
+				},
+			},
+		},
+	}, "test", map[string]string{"pid": pidFile}, true)
+
+	assert.EqualError(t, err, "exit code 2 - Operation cancelled")
+	content, err := os.ReadFile(pidFile)
+	require.NoError(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return !processRunning(pid)
+	}, 2*time.Second, 20*time.Millisecond, "child of the shell is killed")
+}
+
+// processRunning returns true if pid is running and not a zombie waiting to be
+// reaped.
+func processRunning(pid int) bool {
+	if syscall.Kill(pid, 0) == syscall.ESRCH {
+		return false
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		// no procfs to tell zombies apart, eg. on macOS
+		return true
+	}
+	return !strings.Contains(string(stat), ") Z ")
+}
+
+func TestStopGracePeriod(t *testing.T) {
+	tt := []struct {
+		name        string
+		env         string
+		action      string
+		project     string
+		gracePeriod time.Duration
+		err         string
+	}{
+		{name: "default", gracePeriod: 5 * time.Second},
+		{name: "env", env: "1m", gracePeriod: time.Minute},
+		{name: "action takes precedence", env: "1m", action: "2s", gracePeriod: 2 * time.Second},
+		{name: "project", project: "30s", gracePeriod: 30 * time.Second},
+		{name: "env takes precedence over project", env: "1m", project: "30s", gracePeriod: time.Minute},
+		{name: "invalid env", env: "later", err: "exit code 1 - SHUTTLE_STOP_GRACE_PERIOD value 'later' is invalid: must be a duration, eg. 5s"},
+		{name: "invalid action", action: "-1s", err: "exit code 1 - stopGracePeriod of script `test` value '-1s' is invalid: must be a duration, eg. 5s"},
+		{name: "invalid project", project: "soon", err: "exit code 1 - stopGracePeriod of shuttle.yaml value 'soon' is invalid: must be a duration, eg. 5s"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("SHUTTLE_STOP_GRACE_PERIOD", tc.env)
+
+			gracePeriod, err := stopGracePeriod(ActionExecutionContext{
+				ScriptContext: ScriptExecutionContext{
+					ScriptName: "test",
+					Project: config.ShuttleProjectContext{
+						Config: config.ShuttleConfig{StopGracePeriod: tc.project},
+					},
+				},
+				Action: config.ShuttleAction{StopGracePeriod: tc.action},
+			})
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.gracePeriod, gracePeriod)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package executors
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-cmd/cmd"
+	"golang.org/x/sys/windows"
+)
+
+// processTree is a shell command and every process started by it. Windows has
+// no process groups to signal so the command is assigned to a job object the
+// processes it starts are part of as well.
+type processTree struct {
+	lock sync.Mutex
+	job  windows.Handle
+}
+
+func newProcessTree() (*processTree, error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &processTree{job: job}, nil
+}
+
+// Track assigns the started execCmd to the job object of the tree. Processes
+// it starts before it is assigned are not part of the tree.
+func (t *processTree) Track(execCmd *cmd.Cmd) {
+	for {
+		if pid := execCmd.Status().PID; pid > 0 {
+			t.assign(pid)
+			return
+		}
+		select {
+		case <-execCmd.Done():
+			return
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func (t *processTree) assign(pid int) {
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(pid))
+	if err != nil {
+		// the command has already exited
+		return
+	}
+	defer windows.CloseHandle(process)
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	windows.AssignProcessToJobObject(t.job, process)
+}
+
+// Close releases the job object leaving processes still running alone.
+func (t *processTree) Close() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	windows.CloseHandle(t.job)
+}
+
+// Stop terminates every process of the tree right away as Windows has no
+// signals to forward.
+func (t *processTree) Stop(_ context.Context, execCmd *cmd.Cmd, _ time.Duration) error {
+	t.lock.Lock()
+	err := windows.TerminateJobObject(t.job, 1)
+	t.lock.Unlock()
+	if err != nil {
+		return execCmd.Stop()
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package executors
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// goos is the operating system shuttle runs on. It is a variable to allow
+// tests to exercise platform specific behaviour.
+var goos = runtime.GOOS
+
+// sudoCommand returns the command used to elevate privileges of actions. It
+// defaults to sudo and can be changed with SHUTTLE_SUDO_COMMAND.
+func sudoCommand() string {
+	command := os.Getenv("SHUTTLE_SUDO_COMMAND")
+	if command == "" {
+		return "sudo"
+	}
+	return command
+}
+
+// elevate returns the command prefix running a shell action with elevated
+// privileges. Credentials are validated up front such that a password is
+// prompted for on the terminal before output of the action is streamed. When
+// not running interactively passwordless sudo is required. On Windows actions
+// run without elevation.
+func elevate(context ActionExecutionContext, env []string) ([]string, error) {
+	if !context.Action.Sudo {
+		return nil, nil
+	}
+	ui := context.ScriptContext.Project.UI
+	if goos == "windows" {
+		ui.Verboseln("Ignoring sudo of script `%s` on windows", context.ScriptContext.ScriptName)
+		return nil, nil
+	}
+
+	sudo := sudoCommand()
+	if context.ScriptContext.DryRun {
+		// privileges are only needed to actually run the command
+		return sudoPrefix(sudo, env), nil
+	}
+	if context.ScriptContext.Interactive {
+		ui.Flush()
+		validate := exec.Command(sudo, "-v")
+		validate.Stdin = os.Stdin
+		validate.Stdout = os.Stdout
+		validate.Stderr = os.Stderr
+		err := validate.Run()
+		if err != nil {
+			return nil, errors.NewExitCode(
+				4,
+				"Failed to obtain privileges with '%s' for script `%s`: %v",
+				sudo,
+				context.ScriptContext.ScriptName,
+				err,
+			)
+		}
+	} else {
+		err := exec.Command(sudo, "-n", "true").Run()
+		if err != nil {
+			return nil, errors.NewExitCode(
+				4,
+				"Action %d of script `%s` requires '%s' which needs a password when not running interactively: %v\n\nRun with --interactive to enter a password or allow passwordless %s.",
+				context.ActionIndex,
+				context.ScriptContext.ScriptName,
+				sudo,
+				err,
+				sudo,
+			)
+		}
+	}
+
+	return sudoPrefix(sudo, env), nil
+}
+
+// sudoPrefix returns the arguments prefixing a command to run it with sudo.
+// sudo commonly resets PATH so it is passed explicitly for the shuttle binary
+// and tools of the plan to be found.
+func sudoPrefix(sudo string, env []string) []string {
+	return []string{sudo, "-n", "-E", "env", "PATH=" + environmentValue(env, "PATH")}
+}
+
+// environmentValue returns the last value of name in env as later entries
+// take precedence.
+func environmentValue(env []string, name string) string {
+	value := ""
+	for _, entry := range env {
+		if strings.HasPrefix(entry, name+"=") {
+			value = strings.TrimPrefix(entry, name+"=")
+		}
+	}
+	return value
+}
@@ -0,0 +1,106 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSudo writes a sudo replacement to a temporary directory that records its
+// arguments to the returned log file and runs the wrapped command.
+func fakeSudo(t *testing.T, exitCode string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "sudo.log")
+	sudo := filepath.Join(dir, "sudo")
+	err := os.WriteFile(sudo, []byte(`#!/bin/sh
+echo "$1 $2" >> '`+log+`'
+[ "`+exitCode+`" = "0" ] || exit `+exitCode+`
+while [ "${1#-}" != "$1" ]; do shift; done
+[ "$1" = "true" ] && exit 0
+[ $# -eq 0 ] && exit 0
+exec "$@"
+`), 0o755)
+	require.NoError(t, err)
+	return sudo, log
+}
+
+func TestExecute_sudo(t *testing.T) {
+	tt := []struct {
+		name        string
+		exitCode    string
+		interactive bool
+		goos        string
+		script      string
+		log         string
+		err         string
+	}{
+		{
+			name:     "passwordless",
+			exitCode: "0",
+			goos:     "linux",
+			script:   `test "$SHUTTLE_SELECTED_ACTIONS" = "test"`,
+			log:      "-n true\n-n -E\n",
+		},
+		{
+			name:        "interactive",
+			exitCode:    "0",
+			interactive: true,
+			goos:        "linux",
+			script:      "true",
+			log:         "-v \n-n -E\n",
+		},
+		{
+			name:     "password required",
+			exitCode: "1",
+			goos:     "linux",
+			script:   "true",
+			log:      "-n true\n",
+			err:      "exit code 4 - Action 0 of script `test` requires '<sudo>' which needs a password when not running interactively: exit status 1\n\nRun with --interactive to enter a password or allow passwordless <sudo>.",
+		},
+		{
+			name:     "windows",
+			exitCode: "1",
+			goos:     "windows",
+			script:   "true",
+			log:      "",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			sudo, log := fakeSudo(t, tc.exitCode)
+			t.Setenv("SHUTTLE_SUDO_COMMAND", sudo)
+			defer func(previous string) { goos = previous }(goos)
+			goos = tc.goos
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{
+							{Shell: tc.script, Sudo: true},
+						},
+					},
+				},
+			}, "test", nil, true, WithInteractive(tc.interactive))
+
+			if tc.err != "" {
+				assert.EqualError(t, err, strings.ReplaceAll(tc.err, "<sudo>", sudo))
+			} else {
+				assert.NoError(t, err)
+			}
+			content, _ := os.ReadFile(log)
+			assert.Equal(t, tc.log, string(content))
+		})
+	}
+}
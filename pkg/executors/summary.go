@@ -0,0 +1,149 @@
+package executors
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/config"
+)
+
+// summaryOutputLines is the number of output lines kept for each action in a
+// run summary
+const summaryOutputLines = 20
+
+// ActionStatus is the outcome of an action
+type ActionStatus string
+
+const (
+	ActionStatusPassed  ActionStatus = "passed"
+	ActionStatusFailed  ActionStatus = "failed"
+	ActionStatusSkipped ActionStatus = "skipped"
+)
+
+// RunSummary describes the outcome of executing a script
+type RunSummary struct {
+	// RunID identifies the shuttle invocation, see SHUTTLE_RUN_ID
+	RunID    string
+	Script   string
+	Duration time.Duration
+	Actions  []ActionResult
+}
+
+// ActionResult describes the outcome of a single action in a script
+type ActionResult struct {
+	Index       int
+	Description string
+	Status      ActionStatus
+	Duration    time.Duration
+	// Err is the error returned by the action if it failed
+	Err error
+	// ExitCode is the exit code shuttle would exit with because of Err
+	ExitCode int
+	// Output holds the last output lines of the action and Stdout and Stderr
+	// those of them written to each stream
+	Output []string
+	Stdout []string
+	Stderr []string
+}
+
+// WithSummary records the outcome of the script and its actions into summary.
+func WithSummary(summary *RunSummary) ExecuteOption {
+	return func(c *ScriptExecutionContext) {
+		c.Summary = summary
+	}
+}
+
+// Failed returns true if any action of the summary failed.
+func (s *RunSummary) Failed() bool {
+	for _, action := range s.Actions {
+		if action.Status == ActionStatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// skipAction records the action at index as skipped. It is a no-op on a nil
+// summary.
+func (s *RunSummary) skipAction(index int, action config.ShuttleAction) {
+	if s == nil {
+		return
+	}
+	s.Actions = append(s.Actions, ActionResult{
+		Index:       index,
+		Description: actionDescription(action),
+		Status:      ActionStatusSkipped,
+	})
+}
+
+// actionDescription returns a short human readable description of an action.
+func actionDescription(action config.ShuttleAction) string {
+	switch {
+	case action.Shell != "":
+		return action.Shell
+	case action.PowerShell != "":
+		return action.PowerShell
+	case action.Docker != nil:
+		return "docker " + action.Docker.Image
+	case action.Task != "":
+		return "task " + action.Task
+	case action.Wasm != "":
+		return "wasm " + action.Wasm
+	case action.Dockerfile != "":
+		return "dockerfile " + action.Dockerfile
+	default:
+		return ""
+	}
+}
+
+// outputTail keeps the last lines written to it. A nil tail discards all
+// lines.
+type outputTail struct {
+	mu    sync.Mutex
+	size  int
+	lines []outputLine
+}
+
+type outputLine struct {
+	stream string
+	line   string
+}
+
+func newOutputTail(size int) *outputTail {
+	return &outputTail{size: size}
+}
+
+// Add keeps line written to stream, ie. stdout or stderr.
+func (t *outputTail) Add(stream, line string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lines = append(t.lines, outputLine{stream: stream, line: line})
+	if len(t.lines) > t.size {
+		t.lines = t.lines[len(t.lines)-t.size:]
+	}
+}
+
+// Lines returns the kept lines of all streams.
+func (t *outputTail) Lines() []string {
+	return t.StreamLines("")
+}
+
+// StreamLines returns the kept lines written to stream, or of all streams if
+// stream is empty.
+func (t *outputTail) StreamLines(stream string) []string {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var lines []string
+	for _, line := range t.lines {
+		if stream == "" || line.stream == stream {
+			lines = append(lines, line.line)
+		}
+	}
+	return lines
+}
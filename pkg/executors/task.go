@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/go-cmd/cmd"
 	"github.com/lunarway/shuttle/pkg/config"
@@ -26,24 +27,94 @@ func executeTask(ctx context.Context, ui *ui.UI, context ActionExecutionContext)
 		args = append(args, value)
 	}
 
-	err := executer.Run(ctx, ui, &context.ScriptContext.Project, fmt.Sprintf("%s/shuttle.yaml", context.ScriptContext.Project.ProjectPath), args...)
+	shuttlePath := fmt.Sprintf("%s/shuttle.yaml", context.ScriptContext.Project.ProjectPath)
+	if context.ScriptContext.DryRun {
+		printDryRun(context, append([]string{"task"}, redactTaskArgs(args)...), nil)
+		return printTaskDryRun(ctx, context, shuttlePath)
+	}
+
+	// the task inherits the environment of shuttle like shell actions
+	env, err := inheritedEnvironment(context)
 	if err != nil {
 		return err
 	}
+	// struct results of the task are written to the output file like outputs
+	// of shell actions
+	if context.ScriptContext.Outputs != nil {
+		outputFile, remove, err := createOutputFile(context)
+		if err != nil {
+			return fmt.Errorf("create output file: %w", err)
+		}
+		defer remove()
+		context.outputFile = outputFile
+		env = append(env, fmt.Sprintf("%s=%s", outputFileEnv, outputFile))
+	}
+	if context.contextFile != "" {
+		env = append(env, fmt.Sprintf("%s=%s", contextFileEnv, context.contextFile))
+	}
+
+	// the task writes directly to the terminal
+	ui.Flush()
+	start := time.Now()
+	err = executer.RunWithEnv(ctx, ui, &context.ScriptContext.Project, shuttlePath, env, args...)
+	traceActionSpan(ctx, context, "task", start, 0, err)
+	if err != nil {
+		return err
+	}
+	if context.outputFile != "" {
+		if err := readOutputs(context, context.outputFile); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
-func setupTaskCommandEnvironmentVariables(execCmd *cmd.Cmd, context ActionExecutionContext) {
+// printTaskDryRun prints the golang action binaries the task would be run by
+// in the order they are tried. Binaries are not compiled in a dry run.
+func printTaskDryRun(ctx context.Context, context ActionExecutionContext, shuttlePath string) error {
+	binaries, err := executer.DryRun(ctx, &context.ScriptContext.Project, shuttlePath)
+	if err != nil {
+		return err
+	}
+	for _, binary := range binaries {
+		status := binary.Source
+		if !binary.Compiled {
+			status += ", compiled when run"
+		}
+		context.ScriptContext.Project.UI.Output("  binary: %s (%s)", binary.Path, status)
+	}
+	return nil
+}
+
+// redactTaskArgs returns args of a task with the values of arguments named
+// like secrets redacted.
+func redactTaskArgs(args []string) []string {
+	redacted := append([]string(nil), args...)
+	for i := 1; i+1 < len(redacted); i += 2 {
+		if secretVariableName.MatchString(redacted[i]) {
+			redacted[i+1] = redactedValue
+		}
+	}
+	return redacted
+}
+
+func setupTaskCommandEnvironmentVariables(execCmd *cmd.Cmd, context ActionExecutionContext) error {
 	shuttlePath, _ := filepath.Abs(filepath.Dir(os.Args[0]))
 
-	execCmd.Env = os.Environ()
-	for name, value := range context.ScriptContext.Args {
-		execCmd.Env = append(execCmd.Env, fmt.Sprintf("%s=%s", name, value))
+	inherited, err := inheritedEnvironment(context)
+	if err != nil {
+		return err
 	}
+	execCmd.Env = append(inherited, secretEnvironment(context)...)
+	execCmd.Env = append(execCmd.Env, argumentEnvironment(context)...)
 	execCmd.Env = append(
 		execCmd.Env,
-		fmt.Sprintf("plan=%s", context.ScriptContext.Project.LocalPlanPath),
+		fmt.Sprintf("plan=%s", context.PlanPath()),
+	)
+	execCmd.Env = append(
+		execCmd.Env,
+		fmt.Sprintf("SHUTTLE_PLAN_SOURCE=%s", context.ScriptContext.Project.PlanSourcePath),
 	)
 	execCmd.Env = append(
 		execCmd.Env,
@@ -53,10 +124,14 @@ func setupTaskCommandEnvironmentVariables(execCmd *cmd.Cmd, context ActionExecut
 		execCmd.Env,
 		fmt.Sprintf("project=%s", context.ScriptContext.Project.ProjectPath),
 	)
+	execCmd.Env = append(
+		execCmd.Env,
+		fmt.Sprintf("%s=%s", config.ProfileVariable, context.ScriptContext.Project.Config.Profile),
+	)
 	// TODO: Add project path as a shuttle specific ENV
 	execCmd.Env = append(
 		execCmd.Env,
-		fmt.Sprintf("PATH=%s", shuttlePath+string(os.PathListSeparator)+os.Getenv("PATH")),
+		fmt.Sprintf("PATH=%s", searchPath(context, shuttlePath, false)),
 	)
 	execCmd.Env = append(
 		execCmd.Env,
@@ -65,4 +140,5 @@ func setupTaskCommandEnvironmentVariables(execCmd *cmd.Cmd, context ActionExecut
 			context.ScriptContext.Project.LocalPlanPath,
 		),
 	)
+	return nil
 }
@@ -0,0 +1,74 @@
+package executors
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// errActionTimeout is the cancellation cause of actions exceeding their
+// timeout.
+var errActionTimeout = stderrors.New("action timed out")
+
+// withActionTimeout returns a context cancelled once the timeout of the action
+// is exceeded. Actions without a timeout get ctx as is. It is applied before
+// dispatching to the executor of the action so the timeout bounds actions of
+// any kind.
+func withActionTimeout(
+	ctx context.Context,
+	actionContext ActionExecutionContext,
+) (context.Context, context.CancelFunc, error) {
+	timeout, err := actionTimeout(actionContext)
+	if err != nil {
+		return nil, nil, err
+	}
+	if timeout == 0 {
+		return ctx, func() {}, nil
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, errActionTimeout)
+	return ctx, cancel, nil
+}
+
+// actionTimeout returns the timeout of the action or zero if it has none.
+func actionTimeout(actionContext ActionExecutionContext) (time.Duration, error) {
+	raw := actionContext.Action.Timeout
+	if raw == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(raw)
+	if err != nil || timeout <= 0 {
+		return 0, errors.NewExitCode(
+			1,
+			"Action %d of script `%s` has an invalid timeout '%s': must be a positive duration, eg. 30s",
+			actionContext.ActionIndex,
+			actionContext.ScriptContext.ScriptName,
+			raw,
+		)
+	}
+	if actionContext.Action.Background {
+		return 0, errors.NewExitCode(
+			1,
+			"Action %d of script `%s` cannot have a timeout as it runs in the background",
+			actionContext.ActionIndex,
+			actionContext.ScriptContext.ScriptName,
+		)
+	}
+	return timeout, nil
+}
+
+// actionTimeoutError returns a timeout error if err was caused by the action
+// exceeding its timeout. Otherwise err is returned as is.
+func actionTimeoutError(ctx context.Context, actionContext ActionExecutionContext, err error) error {
+	if err == nil || !stderrors.Is(context.Cause(ctx), errActionTimeout) {
+		return err
+	}
+	return errors.NewExitCode(
+		errors.ExitCodeTimeout,
+		"Action %d of script `%s` timed out after %s",
+		actionContext.ActionIndex,
+		actionContext.ScriptContext.ScriptName,
+		actionContext.Action.Timeout,
+	)
+}
@@ -0,0 +1,97 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_timeout(t *testing.T) {
+	tt := []struct {
+		name    string
+		action  config.ShuttleAction
+		stdout  string
+		err     string
+		maxTime time.Duration
+	}{
+		{
+			name:    "sleeping script is killed",
+			action:  config.ShuttleAction{Shell: "echo started; sleep 10", Timeout: "200ms"},
+			stdout:  "started\n",
+			err:     "exit code 124 - Action 0 of script `test` timed out after 200ms",
+			maxTime: 5 * time.Second,
+		},
+		{
+			name:    "preflight is bounded",
+			action:  config.ShuttleAction{Shell: "echo main", Timeout: "200ms", Preflight: []config.ShuttlePreflightCheck{{Shell: "sleep 10"}}},
+			err:     "exit code 124 - Action 0 of script `test` timed out after 200ms",
+			maxTime: 5 * time.Second,
+		},
+		{
+			name:    "completes within timeout",
+			action:  config.ShuttleAction{Shell: "echo done", Timeout: "10s"},
+			stdout:  "done\n",
+			maxTime: 5 * time.Second,
+		},
+		{
+			name:    "invalid timeout",
+			action:  config.ShuttleAction{Shell: "echo main", Timeout: "soon"},
+			err:     "exit code 1 - Action 0 of script `test` has an invalid timeout 'soon': must be a positive duration, eg. 30s",
+			maxTime: time.Second,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			start := time.Now()
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(stdout, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{tc.action},
+					},
+				},
+			}, "test", nil, true)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.stdout, stdout.String())
+			assert.Less(t, time.Since(start), tc.maxTime)
+		})
+	}
+}
+
+func TestExecute_timeoutOfAnyExecutor(t *testing.T) {
+	// blocking stands in for an executor, eg. of tasks, honouring ctx
+	blocking := func(action config.ShuttleAction) (Executor, bool) {
+		return func(ctx context.Context, _ *ui.UI, _ ActionExecutionContext) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}, action.Task != ""
+	}
+	registry := NewRegistry(blocking)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: ".",
+		UI:          ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Actions: []config.ShuttleAction{{Task: "wait", Timeout: "50ms"}},
+			},
+		},
+	}, "test", nil, true)
+
+	assert.EqualError(t, err, "exit code 124 - Action 0 of script `test` timed out after 50ms")
+}
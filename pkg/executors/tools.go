@@ -0,0 +1,151 @@
+package executors
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// defaultVersionPattern extracts the first version like number of the output
+// of a version command, eg. 1.28.2 from "Client Version: v1.28.2".
+var defaultVersionPattern = regexp.MustCompile(`v?(\d+\.\d+(?:\.\d+)?(?:-[0-9A-Za-z.-]+)?)`)
+
+// builtinVersionCommands are the commands printing the version of well known
+// tools for which "<name> --version" does not work or prints additional
+// versions.
+var builtinVersionCommands = map[string]string{
+	"docker":    "docker version --format '{{.Client.Version}}'",
+	"go":        "go version",
+	"helm":      "helm version --short",
+	"kubectl":   "kubectl version --client",
+	"terraform": "terraform version",
+}
+
+// checkTools verifies that tools required by the action are available in
+// versions satisfying their constraints.
+func checkTools(ctx context.Context, actionContext ActionExecutionContext) error {
+	for _, tool := range actionContext.Action.Tools {
+		err := checkTool(ctx, actionContext, tool)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkTool(ctx context.Context, actionContext ActionExecutionContext, tool config.ShuttleToolRequirement) error {
+	scriptName := actionContext.ScriptContext.ScriptName
+	constraint, err := semver.NewConstraint(tool.Version)
+	if err != nil {
+		return errors.NewExitCode(
+			1,
+			"Tool '%s' of action %d of script `%s` has an invalid version constraint '%s': %v",
+			tool.Name,
+			actionContext.ActionIndex,
+			scriptName,
+			tool.Version,
+			err,
+		)
+	}
+	pattern := defaultVersionPattern
+	if tool.Pattern != "" {
+		pattern, err = regexp.Compile(tool.Pattern)
+		if err != nil {
+			return errors.NewExitCode(
+				1,
+				"Tool '%s' of action %d of script `%s` has an invalid pattern '%s': %v",
+				tool.Name,
+				actionContext.ActionIndex,
+				scriptName,
+				tool.Pattern,
+				err,
+			)
+		}
+	}
+
+	_, err = exec.LookPath(tool.Name)
+	if err != nil {
+		return errors.NewExitCode(
+			4,
+			"Script `%s` requires %s %s but it was not found on PATH",
+			scriptName,
+			tool.Name,
+			tool.Version,
+		)
+	}
+
+	command := versionCommand(tool)
+	output, err := exec.CommandContext(ctx, "sh", "-c", command).CombinedOutput()
+	if err != nil {
+		return errors.NewExitCode(
+			4,
+			"Failed to determine version of %s required by script `%s` with `%s`: %v\n%s",
+			tool.Name,
+			scriptName,
+			command,
+			err,
+			strings.TrimSpace(string(output)),
+		)
+	}
+	raw := extractVersion(pattern, string(output))
+	version, err := semver.NewVersion(raw)
+	if err != nil {
+		return errors.NewExitCode(
+			4,
+			"Failed to determine version of %s required by script `%s`: no version found in output of `%s`:\n%s",
+			tool.Name,
+			scriptName,
+			command,
+			strings.TrimSpace(string(output)),
+		)
+	}
+	if !constraint.Check(version) {
+		return errors.NewExitCode(
+			4,
+			"Script `%s` requires %s %s but found version %s",
+			scriptName,
+			tool.Name,
+			tool.Version,
+			version,
+		)
+	}
+	actionContext.ScriptContext.Project.UI.Verboseln(
+		"Found %s version %s satisfying %s",
+		tool.Name,
+		version,
+		tool.Version,
+	)
+	return nil
+}
+
+// versionCommand returns the command printing the version of tool.
+func versionCommand(tool config.ShuttleToolRequirement) string {
+	if tool.Command != "" {
+		return tool.Command
+	}
+	if command, ok := builtinVersionCommands[tool.Name]; ok {
+		return command
+	}
+	return fmt.Sprintf("%s --version", tool.Name)
+}
+
+// extractVersion returns the first match of pattern in output. If the pattern
+// has a capture group its first group is returned.
+func extractVersion(pattern *regexp.Regexp, output string) string {
+	match := pattern.FindStringSubmatch(output)
+	switch {
+	case match == nil:
+		return ""
+	case len(match) > 1:
+		return match[1]
+	default:
+		return match[0]
+	}
+}
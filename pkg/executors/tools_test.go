@@ -0,0 +1,107 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// fakeTool writes an executable named name printing output to a directory
+// which is prepended to PATH.
+func fakeTool(t *testing.T, name, output string) {
+	t.Helper()
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\necho '"+output+"'\n"), 0o755)
+	require.NoError(t, err)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestExecute_tools(t *testing.T) {
+	tt := []struct {
+		name   string
+		output string
+		tool   config.ShuttleToolRequirement
+		err    string
+	}{
+		{
+			name:   "built-in command satisfied",
+			output: "Client Version: v1.29.1",
+			tool:   config.ShuttleToolRequirement{Name: "kubectl", Version: ">= 1.28"},
+		},
+		{
+			name:   "version too old",
+			output: "Client Version: v1.27.3",
+			tool:   config.ShuttleToolRequirement{Name: "kubectl", Version: ">= 1.28"},
+			err:    "exit code 4 - Script `test` requires kubectl >= 1.28 but found version 1.27.3",
+		},
+		{
+			name:   "go version format",
+			output: "go version go1.21.3 linux/amd64",
+			tool:   config.ShuttleToolRequirement{Name: "go", Version: "~1.21"},
+		},
+		{
+			name:   "custom command and pattern",
+			output: "build 20 release 2.4",
+			tool: config.ShuttleToolRequirement{
+				Name:    "mytool",
+				Version: "^2.3",
+				Command: "mytool about",
+				Pattern: `release (\d+\.\d+)`,
+			},
+		},
+		{
+			name:   "no version in output",
+			output: "unknown",
+			tool:   config.ShuttleToolRequirement{Name: "mytool", Version: "^2"},
+			err:    "exit code 4 - Failed to determine version of mytool required by script `test`: no version found in output of `mytool --version`:\nunknown",
+		},
+		{
+			name: "missing tool",
+			tool: config.ShuttleToolRequirement{Name: "shuttle-missing-tool", Version: "^2"},
+			err:  "exit code 4 - Script `test` requires shuttle-missing-tool ^2 but it was not found on PATH",
+		},
+		{
+			name:   "invalid constraint",
+			output: "1.0.0",
+			tool:   config.ShuttleToolRequirement{Name: "mytool", Version: "newest"},
+			err:    "exit code 1 - Tool 'mytool' of action 0 of script `test` has an invalid version constraint 'newest': improper constraint: newest",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.output != "" {
+				fakeTool(t, tc.tool.Name, tc.output)
+			}
+			stdout := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(stdout, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{
+							{Shell: "echo main", Tools: []config.ShuttleToolRequirement{tc.tool}},
+						},
+					},
+				},
+			}, "test", nil, true)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				assert.Empty(t, stdout.String(), "action must not run")
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "main\n", stdout.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package executors
+
+import (
+	stdcontext "context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// uploadArtifacts uploads the declared artifacts of a successful action with
+// HTTP PUT requests. Paths and URLs are expanded with the environment of the
+// action.
+func uploadArtifacts(ctx stdcontext.Context, ui *ui.UI, context ActionExecutionContext) error {
+	shellEnv, err := shellEnvironment(context)
+	if err != nil {
+		return err
+	}
+	env := environmentMap(shellEnv)
+	expand := func(s string) string {
+		return os.Expand(s, func(name string) string {
+			return env[name]
+		})
+	}
+
+	for _, upload := range context.Action.Upload {
+		artifactPath := expand(upload.Path)
+		if !filepath.IsAbs(artifactPath) {
+			artifactPath = filepath.Join(context.ScriptContext.Project.ProjectPath, artifactPath)
+		}
+		target := expand(upload.URL)
+
+		err := uploadArtifact(ctx, artifactPath, target, upload, env)
+		if err != nil {
+			return errors.NewExitCode(
+				4,
+				"Failed to upload artifact '%s' of script `%s`: %v",
+				upload.Path,
+				context.ScriptContext.ScriptName,
+				err,
+			)
+		}
+		ui.Infoln("Uploaded artifact '%s' to %s", upload.Path, redactURL(target))
+	}
+	return nil
+}
+
+// uploadArtifact PUTs the file at artifactPath to target. The token of upload
+// is read from env, the environment of the action.
+func uploadArtifact(ctx stdcontext.Context, artifactPath, target string, upload config.ShuttleUpload, env map[string]string) error {
+	file, err := os.Open(artifactPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, file)
+	if err != nil {
+		// the URL may contain credentials so do not return the raw error
+		return fmt.Errorf("invalid upload url '%s'", redactURL(target))
+	}
+	req.ContentLength = stat.Size()
+	if upload.ContentType != "" {
+		req.Header.Set("Content-Type", upload.ContentType)
+	}
+	if upload.TokenEnv != "" {
+		token := env[upload.TokenEnv]
+		if token == "" {
+			return fmt.Errorf("token environment variable '%s' is not set", upload.TokenEnv)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed", redactURL(target))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s responded with status %s", redactURL(target), resp.Status)
+	}
+	return nil
+}
+
+// redactURL removes credentials and query parameters, eg. presigned
+// signatures, from raw so it can be logged.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "<invalid url>"
+	}
+	u.User = nil
+	if u.RawQuery != "" {
+		u.RawQuery = "<redacted>"
+	}
+	return u.String()
+}
+
+func environmentMap(env []string) map[string]string {
+	m := make(map[string]string, len(env))
+	for _, entry := range env {
+		name, value, _ := strings.Cut(entry, "=")
+		m[name] = value
+	}
+	return m
+}
@@ -0,0 +1,142 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecute_upload(t *testing.T) {
+	type request struct {
+		path          string
+		body          string
+		authorization string
+	}
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{
+			path:          r.URL.Path,
+			body:          string(body),
+			authorization: r.Header.Get("Authorization"),
+		})
+		if r.URL.Path == "/forbidden" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	tt := []struct {
+		name     string
+		upload   config.ShuttleUpload
+		token    string
+		argToken string
+		requests []request
+		stderr   string
+		err      string
+	}{
+		{
+			name: "uploads artifact with expanded url",
+			upload: config.ShuttleUpload{
+				Path: "$dir/app.txt",
+				URL:  server.URL + "/artifacts/$version/app.txt?signature=secret",
+			},
+			requests: []request{
+				{path: "/artifacts/v1/app.txt", body: "artifact\n"},
+			},
+			stderr: "Uploaded artifact '$dir/app.txt' to " + server.URL + "/artifacts/v1/app.txt?<redacted>\n",
+		},
+		{
+			name: "authenticates with token",
+			upload: config.ShuttleUpload{
+				Path:     "$dir/app.txt",
+				URL:      server.URL + "/app.txt",
+				TokenEnv: "SHUTTLE_TEST_UPLOAD_TOKEN",
+			},
+			token: "s3cret",
+			requests: []request{
+				{path: "/app.txt", body: "artifact\n", authorization: "Bearer s3cret"},
+			},
+			stderr: "Uploaded artifact '$dir/app.txt' to " + server.URL + "/app.txt\n",
+		},
+		{
+			name: "authenticates with token of the action environment",
+			upload: config.ShuttleUpload{
+				Path:     "$dir/app.txt",
+				URL:      server.URL + "/app.txt",
+				TokenEnv: "token",
+			},
+			argToken: "s3cret",
+			requests: []request{
+				{path: "/app.txt", body: "artifact\n", authorization: "Bearer s3cret"},
+			},
+			stderr: "Uploaded artifact '$dir/app.txt' to " + server.URL + "/app.txt\n",
+		},
+		{
+			name: "missing token",
+			upload: config.ShuttleUpload{
+				Path:     "$dir/app.txt",
+				URL:      server.URL + "/app.txt",
+				TokenEnv: "SHUTTLE_TEST_UPLOAD_TOKEN",
+			},
+			err: "exit code 4 - Failed to upload artifact '$dir/app.txt' of script `release`: token environment variable 'SHUTTLE_TEST_UPLOAD_TOKEN' is not set",
+		},
+		{
+			name: "rejected upload",
+			upload: config.ShuttleUpload{
+				Path: "$dir/app.txt",
+				URL:  server.URL + "/forbidden",
+			},
+			requests: []request{
+				{path: "/forbidden", body: "artifact\n"},
+			},
+			err: "exit code 4 - Failed to upload artifact '$dir/app.txt' of script `release`: " + server.URL + "/forbidden responded with status 403 Forbidden",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			requests = nil
+			t.Setenv("SHUTTLE_TEST_UPLOAD_TOKEN", tc.token)
+			dir := t.TempDir()
+			stderr := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: ".",
+				UI:          ui.Create(&bytes.Buffer{}, stderr),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"release": {
+						Args: []config.ShuttleScriptArgs{{Name: "dir"}, {Name: "version"}, {Name: "token", Secret: true}},
+						Actions: []config.ShuttleAction{
+							{
+								Shell:  `echo artifact > "$dir/app.txt"`,
+								Upload: []config.ShuttleUpload{tc.upload},
+							},
+						},
+					},
+				},
+			}, "release", map[string]string{"dir": dir, "version": "v1", "token": tc.argToken}, true)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.requests, requests)
+			assert.Equal(t, tc.stderr, stderr.String())
+			assert.NotContains(t, stderr.String(), "s3cret")
+			assert.FileExists(t, filepath.Join(dir, "app.txt"))
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package executors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// ValidateActions validates the configuration of all actions and the needs of
+// scripts without running them, or of all scripts of p if none are given. All
+// problems are reported at once. Interpreters and working directories of
+// actions must exist.
+func (r *Registry) ValidateActions(p config.ShuttleProjectContext, scripts ...string) error {
+	return r.validateActions(p, true, scripts...)
+}
+
+// validateActions validates the actions of scripts like ValidateActions. The
+// existence of interpreters and working directories is only checked if
+// environment is set as they can be provided by earlier actions of a run.
+func (r *Registry) validateActions(p config.ShuttleProjectContext, environment bool, scripts ...string) error {
+	if len(scripts) == 0 {
+		for name := range p.Scripts {
+			scripts = append(scripts, name)
+		}
+		sort.Strings(scripts)
+	}
+	var problems []error
+	for _, name := range scripts {
+		script, ok := p.Scripts[name]
+		if !ok {
+			return errors.NewExitCode(2, "Script '%s' not found", name)
+		}
+		if _, err := scriptPrerequisites(p.Scripts, name); err != nil {
+			problems = append(problems, err)
+		}
+		problems = append(problems, r.validateScriptActions(ScriptExecutionContext{
+			ScriptName: name,
+			Script:     script,
+			Project:    p,
+		}, environment)...)
+	}
+	return actionProblemsError(problems)
+}
+
+// validateScriptActions returns the problems of the when expression and the
+// actions of the script. See validateActions for environment.
+// Problems caused by the environment, eg. an invalid SHUTTLE_SHELL_OUTPUT, are
+// only reported once.
+func (r *Registry) validateScriptActions(scriptContext ScriptExecutionContext, environment bool) []error {
+	var problems []error
+	if err := validateWhen(scriptContext.Script.When, fmt.Sprintf("Script `%s`", scriptContext.ScriptName)); err != nil {
+		problems = append(problems, err)
+	}
+	seen := make(map[string]bool)
+	for actionIndex, action := range scriptContext.Script.Actions {
+		for _, problem := range r.validateAction(ActionExecutionContext{
+			ScriptContext: scriptContext,
+			Action:        action,
+			ActionIndex:   actionIndex,
+		}, environment) {
+			if seen[problem.Error()] {
+				continue
+			}
+			seen[problem.Error()] = true
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+// validateAction returns the problems of the configuration of the action that
+// would otherwise only be reported once it is run. See validateActions for
+// environment.
+func (r *Registry) validateAction(context ActionExecutionContext, environment bool) []error {
+	matches := 0
+	for _, executor := range r.executors {
+		if _, ok := executor(context.Action); ok {
+			matches++
+		}
+	}
+	if matches == 0 {
+		return []error{errors.NewExitCode(
+			1,
+			"Action %d of script `%s` has no shell, powershell, docker, task or wasm to run",
+			context.ActionIndex,
+			context.ScriptContext.ScriptName,
+		)}
+	}
+	if matches > 1 && context.Action.Task != "" && context.Action.Wasm == "" {
+		return []error{errors.NewExitCode(
+			1,
+			"Action %d of script `%s` cannot have a task besides a shell or powershell",
+			context.ActionIndex,
+			context.ScriptContext.ScriptName,
+		)}
+	}
+
+	var problems []error
+	check := func(err error) {
+		if err != nil {
+			problems = append(problems, err)
+		}
+	}
+	workingDirectory := resolveWorkingDirectory
+	if environment {
+		workingDirectory = actionWorkingDirectory
+	}
+	err := validateWhen(context.Action.When, actionSubject(context))
+	check(err)
+	if context.Action.Parallel {
+		check(validateParallelAction(context))
+	}
+	if context.Action.Wasm != "" {
+		check(validateWasmAction(context))
+		_, err = actionTimeout(context)
+		check(err)
+		_, err = cleanupTimeout(context)
+		check(err)
+		_, err = stdinMode(context)
+		check(err)
+		_, err = workingDirectory(context)
+		check(err)
+		return problems
+	}
+	if context.Action.Interactive {
+		check(validateInteractiveAction(context))
+	}
+	if context.Action.Shell == "" && context.Action.PowerShell == "" && context.Action.Docker == nil {
+		return problems
+	}
+
+	if context.Action.Docker != nil {
+		// the interpreter is looked up in the container
+		err = validateDockerAction(context)
+	} else if context.Action.PowerShell != "" {
+		check(validatePowerShellAction(context))
+		if environment {
+			_, err = powerShellInterpreter(context)
+		}
+	} else if err = validateActionShell(context); err == nil && environment {
+		switch shell, native := nativeShell(context); {
+		case !native:
+			_, err = shellInterpreter(context)
+		case shell == config.ShellPwsh:
+			_, err = powerShellInterpreter(context)
+		}
+	}
+	check(err)
+	_, err = actionTimeout(context)
+	check(err)
+	_, err = stopGracePeriod(context)
+	check(err)
+	_, err = heartbeatInterval(context)
+	check(err)
+	_, err = cleanupTimeout(context)
+	check(err)
+	_, err = parseShellRetry(context)
+	check(err)
+	_, err = bufferedOutput(context)
+	check(err)
+	_, err = mergeStderr(context)
+	check(err)
+	_, err = stdinMode(context)
+	check(err)
+	if _, err := newOutputDecoder(context.Action.Encoding); err != nil {
+		check(errors.NewExitCode(
+			1,
+			"Action %d of script `%s` has an invalid encoding '%s'",
+			context.ActionIndex,
+			context.ScriptContext.ScriptName,
+			context.Action.Encoding,
+		))
+	}
+	_, err = workingDirectory(context)
+	check(err)
+	if context.Action.Background {
+		check(validateBackgroundAction(context))
+	}
+	if context.Action.CaptureOutput != "" {
+		check(validateCaptureOutput(context))
+	}
+	return problems
+}
+
+// actionProblemsError returns nil if there are no problems and the problem
+// itself if there is one. Several problems are reported together with exit
+// code 1.
+func actionProblemsError(problems []error) error {
+	switch len(problems) {
+	case 0:
+		return nil
+	case 1:
+		return problems[0]
+	}
+	var s strings.Builder
+	s.WriteString("Actions not valid:")
+	for _, problem := range problems {
+		message := problem.Error()
+		var exitCode *errors.ExitCode
+		if stderrors.As(problem, &exitCode) {
+			message = exitCode.Message
+		}
+		fmt.Fprintf(&s, "\n %s", message)
+	}
+	return errors.NewExitCode(1, "%s", s.String())
+}
@@ -0,0 +1,128 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestValidateActions(t *testing.T) {
+	tt := []struct {
+		name    string
+		env     map[string]string
+		actions []config.ShuttleAction
+		err     string
+	}{
+		{
+			name:    "valid",
+			actions: []config.ShuttleAction{{Shell: "true"}, {Task: "build"}},
+			err:     "",
+		},
+		{
+			name:    "single problem is reported as is",
+			actions: []config.ShuttleAction{{Shell: "true", Stdin: "pipe"}},
+			err:     "exit code 1 - Stdin mode 'pipe' of script `test` is invalid: must be one of 'inherit' or 'none'",
+		},
+		{
+			name: "all problems",
+			actions: []config.ShuttleAction{
+				{},
+				{Shell: "true", Task: "build"},
+				{Shell: "true", When: "env ==", Encoding: "klingon"},
+				{Shell: "true", CaptureOutput: "not-valid", Heartbeat: "-1s", RetryBackoff: "linear"},
+			},
+			err: "exit code 1 - Actions not valid:\n" +
+				" Action 0 of script `test` has no shell, powershell, docker, task or wasm to run\n" +
+				" Action 1 of script `test` cannot have a task besides a shell or powershell\n" +
+				" Action 2 of script `test` has an invalid when 'env ==': expected a variable or a string at the end\n" +
+				" Action 2 of script `test` has an invalid encoding 'klingon'\n" +
+				" heartbeat of script `test` value '-1s' is invalid: must be a duration, eg. 1m\n" +
+				" Action 3 of script `test` has an invalid retryBackoff 'linear': must be one of 'constant' or 'exponential'\n" +
+				" Action 3 of script `test` has an invalid captureOutput 'not-valid': must be a valid environment variable name",
+		},
+		{
+			name: "wasm",
+			actions: []config.ShuttleAction{
+				{Wasm: "lint.wasm"},
+				{Wasm: "lint.wasm", Shell: "true"},
+				{Wasm: "lint.wasm", Sudo: true, Timeout: "soon"},
+			},
+			err: "exit code 1 - Actions not valid:\n" +
+				" Action 1 of script `test` cannot have both wasm and shell\n" +
+				" Action 2 of script `test` cannot use sudo as it is a wasm action\n" +
+				" Action 2 of script `test` has an invalid timeout 'soon': must be a positive duration, eg. 30s",
+		},
+		{
+			name: "several background actions",
+			actions: []config.ShuttleAction{
+				{Shell: "true", Background: true},
+				{Shell: "true"},
+				{Shell: "true", Background: true},
+			},
+			err: "exit code 1 - Action 2 of script `test` cannot run in the background as action 0 already does",
+		},
+		{
+			name: "environment problems are reported once",
+			env:  map[string]string{"SHUTTLE_SHELL_OUTPUT": "paged"},
+			actions: []config.ShuttleAction{
+				{Shell: "true"},
+				{Shell: "true", Timeout: "0s"},
+				{Shell: "true"},
+			},
+			err: "exit code 1 - Actions not valid:\n" +
+				" Output mode 'paged' of script `test` is invalid: must be one of 'streaming' or 'buffered'\n" +
+				" Action 1 of script `test` has an invalid timeout '0s': must be a positive duration, eg. 30s",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			for name, value := range tc.env {
+				t.Setenv(name, value)
+			}
+			registry := NewRegistry(ShellExecutor, PowerShellExecutor, TaskExecutor, WasmExecutor)
+
+			err := registry.ValidateActions(config.ShuttleProjectContext{
+				ProjectPath: t.TempDir(),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {Actions: tc.actions},
+				},
+			})
+
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestExecute_invalidActionIsNotReached(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: t.TempDir(),
+		UI:          ui.Create(stdout, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Actions: []config.ShuttleAction{
+					{Shell: "echo first"},
+					{Shell: "true", RetryDelay: "soon"},
+				},
+			},
+		},
+	}, "test", nil, true)
+
+	var exitCode *errors.ExitCode
+	require.ErrorAs(t, err, &exitCode)
+	assert.Equal(t, "Action 1 of script `test` has an invalid retryDelay 'soon': must be a duration, eg. 2s", exitCode.Message)
+	assert.Empty(t, stdout.String(), "no action must run")
+}
@@ -0,0 +1,378 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// Directories of the host mounted in wasm modules
+const (
+	// wasmProjectDir is the directory the project is mounted at
+	wasmProjectDir = "/project"
+	// wasmTmpDir is the directory the temporary directory of shuttle is
+	// mounted at
+	wasmTmpDir = "/tmp"
+)
+
+// wasmCacheDir is the directory of .shuttle caching compiled wasm modules
+const wasmCacheDir = "wasm"
+
+// WasmExecutor runs actions with a WebAssembly module compiled for WASI, eg.
+// with GOOS=wasip1 GOARCH=wasm. Modules only have access to the project and
+// temporary directories and the environment variables shuttle passes them.
+func WasmExecutor(action config.ShuttleAction) (Executor, bool) {
+	return executeWasm, action.Wasm != ""
+}
+
+func executeWasm(ctx context.Context, ui *ui.UI, context ActionExecutionContext) error {
+	err := runWasmAction(ctx, context)
+	runCleanup(ctx, context)
+	return err
+}
+
+// runWasmAction runs the module of the action and reads its outputs once it
+// succeeds.
+func runWasmAction(ctx context.Context, context ActionExecutionContext) error {
+	if err := validateWasmAction(context); err != nil {
+		return err
+	}
+	modulePath := wasmModulePath(context)
+	// outputs are only available to the module through the mounted temporary
+	// directory
+	if context.ScriptContext.Outputs != nil && context.TempDirectoryPath() != "" {
+		outputFile, remove, err := createOutputFile(context)
+		if err != nil {
+			return fmt.Errorf("create output file: %w", err)
+		}
+		defer remove()
+		context.outputFile = outputFile
+	}
+	env, dir, err := wasmEnvironment(ctx, context)
+	if err != nil {
+		return err
+	}
+	if context.ScriptContext.DryRun {
+		printDryRun(context, []string{"wasm", modulePath}, env)
+		return nil
+	}
+
+	exitCode, err := runWasmModule(ctx, context, modulePath, env, dir)
+	if err != nil {
+		return err
+	}
+	if exitCode > 0 {
+		return errors.NewExitCode(
+			actionFailureExitCode(context, exitCode),
+			"Failed executing script `%s`: wasm module `%s`\nExit code: %v",
+			context.ScriptContext.ScriptName,
+			context.Action.Wasm,
+			exitCode,
+		)
+	}
+	if context.outputFile != "" {
+		if err := readOutputs(context, context.outputFile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateWasmAction returns an error if the action combines its module with
+// another kind of action or uses options only supported by shell actions.
+func validateWasmAction(context ActionExecutionContext) error {
+	action := context.Action
+	other := ""
+	switch {
+	case action.Shell != "":
+		other = "shell"
+	case action.PowerShell != "":
+		other = "powershell"
+	case action.Docker != nil:
+		other = "docker"
+	case action.Task != "":
+		other = "task"
+	}
+	if other != "" {
+		return errors.NewExitCode(
+			1,
+			"Action %d of script `%s` cannot have both wasm and %s",
+			context.ActionIndex,
+			context.ScriptContext.ScriptName,
+			other,
+		)
+	}
+	option := ""
+	switch {
+	case action.Sudo:
+		option = "sudo"
+	case action.Background:
+		option = "background"
+	case action.Interactive:
+		option = "interactive"
+	case action.Interpreter != "":
+		option = "interpreter"
+	default:
+		return nil
+	}
+	return errors.NewExitCode(
+		1,
+		"Action %d of script `%s` cannot use %s as it is a wasm action",
+		context.ActionIndex,
+		context.ScriptContext.ScriptName,
+		option,
+	)
+}
+
+// wasmModulePath returns the path of the module of the action. $plan and
+// $project are replaced with the directories of the plan and the project and
+// relative paths are relative to the project.
+func wasmModulePath(context ActionExecutionContext) string {
+	modulePath := os.Expand(context.Action.Wasm, func(name string) string {
+		switch name {
+		case "plan":
+			return context.PlanPath()
+		case "project":
+			return context.ScriptContext.Project.ProjectPath
+		default:
+			return "$" + name
+		}
+	})
+	if !filepath.IsAbs(modulePath) {
+		modulePath = filepath.Join(context.ScriptContext.Project.ProjectPath, modulePath)
+	}
+	return filepath.Clean(modulePath)
+}
+
+// wasmEnvironment returns the environment variables of the module and its
+// working directory within the mounted directories. Unlike shell actions the
+// environment of shuttle is not inherited.
+func wasmEnvironment(ctx context.Context, context ActionExecutionContext) ([]string, string, error) {
+	project := context.ScriptContext.Project
+	workDir, err := actionWorkingDirectory(context)
+	if err != nil {
+		return nil, "", err
+	}
+	dir, ok := wasmGuestPath(project.ProjectPath, wasmProjectDir, workDir)
+	if !ok {
+		return nil, "", errors.NewExitCode(
+			1,
+			"Action %d of script `%s` must run within the project as it is a wasm action",
+			context.ActionIndex,
+			context.ScriptContext.ScriptName,
+		)
+	}
+
+	envFileEnv, err := envFileEnvironment(context)
+	if err != nil {
+		return nil, "", err
+	}
+	env := append([]string(nil), envFileEnv...)
+	env = append(env, secretEnvironment(context)...)
+	env = append(env, argumentEnvironment(context)...)
+	for name, value := range context.ScriptContext.Outputs {
+		env = append(env, fmt.Sprintf("%s=%s", name, value))
+	}
+	env = append(
+		env,
+		fmt.Sprintf("project=%s", wasmProjectDir),
+		fmt.Sprintf("%s=%s", config.ProfileVariable, project.Config.Profile),
+		fmt.Sprintf("PWD=%s", dir),
+		fmt.Sprintf(
+			"SHUTTLE_SELECTED_ACTIONS=%s",
+			strings.Join(context.ScriptContext.SelectedScripts, " "),
+		),
+	)
+	if project.TempDirectoryPath != "" {
+		env = append(env, fmt.Sprintf("tmp=%s", wasmTmpDir))
+		if actionTmp, ok := wasmGuestPath(project.TempDirectoryPath, wasmTmpDir, context.TempDirectoryPath()); ok {
+			env = append(env, fmt.Sprintf("SHUTTLE_ACTION_TMP=%s", actionTmp))
+		}
+		if outputFile, ok := wasmGuestPath(project.TempDirectoryPath, wasmTmpDir, context.outputFile); ok && context.outputFile != "" {
+			env = append(env, fmt.Sprintf("%s=%s", outputFileEnv, outputFile))
+		}
+	}
+	env = append(env, telemetryEnvironment(ctx)...)
+	return env, dir, nil
+}
+
+// wasmGuestPath returns the path within the module of hostPath in the host
+// directory hostDir mounted at guestDir. False is returned if hostPath is not
+// within hostDir.
+func wasmGuestPath(hostDir, guestDir, hostPath string) (string, bool) {
+	relative, err := filepath.Rel(hostDir, hostPath)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return path.Join(guestDir, filepath.ToSlash(relative)), true
+}
+
+// runWasmModule runs the module at modulePath with WASI and returns its exit
+// code. The module is stopped when ctx is done.
+func runWasmModule(ctx context.Context, context ActionExecutionContext, modulePath string, env []string, dir string) (int, error) {
+	project := context.ScriptContext.Project
+	binary, err := os.ReadFile(modulePath)
+	if err != nil {
+		return 0, errors.NewExitCode(
+			1,
+			"Wasm module '%s' of script `%s` action %d could not be read: %v",
+			context.Action.Wasm,
+			context.ScriptContext.ScriptName,
+			context.ActionIndex,
+			err,
+		)
+	}
+
+	runtimeConfig := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+	if project.LocalShuttleDirectoryPath != "" {
+		cache, err := wazero.NewCompilationCacheWithDir(filepath.Join(project.LocalShuttleDirectoryPath, wasmCacheDir))
+		if err == nil {
+			defer cache.Close(ctx)
+			runtimeConfig = runtimeConfig.WithCompilationCache(cache)
+		} else {
+			project.UI.Verboseln("Compiling wasm modules without a cache: %v", err)
+		}
+	}
+	runtime := wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
+	defer runtime.Close(ctx)
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		return 0, err
+	}
+	module, err := runtime.CompileModule(ctx, binary)
+	if err != nil {
+		return 0, errors.NewExitCode(
+			1,
+			"Wasm module '%s' of script `%s` action %d is invalid: %v",
+			context.Action.Wasm,
+			context.ScriptContext.ScriptName,
+			context.ActionIndex,
+			err,
+		)
+	}
+
+	fsConfig := wazero.NewFSConfig().WithDirMount(project.ProjectPath, wasmProjectDir)
+	if project.TempDirectoryPath != "" {
+		fsConfig = fsConfig.WithDirMount(project.TempDirectoryPath, wasmTmpDir)
+	}
+	// lines of both streams are numbered by the same scanner as for shell
+	// actions
+	secrets := newSecretScanner(context.ScriptContext.SecretDetection)
+	stdout := newWasmOutput(context, "stdout", secrets)
+	stderr := newWasmOutput(context, "stderr", secrets)
+	moduleConfig := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(filepath.Base(modulePath)).
+		WithFSConfig(fsConfig).
+		WithStdout(stdout).
+		WithStderr(stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader)
+	for _, variable := range env {
+		name, value, _ := strings.Cut(variable, "=")
+		moduleConfig = moduleConfig.WithEnv(name, value)
+	}
+	if mode, err := stdinMode(context); err != nil {
+		return 0, err
+	} else if mode != StdinNone && context.ScriptContext.Stdin != nil {
+		moduleConfig = moduleConfig.WithStdin(context.ScriptContext.Stdin)
+	}
+
+	context.ScriptContext.RunLog.logCommand(context, []string{"wasm", modulePath}, env)
+	project.UI.Verboseln("Running wasm module %s from %s", modulePath, dir)
+	_, err = runtime.InstantiateModule(ctx, module, moduleConfig)
+	stdout.Close()
+	stderr.Close()
+	exitCode := 0
+	var exitErr *sys.ExitError
+	if stderrors.As(err, &exitErr) {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		exitCode = int(exitErr.ExitCode())
+	} else if err != nil {
+		return 0, fmt.Errorf("run wasm module '%s': %w", context.Action.Wasm, err)
+	}
+	if finding, found := secrets.First(); found && context.ScriptContext.SecretDetection.Mode == SecretDetectionFail {
+		return exitCode, secretDetectedError(context, finding)
+	}
+	return exitCode, nil
+}
+
+// wasmOutput forwards the output of a module line by line like the output of
+// shell actions, ie. masked, scanned for secrets and prefixed.
+type wasmOutput struct {
+	mu      sync.Mutex
+	context ActionExecutionContext
+	stream  string
+	masker  *secretMasker
+	secrets *secretScanner
+	partial []byte
+}
+
+func newWasmOutput(context ActionExecutionContext, stream string, secrets *secretScanner) *wasmOutput {
+	return &wasmOutput{
+		context: context,
+		stream:  stream,
+		masker:  newSecretMasker(context.ScriptContext),
+		secrets: secrets,
+	}
+}
+
+func (o *wasmOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.partial = append(o.partial, p...)
+	for {
+		i := bytes.IndexByte(o.partial, '\n')
+		if i < 0 {
+			break
+		}
+		o.forward(strings.TrimSuffix(string(o.partial[:i]), "\r"))
+		o.partial = o.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// Close forwards output not terminated by a newline.
+func (o *wasmOutput) Close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.partial) != 0 {
+		o.forward(string(o.partial))
+		o.partial = nil
+	}
+}
+
+func (o *wasmOutput) forward(line string) {
+	line = o.masker.Mask(line)
+	if finding, found := o.secrets.Scan(line); found {
+		if o.context.ScriptContext.SecretDetection.Mode == SecretDetectionWarn {
+			o.context.ScriptContext.Project.UI.EmphasizeInfoln(
+				"Possible secret (%s) detected in output of script `%s` action %d on line %d",
+				finding.kind,
+				o.context.ScriptContext.ScriptName,
+				o.context.ActionIndex,
+				finding.line,
+			)
+		}
+		line = secretSuppressedLine(finding)
+	}
+	o.context.output.Add(o.stream, line)
+	o.context.ScriptContext.Project.UI.StepOutput(lineDecorator(o.context), actionLabel(o.context), o.stream, line)
+}
@@ -0,0 +1,155 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// buildWasmModule compiles the module of testdata/wasm for WASI.
+func buildWasmModule(t *testing.T) string {
+	t.Helper()
+	module := filepath.Join(t.TempDir(), "test.wasm")
+	build := exec.Command("go", "build", "-o", module, "./testdata/wasm")
+	build.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	output, err := build.CombinedOutput()
+	require.NoError(t, err, string(output))
+	return module
+}
+
+func TestExecute_wasm(t *testing.T) {
+	module := buildWasmModule(t)
+	// compiled modules are cached across the test cases
+	shuttleDir := t.TempDir()
+
+	tt := []struct {
+		name      string
+		mode      string
+		workDir   string
+		detection SecretDetectionMode
+		stdout    string
+		err       string
+	}{
+		{
+			name:   "arguments and outputs",
+			stdout: "hello world from /project\ngreeted true\n",
+		},
+		{
+			name:    "working directory",
+			workDir: "sub",
+			stdout:  "hello world from /project/sub\ngreeted true\n",
+		},
+		{
+			name:   "sandboxed",
+			mode:   "sandbox",
+			stdout: "HOME=\ngreeted\n",
+		},
+		{
+			name: "exit code",
+			mode: "exit",
+			err:  "exit code 4 - Failed executing script `test`: wasm module `" + module + "`\nExit code: 3",
+		},
+		{
+			name:      "secret detected",
+			mode:      "secret",
+			detection: SecretDetectionFail,
+			err:       "exit code 4 - Possible secret (AWS access key) detected in output of script `test` action 0 on line 2.\nThe line was suppressed. Add a --detect-secrets-allow pattern if this is a false positive.",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			projectPath := t.TempDir()
+			require.NoError(t, os.Mkdir(filepath.Join(projectPath, "sub"), 0o755))
+			stdout := &bytes.Buffer{}
+			registry := NewRegistry(WasmExecutor, ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath:               projectPath,
+				LocalShuttleDirectoryPath: shuttleDir,
+				TempDirectoryPath:         t.TempDir(),
+				UI:                        ui.Create(stdout, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Args: []config.ShuttleScriptArgs{{Name: "name"}, {Name: "mode"}},
+						Actions: []config.ShuttleAction{
+							{Wasm: module, WorkDir: tc.workDir},
+							{Shell: `echo greeted $greeted`},
+						},
+					},
+				},
+			}, "test", map[string]string{"name": "world", "mode": tc.mode}, true, WithSecretDetection(SecretDetection{Mode: tc.detection}))
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.stdout, stdout.String())
+			if tc.mode == "" {
+				assert.FileExists(t, filepath.Join(projectPath, tc.workDir, "greeting.txt"))
+			}
+		})
+	}
+}
+
+func TestWasmModulePath(t *testing.T) {
+	tt := []struct {
+		name string
+		wasm string
+		path string
+	}{
+		{name: "relative", wasm: "tools/lint.wasm", path: filepath.Join("/src/app", "tools", "lint.wasm")},
+		{name: "plan", wasm: "$plan/tasks/lint.wasm", path: filepath.Join("/src/app/.shuttle/plan", "tasks", "lint.wasm")},
+		{name: "project", wasm: "${project}/lint.wasm", path: filepath.Join("/src/app", "lint.wasm")},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			path := wasmModulePath(ActionExecutionContext{
+				ScriptContext: ScriptExecutionContext{
+					Project: config.ShuttleProjectContext{
+						ProjectPath:   "/src/app",
+						LocalPlanPath: "/src/app/.shuttle/plan",
+					},
+				},
+				Action: config.ShuttleAction{Wasm: tc.wasm},
+			})
+
+			assert.Equal(t, tc.path, path)
+		})
+	}
+}
+
+func TestValidateWasmAction(t *testing.T) {
+	tt := []struct {
+		name   string
+		action config.ShuttleAction
+		err    string
+	}{
+		{name: "valid", action: config.ShuttleAction{Wasm: "lint.wasm"}},
+		{name: "shell", action: config.ShuttleAction{Wasm: "lint.wasm", Shell: "make"}, err: "exit code 1 - Action 0 of script `test` cannot have both wasm and shell"},
+		{name: "background", action: config.ShuttleAction{Wasm: "lint.wasm", Background: true}, err: "exit code 1 - Action 0 of script `test` cannot use background as it is a wasm action"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateWasmAction(ActionExecutionContext{
+				ScriptContext: ScriptExecutionContext{ScriptName: "test"},
+				Action:        tc.action,
+			})
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package executors
+
+import (
+	"context"
+	stderrors "errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// DefaultWatchDebounce is how long files must be unchanged before a watched
+// script is run again
+const DefaultWatchDebounce = 300 * time.Millisecond
+
+// defaultWatchInterval is how often watched files are checked for changes
+const defaultWatchInterval = 250 * time.Millisecond
+
+// maxReportedChanges is the number of changed files named when a watched
+// script is run again
+const maxReportedChanges = 3
+
+// errFilesChanged cancels the run of a watched script when files change
+var errFilesChanged = stderrors.New("watched files changed")
+
+// Watcher runs a script again whenever files of a project change. Files are
+// polled such that it works the same on every platform and file system.
+type Watcher struct {
+	ui          *ui.UI
+	projectPath string
+	patterns    []string
+	ignored     []string
+	debounce    time.Duration
+	interval    time.Duration
+}
+
+// NewWatcher returns a watcher of the files of the project at projectPath
+// matching the project relative glob patterns. ** matches any number of
+// directories. All files are watched if there are no patterns. .git and
+// .shuttle are never watched.
+func NewWatcher(uii *ui.UI, projectPath string, patterns []string, debounce time.Duration) (*Watcher, error) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil || path.IsAbs(pattern) || pattern == "" {
+			return nil, errors.NewExitCode(
+				2,
+				"Watch pattern '%s' is invalid: must be a project relative glob pattern",
+				pattern,
+			)
+		}
+	}
+	if debounce < 0 {
+		return nil, errors.NewExitCode(2, "Watch debounce '%s' is invalid: must not be negative", debounce)
+	}
+	return &Watcher{
+		ui:          uii,
+		projectPath: projectPath,
+		patterns:    patterns,
+		debounce:    debounce,
+		interval:    defaultWatchInterval,
+	}, nil
+}
+
+// Ignore stops watching the files matching the project relative glob
+// patterns, eg. the files written by the watched script.
+func (w *Watcher) Ignore(patterns ...string) {
+	w.ignored = append(w.ignored, patterns...)
+}
+
+// ScriptWrittenFiles returns the project relative glob patterns of the files
+// script declares it writes, ie. its outputs and the artifacts of its actions.
+func ScriptWrittenFiles(script config.ShuttlePlanScript) []string {
+	patterns := append([]string(nil), script.Outputs...)
+	for _, action := range script.Actions {
+		patterns = append(patterns, action.Artifacts...)
+	}
+	return patterns
+}
+
+// Run runs run and runs it again once watched files have changed and then been
+// unchanged for the debounce period. A run in progress when files change is
+// cancelled and waited for before the next one starts. Changes made before a
+// run completes are ignored once it does as they are likely made by the run
+// itself. Failures of run are reported and do not stop watching. Run returns
+// once ctx is done.
+func (w *Watcher) Run(ctx context.Context, name string, run func(context.Context) error) error {
+	files, err := w.snapshot()
+	if err != nil {
+		return err
+	}
+	for {
+		runCtx, cancel := context.WithCancelCause(ctx)
+		done := make(chan error, 1)
+		go func() {
+			done <- run(runCtx)
+		}()
+
+		changed, next, err := w.waitForChanges(ctx, name, files, done)
+		cancel(errFilesChanged)
+		select {
+		case <-done:
+		default:
+			if err == nil {
+				w.ui.Verboseln("Stopping %s as watched files changed", name)
+			}
+			<-done
+		}
+		if err != nil {
+			return err
+		}
+		files = next
+		w.ui.EmphasizeInfoln("Running %s again as %s changed", name, describeChanges(changed))
+	}
+}
+
+// waitForChanges waits until files differ from the watched files and have
+// settled. The outcome of the run reported on done is printed if it completes
+// in the meantime. The changed files and the new state of the watched files
+// are returned.
+func (w *Watcher) waitForChanges(
+	ctx context.Context,
+	name string,
+	files map[string]watchedFile,
+	done chan error,
+) ([]string, map[string]watchedFile, error) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	current := files
+	var changedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil, errors.NewCancellation(ctx)
+		case err := <-done:
+			// the result is put back such that Run can wait for it
+			done <- err
+			w.reportRun(name, err)
+			done = nil
+			// files written by the run do not run it again
+			if next, err := w.snapshot(); err == nil {
+				files = next
+				current = next
+				changedAt = time.Time{}
+			}
+		case now := <-ticker.C:
+			next, err := w.snapshot()
+			if err != nil {
+				w.ui.Verboseln("Failed to check watched files for changes: %v", err)
+				continue
+			}
+			if len(changedFiles(current, next)) > 0 {
+				current = next
+				changedAt = now
+				continue
+			}
+			if !changedAt.IsZero() && now.Sub(changedAt) >= w.debounce {
+				return changedFiles(files, current), current, nil
+			}
+		}
+	}
+}
+
+func (w *Watcher) reportRun(name string, err error) {
+	if err == nil {
+		w.ui.EmphasizeInfoln("Finished %s. Watching for changes...", name)
+		return
+	}
+	var exitCode *errors.ExitCode
+	if stderrors.As(err, &exitCode) {
+		w.ui.Errorln("%s\nWatching for changes...", exitCode.Message)
+		return
+	}
+	w.ui.Errorln("Error: %v\nWatching for changes...", err)
+}
+
+// watchedFile is the state of a watched file used to detect changes
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+}
+
+// snapshot returns the state of the watched files by their slash separated
+// project relative path.
+func (w *Watcher) snapshot() (map[string]watchedFile, error) {
+	files := make(map[string]watchedFile)
+	err := filepath.WalkDir(w.projectPath, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// files may be removed while walking the project
+			if stderrors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		relative, err := filepath.Rel(w.projectPath, file)
+		if err != nil {
+			return err
+		}
+		relative = filepath.ToSlash(relative)
+		if entry.IsDir() {
+			if relative == ".git" || relative == ".shuttle" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !w.watches(relative) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			if stderrors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		files[relative] = watchedFile{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (w *Watcher) watches(file string) bool {
+	for _, pattern := range w.ignored {
+		if matchGlob(pattern, file) {
+			return false
+		}
+	}
+	if len(w.patterns) == 0 {
+		return true
+	}
+	for _, pattern := range w.patterns {
+		if matchGlob(pattern, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// changedFiles returns the sorted files created, modified or removed between
+// before and after.
+func changedFiles(before, after map[string]watchedFile) []string {
+	var changed []string
+	for file, state := range after {
+		if previous, ok := before[file]; !ok || previous != state {
+			changed = append(changed, file)
+		}
+	}
+	for file := range before {
+		if _, ok := after[file]; !ok {
+			changed = append(changed, file)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// describeChanges names the first changed files and the number of others, eg.
+// 'main.go and 2 other files'.
+func describeChanges(changed []string) string {
+	if len(changed) == 0 {
+		// files changed and were changed back before settling
+		return "files"
+	}
+	shown := changed
+	if len(shown) > maxReportedChanges {
+		shown = shown[:maxReportedChanges]
+	}
+	description := strings.Join(shown, ", ")
+	switch others := len(changed) - len(shown); others {
+	case 0:
+	case 1:
+		description += " and 1 other file"
+	default:
+		description += " and " + strconv.Itoa(others) + " other files"
+	}
+	return description
+}
@@ -0,0 +1,176 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestWatcher_Run(t *testing.T) {
+	projectPath := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(projectPath, ".shuttle"), 0o755))
+	stderr := &bytes.Buffer{}
+	watcher, err := NewWatcher(ui.Create(&bytes.Buffer{}, stderr), projectPath, []string{"**/*.go"}, 20*time.Millisecond)
+	require.NoError(t, err)
+	watcher.interval = 5 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	started := make(chan int)
+	stopped := make(chan error)
+	runs := 0
+	done := make(chan error)
+	go func() {
+		done <- watcher.Run(ctx, "script `test`", func(ctx context.Context) error {
+			runs++
+			started <- runs
+			// runs wait for the next change
+			<-ctx.Done()
+			stopped <- context.Cause(ctx)
+			return ctx.Err()
+		})
+	}()
+	write := func(name string) {
+		t.Helper()
+		file := filepath.Join(projectPath, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o755))
+		require.NoError(t, os.WriteFile(file, []byte(name), 0o644))
+	}
+
+	assert.Equal(t, 1, <-started, "first run")
+	// unwatched files do not run the script again
+	write("README.md")
+	write(".shuttle/cache/main.go")
+	write("cmd/main.go")
+	assert.Equal(t, errFilesChanged, <-stopped, "cause of stopping first run")
+	assert.Equal(t, 2, <-started, "run after change")
+	write("main.go")
+	assert.Equal(t, errFilesChanged, <-stopped, "cause of stopping run in progress")
+	assert.Equal(t, 3, <-started, "run after change during run")
+	cancel()
+	assert.Equal(t, context.Canceled, <-stopped, "cause of stopping run on cancellation")
+	assert.EqualError(t, <-done, "exit code 2 - Operation cancelled")
+	assert.Contains(t, stderr.String(), "Running script `test` again as cmd/main.go changed")
+	assert.Contains(t, stderr.String(), "Running script `test` again as main.go changed")
+}
+
+func TestWatcher_Run_ownChanges(t *testing.T) {
+	projectPath := t.TempDir()
+	watcher, err := NewWatcher(ui.Create(&bytes.Buffer{}, &bytes.Buffer{}), projectPath, nil, 20*time.Millisecond)
+	require.NoError(t, err)
+	watcher.interval = 5 * time.Millisecond
+	watcher.Ignore(ScriptWrittenFiles(config.ShuttlePlanScript{
+		Actions: []config.ShuttleAction{{Artifacts: []string{"out/**"}}},
+	})...)
+	write := func(name string) {
+		t.Helper()
+		file := filepath.Join(projectPath, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o755))
+		require.NoError(t, os.WriteFile(file, []byte(time.Now().String()), 0o644))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	started := make(chan int, 10)
+	stopped := make(chan error, 10)
+	runs := 0
+	done := make(chan error)
+	go func() {
+		done <- watcher.Run(ctx, "script `generate`", func(ctx context.Context) error {
+			runs++
+			started <- runs
+			if runs == 1 {
+				// a completed run changing watched files
+				write("generated.go")
+				return nil
+			}
+			// a run in progress writing artifacts
+			for {
+				write("out/report.txt")
+				select {
+				case <-ctx.Done():
+					stopped <- context.Cause(ctx)
+					return ctx.Err()
+				case <-time.After(5 * time.Millisecond):
+				}
+			}
+		})
+	}()
+
+	assert.Equal(t, 1, <-started, "first run")
+	select {
+	case run := <-started:
+		t.Fatalf("run %d started by files written by the first run", run)
+	case <-time.After(200 * time.Millisecond):
+	}
+	write("main.go")
+	assert.Equal(t, 2, <-started, "run after change")
+	select {
+	case cause := <-stopped:
+		t.Fatalf("run stopped by its own artifacts: %v", cause)
+	case <-time.After(200 * time.Millisecond):
+	}
+	cancel()
+	assert.Equal(t, context.Canceled, <-stopped)
+	assert.EqualError(t, <-done, "exit code 2 - Operation cancelled")
+}
+
+func TestNewWatcher_invalid(t *testing.T) {
+	tt := []struct {
+		name     string
+		patterns []string
+		debounce time.Duration
+		err      string
+	}{
+		{
+			name:     "invalid pattern",
+			patterns: []string{"[a-"},
+			err:      "exit code 2 - Watch pattern '[a-' is invalid: must be a project relative glob pattern",
+		},
+		{
+			name:     "absolute pattern",
+			patterns: []string{"/src/*.go"},
+			err:      "exit code 2 - Watch pattern '/src/*.go' is invalid: must be a project relative glob pattern",
+		},
+		{
+			name:     "negative debounce",
+			debounce: -time.Second,
+			err:      "exit code 2 - Watch debounce '-1s' is invalid: must not be negative",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewWatcher(ui.Create(&bytes.Buffer{}, &bytes.Buffer{}), t.TempDir(), tc.patterns, tc.debounce)
+
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestDescribeChanges(t *testing.T) {
+	tt := []struct {
+		name    string
+		changed []string
+		output  string
+	}{
+		{name: "none", output: "files"},
+		{name: "one", changed: []string{"main.go"}, output: "main.go"},
+		{name: "all shown", changed: []string{"a.go", "b.go", "c.go"}, output: "a.go, b.go, c.go"},
+		{name: "one other", changed: []string{"a.go", "b.go", "c.go", "d.go"}, output: "a.go, b.go, c.go and 1 other file"},
+		{name: "other files", changed: []string{"a.go", "b.go", "c.go", "d.go", "e.go"}, output: "a.go, b.go, c.go and 2 other files"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.output, describeChanges(tc.changed))
+		})
+	}
+}
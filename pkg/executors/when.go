@@ -0,0 +1,387 @@
+package executors
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/changes"
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// actionCondition returns whether the action should run according to its
+// when expression. Actions without one always run. A malformed expression
+// fails the action rather than skipping it such that a typo never silently
+// disables a step.
+func actionCondition(context ActionExecutionContext) (bool, error) {
+	return whenCondition(context.ScriptContext, context.Action.When, actionSubject(context))
+}
+
+// scriptCondition returns whether the script should run according to its when
+// expression like actionCondition.
+func scriptCondition(context ScriptExecutionContext) (bool, error) {
+	return whenCondition(context, context.Script.When, fmt.Sprintf("Script `%s`", context.ScriptName))
+}
+
+func whenCondition(context ScriptExecutionContext, expression, subject string) (bool, error) {
+	if strings.TrimSpace(expression) == "" {
+		return true, nil
+	}
+	run, err := evaluateWhen(expression, newWhenScope(context))
+	if err != nil {
+		return false, whenError(subject, expression, err)
+	}
+	return run, nil
+}
+
+// validateWhen returns an error if expression is malformed. Nothing is looked
+// up and no git commands are run.
+func validateWhen(expression, subject string) error {
+	if strings.TrimSpace(expression) == "" {
+		return nil
+	}
+	_, err := evaluateWhen(expression, whenScope{
+		lookup:  func(string) (string, bool) { return "", false },
+		changed: func([]string) (bool, error) { return false, nil },
+	})
+	if err != nil {
+		return whenError(subject, expression, err)
+	}
+	return nil
+}
+
+func actionSubject(context ActionExecutionContext) string {
+	return fmt.Sprintf("Action %d of script `%s`", context.ActionIndex, context.ScriptContext.ScriptName)
+}
+
+func whenError(subject, expression string, err error) error {
+	return errors.NewExitCode(1, "%s has an invalid when '%s': %v", subject, expression, err)
+}
+
+// whenScope resolves the variables and functions of when expressions.
+type whenScope struct {
+	lookup func(name string) (string, bool)
+	// changed returns whether a file changed according to git matches any of
+	// the pathspecs
+	changed func(pathspecs []string) (bool, error)
+}
+
+// newWhenScope returns the scope of when expressions of the script. changed()
+// compares the project to the base of changes.Base.
+func newWhenScope(context ScriptExecutionContext) whenScope {
+	return whenScope{
+		lookup: func(name string) (string, bool) {
+			return whenVariable(context, name)
+		},
+		changed: func(pathspecs []string) (bool, error) {
+			projectPath := context.Project.ProjectPath
+			files, err := changes.Files(projectPath, changes.Base(projectPath), pathspecs...)
+			if err != nil {
+				return false, err
+			}
+			return len(files) != 0, nil
+		},
+	}
+}
+
+// whenVariable looks up name like the environment of the script resolves it:
+// captured outputs take precedence over arguments which take precedence over
+// the environment of shuttle. os and arch are always the platform shuttle runs
+// on as the environment may set them, eg. OS on Windows where names are case
+// insensitive. Names prefixed with args., env., outputs. or vars. are only
+// looked up in the arguments, the environment, the outputs or the variables of
+// shuttle.yaml.
+func whenVariable(context ScriptExecutionContext, name string) (string, bool) {
+	switch name {
+	case "os":
+		return runtime.GOOS, true
+	case "arch":
+		return runtime.GOARCH, true
+	}
+	if namespace, key, ok := strings.Cut(name, "."); ok && key != "" {
+		switch namespace {
+		case "args":
+			value, ok := context.Args[key]
+			return value, ok
+		case "env":
+			return os.LookupEnv(key)
+		case "outputs":
+			value, ok := context.Outputs[key]
+			return value, ok
+		case "vars":
+			return whenVarsValue(context.Project.Config.Variables, key)
+		}
+	}
+	if value, ok := context.Outputs[name]; ok {
+		return value, true
+	}
+	if value, ok := context.Args[name]; ok {
+		return value, true
+	}
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true
+	}
+	return "", false
+}
+
+// whenVarsValue returns the variable at the dot separated path of vars.
+func whenVarsValue(vars config.DynamicYaml, key string) (string, bool) {
+	var value interface{} = map[string]interface{}(vars)
+	for _, property := range strings.Split(key, ".") {
+		switch m := value.(type) {
+		case map[string]interface{}:
+			value = m[property]
+		case map[interface{}]interface{}:
+			value = m[property]
+		default:
+			return "", false
+		}
+	}
+	if value == nil {
+		return "", false
+	}
+	return fmt.Sprint(value), true
+}
+
+// evaluateWhen evaluates expression with variables and functions resolved by
+// scope. The supported conditions are
+//
+//	name              name is set and not empty
+//	!name             name is unset or empty
+//	name == "text"    name equals text. != negates it
+//	changed("path")   a file matching any of the git pathspecs changed
+//	(condition)       groups conditions
+//
+// Operands are variable names, optionally prefixed by $, or single or double
+// quoted strings. Conditions are combined with && and || where && binds
+// tighter.
+func evaluateWhen(expression string, scope whenScope) (bool, error) {
+	tokens, err := tokenizeWhen(expression)
+	if err != nil {
+		return false, err
+	}
+	parser := &whenParser{tokens: tokens, scope: scope}
+	result, err := parser.or()
+	if err != nil {
+		return false, err
+	}
+	if !parser.done() {
+		return false, fmt.Errorf("unexpected '%s'", parser.peek().text)
+	}
+	return result, nil
+}
+
+type whenTokenKind int
+
+const (
+	whenName whenTokenKind = iota
+	whenString
+	whenOperator
+)
+
+type whenToken struct {
+	kind whenTokenKind
+	text string
+}
+
+func tokenizeWhen(expression string) ([]whenToken, error) {
+	var tokens []whenToken
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case strings.HasPrefix(expression[i:], "=="),
+			strings.HasPrefix(expression[i:], "!="),
+			strings.HasPrefix(expression[i:], "&&"),
+			strings.HasPrefix(expression[i:], "||"):
+			tokens = append(tokens, whenToken{kind: whenOperator, text: expression[i : i+2]})
+			i += 2
+		case c == '!', c == '(', c == ')', c == ',':
+			tokens = append(tokens, whenToken{kind: whenOperator, text: string(c)})
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(expression[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string %s", expression[i:])
+			}
+			tokens = append(tokens, whenToken{kind: whenString, text: expression[i+1 : i+1+end]})
+			i += end + 2
+		default:
+			start := i
+			if c == '$' {
+				i++
+			}
+			nameStart := i
+			for i < len(expression) && isWhenNameChar(expression[i], i == nameStart) {
+				i++
+			}
+			if i == nameStart {
+				return nil, fmt.Errorf("unexpected '%s'", expression[start:start+1])
+			}
+			tokens = append(tokens, whenToken{kind: whenName, text: expression[nameStart:i]})
+		}
+	}
+	return tokens, nil
+}
+
+func isWhenNameChar(c byte, first bool) bool {
+	switch {
+	case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return true
+	case c >= '0' && c <= '9', c == '-' || c == '.':
+		return !first
+	default:
+		return false
+	}
+}
+
+type whenParser struct {
+	tokens []whenToken
+	pos    int
+	scope  whenScope
+}
+
+func (p *whenParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *whenParser) peek() whenToken {
+	return p.tokens[p.pos]
+}
+
+// accept consumes the next token if it is the operator op.
+func (p *whenParser) accept(op string) bool {
+	if p.done() || p.peek().kind != whenOperator || p.peek().text != op {
+		return false
+	}
+	p.pos++
+	return true
+}
+
+func (p *whenParser) or() (bool, error) {
+	result, err := p.and()
+	if err != nil {
+		return false, err
+	}
+	for p.accept("||") {
+		right, err := p.and()
+		if err != nil {
+			return false, err
+		}
+		result = result || right
+	}
+	return result, nil
+}
+
+func (p *whenParser) and() (bool, error) {
+	result, err := p.condition()
+	if err != nil {
+		return false, err
+	}
+	for p.accept("&&") {
+		right, err := p.condition()
+		if err != nil {
+			return false, err
+		}
+		result = result && right
+	}
+	return result, nil
+}
+
+func (p *whenParser) condition() (bool, error) {
+	if p.accept("!") {
+		result, err := p.condition()
+		return !result, err
+	}
+	if p.accept("(") {
+		result, err := p.or()
+		if err != nil {
+			return false, err
+		}
+		if !p.accept(")") {
+			return false, fmt.Errorf("expected ')'")
+		}
+		return result, nil
+	}
+	if p.pos+1 < len(p.tokens) && p.peek().kind == whenName &&
+		p.tokens[p.pos+1].kind == whenOperator && p.tokens[p.pos+1].text == "(" {
+		return p.call()
+	}
+	left, err := p.operand()
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case p.accept("=="):
+		right, err := p.operand()
+		return left == right, err
+	case p.accept("!="):
+		right, err := p.operand()
+		return left != right, err
+	default:
+		return left != "", nil
+	}
+}
+
+// operand returns the value of the next variable or string.
+func (p *whenParser) operand() (string, error) {
+	if p.done() {
+		return "", fmt.Errorf("expected a variable or a string at the end")
+	}
+	token := p.peek()
+	switch token.kind {
+	case whenName:
+		p.pos++
+		value, _ := p.scope.lookup(token.text)
+		return value, nil
+	case whenString:
+		p.pos++
+		return token.text, nil
+	default:
+		return "", fmt.Errorf("expected a variable or a string but got '%s'", token.text)
+	}
+}
+
+// call evaluates the function call at the next token.
+func (p *whenParser) call() (bool, error) {
+	name := p.peek().text
+	p.pos += 2
+	var args []string
+	if !p.accept(")") {
+		for {
+			arg, err := p.operand()
+			if err != nil {
+				return false, err
+			}
+			args = append(args, arg)
+			if p.accept(")") {
+				break
+			}
+			if !p.accept(",") {
+				return false, fmt.Errorf("expected ',' or ')' in arguments of %s", name)
+			}
+		}
+	}
+	switch name {
+	case "changed":
+		if len(args) == 0 {
+			return false, fmt.Errorf("changed requires at least one path")
+		}
+		for _, pathspec := range args {
+			if path.IsAbs(pathspec) || pathspec == "" {
+				return false, fmt.Errorf("changed path '%s' is invalid: must be relative to the project", pathspec)
+			}
+		}
+		changed, err := p.scope.changed(args)
+		if err != nil {
+			return false, fmt.Errorf("changed: %w", err)
+		}
+		return changed, nil
+	default:
+		return false, fmt.Errorf("unknown function '%s': must be changed", name)
+	}
+}
@@ -0,0 +1,337 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestEvaluateWhen(t *testing.T) {
+	variables := map[string]string{
+		"branch":   "main",
+		"env":      "prod",
+		"empty":    "",
+		"from-tag": "v1",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := variables[name]
+		return value, ok
+	}
+
+	tt := []struct {
+		expression string
+		result     bool
+		err        string
+	}{
+		{expression: `branch`, result: true},
+		{expression: `$branch`, result: true},
+		{expression: `empty`, result: false},
+		{expression: `unset`, result: false},
+		{expression: `!unset`, result: true},
+		{expression: `!branch`, result: false},
+		{expression: `from-tag`, result: true},
+		{expression: `branch == "main"`, result: true},
+		{expression: `branch == 'main'`, result: true},
+		{expression: `branch == "develop"`, result: false},
+		{expression: `branch != "develop"`, result: true},
+		{expression: `"main" == branch`, result: true},
+		{expression: `unset == ""`, result: true},
+		{expression: `branch == env`, result: false},
+		{expression: `!branch == "develop"`, result: true},
+		{expression: `branch == "main" && env == "prod"`, result: true},
+		{expression: `branch == "main" && env == "dev"`, result: false},
+		{expression: `branch == "develop" || env == "prod"`, result: true},
+		{expression: `unset || branch == "develop" && env == "prod"`, result: false},
+		{expression: `branch == "main" || unset && env == "dev"`, result: true},
+		{expression: `branch ==`, err: "expected a variable or a string at the end"},
+		{expression: `branch == "main`, err: `unterminated string "main`},
+		{expression: `branch = "main"`, err: "unexpected '='"},
+		{expression: `branch "main"`, err: "unexpected 'main'"},
+		{expression: `&& branch`, err: "expected a variable or a string but got '&&'"},
+		{expression: `(branch == "develop" || env == "prod") && !unset`, result: true},
+		{expression: `!(branch == "main" && env == "prod")`, result: false},
+		{expression: `(branch`, err: "expected ')'"},
+		{expression: `branch)`, err: "unexpected ')'"},
+		{expression: `changed("services/api/**")`, result: true},
+		{expression: `changed("docs/*", "*.md")`, result: false},
+		{expression: `!changed("docs/*") && branch == "main"`, result: true},
+		{expression: `changed()`, err: "changed requires at least one path"},
+		{expression: `changed("/etc")`, err: "changed path '/etc' is invalid: must be relative to the project"},
+		{expression: `changed("a" "b")`, err: "expected ',' or ')' in arguments of changed"},
+		{expression: `modified("a")`, err: "unknown function 'modified': must be changed"},
+	}
+	changed := func(pathspecs []string) (bool, error) {
+		for _, pathspec := range pathspecs {
+			if matchGlob(pathspec, "services/api/main.go") {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	for _, tc := range tt {
+		t.Run(tc.expression, func(t *testing.T) {
+			result, err := evaluateWhen(tc.expression, whenScope{lookup: lookup, changed: changed})
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.result, result)
+		})
+	}
+}
+
+func TestExecute_when(t *testing.T) {
+	t.Setenv("SHUTTLE_TEST_WHEN_BRANCH", "main")
+
+	tt := []struct {
+		name   string
+		when   string
+		args   map[string]string
+		stdout string
+		stderr string
+		err    string
+	}{
+		{
+			name:   "no condition",
+			stdout: "ran\n",
+		},
+		{
+			name:   "true",
+			when:   `env == "prod"`,
+			args:   map[string]string{"env": "prod"},
+			stdout: "ran\n",
+		},
+		{
+			name:   "false",
+			when:   `env == "prod"`,
+			args:   map[string]string{"env": "dev"},
+			stderr: "Skipped action 0 of script `test` as when 'env == \"prod\"' is false\n",
+		},
+		{
+			name:   "environment variable",
+			when:   `SHUTTLE_TEST_WHEN_BRANCH == "main"`,
+			stdout: "ran\n",
+		},
+		{
+			name: "argument takes precedence over environment",
+			when: `SHUTTLE_TEST_WHEN_BRANCH == "main"`,
+			args: map[string]string{"SHUTTLE_TEST_WHEN_BRANCH": "develop"},
+			stderr: "Skipped action 0 of script `test` as when " +
+				"'SHUTTLE_TEST_WHEN_BRANCH == \"main\"' is false\n",
+		},
+		{
+			name: "malformed",
+			when: `env = "prod"`,
+			args: map[string]string{"env": "prod"},
+			err:  "exit code 1 - Action 0 of script `test` has an invalid when 'env = \"prod\"': unexpected '='",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: t.TempDir(),
+				UI:          ui.Create(stdout, stderr),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{{Shell: "echo ran", When: tc.when}},
+					},
+				},
+			}, "test", tc.args, false)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.stdout, stdout.String())
+			assert.Equal(t, tc.stderr, stderr.String())
+		})
+	}
+}
+
+func TestExecute_whenCapturedOutput(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: t.TempDir(),
+		UI:          ui.Create(stdout, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Actions: []config.ShuttleAction{
+					{Shell: "echo yes", CaptureOutput: "changed"},
+					{Shell: "echo deploying", When: `changed == "yes"`},
+					{Shell: "echo skipped", When: `!changed`},
+				},
+			},
+		},
+	}, "test", nil, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "deploying\n", stdout.String())
+}
+
+func TestWhenVariable(t *testing.T) {
+	t.Setenv("SHUTTLE_TEST_WHEN_ENV", "environment")
+	t.Setenv("name", "environment")
+	t.Setenv("os", "Windows_NT")
+	context := ScriptExecutionContext{
+		Args:    map[string]string{"name": "argument", "env": "prod", "arch": "argument"},
+		Outputs: map[string]string{"version": "1.2.3"},
+		Project: config.ShuttleProjectContext{
+			Config: config.ShuttleConfig{
+				Variables: config.DynamicYaml{
+					"service": map[interface{}]interface{}{"name": "api", "port": 8080},
+					"team":    "platform",
+				},
+			},
+		},
+	}
+
+	tt := []struct {
+		name  string
+		value string
+		set   bool
+	}{
+		{name: "name", value: "argument", set: true},
+		{name: "args.name", value: "argument", set: true},
+		{name: "env.name", value: "environment", set: true},
+		{name: "args.env", value: "prod", set: true},
+		{name: "env.SHUTTLE_TEST_WHEN_ENV", value: "environment", set: true},
+		{name: "outputs.version", value: "1.2.3", set: true},
+		{name: "args.version", set: false},
+		{name: "vars.team", value: "platform", set: true},
+		{name: "vars.service.name", value: "api", set: true},
+		{name: "vars.service.port", value: "8080", set: true},
+		{name: "vars.team.name", set: false},
+		{name: "vars.missing", set: false},
+		{name: "os", value: runtime.GOOS, set: true},
+		{name: "arch", value: runtime.GOARCH, set: true},
+		{name: "env.os", value: "Windows_NT", set: true},
+		{name: "args.arch", value: "argument", set: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			value, set := whenVariable(context, tc.name)
+
+			assert.Equal(t, tc.value, value)
+			assert.Equal(t, tc.set, set)
+		})
+	}
+}
+
+func TestExecute_scriptWhen(t *testing.T) {
+	tt := []struct {
+		name   string
+		when   string
+		stdout string
+		stderr string
+		err    string
+	}{
+		{
+			name:   "true",
+			when:   `args.env == "prod"`,
+			stdout: "needed\nran\n",
+		},
+		{
+			name:   "false",
+			when:   `args.env != "prod"`,
+			stdout: "needed\n",
+			stderr: "Running script 'setup' needed by script 'test'\nSkipped script `test` as when 'args.env != \"prod\"' is false\n",
+		},
+		{
+			name:   "outputs of needed scripts",
+			when:   `outputs.needed == "yes"`,
+			stdout: "needed\nran\n",
+		},
+		{
+			name: "malformed",
+			when: `args.env = "prod"`,
+			err:  "exit code 1 - Script `test` has an invalid when 'args.env = \"prod\"': unexpected '='",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			summary := &RunSummary{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: t.TempDir(),
+				UI:          ui.Create(stdout, stderr),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"setup": {
+						Actions: []config.ShuttleAction{{Shell: `echo needed; echo needed=yes >> "$SHUTTLE_OUTPUT"`}},
+					},
+					"test": {
+						Args:    []config.ShuttleScriptArgs{{Name: "env"}},
+						Needs:   []string{"setup"},
+						When:    tc.when,
+						Actions: []config.ShuttleAction{{Shell: "echo ran"}},
+					},
+				},
+			}, "test", map[string]string{"env": "prod"}, true, WithSummary(summary))
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.stdout, stdout.String())
+			if tc.stderr != "" {
+				assert.Equal(t, tc.stderr, stderr.String())
+				assert.Equal(t, []ActionResult{{Index: 0, Description: "echo ran", Status: ActionStatusSkipped}}, summary.Actions)
+			}
+		})
+	}
+}
+
+func TestExecute_whenChanged(t *testing.T) {
+	projectPath := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch=main"},
+		{"-c", "user.name=shuttle", "-c", "user.email=shuttle@example.com", "commit", "--quiet", "--allow-empty", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = projectPath
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(projectPath, "services", "api"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(projectPath, "services", "api", "main.go"), []byte("package main"), 0o644))
+	stdout := &bytes.Buffer{}
+	registry := NewRegistry(ShellExecutor)
+
+	err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+		ProjectPath: projectPath,
+		UI:          ui.Create(stdout, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Actions: []config.ShuttleAction{
+					{Shell: "echo api", When: `changed("services/api/**")`},
+					{Shell: "echo web", When: `changed("services/web/**")`},
+				},
+			},
+		},
+	}, "test", nil, true)
+
+	require.NoError(t, err)
+	assert.Equal(t, "api\n", stdout.String())
+}
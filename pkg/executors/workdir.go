@@ -0,0 +1,192 @@
+package executors
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// Styles of entering the working directory of shell actions
+const (
+	CdStyleSingleQuote = "single-quote"
+	CdStyleDoubleQuote = "double-quote"
+	CdStyleEnv         = "env"
+)
+
+// Directories shell actions are run from
+const (
+	// CwdProject runs actions from the project directory
+	CwdProject = "project"
+	// CwdInvocation runs actions from the working directory shuttle is
+	// invoked from
+	CwdInvocation = "invocation"
+)
+
+// getwd returns the working directory shuttle is invoked from
+var getwd = os.Getwd
+
+// workingDirectoryEnv is the environment variable holding the working
+// directory with the env style.
+const workingDirectoryEnv = "SHUTTLE_WORKING_DIRECTORY"
+
+// windowsDrivePath matches absolute windows paths, eg. C:\src\app.
+var windowsDrivePath = regexp.MustCompile(`^([A-Za-z]):[\\/]`)
+
+// actionWorkingDirectory returns the directory the action is run from. It is
+// the project directory unless the action sets a project relative workDir
+// which must be an existing directory within the project or is run from the
+// invocation directory with cwd.
+func actionWorkingDirectory(context ActionExecutionContext) (string, error) {
+	dir, err := resolveWorkingDirectory(context)
+	if err != nil || context.Action.WorkDir == "" {
+		return dir, err
+	}
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return "", errors.NewExitCode(
+			1,
+			"Working directory '%s' of script `%s` action %d is not a directory at %s",
+			context.Action.WorkDir,
+			context.ScriptContext.ScriptName,
+			context.ActionIndex,
+			dir,
+		)
+	}
+	return dir, nil
+}
+
+// resolveWorkingDirectory returns the directory the action is run from like
+// actionWorkingDirectory without checking that a workDir exists, eg. as it is
+// created by an earlier action.
+func resolveWorkingDirectory(context ActionExecutionContext) (string, error) {
+	projectPath := context.ScriptContext.Project.ProjectPath
+	cwd, err := cwdMode(context)
+	if err != nil {
+		return "", err
+	}
+	if context.Action.WorkDir == "" {
+		if cwd == CwdInvocation {
+			dir, err := getwd()
+			if err != nil {
+				return "", fmt.Errorf("get invocation directory: %w", err)
+			}
+			return dir, nil
+		}
+		return projectPath, nil
+	}
+	if context.Action.Cwd == CwdInvocation {
+		return "", errors.NewExitCode(
+			1,
+			"Action %d of script `%s` cannot have a workDir as it runs from the invocation directory",
+			context.ActionIndex,
+			context.ScriptContext.ScriptName,
+		)
+	}
+	dir := context.Action.WorkDir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(projectPath, dir)
+	}
+	dir = filepath.Clean(dir)
+	relative, err := filepath.Rel(projectPath, dir)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", errors.NewExitCode(
+			1,
+			"Working directory '%s' of script `%s` action %d must be within the project",
+			context.Action.WorkDir,
+			context.ScriptContext.ScriptName,
+			context.ActionIndex,
+		)
+	}
+	return dir, nil
+}
+
+// cwdMode returns the directory mode of the action. The mode of the action
+// takes precedence over the global SHUTTLE_SHELL_CWD mode.
+func cwdMode(context ActionExecutionContext) (string, error) {
+	mode := context.Action.Cwd
+	if mode == "" {
+		mode = os.Getenv("SHUTTLE_SHELL_CWD")
+	}
+	switch mode {
+	case "", CwdProject:
+		return CwdProject, nil
+	case CwdInvocation:
+		return CwdInvocation, nil
+	default:
+		return "", errors.NewExitCode(
+			1,
+			"Cwd mode '%s' of script `%s` is invalid: must be one of '%s' or '%s'",
+			mode,
+			context.ScriptContext.ScriptName,
+			CwdProject,
+			CwdInvocation,
+		)
+	}
+}
+
+// changeDirectory returns the shell snippet entering dir and environment
+// variables it relies on. The style is set with SHUTTLE_SHELL_CD_STYLE and
+// defaults to single quoting the directory.
+func changeDirectory(dir string) (string, []string, error) {
+	dir = shellPath(dir)
+	style := os.Getenv("SHUTTLE_SHELL_CD_STYLE")
+	switch style {
+	case "", CdStyleSingleQuote:
+		return fmt.Sprintf("cd %s", singleQuote(dir)), nil, nil
+	case CdStyleDoubleQuote:
+		return fmt.Sprintf("cd %s", doubleQuote(dir)), nil, nil
+	case CdStyleEnv:
+		// the directory never becomes part of the script so no quoting is
+		// needed
+		return fmt.Sprintf(`cd "$%s"`, workingDirectoryEnv), []string{
+			fmt.Sprintf("%s=%s", workingDirectoryEnv, dir),
+		}, nil
+	default:
+		return "", nil, errors.NewExitCode(
+			1,
+			"SHUTTLE_SHELL_CD_STYLE value '%s' is invalid: must be one of '%s', '%s' or '%s'",
+			style,
+			CdStyleSingleQuote,
+			CdStyleDoubleQuote,
+			CdStyleEnv,
+		)
+	}
+}
+
+// shellPath converts windows paths to the form understood by Git Bash, eg.
+// C:\src\app to /c/src/app. Other paths are returned as is.
+func shellPath(dir string) string {
+	if goos != "windows" {
+		return dir
+	}
+	if match := windowsDrivePath.FindStringSubmatch(dir); match != nil {
+		dir = "/" + strings.ToLower(match[1]) + "/" + dir[len(match[0]):]
+	}
+	return strings.ReplaceAll(dir, `\`, "/")
+}
+
+// singleQuote quotes s for a POSIX shell. Single quotes in s are closed,
+// escaped and reopened.
+func singleQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// doubleQuote quotes s for a POSIX shell escaping the characters that are
+// special within double quotes.
+func doubleQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\', '$', '`':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,356 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestExecute_cdStyle(t *testing.T) {
+	dirs := []string{
+		"plain",
+		"with space",
+		"it's quoted",
+		`double "quoted"`,
+		"dollar $HOME `tick`",
+	}
+	styles := []string{"", CdStyleSingleQuote, CdStyleDoubleQuote, CdStyleEnv}
+	for _, style := range styles {
+		name := style
+		if name == "" {
+			name = "default"
+		}
+		for _, dir := range dirs {
+			t.Run(name+"/"+dir, func(t *testing.T) {
+				t.Setenv("SHUTTLE_SHELL_CD_STYLE", style)
+				projectPath := filepath.Join(t.TempDir(), dir)
+				require.NoError(t, os.Mkdir(projectPath, 0o755))
+				stdout := &bytes.Buffer{}
+				registry := NewRegistry(ShellExecutor)
+
+				err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+					ProjectPath: projectPath,
+					UI:          ui.Create(stdout, &bytes.Buffer{}),
+					Scripts: map[string]config.ShuttlePlanScript{
+						"test": {
+							Actions: []config.ShuttleAction{{Shell: "pwd"}},
+						},
+					},
+				}, "test", nil, true)
+
+				assert.NoError(t, err)
+				assert.Equal(t, projectPath+"\n", stdout.String())
+			})
+		}
+	}
+}
+
+func TestExecute_workDir(t *testing.T) {
+	projectPath, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(projectPath, "sub", "dir"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(projectPath, "file"), nil, 0o644))
+
+	tt := []struct {
+		name    string
+		workDir string
+		output  string
+		err     string
+	}{
+		{
+			name:    "default",
+			workDir: "",
+			output:  projectPath + "\n",
+		},
+		{
+			name:    "relative",
+			workDir: "sub/dir",
+			output:  filepath.Join(projectPath, "sub", "dir") + "\n",
+		},
+		{
+			name:    "relative with traversal within project",
+			workDir: "sub/dir/..",
+			output:  filepath.Join(projectPath, "sub") + "\n",
+		},
+		{
+			name:    "absolute within project",
+			workDir: filepath.Join(projectPath, "sub"),
+			output:  filepath.Join(projectPath, "sub") + "\n",
+		},
+		{
+			name:    "traversal outside project",
+			workDir: "sub/../..",
+			err:     "exit code 1 - Working directory 'sub/../..' of script `test` action 0 must be within the project",
+		},
+		{
+			name:    "absolute outside project",
+			workDir: filepath.Dir(projectPath),
+			err:     "exit code 1 - Working directory '" + filepath.Dir(projectPath) + "' of script `test` action 0 must be within the project",
+		},
+		{
+			name:    "missing",
+			workDir: "missing",
+			err:     "exit code 1 - Working directory 'missing' of script `test` action 0 is not a directory at " + filepath.Join(projectPath, "missing"),
+		},
+		{
+			name:    "file",
+			workDir: "file",
+			err:     "exit code 1 - Working directory 'file' of script `test` action 0 is not a directory at " + filepath.Join(projectPath, "file"),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: projectPath,
+				UI:          ui.Create(stdout, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{{Shell: "pwd", WorkDir: tc.workDir}},
+					},
+				},
+			}, "test", nil, true)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.output, stdout.String())
+		})
+	}
+}
+
+// TestExecute_workDirCreatedByEarlierAction tests that working directories are
+// only required to exist once their action is run while shuttle validate
+// requires them up front.
+func TestExecute_workDirCreatedByEarlierAction(t *testing.T) {
+	projectPath := t.TempDir()
+	stdout := &bytes.Buffer{}
+	registry := NewRegistry(ShellExecutor)
+	project := config.ShuttleProjectContext{
+		ProjectPath: projectPath,
+		UI:          ui.Create(stdout, &bytes.Buffer{}),
+		Scripts: map[string]config.ShuttlePlanScript{
+			"test": {
+				Actions: []config.ShuttleAction{
+					{Shell: "mkdir -p out"},
+					{Shell: "pwd", WorkDir: "out"},
+				},
+			},
+		},
+	}
+
+	err := registry.ValidateActions(project, "test")
+	assert.EqualError(t, err, "exit code 1 - Working directory 'out' of script `test` action 1 is not a directory at "+filepath.Join(projectPath, "out"))
+
+	err = registry.Execute(context.Background(), project, "test", nil, true)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(projectPath, "out")+"\n", stdout.String())
+}
+
+func TestChangeDirectory(t *testing.T) {
+	tt := []struct {
+		name  string
+		goos  string
+		style string
+		dir   string
+		cd    string
+		env   []string
+		err   string
+	}{
+		{
+			name: "default",
+			goos: "linux",
+			dir:  "/src/it's here",
+			cd:   `cd '/src/it'\''s here'`,
+		},
+		{
+			name:  "double quote",
+			goos:  "linux",
+			style: CdStyleDoubleQuote,
+			dir:   `/src/"$app"`,
+			cd:    `cd "/src/\"\$app\""`,
+		},
+		{
+			name:  "env",
+			goos:  "linux",
+			style: CdStyleEnv,
+			dir:   "/src/app",
+			cd:    `cd "$SHUTTLE_WORKING_DIRECTORY"`,
+			env:   []string{"SHUTTLE_WORKING_DIRECTORY=/src/app"},
+		},
+		{
+			name: "windows backslashes",
+			goos: "windows",
+			dir:  `C:\Users\me\my app`,
+			cd:   `cd '/c/Users/me/my app'`,
+		},
+		{
+			name:  "windows env",
+			goos:  "windows",
+			style: CdStyleEnv,
+			dir:   `D:\src`,
+			cd:    `cd "$SHUTTLE_WORKING_DIRECTORY"`,
+			env:   []string{"SHUTTLE_WORKING_DIRECTORY=/d/src"},
+		},
+		{
+			name:  "invalid style",
+			goos:  "linux",
+			style: "backtick",
+			dir:   "/src/app",
+			err:   "exit code 1 - SHUTTLE_SHELL_CD_STYLE value 'backtick' is invalid: must be one of 'single-quote', 'double-quote' or 'env'",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("SHUTTLE_SHELL_CD_STYLE", tc.style)
+			defer func(previous string) { goos = previous }(goos)
+			goos = tc.goos
+
+			cd, env, err := changeDirectory(tc.dir)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.cd, cd)
+			assert.Equal(t, tc.env, env)
+		})
+	}
+}
+
+func TestExecute_cwd(t *testing.T) {
+	projectPath, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(projectPath, "sub"), 0o755))
+	invocationPath, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	defer func(previous func() (string, error)) { getwd = previous }(getwd)
+	getwd = func() (string, error) {
+		return invocationPath, nil
+	}
+
+	tt := []struct {
+		name    string
+		env     string
+		cwd     string
+		workDir string
+		output  string
+		err     string
+	}{
+		{
+			name:   "default",
+			output: projectPath + "\n" + projectPath + "\n",
+		},
+		{
+			name:   "project",
+			cwd:    CwdProject,
+			output: projectPath + "\n" + projectPath + "\n",
+		},
+		{
+			name:   "invocation",
+			cwd:    CwdInvocation,
+			output: invocationPath + "\n" + projectPath + "\n",
+		},
+		{
+			name:   "invocation from environment",
+			env:    CwdInvocation,
+			output: invocationPath + "\n" + projectPath + "\n",
+		},
+		{
+			name:   "action takes precedence",
+			env:    CwdInvocation,
+			cwd:    CwdProject,
+			output: projectPath + "\n" + projectPath + "\n",
+		},
+		{
+			name:    "workDir takes precedence over environment",
+			env:     CwdInvocation,
+			workDir: "sub",
+			output:  filepath.Join(projectPath, "sub") + "\n" + projectPath + "\n",
+		},
+		{
+			name:    "invocation with workDir",
+			cwd:     CwdInvocation,
+			workDir: "sub",
+			err:     "exit code 1 - Action 0 of script `test` cannot have a workDir as it runs from the invocation directory",
+		},
+		{
+			name: "invalid",
+			cwd:  "home",
+			err:  "exit code 1 - Cwd mode 'home' of script `test` is invalid: must be one of 'project' or 'invocation'",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("SHUTTLE_SHELL_CWD", tc.env)
+			stdout := &bytes.Buffer{}
+			registry := NewRegistry(ShellExecutor)
+
+			err := registry.Execute(context.Background(), config.ShuttleProjectContext{
+				ProjectPath: projectPath,
+				UI:          ui.Create(stdout, &bytes.Buffer{}),
+				Scripts: map[string]config.ShuttlePlanScript{
+					"test": {
+						Actions: []config.ShuttleAction{
+							{Shell: `pwd; echo "$project"`, Cwd: tc.cwd, WorkDir: tc.workDir},
+						},
+					},
+				},
+			}, "test", nil, true)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.output, stdout.String())
+		})
+	}
+}
+
+func TestShellCommand_cwdWindows(t *testing.T) {
+	defer func(previous string) { goos = previous }(goos)
+	goos = "windows"
+	defer func(previous func() (string, error)) { getwd = previous }(getwd)
+	getwd = func() (string, error) {
+		return `D:\work\my files`, nil
+	}
+	tt := []struct {
+		name string
+		cwd  string
+		cd   string
+	}{
+		{name: "project", cwd: CwdProject, cd: `cd '/c/src/app'; true`},
+		{name: "invocation", cwd: CwdInvocation, cd: `cd '/d/work/my files'; true`},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cmdArgs, _, _, err := shellCommand(context.Background(), ActionExecutionContext{
+				ScriptContext: ScriptExecutionContext{
+					ScriptName: "test",
+					Project: config.ShuttleProjectContext{
+						ProjectPath: `C:\src\app`,
+						UI:          ui.Create(&bytes.Buffer{}, &bytes.Buffer{}),
+					},
+				},
+				Action: config.ShuttleAction{Shell: "true", Cwd: tc.cwd},
+			}, "true", false)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.cd, cmdArgs[len(cmdArgs)-1])
+		})
+	}
+}
@@ -0,0 +1,2 @@
+// Package filelock takes exclusive locks of files shared by shuttle processes.
+package filelock
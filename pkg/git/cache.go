@@ -0,0 +1,131 @@
+package git
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+// PlanCache controls whether a previously fetched git plan is used without
+// fetching it again.
+type PlanCache struct {
+	// TTL is how long a fetched plan is used before it is fetched again, eg.
+	// 1h. It defaults to the minutes of SHUTTLE_CACHE_DURATION_MIN and caching
+	// is disabled if neither is set.
+	TTL string
+	// Refresh fetches the plan regardless of the TTL.
+	Refresh bool
+	// Offline uses the plan last checked out without fetching it, failing if
+	// it was never cloned. It defaults to SHUTTLE_OFFLINE.
+	Offline bool
+}
+
+// planFetchState is the state file recording when a plan was last fetched.
+type planFetchState struct {
+	Plan      string    `json:"plan"`
+	Head      string    `json:"head"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// planFetchStatePath returns the path of the state file of the plan cloned
+// into localShuttleDirectoryPath.
+func planFetchStatePath(localShuttleDirectoryPath string) string {
+	return path.Join(localShuttleDirectoryPath, "plan-fetch.json")
+}
+
+// writePlanFetchState records that plan was fetched at head now.
+func writePlanFetchState(localShuttleDirectoryPath string, plan Plan, now time.Time) error {
+	content, err := json.Marshal(planFetchState{
+		Plan:      plan.Repository,
+		Head:      plan.Head,
+		FetchedAt: now,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal plan fetch state: %w", err)
+	}
+	statePath := planFetchStatePath(localShuttleDirectoryPath)
+	err = os.WriteFile(statePath, content, 0o644)
+	if err != nil {
+		return fmt.Errorf("write plan fetch state '%s': %w", statePath, err)
+	}
+	return nil
+}
+
+// cacheTTL returns the TTL of the cache and whether caching is enabled.
+func (c PlanCache) cacheTTL() (time.Duration, bool, error) {
+	if c.TTL != "" {
+		ttl, err := time.ParseDuration(c.TTL)
+		if err != nil || ttl < 0 {
+			return 0, false, errors.NewExitCode(
+				2,
+				"Failed to parse shuttle configuration: planTTL '%s' is invalid: must be a duration, eg. 1h\n\nMake sure your 'shuttle.yaml' is valid.",
+				c.TTL,
+			)
+		}
+		return ttl, ttl > 0, nil
+	}
+
+	duration := os.Getenv(cacheDurationMinKey)
+	if duration == "" {
+		return 0, false, nil
+	}
+	durationMin, err := strconv.Atoi(duration)
+	if err != nil {
+		return 0, false, fmt.Errorf("%s is not valid: %s", cacheDurationMinKey, duration)
+	}
+	return time.Minute * time.Duration(durationMin), durationMin > 0, nil
+}
+
+// offline returns whether plans must be used without fetching them.
+func (c PlanCache) offline() (bool, error) {
+	if c.Offline {
+		return true, nil
+	}
+	value := os.Getenv(offlineKey)
+	if value == "" {
+		return false, nil
+	}
+	offline, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s is not valid: must be true or false but was %s", offlineKey, value)
+	}
+	return offline, nil
+}
+
+// cacheIsValid returns true if plan was fetched within the TTL of the cache.
+// Caching is opt in only and a plan is never cached across changes of its
+// repository or head.
+func cacheIsValid(cache PlanCache, localShuttleDirectoryPath string, plan Plan, now time.Time) (bool, error) {
+	ttl, enabled, err := cache.cacheTTL()
+	if err != nil {
+		return false, err
+	}
+	if !enabled || cache.Refresh {
+		return false, nil
+	}
+
+	content, err := os.ReadFile(planFetchStatePath(localShuttleDirectoryPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("read plan fetch state: %w", err)
+	}
+	var state planFetchState
+	err = json.Unmarshal(content, &state)
+	if err != nil {
+		// a corrupt state file is treated as a stale cache as it is rewritten
+		// on the next fetch
+		return false, nil
+	}
+	if state.Plan != plan.Repository || state.Head != plan.Head {
+		return false, nil
+	}
+
+	return now.Sub(state.FetchedAt) < ttl, nil
+}
@@ -0,0 +1,141 @@
+package git
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheIsValid(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	plan := Plan{Repository: "github.com/lunarway/shuttle-example-go-plan.git", Head: "master"}
+
+	tt := []struct {
+		name     string
+		cache    PlanCache
+		env      string
+		state    *planFetchState
+		valid    bool
+		errorMsg string
+	}{
+		{
+			name:  "no ttl",
+			state: &planFetchState{Plan: plan.Repository, Head: plan.Head, FetchedAt: now},
+			valid: false,
+		},
+		{
+			name:  "within ttl",
+			cache: PlanCache{TTL: "1h"},
+			state: &planFetchState{Plan: plan.Repository, Head: plan.Head, FetchedAt: now.Add(-30 * time.Minute)},
+			valid: true,
+		},
+		{
+			name:  "stale",
+			cache: PlanCache{TTL: "1h"},
+			state: &planFetchState{Plan: plan.Repository, Head: plan.Head, FetchedAt: now.Add(-2 * time.Hour)},
+			valid: false,
+		},
+		{
+			name:  "refresh",
+			cache: PlanCache{TTL: "1h", Refresh: true},
+			state: &planFetchState{Plan: plan.Repository, Head: plan.Head, FetchedAt: now},
+			valid: false,
+		},
+		{
+			name:  "never fetched",
+			cache: PlanCache{TTL: "1h"},
+			valid: false,
+		},
+		{
+			name:  "other head",
+			cache: PlanCache{TTL: "1h"},
+			state: &planFetchState{Plan: plan.Repository, Head: "v1.0.0", FetchedAt: now},
+			valid: false,
+		},
+		{
+			name:  "zero ttl disables env cache",
+			cache: PlanCache{TTL: "0s"},
+			env:   "60",
+			state: &planFetchState{Plan: plan.Repository, Head: plan.Head, FetchedAt: now},
+			valid: false,
+		},
+		{
+			name:  "env within ttl",
+			env:   "60",
+			state: &planFetchState{Plan: plan.Repository, Head: plan.Head, FetchedAt: now.Add(-30 * time.Minute)},
+			valid: true,
+		},
+		{
+			name:     "invalid ttl",
+			cache:    PlanCache{TTL: "1 hour"},
+			errorMsg: "exit code 2 - Failed to parse shuttle configuration: planTTL '1 hour' is invalid: must be a duration, eg. 1h\n\nMake sure your 'shuttle.yaml' is valid.",
+		},
+		{
+			name:     "invalid env",
+			env:      "an hour",
+			errorMsg: "SHUTTLE_CACHE_DURATION_MIN is not valid: an hour",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(cacheDurationMinKey, tc.env)
+			dir := t.TempDir()
+			if tc.state != nil {
+				require.NoError(t, writePlanFetchState(dir, Plan{Repository: tc.state.Plan, Head: tc.state.Head}, tc.state.FetchedAt))
+			}
+
+			valid, err := cacheIsValid(tc.cache, dir, plan, now)
+
+			if tc.errorMsg != "" {
+				assert.EqualError(t, err, tc.errorMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.valid, valid)
+		})
+	}
+}
+
+func TestCacheIsValid_corruptState(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(planFetchStatePath(dir), []byte("not json"), 0o644))
+
+	valid, err := cacheIsValid(PlanCache{TTL: "1h"}, dir, Plan{}, time.Now())
+
+	assert.NoError(t, err)
+	assert.False(t, valid)
+}
+
+func TestPlanCache_offline(t *testing.T) {
+	tt := []struct {
+		name     string
+		cache    PlanCache
+		env      string
+		offline  bool
+		errorMsg string
+	}{
+		{name: "online", offline: false},
+		{name: "offline", cache: PlanCache{Offline: true}, offline: true},
+		{name: "env", env: "1", offline: true},
+		{name: "env disabled", env: "false", offline: false},
+		{name: "flag takes precedence", cache: PlanCache{Offline: true}, env: "false", offline: true},
+		{name: "invalid env", env: "yes please", errorMsg: "SHUTTLE_OFFLINE is not valid: must be true or false but was yes please"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(offlineKey, tc.env)
+
+			offline, err := tc.cache.offline()
+
+			if tc.errorMsg != "" {
+				assert.EqualError(t, err, tc.errorMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.offline, offline)
+		})
+	}
+}
@@ -1,15 +1,20 @@
 package git
 
 import (
+	"context"
+	stderrors "errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path"
 	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
 	"time"
 
 	go_cmd "github.com/go-cmd/cmd"
+	"github.com/lunarway/shuttle/pkg/credentials"
 	"github.com/lunarway/shuttle/pkg/errors"
 	"github.com/lunarway/shuttle/pkg/ui"
 )
@@ -27,7 +32,10 @@ var gitRegex = regexp.MustCompile(
 	`^((git://((?P<user>[^@]+)@)?(?P<repository1>(?P<host>[^:]+):(?P<path>[^#]*)))|((?P<protocol>https)://(?P<repository2>.*\.git)))(#(?P<head>.*))?$`,
 )
 
-const cacheDurationMinKey = "SHUTTLE_CACHE_DURATION_MIN"
+const (
+	cacheDurationMinKey = "SHUTTLE_CACHE_DURATION_MIN"
+	offlineKey          = "SHUTTLE_OFFLINE"
+)
 
 func ParsePlan(plan string) Plan {
 	if !gitRegex.MatchString(plan) {
@@ -73,13 +81,16 @@ func IsPlan(plan string) bool {
 	return parsedGitPlan.IsGitPlan
 }
 
-// GetGitPlan will pull git repository and return its path
+// GetGitPlan will pull git repository and return its path. If commit is set the
+// plan is checked out at that commit instead of the head of the plan.
 func GetGitPlan(
 	plan string,
 	localShuttleDirectoryPath string,
 	uii *ui.UI,
 	skipGitPlanPulling bool,
 	planArgument string,
+	cache PlanCache,
+	commit string,
 ) (string, error) {
 	parsedGitPlan := ParsePlan(plan)
 
@@ -93,6 +104,10 @@ func GetGitPlan(
 	}
 
 	planPath := path.Join(localShuttleDirectoryPath, "plan")
+	offline, err := cache.offline()
+	if err != nil {
+		return "", err
+	}
 
 	plansAlreadyValidated := strings.Split(
 		os.Getenv("SHUTTLE_PLANS_ALREADY_VALIDATED"),
@@ -117,11 +132,25 @@ func GetGitPlan(
 			uii.EmphasizeInfoln("Found %v files locally changed in plan", len(status.files))
 			uii.EmphasizeInfoln("Skipping plan pull because of changes")
 		} else {
+			if commit != "" {
+				if offline && !hasCommit(planPath, commit) {
+					return "", errors.NewExitCode(
+						4,
+						"Locked plan commit %s is not available offline\n\nRun shuttle without --offline once to fetch it.",
+						commit,
+					)
+				}
+				return planPath, checkoutLockedCommit(planPath, parsedGitPlan, status, commit, skipGitPlanPulling || offline, uii)
+			}
 			if skipGitPlanPulling {
 				uii.Verboseln("Skipping git plan pulling")
 				return planPath, nil
 			}
-			valid, err := cacheIsValid(planPath)
+			if offline {
+				uii.Verboseln("Offline: using plan on %s at commit %s without fetching", status.branch, status.commit)
+				return planPath, nil
+			}
+			valid, err := cacheIsValid(cache, localShuttleDirectoryPath, parsedGitPlan, time.Now())
 			if err != nil {
 				return "", err
 			}
@@ -129,7 +158,10 @@ func GetGitPlan(
 				uii.Verboseln("Cache is still valid continuing")
 				return planPath, nil
 			}
-			err = gitCmd("fetch origin", planPath, uii)
+			if cache.Refresh {
+				uii.Verboseln("Refreshing git plan regardless of cache")
+			}
+			err = gitFetchCmd("fetch origin", planPath, parsedGitPlan, uii)
 			if err != nil {
 				return "", err
 			}
@@ -140,41 +172,49 @@ func GetGitPlan(
 			status := getStatus(planPath)
 			if !status.isDetached {
 				uii.Infoln("Pulling latest plan changes on %v", parsedGitPlan.Head)
-				err = gitCmd(fmt.Sprintf("pull origin %v", parsedGitPlan.Head), planPath, uii)
+				err = gitFetchCmd(fmt.Sprintf("pull origin %v", parsedGitPlan.Head), planPath, parsedGitPlan, uii)
 				if err != nil {
 					return "", err
 				}
 				status = getStatus(planPath)
-				currentTime := time.Now()
-				os.Chtimes(planPath, currentTime, currentTime)
 			} else {
 				uii.EmphasizeInfoln("Skipping plan pull because its running on detached head")
 			}
+			err = writePlanFetchState(localShuttleDirectoryPath, parsedGitPlan, time.Now())
+			if err != nil {
+				return "", err
+			}
 			uii.Verboseln("Using %s - branch %s - commit %s", plan, status.branch, status.commit)
 		}
 		return planPath, nil
 	} else {
+		if offline {
+			return "", errors.NewExitCode(
+				4,
+				"Plan %s has not been cloned to '%s' and cannot be cloned offline\n\nRun shuttle without --offline once to fetch it.",
+				plan,
+				planPath,
+			)
+		}
 		err := os.MkdirAll(localShuttleDirectoryPath, os.ModePerm)
 		if err != nil {
 			return "", fmt.Errorf("create '%s' directory: %w", localShuttleDirectoryPath, err)
 		}
 
-		var cloneArg string
-		if parsedGitPlan.Protocol == "https" {
-			cloneToken := os.Getenv("SHUTTLE_GIT_TOKEN")
-			if cloneToken == "" {
-				cloneArg = "https://" + parsedGitPlan.Repository
-			} else {
-				cloneArg = fmt.Sprintf("https://%s@%s", cloneToken, parsedGitPlan.Repository)
+		cloneArg := parsedGitPlan.cloneURL()
+		uii.Infoln("Cloning plan %s", cloneArg)
+		err = gitFetchCmd(fmt.Sprintf("clone %v --branch %v plan", cloneArg, parsedGitPlan.Head), localShuttleDirectoryPath, parsedGitPlan, uii)
+		if err != nil {
+			return "", err
+		}
+		if commit != "" {
+			uii.Infoln("Using locked plan commit %s", commit)
+			err = gitCmd(fmt.Sprintf("checkout %s", commit), planPath, uii)
+			if err != nil {
+				return "", err
 			}
-		} else if parsedGitPlan.Protocol == "ssh" {
-			cloneArg = parsedGitPlan.User + "@" + parsedGitPlan.Repository
-		} else {
-			panic(fmt.Sprintf("Unknown protocol '%s'", parsedGitPlan.Protocol))
 		}
-
-		uii.Infoln("Cloning plan %s", cloneArg)
-		err = gitCmd(fmt.Sprintf("clone %v --branch %v plan", cloneArg, parsedGitPlan.Head), localShuttleDirectoryPath, uii)
+		err = writePlanFetchState(localShuttleDirectoryPath, parsedGitPlan, time.Now())
 		if err != nil {
 			return "", err
 		}
@@ -183,27 +223,109 @@ func GetGitPlan(
 	return planPath, nil
 }
 
-// cacheIsValid optionally allows the plan to be cached, depending on when it was modified last. It is opt in only
-func cacheIsValid(planPath string) (bool, error) {
-	duration := os.Getenv(cacheDurationMinKey)
-	if duration == "" {
-		return false, nil
+// cloneURL returns the URL the plan is cloned from. HTTPS plans are cloned
+// with the token of SHUTTLE_GIT_TOKEN if set.
+func (p Plan) cloneURL() string {
+	switch p.Protocol {
+	case "https":
+		cloneToken := os.Getenv("SHUTTLE_GIT_TOKEN")
+		if cloneToken == "" {
+			return "https://" + p.Repository
+		}
+		return fmt.Sprintf("https://%s@%s", cloneToken, p.Repository)
+	case "ssh":
+		return p.User + "@" + p.Repository
+	default:
+		panic(fmt.Sprintf("Unknown protocol '%s'", p.Protocol))
 	}
+}
 
-	durationMin, err := strconv.Atoi(duration)
-	if err != nil {
-		return false, fmt.Errorf("%s is not valid: %s", cacheDurationMinKey, duration)
+// credentialEnv returns the environment git is run with to authenticate to
+// the repository of the plan. HTTPS plans not authenticated with
+// SHUTTLE_GIT_TOKEN send the credential of their host found by
+// credentials.Lookup in a header such that it is never stored in the clone.
+func (p Plan) credentialEnv() ([]string, error) {
+	if p.Protocol != "https" || os.Getenv("SHUTTLE_GIT_TOKEN") != "" {
+		return nil, nil
 	}
+	host := credentials.Host(p.Repository)
+	credential, ok, err := credentials.Lookup(host)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return gitConfigEnv(
+		fmt.Sprintf("http.https://%s/.extraHeader", host),
+		"Authorization: "+credential.BasicAuth(),
+	), nil
+}
+
+// credentialHint returns a hint on how to authenticate to the repository of
+// the plan if git failed without credentials.
+func (p Plan) credentialHint(authenticated bool) string {
+	switch {
+	case p.Protocol == "https" && !authenticated && os.Getenv("SHUTTLE_GIT_TOKEN") == "":
+		host := credentials.Host(p.Repository)
+		return fmt.Sprintf("No credentials found for %s: run `shuttle auth login %s` or set SHUTTLE_GIT_TOKEN if the plan is private\n", host, host)
+	case p.Protocol == "ssh" && runtime.GOOS != "windows" && os.Getenv("SSH_AUTH_SOCK") == "":
+		return "No SSH agent is running: start one with `eval $(ssh-agent)` and add your key with ssh-add if the plan is private\n"
+	}
+	return ""
+}
+
+// gitConfigEnv returns environment variables setting a git configuration key
+// in addition to any already set with GIT_CONFIG_COUNT.
+func gitConfigEnv(key string, value string) []string {
+	count, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	return []string{
+		fmt.Sprintf("GIT_CONFIG_COUNT=%d", count+1),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", count, key),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", count, value),
+	}
+}
 
-	fi, err := os.Stat(planPath)
+// Reachable returns an error if the repository of the git plan cannot be
+// reached, eg. because of network problems or missing access. git never
+// prompts for credentials while checking.
+func Reachable(ctx context.Context, plan string) error {
+	parsedGitPlan := ParsePlan(plan)
+	env, err := parsedGitPlan.credentialEnv()
 	if err != nil {
-		return false, fmt.Errorf("path doesn't exist: %w", err)
+		return err
 	}
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", parsedGitPlan.cloneURL(), "HEAD")
+	cmd.Env = append(append(os.Environ(), env...), "GIT_TERMINAL_PROMPT=0")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		message := strings.TrimSpace(string(output))
+		if token := os.Getenv("SHUTTLE_GIT_TOKEN"); token != "" {
+			message = strings.ReplaceAll(message, token, "***")
+		}
+		return fmt.Errorf("%w: %s", err, message)
+	}
+	return nil
+}
 
-	folderTime := fi.ModTime()
-	cacheTime := time.Now().Add(-time.Minute * time.Duration(durationMin))
+// checkoutLockedCommit checks out commit in the plan unless it is already
+// checked out. The plan is fetched first unless skipGitPlanPulling is set.
+func checkoutLockedCommit(planPath string, plan Plan, status Status, commit string, skipGitPlanPulling bool, uii *ui.UI) error {
+	if status.commit == commit {
+		uii.Verboseln("Plan is at locked commit %s", commit)
+		return nil
+	}
+	uii.Infoln("Using locked plan commit %s", commit)
+	if !skipGitPlanPulling {
+		err := gitFetchCmd("fetch origin", planPath, plan, uii)
+		if err != nil {
+			return err
+		}
+	}
+	return gitCmd(fmt.Sprintf("checkout %s", commit), planPath, uii)
+}
 
-	return cacheTime.Before(folderTime), nil
+// hasCommit returns whether commit is available in the repository at dir
+// without fetching it.
+func hasCommit(dir string, commit string) bool {
+	return syncGitCmd(fmt.Sprintf("cat-file -e '%s^{commit}'", commit), dir).Exit == 0
 }
 
 func RunGitPlanCommand(command string, plan string, uii *ui.UI) {
@@ -253,13 +375,34 @@ func fileAvailable(name string) bool {
 	return true
 }
 
+// gitFetchCmd runs a git command fetching from the repository of plan with the
+// credentials of its host. Failures hint at how to authenticate.
+func gitFetchCmd(command string, dir string, plan Plan, uii *ui.UI) error {
+	env, err := plan.credentialEnv()
+	if err != nil {
+		return errors.NewExitCode(4, "Failed to find credentials of plan %s: %v", plan.Repository, err)
+	}
+	err = gitCmdEnv(command, dir, env, uii)
+	var exitCode *errors.ExitCode
+	if hint := plan.credentialHint(len(env) != 0); hint != "" && stderrors.As(err, &exitCode) {
+		exitCode.Message += hint
+	}
+	return err
+}
+
 func gitCmd(command string, dir string, uii *ui.UI) error {
+	return gitCmdEnv(command, dir, nil, uii)
+}
+
+// gitCmdEnv runs a git command with env in addition to the environment of
+// shuttle.
+func gitCmdEnv(command string, dir string, env []string, uii *ui.UI) error {
 	cmdOptions := go_cmd.Options{
 		Buffered:  true,
 		Streaming: true,
 	}
 	execCmd := go_cmd.NewCmdOptions(cmdOptions, "sh", "-c", "cd '"+dir+"'; git "+command)
-	execCmd.Env = os.Environ()
+	execCmd.Env = append(os.Environ(), env...)
 	doneChan := make(chan struct{})
 	go func() {
 		defer close(doneChan)
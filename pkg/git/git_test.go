@@ -0,0 +1,122 @@
+package git
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestGetGitPlan_offline(t *testing.T) {
+	const plan = "https://github.com/lunarway/shuttle-example-go-plan.git"
+	t.Setenv(offlineKey, "")
+	t.Setenv("SHUTTLE_PLANS_ALREADY_VALIDATED", "")
+	offline := PlanCache{Offline: true}
+
+	t.Run("not cloned", func(t *testing.T) {
+		dir := t.TempDir()
+
+		_, err := GetGitPlan(plan, dir, ui.Create(&bytes.Buffer{}, &bytes.Buffer{}), false, "", offline, "")
+
+		assert.EqualError(t, err, "exit code 4 - Plan "+plan+" has not been cloned to '"+filepath.Join(dir, "plan")+
+			"' and cannot be cloned offline\n\nRun shuttle without --offline once to fetch it.")
+	})
+
+	t.Run("cloned", func(t *testing.T) {
+		dir := t.TempDir()
+		planPath := filepath.Join(dir, "plan")
+		gitCommand(t, dir, "clone", "--quiet", initRepository(t), "plan")
+		// the plan cannot be fetched as its origin is gone
+		gitCommand(t, planPath, "remote", "set-url", "origin", filepath.Join(dir, "missing"))
+
+		path, err := GetGitPlan(plan, dir, ui.Create(&bytes.Buffer{}, &bytes.Buffer{}), false, "", offline, "")
+
+		require.NoError(t, err)
+		assert.Equal(t, planPath, path)
+	})
+
+	t.Run("locked commit not available", func(t *testing.T) {
+		dir := t.TempDir()
+		gitCommand(t, dir, "clone", "--quiet", initRepository(t), "plan")
+		commit := "0123456789abcdef0123456789abcdef01234567"
+
+		_, err := GetGitPlan(plan, dir, ui.Create(&bytes.Buffer{}, &bytes.Buffer{}), false, "", offline, commit)
+
+		assert.EqualError(t, err, "exit code 4 - Locked plan commit "+commit+
+			" is not available offline\n\nRun shuttle without --offline once to fetch it.")
+	})
+}
+
+func TestPlan_credentialEnv(t *testing.T) {
+	keyring.MockInit()
+	netrc := filepath.Join(t.TempDir(), "netrc")
+	require.NoError(t, os.WriteFile(netrc, []byte("machine github.com login user password token\n"), 0o600))
+	t.Setenv("NETRC", netrc)
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	t.Setenv("GIT_CONFIG_COUNT", "")
+	t.Setenv("SHUTTLE_GIT_TOKEN", "")
+	const header = "Authorization: Basic dXNlcjp0b2tlbg=="
+
+	tt := []struct {
+		name   string
+		plan   string
+		env    map[string]string
+		gitEnv []string
+		hint   string
+	}{
+		{
+			name: "https",
+			plan: "https://github.com/lunarway/shuttle-example-go-plan.git",
+			gitEnv: []string{
+				"GIT_CONFIG_COUNT=1",
+				"GIT_CONFIG_KEY_0=http.https://github.com/.extraHeader",
+				"GIT_CONFIG_VALUE_0=" + header,
+			},
+		},
+		{
+			name: "existing git configuration",
+			plan: "https://github.com/lunarway/shuttle-example-go-plan.git",
+			env:  map[string]string{"GIT_CONFIG_COUNT": "2"},
+			gitEnv: []string{
+				"GIT_CONFIG_COUNT=3",
+				"GIT_CONFIG_KEY_2=http.https://github.com/.extraHeader",
+				"GIT_CONFIG_VALUE_2=" + header,
+			},
+		},
+		{
+			name: "SHUTTLE_GIT_TOKEN",
+			plan: "https://github.com/lunarway/shuttle-example-go-plan.git",
+			env:  map[string]string{"SHUTTLE_GIT_TOKEN": "token"},
+		},
+		{
+			name: "no credentials",
+			plan: "https://gitlab.com/lunarway/plan.git",
+			hint: "No credentials found for gitlab.com: run `shuttle auth login gitlab.com` or set SHUTTLE_GIT_TOKEN if the plan is private\n",
+		},
+		{
+			name: "ssh with agent",
+			plan: "git://git@github.com:lunarway/shuttle-example-go-plan.git",
+			env:  map[string]string{"SSH_AUTH_SOCK": "/tmp/agent.sock"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			for name, value := range tc.env {
+				t.Setenv(name, value)
+			}
+			plan := ParsePlan(tc.plan)
+
+			gitEnv, err := plan.credentialEnv()
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.gitEnv, gitEnv)
+			assert.Equal(t, tc.hint, plan.credentialHint(len(gitEnv) != 0))
+		})
+	}
+}
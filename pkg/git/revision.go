@@ -0,0 +1,70 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// FileChange is a file changed between two commits.
+type FileChange struct {
+	// Status is the status of the file as reported by git diff --name-status,
+	// eg. A for added, M for modified and D for deleted
+	Status string
+	Path   string
+}
+
+// FetchRevision fetches the plan cloned to planPath and returns the commit of
+// ref, a branch, tag or commit of the plan. If ref is empty the head of the
+// plan is used. Branches resolve to their latest fetched commit.
+func FetchRevision(planPath string, plan string, ref string, uii *ui.UI) (string, error) {
+	parsedGitPlan := ParsePlan(plan)
+	if ref == "" {
+		ref = parsedGitPlan.Head
+	}
+	err := gitFetchCmd("fetch --tags origin", planPath, parsedGitPlan, uii)
+	if err != nil {
+		return "", err
+	}
+	for _, candidate := range []string{"refs/remotes/origin/" + ref, "refs/tags/" + ref, ref} {
+		status := syncGitCmd(fmt.Sprintf("rev-parse --verify --quiet '%s^{commit}'", candidate), planPath)
+		if status.Exit == 0 && len(status.Stdout) != 0 {
+			return status.Stdout[0], nil
+		}
+	}
+	return "", errors.NewExitCode(2, "Plan %s has no branch, tag or commit '%s'", plan, ref)
+}
+
+// ExportRevision writes the files of commit in the repository at dir to the
+// existing directory dest without touching the checkout of dir.
+func ExportRevision(dir string, commit string, dest string) error {
+	if err := os.MkdirAll(dest, os.ModePerm); err != nil {
+		return fmt.Errorf("create '%s' directory: %w", dest, err)
+	}
+	status := syncGitCmd(fmt.Sprintf("archive --format=tar '%s' | tar -xf - -C '%s'", commit, dest), dir)
+	if status.Exit != 0 {
+		return fmt.Errorf("export commit %s: %s", commit, strings.Join(status.Stderr, "\n"))
+	}
+	return nil
+}
+
+// ChangedFilesBetween returns the files changed from commit from to commit to
+// in the repository at dir.
+func ChangedFilesBetween(dir string, from string, to string) ([]FileChange, error) {
+	status := syncGitCmd(fmt.Sprintf("diff --name-status --no-renames '%s' '%s'", from, to), dir)
+	if status.Exit != 0 {
+		return nil, fmt.Errorf("diff commits %s and %s: %s", from, to, strings.Join(status.Stderr, "\n"))
+	}
+	var changes []FileChange
+	for _, line := range status.Stdout {
+		fileStatus, path, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		changes = append(changes, FileChange{Status: fileStatus, Path: path})
+	}
+	return changes, nil
+}
@@ -0,0 +1,82 @@
+package git
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+func TestRevisions(t *testing.T) {
+	const plan = "https://github.com/lunarway/shuttle-example-go-plan.git"
+	// the token keeps credentials from being looked up for the plan
+	t.Setenv("SHUTTLE_GIT_TOKEN", "token")
+	origin := initRepository(t)
+	gitCommand(t, origin, "branch", "-M", "master")
+	first := Commit(origin)
+	dir := t.TempDir()
+	gitCommand(t, dir, "clone", "--quiet", origin, "plan")
+	planPath := filepath.Join(dir, "plan")
+
+	require.NoError(t, os.WriteFile(filepath.Join(origin, "plan.yaml"), []byte("scripts:\n  build: {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(origin, "build.tmpl"), []byte("{{.}}"), 0o644))
+	gitCommand(t, origin, "add", ".")
+	gitCommand(t, origin, "-c", "user.name=shuttle", "-c", "user.email=shuttle@example.com", "commit", "--quiet", "-m", "build")
+	gitCommand(t, origin, "tag", "v2")
+	second := Commit(origin)
+	uii := ui.Create(&bytes.Buffer{}, &bytes.Buffer{})
+
+	t.Run("fetch revision", func(t *testing.T) {
+		tt := []struct {
+			name   string
+			ref    string
+			commit string
+			err    string
+		}{
+			{name: "head", ref: "", commit: second},
+			{name: "branch", ref: "master", commit: second},
+			{name: "tag", ref: "v2", commit: second},
+			{name: "commit", ref: first, commit: first},
+			{name: "unknown", ref: "v3", err: "exit code 2 - Plan " + plan + " has no branch, tag or commit 'v3'"},
+		}
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				commit, err := FetchRevision(planPath, plan, tc.ref, uii)
+				if tc.err != "" {
+					assert.EqualError(t, err, tc.err)
+					return
+				}
+				require.NoError(t, err)
+				assert.Equal(t, tc.commit, commit)
+			})
+		}
+		assert.Equal(t, first, Commit(planPath), "checked out commit")
+	})
+
+	t.Run("export revision", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "revision")
+
+		err := ExportRevision(planPath, first, dest)
+
+		require.NoError(t, err)
+		content, err := os.ReadFile(filepath.Join(dest, "plan.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, "scripts: {}", string(content))
+		assert.NoFileExists(t, filepath.Join(dest, "build.tmpl"))
+	})
+
+	t.Run("changed files between", func(t *testing.T) {
+		changes, err := ChangedFilesBetween(planPath, first, second)
+
+		require.NoError(t, err)
+		assert.Equal(t, []FileChange{
+			{Status: "A", Path: "build.tmpl"},
+			{Status: "M", Path: "plan.yaml"},
+		}, changes)
+	})
+}
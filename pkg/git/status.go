@@ -1,7 +1,9 @@
 package git
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	go_cmd "github.com/go-cmd/cmd"
@@ -245,3 +247,54 @@ func isChange(statusType StatusType) bool {
 // This is only present in a renamed/copied entry, and
 // tells where the renamed/copied contents came from.
 // --------------------------------------------------------
+
+// ErrNotRepository is returned when a directory is not the root of a git
+// repository.
+var ErrNotRepository = errors.New("not a git repository")
+
+// ChangedFiles returns the paths of files with uncommitted changes in the git
+// repository rooted at dir. Like the plan pulling logic, untracked files are
+// not considered changes. If dir is not the root of a git repository
+// ErrNotRepository is returned.
+func ChangedFiles(dir string) ([]string, error) {
+	topLevel := syncGitCmd("rev-parse --show-toplevel", dir)
+	if topLevel.Exit != 0 || len(topLevel.Stdout) == 0 {
+		return nil, ErrNotRepository
+	}
+	if !samePath(topLevel.Stdout[0], dir) {
+		return nil, ErrNotRepository
+	}
+
+	status := getStatus(dir)
+	if status.mergeState {
+		return nil, fmt.Errorf("repository is in merge state")
+	}
+
+	var files []string
+	for _, file := range status.files {
+		if isChange(file.IndexStatus) || isChange(file.WorkTreeStatus) {
+			files = append(files, file.FilePath)
+		}
+	}
+	return files, nil
+}
+
+// Commit returns the commit checked out in the git repository at dir or an
+// empty string if it has none, eg. as dir is not a repository.
+func Commit(dir string) string {
+	return getStatus(dir).commit
+}
+
+func samePath(a, b string) bool {
+	a, err := filepath.EvalSymlinks(a)
+	if err != nil {
+		return false
+	}
+	b, err = filepath.EvalSymlinks(b)
+	if err != nil {
+		return false
+	}
+	a, _ = filepath.Abs(a)
+	b, _ = filepath.Abs(b)
+	return a == b
+}
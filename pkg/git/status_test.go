@@ -0,0 +1,74 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangedFiles(t *testing.T) {
+	t.Run("not a repository", func(t *testing.T) {
+		_, err := ChangedFiles(t.TempDir())
+
+		assert.ErrorIs(t, err, ErrNotRepository)
+	})
+
+	t.Run("sub directory of a repository", func(t *testing.T) {
+		dir := initRepository(t)
+		subDir := filepath.Join(dir, "sub")
+		require.NoError(t, os.Mkdir(subDir, 0o755))
+
+		_, err := ChangedFiles(subDir)
+
+		assert.ErrorIs(t, err, ErrNotRepository)
+	})
+
+	t.Run("clean repository", func(t *testing.T) {
+		dir := initRepository(t)
+
+		files, err := ChangedFiles(dir)
+
+		assert.NoError(t, err)
+		assert.Empty(t, files)
+	})
+
+	t.Run("modified and untracked files", func(t *testing.T) {
+		dir := initRepository(t)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "plan.yaml"), []byte("changed"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "new.sh"), []byte("new"), 0o644))
+
+		files, err := ChangedFiles(dir)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"plan.yaml"}, files)
+	})
+}
+
+func gitCommand(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
+func initRepository(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "plan.yaml"), []byte("scripts: {}"), 0o644))
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.name=shuttle", "-c", "user.email=shuttle@example.com", "commit", "--quiet", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	return dir
+}
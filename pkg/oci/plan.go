@@ -0,0 +1,205 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+const (
+	// ArtifactTypePlan is the artifact type of plans pushed by shuttle
+	ArtifactTypePlan = "application/vnd.lunarway.shuttle.plan.v1"
+	// MediaTypePlanLayer is the media type of the single layer of a plan
+	// holding its files as a gzipped tar archive
+	MediaTypePlanLayer = "application/vnd.lunarway.shuttle.plan.layer.v1.tar+gzip"
+)
+
+// planPullState is the state file recording the digest of the pulled plan.
+type planPullState struct {
+	Plan   string `json:"plan"`
+	Digest string `json:"digest"`
+}
+
+func planPullStatePath(localShuttleDirectoryPath string) string {
+	return path.Join(localShuttleDirectoryPath, "plan-oci.json")
+}
+
+// GetPlan pulls the OCI plan into the plan directory of
+// localShuttleDirectoryPath and returns its path. The plan is only downloaded
+// if the digest of the reference changed since it was last pulled and a plan
+// pinned by digest is never resolved again.
+func GetPlan(
+	ctx context.Context,
+	plan string,
+	localShuttleDirectoryPath string,
+	uii *ui.UI,
+	skipPlanPulling bool,
+	planArgument string,
+) (string, error) {
+	ref, err := ParseReference(plan)
+	if err != nil {
+		return "", err
+	}
+	if planArgument != "" {
+		ref, err = ref.Overload(planArgument)
+		if err != nil {
+			return "", err
+		}
+		uii.EmphasizeInfoln("Overload OCI plan with %v", ref)
+	}
+
+	planPath := path.Join(localShuttleDirectoryPath, "plan")
+	state, pulled := readPlanPullState(localShuttleDirectoryPath, planPath, ref)
+	if pulled && skipPlanPulling {
+		uii.Verboseln("Skipping OCI plan pulling")
+		return planPath, nil
+	}
+	if pulled && ref.Digest != "" && state.Digest == ref.Digest {
+		uii.Verboseln("Plan pinned by digest is already pulled")
+		return planPath, nil
+	}
+
+	client := newClient(ref, "pull")
+	m, digest, err := client.getManifest(ctx)
+	if err != nil {
+		return "", errors.NewExitCode(1, "Failed to pull plan %s: %v", ref, err)
+	}
+	if pulled && state.Digest == digest {
+		uii.Verboseln("Using %s - digest %s", ref, digest)
+		return planPath, nil
+	}
+	layer, err := planLayer(m)
+	if err != nil {
+		return "", errors.NewExitCode(1, "Failed to pull plan %s: %v", ref, err)
+	}
+
+	uii.Infoln("Pulling plan %s", ref)
+	archive, err := client.getBlob(ctx, layer)
+	if err != nil {
+		return "", errors.NewExitCode(1, "Failed to pull plan %s: %v", ref, err)
+	}
+
+	// the plan is extracted next to the plan directory and renamed into place
+	// such that a partially extracted plan is never used
+	tmpPath := planPath + ".tmp"
+	if err := os.RemoveAll(tmpPath); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(tmpPath, os.ModePerm); err != nil {
+		return "", fmt.Errorf("create '%s' directory: %w", tmpPath, err)
+	}
+	if err := extractArchive(archive, tmpPath); err != nil {
+		os.RemoveAll(tmpPath)
+		return "", errors.NewExitCode(1, "Failed to unpack plan %s: %v", ref, err)
+	}
+	if err := os.RemoveAll(planPath); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmpPath, planPath); err != nil {
+		return "", err
+	}
+	if err := writePlanPullState(localShuttleDirectoryPath, planPullState{Plan: ref.String(), Digest: digest}); err != nil {
+		return "", err
+	}
+	uii.Verboseln("Using %s - digest %s", ref, digest)
+	return planPath, nil
+}
+
+// PulledDigest returns the manifest digest of the OCI plan last pulled into
+// localShuttleDirectoryPath or an empty string if no plan is pulled.
+func PulledDigest(localShuttleDirectoryPath string) string {
+	content, err := os.ReadFile(planPullStatePath(localShuttleDirectoryPath))
+	if err != nil {
+		return ""
+	}
+	var state planPullState
+	if err := json.Unmarshal(content, &state); err != nil {
+		return ""
+	}
+	return state.Digest
+}
+
+// readPlanPullState returns the state of the last pull and whether the plan of
+// ref is pulled into planPath.
+func readPlanPullState(localShuttleDirectoryPath, planPath string, ref Reference) (planPullState, bool) {
+	content, err := os.ReadFile(planPullStatePath(localShuttleDirectoryPath))
+	if err != nil {
+		return planPullState{}, false
+	}
+	var state planPullState
+	// a corrupt state file is treated as if the plan was never pulled as it is
+	// rewritten by the pull
+	if err := json.Unmarshal(content, &state); err != nil || state.Plan != ref.String() {
+		return planPullState{}, false
+	}
+	if _, err := os.Stat(planPath); err != nil {
+		return planPullState{}, false
+	}
+	return state, true
+}
+
+func writePlanPullState(localShuttleDirectoryPath string, state planPullState) error {
+	content, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshal plan pull state: %w", err)
+	}
+	statePath := planPullStatePath(localShuttleDirectoryPath)
+	if err := os.WriteFile(statePath, content, 0o644); err != nil {
+		return fmt.Errorf("write plan pull state '%s': %w", statePath, err)
+	}
+	return nil
+}
+
+// planLayer returns the layer holding the files of the plan of m.
+func planLayer(m manifest) (descriptor, error) {
+	if len(m.Layers) != 1 || m.Layers[0].MediaType != MediaTypePlanLayer {
+		return descriptor{}, fmt.Errorf("artifact is not a shuttle plan: it must have a single layer of type %s", MediaTypePlanLayer)
+	}
+	return m.Layers[0], nil
+}
+
+// PushPlan pushes the plan in dir to the tag of the OCI reference and returns
+// the digest of the pushed manifest. The .git and .shuttle directories of the
+// plan are left out.
+func PushPlan(ctx context.Context, raw string, dir string) (string, error) {
+	ref, err := ParseReference(raw)
+	if err != nil {
+		return "", err
+	}
+	if ref.Digest != "" {
+		return "", errors.NewExitCode(2, "Cannot push plan to %s: the reference must have a tag and not a digest", ref)
+	}
+	if _, err := os.Stat(path.Join(dir, "plan.yaml")); err != nil {
+		return "", errors.NewExitCode(2, "Cannot push plan from '%s': the directory has no plan.yaml", dir)
+	}
+
+	archive, err := archiveDirectory(dir, []string{".git", ".shuttle"})
+	if err != nil {
+		return "", fmt.Errorf("archive plan '%s': %w", dir, err)
+	}
+	client := newClient(ref, "pull,push")
+	config, err := client.pushBlob(ctx, mediaTypeEmpty, []byte("{}"))
+	if err != nil {
+		return "", errors.NewExitCode(1, "Failed to push plan to %s: %v", ref, err)
+	}
+	layer, err := client.pushBlob(ctx, MediaTypePlanLayer, archive)
+	if err != nil {
+		return "", errors.NewExitCode(1, "Failed to push plan to %s: %v", ref, err)
+	}
+	digest, err := client.putManifest(ctx, manifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeManifest,
+		ArtifactType:  ArtifactTypePlan,
+		Config:        config,
+		Layers:        []descriptor{layer},
+	})
+	if err != nil {
+		return "", errors.NewExitCode(1, "Failed to push plan to %s: %v", ref, err)
+	}
+	return digest, nil
+}
@@ -0,0 +1,187 @@
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// registry is an OCI registry holding manifests and blobs in memory. It
+// requires a bearer token issued by its /token endpoint.
+type registry struct {
+	lock      sync.Mutex
+	manifests map[string][]byte
+	blobs     map[string][]byte
+	blobGets  int
+}
+
+func newRegistry(t *testing.T) (*registry, string) {
+	r := &registry{manifests: map[string][]byte{}, blobs: map[string][]byte{}}
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return r, strings.TrimPrefix(server.URL, "http://")
+}
+
+func (r *registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if req.URL.Path == "/token" {
+		fmt.Fprint(w, `{"token":"secret"}`)
+		return
+	}
+	if req.Header.Get("Authorization") != "Bearer secret" {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="registry"`, req.Host))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	path := strings.TrimPrefix(req.URL.Path, "/v2/org/plan")
+	switch {
+	case strings.HasPrefix(path, "/manifests/"):
+		reference := strings.TrimPrefix(path, "/manifests/")
+		switch req.Method {
+		case http.MethodPut:
+			content, _ := io.ReadAll(req.Body)
+			r.manifests[reference] = content
+			r.manifests[sha256Digest(content)] = content
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodGet:
+			content, ok := r.manifests[reference]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", sha256Digest(content))
+			w.Write(content)
+		}
+	case path == "/blobs/uploads/":
+		w.Header().Set("Location", "/v2/org/plan/blobs/uploads/1")
+		w.WriteHeader(http.StatusAccepted)
+	case path == "/blobs/uploads/1":
+		content, _ := io.ReadAll(req.Body)
+		r.blobs[req.URL.Query().Get("digest")] = content
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, "/blobs/"):
+		content, ok := r.blobs[strings.TrimPrefix(path, "/blobs/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if req.Method == http.MethodGet {
+			r.blobGets++
+			w.Write(content)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func writePlan(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), os.ModePerm))
+		require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestPushAndGetPlan(t *testing.T) {
+	registry, host := newRegistry(t)
+	ctx := context.Background()
+	uii := ui.Create(&bytes.Buffer{}, &bytes.Buffer{})
+	plan := "oci://" + host + "/org/plan:v1"
+	planDir := writePlan(t, map[string]string{
+		"plan.yaml":          "scripts: {}\n",
+		"scripts/build.sh":   "echo build\n",
+		".git/HEAD":          "ref: refs/heads/main\n",
+		".shuttle/plan.json": "{}\n",
+	})
+
+	digest, err := PushPlan(ctx, plan, planDir)
+	require.NoError(t, err)
+	again, err := PushPlan(ctx, plan, planDir)
+	require.NoError(t, err)
+	assert.Equal(t, digest, again, "pushing the same plan must produce the same digest")
+
+	shuttleDir := filepath.Join(t.TempDir(), ".shuttle")
+	planPath, err := GetPlan(ctx, plan, shuttleDir, uii, false, "")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(shuttleDir, "plan"), planPath)
+	content, err := os.ReadFile(filepath.Join(planPath, "scripts", "build.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, "echo build\n", string(content))
+	assert.NoDirExists(t, filepath.Join(planPath, ".git"))
+	assert.NoDirExists(t, filepath.Join(planPath, ".shuttle"))
+
+	_, err = GetPlan(ctx, plan, shuttleDir, uii, false, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, registry.blobGets, "an unchanged plan must not be downloaded again")
+
+	pinned, err := GetPlan(ctx, "oci://"+host+"/org/plan@"+digest, t.TempDir(), uii, false, "")
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(pinned, "plan.yaml"))
+}
+
+func TestGetPlan_digestMismatch(t *testing.T) {
+	registry, host := newRegistry(t)
+	ctx := context.Background()
+	uii := ui.Create(&bytes.Buffer{}, &bytes.Buffer{})
+	plan := "oci://" + host + "/org/plan:v1"
+	_, err := PushPlan(ctx, plan, writePlan(t, map[string]string{"plan.yaml": "scripts: {}\n"}))
+	require.NoError(t, err)
+
+	t.Run("pinned manifest", func(t *testing.T) {
+		pinned := "sha256:" + strings.Repeat("a", 64)
+		// a registry serving other content than the pinned digest is caught
+		registry.lock.Lock()
+		registry.manifests[pinned] = registry.manifests["v1"]
+		registry.lock.Unlock()
+
+		_, err := GetPlan(ctx, "oci://"+host+"/org/plan:v1@"+pinned, t.TempDir(), uii, false, "")
+
+		assert.ErrorContains(t, err, "has digest sha256:")
+		assert.ErrorContains(t, err, "but "+pinned+" was expected")
+	})
+
+	t.Run("tampered layer", func(t *testing.T) {
+		registry.lock.Lock()
+		for digest := range registry.blobs {
+			registry.blobs[digest] = append(registry.blobs[digest], 'x')
+		}
+		registry.lock.Unlock()
+		shuttleDir := t.TempDir()
+
+		_, err := GetPlan(ctx, plan, shuttleDir, uii, false, "")
+
+		assert.ErrorContains(t, err, "Failed to pull plan "+plan+": blob has digest")
+		assert.NoDirExists(t, filepath.Join(shuttleDir, "plan"))
+	})
+}
+
+func TestExtractArchive_outsideDestination(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "../escape", Mode: 0o644}))
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	err := extractArchive(buf.Bytes(), t.TempDir())
+
+	assert.EqualError(t, err, "entry '../escape' is outside the archive")
+}
@@ -0,0 +1,105 @@
+package oci
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/errors"
+)
+
+const scheme = "oci://"
+
+// Reference is a reference to an artifact in an OCI registry, eg.
+// oci://ghcr.io/org/plan:v1.2.3
+type Reference struct {
+	// Registry is the host of the registry including any port, eg. ghcr.io
+	Registry string
+	// Repository is the repository within the registry, eg. org/plan
+	Repository string
+	// Tag is the tag of the artifact. It is latest if neither a tag nor a
+	// digest is given.
+	Tag string
+	// Digest pins the manifest of the artifact, eg. sha256:abc...
+	Digest string
+}
+
+var (
+	repositoryRegexp = regexp.MustCompile(`^[a-z0-9]+([._-][a-z0-9]+)*(/[a-z0-9]+([._-][a-z0-9]+)*)*$`)
+	tagRegexp        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	digestRegexp     = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// IsPlan returns true if plan is an OCI plan
+func IsPlan(plan string) bool {
+	return strings.HasPrefix(plan, scheme)
+}
+
+// ParseReference parses an oci:// reference to an artifact.
+func ParseReference(raw string) (Reference, error) {
+	rest, ok := strings.CutPrefix(raw, scheme)
+	if !ok {
+		return Reference{}, invalidReference(raw, "must start with oci://")
+	}
+	registry, name, ok := strings.Cut(rest, "/")
+	if !ok || registry == "" {
+		return Reference{}, invalidReference(raw, "must be on the form oci://registry/repository[:tag][@digest]")
+	}
+
+	ref := Reference{Registry: registry}
+	rest, ref.Digest, _ = strings.Cut(name, "@")
+	// a colon after the last slash separates the tag
+	if i := strings.LastIndex(rest, ":"); i > strings.LastIndex(rest, "/") {
+		ref.Tag = rest[i+1:]
+		rest = rest[:i]
+	}
+	ref.Repository = rest
+
+	if !repositoryRegexp.MatchString(ref.Repository) {
+		return Reference{}, invalidReference(raw, fmt.Sprintf("repository '%s' must be lowercase alphanumerics separated by /, ., _ or -", ref.Repository))
+	}
+	if ref.Tag != "" && !tagRegexp.MatchString(ref.Tag) {
+		return Reference{}, invalidReference(raw, fmt.Sprintf("tag '%s' is invalid", ref.Tag))
+	}
+	if ref.Digest != "" && !digestRegexp.MatchString(ref.Digest) {
+		return Reference{}, invalidReference(raw, fmt.Sprintf("digest '%s' must be on the form sha256:<hex>", ref.Digest))
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+	return ref, nil
+}
+
+func invalidReference(raw, reason string) error {
+	return errors.NewExitCode(2, "OCI reference '%s' is invalid: %s", raw, reason)
+}
+
+// Overload returns the reference with the tag or digest of planArgument, ie.
+// :<tag> or @<digest>.
+func (r Reference) Overload(planArgument string) (Reference, error) {
+	if !strings.HasPrefix(planArgument, ":") && !strings.HasPrefix(planArgument, "@") {
+		return Reference{}, fmt.Errorf("Plan argument wasn't valid for an OCI plan (:<tag> or @<digest>): %s", planArgument)
+	}
+	return ParseReference(scheme + r.Registry + "/" + r.Repository + planArgument)
+}
+
+// manifestReference returns the reference of the manifest in the registry API,
+// ie. the digest if pinned and the tag otherwise.
+func (r Reference) manifestReference() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// String returns the reference on the form oci://registry/repository:tag@digest.
+func (r Reference) String() string {
+	s := scheme + r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
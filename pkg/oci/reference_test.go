@@ -0,0 +1,84 @@
+package oci
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	tt := []struct {
+		name  string
+		input string
+		ref   Reference
+		err   string
+	}{
+		{
+			name:  "tag",
+			input: "oci://ghcr.io/org/plan:v1.2.3",
+			ref:   Reference{Registry: "ghcr.io", Repository: "org/plan", Tag: "v1.2.3"},
+		},
+		{
+			name:  "default tag",
+			input: "oci://ghcr.io/org/plan",
+			ref:   Reference{Registry: "ghcr.io", Repository: "org/plan", Tag: "latest"},
+		},
+		{
+			name:  "digest",
+			input: "oci://ghcr.io/org/plan@" + digest,
+			ref:   Reference{Registry: "ghcr.io", Repository: "org/plan", Digest: digest},
+		},
+		{
+			name:  "tag and digest",
+			input: "oci://ghcr.io/org/plan:v1@" + digest,
+			ref:   Reference{Registry: "ghcr.io", Repository: "org/plan", Tag: "v1", Digest: digest},
+		},
+		{
+			name:  "registry port",
+			input: "oci://localhost:5000/plan",
+			ref:   Reference{Registry: "localhost:5000", Repository: "plan", Tag: "latest"},
+		},
+		{
+			name:  "no repository",
+			input: "oci://ghcr.io",
+			err:   "exit code 2 - OCI reference 'oci://ghcr.io' is invalid: must be on the form oci://registry/repository[:tag][@digest]",
+		},
+		{
+			name:  "uppercase repository",
+			input: "oci://ghcr.io/Org/plan",
+			err:   "exit code 2 - OCI reference 'oci://ghcr.io/Org/plan' is invalid: repository 'Org/plan' must be lowercase alphanumerics separated by /, ., _ or -",
+		},
+		{
+			name:  "invalid digest",
+			input: "oci://ghcr.io/org/plan@sha256:abc",
+			err:   "exit code 2 - OCI reference 'oci://ghcr.io/org/plan@sha256:abc' is invalid: digest 'sha256:abc' must be on the form sha256:<hex>",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ref, err := ParseReference(tc.input)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.ref, ref)
+			roundTrip, err := ParseReference(ref.String())
+			assert.NoError(t, err)
+			assert.Equal(t, ref, roundTrip, "string must parse to the same reference")
+		})
+	}
+}
+
+func TestReference_Overload(t *testing.T) {
+	ref := Reference{Registry: "ghcr.io", Repository: "org/plan", Tag: "v1"}
+
+	overloaded, err := ref.Overload(":v2")
+	assert.NoError(t, err)
+	assert.Equal(t, Reference{Registry: "ghcr.io", Repository: "org/plan", Tag: "v2"}, overloaded)
+
+	_, err = ref.Overload("v2")
+	assert.EqualError(t, err, "Plan argument wasn't valid for an OCI plan (:<tag> or @<digest>): v2")
+}
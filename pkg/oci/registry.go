@@ -0,0 +1,357 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/credentials"
+)
+
+const (
+	// MediaTypeManifest is the media type of OCI image manifests
+	MediaTypeManifest = "application/vnd.oci.image.manifest.v1+json"
+	// mediaTypeEmpty is the media type of the empty config of artifacts
+	mediaTypeEmpty = "application/vnd.oci.empty.v1+json"
+)
+
+// descriptor describes content stored in a registry
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// manifest is an OCI image manifest
+type manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	ArtifactType  string       `json:"artifactType,omitempty"`
+	Config        descriptor   `json:"config"`
+	Layers        []descriptor `json:"layers"`
+}
+
+// client is a client of the distribution API of an OCI registry. Credentials
+// are read from SHUTTLE_OCI_USERNAME and SHUTTLE_OCI_PASSWORD or looked up
+// with credentials.Lookup once the registry demands them and used for basic
+// authentication or to request bearer tokens. Anonymous bearer tokens are
+// requested without credentials.
+type client struct {
+	http     *http.Client
+	ref      Reference
+	username string
+	password string
+	// source is where the credentials are from, eg. docker config
+	source string
+	// lookedUp is set once credentials have been looked up
+	lookedUp bool
+	// actions are the actions on the repository tokens are requested for, eg.
+	// pull or pull,push
+	actions string
+	// token is the bearer token of the repository once authenticated
+	token string
+}
+
+func newClient(ref Reference, actions string) *client {
+	c := &client{
+		http:     http.DefaultClient,
+		ref:      ref,
+		actions:  actions,
+		username: os.Getenv("SHUTTLE_OCI_USERNAME"),
+		password: os.Getenv("SHUTTLE_OCI_PASSWORD"),
+	}
+	if c.username != "" {
+		c.source = "SHUTTLE_OCI_USERNAME"
+		c.lookedUp = true
+	}
+	return c
+}
+
+// lookupCredential looks up the credentials of the registry unless they have
+// been looked up before and returns whether any were found.
+func (c *client) lookupCredential() (bool, error) {
+	if c.lookedUp {
+		return false, nil
+	}
+	c.lookedUp = true
+	credential, ok, err := credentials.Lookup(c.ref.Registry)
+	if err != nil {
+		return false, fmt.Errorf("find credentials of registry %s: %w", c.ref.Registry, err)
+	}
+	if !ok {
+		return false, nil
+	}
+	c.username, c.password, c.source = credential.Username, credential.Password, credential.Source
+	return true, nil
+}
+
+// baseURL returns the URL of the repository in the registry API. Registries on
+// localhost are accessed over plain http like docker does.
+func (c *client) baseURL() string {
+	protocol := "https"
+	host := c.ref.Registry
+	if i := strings.LastIndex(host, ":"); i != -1 {
+		host = host[:i]
+	}
+	if host == "localhost" || host == "127.0.0.1" {
+		protocol = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s", protocol, c.ref.Registry, c.ref.Repository)
+}
+
+// getManifest returns the manifest of the reference and its digest. The digest
+// is verified against the pinned digest of the reference and the digest
+// reported by the registry.
+func (c *client) getManifest(ctx context.Context) (manifest, string, error) {
+	resp, err := c.do(ctx, http.MethodGet, c.baseURL()+"/manifests/"+c.ref.manifestReference(), nil, map[string]string{
+		"Accept": MediaTypeManifest,
+	})
+	if err != nil {
+		return manifest{}, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return manifest{}, "", unexpectedStatus("get manifest of "+c.ref.String(), resp)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return manifest{}, "", fmt.Errorf("read manifest of %s: %w", c.ref, err)
+	}
+
+	digest := sha256Digest(content)
+	for _, expected := range []string{c.ref.Digest, resp.Header.Get("Docker-Content-Digest")} {
+		if expected != "" && expected != digest {
+			return manifest{}, "", fmt.Errorf("manifest of %s has digest %s but %s was expected", c.ref, digest, expected)
+		}
+	}
+
+	var m manifest
+	if err := json.Unmarshal(content, &m); err != nil {
+		return manifest{}, "", fmt.Errorf("parse manifest of %s: %w", c.ref, err)
+	}
+	return m, digest, nil
+}
+
+// getBlob returns the content of the blob of desc after verifying its digest.
+func (c *client) getBlob(ctx context.Context, desc descriptor) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, c.baseURL()+"/blobs/"+desc.Digest, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, unexpectedStatus("get blob "+desc.Digest, resp)
+	}
+	// a blob is never read beyond its declared size
+	content, err := io.ReadAll(io.LimitReader(resp.Body, desc.Size+1))
+	if err != nil {
+		return nil, fmt.Errorf("read blob %s: %w", desc.Digest, err)
+	}
+	if digest := sha256Digest(content); digest != desc.Digest {
+		return nil, fmt.Errorf("blob has digest %s but %s was expected", digest, desc.Digest)
+	}
+	return content, nil
+}
+
+// pushBlob uploads content unless the registry already has it and returns its
+// descriptor.
+func (c *client) pushBlob(ctx context.Context, mediaType string, content []byte) (descriptor, error) {
+	desc := descriptor{MediaType: mediaType, Digest: sha256Digest(content), Size: int64(len(content))}
+	resp, err := c.do(ctx, http.MethodHead, c.baseURL()+"/blobs/"+desc.Digest, nil, nil)
+	if err != nil {
+		return descriptor{}, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return desc, nil
+	}
+
+	resp, err = c.do(ctx, http.MethodPost, c.baseURL()+"/blobs/uploads/", nil, nil)
+	if err != nil {
+		return descriptor{}, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return descriptor{}, unexpectedStatus("start upload of blob "+desc.Digest, resp)
+	}
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return descriptor{}, fmt.Errorf("parse upload location of blob %s: %w", desc.Digest, err)
+	}
+	query := location.Query()
+	query.Set("digest", desc.Digest)
+	location.RawQuery = query.Encode()
+
+	resp, err = c.do(ctx, http.MethodPut, location.String(), content, map[string]string{
+		"Content-Type": "application/octet-stream",
+	})
+	if err != nil {
+		return descriptor{}, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return descriptor{}, unexpectedStatus("upload blob "+desc.Digest, resp)
+	}
+	return desc, nil
+}
+
+// putManifest uploads m as the tag of the reference and returns its digest.
+func (c *client) putManifest(ctx context.Context, m manifest) (string, error) {
+	content, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.do(ctx, http.MethodPut, c.baseURL()+"/manifests/"+c.ref.Tag, content, map[string]string{
+		"Content-Type": m.MediaType,
+	})
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", unexpectedStatus("put manifest of "+c.ref.String(), resp)
+	}
+	return sha256Digest(content), nil
+}
+
+// do sends a request authenticating as the registry demands.
+func (c *client) do(ctx context.Context, method, rawURL string, body []byte, headers map[string]string) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		switch {
+		case c.token != "":
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		case c.username != "":
+			req.SetBasicAuth(c.username, c.password)
+		}
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("request registry %s: %w", c.ref.Registry, err)
+		}
+		return resp, nil
+	}
+
+	resp, err := send()
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || c.token != "" {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	found, err := c.lookupCredential()
+	if err != nil {
+		return nil, err
+	}
+	scheme, _, _ := strings.Cut(challenge, " ")
+	if found && !strings.EqualFold(scheme, "Bearer") {
+		return send()
+	}
+	if err := c.authenticate(ctx, challenge); err != nil {
+		return nil, err
+	}
+	return send()
+}
+
+// authenticate requests a bearer token of the repository with the credentials
+// of the client as demanded by challenge.
+func (c *client) authenticate(ctx context.Context, challenge string) error {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		if c.username == "" {
+			return fmt.Errorf(
+				"registry %s requires credentials: run shuttle auth login %s, docker login or set SHUTTLE_OCI_USERNAME and SHUTTLE_OCI_PASSWORD",
+				c.ref.Registry, c.ref.Registry,
+			)
+		}
+		return fmt.Errorf("registry %s rejected the credentials of %s", c.ref.Registry, c.source)
+	}
+	values := parseChallenge(params)
+	realm, err := url.Parse(values["realm"])
+	if err != nil || values["realm"] == "" {
+		return fmt.Errorf("registry %s sent an invalid authentication challenge: %s", c.ref.Registry, challenge)
+	}
+	query := realm.Query()
+	if service := values["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:%s", c.ref.Repository, c.actions))
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("request token of registry %s: %w", c.ref.Registry, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return unexpectedStatus("request token of registry "+c.ref.Registry, resp)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("parse token of registry %s: %w", c.ref.Registry, err)
+	}
+	c.token = token.Token
+	if c.token == "" {
+		c.token = token.AccessToken
+	}
+	if c.token == "" {
+		return fmt.Errorf("registry %s returned no token", c.ref.Registry)
+	}
+	return nil
+}
+
+// parseChallenge parses the comma separated key="value" parameters of a
+// WWW-Authenticate challenge.
+func parseChallenge(params string) map[string]string {
+	values := make(map[string]string)
+	for params != "" {
+		key, rest, ok := strings.Cut(strings.TrimLeft(params, ", "), "=")
+		if !ok {
+			break
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end == -1 {
+				break
+			}
+			value, rest = rest[1:end+1], rest[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		values[strings.ToLower(strings.TrimSpace(key))] = value
+		params = rest
+	}
+	return values
+}
+
+func unexpectedStatus(action string, resp *http.Response) error {
+	return fmt.Errorf("%s: unexpected status %s", action, resp.Status)
+}
+
+func sha256Digest(content []byte) string {
+	digest := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(digest[:])
+}
@@ -0,0 +1,106 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/lunarway/shuttle/pkg/config"
+)
+
+// Resolver resolves secrets of a project. The sops, vault and aws CLIs are
+// used for SOPS, Vault and SSM secrets such that their usual configuration and
+// authentication applies.
+type Resolver struct {
+	projectPath string
+	// run runs a CLI and returns its stdout
+	run func(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// NewResolver returns a resolver of secrets with files relative to
+// projectPath.
+func NewResolver(projectPath string) *Resolver {
+	return &Resolver{projectPath: projectPath, run: runCLI}
+}
+
+// Resolve returns the value of secret. Trailing newlines are removed from the
+// value.
+func (r *Resolver) Resolve(ctx context.Context, secret config.ShuttleSecret) (string, error) {
+	provider, reference := secret.Provider()
+	var (
+		value []byte
+		err   error
+	)
+	switch provider {
+	case config.SecretProviderEnv:
+		v, ok := os.LookupEnv(reference)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", reference)
+		}
+		value = []byte(v)
+	case config.SecretProviderFile:
+		value, err = os.ReadFile(r.projectFile(reference))
+	case config.SecretProviderSops:
+		args := []string{"--decrypt"}
+		if secret.Key != "" {
+			args = append(args, "--extract", sopsPath(secret.Key))
+		}
+		value, err = r.run(ctx, "sops", append(args, r.projectFile(reference))...)
+	case config.SecretProviderVault:
+		value, err = r.run(ctx, "vault", "kv", "get", "-field="+secret.Key, reference)
+	case config.SecretProviderSSM:
+		value, err = r.run(
+			ctx,
+			"aws", "ssm", "get-parameter",
+			"--name", reference,
+			"--with-decryption",
+			"--query", "Parameter.Value",
+			"--output", "text",
+		)
+	default:
+		return "", errors.New("secret has no provider")
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(value), "\r\n"), nil
+}
+
+func (r *Resolver) projectFile(file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(r.projectPath, file)
+}
+
+// sopsPath returns the sops --extract path of a dot separated key, eg.
+// ["db"]["password"] for db.password.
+func sopsPath(key string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(key, ".") {
+		fmt.Fprintf(&b, "[%q]", part)
+	}
+	return b.String()
+}
+
+func runCLI(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, fmt.Errorf("the %s CLI is not installed", name)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%s failed: %s", name, message)
+		}
+		return nil, fmt.Errorf("%s failed: %w", name, err)
+	}
+	return stdout.Bytes(), nil
+}
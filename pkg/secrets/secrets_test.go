@@ -0,0 +1,97 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/config"
+)
+
+func TestResolver_Resolve(t *testing.T) {
+	projectPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(projectPath, "token"), []byte("file-s3cr3t\n"), 0o600))
+	t.Setenv("SHUTTLE_TEST_SECRET", "env-s3cr3t")
+
+	tt := []struct {
+		name    string
+		secret  config.ShuttleSecret
+		command []string
+		value   string
+		err     string
+	}{
+		{
+			name:   "env",
+			secret: config.ShuttleSecret{Env: "SHUTTLE_TEST_SECRET"},
+			value:  "env-s3cr3t",
+		},
+		{
+			name:   "unset env",
+			secret: config.ShuttleSecret{Env: "SHUTTLE_TEST_UNSET_SECRET"},
+			err:    "environment variable SHUTTLE_TEST_UNSET_SECRET is not set",
+		},
+		{
+			name:   "file",
+			secret: config.ShuttleSecret{File: "token"},
+			value:  "file-s3cr3t",
+		},
+		{
+			name:    "sops",
+			secret:  config.ShuttleSecret{Sops: "secrets.enc.yaml"},
+			command: []string{"sops", "--decrypt", filepath.Join(projectPath, "secrets.enc.yaml")},
+			value:   "cli-s3cr3t",
+		},
+		{
+			name:    "sops with key",
+			secret:  config.ShuttleSecret{Sops: "secrets.enc.yaml", Key: "db.password"},
+			command: []string{"sops", "--decrypt", "--extract", `["db"]["password"]`, filepath.Join(projectPath, "secrets.enc.yaml")},
+			value:   "cli-s3cr3t",
+		},
+		{
+			name:    "vault",
+			secret:  config.ShuttleSecret{Vault: "secret/ci", Key: "token"},
+			command: []string{"vault", "kv", "get", "-field=token", "secret/ci"},
+			value:   "cli-s3cr3t",
+		},
+		{
+			name:    "ssm",
+			secret:  config.ShuttleSecret{SSM: "/ci/token"},
+			command: []string{"aws", "ssm", "get-parameter", "--name", "/ci/token", "--with-decryption", "--query", "Parameter.Value", "--output", "text"},
+			value:   "cli-s3cr3t",
+		},
+		{
+			name:    "failing cli",
+			secret:  config.ShuttleSecret{SSM: "/ci/missing"},
+			command: []string{"aws", "ssm", "get-parameter", "--name", "/ci/missing", "--with-decryption", "--query", "Parameter.Value", "--output", "text"},
+			err:     "aws failed: ParameterNotFound",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var command []string
+			resolver := NewResolver(projectPath)
+			resolver.run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				command = append([]string{name}, args...)
+				if tc.err != "" {
+					return nil, errors.New(tc.err)
+				}
+				return []byte(tc.value + "\n"), nil
+			}
+
+			value, err := resolver.Resolve(context.Background(), tc.secret)
+
+			assert.Equal(t, tc.command, command, "command")
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.value, value)
+		})
+	}
+}
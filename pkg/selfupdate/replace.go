@@ -0,0 +1,31 @@
+package selfupdate
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Executable returns the path of the running shuttle executable with symbolic
+// links resolved, eg. of package managers.
+func Executable() (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(executable)
+}
+
+// Replace replaces executable with binary. binary must be in the directory of
+// executable for the replacement to be atomic. binary is given the mode of
+// executable, or 0755 if it cannot be read, as temporary files are only
+// readable by their owner.
+func Replace(executable, binary string) error {
+	mode := os.FileMode(0o755)
+	if info, err := os.Stat(executable); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.Chmod(binary, mode); err != nil {
+		return err
+	}
+	return replace(executable, binary)
+}
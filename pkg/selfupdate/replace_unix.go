@@ -0,0 +1,11 @@
+//go:build !windows
+
+package selfupdate
+
+import "os"
+
+// replace renames binary over executable. The running process keeps the
+// replaced file open until it exits.
+func replace(executable, binary string) error {
+	return os.Rename(binary, executable)
+}
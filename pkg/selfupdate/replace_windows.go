@@ -0,0 +1,21 @@
+package selfupdate
+
+import "os"
+
+// replace moves executable aside before renaming binary into its place as
+// Windows does not allow replacing or removing a running executable, only
+// renaming it. The executable moved aside is removed by the next update.
+func replace(executable, binary string) error {
+	old := executable + ".old"
+	// left behind by the previous update
+	_ = os.Remove(old)
+	if err := os.Rename(executable, old); err != nil {
+		return err
+	}
+	if err := os.Rename(binary, executable); err != nil {
+		// restore the running executable
+		_ = os.Rename(old, executable)
+		return err
+	}
+	return nil
+}
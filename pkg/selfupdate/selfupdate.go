@@ -0,0 +1,221 @@
+// Package selfupdate finds releases of shuttle and replaces the running
+// executable with a verified binary of one of them.
+package selfupdate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/lunarway/shuttle/pkg/signature"
+)
+
+// DefaultFeed is the GitHub API listing the releases of shuttle.
+const DefaultFeed = "https://api.github.com/repos/lunarway/shuttle/releases"
+
+// Channels of releases
+const (
+	// ChannelStable are releases that are not prereleases
+	ChannelStable = "stable"
+	// ChannelPrerelease are all releases including prereleases
+	ChannelPrerelease = "prerelease"
+)
+
+// ChecksumsAsset is the asset of a release with the SHA-256 checksums of its
+// binaries. Its signature is the asset of the same name with the extension of
+// the signature files of the public key, eg. shuttle-checksums.txt.minisig.
+const ChecksumsAsset = "shuttle-checksums.txt"
+
+// Release is a release of shuttle as listed by the GitHub API.
+type Release struct {
+	TagName    string  `json:"tag_name"`
+	Prerelease bool    `json:"prerelease"`
+	Draft      bool    `json:"draft"`
+	Assets     []Asset `json:"assets"`
+}
+
+// Asset is a file of a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Version returns the semantic version of the release.
+func (r Release) Version() (*semver.Version, error) {
+	return semver.NewVersion(r.TagName)
+}
+
+func (r Release) asset(name string) (Asset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return Asset{}, false
+}
+
+// AssetName returns the name of the binary of releases for goos and goarch,
+// eg. shuttle-linux-amd64.
+func AssetName(goos, goarch string) string {
+	name := fmt.Sprintf("shuttle-%s-%s", goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// LatestRelease returns the release of channel with the highest version of
+// those listed by feed. Drafts and releases not tagged with a semantic version
+// are left out.
+func LatestRelease(ctx context.Context, feed, channel string) (Release, error) {
+	if channel != ChannelStable && channel != ChannelPrerelease {
+		return Release{}, fmt.Errorf("unknown channel '%s'", channel)
+	}
+	body, err := get(ctx, feed, "application/vnd.github+json")
+	if err != nil {
+		return Release{}, err
+	}
+	var releases []Release
+	if err := json.Unmarshal(body, &releases); err != nil {
+		return Release{}, fmt.Errorf("decode releases of %s: %w", feed, err)
+	}
+
+	var (
+		latest        Release
+		latestVersion *semver.Version
+	)
+	for _, release := range releases {
+		version, err := release.Version()
+		if err != nil || release.Draft {
+			continue
+		}
+		if channel == ChannelStable && (release.Prerelease || version.Prerelease() != "") {
+			continue
+		}
+		if latestVersion == nil || version.GreaterThan(latestVersion) {
+			latest, latestVersion = release, version
+		}
+	}
+	if latestVersion == nil {
+		return Release{}, fmt.Errorf("no %s release found at %s", channel, feed)
+	}
+	return latest, nil
+}
+
+// Download writes the binary named asset of release to path once its checksum
+// is verified. If key is not nil the checksums must be signed by it.
+func Download(ctx context.Context, release Release, asset string, key signature.PublicKey, path string) error {
+	binary, ok := release.asset(asset)
+	if !ok {
+		return fmt.Errorf("release %s has no binary %s", release.TagName, asset)
+	}
+	checksumsAsset, ok := release.asset(ChecksumsAsset)
+	if !ok {
+		return fmt.Errorf("release %s has no checksums in %s", release.TagName, ChecksumsAsset)
+	}
+	checksums, err := get(ctx, checksumsAsset.URL, "")
+	if err != nil {
+		return err
+	}
+	if key != nil {
+		signatureName := ChecksumsAsset + filepath.Ext(key.SignatureFile())
+		signatureAsset, ok := release.asset(signatureName)
+		if !ok {
+			return fmt.Errorf("release %s has no signature of its checksums in %s", release.TagName, signatureName)
+		}
+		sig, err := get(ctx, signatureAsset.URL, "")
+		if err != nil {
+			return err
+		}
+		if err := key.Verify(checksums, sig); err != nil {
+			return fmt.Errorf("signature of the checksums of release %s is invalid: %w", release.TagName, err)
+		}
+	}
+	expected, ok := checksumOf(checksums, asset)
+	if !ok {
+		return fmt.Errorf("release %s has no checksum of %s", release.TagName, asset)
+	}
+
+	resp, err := request(ctx, binary.URL, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o755)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(file, hash), resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("download %s: %w", binary.URL, err)
+	}
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+		return fmt.Errorf("checksum of %s is %s but must be %s", asset, actual, expected)
+	}
+	return nil
+}
+
+// checksumOf returns the checksum of name in checksums written by sha256sum.
+func checksumOf(checksums []byte, name string) (string, bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), true
+		}
+	}
+	return "", false
+}
+
+// get returns the body of a successful GET request of url.
+func get(ctx context.Context, url, accept string) ([]byte, error) {
+	resp, err := request(ctx, url, accept)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", url, err)
+	}
+	return body, nil
+}
+
+// request sends a GET request of url authenticated with GITHUB_TOKEN if set,
+// eg. to not be rate limited in CI. Responses other than 2xx are errors.
+func request(ctx context.Context, url, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" && strings.HasPrefix(url, "https://api.github.com/") {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s responded with status %s", url, resp.Status)
+	}
+	return resp, nil
+}
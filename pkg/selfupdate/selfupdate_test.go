@@ -0,0 +1,238 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/signature"
+)
+
+func TestLatestRelease(t *testing.T) {
+	releases := []Release{
+		{TagName: "v1.1.0"},
+		{TagName: "v2.0.0", Draft: true},
+		{TagName: "v1.2.0-rc.1", Prerelease: true},
+		{TagName: "nightly", Prerelease: true},
+		{TagName: "v1.0.3"},
+		{TagName: "v1.1.1-beta.1"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/releases":
+			_ = json.NewEncoder(w).Encode(releases)
+		case "/empty":
+			_, _ = w.Write([]byte("[]"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tt := []struct {
+		name    string
+		feed    string
+		channel string
+		tag     string
+		err     string
+	}{
+		{
+			name:    "stable",
+			feed:    server.URL + "/releases",
+			channel: ChannelStable,
+			tag:     "v1.1.0",
+		},
+		{
+			name:    "prerelease",
+			feed:    server.URL + "/releases",
+			channel: ChannelPrerelease,
+			tag:     "v1.2.0-rc.1",
+		},
+		{
+			name:    "no releases",
+			feed:    server.URL + "/empty",
+			channel: ChannelStable,
+			err:     "no stable release found at " + server.URL + "/empty",
+		},
+		{
+			name:    "unavailable feed",
+			feed:    server.URL + "/missing",
+			channel: ChannelStable,
+			err:     server.URL + "/missing responded with status 404 Not Found",
+		},
+		{
+			name:    "unknown channel",
+			feed:    server.URL + "/releases",
+			channel: "nightly",
+			err:     "unknown channel 'nightly'",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			release, err := LatestRelease(context.Background(), tc.feed, tc.channel)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.tag, release.TagName)
+		})
+	}
+}
+
+func TestDownload(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+	key, err := signature.ParsePublicKey(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+	require.NoError(t, err)
+
+	binary := []byte("#!/bin/sh\necho shuttle v1.1.0\n")
+	sum := sha256.Sum256(binary)
+	checksums := []byte(hex.EncodeToString(sum[:]) + "  shuttle-linux-amd64\n" + hex.EncodeToString(make([]byte, 32)) + "  shuttle-darwin-arm64\n")
+	files := map[string][]byte{
+		"/shuttle-linux-amd64":       binary,
+		"/shuttle-darwin-arm64":      binary,
+		"/shuttle-checksums.txt":     checksums,
+		"/shuttle-checksums.txt.sig": []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, checksums))),
+		"/other.sig":                 []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(otherKey, checksums))),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+	asset := func(name, path string) Asset {
+		return Asset{Name: name, URL: server.URL + path}
+	}
+	release := Release{TagName: "v1.1.0", Assets: []Asset{
+		asset("shuttle-linux-amd64", "/shuttle-linux-amd64"),
+		asset("shuttle-darwin-arm64", "/shuttle-darwin-arm64"),
+		asset("shuttle-checksums.txt", "/shuttle-checksums.txt"),
+		asset("shuttle-checksums.txt.sig", "/shuttle-checksums.txt.sig"),
+	}}
+
+	tt := []struct {
+		name    string
+		release Release
+		asset   string
+		key     signature.PublicKey
+		err     string
+	}{
+		{
+			name:    "verified checksum",
+			release: release,
+			asset:   "shuttle-linux-amd64",
+		},
+		{
+			name:    "verified signature",
+			release: release,
+			asset:   "shuttle-linux-amd64",
+			key:     key,
+		},
+		{
+			name:    "checksum mismatch",
+			release: release,
+			asset:   "shuttle-darwin-arm64",
+			err:     "checksum of shuttle-darwin-arm64 is " + hex.EncodeToString(sum[:]) + " but must be " + hex.EncodeToString(make([]byte, 32)),
+		},
+		{
+			name:    "no binary for platform",
+			release: release,
+			asset:   "shuttle-windows-amd64.exe",
+			err:     "release v1.1.0 has no binary shuttle-windows-amd64.exe",
+		},
+		{
+			name:    "no checksum of binary",
+			release: Release{TagName: "v1.1.0", Assets: []Asset{asset("shuttle-linux-arm64", "/shuttle-linux-amd64"), release.Assets[2]}},
+			asset:   "shuttle-linux-arm64",
+			err:     "release v1.1.0 has no checksum of shuttle-linux-arm64",
+		},
+		{
+			name:    "no checksums",
+			release: Release{TagName: "v1.1.0", Assets: release.Assets[:1]},
+			asset:   "shuttle-linux-amd64",
+			err:     "release v1.1.0 has no checksums in shuttle-checksums.txt",
+		},
+		{
+			name:    "no signature",
+			release: Release{TagName: "v1.1.0", Assets: release.Assets[:3]},
+			asset:   "shuttle-linux-amd64",
+			key:     key,
+			err:     "release v1.1.0 has no signature of its checksums in shuttle-checksums.txt.sig",
+		},
+		{
+			name: "signed by other key",
+			release: Release{TagName: "v1.1.0", Assets: append(append([]Asset{}, release.Assets[:3]...),
+				asset("shuttle-checksums.txt.sig", "/other.sig"))},
+			asset: "shuttle-linux-amd64",
+			key:   key,
+			err:   "signature of the checksums of release v1.1.0 is invalid: signature does not match",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "shuttle")
+
+			err := Download(context.Background(), tc.release, tc.asset, tc.key, path)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, binary, content)
+		})
+	}
+}
+
+func TestReplace(t *testing.T) {
+	dir := t.TempDir()
+	executable := filepath.Join(dir, "shuttle")
+	binary := filepath.Join(dir, ".shuttle-update")
+	require.NoError(t, os.WriteFile(executable, []byte("old"), 0o755))
+	// temporary files are created with mode 0600
+	require.NoError(t, os.WriteFile(binary, []byte("new"), 0o600))
+
+	require.NoError(t, Replace(executable, binary))
+
+	content, err := os.ReadFile(executable)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+	assert.NoFileExists(t, binary)
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(executable)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o755), info.Mode().Perm(), "expected the mode of the replaced executable")
+	}
+}
+
+func TestAssetName(t *testing.T) {
+	assert.Equal(t, "shuttle-linux-amd64", AssetName("linux", "amd64"))
+	assert.Equal(t, "shuttle-darwin-arm64", AssetName("darwin", "arm64"))
+	assert.Equal(t, "shuttle-windows-amd64.exe", AssetName("windows", "amd64"))
+}
@@ -0,0 +1,264 @@
+package shuttle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"sync"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/executors"
+	"github.com/lunarway/shuttle/pkg/executors/golang/executer"
+	"github.com/lunarway/shuttle/pkg/telemetry"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// Options configures a Runner. The zero value runs scripts of the project in
+// the working directory like shuttle run without flags.
+type Options struct {
+	// ProjectPath is the directory of the project. Parent directories are
+	// searched for a shuttle.yaml if it is empty. Defaults to the working
+	// directory.
+	ProjectPath string
+	// Plan overrides the plan of shuttle.yaml like --plan.
+	Plan string
+	// Profile activates a profile of shuttle.yaml like --profile. Defaults to
+	// SHUTTLE_PROFILE.
+	Profile string
+	// SkipGitPlanPulling uses the plan already fetched to the project like
+	// --skip-pull.
+	SkipGitPlanPulling bool
+	// Offline uses the git plans last checked out without fetching them like
+	// --offline. Defaults to SHUTTLE_OFFLINE.
+	Offline bool
+	// InsecureSkipVerify does not verify the signature of the plan like
+	// --insecure-skip-verify.
+	InsecureSkipVerify bool
+	// SkipArgValidation runs scripts with unknown and without required
+	// arguments like --validate=false.
+	SkipArgValidation bool
+	// Stdout and Stderr receive the output of shuttle and the actions. They
+	// default to os.Stdout and os.Stderr.
+	Stdout io.Writer
+	Stderr io.Writer
+	// Stdin is connected to shell actions. Actions have no stdin if it is
+	// nil.
+	Stdin io.Reader
+	// Format is the format output is written in. Defaults to
+	// ui.FormatText.
+	Format ui.Format
+	// Verbose writes verbose output like --verbose.
+	Verbose bool
+	// ShuttleVersion is the version of shuttle the requires.shuttle constraint
+	// of the plan is checked against. Defaults to the version of the shuttle
+	// module the program is built with. The constraint is not checked for
+	// development versions.
+	ShuttleVersion string
+	// ExecuteOptions are applied to every run, eg. executors.WithDryRun.
+	ExecuteOptions []executors.ExecuteOption
+}
+
+// Runner runs scripts of a shuttle project like shuttle run. The plan of the
+// project is resolved on first use and reused by later runs.
+type Runner struct {
+	opts     Options
+	ui       *ui.UI
+	registry *executors.Registry
+
+	load    sync.Once
+	project config.ShuttleProjectContext
+	loadErr error
+}
+
+// NewRunner returns a runner of scripts configured by opts.
+func NewRunner(opts Options) *Runner {
+	stdout, stderr := opts.Stdout, opts.Stderr
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	uii := ui.Create(stdout, stderr)
+	if opts.Format != "" {
+		uii.SetFormat(opts.Format)
+	}
+	if opts.Verbose {
+		uii.SetUserLevel(ui.LevelVerbose)
+	}
+	return &Runner{
+		opts:     opts,
+		ui:       uii,
+		registry: executors.NewDefaultRegistry(),
+	}
+}
+
+// modulePath is the path of the shuttle module.
+const modulePath = "github.com/lunarway/shuttle"
+
+// moduleVersion returns the version of the shuttle module the program is built
+// with or an empty string if it is unknown.
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != modulePath {
+			continue
+		}
+		if dep.Replace != nil {
+			return dep.Replace.Version
+		}
+		return dep.Version
+	}
+	return ""
+}
+
+// NewProjectRunner returns a runner of scripts of an already resolved project.
+// Output is written to the UI of project and the plan related fields of opts
+// are ignored.
+func NewProjectRunner(project config.ShuttleProjectContext, opts Options) *Runner {
+	r := &Runner{
+		opts:     opts,
+		ui:       project.UI,
+		registry: executors.NewDefaultRegistry(),
+		project:  project,
+	}
+	r.load.Do(func() {})
+	return r
+}
+
+// Scripts returns the scripts of the project by name including those running
+// golang actions.
+func (r *Runner) Scripts(ctx context.Context) (map[string]config.ShuttlePlanScript, error) {
+	project, err := r.projectContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return project.Scripts, nil
+}
+
+// Run runs script with args like shuttle run. The requirements of the plan are
+// checked and the guards of the project are run first. args are validated
+// against the script unless SkipArgValidation is set. Failures are returned as
+// *errors.ExitCode with the exit code shuttle would exit with.
+func (r *Runner) Run(ctx context.Context, script string, args map[string]string) error {
+	project, err := r.projectContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.ui.Flush()
+	return r.run(ctx, project, r.opts.Stdin, script, args)
+}
+
+// validate returns an error if script is not a script of project or args are
+// invalid for it unless SkipArgValidation is set.
+func (r *Runner) validate(project config.ShuttleProjectContext, script string, args map[string]string) error {
+	if _, ok := project.Scripts[script]; !ok {
+		return errors.NewExitCode(2, "Script '%s' not found", script)
+	}
+	if r.opts.SkipArgValidation {
+		return nil
+	}
+	return executors.Validate(project, script, formatArgs(args))
+}
+
+// run runs script of project writing output to the UI of project.
+func (r *Runner) run(ctx context.Context, project config.ShuttleProjectContext, stdin io.Reader, script string, args map[string]string) error {
+	if err := r.validate(project, script, args); err != nil {
+		return err
+	}
+	if telemetry.ContextIDFrom(ctx) == "" {
+		ctx = telemetry.WithContextID(ctx)
+	}
+	if telemetry.RunIDFrom(ctx) == "" {
+		ctx = telemetry.WithRunID(ctx)
+	}
+	shuttleVersion := r.opts.ShuttleVersion
+	if shuttleVersion == "" {
+		shuttleVersion = moduleVersion()
+	}
+	options := []executors.ExecuteOption{
+		executors.WithStdin(stdin),
+		executors.WithRunMetadata(executors.RunMetadata{ShuttleVersion: shuttleVersion}),
+	}
+	options = append(options, r.opts.ExecuteOptions...)
+	return r.registry.Run(ctx, project, script, args, !r.opts.SkipArgValidation, options...)
+}
+
+// formatArgs returns args on the form <argument>=<value> in a stable order.
+func formatArgs(args map[string]string) []string {
+	formatted := make([]string, 0, len(args))
+	for name, value := range args {
+		formatted = append(formatted, fmt.Sprintf("%s=%s", name, value))
+	}
+	sort.Strings(formatted)
+	return formatted
+}
+
+// projectContext resolves the project and its plan once.
+func (r *Runner) projectContext(ctx context.Context) (config.ShuttleProjectContext, error) {
+	r.load.Do(func() {
+		r.project, r.loadErr = r.loadProject(ctx)
+	})
+	return r.project, r.loadErr
+}
+
+func (r *Runner) loadProject(ctx context.Context) (config.ShuttleProjectContext, error) {
+	projectPath := r.opts.ProjectPath
+	strictConfigLookup := projectPath != ""
+	if !filepath.IsAbs(projectPath) {
+		dir, err := os.Getwd()
+		if err != nil {
+			return config.ShuttleProjectContext{}, err
+		}
+		projectPath = filepath.Join(dir, projectPath)
+	}
+	plan := r.opts.Plan
+	if plan == "" {
+		plan = os.Getenv("SHUTTLE_PLAN_OVERLOAD")
+	}
+
+	profile := r.opts.Profile
+	if profile == "" {
+		profile = os.Getenv("SHUTTLE_PROFILE")
+	}
+
+	spanAttributes := map[string]string{telemetry.TelemetryPlan: plan}
+	_, endSpan := telemetry.StartSpan(ctx, "shuttle.plan", spanAttributes)
+	var c config.ShuttleProjectContext
+	_, err := c.Setup(
+		projectPath,
+		r.ui,
+		false,
+		r.opts.SkipGitPlanPulling,
+		false,
+		r.opts.Offline,
+		plan,
+		strictConfigLookup,
+		false,
+		r.opts.InsecureSkipVerify,
+		profile,
+	)
+	if plan == "" {
+		spanAttributes[telemetry.TelemetryPlan] = c.Config.Plan
+	}
+	endSpan(err)
+	if err != nil {
+		return config.ShuttleProjectContext{}, err
+	}
+	err = executer.AddScripts(ctx, r.ui, &c)
+	if err != nil {
+		return config.ShuttleProjectContext{}, err
+	}
+	return c, nil
+}
@@ -0,0 +1,171 @@
+package shuttle
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lunarway/shuttle/pkg/executors"
+)
+
+const testProject = `plan: false
+scripts:
+  greet:
+    args:
+      - name: name
+        required: true
+    actions:
+      - shell: echo "Hello $name"
+  read:
+    actions:
+      - shell: read line; echo "got $line"
+`
+
+func TestRunner_Run(t *testing.T) {
+	projectPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(projectPath, "shuttle.yaml"), []byte(testProject), 0o644))
+
+	tt := []struct {
+		name    string
+		opts    Options
+		script  string
+		args    map[string]string
+		stdout  string
+		err     string
+		errLine string
+	}{
+		{
+			name:   "script",
+			script: "greet",
+			args:   map[string]string{"name": "shuttle"},
+			stdout: "Hello shuttle\n",
+		},
+		{
+			name:   "stdin",
+			opts:   Options{Stdin: strings.NewReader("input\n")},
+			script: "read",
+			stdout: "got input\n",
+		},
+		{
+			name:   "dry run",
+			opts:   Options{ExecuteOptions: []executors.ExecuteOption{executors.WithDryRun(true)}},
+			script: "greet",
+			args:   map[string]string{"name": "shuttle"},
+			stdout: "Dry run of action 0 of script `greet`:\n",
+		},
+		{
+			name:    "missing argument",
+			script:  "greet",
+			errLine: "'name' not supplied but is required",
+		},
+		{
+			name:   "skip argument validation",
+			opts:   Options{SkipArgValidation: true},
+			script: "greet",
+			stdout: "Hello \n",
+		},
+		{
+			name:   "unknown script",
+			script: "deploy",
+			err:    "exit code 2 - Script 'deploy' not found",
+		},
+		{
+			name:   "no project",
+			opts:   Options{ProjectPath: t.TempDir()},
+			script: "greet",
+			err:    "exit code 2 - Failed to load shuttle configuration",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			opts := tc.opts
+			if opts.ProjectPath == "" {
+				opts.ProjectPath = projectPath
+			}
+			opts.Stdout = stdout
+			opts.Stderr = &bytes.Buffer{}
+
+			err := NewRunner(opts).Run(context.Background(), tc.script, tc.args)
+
+			switch {
+			case tc.err != "":
+				assert.ErrorContains(t, err, tc.err)
+			case tc.errLine != "":
+				assert.ErrorContains(t, err, tc.errLine)
+			default:
+				assert.NoError(t, err)
+				assert.Contains(t, stdout.String(), tc.stdout)
+			}
+		})
+	}
+}
+
+func TestRunner_Scripts(t *testing.T) {
+	projectPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(projectPath, "shuttle.yaml"), []byte(testProject), 0o644))
+	runner := NewRunner(Options{ProjectPath: projectPath, Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}})
+
+	scripts, err := runner.Scripts(context.Background())
+
+	require.NoError(t, err)
+	assert.Len(t, scripts, 2)
+	assert.Contains(t, scripts, "greet")
+	assert.Contains(t, scripts, "read")
+}
+
+func TestRunner_shuttleVersion(t *testing.T) {
+	plan := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(plan, "plan.yaml"), []byte(`requires:
+  shuttle: ">= 2.0.0"
+scripts:
+  hello:
+    actions:
+      - shell: echo hello
+`), 0o644))
+	projectPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(projectPath, "shuttle.yaml"), []byte("plan: "+plan+"\n"), 0o644))
+
+	tt := []struct {
+		name    string
+		version string
+		err     string
+	}{
+		{
+			name:    "unmet",
+			version: "v1.0.0",
+			err:     "exit code 4 - Requirements of the plan are not met:\n- shuttle >= 2.0.0: this is shuttle v1.0.0\n  → Update shuttle with 'shuttle self-update'",
+		},
+		{
+			name:    "met",
+			version: "v2.1.0",
+		},
+		{
+			name: "development version of the module",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := NewRunner(Options{
+				ProjectPath:    projectPath,
+				ShuttleVersion: tc.version,
+				Stdout:         &bytes.Buffer{},
+				Stderr:         &bytes.Buffer{},
+			})
+
+			err := runner.Run(context.Background(), "hello", nil)
+
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
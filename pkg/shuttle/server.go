@@ -0,0 +1,500 @@
+package shuttle
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	stderrors "errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lunarway/shuttle/pkg/config"
+	"github.com/lunarway/shuttle/pkg/errors"
+	"github.com/lunarway/shuttle/pkg/telemetry"
+	"github.com/lunarway/shuttle/pkg/ui"
+)
+
+// RunStatus is the state of a run started through a Server
+type RunStatus string
+
+const (
+	RunRunning   RunStatus = "running"
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+	RunCancelled RunStatus = "cancelled"
+)
+
+// maxExitedRuns is the number of exited runs a Server keeps. The oldest are
+// forgotten first.
+const maxExitedRuns = 100
+
+// errCancelledByClient is the cancellation cause of runs cancelled through
+// the API.
+var errCancelledByClient = stderrors.New("cancelled by client")
+
+// RunInfo describes a run of a script started through a Server. Runs are
+// identified by the telemetry run ID of their context.
+type RunInfo struct {
+	ID        string            `json:"id"`
+	ContextID string            `json:"contextId"`
+	Script    string            `json:"script"`
+	Args      map[string]string `json:"args,omitempty"`
+	Status    RunStatus         `json:"status"`
+	ExitCode  *int              `json:"exitCode,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	StartedAt time.Time         `json:"startedAt"`
+	ExitedAt  *time.Time        `json:"exitedAt,omitempty"`
+}
+
+// ScriptInfo describes a script of the project served by a Server.
+type ScriptInfo struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Args        []ScriptArg `json:"args,omitempty"`
+}
+
+// ScriptArg describes an argument of a script.
+type ScriptArg struct {
+	Name        string `json:"name"`
+	Required    bool   `json:"required,omitempty"`
+	Description string `json:"description,omitempty"`
+	Secret      bool   `json:"secret,omitempty"`
+}
+
+// secretMask replaces the values of secret arguments in descriptions of runs
+const secretMask = "***"
+
+// RunRequest is the body of requests starting a run.
+type RunRequest struct {
+	Script string            `json:"script"`
+	Args   map[string]string `json:"args"`
+}
+
+// Server exposes the scripts of a Runner over HTTP. Scripts can be listed,
+// run, cancelled and their output streamed as JSON lines like those of
+// --output json.
+//
+//	GET  /scripts          list scripts
+//	GET  /runs             list runs
+//	POST /runs             start a run of a RunRequest
+//	GET  /runs/<id>        describe a run
+//	GET  /runs/<id>/logs   stream the output of a run, ?follow=false stops at the current end
+//	POST /runs/<id>/cancel cancel a run
+type Server struct {
+	runner *Runner
+	token  string
+
+	mu   sync.Mutex
+	runs map[string]*serverRun
+	// order is the IDs of runs in the order they were started
+	order []string
+	wg    sync.WaitGroup
+}
+
+type serverRun struct {
+	info   RunInfo
+	log    *runLog
+	cancel context.CancelCauseFunc
+}
+
+// NewServer returns a server of the scripts of runner. If token is not empty
+// requests must authenticate with it as a bearer token.
+func NewServer(runner *Runner, token string) *Server {
+	return &Server{
+		runner: runner,
+		token:  token,
+		runs:   make(map[string]*serverRun),
+	}
+}
+
+// ServeHTTP serves the API of the server.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// browsers send the origin of cross-origin requests, eg. of a web page
+	// posting to the server
+	if origin := r.Header.Get("Origin"); origin != "" && !sameOrigin(origin, r.Host) {
+		writeError(w, http.StatusForbidden, "cross-origin requests are not allowed")
+		return
+	}
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+		return
+	}
+	path := strings.Trim(r.URL.Path, "/")
+	switch {
+	case path == "scripts":
+		s.allow(w, r, http.MethodGet, s.listScripts)
+	case path == "runs" && r.Method == http.MethodPost:
+		s.startRun(w, r)
+	case path == "runs":
+		s.allow(w, r, http.MethodGet, s.listRuns)
+	case strings.HasPrefix(path, "runs/"):
+		id, action, _ := strings.Cut(strings.TrimPrefix(path, "runs/"), "/")
+		run, ok := s.run(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, "run '"+id+"' not found")
+			return
+		}
+		switch action {
+		case "":
+			s.allow(w, r, http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, s.info(run))
+			})
+		case "logs":
+			s.allow(w, r, http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+				streamLog(w, r, run.log)
+			})
+		case "cancel":
+			s.allow(w, r, http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+				run.cancel(errCancelledByClient)
+				writeJSON(w, http.StatusAccepted, s.info(run))
+			})
+		default:
+			writeError(w, http.StatusNotFound, "not found")
+		}
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// Shutdown cancels all runs in progress and waits for them to exit or ctx to
+// be done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	for _, run := range s.runs {
+		run.cancel(context.Canceled)
+	}
+	s.mu.Unlock()
+
+	exited := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(exited)
+	}()
+	select {
+	case <-exited:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sameOrigin returns whether origin is the origin of the server at host.
+func sameOrigin(origin, host string) bool {
+	parsed, err := url.Parse(origin)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host == host
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// allow serves r with handler if it has method.
+func (s *Server) allow(w http.ResponseWriter, r *http.Request, method string, handler http.HandlerFunc) {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, "method "+r.Method+" not allowed")
+		return
+	}
+	handler(w, r)
+}
+
+func (s *Server) listScripts(w http.ResponseWriter, r *http.Request) {
+	scripts, err := s.runner.Scripts(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, errorMessage(err))
+		return
+	}
+	infos := make([]ScriptInfo, 0, len(scripts))
+	for name, script := range scripts {
+		info := ScriptInfo{Name: name, Description: script.Description}
+		for _, arg := range script.Args {
+			info.Args = append(info.Args, ScriptArg{
+				Name:        arg.Name,
+				Required:    arg.Required,
+				Description: arg.Description,
+				Secret:      arg.Secret,
+			})
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (s *Server) listRuns(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	infos := make([]RunInfo, 0, len(s.order))
+	for _, id := range s.order {
+		infos = append(infos, s.runs[id].info)
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, infos)
+}
+
+// startRun starts a run of the script of the JSON request. Unknown scripts and
+// invalid arguments are rejected before the run is started.
+func (s *Server) startRun(w http.ResponseWriter, r *http.Request) {
+	// forms and no-cors requests of web pages cannot send JSON
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "content type must be application/json")
+		return
+	}
+	var request RunRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	project, err := s.runner.projectContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, errorMessage(err))
+		return
+	}
+	if err := s.runner.validate(project, request.Script, request.Args); err != nil {
+		writeError(w, http.StatusBadRequest, errorMessage(err))
+		return
+	}
+
+	// runs outlive the request starting them
+	maskedArgs := maskSecretArgs(project.Scripts[request.Script], request.Args)
+	ctx := telemetry.WithContextID(context.Background())
+	ctx = telemetry.WithRunID(ctx)
+	ctx = context.WithValue(ctx, telemetry.TelemetryCommand, "serve-run")
+	ctx = context.WithValue(ctx, telemetry.TelemetryCommandArgs, strings.Join(append([]string{request.Script}, formatArgs(maskedArgs)...), " "))
+	ctx, cancel := context.WithCancelCause(ctx)
+	run := &serverRun{
+		info: RunInfo{
+			ID:        telemetry.RunIDFrom(ctx),
+			ContextID: telemetry.ContextIDFrom(ctx),
+			Script:    request.Script,
+			Args:      maskedArgs,
+			Status:    RunRunning,
+			StartedAt: time.Now(),
+		},
+		log:    newRunLog(),
+		cancel: cancel,
+	}
+	// output of the run is written as JSON lines to its log
+	project.UI = ui.Create(run.log, run.log).SetFormat(ui.FormatJSON)
+	if base := s.runner.ui; base.UserLevelSet {
+		project.UI.SetUserLevel(base.UserLevel)
+	}
+
+	s.mu.Lock()
+	s.runs[run.info.ID] = run
+	s.order = append(s.order, run.info.ID)
+	s.forgetExitedRuns()
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel(nil)
+		telemetry.Trace(ctx, "serve-run", telemetry.WithPhase("start"))
+		err := s.runner.run(ctx, project, nil, request.Script, request.Args)
+		if err != nil {
+			telemetry.TraceError(ctx, "serve-run", err)
+		}
+		telemetry.Trace(ctx, "serve-run", telemetry.WithPhase("end"))
+		s.exited(run, err, context.Cause(ctx))
+	}()
+
+	writeJSON(w, http.StatusAccepted, s.info(run))
+}
+
+// exited records the result of run and closes its log.
+func (s *Server) exited(run *serverRun, err error, cause error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exitedAt := time.Now()
+	exitCode := runExitCode(err)
+	run.info.ExitedAt = &exitedAt
+	run.info.ExitCode = &exitCode
+	switch {
+	case err == nil:
+		run.info.Status = RunSucceeded
+	case cause != nil:
+		run.info.Status = RunCancelled
+		run.info.Error = errorMessage(err)
+	default:
+		run.info.Status = RunFailed
+		run.info.Error = errorMessage(err)
+	}
+	run.log.close()
+}
+
+// forgetExitedRuns removes the oldest exited runs keeping at most
+// maxExitedRuns of them. s.mu must be held.
+func (s *Server) forgetExitedRuns() {
+	exited := 0
+	for _, id := range s.order {
+		if s.runs[id].info.Status != RunRunning {
+			exited++
+		}
+	}
+	order := s.order[:0]
+	for _, id := range s.order {
+		if exited > maxExitedRuns && s.runs[id].info.Status != RunRunning {
+			delete(s.runs, id)
+			exited--
+			continue
+		}
+		order = append(order, id)
+	}
+	s.order = order
+}
+
+func (s *Server) run(id string) (*serverRun, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[id]
+	return run, ok
+}
+
+func (s *Server) info(run *serverRun) RunInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return run.info
+}
+
+// maskSecretArgs returns args with the values of the secret arguments of
+// script masked.
+func maskSecretArgs(script config.ShuttlePlanScript, args map[string]string) map[string]string {
+	if len(args) == 0 {
+		return nil
+	}
+	masked := make(map[string]string, len(args))
+	for name, value := range args {
+		masked[name] = value
+	}
+	for _, arg := range script.Args {
+		if _, ok := masked[arg.Name]; ok && arg.Secret {
+			masked[arg.Name] = secretMask
+		}
+	}
+	return masked
+}
+
+// runExitCode returns the exit code shuttle would exit with on err.
+func runExitCode(err error) int {
+	var exitCode *errors.ExitCode
+	switch {
+	case err == nil:
+		return 0
+	case stderrors.As(err, &exitCode):
+		return exitCode.Code
+	case stderrors.Is(err, context.Canceled):
+		return errors.ExitCodeCancelled
+	case stderrors.Is(err, context.DeadlineExceeded):
+		return errors.ExitCodeTimeout
+	default:
+		return 1
+	}
+}
+
+// errorMessage returns the message of err without the exit code prefix of
+// *errors.ExitCode.
+func errorMessage(err error) string {
+	var exitCode *errors.ExitCode
+	if stderrors.As(err, &exitCode) {
+		return exitCode.Message
+	}
+	return err.Error()
+}
+
+// streamLog writes log to w as it is written. Unless the follow query
+// parameter is false the log is followed until the run exits or the client
+// goes away.
+func streamLog(w http.ResponseWriter, r *http.Request, log *runLog) {
+	follow := r.URL.Query().Get("follow") != "false"
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	offset := 0
+	for {
+		chunk, changed, closed := log.read(offset)
+		if len(chunk) != 0 {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			offset += len(chunk)
+		}
+		if closed || !follow {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// runLog is the output of a run. It can be read while it is written.
+type runLog struct {
+	mu   sync.Mutex
+	data []byte
+	// changed is closed and replaced when data is written or the log is
+	// closed
+	changed chan struct{}
+	closed  bool
+}
+
+func newRunLog() *runLog {
+	return &runLog{changed: make(chan struct{})}
+}
+
+func (l *runLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.data = append(l.data, p...)
+	if !l.closed {
+		close(l.changed)
+		l.changed = make(chan struct{})
+	}
+	return len(p), nil
+}
+
+// read returns the log from offset, a channel closed once the log changes
+// and whether the log is closed.
+func (l *runLog) read(offset int) ([]byte, <-chan struct{}, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]byte(nil), l.data[offset:]...), l.changed, l.closed
+}
+
+func (l *runLog) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+	l.closed = true
+	close(l.changed)
+}
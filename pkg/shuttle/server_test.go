@@ -0,0 +1,281 @@
+package shuttle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testServerProject = `plan: false
+scripts:
+  greet:
+    description: Greet someone
+    args:
+      - name: name
+        required: true
+      - name: password
+        secret: true
+    actions:
+      - shell: echo "Hello $name"
+  wait:
+    actions:
+      - shell: sleep 10
+`
+
+func newTestServer(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+	projectPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(projectPath, "shuttle.yaml"), []byte(testServerProject), 0o644))
+	server := NewServer(NewRunner(Options{ProjectPath: projectPath, Stdout: io.Discard, Stderr: io.Discard}), token)
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(func() {
+		server.Shutdown(context.Background())
+		httpServer.Close()
+	})
+	return httpServer
+}
+
+func request(t *testing.T, method, url, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(data)
+}
+
+func startRun(t *testing.T, url, body string) RunInfo {
+	t.Helper()
+	status, response := request(t, http.MethodPost, url+"/runs", body)
+	require.Equal(t, http.StatusAccepted, status, response)
+	var info RunInfo
+	require.NoError(t, json.Unmarshal([]byte(response), &info))
+	return info
+}
+
+func TestServer_scripts(t *testing.T) {
+	server := newTestServer(t, "")
+
+	status, body := request(t, http.MethodGet, server.URL+"/scripts", "")
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `[
+		{"name": "greet", "description": "Greet someone", "args": [{"name": "name", "required": true}, {"name": "password", "secret": true}]},
+		{"name": "wait"}
+	]`, body)
+}
+
+func TestServer_run(t *testing.T) {
+	server := newTestServer(t, "")
+
+	info := startRun(t, server.URL, `{"script": "greet", "args": {"name": "shuttle", "password": "hunter2"}}`)
+	assert.Equal(t, RunRunning, info.Status)
+	assert.NotEmpty(t, info.ID)
+	assert.NotEmpty(t, info.ContextID)
+	assert.Equal(t, map[string]string{"name": "shuttle", "password": "***"}, info.Args)
+
+	// logs are followed until the run exits
+	status, logs := request(t, http.MethodGet, server.URL+"/runs/"+info.ID+"/logs", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, logs, `"script":"greet","action":0,"stream":"stdout","message":"Hello shuttle"`)
+	assert.Contains(t, logs, `"event":"script-exited","exitCode":0`)
+
+	status, body := request(t, http.MethodGet, server.URL+"/runs/"+info.ID, "")
+	assert.Equal(t, http.StatusOK, status)
+	var exited RunInfo
+	require.NoError(t, json.Unmarshal([]byte(body), &exited))
+	assert.Equal(t, RunSucceeded, exited.Status)
+	assert.Equal(t, 0, *exited.ExitCode)
+
+	status, body = request(t, http.MethodGet, server.URL+"/runs", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"id":"`+info.ID+`"`)
+}
+
+func TestServer_cancel(t *testing.T) {
+	server := newTestServer(t, "")
+	info := startRun(t, server.URL, `{"script": "wait"}`)
+
+	status, _ := request(t, http.MethodPost, server.URL+"/runs/"+info.ID+"/cancel", "")
+	assert.Equal(t, http.StatusAccepted, status)
+
+	request(t, http.MethodGet, server.URL+"/runs/"+info.ID+"/logs", "")
+	_, body := request(t, http.MethodGet, server.URL+"/runs/"+info.ID, "")
+	var exited RunInfo
+	require.NoError(t, json.Unmarshal([]byte(body), &exited))
+	assert.Equal(t, RunCancelled, exited.Status)
+	assert.NotZero(t, *exited.ExitCode)
+}
+
+func TestServer_errors(t *testing.T) {
+	server := newTestServer(t, "")
+
+	tt := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		error  string
+	}{
+		{
+			name:   "unknown script",
+			method: http.MethodPost,
+			path:   "/runs",
+			body:   `{"script": "deploy"}`,
+			status: http.StatusBadRequest,
+			error:  "Script 'deploy' not found",
+		},
+		{
+			name:   "missing argument",
+			method: http.MethodPost,
+			path:   "/runs",
+			body:   `{"script": "greet"}`,
+			status: http.StatusBadRequest,
+			error:  "'name' not supplied but is required",
+		},
+		{
+			name:   "invalid body",
+			method: http.MethodPost,
+			path:   "/runs",
+			body:   `{"name": "greet"}`,
+			status: http.StatusBadRequest,
+			error:  `invalid request: json: unknown field "name"`,
+		},
+		{
+			name:   "unknown run",
+			method: http.MethodGet,
+			path:   "/runs/unknown",
+			status: http.StatusNotFound,
+			error:  "run 'unknown' not found",
+		},
+		{
+			name:   "method not allowed",
+			method: http.MethodDelete,
+			path:   "/scripts",
+			status: http.StatusMethodNotAllowed,
+			error:  "method DELETE not allowed",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := request(t, tc.method, server.URL+tc.path, tc.body)
+
+			assert.Equal(t, tc.status, status)
+			var response map[string]string
+			require.NoError(t, json.Unmarshal([]byte(body), &response))
+			assert.Contains(t, response["error"], tc.error)
+		})
+	}
+}
+
+func TestServer_token(t *testing.T) {
+	server := newTestServer(t, "secret")
+
+	status, _ := request(t, http.MethodGet, server.URL+"/scripts", "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/scripts", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestServer_crossOrigin(t *testing.T) {
+	server := newTestServer(t, "")
+
+	tt := []struct {
+		name        string
+		contentType string
+		origin      string
+		status      int
+		error       string
+	}{
+		{
+			name:        "same origin",
+			contentType: "application/json; charset=utf-8",
+			origin:      server.URL,
+			status:      http.StatusAccepted,
+		},
+		{
+			name:        "plain text",
+			contentType: "text/plain",
+			status:      http.StatusUnsupportedMediaType,
+			error:       "content type must be application/json",
+		},
+		{
+			name:   "no content type",
+			status: http.StatusUnsupportedMediaType,
+			error:  "content type must be application/json",
+		},
+		{
+			name:        "foreign origin",
+			contentType: "application/json",
+			origin:      "https://example.com",
+			status:      http.StatusForbidden,
+			error:       "cross-origin requests are not allowed",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/runs", strings.NewReader(`{"script": "greet", "args": {"name": "shuttle"}}`))
+			require.NoError(t, err)
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.status, resp.StatusCode, string(body))
+			if tc.error != "" {
+				var response map[string]string
+				require.NoError(t, json.Unmarshal(body, &response))
+				assert.Equal(t, tc.error, response["error"])
+			}
+		})
+	}
+}
+
+func TestRunLog(t *testing.T) {
+	log := newRunLog()
+	log.Write([]byte("first\n"))
+
+	chunk, changed, closed := log.read(0)
+	assert.Equal(t, "first\n", string(chunk))
+	assert.False(t, closed)
+
+	log.Write([]byte("second\n"))
+	<-changed
+	chunk, _, _ = log.read(len("first\n"))
+	assert.Equal(t, "second\n", string(chunk))
+
+	log.close()
+	_, _, closed = log.read(0)
+	assert.True(t, closed)
+	assert.True(t, bytes.HasSuffix(log.data, []byte("second\n")))
+}
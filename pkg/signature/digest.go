@@ -0,0 +1,98 @@
+package signature
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// MinisignFile is the file of a plan holding the minisign signature of its
+	// digest
+	MinisignFile = "plan.digest.minisig"
+	// CosignFile is the file of a plan holding the cosign signature of its
+	// digest
+	CosignFile = "plan.digest.sig"
+	// DigestFile is the conventional file publishers write the digest to
+	// before signing it. It is not part of the digest.
+	DigestFile = "plan.digest"
+)
+
+// ignored are the files and directories of a plan that are not part of its
+// digest
+var ignored = map[string]bool{
+	".git":       true,
+	".shuttle":   true,
+	DigestFile:   true,
+	MinisignFile: true,
+	CosignFile:   true,
+}
+
+// Digest returns the digest of the files of the plan in dir on the form
+// sha256:<hex>. It covers the paths and contents of all files except the .git
+// and .shuttle directories and the signature files, so the same files always
+// have the same digest wherever they are fetched from.
+func Digest(dir string) (string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		if relative == "." {
+			return nil
+		}
+		relative = filepath.ToSlash(relative)
+		if ignored[relative] {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.IsDir() {
+			files = append(files, relative)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	hash := sha256.New()
+	for _, file := range files {
+		digest, err := fileDigest(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s  %s\n", digest, file)
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func fileDigest(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Message returns the message signed for a plan with digest, ie. the content
+// of DigestFile as written by shuttle plan digest.
+func Message(digest string) []byte {
+	return []byte(digest + "\n")
+}
@@ -0,0 +1,178 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// PublicKey verifies signatures of plans made with the matching private key.
+type PublicKey interface {
+	// SignatureFile is the file of a plan holding signatures of this kind of
+	// key
+	SignatureFile() string
+	// Verify returns an error if signature is not a valid signature of message.
+	Verify(message, signature []byte) error
+}
+
+// ParsePublicKey parses a minisign public key, optionally preceded by its
+// untrusted comment, or a PEM encoded cosign public key.
+func ParsePublicKey(raw string) (PublicKey, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "-----BEGIN") {
+		return parseCosignKey(raw)
+	}
+	return parseMinisignKey(raw)
+}
+
+// VerifyPlan returns an error unless the plan in dir is signed by key.
+func VerifyPlan(dir string, key PublicKey) error {
+	signature, err := os.ReadFile(filepath.Join(dir, key.SignatureFile()))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("the plan has no signature in %s", key.SignatureFile())
+	}
+	if err != nil {
+		return err
+	}
+	digest, err := Digest(dir)
+	if err != nil {
+		return fmt.Errorf("digest plan: %w", err)
+	}
+	if err := key.Verify(Message(digest), signature); err != nil {
+		return fmt.Errorf("signature of plan digest %s is invalid: %w", digest, err)
+	}
+	return nil
+}
+
+// minisignKey is an Ed25519 minisign public key
+type minisignKey struct {
+	id  []byte
+	key ed25519.PublicKey
+}
+
+const minisignCommentPrefix = "untrusted comment:"
+
+func parseMinisignKey(raw string) (PublicKey, error) {
+	lines := strings.Split(raw, "\n")
+	if strings.HasPrefix(lines[0], minisignCommentPrefix) {
+		lines = lines[1:]
+	}
+	if len(lines) != 1 {
+		return nil, errors.New("public key must be a minisign or PEM encoded cosign public key")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[0]))
+	if err != nil || len(decoded) != 2+8+ed25519.PublicKeySize || string(decoded[:2]) != "Ed" {
+		return nil, errors.New("public key must be a minisign or PEM encoded cosign public key")
+	}
+	return &minisignKey{id: decoded[2:10], key: ed25519.PublicKey(decoded[10:])}, nil
+}
+
+func (k *minisignKey) SignatureFile() string {
+	return MinisignFile
+}
+
+// Verify verifies a minisign signature file of message, ie. the signature of
+// the message, which is prehashed with BLAKE2b if the algorithm is ED, and
+// the global signature of the trusted comment.
+func (k *minisignKey) Verify(message, signature []byte) error {
+	lines := strings.Split(strings.TrimSpace(string(signature)), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], minisignCommentPrefix) {
+		return errors.New("not a minisign signature")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(decoded) != 2+8+ed25519.SignatureSize {
+		return errors.New("not a minisign signature")
+	}
+	algorithm, id, sig := string(decoded[:2]), decoded[2:10], decoded[10:]
+	if !bytes.Equal(id, k.id) {
+		return fmt.Errorf("signed by key %X and not %X", reverse(id), reverse(k.id))
+	}
+	switch algorithm {
+	case "Ed":
+	case "ED":
+		prehashed := blake2b.Sum512(message)
+		message = prehashed[:]
+	default:
+		return fmt.Errorf("unsupported signature algorithm %q", algorithm)
+	}
+	if !ed25519.Verify(k.key, message, sig) {
+		return errors.New("signature does not match")
+	}
+
+	trustedComment, ok := strings.CutPrefix(lines[2], "trusted comment: ")
+	if !ok {
+		return errors.New("not a minisign signature")
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || !ed25519.Verify(k.key, append(append([]byte{}, sig...), trustedComment...), globalSig) {
+		return errors.New("trusted comment signature does not match")
+	}
+	return nil
+}
+
+// reverse returns the bytes of the little endian key id in the order minisign
+// prints them.
+func reverse(id []byte) []byte {
+	reversed := make([]byte, len(id))
+	for i, b := range id {
+		reversed[len(id)-1-i] = b
+	}
+	return reversed
+}
+
+// cosignKey is an ECDSA or Ed25519 public key used by cosign sign-blob
+type cosignKey struct {
+	key interface{}
+}
+
+func parseCosignKey(raw string) (PublicKey, error) {
+	block, _ := pem.Decode([]byte(raw))
+	if block == nil {
+		return nil, errors.New("public key is not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey:
+		return &cosignKey{key: key}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+func (k *cosignKey) SignatureFile() string {
+	return CosignFile
+}
+
+// Verify verifies a base64 encoded signature as written by cosign sign-blob.
+func (k *cosignKey) Verify(message, signature []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return errors.New("signature is not base64 encoded")
+	}
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(message)
+		if !ecdsa.VerifyASN1(key, digest[:], sig) {
+			return errors.New("signature does not match")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, message, sig) {
+			return errors.New("signature does not match")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package signature
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestDigest(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "plan.yaml", "scripts: {}\n")
+	writeFile(t, dir, "scripts/build.sh", "echo build\n")
+	digest, err := Digest(dir)
+	require.NoError(t, err)
+
+	writeFile(t, dir, ".git/HEAD", "ref: refs/heads/main\n")
+	writeFile(t, dir, ".shuttle/cache/x", "x")
+	writeFile(t, dir, DigestFile, digest+"\n")
+	writeFile(t, dir, MinisignFile, "signature")
+	writeFile(t, dir, CosignFile, "signature")
+	ignoredDigest, err := Digest(dir)
+	require.NoError(t, err)
+	assert.Equal(t, digest, ignoredDigest, "digest with ignored files")
+
+	writeFile(t, dir, "scripts/build.sh", "echo changed\n")
+	changedDigest, err := Digest(dir)
+	require.NoError(t, err)
+	assert.NotEqual(t, digest, changedDigest, "digest with changed file")
+}
+
+func TestVerifyPlan_minisign(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	id := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	otherID := []byte{8, 7, 6, 5, 4, 3, 2, 1}
+
+	tt := []struct {
+		name      string
+		keyID     []byte
+		sign      func(dir string)
+		err       string
+		algorithm string
+	}{
+		{
+			name:      "prehashed signature",
+			keyID:     id,
+			algorithm: "ED",
+		},
+		{
+			name:      "legacy signature",
+			keyID:     id,
+			algorithm: "Ed",
+		},
+		{
+			name:      "tampered plan",
+			keyID:     id,
+			algorithm: "ED",
+			sign: func(dir string) {
+				writeFile(t, dir, "plan.yaml", "scripts: {tampered: {}}\n")
+			},
+			err: "signature does not match",
+		},
+		{
+			name:      "other key",
+			keyID:     otherID,
+			algorithm: "ED",
+			err:       "signed by key 0102030405060708 and not 0807060504030201",
+		},
+		{
+			name:      "no signature",
+			keyID:     id,
+			algorithm: "ED",
+			sign: func(dir string) {
+				require.NoError(t, os.Remove(path.Join(dir, MinisignFile)))
+			},
+			err: "the plan has no signature in plan.digest.minisig",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, dir, "plan.yaml", "scripts: {}\n")
+			digest, err := Digest(dir)
+			require.NoError(t, err)
+			writeFile(t, dir, MinisignFile, minisignSignature(privateKey, tc.keyID, tc.algorithm, Message(digest)))
+			if tc.sign != nil {
+				tc.sign(dir)
+			}
+			key, err := ParsePublicKey("untrusted comment: minisign public key\n" + minisignPublicKey(publicKey, id))
+			require.NoError(t, err)
+
+			err = VerifyPlan(dir, key)
+
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestVerifyPlan_cosign(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tt := []struct {
+		name   string
+		signer *ecdsa.PrivateKey
+		err    string
+	}{
+		{
+			name:   "valid signature",
+			signer: privateKey,
+		},
+		{
+			name:   "other key",
+			signer: otherKey,
+			err:    "signature does not match",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, dir, "plan.yaml", "scripts: {}\n")
+			digest, err := Digest(dir)
+			require.NoError(t, err)
+			hash := sha256.Sum256(Message(digest))
+			sig, err := ecdsa.SignASN1(rand.Reader, tc.signer, hash[:])
+			require.NoError(t, err)
+			writeFile(t, dir, CosignFile, base64.StdEncoding.EncodeToString(sig))
+			der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+			require.NoError(t, err)
+			key, err := ParsePublicKey(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+			require.NoError(t, err)
+
+			err = VerifyPlan(dir, key)
+
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestParsePublicKey_invalid(t *testing.T) {
+	_, err := ParsePublicKey("not a key")
+	assert.EqualError(t, err, "public key must be a minisign or PEM encoded cosign public key")
+	_, err = ParsePublicKey("-----BEGIN PUBLIC KEY-----\nbm90IGEga2V5\n-----END PUBLIC KEY-----\n")
+	assert.ErrorContains(t, err, "parse public key")
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	file := path.Join(dir, name)
+	require.NoError(t, os.MkdirAll(path.Dir(file), os.ModePerm))
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+}
+
+func minisignPublicKey(key ed25519.PublicKey, id []byte) string {
+	return base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), id...), key...))
+}
+
+func minisignSignature(key ed25519.PrivateKey, id []byte, algorithm string, message []byte) string {
+	if algorithm == "ED" {
+		prehashed := blake2b.Sum512(message)
+		message = prehashed[:]
+	}
+	sig := ed25519.Sign(key, message)
+	trustedComment := "timestamp:1700000000\tfile:plan.digest"
+	globalSig := ed25519.Sign(key, append(append([]byte{}, sig...), trustedComment...))
+	return "untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(append(append([]byte(algorithm), id...), sig...)) + "\n" +
+		"trusted comment: " + trustedComment + "\n" +
+		base64.StdEncoding.EncodeToString(globalSig) + "\n"
+}
@@ -0,0 +1,58 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	TelemetryActionScript   string = "shuttle.action.script"
+	TelemetryActionIndex    string = "shuttle.action.index"
+	TelemetryActionKind     string = "shuttle.action.kind"
+	TelemetryActionStart    string = "shuttle.action.start"
+	TelemetryActionEnd      string = "shuttle.action.end"
+	TelemetryActionDuration string = "shuttle.action.durationMs"
+	TelemetryActionExitCode string = "shuttle.action.exitCode"
+)
+
+// ActionSpan is the execution of a single action of a script from the start
+// of its command to its completion.
+type ActionSpan struct {
+	Script string
+	Action int
+	// Kind of action, eg. shell or task
+	Kind     string
+	Start    time.Time
+	End      time.Time
+	ExitCode int
+}
+
+// TraceAction records the timing and exit code of an action. Spans of one
+// invocation share its context ID. The action is also recorded as a child of
+// the span of ctx if OpenTelemetry spans are exported.
+func TraceAction(ctx context.Context, span ActionSpan) {
+	var err error
+	if span.ExitCode != 0 {
+		err = fmt.Errorf("exited with code %d", span.ExitCode)
+	}
+	recordSpan(ctx, "shuttle.action", span.Start, span.End, map[string]string{
+		TelemetryActionScript:   span.Script,
+		TelemetryActionIndex:    strconv.Itoa(span.Action),
+		TelemetryActionKind:     span.Kind,
+		TelemetryActionExitCode: strconv.Itoa(span.ExitCode),
+	}, err)
+	Trace(
+		ctx,
+		span.Script,
+		WithPhase("action"),
+		WithEntry(TelemetryActionScript, span.Script),
+		WithEntry(TelemetryActionIndex, strconv.Itoa(span.Action)),
+		WithEntry(TelemetryActionKind, span.Kind),
+		WithEntry(TelemetryActionStart, span.Start.UTC().Format(time.RFC3339Nano)),
+		WithEntry(TelemetryActionEnd, span.End.UTC().Format(time.RFC3339Nano)),
+		WithEntry(TelemetryActionDuration, strconv.FormatInt(span.End.Sub(span.Start).Milliseconds(), 10)),
+		WithEntry(TelemetryActionExitCode, strconv.Itoa(span.ExitCode)),
+	)
+}
@@ -0,0 +1,43 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceAction(t *testing.T) {
+	recorder := &recordingTelemetryClient{}
+	defer func(previous TelemetryClient) { client = previous }(client)
+	client = recorder
+	ctx := context.WithValue(context.Background(), telemetryContextID, "context-id")
+	ctx = context.WithValue(ctx, telemetryRunID, "run-id")
+	start := time.Date(2023, 7, 17, 15, 21, 27, 0, time.UTC)
+
+	TraceAction(ctx, ActionSpan{
+		Script:   "build",
+		Action:   1,
+		Kind:     "shell",
+		Start:    start,
+		End:      start.Add(1500 * time.Millisecond),
+		ExitCode: 2,
+	})
+
+	assert.Equal(t, []map[string]string{
+		{
+			"label":                     "build",
+			"phase":                     "action",
+			"shuttle.contextID":         "context-id",
+			"shuttle.runID":             "run-id",
+			"shuttle.action.script":     "build",
+			"shuttle.action.index":      "1",
+			"shuttle.action.kind":       "shell",
+			"shuttle.action.start":      "2023-07-17T15:21:27Z",
+			"shuttle.action.end":        "2023-07-17T15:21:28.5Z",
+			"shuttle.action.durationMs": "1500",
+			"shuttle.action.exitCode":   "2",
+		},
+	}, recorder.traces)
+}
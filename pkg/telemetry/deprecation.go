@@ -0,0 +1,23 @@
+package telemetry
+
+import "context"
+
+const (
+	TelemetryDeprecatedScript  string = "shuttle.deprecated.script"
+	TelemetryDeprecatedMessage string = "shuttle.deprecated.message"
+	TelemetryDeprecatedSource  string = "shuttle.deprecated.source"
+)
+
+// TraceDeprecation records that the deprecated script was run. source is the
+// file the script is defined in and message is the deprecation notice of the
+// script.
+func TraceDeprecation(ctx context.Context, script, source, message string) {
+	Trace(
+		ctx,
+		script,
+		WithPhase("deprecated"),
+		WithEntry(TelemetryDeprecatedScript, script),
+		WithEntry(TelemetryDeprecatedSource, source),
+		WithEntry(TelemetryDeprecatedMessage, message),
+	)
+}
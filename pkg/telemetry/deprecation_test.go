@@ -0,0 +1,38 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingTelemetryClient struct {
+	traces []map[string]string
+}
+
+func (r *recordingTelemetryClient) Trace(ctx context.Context, properties map[string]string) {
+	r.traces = append(r.traces, properties)
+}
+
+func TestTraceDeprecation(t *testing.T) {
+	recorder := &recordingTelemetryClient{}
+	defer func(previous TelemetryClient) { client = previous }(client)
+	client = recorder
+	ctx := context.WithValue(context.Background(), telemetryContextID, "context-id")
+	ctx = context.WithValue(ctx, telemetryRunID, "run-id")
+
+	TraceDeprecation(ctx, "deploy", "plan.yaml", "Use deploy-v2 instead")
+
+	assert.Equal(t, []map[string]string{
+		{
+			"label":                      "deploy",
+			"phase":                      "deprecated",
+			"shuttle.contextID":          "context-id",
+			"shuttle.runID":              "run-id",
+			"shuttle.deprecated.script":  "deploy",
+			"shuttle.deprecated.source":  "plan.yaml",
+			"shuttle.deprecated.message": "Use deploy-v2 instead",
+		},
+	}, recorder.traces)
+}
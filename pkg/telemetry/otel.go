@@ -0,0 +1,361 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	envOTelEndpoint = "SHUTTLE_OTEL_ENDPOINT"
+	envOTelHeaders  = "SHUTTLE_OTEL_HEADERS"
+	// envTraceParent is the W3C trace context of the span nested shuttle
+	// invocations are children of.
+	envTraceParent = "TRACEPARENT"
+
+	telemetrySpan string = "shuttle.span"
+)
+
+// attributes of spans
+const (
+	TelemetryScript        string = "shuttle.script"
+	TelemetryPlan          string = "shuttle.plan"
+	TelemetryGolangActions string = "shuttle.golang.actions"
+	TelemetryGolangTarget  string = "shuttle.golang.target"
+)
+
+// otelExportTimeout is how long exporting spans may take before they are
+// dropped
+const otelExportTimeout = 5 * time.Second
+
+// tracer exports spans if SHUTTLE_OTEL_ENDPOINT is set. It is nil otherwise.
+var tracer *otelTracer
+
+// span is an OpenTelemetry span recorded by shuttle.
+type span struct {
+	traceID    [16]byte
+	spanID     [8]byte
+	parentID   [8]byte
+	name       string
+	start      time.Time
+	end        time.Time
+	attributes map[string]string
+	// err is the error the span failed with if any
+	err error
+	// root is set for spans without a parent in this invocation
+	root bool
+}
+
+// StartSpan starts a span named name as a child of the span of ctx and
+// returns a context carrying it along with a function ending it. Spans of one
+// invocation share a trace derived from its context ID. Nothing is recorded
+// unless SHUTTLE_OTEL_ENDPOINT is set or ctx records timings, see
+// WithTimings.
+func StartSpan(ctx context.Context, name string, attributes map[string]string) (context.Context, func(err error)) {
+	timings := TimingsFrom(ctx)
+	if tracer == nil && timings == nil {
+		return ctx, func(error) {}
+	}
+	s := newSpan(ctx, name, time.Now(), attributes)
+	return context.WithValue(ctx, telemetrySpan, s), func(err error) {
+		s.end = time.Now()
+		s.err = err
+		endSpan(timings, s)
+	}
+}
+
+// recordSpan records a completed span named name as a child of the span of
+// ctx.
+func recordSpan(ctx context.Context, name string, start, end time.Time, attributes map[string]string, err error) {
+	timings := TimingsFrom(ctx)
+	if tracer == nil && timings == nil {
+		return
+	}
+	s := newSpan(ctx, name, start, attributes)
+	s.end = end
+	s.err = err
+	endSpan(timings, s)
+}
+
+// endSpan records the completed span s in timings and exports it if spans
+// are exported.
+func endSpan(timings *Timings, s *span) {
+	timings.record(s)
+	if tracer != nil {
+		tracer.record(s)
+	}
+}
+
+func newSpan(ctx context.Context, name string, start time.Time, attributes map[string]string) *span {
+	s := &span{
+		name:       name,
+		start:      start,
+		attributes: attributes,
+	}
+	rand.Read(s.spanID[:])
+	if parent, ok := ctx.Value(telemetrySpan).(*span); ok {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+		return s
+	}
+	s.root = true
+	s.traceID = traceIDFrom(ContextIDFrom(ctx))
+	// invocations nested in an action continue its trace
+	if traceID, parentID, ok := parseTraceParent(os.Getenv(envTraceParent)); ok && traceID == s.traceID {
+		s.parentID = parentID
+	}
+	return s
+}
+
+// TraceParent returns the W3C trace context of the span of ctx, or an empty
+// string if there is none.
+func TraceParent(ctx context.Context) string {
+	s, ok := ctx.Value(telemetrySpan).(*span)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(s.traceID[:]), hex.EncodeToString(s.spanID[:]))
+}
+
+// traceIDFrom returns the trace ID of spans of an invocation with contextID.
+// Context IDs are UUIDs unless set otherwise through SHUTTLE_CONTEXT_ID in
+// which case they are hashed. Spans without a context ID get a trace of their
+// own.
+func traceIDFrom(contextID string) [16]byte {
+	var traceID [16]byte
+	if contextID == "" {
+		rand.Read(traceID[:])
+		return traceID
+	}
+	if id, err := uuid.Parse(contextID); err == nil {
+		return id
+	}
+	sum := sha256.Sum256([]byte(contextID))
+	copy(traceID[:], sum[:])
+	return traceID
+}
+
+// parseTraceParent returns the trace and span ID of a W3C trace context, eg.
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+func parseTraceParent(raw string) ([16]byte, [8]byte, bool) {
+	var traceID [16]byte
+	var spanID [8]byte
+	parts := strings.Split(raw, "-")
+	if len(parts) != 4 || parts[0] != "00" {
+		return traceID, spanID, false
+	}
+	if n, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil || n != len(traceID) {
+		return traceID, spanID, false
+	}
+	if n, err := hex.Decode(spanID[:], []byte(parts[2])); err != nil || n != len(spanID) {
+		return traceID, spanID, false
+	}
+	return traceID, spanID, true
+}
+
+// setupOTel enables exporting spans to the OTLP endpoint of
+// SHUTTLE_OTEL_ENDPOINT.
+func setupOTel() {
+	endpoint := os.Getenv(envOTelEndpoint)
+	if endpoint == "" {
+		return
+	}
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint = strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+	properties := make(map[string]string)
+	WithGoInfo()(properties)
+	tracer = &otelTracer{
+		endpoint: endpoint,
+		headers:  parseOTelHeaders(os.Getenv(envOTelHeaders)),
+		resource: properties,
+		client:   http.DefaultClient,
+	}
+}
+
+// parseOTelHeaders returns the headers of raw on the form
+// <key>=<value>,<key>=<value>.
+func parseOTelHeaders(raw string) map[string]string {
+	headers := make(map[string]string)
+	for _, header := range strings.Split(raw, ",") {
+		key, value, ok := strings.Cut(header, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			continue
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+// otelTracer buffers spans and exports them over OTLP/HTTP using the JSON
+// encoding. Spans are exported once a root span ends as its children end
+// before it.
+type otelTracer struct {
+	endpoint string
+	headers  map[string]string
+	resource map[string]string
+	client   *http.Client
+
+	mu    sync.Mutex
+	spans []*span
+}
+
+func (t *otelTracer) record(s *span) {
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	var spans []*span
+	if s.root {
+		spans = t.spans
+		t.spans = nil
+	}
+	t.mu.Unlock()
+
+	if len(spans) == 0 {
+		return
+	}
+	if err := t.export(spans); err != nil {
+		log.Printf("failed to export spans to %s: %s", t.endpoint, err)
+	}
+}
+
+func (t *otelTracer) export(spans []*span) error {
+	body, err := json.Marshal(otlpRequest(t.resource, spans))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), otelExportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// OTLP JSON encoding of spans, see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+type (
+	otlpTraces struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusOK         = 1
+	otlpStatusError      = 2
+)
+
+func otlpRequest(resource map[string]string, spans []*span) otlpTraces {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		status := otlpStatus{Code: otlpStatusOK}
+		if s.err != nil {
+			status = otlpStatus{Code: otlpStatusError, Message: s.err.Error()}
+		}
+		parentID := ""
+		if s.parentID != ([8]byte{}) {
+			parentID = hex.EncodeToString(s.parentID[:])
+		}
+		encoded = append(encoded, otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			ParentSpanID:      parentID,
+			Name:              s.name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.attributes),
+			Status:            status,
+		})
+	}
+	resourceAttributes := map[string]string{"service.name": appKey}
+	for key, value := range resource {
+		resourceAttributes[key] = value
+	}
+	return otlpTraces{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{Attributes: otlpAttributes(resourceAttributes)},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/lunarway/shuttle"},
+				Spans: encoded,
+			}},
+		}},
+	}
+}
+
+// otlpAttributes returns attributes ordered by key.
+func otlpAttributes(attributes map[string]string) []otlpAttribute {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	encoded := make([]otlpAttribute, 0, len(keys))
+	for _, key := range keys {
+		encoded = append(encoded, otlpAttribute{Key: key, Value: otlpValue{StringValue: attributes[key]}})
+	}
+	return encoded
+}
@@ -0,0 +1,109 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartSpan_export(t *testing.T) {
+	requests := make(chan otlpTraces, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		var traces otlpTraces
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&traces))
+		requests <- traces
+	}))
+	defer server.Close()
+	t.Setenv("SHUTTLE_OTEL_ENDPOINT", server.URL)
+	t.Setenv("SHUTTLE_OTEL_HEADERS", "Authorization=Bearer token")
+	t.Setenv("TRACEPARENT", "")
+	defer func() { tracer = nil }()
+	setupOTel()
+
+	ctx := context.WithValue(context.Background(), telemetryContextID, "4bf92f35-77b3-4da6-a3ce-929d0e0e4736")
+	scriptCtx, endScript := StartSpan(ctx, "shuttle.script", map[string]string{TelemetryScript: "build"})
+	start := time.Date(2023, 7, 17, 15, 21, 27, 0, time.UTC)
+	TraceAction(scriptCtx, ActionSpan{Script: "build", Action: 0, Kind: "shell", Start: start, End: start.Add(time.Second), ExitCode: 2})
+	endScript(errors.New("exit code 2"))
+
+	traces := <-requests
+	require.Len(t, traces.ResourceSpans, 1)
+	assert.Contains(t, traces.ResourceSpans[0].Resource.Attributes, otlpAttribute{Key: "service.name", Value: otlpValue{StringValue: "shuttle"}})
+	spans := traces.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+	action, script := spans[0], spans[1]
+	assert.Equal(t, "shuttle.action", action.Name)
+	assert.Equal(t, "shuttle.script", script.Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", script.TraceID, "trace of the context ID")
+	assert.Equal(t, script.TraceID, action.TraceID)
+	assert.Empty(t, script.ParentSpanID)
+	assert.Equal(t, script.SpanID, action.ParentSpanID)
+	assert.Equal(t, "1689607287000000000", action.StartTimeUnixNano)
+	assert.Equal(t, "1689607288000000000", action.EndTimeUnixNano)
+	assert.Equal(t, otlpStatus{Code: otlpStatusError, Message: "exited with code 2"}, action.Status)
+	assert.Equal(t, []otlpAttribute{
+		{Key: "shuttle.action.exitCode", Value: otlpValue{StringValue: "2"}},
+		{Key: "shuttle.action.index", Value: otlpValue{StringValue: "0"}},
+		{Key: "shuttle.action.kind", Value: otlpValue{StringValue: "shell"}},
+		{Key: "shuttle.action.script", Value: otlpValue{StringValue: "build"}},
+	}, action.Attributes)
+	assert.Equal(t, otlpStatus{Code: otlpStatusError, Message: "exit code 2"}, script.Status)
+}
+
+func TestStartSpan_traceParent(t *testing.T) {
+	t.Setenv("TRACEPARENT", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	defer func(previous *otelTracer) { tracer = previous }(tracer)
+	tracer = &otelTracer{}
+
+	ctx := context.WithValue(context.Background(), telemetryContextID, "4bf92f35-77b3-4da6-a3ce-929d0e0e4736")
+	ctx, _ = StartSpan(ctx, "shuttle.script", nil)
+
+	s := ctx.Value(telemetrySpan).(*span)
+	assert.True(t, s.root)
+	assert.Equal(t, [8]byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}, s.parentID, "parent of the invoking shuttle")
+	traceID, spanID, ok := parseTraceParent(TraceParent(ctx))
+	assert.True(t, ok)
+	assert.Equal(t, s.traceID, traceID)
+	assert.Equal(t, s.spanID, spanID)
+}
+
+func TestStartSpan_disabled(t *testing.T) {
+	defer func(previous *otelTracer) { tracer = previous }(tracer)
+	tracer = nil
+	ctx := context.Background()
+
+	spanCtx, end := StartSpan(ctx, "shuttle.script", nil)
+	end(nil)
+
+	assert.Equal(t, ctx, spanCtx)
+	assert.Empty(t, TraceParent(spanCtx))
+}
+
+func TestParseTraceParent_invalid(t *testing.T) {
+	for _, raw := range []string{
+		"",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902zz-01",
+	} {
+		_, _, ok := parseTraceParent(raw)
+		assert.False(t, ok, raw)
+	}
+}
+
+func TestParseOTelHeaders(t *testing.T) {
+	assert.Equal(t, map[string]string{
+		"Authorization": "Bearer token",
+		"X-Tenant":      "ci",
+	}, parseOTelHeaders("Authorization=Bearer token, X-Tenant=ci,invalid"))
+}
@@ -25,6 +25,8 @@ var (
 
 // Initializes the telemetry setup, if not called, NoopTelemetryClient will be used
 func Setup() {
+	setupOTel()
+
 	if remoteTracing := os.Getenv("SHUTTLE_REMOTE_TRACING"); remoteTracing != "" {
 		properties := make(map[string]string, 0)
 		sysinfo := WithGoInfo()
@@ -0,0 +1,80 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const telemetryTimings string = "shuttle.timings"
+
+// Timing is a completed span recorded by Timings.
+type Timing struct {
+	// ID and ParentID identify the span and its parent. ParentID is empty
+	// for spans without a parent in the invocation.
+	ID         string
+	ParentID   string
+	Name       string
+	Attributes map[string]string
+	Start      time.Time
+	End        time.Time
+	// Err is the error the span failed with if any
+	Err error
+}
+
+// Duration returns how long the span took.
+func (t Timing) Duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
+// Timings records the spans started from a context in the order they
+// complete, eg. for reporting where a run spent its time.
+type Timings struct {
+	mu      sync.Mutex
+	timings []Timing
+}
+
+// WithTimings returns a context recording the spans started from it, and
+// contexts derived from it, in the returned Timings.
+func WithTimings(ctx context.Context) (context.Context, *Timings) {
+	timings := &Timings{}
+	return context.WithValue(ctx, telemetryTimings, timings), timings
+}
+
+// TimingsFrom returns the Timings recording the spans of ctx, or nil if they
+// are not recorded.
+func TimingsFrom(ctx context.Context) *Timings {
+	timings, _ := ctx.Value(telemetryTimings).(*Timings)
+	return timings
+}
+
+// Take returns the spans recorded since the last call.
+func (t *Timings) Take() []Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timings := t.timings
+	t.timings = nil
+	return timings
+}
+
+// record records s. It is a no-op on nil Timings.
+func (t *Timings) record(s *span) {
+	if t == nil {
+		return
+	}
+	timing := Timing{
+		ID:         hex.EncodeToString(s.spanID[:]),
+		Name:       s.name,
+		Attributes: s.attributes,
+		Start:      s.start,
+		End:        s.end,
+		Err:        s.err,
+	}
+	if !s.root {
+		timing.ParentID = hex.EncodeToString(s.parentID[:])
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timings = append(t.timings, timing)
+}
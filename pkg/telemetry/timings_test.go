@@ -0,0 +1,29 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTimings(t *testing.T) {
+	defer func(previous *otelTracer) { tracer = previous }(tracer)
+	tracer = nil
+	ctx, timings := WithTimings(context.Background())
+
+	scriptCtx, endScript := StartSpan(ctx, "shuttle.script", map[string]string{TelemetryScript: "build"})
+	TraceAction(scriptCtx, ActionSpan{Script: "build", Action: 0, Kind: "shell"})
+	endScript(nil)
+
+	recorded := timings.Take()
+	require.Len(t, recorded, 2)
+	action, script := recorded[0], recorded[1]
+	assert.Equal(t, "shuttle.action", action.Name)
+	assert.Equal(t, script.ID, action.ParentID)
+	assert.Equal(t, "shuttle.script", script.Name)
+	assert.Empty(t, script.ParentID)
+	assert.Equal(t, "build", script.Attributes[TelemetryScript])
+	assert.Empty(t, timings.Take(), "timings are taken once")
+}
@@ -10,6 +10,8 @@ import (
 
 	sprig "github.com/Masterminds/sprig/v3"
 	yaml "gopkg.in/yaml.v2"
+
+	"github.com/lunarway/shuttle/pkg/changes"
 )
 
 type KeyValuePair struct {
@@ -38,6 +40,7 @@ func GetFuncMap() template.FuncMap {
 		"trim":           strings.TrimSpace,
 		"upperFirst":     TmplUpperFirst,
 		"rightPad":       TmplRightPad,
+		"changed":        TmplChanged,
 	}
 
 	for k, v := range extra {
@@ -213,6 +216,16 @@ func TmplGetFiles(directoryPath string) []os.FileInfo {
 	return files
 }
 
+// TmplChanged returns whether files matching any of the git pathspecs changed
+// in the working directory compared to the base of changes.Base.
+func TmplChanged(pathspecs ...string) (bool, error) {
+	files, err := changes.Files(".", changes.Base("."), pathspecs...)
+	if err != nil {
+		return false, err
+	}
+	return len(files) != 0, nil
+}
+
 func getInner(property string, input interface{}) interface{} {
 	switch t := input.(type) {
 	default:
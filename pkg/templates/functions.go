@@ -0,0 +1,116 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// FunctionsFile is the file in the templates directory of a plan or project
+// defining custom template functions.
+const FunctionsFile = "functions.yaml"
+
+// maxFunctionDepth is how deep custom functions may call each other before
+// rendering fails, eg. if a function calls itself.
+const maxFunctionDepth = 100
+
+var functionName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Function is a custom template function rendering Template with its
+// arguments available as the fields named by Params, eg. {{ .service }}.
+type Function struct {
+	Params   []string `yaml:"params"`
+	Template string   `yaml:"template"`
+}
+
+// LoadFunctions returns the functions of GetFuncMap along with the custom
+// functions of the files at paths. Files that do not exist are skipped and
+// functions of later files replace those of earlier files with the same name.
+// Custom functions can use each other but cannot replace built-in functions.
+func LoadFunctions(paths ...string) (template.FuncMap, error) {
+	funcs := GetFuncMap()
+	builtIn := make(map[string]bool, len(funcs))
+	for name := range funcs {
+		builtIn[name] = true
+	}
+
+	functions := make(map[string]Function)
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var fileFunctions map[string]Function
+		if err := yaml.UnmarshalStrict(content, &fileFunctions); err != nil {
+			return nil, fmt.Errorf("parse template functions '%s': %w", path, err)
+		}
+		for name, function := range fileFunctions {
+			if !functionName.MatchString(name) {
+				return nil, fmt.Errorf("template function '%s' of '%s' is invalid: names must be letters, digits and underscores", name, path)
+			}
+			if builtIn[name] {
+				return nil, fmt.Errorf("template function '%s' of '%s' is invalid: it replaces a built-in function", name, path)
+			}
+			functions[name] = function
+		}
+	}
+
+	templates := make(map[string]*template.Template, len(functions))
+	depth := 0
+	for name, function := range functions {
+		name, function := name, function
+		funcs[name] = func(args ...interface{}) (string, error) {
+			if len(args) != len(function.Params) {
+				return "", fmt.Errorf(
+					"template function '%s' takes %d arguments (%s) but got %d",
+					name,
+					len(function.Params),
+					strings.Join(function.Params, ", "),
+					len(args),
+				)
+			}
+			if depth >= maxFunctionDepth {
+				return "", fmt.Errorf("template function '%s' is nested more than %d times", name, maxFunctionDepth)
+			}
+			depth++
+			defer func() { depth-- }()
+
+			data := make(map[string]interface{}, len(args))
+			for i, param := range function.Params {
+				data[param] = args[i]
+			}
+			var output bytes.Buffer
+			if err := templates[name].Execute(&output, data); err != nil {
+				return "", err
+			}
+			return output.String(), nil
+		}
+	}
+	// functions are parsed once all are known such that they can use each
+	// other
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t, err := template.New(name).
+			Funcs(funcs).
+			Option("missingkey=error").
+			Parse(functions[name].Template)
+		if err != nil {
+			return nil, fmt.Errorf("parse template function '%s': %w", name, err)
+		}
+		templates[name] = t
+	}
+	return funcs, nil
+}
@@ -0,0 +1,128 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFunctions(t *testing.T) {
+	writeFunctions := func(t *testing.T, content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), FunctionsFile)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+	plan := writeFunctions(t, `
+image:
+  params: [service, tag]
+  template: '{{ registry }}/{{ .service | upper }}:{{ .tag }}'
+registry:
+  template: registry.example.com
+loop:
+  template: '{{ loop }}'
+`)
+	project := writeFunctions(t, `
+registry:
+  template: registry.local
+`)
+
+	tt := []struct {
+		name     string
+		paths    []string
+		template string
+		output   string
+		err      string
+	}{
+		{
+			name:     "plan functions",
+			paths:    []string{plan},
+			template: `{{ image "api" "v1" }}`,
+			output:   "registry.example.com/API:v1",
+		},
+		{
+			name:     "project replaces plan",
+			paths:    []string{plan, project},
+			template: `{{ image "api" "v1" }}`,
+			output:   "registry.local/API:v1",
+		},
+		{
+			name:     "missing files",
+			paths:    []string{filepath.Join(t.TempDir(), FunctionsFile)},
+			template: `{{ "api" | upper }}`,
+			output:   "API",
+		},
+		{
+			name:     "wrong number of arguments",
+			paths:    []string{plan},
+			template: `{{ image "api" }}`,
+			err:      "template function 'image' takes 2 arguments (service, tag) but got 1",
+		},
+		{
+			name:     "recursion",
+			paths:    []string{plan},
+			template: `{{ loop }}`,
+			err:      "template function 'loop' is nested more than 100 times",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			funcs, err := LoadFunctions(tc.paths...)
+			require.NoError(t, err)
+			tmpl, err := template.New("test").Funcs(funcs).Parse(tc.template)
+			require.NoError(t, err)
+
+			var output strings.Builder
+			err = tmpl.Execute(&output, nil)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.output, output.String())
+		})
+	}
+}
+
+func TestLoadFunctions_invalid(t *testing.T) {
+	tt := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{
+			name:    "built-in function",
+			content: "upper:\n  template: x",
+			err:     "it replaces a built-in function",
+		},
+		{
+			name:    "invalid name",
+			content: "image-name:\n  template: x",
+			err:     "names must be letters, digits and underscores",
+		},
+		{
+			name:    "unknown field",
+			content: "image:\n  body: x",
+			err:     "field body not found",
+		},
+		{
+			name:    "invalid template",
+			content: "image:\n  template: '{{ .name '",
+			err:     "parse template function 'image'",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), FunctionsFile)
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o644))
+
+			_, err := LoadFunctions(path)
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// maxFrameSize is the amount of pending output that triggers a flush before
+// the frame interval has passed
+const maxFrameSize = 64 * 1024
+
+// FrameBuffer coalesces writes to one or more writers and flushes them at most
+// once per interval. The order of writes is preserved across all writers of
+// the buffer, eg. stdout and stderr.
+type FrameBuffer struct {
+	mu       sync.Mutex
+	interval time.Duration
+	pending  []frameChunk
+	size     int
+	timer    *time.Timer
+}
+
+type frameChunk struct {
+	w    io.Writer
+	data []byte
+}
+
+// NewFrameBuffer returns a FrameBuffer flushing writes every interval.
+func NewFrameBuffer(interval time.Duration) *FrameBuffer {
+	return &FrameBuffer{
+		interval: interval,
+	}
+}
+
+// Writer returns a writer buffering writes to w in the frame buffer.
+func (f *FrameBuffer) Writer(w io.Writer) io.Writer {
+	return &frameWriter{
+		frames: f,
+		w:      w,
+	}
+}
+
+// Flush writes all pending output to the underlying writers.
+func (f *FrameBuffer) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.flush()
+}
+
+func (f *FrameBuffer) flush() error {
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+	var err error
+	for _, chunk := range f.pending {
+		_, writeErr := chunk.w.Write(chunk.data)
+		if writeErr != nil && err == nil {
+			err = writeErr
+		}
+	}
+	f.pending = f.pending[:0]
+	f.size = 0
+	return err
+}
+
+func (f *FrameBuffer) write(w io.Writer, p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	last := len(f.pending) - 1
+	if last >= 0 && f.pending[last].w == w {
+		f.pending[last].data = append(f.pending[last].data, p...)
+	} else {
+		f.pending = append(f.pending, frameChunk{
+			w:    w,
+			data: append([]byte(nil), p...),
+		})
+	}
+	f.size += len(p)
+
+	if f.size >= maxFrameSize {
+		return len(p), f.flush()
+	}
+	if f.timer == nil {
+		f.timer = time.AfterFunc(f.interval, func() {
+			f.Flush()
+		})
+	}
+	return len(p), nil
+}
+
+type frameWriter struct {
+	frames *FrameBuffer
+	w      io.Writer
+}
+
+func (w *frameWriter) Write(p []byte) (int, error) {
+	return w.frames.write(w.w, p)
+}
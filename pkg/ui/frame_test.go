@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingWriter records the number of writes to it as an approximation of
+// write syscalls to a terminal.
+type countingWriter struct {
+	mu     sync.Mutex
+	writes int
+	buf    *bytes.Buffer
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes++
+	return w.buf.Write(p)
+}
+
+func TestFrameBuffer(t *testing.T) {
+	t.Run("preserves order across writers", func(t *testing.T) {
+		var combined bytes.Buffer
+		out := &countingWriter{buf: &combined}
+		err := &countingWriter{buf: &combined}
+		uii := Create(out, err).SetFrameInterval(time.Hour)
+
+		uii.Output("out 1")
+		uii.Output("out 2")
+		uii.Infoln("err 1")
+		uii.Output("out 3")
+
+		assert.Empty(t, combined.String(), "output must be buffered until flushed")
+		uii.Flush()
+		assert.Equal(t, "out 1\nout 2\nerr 1\nout 3\n", combined.String())
+		assert.Equal(t, 2, out.writes, "consecutive writes must be coalesced")
+		assert.Equal(t, 1, err.writes)
+	})
+
+	t.Run("flushes after interval", func(t *testing.T) {
+		out := &countingWriter{buf: &bytes.Buffer{}}
+		uii := Create(out, out).SetFrameInterval(10 * time.Millisecond)
+
+		uii.Output("line")
+
+		assert.Eventually(t, func() bool {
+			out.mu.Lock()
+			defer out.mu.Unlock()
+			return out.buf.String() == "line\n"
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("flushes large frames immediately", func(t *testing.T) {
+		out := &countingWriter{buf: &bytes.Buffer{}}
+		frames := NewFrameBuffer(time.Hour)
+		w := frames.Writer(out)
+
+		w.Write(bytes.Repeat([]byte("a"), maxFrameSize))
+
+		assert.Equal(t, 1, out.writes)
+	})
+
+	t.Run("flush without frames", func(t *testing.T) {
+		var out bytes.Buffer
+		uii := Create(&out, &out)
+
+		uii.Output("line")
+		uii.Flush()
+
+		assert.Equal(t, "line\n", out.String())
+	})
+}
+
+// BenchmarkOutput compares the number of writes reaching the terminal with
+// and without output frames. Run with
+//
+//	go test ./pkg/ui -run xxx -bench BenchmarkOutput
+func BenchmarkOutput(b *testing.B) {
+	for _, tc := range []struct {
+		name     string
+		interval time.Duration
+	}{
+		{name: "unbuffered", interval: 0},
+		{name: "frames", interval: 50 * time.Millisecond},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			out := &countingWriter{buf: &bytes.Buffer{}}
+			uii := Create(out, out)
+			if tc.interval > 0 {
+				uii.SetFrameInterval(tc.interval)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				uii.Output("%s", fmt.Sprintf("line %d", i))
+			}
+			uii.Flush()
+			b.StopTimer()
+			b.ReportMetric(float64(out.writes)/float64(b.N), "writes/op")
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Format is the format output is written in
+type Format string
+
+const (
+	// FormatText writes output as plain text for humans
+	FormatText Format = "text"
+	// FormatJSON writes output as one JSON object per line for machine
+	// consumption
+	FormatJSON Format = "json"
+	// FormatPrefixed writes output as text with every output line of actions
+	// prefixed with its action
+	FormatPrefixed Format = "prefixed"
+	// FormatPlain writes output as text without ever prefixing output lines
+	// of actions, not even those of actions running at the same time
+	FormatPlain Format = "plain"
+)
+
+// ParseFormat returns the Format named by raw.
+func ParseFormat(raw string) (Format, error) {
+	switch Format(raw) {
+	case FormatText, FormatJSON, FormatPrefixed, FormatPlain:
+		return Format(raw), nil
+	default:
+		return "", fmt.Errorf(
+			"output format '%s' is invalid: must be one of %s, %s, %s, %s",
+			raw,
+			FormatText,
+			FormatJSON,
+			FormatPrefixed,
+			FormatPlain,
+		)
+	}
+}
+
+// SetFormat selects the format output is written in.
+func (ui *UI) SetFormat(format Format) *UI {
+	ui.format = format
+	return ui
+}
+
+// Format returns the format output is written in.
+func (ui *UI) Format() Format {
+	if ui.format == "" {
+		return FormatText
+	}
+	return ui.format
+}
+
+// WithAction returns a UI attributing its output to action of script when
+// writing JSON. Text output is unchanged.
+func (ui *UI) WithAction(script string, action int) *UI {
+	actionUI := *ui
+	actionUI.script = script
+	actionUI.action = &action
+	return &actionUI
+}
+
+// ScriptStarted emits the start of script as an event when writing JSON.
+// Nothing is written as text.
+func (ui *UI) ScriptStarted(script string) {
+	if ui.format != FormatJSON {
+		return
+	}
+	ui.withScript(script).writeJSON(jsonLine{
+		Level:   "info",
+		Stream:  "stderr",
+		Event:   "script-started",
+		Message: "started",
+	})
+}
+
+// ScriptExited emits the exit code and duration of script as an event when
+// writing JSON. Nothing is written as text.
+func (ui *UI) ScriptExited(script string, exitCode int, duration time.Duration) {
+	if ui.format != FormatJSON {
+		return
+	}
+	durationMs := duration.Milliseconds()
+	ui.withScript(script).writeJSON(jsonLine{
+		Level:      "info",
+		Stream:     "stderr",
+		Event:      "script-exited",
+		ExitCode:   &exitCode,
+		DurationMs: &durationMs,
+		Message:    fmt.Sprintf("exited with code %d", exitCode),
+	})
+}
+
+// ActionStarted emits the start of the action of the UI as an event when
+// writing JSON. Nothing is written as text.
+func (ui *UI) ActionStarted() {
+	if ui.format != FormatJSON {
+		return
+	}
+	ui.writeJSON(jsonLine{
+		Level:   "info",
+		Stream:  "stderr",
+		Event:   "action-started",
+		Message: "started",
+	})
+}
+
+// ActionExited emits the exit code and duration of the action of the UI as an
+// event when writing JSON. Nothing is written as text.
+func (ui *UI) ActionExited(exitCode int, duration time.Duration) {
+	if ui.format != FormatJSON {
+		return
+	}
+	durationMs := duration.Milliseconds()
+	ui.writeJSON(jsonLine{
+		Level:      "info",
+		Stream:     "stderr",
+		Event:      "action-exited",
+		ExitCode:   &exitCode,
+		DurationMs: &durationMs,
+		Message:    fmt.Sprintf("exited with code %d", exitCode),
+	})
+}
+
+// withScript returns a UI attributing its output to script.
+func (ui *UI) withScript(script string) *UI {
+	scriptUI := *ui
+	scriptUI.script = script
+	scriptUI.action = nil
+	return &scriptUI
+}
+
+// jsonLine is a single line of JSON output
+type jsonLine struct {
+	Timestamp string `json:"timestamp"`
+	Level     string `json:"level"`
+	Script    string `json:"script,omitempty"`
+	Action    *int   `json:"action,omitempty"`
+	Stream    string `json:"stream"`
+	Event     string `json:"event,omitempty"`
+	ExitCode  *int   `json:"exitCode,omitempty"`
+	// DurationMs is the duration in milliseconds of the script or action of
+	// an exited event
+	DurationMs *int64 `json:"durationMs,omitempty"`
+	Message    string `json:"message"`
+}
+
+// ansiEscape matches the terminal colour codes used in text output
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// writeJSON writes line to stdout. All lines are written to the same stream
+// to keep their order and the original stream is kept in the line itself.
+func (ui *UI) writeJSON(line jsonLine) {
+	now := time.Now
+	if ui.now != nil {
+		now = ui.now
+	}
+	line.Timestamp = now().UTC().Format(time.RFC3339Nano)
+	line.Script = ui.script
+	line.Action = ui.action
+	line.Message = ansiEscape.ReplaceAllString(line.Message, "")
+	// a line of plain strings and ints always encodes
+	encoded, _ := json.Marshal(line)
+	ui.writeLine(ui.Out, string(encoded))
+}
@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormat(t *testing.T) {
+	tt := []struct {
+		input    string
+		format   Format
+		errorMsg string
+	}{
+		{input: "text", format: FormatText},
+		{input: "json", format: FormatJSON},
+		{input: "prefixed", format: FormatPrefixed},
+		{input: "plain", format: FormatPlain},
+		{input: "yaml", errorMsg: "output format 'yaml' is invalid: must be one of text, json, prefixed, plain"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.input, func(t *testing.T) {
+			format, err := ParseFormat(tc.input)
+			if tc.errorMsg != "" {
+				assert.EqualError(t, err, tc.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.format, format)
+		})
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	uii := Create(&stdout, &stderr).SetFormat(FormatJSON)
+	uii.now = func() time.Time {
+		return time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	}
+
+	uii.Verboseln("hidden")
+	uii.Titleln("title")
+	uii.Errorln("failed")
+	uii.ScriptStarted("build")
+	actionUI := uii.WithAction("build", 1)
+	actionUI.ActionStarted()
+	actionUI.Output("hello")
+	actionUI.Infoln("warning")
+	actionUI.ActionExited(4, 1500*time.Millisecond)
+	uii.ScriptExited("build", 4, 2*time.Second)
+	uii.SetUserLevel(LevelVerbose)
+	uii.Verboseln("shown")
+
+	assert.Equal(t, `{"timestamp":"2024-01-01T10:00:00Z","level":"info","stream":"stderr","message":"title"}
+{"timestamp":"2024-01-01T10:00:00Z","level":"error","stream":"stderr","message":"failed"}
+{"timestamp":"2024-01-01T10:00:00Z","level":"info","script":"build","stream":"stderr","event":"script-started","message":"started"}
+{"timestamp":"2024-01-01T10:00:00Z","level":"info","script":"build","action":1,"stream":"stderr","event":"action-started","message":"started"}
+{"timestamp":"2024-01-01T10:00:00Z","level":"info","script":"build","action":1,"stream":"stdout","message":"hello"}
+{"timestamp":"2024-01-01T10:00:00Z","level":"info","script":"build","action":1,"stream":"stderr","message":"warning"}
+{"timestamp":"2024-01-01T10:00:00Z","level":"info","script":"build","action":1,"stream":"stderr","event":"action-exited","exitCode":4,"durationMs":1500,"message":"exited with code 4"}
+{"timestamp":"2024-01-01T10:00:00Z","level":"info","script":"build","stream":"stderr","event":"script-exited","exitCode":4,"durationMs":2000,"message":"exited with code 4"}
+{"timestamp":"2024-01-01T10:00:00Z","level":"verbose","stream":"stderr","message":"shown"}
+`, stdout.String())
+	assert.Empty(t, stderr.String(), "all JSON lines must be written to stdout")
+}
+
+func TestTextFormat_actionExited(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	uii := Create(&stdout, &stderr).WithAction("build", 0)
+
+	uii.ScriptStarted("build")
+	uii.ActionStarted()
+	uii.Output("hello")
+	uii.ActionExited(0, time.Second)
+	uii.ScriptExited("build", 0, time.Second)
+
+	assert.Equal(t, "hello\n", stdout.String())
+	assert.Empty(t, stderr.String())
+}
@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"fmt"
+	"sync"
+)
+
+// LineDecorator decorates the output lines of the steps of scripts, eg. to
+// tell apart the output of actions running at the same time.
+type LineDecorator interface {
+	// Decorate returns line written to stream by step, eg. build/0.
+	Decorate(step, stream, line string) string
+}
+
+// PlainLines writes lines as they are.
+type PlainLines struct{}
+
+// Decorate returns line as is.
+func (PlainLines) Decorate(step, stream, line string) string {
+	return line
+}
+
+// stepColors are the colours steps are given in order. Red is left out as it
+// is used for errors.
+var stepColors = []string{"36", "35", "33", "34", "32", "96", "95", "93", "94", "92"}
+
+// PrefixedLines prefixes lines with their step, eg. "[build/0] ".
+type PrefixedLines struct {
+	color  bool
+	lock   sync.Mutex
+	colors map[string]string
+}
+
+// NewPrefixedLines returns a LineDecorator prefixing lines with their step. If
+// color is set each step is given a colour of its own in the order they
+// write their first line.
+func NewPrefixedLines(color bool) *PrefixedLines {
+	return &PrefixedLines{color: color, colors: make(map[string]string)}
+}
+
+// Decorate returns line prefixed with step.
+func (p *PrefixedLines) Decorate(step, stream, line string) string {
+	if !p.color {
+		return fmt.Sprintf("[%s] %s", step, line)
+	}
+	return fmt.Sprintf("\x1b[%sm[%s]\x1b[0m %s", p.stepColor(step), step, line)
+}
+
+func (p *PrefixedLines) stepColor(step string) string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	color, ok := p.colors[step]
+	if !ok {
+		color = stepColors[len(p.colors)%len(stepColors)]
+		p.colors[step] = color
+	}
+	return color
+}
+
+// SetLineDecorator selects how output lines of steps are decorated when
+// writing text. Without a decorator it is up to the steps themselves.
+func (ui *UI) SetLineDecorator(decorator LineDecorator) *UI {
+	ui.lines = decorator
+	return ui
+}
+
+// LineDecorator returns the decorator selected with SetLineDecorator or nil.
+func (ui *UI) LineDecorator() LineDecorator {
+	return ui.lines
+}
+
+// StepOutput writes a line written to stream by step decorated with
+// decorator. Lines of stderr are written as info messages. JSON lines are
+// never decorated as they already name their action.
+func (ui *UI) StepOutput(decorator LineDecorator, step, stream, line string) {
+	if ui.format != FormatJSON {
+		line = decorator.Decorate(step, stream, line)
+	}
+	if stream == "stderr" {
+		ui.Infoln("%s", line)
+		return
+	}
+	ui.Output("%s", line)
+}
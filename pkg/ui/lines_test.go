@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefixedLines(t *testing.T) {
+	t.Run("without colour", func(t *testing.T) {
+		lines := NewPrefixedLines(false)
+
+		assert.Equal(t, "[build/0] out", lines.Decorate("build/0", "stdout", "out"))
+		assert.Equal(t, "[build/1] err", lines.Decorate("build/1", "stderr", "err"))
+	})
+
+	t.Run("colour per step", func(t *testing.T) {
+		lines := NewPrefixedLines(true)
+
+		assert.Equal(t, "\x1b[36m[build/0]\x1b[0m first", lines.Decorate("build/0", "stdout", "first"))
+		assert.Equal(t, "\x1b[35m[compile]\x1b[0m second", lines.Decorate("compile", "stderr", "second"))
+		assert.Equal(t, "\x1b[36m[build/0]\x1b[0m third", lines.Decorate("build/0", "stdout", "third"), "steps keep their colour")
+	})
+}
+
+func TestStepOutput(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		uii := Create(&stdout, &stderr)
+
+		uii.StepOutput(NewPrefixedLines(false), "build/0", "stdout", "out")
+		uii.StepOutput(NewPrefixedLines(false), "build/0", "stderr", "err")
+		uii.StepOutput(PlainLines{}, "build/0", "stdout", "plain")
+
+		assert.Equal(t, "[build/0] out\nplain\n", stdout.String())
+		assert.Equal(t, "[build/0] err\n", stderr.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var stdout bytes.Buffer
+		uii := Create(&stdout, &bytes.Buffer{}).SetFormat(FormatJSON)
+
+		uii.StepOutput(NewPrefixedLines(true), "build/0", "stdout", "out")
+
+		assert.Contains(t, stdout.String(), `"message":"out"`)
+		assert.NotContains(t, stdout.String(), "[build/0]")
+	})
+}
+
+// TestStepOutput_concurrent tests that lines written by goroutines sharing a
+// UI, eg. the output of a command and its lifecycle events, are never
+// interleaved.
+func TestStepOutput_concurrent(t *testing.T) {
+	var stderr bytes.Buffer
+	uii := Create(&bytes.Buffer{}, &stderr).SetUserLevel(LevelVerbose)
+	actionUI := uii.WithAction("build", 0)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			actionUI.StepOutput(PlainLines{}, "build/0", "stderr", "output")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			uii.Verboseln("lifecycle")
+		}
+	}()
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(stderr.String(), "\n"), "\n")
+	assert.Len(t, lines, 200)
+	for _, line := range lines {
+		assert.Contains(t, []string{"output", "lifecycle"}, line)
+	}
+}
@@ -3,6 +3,8 @@ package ui
 import (
 	"fmt"
 	"io"
+	"sync"
+	"time"
 )
 
 // UI is the abstraction of handling terminal output for shuttle
@@ -13,6 +15,15 @@ type UI struct {
 	UserLevelSet   bool
 	Out            io.Writer
 	Err            io.Writer
+	frames         *FrameBuffer
+	format         Format
+	lines          LineDecorator
+	script         string
+	action         *int
+	now            func() time.Time
+	// writes serializes lines written by goroutines of the UI and the UIs
+	// derived from it, eg. output of a command and its lifecycle events
+	writes *sync.Mutex
 }
 
 // Create doc
@@ -23,6 +34,7 @@ func Create(out, err io.Writer) *UI {
 		UserLevelSet:   false,
 		Out:            out,
 		Err:            err,
+		writes:         &sync.Mutex{},
 	}
 }
 
@@ -46,28 +58,62 @@ func (ui *UI) SetContext(level Level) *UI {
 	return ui
 }
 
+// SetFrameInterval coalesces output and flushes it at most once per interval
+// to reduce flicker on slow terminals. Call Flush before handing the terminal
+// to other writers.
+func (ui *UI) SetFrameInterval(interval time.Duration) *UI {
+	ui.frames = NewFrameBuffer(interval)
+	ui.Out = ui.frames.Writer(ui.Out)
+	ui.Err = ui.frames.Writer(ui.Err)
+	return ui
+}
+
+// Flush writes any output buffered by SetFrameInterval.
+func (ui *UI) Flush() {
+	if ui.frames == nil {
+		return
+	}
+	ui.frames.Flush()
+}
+
 // Output.
 func (ui *UI) Output(format string, args ...interface{}) {
-	fmt.Fprintln(ui.Out, fmt.Sprintf(format, args...))
+	if ui.format == FormatJSON {
+		ui.writeJSON(jsonLine{Level: "info", Stream: "stdout", Message: fmt.Sprintf(format, args...)})
+		return
+	}
+	ui.writeLine(ui.Out, fmt.Sprintf(format, args...))
 }
 
 // Verboseln prints a formatted verbose message line.
 func (ui *UI) Verboseln(format string, args ...interface{}) {
 	if ui.EffectiveLevel.OutputIsIncluded(LevelVerbose) {
-		fmt.Fprintln(ui.Err, fmt.Sprintf(format, args...))
+		if ui.format == FormatJSON {
+			ui.writeJSON(jsonLine{Level: "verbose", Stream: "stderr", Message: fmt.Sprintf(format, args...)})
+			return
+		}
+		ui.writeLine(ui.Err, fmt.Sprintf(format, args...))
 	}
 }
 
 // Infoln prints a formatted info message line.
 func (ui *UI) Infoln(format string, args ...interface{}) {
 	if ui.EffectiveLevel.OutputIsIncluded(LevelInfo) {
-		fmt.Fprintln(ui.Err, fmt.Sprintf(format, args...))
+		if ui.format == FormatJSON {
+			ui.writeJSON(jsonLine{Level: "info", Stream: "stderr", Message: fmt.Sprintf(format, args...)})
+			return
+		}
+		ui.writeLine(ui.Err, fmt.Sprintf(format, args...))
 	}
 }
 
 func (ui *UI) EmphasizeInfoln(format string, args ...interface{}) {
 	if ui.EffectiveLevel.OutputIsIncluded(LevelInfo) {
-		fmt.Fprintf(ui.Err, "\x1b[032;1m%s\x1b[0m\n", fmt.Sprintf(format, args...))
+		if ui.format == FormatJSON {
+			ui.writeJSON(jsonLine{Level: "info", Stream: "stderr", Message: fmt.Sprintf(format, args...)})
+			return
+		}
+		ui.writeLine(ui.Err, fmt.Sprintf("\x1b[032;1m%s\x1b[0m", fmt.Sprintf(format, args...)))
 	}
 }
 
@@ -79,6 +125,20 @@ func (ui *UI) Titleln(format string, args ...interface{}) {
 // Errorln doc
 func (ui *UI) Errorln(format string, args ...interface{}) {
 	if ui.EffectiveLevel.OutputIsIncluded(LevelError) {
-		fmt.Fprintf(ui.Err, "\x1b[31;1m%s\x1b[0m\n", fmt.Sprintf(format, args...))
+		if ui.format == FormatJSON {
+			ui.writeJSON(jsonLine{Level: "error", Stream: "stderr", Message: fmt.Sprintf(format, args...)})
+			return
+		}
+		ui.writeLine(ui.Err, fmt.Sprintf("\x1b[31;1m%s\x1b[0m", fmt.Sprintf(format, args...)))
+	}
+}
+
+// writeLine writes line and a newline to w. Lines are written one at a time
+// such that lines of goroutines sharing the UI are never interleaved.
+func (ui *UI) writeLine(w io.Writer, line string) {
+	if ui.writes != nil {
+		ui.writes.Lock()
+		defer ui.writes.Unlock()
 	}
+	fmt.Fprintln(w, line)
 }